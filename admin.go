@@ -0,0 +1,18 @@
+package proxyd
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// checkAdminToken reports whether r carries token as its bearer token, and
+// answers with 401 Unauthorized otherwise. An empty token authorizes nothing.
+func checkAdminToken(w http.ResponseWriter, r *http.Request, token string) bool {
+	bearer, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if token == "" || subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) != 1 {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
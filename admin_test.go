@@ -0,0 +1,29 @@
+package proxyd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckAdminToken(t *testing.T) {
+	check := func(header, token string) int {
+		r := httptest.NewRequest(http.MethodGet, "/admin/keys", nil)
+		if header != "" {
+			r.Header.Set("Authorization", header)
+		}
+		w := httptest.NewRecorder()
+		if checkAdminToken(w, r, token) {
+			return http.StatusOK
+		}
+		return w.Code
+	}
+	require.Equal(t, http.StatusOK, check("Bearer secret", "secret"))
+	require.Equal(t, http.StatusUnauthorized, check("Bearer wrong", "secret"))
+	require.Equal(t, http.StatusUnauthorized, check("secret", "other"))
+	require.Equal(t, http.StatusUnauthorized, check("", "secret"))
+	require.Equal(t, http.StatusUnauthorized, check("Bearer ", ""))
+	require.Equal(t, http.StatusUnauthorized, check("", ""))
+}
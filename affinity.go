@@ -0,0 +1,200 @@
+package proxyd
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+const (
+	AffinityRepinnedHeader = "X-Proxyd-Affinity-Repinned"
+
+	defaultAffinityTTL   = 10 * time.Minute
+	affinityMemoryLimit  = 100000
+	affinityCookieMaxAge = 24 * time.Hour
+)
+
+// BackendAffinity pins clients to one backend of a group for stateful
+// methods, such as filters or debug sessions, which only work against the
+// backend that holds their state. Clients are identified by a cookie if one
+// is configured, or otherwise by their auth alias and IP. When the pinned
+// backend can't serve a request, the client is pinned to the backend that
+// did, and the response tells so with the X-Proxyd-Affinity-Repinned header.
+type BackendAffinity struct {
+	methods map[string]bool
+	cookie  string
+	ttl     time.Duration
+
+	mu   sync.Mutex
+	pins *lru.Cache
+}
+
+type affinityPin struct {
+	backend string
+	expires time.Time
+}
+
+// affinitySession is the client of a request, and whether the request moved
+// it to another backend.
+type affinitySession struct {
+	key string
+
+	mu       sync.Mutex
+	repinned string
+}
+
+func NewBackendAffinity(cfg AffinityConfig) *BackendAffinity {
+	a := &BackendAffinity{
+		methods: make(map[string]bool, len(cfg.Methods)),
+		cookie:  cfg.Cookie,
+		ttl:     time.Duration(cfg.TTL),
+	}
+	for _, method := range cfg.Methods {
+		a.methods[method] = true
+	}
+	if a.ttl == 0 {
+		a.ttl = defaultAffinityTTL
+	}
+	a.pins, _ = lru.New(affinityMemoryLimit)
+	return a
+}
+
+// Session identifies the client of the request, setting an affinity cookie
+// on the response if needed.
+func (a *BackendAffinity) Session(ctx context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+	if a == nil {
+		return ctx
+	}
+	var key string
+	if a.cookie != "" {
+		if c, err := r.Cookie(a.cookie); err == nil && c.Value != "" {
+			key = c.Value
+		} else {
+			var b [16]byte
+			if _, err := rand.Read(b[:]); err != nil {
+				log.Error("error generating affinity cookie", "err", err, "req_id", GetReqID(ctx))
+				return ctx
+			}
+			key = hex.EncodeToString(b[:])
+			http.SetCookie(w, &http.Cookie{
+				Name:     a.cookie,
+				Value:    key,
+				Path:     "/",
+				MaxAge:   int(affinityCookieMaxAge.Seconds()),
+				HttpOnly: true,
+			})
+		}
+	} else {
+		sum := sha256.Sum256([]byte(GetAuthCtx(ctx) + "/" + stripXFF(GetXForwardedFor(ctx))))
+		key = hex.EncodeToString(sum[:])
+	}
+	return context.WithValue(ctx, ContextKeyAffinity, &affinitySession{key: key}) // nolint:staticcheck
+}
+
+// Applies reports whether any of the requests need affinity.
+func (a *BackendAffinity) Applies(elems []batchElem) bool {
+	if a == nil {
+		return false
+	}
+	for _, elem := range elems {
+		if a.methods[elem.Req.Method] {
+			return true
+		}
+	}
+	return false
+}
+
+// Pin prefers the client's pinned backend of the group for the requests
+// forwarded with the returned context.
+func (a *BackendAffinity) Pin(ctx context.Context, group string) (context.Context, string) {
+	session := getAffinitySession(ctx)
+	if session == nil {
+		return ctx, ""
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	val, ok := a.pins.Get(group + "/" + session.key)
+	if !ok {
+		return ctx, ""
+	}
+	pin := val.(affinityPin)
+	if time.Now().After(pin.expires) {
+		a.pins.Remove(group + "/" + session.key)
+		return ctx, ""
+	}
+	return WithPreferredBackend(ctx, pin.backend), pin.backend
+}
+
+// Served pins the client to the backend that served its requests, given as
+// group/backend.
+func (a *BackendAffinity) Served(ctx context.Context, group, pinned, servedBy string) {
+	session := getAffinitySession(ctx)
+	if session == nil || servedBy == "" {
+		return
+	}
+	backend := strings.TrimPrefix(servedBy, group+"/")
+	a.mu.Lock()
+	a.pins.Add(group+"/"+session.key, affinityPin{backend: backend, expires: time.Now().Add(a.ttl)})
+	a.mu.Unlock()
+
+	if pinned != "" && pinned != backend {
+		log.Info("repinned client to backend",
+			"backend_group", group,
+			"from", pinned,
+			"to", backend,
+			"req_id", GetReqID(ctx),
+		)
+		RecordAffinityRepin(group)
+		session.mu.Lock()
+		session.repinned = backend
+		session.mu.Unlock()
+	}
+}
+
+// Repinned returns the backend the request moved the client to, if any.
+func (a *BackendAffinity) Repinned(ctx context.Context) string {
+	session := getAffinitySession(ctx)
+	if session == nil {
+		return ""
+	}
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	return session.repinned
+}
+
+func getAffinitySession(ctx context.Context) *affinitySession {
+	session, _ := ctx.Value(ContextKeyAffinity).(*affinitySession)
+	return session
+}
+
+// WithPreferredBackend makes backend groups try the named backend first, if
+// it's one they would use.
+func WithPreferredBackend(ctx context.Context, backend string) context.Context {
+	return context.WithValue(ctx, ContextKeyPreferredBackend, backend) // nolint:staticcheck
+}
+
+func GetPreferredBackend(ctx context.Context) string {
+	backend, _ := ctx.Value(ContextKeyPreferredBackend).(string)
+	return backend
+}
+
+// preferBackend moves the named backend to the front.
+func preferBackend(backends []*Backend, name string) []*Backend {
+	for i, be := range backends {
+		if be.Name == name {
+			ordered := make([]*Backend, 0, len(backends))
+			ordered = append(ordered, be)
+			ordered = append(ordered, backends[:i]...)
+			return append(ordered, backends[i+1:]...)
+		}
+	}
+	return backends
+}
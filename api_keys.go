@@ -0,0 +1,327 @@
+package proxyd
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	apiKeysRedisKey        = "api_keys"
+	defaultAPIKeysCacheTTL = 10 * time.Second
+	apiKeyPrefix           = "pk_"
+)
+
+var errInvalidAPIKeyRequest = errors.New("invalid api key request")
+
+// APIKey is a key issued at runtime. It authenticates requests the way
+// [authentication] secrets do, as its owner: the key goes in the first
+// segment of the path, the owner's auth policy applies, and the key's tier,
+// if set, replaces the policy's rate limit tier.
+type APIKey struct {
+	ID        string     `json:"id"`
+	Owner     string     `json:"owner"`
+	Tier      string     `json:"tier,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+func (k *APIKey) expired() bool {
+	return k.ExpiresAt != nil && !time.Now().Before(*k.ExpiresAt)
+}
+
+// WithAPIKeyID records the ID of the key issued at runtime a request
+// authenticated with.
+func WithAPIKeyID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ContextKeyAPIKeyID, id) // nolint:staticcheck
+}
+
+// GetAPIKeyID returns the ID of the key issued at runtime the request
+// authenticated with, or "".
+func GetAPIKeyID(ctx context.Context) string {
+	id, _ := ctx.Value(ContextKeyAPIKeyID).(string)
+	return id
+}
+
+// storedAPIKey is an APIKey as stored in Redis. Only the key's hash is
+// stored, so the key itself is only known to whoever created it.
+type storedAPIKey struct {
+	APIKey
+	Hash string `json:"hash"`
+}
+
+type cachedAPIKey struct {
+	key     *APIKey
+	policy  *AuthPolicy
+	expires time.Time
+}
+
+// KeyStore keeps the API keys in a Redis hash mapping key IDs to JSON encoded
+// keys. A key's ID is derived from the key, so lookups take one read.
+type KeyStore struct {
+	redisClient redis.UniversalClient
+	redisKey    string
+	adminToken  secretValue
+	tiers       map[string]*rateLimitTier
+	policies    AuthPolicies
+	cacheTTL    time.Duration
+
+	mu    sync.Mutex
+	cache map[string]cachedAPIKey
+}
+
+func NewKeyStore(cfg APIKeysConfig, redisClient redis.UniversalClient, namespace string, tiers map[string]*rateLimitTier, policies AuthPolicies) (*KeyStore, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	if redisClient == nil {
+		return nil, errors.New("api_keys requires a redis config")
+	}
+	if cfg.AdminToken == "" {
+		return nil, errors.New("api_keys.admin_token must be set")
+	}
+	ks := &KeyStore{
+		redisClient: redisClient,
+		redisKey:    apiKeysRedisKey,
+		tiers:       tiers,
+		policies:    policies,
+		cacheTTL:    time.Duration(cfg.CacheTTL),
+		cache:       make(map[string]cachedAPIKey),
+	}
+	ks.adminToken.Store(cfg.AdminToken)
+	if namespace != "" {
+		ks.redisKey = namespace + ":" + apiKeysRedisKey
+	}
+	if ks.cacheTTL == 0 {
+		ks.cacheTTL = defaultAPIKeysCacheTTL
+	}
+	return ks, nil
+}
+
+// hashAPIKey returns the ID and hash of a key.
+func hashAPIKey(secret string) (string, string) {
+	sum := sha256.Sum256([]byte(secret))
+	hash := hex.EncodeToString(sum[:])
+	return hash[:16], hash
+}
+
+// Create issues a new key, returned along with its metadata.
+func (ks *KeyStore) Create(ctx context.Context, owner, tier string, expiresAt *time.Time) (string, *APIKey, error) {
+	if owner == "" {
+		return "", nil, fmt.Errorf("%w: owner must be set", errInvalidAPIKeyRequest)
+	}
+	if tier != "" && ks.tiers[tier] == nil {
+		return "", nil, fmt.Errorf("%w: undefined rate limit tier %s", errInvalidAPIKeyRequest, tier)
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return "", nil, fmt.Errorf("%w: expires_at must be in the future", errInvalidAPIKeyRequest)
+	}
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", nil, err
+	}
+	secret := apiKeyPrefix + hex.EncodeToString(b)
+	id, hash := hashAPIKey(secret)
+	stored := storedAPIKey{
+		APIKey: APIKey{
+			ID:        id,
+			Owner:     owner,
+			Tier:      tier,
+			CreatedAt: time.Now().UTC(),
+			ExpiresAt: expiresAt,
+		},
+		Hash: hash,
+	}
+	if err := ks.redisClient.HSet(ctx, ks.redisKey, id, mustMarshalJSON(stored)).Err(); err != nil {
+		RecordRedisError("KeyStore")
+		return "", nil, wrapErr(err, "error storing api key")
+	}
+	return secret, &stored.APIKey, nil
+}
+
+// Revoke deletes a key. It reports whether the key existed.
+func (ks *KeyStore) Revoke(ctx context.Context, id string) (bool, error) {
+	n, err := ks.redisClient.HDel(ctx, ks.redisKey, id).Result()
+	if err != nil {
+		RecordRedisError("KeyStore")
+		return false, wrapErr(err, "error revoking api key")
+	}
+	ks.mu.Lock()
+	delete(ks.cache, id)
+	ks.mu.Unlock()
+	return n > 0, nil
+}
+
+// List returns the keys, without the keys themselves, ordered by creation.
+func (ks *KeyStore) List(ctx context.Context) ([]*APIKey, error) {
+	values, err := ks.redisClient.HGetAll(ctx, ks.redisKey).Result()
+	if err != nil {
+		RecordRedisError("KeyStore")
+		return nil, wrapErr(err, "error reading api keys")
+	}
+	keys := make([]*APIKey, 0, len(values))
+	for id, value := range values {
+		var stored storedAPIKey
+		if err := json.Unmarshal([]byte(value), &stored); err != nil {
+			log.Warn("invalid api key in redis", "id", id, "err", err)
+			continue
+		}
+		keys = append(keys, &stored.APIKey)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+	return keys, nil
+}
+
+// Lookup returns the key and the auth policy applying to its requests, or a
+// nil key if secret isn't a valid key. A nil KeyStore has no keys.
+func (ks *KeyStore) Lookup(ctx context.Context, secret string) (*APIKey, *AuthPolicy, error) {
+	if ks == nil || !strings.HasPrefix(secret, apiKeyPrefix) {
+		return nil, nil, nil
+	}
+	id, hash := hashAPIKey(secret)
+
+	ks.mu.Lock()
+	cached, ok := ks.cache[id]
+	ks.mu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		if cached.key.expired() {
+			return nil, nil, nil
+		}
+		return cached.key, cached.policy, nil
+	}
+
+	value, err := ks.redisClient.HGet(ctx, ks.redisKey, id).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		RecordRedisError("KeyStore")
+		return nil, nil, wrapErr(err, "error reading api key")
+	}
+	var stored storedAPIKey
+	if err := json.Unmarshal([]byte(value), &stored); err != nil {
+		return nil, nil, fmt.Errorf("invalid api key %s in redis: %w", id, err)
+	}
+	if subtle.ConstantTimeCompare([]byte(stored.Hash), []byte(hash)) != 1 {
+		return nil, nil, nil
+	}
+
+	key := &stored.APIKey
+	policy := ks.policy(key)
+	ks.mu.Lock()
+	ks.cache[id] = cachedAPIKey{key: key, policy: policy, expires: time.Now().Add(ks.cacheTTL)}
+	ks.mu.Unlock()
+	if key.expired() {
+		return nil, nil, nil
+	}
+	return key, policy, nil
+}
+
+// policy returns the owner's auth policy with the key's tier.
+func (ks *KeyStore) policy(key *APIKey) *AuthPolicy {
+	policy := ks.policies[key.Owner]
+	if key.Tier == "" {
+		return policy
+	}
+	tier := ks.tiers[key.Tier]
+	if tier == nil {
+		log.Warn("api key has undefined rate limit tier", "id", key.ID, "tier", key.Tier)
+		return policy
+	}
+	withTier := &AuthPolicy{Alias: key.Owner}
+	if policy != nil {
+		*withTier = *policy
+	}
+	withTier.tier = tier
+	return withTier
+}
+
+type createAPIKeyRequest struct {
+	Owner     string     `json:"owner"`
+	Tier      string     `json:"tier"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type createAPIKeyResponse struct {
+	Key string `json:"key"`
+	*APIKey
+}
+
+// ServeHTTP serves the key management endpoints:
+//
+//	POST /admin/keys         creates a key from {"owner", "tier", "expires_at"}
+//	GET /admin/keys          lists the keys
+//	DELETE /admin/keys/{id}  revokes a key
+func (ks *KeyStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !checkAdminToken(w, r, ks.adminToken.Load()) {
+		return
+	}
+
+	id := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/admin/keys"), "/")
+	switch {
+	case id == "" && r.Method == http.MethodPost:
+		var req createAPIKeyRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&req); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+		secret, key, err := ks.Create(r.Context(), req.Owner, req.Tier, req.ExpiresAt)
+		if errors.Is(err, errInvalidAPIKeyRequest) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			log.Error("error creating api key", "owner", req.Owner, "err", err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		log.Info("created api key", "id", key.ID, "owner", key.Owner, "tier", key.Tier)
+		writeAdminJSON(w, http.StatusCreated, createAPIKeyResponse{Key: secret, APIKey: key})
+	case id == "" && r.Method == http.MethodGet:
+		keys, err := ks.List(r.Context())
+		if err != nil {
+			log.Error("error listing api keys", "err", err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		writeAdminJSON(w, http.StatusOK, keys)
+	case id != "" && r.Method == http.MethodDelete:
+		found, err := ks.Revoke(r.Context(), id)
+		if err != nil {
+			log.Error("error revoking api key", "id", sanitizeLogValue(id), "err", err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		if !found {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		log.Info("revoked api key", "id", id)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func writeAdminJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Error("error writing admin response", "err", err)
+	}
+}
@@ -0,0 +1,131 @@
+package proxyd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestKeyStore(t *testing.T) (*KeyStore, *miniredis.Miniredis) {
+	redisServer, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(redisServer.Close)
+	redisClient := redis.NewClient(&redis.Options{
+		Addr: fmt.Sprintf("127.0.0.1:%s", redisServer.Port()),
+	})
+
+	tiers := map[string]*rateLimitTier{"pro": {mainLim: NoopFrontendRateLimiter}}
+	policies := AuthPolicies{"acme": {Alias: "acme", backendGroup: "acme"}}
+	ks, err := NewKeyStore(APIKeysConfig{Enabled: true, AdminToken: "admin"}, redisClient, "proxyd", tiers, policies)
+	require.NoError(t, err)
+	return ks, redisServer
+}
+
+func TestKeyStore(t *testing.T) {
+	ks, redisServer := newTestKeyStore(t)
+	ctx := context.Background()
+
+	secret, key, err := ks.Create(ctx, "acme", "pro", nil)
+	require.NoError(t, err)
+	require.Equal(t, "acme", key.Owner)
+	// only the hash is stored
+	require.NotContains(t, redisServer.HGet("proxyd:api_keys", key.ID), secret)
+
+	found, policy, err := ks.Lookup(ctx, secret)
+	require.NoError(t, err)
+	require.Equal(t, key.ID, found.ID)
+	require.Equal(t, "acme", policy.backendGroup)
+	require.Equal(t, ks.tiers["pro"], policy.tier)
+	// the owner's policy is left alone
+	require.Nil(t, ks.policies["acme"].tier)
+
+	found, _, err = ks.Lookup(ctx, secret+"0")
+	require.NoError(t, err)
+	require.Nil(t, found)
+
+	expiresAt := time.Now().Add(100 * time.Millisecond)
+	expiring, _, err := ks.Create(ctx, "other", "", &expiresAt)
+	require.NoError(t, err)
+	found, policy, err = ks.Lookup(ctx, expiring)
+	require.NoError(t, err)
+	require.Equal(t, "other", found.Owner)
+	require.Nil(t, policy)
+	// expiry applies to cached keys too
+	time.Sleep(150 * time.Millisecond)
+	found, _, err = ks.Lookup(ctx, expiring)
+	require.NoError(t, err)
+	require.Nil(t, found)
+
+	keys, err := ks.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	require.Equal(t, key.ID, keys[0].ID)
+
+	revoked, err := ks.Revoke(ctx, key.ID)
+	require.NoError(t, err)
+	require.True(t, revoked)
+	found, _, err = ks.Lookup(ctx, secret)
+	require.NoError(t, err)
+	require.Nil(t, found)
+	revoked, err = ks.Revoke(ctx, key.ID)
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	_, _, err = ks.Create(ctx, "acme", "enterprise", nil)
+	require.ErrorIs(t, err, errInvalidAPIKeyRequest)
+	_, _, err = ks.Create(ctx, "", "", nil)
+	require.ErrorIs(t, err, errInvalidAPIKeyRequest)
+
+	var nilStore *KeyStore
+	found, _, err = nilStore.Lookup(ctx, secret)
+	require.NoError(t, err)
+	require.Nil(t, found)
+}
+
+func TestKeyStoreAdmin(t *testing.T) {
+	ks, _ := newTestKeyStore(t)
+
+	do := func(method, path, token string, body []byte) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		ks.ServeHTTP(rec, req)
+		return rec
+	}
+
+	require.Equal(t, http.StatusUnauthorized, do("GET", "/admin/keys", "", nil).Code)
+	require.Equal(t, http.StatusUnauthorized, do("GET", "/admin/keys", "wrong", nil).Code)
+
+	rec := do("POST", "/admin/keys", "admin", []byte(`{"owner":"acme","tier":"pro"}`))
+	require.Equal(t, http.StatusCreated, rec.Code)
+	var created createAPIKeyResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+	require.NotEmpty(t, created.Key)
+	require.Equal(t, "pro", created.Tier)
+
+	require.Equal(t, http.StatusBadRequest, do("POST", "/admin/keys", "admin", []byte(`{"owner":"acme","tier":"enterprise"}`)).Code)
+	require.Equal(t, http.StatusBadRequest, do("POST", "/admin/keys", "admin", []byte(`{`)).Code)
+
+	rec = do("GET", "/admin/keys", "admin", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NotContains(t, rec.Body.String(), created.Key)
+	var keys []*APIKey
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &keys))
+	require.Len(t, keys, 1)
+	require.Equal(t, created.ID, keys[0].ID)
+
+	require.Equal(t, http.StatusNoContent, do("DELETE", "/admin/keys/"+created.ID, "admin", nil).Code)
+	require.Equal(t, http.StatusNotFound, do("DELETE", "/admin/keys/"+created.ID, "admin", nil).Code)
+	require.Equal(t, http.StatusMethodNotAllowed, do("PUT", "/admin/keys", "admin", nil).Code)
+}
@@ -0,0 +1,140 @@
+package proxyd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// AuthPolicy applies to the requests of one authenticated path, turning its
+// secret into a full API key: it can carry its own rate limit tier, restrict
+// the methods it may call, send them all to one backend group and set headers
+// on the requests forwarded to backends.
+type AuthPolicy struct {
+	Alias          string
+	tier           *rateLimitTier
+	allowedMethods map[string]bool
+	backendGroup   string
+	headers        map[string]string
+	debugHeader    bool
+}
+
+// rateLimitTier limits the requests of each alias assigned to it separately.
+type rateLimitTier struct {
+	name         string
+	mainLim      FrontendRateLimiter
+	overrideLims map[string]FrontendRateLimiter
+}
+
+func newRateLimitTiers(cfg map[string]RateLimitConfig, limiterFactory limiterFactoryFunc) map[string]*rateLimitTier {
+	tiers := make(map[string]*rateLimitTier, len(cfg))
+	for name, rc := range cfg {
+		tier := &rateLimitTier{
+			name:         name,
+			mainLim:      NoopFrontendRateLimiter,
+			overrideLims: make(map[string]FrontendRateLimiter),
+		}
+		if rc.BaseRate > 0 {
+			tier.mainLim = limiterFactory(time.Duration(rc.BaseInterval), rc.BaseRate, "tier_"+name)
+		}
+		for method, override := range rc.MethodOverrides {
+			tier.overrideLims[method] = limiterFactory(time.Duration(override.Interval), override.Limit, "tier_"+name+"_"+method)
+		}
+		tiers[name] = tier
+	}
+	return tiers
+}
+
+// AuthPolicies are keyed by authentication alias.
+type AuthPolicies map[string]*AuthPolicy
+
+func NewAuthPolicies(cfg map[string]AuthPolicyConfig, tiers map[string]*rateLimitTier, groups map[string]*BackendGroup) (AuthPolicies, error) {
+	if len(cfg) == 0 {
+		return nil, nil
+	}
+	policies := make(AuthPolicies, len(cfg))
+	for _, alias := range sortedKeys(cfg) {
+		pc := cfg[alias]
+		policy := &AuthPolicy{
+			Alias:        alias,
+			backendGroup: pc.BackendGroup,
+			headers:      pc.Headers,
+			debugHeader:  pc.DebugHeader,
+		}
+		if pc.RateLimitTier != "" {
+			policy.tier = tiers[pc.RateLimitTier]
+			if policy.tier == nil {
+				return nil, fmt.Errorf("auth policy %s has undefined rate limit tier %s", alias, pc.RateLimitTier)
+			}
+		}
+		if pc.BackendGroup != "" && groups[pc.BackendGroup] == nil {
+			return nil, fmt.Errorf("auth policy %s has undefined backend group %s", alias, pc.BackendGroup)
+		}
+		if len(pc.AllowedMethods) > 0 {
+			policy.allowedMethods = make(map[string]bool, len(pc.AllowedMethods))
+			for _, method := range pc.AllowedMethods {
+				policy.allowedMethods[method] = true
+			}
+		}
+		policies[alias] = policy
+	}
+	return policies, nil
+}
+
+// Route returns the backend group serving method for the policy's alias,
+// given the group it's mapped to otherwise. An empty group means the method
+// isn't allowed.
+func (p *AuthPolicy) Route(method, group string) string {
+	if p == nil {
+		return group
+	}
+	if p.allowedMethods != nil && !p.allowedMethods[method] {
+		return ""
+	}
+	// explicitly allowed methods don't need a mapping of their own
+	if p.backendGroup != "" && (group != "" || p.allowedMethods != nil) {
+		return p.backendGroup
+	}
+	return group
+}
+
+// wsMethodWhitelist narrows the websocket method whitelist to the allowed
+// methods.
+func (p *AuthPolicy) wsMethodWhitelist(whitelist *StringSet) *StringSet {
+	if p == nil || p.allowedMethods == nil {
+		return whitelist
+	}
+	allowed := NewStringSet()
+	for method := range p.allowedMethods {
+		if whitelist.Has(method) {
+			allowed.Add(method)
+		}
+	}
+	return allowed
+}
+
+// setHeaders sets the policy's headers on a request forwarded to a backend.
+func (p *AuthPolicy) setHeaders(header http.Header) {
+	if p == nil {
+		return
+	}
+	for name, value := range p.headers {
+		header.Set(name, value)
+	}
+}
+
+// debugHeaderEnabled returns whether the alias opted into the X-Proxyd-Debug
+// response header.
+func (p *AuthPolicy) debugHeaderEnabled() bool {
+	return p != nil && p.debugHeader
+}
+
+func WithAuthPolicy(ctx context.Context, policy *AuthPolicy) context.Context {
+	return context.WithValue(ctx, ContextKeyAuthPolicy, policy) // nolint:staticcheck
+}
+
+func GetAuthPolicy(ctx context.Context) *AuthPolicy {
+	policy, _ := ctx.Value(ContextKeyAuthPolicy).(*AuthPolicy)
+	return policy
+}
@@ -0,0 +1,36 @@
+package proxyd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthPolicyRoute(t *testing.T) {
+	var noPolicy *AuthPolicy
+	require.Equal(t, "main", noPolicy.Route("eth_call", "main"))
+
+	override := &AuthPolicy{backendGroup: "partner"}
+	require.Equal(t, "partner", override.Route("eth_call", "main"))
+	require.Equal(t, "", override.Route("eth_sign", ""))
+
+	restricted := &AuthPolicy{allowedMethods: map[string]bool{"eth_call": true, "eth_getBalance": true}}
+	require.Equal(t, "main", restricted.Route("eth_call", "main"))
+	require.Equal(t, "", restricted.Route("eth_getBalance", ""))
+	require.Equal(t, "", restricted.Route("eth_blockNumber", "main"))
+
+	restricted.backendGroup = "partner"
+	require.Equal(t, "partner", restricted.Route("eth_getBalance", ""))
+	require.Equal(t, "", restricted.Route("eth_blockNumber", "main"))
+}
+
+func TestAuthPolicyWSMethodWhitelist(t *testing.T) {
+	whitelist := NewStringSetFromStrings([]string{"eth_call", "eth_subscribe"})
+	require.Equal(t, whitelist, (*AuthPolicy)(nil).wsMethodWhitelist(whitelist))
+
+	policy := &AuthPolicy{allowedMethods: map[string]bool{"eth_call": true, "eth_getBalance": true}}
+	allowed := policy.wsMethodWhitelist(whitelist)
+	require.True(t, allowed.Has("eth_call"))
+	require.False(t, allowed.Has("eth_subscribe"))
+	require.False(t, allowed.Has("eth_getBalance"))
+}
@@ -15,6 +15,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	sw "github.com/ethereum-optimism/infra/proxyd/pkg/avg-sliding-window"
@@ -135,6 +136,101 @@ var (
 		Message:       "invalid flashbots signature",
 		HTTPErrorCode: 403,
 	}
+	ErrPluginFailed = &RPCErr{
+		Code:          JSONRPCErrorInternal - 26,
+		Message:       "plugin error",
+		HTTPErrorCode: 500,
+	}
+	ErrPolicyDenied = &RPCErr{
+		Code:          JSONRPCErrorInternal - 27,
+		Message:       "request denied by policy",
+		HTTPErrorCode: 403,
+	}
+	ErrPolicyUnavailable = &RPCErr{
+		Code:          JSONRPCErrorInternal - 28,
+		Message:       "policy service unavailable",
+		HTTPErrorCode: 503,
+	}
+	ErrTooManyBlobs = &RPCErr{
+		Code:          JSONRPCErrorInternal - 29,
+		Message:       "too many blobs in transaction",
+		HTTPErrorCode: 400,
+	}
+	ErrBlobFeeCapTooHigh = &RPCErr{
+		Code:          JSONRPCErrorInternal - 30,
+		Message:       "blob fee cap too high",
+		HTTPErrorCode: 400,
+	}
+	// ErrFilterNotFound is the error geth returns for unknown filters.
+	ErrFilterNotFound = &RPCErr{
+		Code:    JSONRPCErrorInternal,
+		Message: "filter not found",
+	}
+	ErrTooManyFilters = &RPCErr{
+		Code:          JSONRPCErrorInternal - 33,
+		Message:       "too many filters installed",
+		HTTPErrorCode: 429,
+	}
+	ErrTooManyPendingTxs = &RPCErr{
+		Code:          JSONRPCErrorInternal - 32,
+		Message:       "sender has too many pending transactions",
+		HTTPErrorCode: 429,
+	}
+	ErrOverloaded = &RPCErr{
+		Code:          JSONRPCErrorInternal - 34,
+		Message:       "server is overloaded, try again later",
+		HTTPErrorCode: 503,
+	}
+	ErrHMACSignature = &RPCErr{
+		Code:          JSONRPCErrorInternal - 35,
+		Message:       "invalid hmac signature",
+		HTTPErrorCode: 401,
+	}
+	ErrRequestBodyTooSlow = &RPCErr{
+		Code:          JSONRPCErrorInternal - 36,
+		Message:       "request body sent too slowly",
+		HTTPErrorCode: 408,
+	}
+	ErrMaintenance = &RPCErr{
+		Code:          JSONRPCErrorInternal - 37,
+		Message:       "service under planned maintenance",
+		HTTPErrorCode: 503,
+	}
+	ErrRollupNoConsensus = &RPCErr{
+		Code:          JSONRPCErrorInternal - 38,
+		Message:       "rollup nodes disagree",
+		HTTPErrorCode: 503,
+	}
+	ErrTxPreferencesUnauthenticated = &RPCErr{
+		Code:          JSONRPCErrorInternal - 39,
+		Message:       "tx preferences require an api key or a signed request",
+		HTTPErrorCode: 401,
+	}
+	ErrExpiredSignature = &RPCErr{
+		Code:          JSONRPCErrorInternal - 40,
+		Message:       "signature expired",
+		HTTPErrorCode: 401,
+	}
+	ErrReplayedSignature = &RPCErr{
+		Code:          JSONRPCErrorInternal - 41,
+		Message:       "signature already used",
+		HTTPErrorCode: 401,
+	}
+	ErrRedisUnavailable = &RPCErr{
+		Code:          JSONRPCErrorInternal - 42,
+		Message:       "service temporarily unavailable",
+		HTTPErrorCode: 503,
+	}
+	ErrCallNotAllowed = &RPCErr{
+		Code:          JSONRPCErrorInternal - 43,
+		Message:       "call target not allowed",
+		HTTPErrorCode: 403,
+	}
+	ErrMethodRemoved = &RPCErr{
+		Code:          JSONRPCErrorInternal - 44,
+		Message:       "rpc method has been removed",
+		HTTPErrorCode: 410,
+	}
 
 	ErrBackendUnexpectedJSONRPC = errors.New("backend returned an unexpected JSON-RPC response")
 
@@ -292,24 +388,33 @@ func ErrInvalidParams(msg string) *RPCErr {
 	}
 }
 
+func ErrTxFeeOutOfRange(msg string) *RPCErr {
+	return &RPCErr{
+		Code:          JSONRPCErrorInternal - 31,
+		Message:       msg,
+		HTTPErrorCode: 400,
+	}
+}
+
 type Backend struct {
-	Name                  string
-	rpcURL                string
-	receiptsTarget        string
-	wsURL                 string
-	authUsername          string
-	authPassword          string
-	headers               map[string]string
-	client                *LimitedHTTPClient
-	dialer                *websocket.Dialer
-	maxRetries            int
-	maxResponseSize       int64
-	maxRPS                int
-	maxWSConns            int
-	outOfServiceInterval  time.Duration
-	stripTrailingXFF      bool
-	forwardRequestHeaders []string
-	proxydIP              string
+	Name                 string
+	rpcURL               string
+	receiptsTarget       string
+	wsURL                string
+	authUsername         string
+	authPassword         string
+	headers              map[string]string
+	authMu               sync.RWMutex // guards the URLs, authPassword and headers
+	client               *LimitedHTTPClient
+	dialer               *websocket.Dialer
+	maxRetries           int
+	maxResponseSize      int64
+	maxRPS               int
+	maxWSConns           int
+	outOfServiceInterval time.Duration
+	stripTrailingXFF     bool
+	headerPolicy         *HeaderPolicy
+	proxydIP             string
 
 	skipIsSyncingCheck bool
 	skipPeerCountCheck bool
@@ -324,8 +429,20 @@ type Backend struct {
 	latencySlidingWindow            *sw.AvgSlidingWindow
 	networkRequestsSlidingWindow    *sw.AvgSlidingWindow
 	intermittentErrorsSlidingWindow *sw.AvgSlidingWindow
+	rateLimitedSlidingWindow        *sw.AvgSlidingWindow
+
+	maintenance    *backendMaintenance
+	methodTimeouts *MethodTimeouts
+	wsCompression  WSCompressionConfig
 
-	weight int
+	clientFlavor   ClientFlavor
+	leaderCheck    *leaderCheck
+	derivation     *derivationState
+	peerReport     peerReport
+	capabilitiesMu sync.RWMutex
+	capabilities   *backendCapabilities
+
+	weight atomic.Int64
 }
 
 type BackendOpt func(b *Backend)
@@ -343,12 +460,53 @@ func WithHeaders(headers map[string]string) BackendOpt {
 	}
 }
 
+// SetAuthPassword replaces the basic auth password, e.g. after its secret is
+// refreshed.
+func (b *Backend) SetAuthPassword(password string) {
+	b.authMu.Lock()
+	b.authPassword = password
+	b.authMu.Unlock()
+}
+
+// SetRPCURL replaces the HTTP URL, e.g. after its secret is refreshed.
+func (b *Backend) SetRPCURL(rpcURL string) {
+	b.authMu.Lock()
+	b.rpcURL = rpcURL
+	b.authMu.Unlock()
+}
+
+// SetWSURL replaces the websocket URL, which new connections are dialed to.
+func (b *Backend) SetWSURL(wsURL string) {
+	b.authMu.Lock()
+	b.wsURL = wsURL
+	b.authMu.Unlock()
+}
+
+// SetHeader replaces the value of a header sent with every request.
+func (b *Backend) SetHeader(name, value string) {
+	b.authMu.Lock()
+	defer b.authMu.Unlock()
+	headers := make(map[string]string, len(b.headers)+1)
+	for k, v := range b.headers {
+		headers[k] = v
+	}
+	headers[name] = value
+	b.headers = headers
+}
+
 func WithTimeout(timeout time.Duration) BackendOpt {
 	return func(b *Backend) {
 		b.client.Timeout = timeout
 	}
 }
 
+// WithMethodTimeouts overrides the response timeout for some methods.
+func WithMethodTimeouts(timeouts *MethodTimeouts) BackendOpt {
+	return func(b *Backend) {
+		b.methodTimeouts = timeouts
+	}
+}
+
 func WithMaxRetries(retries int) BackendOpt {
 	return func(b *Backend) {
 		b.maxRetries = retries
@@ -384,7 +542,51 @@ func WithTLSConfig(tlsConfig *tls.Config) BackendOpt {
 		if b.client.Transport == nil {
 			b.client.Transport = &http.Transport{}
 		}
-		b.client.Transport.(*http.Transport).TLSClientConfig = tlsConfig
+		transport, ok := b.client.Transport.(*http.Transport)
+		if !ok {
+			// IPC backends don't speak TLS, checkConfig rejects the combination
+			log.Warn("ignoring TLS config of backend without an HTTP transport", "name", b.Name)
+			return
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+}
+
+// WithIPCPath routes requests over a Unix domain socket or named pipe
+// instead of HTTP.
+func WithIPCPath(path string) BackendOpt {
+	return func(b *Backend) {
+		b.client.Transport = newIPCTransport(path)
+	}
+}
+
+// WithFaultInjector injects f's faults into the backend's HTTP requests. It
+// must come after the options setting up the transport.
+func WithFaultInjector(f *FaultInjector) BackendOpt {
+	return func(b *Backend) {
+		next := b.client.Transport
+		if next == nil {
+			next = http.DefaultTransport
+		}
+		b.client.Transport = &faultTransport{next: next, backend: b.Name, faults: f}
+	}
+}
+
+// WithCassette records the backend's HTTP responses to c or replays them. It
+// must come after the options setting up the transport.
+func WithCassette(c *Cassette) BackendOpt {
+	return func(b *Backend) {
+		next := b.client.Transport
+		if next == nil {
+			next = http.DefaultTransport
+		}
+		b.client.Transport = &cassetteTransport{next: next, backend: b.Name, cassette: c}
+	}
+}
+
+func WithHeaderPolicy(policy *HeaderPolicy) BackendOpt {
+	return func(b *Backend) {
+		b.headerPolicy = policy
 	}
 }
 
@@ -394,6 +596,15 @@ func WithStrippedTrailingXFF() BackendOpt {
 	}
 }
 
+// WithWSCompression negotiates permessage-deflate with the backend if
+// cfg.Upstream is set, and compresses messages to the client if it did.
+func WithWSCompression(cfg WSCompressionConfig) BackendOpt {
+	return func(b *Backend) {
+		b.dialer.EnableCompression = cfg.Upstream
+		b.wsCompression = cfg
+	}
+}
+
 func WithProxydIP(ip string) BackendOpt {
 	return func(b *Backend) {
 		b.proxydIP = ip
@@ -432,10 +643,15 @@ func WithConsensusForcedCandidate(forcedCandidate bool) BackendOpt {
 
 func WithWeight(weight int) BackendOpt {
 	return func(b *Backend) {
-		b.weight = weight
+		b.weight.Store(int64(weight))
 	}
 }
 
+// SetWeight changes the weight of a running backend.
+func (b *Backend) SetWeight(weight int) {
+	b.weight.Store(int64(weight))
+}
+
 func WithMaxDegradedLatencyThreshold(maxDegradedLatencyThreshold time.Duration) BackendOpt {
 	return func(b *Backend) {
 		b.maxDegradedLatencyThreshold = maxDegradedLatencyThreshold
@@ -501,6 +717,9 @@ func NewBackend(
 	rpcSemaphore *semaphore.Weighted,
 	opts ...BackendOpt,
 ) *Backend {
+	// the zero config only forwards the default headers and can't fail
+	headerPolicy, _ := NewHeaderPolicy(HeaderPolicyConfig{})
+
 	backend := &Backend{
 		Name:            name,
 		rpcURL:          rpcURL,
@@ -511,7 +730,8 @@ func NewBackend(
 			sem:         rpcSemaphore,
 			backendName: name,
 		},
-		dialer: &websocket.Dialer{},
+		dialer:       &websocket.Dialer{},
+		headerPolicy: headerPolicy,
 
 		maxLatencyThreshold:         10 * time.Second,
 		maxDegradedLatencyThreshold: 5 * time.Second,
@@ -520,6 +740,7 @@ func NewBackend(
 		latencySlidingWindow:            sw.NewSlidingWindow(),
 		networkRequestsSlidingWindow:    sw.NewSlidingWindow(),
 		intermittentErrorsSlidingWindow: sw.NewSlidingWindow(),
+		rateLimitedSlidingWindow:        sw.NewSlidingWindow(),
 	}
 
 	backend.Override(opts...)
@@ -543,6 +764,7 @@ func (b *Backend) Forward(ctx context.Context, reqs []*RPCReq, isBatch bool) ([]
 	// a retry
 	for i := 0; i <= b.maxRetries; i++ {
 		RecordBatchRPCForward(ctx, b.Name, reqs, RPCRequestSourceHTTP)
+		countBackendAttempt(ctx)
 		metricLabelMethod := reqs[0].Method
 		if isBatch {
 			metricLabelMethod = "<batch>"
@@ -563,7 +785,9 @@ func (b *Backend) Forward(ctx context.Context, reqs []*RPCReq, isBatch bool) ([]
 			"max_attempts", b.maxRetries+1,
 			"method", metricLabelMethod,
 		)
+		start := time.Now()
 		res, err := b.doForward(ctx, reqs, isBatch)
+		addStageTime(ctx, requestStageBackend, start)
 		switch err {
 		case nil: // do nothing
 		case ErrBackendResponseTooLarge:
@@ -630,6 +854,7 @@ func (b *Backend) Forward(ctx context.Context, reqs []*RPCReq, isBatch bool) ([]
 		}
 		timer.ObserveDuration()
 
+		normalizeResponses(b.clientFlavor, reqs, res)
 		MaybeRecordErrorsInRPCRes(ctx, b.Name, reqs, res)
 		return res, err
 	}
@@ -638,15 +863,29 @@ func (b *Backend) Forward(ctx context.Context, reqs []*RPCReq, isBatch bool) ([]
 }
 
 func (b *Backend) ProxyWS(clientConn *websocket.Conn, methodWhitelist *StringSet) (*WSProxier, error) {
-	backendConn, _, err := b.dialer.Dial(b.wsURL, nil) // nolint:bodyclose
+	backendConn, err := b.dialWS()
 	if err != nil {
-		return nil, wrapErr(err, "error dialing backend")
+		return nil, err
 	}
 
+	setWSCompressionLevel(clientConn, b.wsCompression)
+	setWSCompressionLevel(backendConn, b.wsCompression)
+
 	activeBackendWsConnsGauge.WithLabelValues(b.Name).Inc()
 	return NewWSProxier(b, clientConn, backendConn, methodWhitelist), nil
 }
 
+func (b *Backend) dialWS() (*websocket.Conn, error) {
+	b.authMu.RLock()
+	wsURL := b.wsURL
+	b.authMu.RUnlock()
+	conn, _, err := b.dialer.Dial(wsURL, nil) // nolint:bodyclose
+	if err != nil {
+		return nil, wrapErr(err, "error dialing backend")
+	}
+	return conn, nil
+}
+
 // ForwardRPC makes a call directly to a backend and populate the response into `res`
 func (b *Backend) ForwardRPC(ctx context.Context, res *RPCRes, id string, method string, params ...any) error {
 	jsonParams, err := json.Marshal(params)
@@ -678,15 +917,32 @@ func (b *Backend) ForwardRPC(ctx context.Context, res *RPCRes, id string, method
 }
 
 // buildBackendURL constructs the backend URL for forwarding requests.
-// Only eth_sendRawTransaction uses URL parameters for MEV protection configuration.
-// TODO: Remove when API gateway handles protocol translation at the boundary.
+// The client's path and query are only carried over for methods with a URL
+// rewrite, either from the backend group's rewrite rules or the defaults.
+// Both are keyed on the method the client asked for, before any rename.
 func buildBackendURL(baseURL string, rpcReqs []*RPCReq, ctx context.Context) string {
 	backendURL := baseURL
+	if len(rpcReqs) == 0 {
+		return backendURL
+	}
 
-	if len(rpcReqs) > 0 && rpcReqs[0].Method == "eth_sendRawTransaction" {
+	method := rpcReqs[0].Method
+	if rpcReqs[0].renamedFrom != "" {
+		method = rpcReqs[0].renamedFrom
+	}
+	rw := GetRequestRewriter(ctx).urlRewrite(ctx, method)
+	if rw == nil {
+		return backendURL
+	}
+
+	if rw.path != "" {
+		backendURL = strings.TrimSuffix(baseURL, "/") + rw.path
+	} else if rw.forwardPath {
 		if path, ok := ctx.Value(ContextKeyPath).(string); ok && path != "" && path != "/" {
 			backendURL = strings.TrimSuffix(baseURL, "/") + path
 		}
+	}
+	if rw.forwardQuery {
 		if rawQuery, ok := ctx.Value(ContextKeyRawQuery).(string); ok && rawQuery != "" {
 			backendURL += "?" + rawQuery
 		}
@@ -764,7 +1020,10 @@ func (b *Backend) doForward(ctx context.Context, rpcReqs []*RPCReq, isBatch bool
 	}
 
 	// Build backend URL
-	backendURL := buildBackendURL(b.rpcURL, rpcReqs, ctx)
+	b.authMu.RLock()
+	rpcURL := b.rpcURL
+	b.authMu.RUnlock()
+	backendURL := buildBackendURL(rpcURL, rpcReqs, ctx)
 
 	httpReq, err := http.NewRequestWithContext(ctx, "POST", backendURL, bytes.NewReader(body))
 	if err != nil {
@@ -774,25 +1033,16 @@ func (b *Backend) doForward(ctx context.Context, rpcReqs []*RPCReq, isBatch bool
 	}
 
 	headersToForward := GetHeadersToForward(ctx)
-	if len(headersToForward) != 0 {
-		for _, header := range b.forwardRequestHeaders {
-			values, ok := headersToForward[header]
-			if !ok {
-				continue
-			}
-			for _, value := range values {
-				httpReq.Header.Add(header, value)
-			}
-		}
+	if len(headersToForward) != 0 && b.headerPolicy != nil {
+		b.headerPolicy.Apply(headersToForward, httpReq.Header)
 	}
+	GetAuthPolicy(ctx).setHeaders(httpReq.Header)
 
-	if b.authPassword != "" {
-		httpReq.SetBasicAuth(b.authUsername, b.authPassword)
-	}
-
-	opTxProxyAuth := GetOpTxProxyAuthHeader(ctx)
-	if opTxProxyAuth != "" {
-		httpReq.Header.Set(DefaultOpTxProxyAuthHeader, opTxProxyAuth)
+	b.authMu.RLock()
+	authPassword, headers := b.authPassword, b.headers
+	b.authMu.RUnlock()
+	if authPassword != "" {
+		httpReq.SetBasicAuth(b.authUsername, authPassword)
 	}
 
 	xForwardedFor := GetXForwardedFor(ctx)
@@ -805,12 +1055,30 @@ func (b *Backend) doForward(ctx context.Context, rpcReqs []*RPCReq, isBatch bool
 	httpReq.Header.Set("content-type", "application/json")
 	httpReq.Header.Set("X-Forwarded-For", xForwardedFor)
 
-	for name, value := range b.headers {
+	for name, value := range headers {
 		httpReq.Header.Set(name, value)
 	}
 
+	client := b.client
+	if b.methodTimeouts != nil {
+		methods := make([]string, len(rpcReqs))
+		for i, req := range rpcReqs {
+			methods[i] = req.Method
+		}
+		if timeout := b.methodTimeouts.Timeout(client.Timeout, methods...); timeout != client.Timeout {
+			client = &LimitedHTTPClient{
+				Client:      client.Client,
+				sem:         client.sem,
+				backendName: client.backendName,
+			}
+			client.Timeout = timeout
+		}
+	}
+
+	setRequestTimeout(ctx, httpReq.Header, client.Timeout)
+
 	start := time.Now()
-	httpRes, err := b.client.DoLimited(httpReq)
+	httpRes, err := client.DoLimited(httpReq)
 	if err != nil {
 		if !(errors.Is(err, context.Canceled) || errors.Is(err, ErrTooManyRequests)) {
 			b.intermittentErrorsSlidingWindow.Incr()
@@ -819,6 +1087,9 @@ func (b *Backend) doForward(ctx context.Context, rpcReqs []*RPCReq, isBatch bool
 		if errors.Is(err, ErrContextCanceled) {
 			return nil, err
 		}
+		if errors.Is(err, context.Canceled) {
+			return nil, ErrContextCanceled
+		}
 		return nil, wrapErr(err, "error in backend request")
 	}
 
@@ -834,6 +1105,10 @@ func (b *Backend) doForward(ctx context.Context, rpcReqs []*RPCReq, isBatch bool
 		strconv.FormatBool(isBatch),
 	).Inc()
 
+	if httpRes.StatusCode == http.StatusTooManyRequests {
+		b.rateLimitedSlidingWindow.Incr()
+	}
+
 	// Alchemy returns a 400 on bad JSONs, so handle that case
 	if httpRes.StatusCode != 200 && httpRes.StatusCode != 400 {
 		b.intermittentErrorsSlidingWindow.Incr()
@@ -932,6 +1207,14 @@ func (b *Backend) ErrorRate() (errorRate float64) {
 	return errorRate
 }
 
+// RateLimitRate returns the share of requests the backend answered with a 429
+func (b *Backend) RateLimitRate() (rate float64) {
+	if b.networkRequestsSlidingWindow.Sum() >= 10 {
+		rate = b.rateLimitedSlidingWindow.Sum() / b.networkRequestsSlidingWindow.Sum()
+	}
+	return rate
+}
+
 // IsDegraded checks if the backend is serving traffic in a degraded state (i.e. used as a last resource)
 func (b *Backend) IsDegraded() bool {
 	avgLatency := time.Duration(b.latencySlidingWindow.Avg())
@@ -969,15 +1252,40 @@ type BackendGroup struct {
 	FallbackBackends       map[string]bool
 	routingStrategy        RoutingStrategy
 	multicallRPCErrorCheck bool
+	receiptAggregation     bool
+	blockReceipts          *BlockReceipts
+	rollupConsensus        bool
+	inflight               *InflightLimiter
+	requestRewriter        *RequestRewriter
+	responseRewriter       *ResponseRewriter
+	heads                  *HeadTracker
+
+	// backendsMu guards Backends once discovery may replace them.
+	backendsMu sync.RWMutex
 }
 
 func (bg *BackendGroup) GetRoutingStrategy() RoutingStrategy {
 	return bg.routingStrategy
 }
 
+// members returns the current backends of the group. The returned slice must
+// not be modified.
+func (bg *BackendGroup) members() []*Backend {
+	bg.backendsMu.RLock()
+	defer bg.backendsMu.RUnlock()
+	return bg.Backends
+}
+
+// SetBackends replaces the backends of a running group.
+func (bg *BackendGroup) SetBackends(backends []*Backend) {
+	bg.backendsMu.Lock()
+	bg.Backends = backends
+	bg.backendsMu.Unlock()
+}
+
 func (bg *BackendGroup) Fallbacks() []*Backend {
 	fallbacks := []*Backend{}
-	for _, a := range bg.Backends {
+	for _, a := range bg.members() {
 		if fallback, ok := bg.FallbackBackends[a.Name]; ok && fallback {
 			fallbacks = append(fallbacks, a)
 		}
@@ -987,9 +1295,9 @@ func (bg *BackendGroup) Fallbacks() []*Backend {
 
 func (bg *BackendGroup) Primaries() []*Backend {
 	primaries := []*Backend{}
-	for _, a := range bg.Backends {
-		fallback, ok := bg.FallbackBackends[a.Name]
-		if ok && !fallback {
+	for _, a := range bg.members() {
+		// discovered backends aren't in FallbackBackends and are primaries
+		if !bg.FallbackBackends[a.Name] {
 			primaries = append(primaries, a)
 		}
 	}
@@ -1002,7 +1310,34 @@ func (bg *BackendGroup) Forward(ctx context.Context, rpcReqs []*RPCReq, isBatch
 		return nil, "", nil
 	}
 
+	release, err := bg.inflight.Acquire(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	defer release()
+
 	backends := bg.orderedBackendsForRequest()
+	backends = supportingBackends(backends, rpcReqs)
+	backends = leaderBackends(backends, rpcReqs)
+	backends = derivationOrderedBackends(backends, rpcReqs)
+	backends = peerOrderedBackends(backends)
+	backends = bg.heads.Order(backends)
+	if preferred := GetPreferredBackend(ctx); preferred != "" {
+		backends = preferBackend(backends, preferred)
+	}
+
+	// response rewrites are keyed on the methods the client asked for
+	clientReqs := rpcReqs
+
+	if bg.requestRewriter != nil {
+		rpcReqs = bg.requestRewriter.RewriteRequests(ctx, rpcReqs)
+		ctx = context.WithValue(ctx, ContextKeyRequestRewriter, bg.requestRewriter) // nolint:staticcheck
+	}
+
+	// receipts served from eth_getBlockReceipts are indexed before consensus
+	// overrides, and are re-applied after them
+	var receiptResponses []*indexedReqRes
+	rpcReqs, receiptResponses = bg.blockReceipts.Batch(ctx, bg, backends, rpcReqs)
 
 	overriddenResponses := make([]*indexedReqRes, 0)
 	rewrittenReqs := make([]*RPCReq, 0, len(rpcReqs))
@@ -1016,10 +1351,21 @@ func (bg *BackendGroup) Forward(ctx context.Context, rpcReqs []*RPCReq, isBatch
 
 	rpcRequestsTotal.Inc()
 
+	if bg.rollupConsensus && !isBatch && len(rpcReqs) == 1 && isRollupConsensusMethod(rpcReqs[0].Method) {
+		res, servedBy, err := bg.forwardRollupConsensus(ctx, rpcReqs[0], backends)
+		if err == nil {
+			bg.responseRewriter.RewriteResponses(clientReqs, res, servedBy)
+		}
+		return res, servedBy, err
+	}
+
 	// When routing_strategy is set to 'multicall' the request will be forward to all backends
 	// and return the first successful response
 	if bg.GetRoutingStrategy() == MulticallRoutingStrategy && isValidMulticallTx(rpcReqs) && !isBatch {
 		backendResp := bg.ExecuteMulticall(ctx, rpcReqs)
+		if backendResp.error == nil {
+			bg.responseRewriter.RewriteResponses(clientReqs, backendResp.RPCRes, backendResp.ServedBy)
+		}
 		return backendResp.RPCRes, backendResp.ServedBy, backendResp.error
 	}
 
@@ -1040,15 +1386,72 @@ func (bg *BackendGroup) Forward(ctx context.Context, rpcReqs []*RPCReq, isBatch
 		return backendResp.RPCRes, backendResp.ServedBy, backendResp.error
 	}
 
+	if bg.receiptAggregation {
+		bg.aggregateReceipts(ctx, rpcReqs, backendResp.RPCRes, backends, backendResp.ServedBy)
+	}
+
 	// re-apply overridden responses
 	log.Trace("successfully served request overriding responses",
 		"req_id", GetReqID(ctx),
 		"auth", GetAuthCtx(ctx),
 	)
 	res := OverrideResponses(backendResp.RPCRes, overriddenResponses)
+	res = OverrideResponses(res, receiptResponses)
+	bg.blockReceipts.Observe(clientReqs, res)
+	bg.responseRewriter.RewriteResponses(clientReqs, res, backendResp.ServedBy)
 	return res, backendResp.ServedBy, backendResp.error
 }
 
+// aggregateReceipts asks the other backends for the receipts the serving
+// backend doesn't have yet, in order, and fills in the first ones found.
+// Backends receive transactions and blocks at different times, so a receipt
+// missing from one backend may be on another.
+func (bg *BackendGroup) aggregateReceipts(ctx context.Context, rpcReqs []*RPCReq, res []*RPCRes, backends []*Backend, servedBy string) {
+	missing := make(map[string]int)
+	for i, req := range rpcReqs {
+		if req.Method == "eth_getTransactionReceipt" && i < len(res) && !res[i].IsError() && res[i].Result == nil {
+			missing[string(req.ID)] = i
+		}
+	}
+	if len(missing) == 0 {
+		return
+	}
+
+	for _, back := range backends {
+		if fmt.Sprintf("%s/%s", bg.Name, back.Name) == servedBy {
+			continue
+		}
+		reqs := make([]*RPCReq, 0, len(missing))
+		for _, i := range missing {
+			reqs = append(reqs, rpcReqs[i])
+		}
+		backendResp := bg.ForwardRequestToBackendGroup(reqs, []*Backend{back}, ctx, true)
+		if backendResp.error != nil {
+			log.Debug("error aggregating receipts",
+				"name", back.Name,
+				"req_id", GetReqID(ctx),
+				"err", backendResp.error,
+			)
+			continue
+		}
+		for _, r := range backendResp.RPCRes {
+			i, ok := missing[string(r.ID)]
+			if !ok || r.IsError() || r.Result == nil {
+				continue
+			}
+			res[i].Result = r.Result
+			delete(missing, string(r.ID))
+			RecordReceiptAggregation(bg.Name, true)
+		}
+		if len(missing) == 0 {
+			return
+		}
+	}
+	for range missing {
+		RecordReceiptAggregation(bg.Name, false)
+	}
+}
+
 func isValidMulticallTx(rpcReqs []*RPCReq) bool {
 	if len(rpcReqs) == 1 {
 		if rpcReqs[0].Method == "eth_sendRawTransaction" {
@@ -1075,8 +1478,9 @@ func (bg *BackendGroup) ExecuteMulticall(ctx context.Context, rpcReqs []*RPCReq)
 		"auth", GetAuthCtx(bgCtx),
 	)
 	var wg sync.WaitGroup
-	ch := make(chan *multicallTuple, len(bg.Backends))
-	for _, backend := range bg.Backends {
+	backends := bg.members()
+	ch := make(chan *multicallTuple, len(backends))
+	for _, backend := range backends {
 		wg.Add(1)
 		go bg.MulticallRequest(backend, rpcReqs, &wg, bgCtx, ch)
 	}
@@ -1189,7 +1593,10 @@ func (bg *BackendGroup) ProcessMulticallResponses(ch chan *multicallTuple, ctx c
 }
 
 func (bg *BackendGroup) ProxyWS(ctx context.Context, clientConn *websocket.Conn, methodWhitelist *StringSet) (*WSProxier, error) {
-	for _, back := range bg.Backends {
+	for _, back := range bg.members() {
+		if back.InMaintenance() {
+			continue
+		}
 		proxier, err := back.ProxyWS(clientConn, methodWhitelist)
 		if errors.Is(err, ErrBackendOffline) {
 			log.Warn(
@@ -1227,7 +1634,7 @@ func (bg *BackendGroup) ProxyWS(ctx context.Context, clientConn *websocket.Conn,
 
 func weightedShuffle(backends []*Backend) {
 	weight := func(i int) float64 {
-		return float64(backends[i].weight)
+		return float64(backends[i].weight.Load())
 	}
 
 	weightedshuffle.ShuffleInplace(backends, weight, nil)
@@ -1237,9 +1644,13 @@ func (bg *BackendGroup) orderedBackendsForRequest() []*Backend {
 	if bg.Consensus != nil {
 		return bg.loadBalancedConsensusGroup()
 	} else {
-		healthy := make([]*Backend, 0, len(bg.Backends))
-		unhealthy := make([]*Backend, 0, len(bg.Backends))
-		for _, be := range bg.Backends {
+		backends := bg.members()
+		healthy := make([]*Backend, 0, len(backends))
+		unhealthy := make([]*Backend, 0, len(backends))
+		for _, be := range backends {
+			if be.InMaintenance() {
+				continue
+			}
 			if be.IsHealthy() {
 				healthy = append(healthy, be)
 			} else {
@@ -1262,7 +1673,7 @@ func (bg *BackendGroup) loadBalancedConsensusGroup() []*Backend {
 	// separate into healthy, degraded and unhealthy backends
 	for _, be := range cg {
 		// unhealthy are filtered out and not attempted
-		if !be.IsHealthy() {
+		if !be.IsHealthy() || be.InMaintenance() {
 			continue
 		}
 		if be.IsDegraded() {
@@ -1296,6 +1707,7 @@ func (bg *BackendGroup) Shutdown() {
 	if bg.Consensus != nil {
 		bg.Consensus.Shutdown()
 	}
+	bg.heads.Stop()
 }
 
 func calcBackoff(i int) time.Duration {
@@ -1313,6 +1725,32 @@ type WSProxier struct {
 	methodWhitelist *StringSet
 	readTimeout     time.Duration
 	writeTimeout    time.Duration
+	// messages smaller than this are written uncompressed
+	compressionThreshold int
+	// codec, if set, encodes the client's messages
+	codec *binaryCodec
+	// sendQueue, if set, buffers the backend's messages for the client
+	sendQueue *wsSendQueue
+	// batching, if set, coalesces notifications for the client
+	batching      *WSNotificationBatchingConfig
+	notifications *wsNotificationBatcher
+	// replay, if set, records and replays subscription notifications
+	replay *WSReplay
+	// deprecations answers removed methods with ErrMethodRemoved
+	deprecations *MethodDeprecations
+
+	replayMu sync.Mutex
+	// pendingReplays are the cursors of eth_subscribe requests by request ID
+	pendingReplays map[string]wsPendingReplay
+	// replaySubs are the WSReplay keys and params of subscriptions by
+	// subscription ID
+	replaySubs map[string]wsPendingReplay
+}
+
+type wsPendingReplay struct {
+	key    string
+	params json.RawMessage
+	cursor *WSReplayCursor
 }
 
 func NewWSProxier(backend *Backend, clientConn, backendConn *websocket.Conn, methodWhitelist *StringSet) *WSProxier {
@@ -1323,18 +1761,54 @@ func NewWSProxier(backend *Backend, clientConn, backendConn *websocket.Conn, met
 		methodWhitelist: methodWhitelist,
 		readTimeout:     defaultWSReadTimeout,
 		writeTimeout:    defaultWSWriteTimeout,
+
+		compressionThreshold: backend.wsCompression.threshold(),
 	}
 }
 
 func (w *WSProxier) Proxy(ctx context.Context) error {
-	errC := make(chan error, 2)
+	errC := make(chan error, 3)
+	done := make(chan struct{})
 	go w.clientPump(ctx, errC)
-	go w.backendPump(ctx, errC)
+	go w.backendPump(ctx, errC, done)
+	if w.sendQueue != nil {
+		go w.sendPump(errC, done)
+	}
+	if w.batching != nil {
+		w.notifications = newWSNotificationBatcher(*w.batching, func(msgType int, msg []byte) error {
+			return w.deliverClient(ctx, done, msgType, msg, true)
+		}, func(err error) {
+			select {
+			case errC <- err:
+			default:
+			}
+		})
+	}
 	err := <-errC
+	close(done)
+	if w.notifications != nil {
+		w.notifications.close()
+	}
 	w.close()
+	w.leaveReplays()
 	return err
 }
 
+// sendPump writes the queued backend messages to the client.
+func (w *WSProxier) sendPump(errC chan error, done chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		case m := <-w.sendQueue.msgs:
+			if err := w.writeClientConn(m.msgType, m.data); err != nil {
+				errC <- err
+				return
+			}
+		}
+	}
+}
+
 func (w *WSProxier) clientPump(ctx context.Context, errC chan error) {
 	for {
 		// Block until we get a message.
@@ -1362,15 +1836,27 @@ func (w *WSProxier) clientPump(ctx context.Context, errC chan error) {
 
 		rpcRequestsTotal.Inc()
 
+		if w.codec != nil {
+			if msg, err = w.codec.ToJSON(msg); err != nil {
+				log.Info("error transcoding client message", "encoding", w.codec.name, "req_id", GetReqID(ctx), "err", err)
+				RecordRPCError(ctx, BackendProxyd, MethodUnknown, ErrParseErr)
+				RecordRejectedRequest(RejectReasonBadJSON)
+				if err := w.writeClientConn(msgType, mustMarshalJSON(NewRPCErrorRes(nil, ErrParseErr))); err != nil {
+					errC <- err
+					return
+				}
+				continue
+			}
+			msgType = websocket.TextMessage
+		}
+
 		// Don't bother sending invalid requests to the backend,
 		// just handle them here.
 		req, err := w.prepareClientMsg(msg)
 		if err != nil {
 			var id json.RawMessage
-			method := MethodUnknown
 			if req != nil {
 				id = req.ID
-				method = req.Method
 			}
 			log.Info(
 				"error preparing client message",
@@ -1379,7 +1865,13 @@ func (w *WSProxier) clientPump(ctx context.Context, errC chan error) {
 				"err", err,
 			)
 			msg = mustMarshalJSON(NewRPCErrorRes(id, err))
-			RecordRPCError(ctx, BackendProxyd, method, err)
+			// rejected methods aren't bounded, so they'd make unbounded labels
+			RecordRPCError(ctx, BackendProxyd, MethodUnknown, err)
+			if errors.Is(err, ErrMethodNotWhitelisted) {
+				RecordRejectedRequest(RejectReasonMethodBlocked)
+			} else {
+				RecordRejectedRequest(RejectReasonBadJSON)
+			}
 
 			// Send error response to client
 			err = w.writeClientConn(msgType, msg)
@@ -1390,6 +1882,16 @@ func (w *WSProxier) clientPump(ctx context.Context, errC chan error) {
 			continue
 		}
 
+		if err := w.deprecations.Check(ctx, req.Method); err != nil {
+			RecordRPCError(ctx, BackendProxyd, req.Method, err)
+			RecordRejectedRequest(RejectReasonMethodBlocked)
+			if err := w.writeClientConn(msgType, mustMarshalJSON(NewRPCErrorRes(req.ID, err))); err != nil {
+				errC <- err
+				return
+			}
+			continue
+		}
+
 		// Send eth_accounts requests directly to the client
 		if req.Method == "eth_accounts" {
 			msg = mustMarshalJSON(NewRPCRes(req.ID, emptyArrayResponse))
@@ -1402,6 +1904,16 @@ func (w *WSProxier) clientPump(ctx context.Context, errC chan error) {
 			continue
 		}
 
+		if key, cursor, ok := w.replay.Subscribe(req); ok {
+			msg = mustMarshalJSON(req)
+			w.replayMu.Lock()
+			w.pendingReplays[string(req.ID)] = wsPendingReplay{key: key, params: req.Params, cursor: cursor}
+			w.replayMu.Unlock()
+		}
+		if req.Method == "eth_unsubscribe" {
+			w.unsubscribeReplay(req)
+		}
+
 		RecordRPCForward(ctx, w.backend.Name, req.Method, RPCRequestSourceWS)
 		log.Info(
 			"forwarded WS message to backend",
@@ -1418,7 +1930,7 @@ func (w *WSProxier) clientPump(ctx context.Context, errC chan error) {
 	}
 }
 
-func (w *WSProxier) backendPump(ctx context.Context, errC chan error) {
+func (w *WSProxier) backendPump(ctx context.Context, errC chan error, done chan struct{}) {
 	for {
 		// Block until we get a message.
 		msgType, msg, err := w.backendConn.ReadMessage()
@@ -1470,14 +1982,142 @@ func (w *WSProxier) backendPump(ctx context.Context, errC chan error) {
 			}
 		}
 
-		err = w.writeClientConn(msgType, msg)
-		if err != nil {
+		parsed := err == nil
+		notification := parsed && len(res.ID) == 0
+		if notification {
+			w.recordNotification(ctx, msg)
+		}
+
+		if err := w.sendClient(ctx, done, msgType, msg, notification); err != nil {
 			errC <- err
 			return
 		}
+
+		if parsed && !notification {
+			if err := w.replaySubscription(ctx, done, res); err != nil {
+				errC <- err
+				return
+			}
+		}
+	}
+}
+
+// recordNotification keeps a newHeads or logs notification of a subscription
+// made with replay, see WSReplay.
+func (w *WSProxier) recordNotification(ctx context.Context, msg []byte) {
+	if w.replay == nil {
+		return
+	}
+	subID, result, ok := parseWSSubscriptionNotification(msg)
+	if !ok {
+		return
+	}
+	w.replayMu.Lock()
+	sub, ok := w.replaySubs[subID]
+	w.replayMu.Unlock()
+	if ok {
+		w.replay.Record(ctx, sub.key, result)
+	}
+}
+
+// unsubscribeReplay stops tracking a subscription made with replay once the
+// client unsubscribes, see WSReplay.Leave.
+func (w *WSProxier) unsubscribeReplay(req *RPCReq) {
+	if w.replay == nil {
+		return
+	}
+	var params []string
+	if err := json.Unmarshal(req.Params, &params); err != nil || len(params) == 0 {
+		return
+	}
+	w.replayMu.Lock()
+	sub, ok := w.replaySubs[params[0]]
+	delete(w.replaySubs, params[0])
+	w.replayMu.Unlock()
+	if ok {
+		w.replay.Leave(sub.key, w.backend, sub.params)
 	}
 }
 
+// leaveReplays stops tracking the subscriptions made with replay once the
+// client is gone.
+func (w *WSProxier) leaveReplays() {
+	if w.replay == nil {
+		return
+	}
+	w.replayMu.Lock()
+	subs := w.replaySubs
+	w.replaySubs = make(map[string]wsPendingReplay)
+	w.replayMu.Unlock()
+	for _, sub := range subs {
+		w.replay.Leave(sub.key, w.backend, sub.params)
+	}
+}
+
+// replaySubscription sends the notifications the client missed since the
+// cursor of its eth_subscribe request, once the backend has accepted it.
+func (w *WSProxier) replaySubscription(ctx context.Context, done chan struct{}, res *RPCRes) error {
+	if w.replay == nil {
+		return nil
+	}
+	w.replayMu.Lock()
+	pending, ok := w.pendingReplays[string(res.ID)]
+	delete(w.pendingReplays, string(res.ID))
+	subID, isSub := res.Result.(string)
+	if ok && isSub && !res.IsError() {
+		w.replaySubs[subID] = pending
+		w.replay.Join(pending.key)
+	}
+	w.replayMu.Unlock()
+	if !ok || !isSub || res.IsError() || pending.cursor == nil {
+		return nil
+	}
+
+	events, err := w.replay.Replay(ctx, pending.key, pending.cursor, w.backend)
+	if err != nil {
+		log.Error("error getting ws notifications to replay", "req_id", GetReqID(ctx), "err", err)
+		return nil
+	}
+	for _, ev := range events {
+		msg := mustMarshalJSON(map[string]interface{}{
+			"jsonrpc": JSONRPCVersion,
+			"method":  "eth_subscription",
+			"params": map[string]interface{}{
+				"subscription": subID,
+				"result":       ev,
+			},
+		})
+		if err := w.sendClient(ctx, done, websocket.TextMessage, msg, true); err != nil {
+			return err
+		}
+	}
+	RecordWSReplay(w.backend.Name, len(events))
+	return nil
+}
+
+// sendClient writes a backend message to the client, batching notifications
+// if the client opted in.
+func (w *WSProxier) sendClient(ctx context.Context, done chan struct{}, msgType int, msg []byte, notification bool) error {
+	if w.notifications != nil {
+		return w.notifications.send(msgType, msg, notification)
+	}
+	return w.deliverClient(ctx, done, msgType, msg, notification)
+}
+
+// deliverClient writes a message to the client, through the send queue if
+// there is one.
+func (w *WSProxier) deliverClient(ctx context.Context, done chan struct{}, msgType int, msg []byte, notification bool) error {
+	if w.sendQueue == nil {
+		return w.writeClientConn(msgType, msg)
+	}
+	if err := w.sendQueue.push(done, wsMessage{msgType, msg}, notification, w.backend.Name); err != nil {
+		log.Warn("disconnecting slow websocket client", "auth", GetAuthCtx(ctx), "req_id", GetReqID(ctx))
+		_ = w.writeClientConn(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseTryAgainLater, err.Error()))
+		return err
+	}
+	return nil
+}
+
 func (w *WSProxier) close() {
 	w.clientConn.Close()
 	w.backendConn.Close()
@@ -1490,6 +2130,10 @@ func (w *WSProxier) prepareClientMsg(msg []byte) (*RPCReq, error) {
 		return nil, err
 	}
 
+	if !isValidMethodName(req.Method) {
+		return req, ErrInvalidRequest("invalid method name")
+	}
+
 	if !w.methodWhitelist.Has(req.Method) {
 		return req, ErrMethodNotWhitelisted
 	}
@@ -1507,13 +2151,21 @@ func (w *WSProxier) parseBackendMsg(msg []byte) (*RPCRes, error) {
 }
 
 func (w *WSProxier) writeClientConn(msgType int, msg []byte) error {
+	if w.codec != nil && (msgType == websocket.TextMessage || msgType == websocket.BinaryMessage) {
+		var err error
+		if msg, err = w.codec.FromJSON(msg); err != nil {
+			return wrapErr(err, "error transcoding client message")
+		}
+		msgType = websocket.BinaryMessage
+	}
+
 	w.clientConnMu.Lock()
 	defer w.clientConnMu.Unlock()
 	if err := w.clientConn.SetWriteDeadline(time.Now().Add(w.writeTimeout)); err != nil {
 		log.Error("ws client write timeout", "err", err)
 		return err
 	}
-	err := w.clientConn.WriteMessage(msgType, msg)
+	err := writeWSMessage(w.clientConn, msgType, msg, w.compressionThreshold)
 	return err
 }
 
@@ -1524,7 +2176,7 @@ func (w *WSProxier) writeBackendConn(msgType int, msg []byte) error {
 		log.Error("ws backend write timeout", "err", err)
 		return err
 	}
-	err := w.backendConn.WriteMessage(msgType, msg)
+	err := writeWSMessage(w.backendConn, msgType, msg, w.compressionThreshold)
 	return err
 }
 
@@ -1619,6 +2271,7 @@ func RecordBatchRPCForward(ctx context.Context, backendName string, reqs []*RPCR
 func (b *Backend) ClearSlidingWindows() {
 	b.intermittentErrorsSlidingWindow.Clear()
 	b.networkRequestsSlidingWindow.Clear()
+	b.rateLimitedSlidingWindow.Clear()
 }
 
 func stripXFF(xff string) string {
@@ -1638,6 +2291,7 @@ func (bg *BackendGroup) ForwardRequestToBackendGroup(
 	ctx context.Context,
 	isBatch bool,
 ) *BackendGroupRPCResponse {
+	countBackendForward(ctx)
 	for _, back := range backends {
 		res := make([]*RPCRes, 0)
 		var err error
@@ -1646,7 +2300,7 @@ func (bg *BackendGroup) ForwardRequestToBackendGroup(
 
 		if len(rpcReqs) > 0 {
 
-			res, err = back.Forward(ctx, rpcReqs, isBatch)
+			res, err = back.Forward(ctx, bg.heads.Translate(back, rpcReqs), isBatch)
 
 			// below are errors that we explicitly handle so that we don't
 			// mark this request as unserviceable (unserviceable requests
@@ -0,0 +1,186 @@
+package proxyd
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const defaultBackendScoringInterval = 10 * time.Second
+
+// BackendScore is the health of a backend, from 0 for the worst to 1 for
+// the best. It's the average of four penalties, each capped at 1: the error
+// rate relative to max_error_rate_threshold, the average latency relative to
+// max_latency_threshold, the block lag relative to consensus_max_block_lag,
+// and the rate of 429 responses.
+type BackendScore struct {
+	Backend       string  `json:"backend"`
+	Score         float64 `json:"score"`
+	ErrorRate     float64 `json:"error_rate"`
+	LatencyMs     int64   `json:"latency_ms"`
+	BlockLag      uint64  `json:"block_lag"`
+	RateLimitRate float64 `json:"rate_limit_rate"`
+}
+
+// BackendScorer scores the backends of every group on an interval, and
+// orders each group's backends from the best score to the worst. Groups
+// without weighted or consensus aware routing try their backends in that
+// order.
+type BackendScorer struct {
+	groups   map[string]*BackendGroup
+	interval time.Duration
+
+	mu     sync.RWMutex
+	scores map[string][]BackendScore
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+func NewBackendScorer(cfg BackendScoringConfig, groups map[string]*BackendGroup) *BackendScorer {
+	s := &BackendScorer{
+		groups:   groups,
+		interval: time.Duration(cfg.Interval),
+		scores:   make(map[string][]BackendScore),
+		stop:     make(chan struct{}),
+	}
+	if s.interval == 0 {
+		s.interval = defaultBackendScoringInterval
+	}
+	return s
+}
+
+// Start scores the backends until Stop is called.
+func (s *BackendScorer) Start() {
+	if s == nil {
+		return
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				s.update()
+			}
+		}
+	}()
+}
+
+func (s *BackendScorer) Stop() {
+	if s == nil {
+		return
+	}
+	close(s.stop)
+	s.wg.Wait()
+}
+
+func (s *BackendScorer) update() {
+	for name, bg := range s.groups {
+		scores := make(map[string]BackendScore)
+		for _, be := range bg.members() {
+			score := scoreBackend(bg, be)
+			scores[be.Name] = score
+			RecordBackendScore(be, score.Score)
+		}
+		ordered := bg.reorderBackends(func(a, b *Backend) bool {
+			return scores[a.Name].Score > scores[b.Name].Score
+		})
+
+		list := make([]BackendScore, 0, len(ordered))
+		for _, be := range ordered {
+			list = append(list, scores[be.Name])
+		}
+		s.mu.Lock()
+		s.scores[name] = list
+		s.mu.Unlock()
+	}
+}
+
+// Scores returns the scores of each group's backends, in the order the
+// group prefers them.
+func (s *BackendScorer) Scores() map[string][]BackendScore {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	scores := make(map[string][]BackendScore, len(s.scores))
+	for name, list := range s.scores {
+		scores[name] = list
+	}
+	return scores
+}
+
+// ServeHTTP serves the scores as JSON on the admin API.
+func (s *BackendScorer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(s.Scores()); err != nil {
+		log.Error("error writing backend scores", "err", err)
+	}
+}
+
+func scoreBackend(bg *BackendGroup, be *Backend) BackendScore {
+	latency := time.Duration(be.latencySlidingWindow.Avg())
+	score := BackendScore{
+		Backend:       be.Name,
+		ErrorRate:     be.ErrorRate(),
+		LatencyMs:     latency.Milliseconds(),
+		RateLimitRate: be.RateLimitRate(),
+	}
+
+	penalties := []float64{
+		ratio(score.ErrorRate, be.maxErrorRateThreshold),
+		ratio(float64(latency), float64(be.maxLatencyThreshold)),
+		0,
+		math.Min(score.RateLimitRate, 1),
+	}
+	if bg.Consensus != nil {
+		consensus := uint64(bg.Consensus.GetLatestBlockNumber())
+		if latest, _ := bg.Consensus.GetBackendState(be).GetLatestBlock(); uint64(latest) < consensus {
+			score.BlockLag = consensus - uint64(latest)
+		}
+		penalties[2] = ratio(float64(score.BlockLag), float64(bg.Consensus.maxBlockLag))
+	}
+
+	var total float64
+	for _, p := range penalties {
+		total += p
+	}
+	score.Score = 1 - total/float64(len(penalties))
+	return score
+}
+
+// ratio returns v relative to max, capped at 1.
+func ratio(v, max float64) float64 {
+	if max <= 0 {
+		return 0
+	}
+	return math.Min(v/max, 1)
+}
+
+// reorderBackends sorts the backends of the group, keeping the order of
+// equal ones, and returns them.
+func (bg *BackendGroup) reorderBackends(less func(a, b *Backend) bool) []*Backend {
+	bg.backendsMu.Lock()
+	defer bg.backendsMu.Unlock()
+	ordered := make([]*Backend, len(bg.Backends))
+	copy(ordered, bg.Backends)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return less(ordered[i], ordered[j])
+	})
+	for i := range ordered {
+		if ordered[i] != bg.Backends[i] {
+			log.Info("reordered backends by score", "backend_group", bg.Name, "first", ordered[0].Name)
+			bg.Backends = ordered
+			break
+		}
+	}
+	return ordered
+}
@@ -0,0 +1,51 @@
+package proxyd
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackendScorer(t *testing.T) {
+	a := NewBackend("a", "http://a:8545", "", nil)
+	b := NewBackend("b", "http://b:8545", "", nil)
+	c := NewBackend("c", "http://c:8545", "", nil)
+	bg := &BackendGroup{Name: "main", Backends: []*Backend{a, b, c}}
+	scorer := NewBackendScorer(BackendScoringConfig{}, map[string]*BackendGroup{"main": bg})
+
+	for i := 0; i < 10; i++ {
+		for _, be := range []*Backend{a, b, c} {
+			be.networkRequestsSlidingWindow.Incr()
+		}
+		// a fails half of its requests, b is rate limited on some
+		if i%2 == 0 {
+			a.intermittentErrorsSlidingWindow.Incr()
+		}
+		if i%5 == 0 {
+			b.rateLimitedSlidingWindow.Incr()
+		}
+	}
+	c.latencySlidingWindow.Add(float64(time.Second))
+
+	scorer.update()
+	require.Equal(t, []*Backend{c, b, a}, bg.members())
+
+	scores := scorer.Scores()["main"]
+	require.Len(t, scores, 3)
+	require.Equal(t, "c", scores[0].Backend)
+	require.InDelta(t, 1-0.1/4, scores[0].Score, 0.001)
+	require.Equal(t, int64(1000), scores[0].LatencyMs)
+	require.Equal(t, "b", scores[1].Backend)
+	require.InDelta(t, 0.2, scores[1].RateLimitRate, 0.001)
+	require.Equal(t, "a", scores[2].Backend)
+	require.InDelta(t, 0.75, scores[2].Score, 0.001)
+
+	rec := httptest.NewRecorder()
+	scorer.ServeHTTP(rec, httptest.NewRequest("GET", "/admin/backends", nil))
+	var served map[string][]BackendScore
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &served))
+	require.Equal(t, scorer.Scores(), served)
+}
@@ -0,0 +1,222 @@
+package proxyd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// binaryCodec maps JSON-RPC to a binary encoding of the same data model.
+// Byte strings, which JSON doesn't have, become 0x-prefixed hex strings, the
+// way Ethereum's JSON-RPC represents bytes.
+type binaryCodec struct {
+	// name is also the path of the codec's endpoints
+	name        string
+	contentType string
+	marshal     func(v interface{}) ([]byte, error)
+	unmarshal   func(data []byte, v interface{}) error
+}
+
+var cborDecMode, _ = cbor.DecOptions{
+	DefaultMapType: reflect.TypeOf(map[string]interface{}(nil)),
+}.DecMode()
+
+var (
+	msgpackCodec = &binaryCodec{
+		name:        "msgpack",
+		contentType: "application/msgpack",
+		marshal:     msgpack.Marshal,
+		unmarshal:   msgpack.Unmarshal,
+	}
+	cborCodec = &binaryCodec{
+		name:        "cbor",
+		contentType: "application/cbor",
+		marshal:     cbor.Marshal,
+		unmarshal:   cborDecMode.Unmarshal,
+	}
+)
+
+func newBinaryCodecs(cfg BinaryEncodingConfig) []*binaryCodec {
+	var codecs []*binaryCodec
+	if cfg.MessagePack {
+		codecs = append(codecs, msgpackCodec)
+	}
+	if cfg.CBOR {
+		codecs = append(codecs, cborCodec)
+	}
+	return codecs
+}
+
+// ToJSON transcodes a request or response to JSON.
+func (c *binaryCodec) ToJSON(data []byte) ([]byte, error) {
+	var v interface{}
+	if err := c.unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	v, err := fromBinaryValue(v)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+// FromJSON transcodes a request or response from JSON.
+func (c *binaryCodec) FromJSON(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return c.marshal(fromJSONValue(v))
+}
+
+func fromBinaryValue(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case []byte:
+		return hexutil.Encode(v), nil
+	case []interface{}:
+		for i := range v {
+			elem, err := fromBinaryValue(v[i])
+			if err != nil {
+				return nil, err
+			}
+			v[i] = elem
+		}
+		return v, nil
+	case map[string]interface{}:
+		for k := range v {
+			elem, err := fromBinaryValue(v[k])
+			if err != nil {
+				return nil, err
+			}
+			v[k] = elem
+		}
+		return v, nil
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, elem := range v {
+			key, ok := k.(string)
+			if !ok {
+				return nil, fmt.Errorf("unsupported map key %v", k)
+			}
+			elem, err := fromBinaryValue(elem)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = elem
+		}
+		return m, nil
+	default:
+		return v, nil
+	}
+}
+
+// fromJSONValue turns the json.Numbers of v into integers where they fit,
+// since the binary encodings tell them apart from floats.
+func fromJSONValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		if u, err := strconv.ParseUint(string(v), 10, 64); err == nil {
+			return u
+		}
+		f, _ := v.Float64()
+		return f
+	case []interface{}:
+		for i := range v {
+			v[i] = fromJSONValue(v[i])
+		}
+		return v
+	case map[string]interface{}:
+		for k := range v {
+			v[k] = fromJSONValue(v[k])
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// handleBinaryRPC serves JSON-RPC encoded with codec by transcoding to and
+// from HandleRPC.
+func (s *Server) handleBinaryRPC(codec *binaryCodec) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		bw := &binaryResponseWriter{ResponseWriter: w, codec: codec}
+		defer bw.flush()
+
+		body, err := io.ReadAll(LimitReader(r.Body, s.maxBodySize))
+		if errors.Is(err, ErrLimitReaderOverLimit) {
+			RecordRejectedRequest(RejectReasonTooLarge)
+			writeRPCError(r.Context(), bw, nil, ErrRequestBodyTooLarge)
+			return
+		}
+		if err != nil {
+			writeRPCError(r.Context(), bw, nil, ErrInternal)
+			return
+		}
+		jsonBody, err := codec.ToJSON(body)
+		if err != nil {
+			log.Debug("error transcoding binary RPC request", "encoding", codec.name, "err", err)
+			RecordRejectedRequest(RejectReasonBadJSON)
+			writeRPCError(r.Context(), bw, nil, ErrParseErr)
+			return
+		}
+
+		jsonReq := r.Clone(r.Context())
+		jsonReq.Body = io.NopCloser(bytes.NewReader(jsonBody))
+		jsonReq.ContentLength = int64(len(jsonBody))
+		jsonReq.Header.Set("Content-Type", "application/json")
+		// the endpoint's path isn't forwarded to backends
+		jsonReq.URL.Path = "/"
+		s.HandleRPC(bw, jsonReq)
+	}
+}
+
+// binaryResponseWriter buffers a JSON response and writes it transcoded.
+type binaryResponseWriter struct {
+	http.ResponseWriter
+	codec  *binaryCodec
+	status int
+	buf    bytes.Buffer
+}
+
+func (w *binaryResponseWriter) WriteHeader(status int) {
+	w.status = status
+}
+
+func (w *binaryResponseWriter) Write(b []byte) (int, error) {
+	return w.buf.Write(b)
+}
+
+func (w *binaryResponseWriter) flush() {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if w.buf.Len() == 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+		return
+	}
+	body, err := w.codec.FromJSON(w.buf.Bytes())
+	if err != nil {
+		log.Error("error transcoding binary RPC response", "encoding", w.codec.name, "err", err)
+		w.ResponseWriter.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", w.codec.contentType)
+	w.Header().Del("Content-Length")
+	w.ResponseWriter.WriteHeader(w.status)
+	_, _ = w.ResponseWriter.Write(body)
+}
@@ -0,0 +1,52 @@
+package proxyd
+
+import (
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func TestBinaryCodecs(t *testing.T) {
+	req := `{"jsonrpc":"2.0","id":7,"method":"eth_getBalance","params":["0xd8da6bf26964af9d7eed9e03e53415d37aa96045","latest"]}`
+
+	for _, codec := range []*binaryCodec{msgpackCodec, cborCodec} {
+		t.Run(codec.name, func(t *testing.T) {
+			encoded, err := codec.FromJSON([]byte(req))
+			require.NoError(t, err)
+			decoded, err := codec.ToJSON(encoded)
+			require.NoError(t, err)
+			require.JSONEq(t, req, string(decoded))
+
+			var v map[string]interface{}
+			require.NoError(t, codec.unmarshal(encoded, &v))
+			require.EqualValues(t, 7, v["id"])
+
+			_, err = codec.ToJSON([]byte{0xc1})
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestBinaryCodecsBytes(t *testing.T) {
+	msg := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "eth_sendRawTransaction",
+		"params":  []interface{}{[]byte{0x02, 0xf8}},
+	}
+	exp := `{"jsonrpc":"2.0","id":1,"method":"eth_sendRawTransaction","params":["0x02f8"]}`
+
+	encoded, err := msgpack.Marshal(msg)
+	require.NoError(t, err)
+	decoded, err := msgpackCodec.ToJSON(encoded)
+	require.NoError(t, err)
+	require.JSONEq(t, exp, string(decoded))
+
+	encoded, err = cbor.Marshal(msg)
+	require.NoError(t, err)
+	decoded, err = cborCodec.ToJSON(encoded)
+	require.NoError(t, err)
+	require.JSONEq(t, exp, string(decoded))
+}
@@ -0,0 +1,199 @@
+package proxyd
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/log"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+const blockReceiptsMemoryLimit = 100000
+
+// BlockReceipts serves batches of eth_getTransactionReceipt requests for
+// transactions of the same block, as indexers send, with one
+// eth_getBlockReceipts request. The block of a transaction is learned from
+// the blocks and transactions the group serves. Receipts are only used if
+// they're from the learned block, so reorged transactions are requested one
+// by one, like the requests eth_getBlockReceipts fails for.
+type BlockReceipts struct {
+	minBatch int
+
+	mu       sync.Mutex
+	txBlocks *lru.Cache
+}
+
+type txBlock struct {
+	hash   string
+	number hexutil.Uint64
+}
+
+func NewBlockReceipts(minBatch int) *BlockReceipts {
+	if minBatch <= 0 {
+		return nil
+	}
+	r := &BlockReceipts{minBatch: minBatch}
+	r.txBlocks, _ = lru.New(blockReceiptsMemoryLimit)
+	return r
+}
+
+// Observe learns the blocks of the transactions in the responses to
+// eth_getBlockByNumber, eth_getBlockByHash and eth_getTransactionByHash.
+func (r *BlockReceipts) Observe(reqs []*RPCReq, res []*RPCRes) {
+	if r == nil {
+		return
+	}
+	for i, req := range reqs {
+		if i >= len(res) || res[i] == nil || res[i].IsError() {
+			continue
+		}
+		result, ok := res[i].Result.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		switch req.Method {
+		case "eth_getBlockByNumber", "eth_getBlockByHash":
+			block, ok := parseTxBlock(result["hash"], result["number"])
+			if !ok {
+				continue
+			}
+			txs, _ := result["transactions"].([]interface{})
+			for _, tx := range txs {
+				if obj, ok := tx.(map[string]interface{}); ok {
+					tx = obj["hash"]
+				}
+				if hash, ok := tx.(string); ok {
+					r.add(hash, block)
+				}
+			}
+		case "eth_getTransactionByHash":
+			block, ok := parseTxBlock(result["blockHash"], result["blockNumber"])
+			if hash, isString := result["hash"].(string); ok && isString {
+				r.add(hash, block)
+			}
+		}
+	}
+}
+
+// Batch requests the receipts of blocks with at least minBatch receipt
+// requests with eth_getBlockReceipts, and returns the requests left to
+// forward and the responses to the others.
+func (r *BlockReceipts) Batch(ctx context.Context, bg *BackendGroup, backends []*Backend, rpcReqs []*RPCReq) ([]*RPCReq, []*indexedReqRes) {
+	if r == nil {
+		return rpcReqs, nil
+	}
+	byBlock := make(map[txBlock][]int)
+	txHashes := make(map[int]string)
+	r.mu.Lock()
+	for i, req := range rpcReqs {
+		if req.Method != "eth_getTransactionReceipt" {
+			continue
+		}
+		var params []string
+		if err := json.Unmarshal(req.Params, &params); err != nil || len(params) != 1 {
+			continue
+		}
+		hash := strings.ToLower(params[0])
+		if val, ok := r.txBlocks.Get(hash); ok {
+			block := val.(txBlock)
+			byBlock[block] = append(byBlock[block], i)
+			txHashes[i] = hash
+		}
+	}
+	r.mu.Unlock()
+
+	served := make(map[int]*RPCRes)
+	for block, indexes := range byBlock {
+		if len(indexes) < r.minBatch {
+			continue
+		}
+		receipts := r.fetch(ctx, bg, backends, block)
+		if receipts == nil {
+			RecordBlockReceipts(bg.Name, "fallback", len(indexes))
+			continue
+		}
+		for _, i := range indexes {
+			if receipt, ok := receipts[txHashes[i]]; ok {
+				served[i] = &RPCRes{JSONRPC: JSONRPCVersion, Result: receipt, ID: rpcReqs[i].ID}
+			}
+		}
+		RecordBlockReceipts(bg.Name, "batched", len(indexes))
+	}
+	if len(served) == 0 {
+		return rpcReqs, nil
+	}
+
+	remaining := make([]*RPCReq, 0, len(rpcReqs)-len(served))
+	overridden := make([]*indexedReqRes, 0, len(served))
+	for i, req := range rpcReqs {
+		if res, ok := served[i]; ok {
+			overridden = append(overridden, &indexedReqRes{index: i, req: req, res: res})
+		} else {
+			remaining = append(remaining, req)
+		}
+	}
+	return remaining, overridden
+}
+
+// fetch returns the receipts of a block by transaction hash, or nil if the
+// backends can't serve them or the block was reorged.
+func (r *BlockReceipts) fetch(ctx context.Context, bg *BackendGroup, backends []*Backend, block txBlock) map[string]interface{} {
+	req := &RPCReq{
+		JSONRPC: JSONRPCVersion,
+		Method:  "eth_getBlockReceipts",
+		Params:  mustMarshalJSON([]interface{}{block.number}),
+		ID:      json.RawMessage("1"),
+	}
+	backendResp := bg.ForwardRequestToBackendGroup([]*RPCReq{req}, backends, ctx, false)
+	if backendResp.error != nil || len(backendResp.RPCRes) != 1 || backendResp.RPCRes[0].IsError() {
+		log.Debug("error getting block receipts",
+			"backend_group", bg.Name,
+			"req_id", GetReqID(ctx),
+			"err", backendResp.error,
+		)
+		return nil
+	}
+	list, ok := backendResp.RPCRes[0].Result.([]interface{})
+	if !ok {
+		return nil
+	}
+	receipts := make(map[string]interface{}, len(list))
+	for _, item := range list {
+		receipt, ok := item.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		blockHash, _ := receipt["blockHash"].(string)
+		txHash, _ := receipt["transactionHash"].(string)
+		if !strings.EqualFold(blockHash, block.hash) {
+			return nil
+		}
+		receipts[strings.ToLower(txHash)] = receipt
+	}
+	return receipts
+}
+
+func (r *BlockReceipts) add(txHash string, block txBlock) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.txBlocks.Add(strings.ToLower(txHash), block)
+}
+
+func parseTxBlock(hash, number interface{}) (txBlock, bool) {
+	h, ok := hash.(string)
+	if !ok || h == "" {
+		return txBlock{}, false
+	}
+	n, ok := number.(string)
+	if !ok {
+		return txBlock{}, false
+	}
+	decoded, err := hexutil.DecodeUint64(n)
+	if err != nil {
+		return txBlock{}, false
+	}
+	return txBlock{hash: strings.ToLower(h), number: hexutil.Uint64(decoded)}, true
+}
@@ -175,6 +175,12 @@ func newRPCCache(cache Cache) RPCCache {
 			return len(rawReceipts) > 0
 		},
 	}
+	// outputs of finalized blocks don't change anymore
+	outputAtBlockHandler := &StaticMethodHandler{cache: cache,
+		filterPut: func(req *RPCReq, res *RPCRes) bool {
+			return isFinalOutput(res)
+		},
+	}
 	handlers := map[string]RPCMethodHandler{
 		"eth_chainId":                           staticHandler,
 		"net_version":                           staticHandler,
@@ -184,6 +190,8 @@ func newRPCCache(cache Cache) RPCCache {
 		"eth_getTransactionByBlockHashAndIndex": staticHandler,
 		"eth_getUncleByBlockHashAndIndex":       staticHandler,
 		"debug_getRawReceipts":                  debugGetRawReceiptsHandler,
+		"optimism_rollupConfig":                 staticHandler,
+		"optimism_outputAtBlock":                outputAtBlockHandler,
 	}
 	return &rpcCache{
 		cache:    cache,
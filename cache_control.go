@@ -0,0 +1,207 @@
+package proxyd
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultCacheControlMaxAge = 365 * 24 * time.Hour
+
+// cacheControlStaticMethods are addressed by block hash or constant, so their
+// responses never change.
+var cacheControlStaticMethods = map[string]bool{
+	"eth_chainId":                           true,
+	"net_version":                           true,
+	"eth_getBlockTransactionCountByHash":    true,
+	"eth_getUncleCountByBlockHash":          true,
+	"eth_getBlockByHash":                    true,
+	"eth_getTransactionByBlockHashAndIndex": true,
+	"eth_getUncleByBlockHashAndIndex":       true,
+}
+
+// CacheControl tells HTTP caches in front of proxyd, like a CDN, which
+// responses they can keep. Responses whose every result is immutable, by the
+// same rules as the caches and the finality of the backend group, can be kept
+// for MaxAge and get an ETag. Others must not be stored. Only GET responses,
+// see RequestBody, are public, as shared caches don't key POST requests by
+// body.
+type CacheControl struct {
+	header       string
+	publicHeader string
+}
+
+// cacheControlSession tracks the requests of a batch that may be immutable as
+// sent by the client, before tags are rewritten, and the responses that are.
+type cacheControlSession struct {
+	mu        sync.Mutex
+	eligible  map[int]bool
+	immutable map[int]bool
+}
+
+func NewCacheControl(cfg CacheControlConfig) *CacheControl {
+	if !cfg.Enabled {
+		return nil
+	}
+	maxAge := time.Duration(cfg.MaxAge)
+	if maxAge == 0 {
+		maxAge = defaultCacheControlMaxAge
+	}
+	header := fmt.Sprintf("max-age=%d, immutable", int64(maxAge/time.Second))
+	return &CacheControl{
+		header:       header,
+		publicHeader: "public, " + header,
+	}
+}
+
+// RequestBody builds the JSON-RPC request of a GET request from its method
+// and params query parameters, e.g.
+// ?method=eth_getBlockByNumber&params=["0x1",false]. The id is always 1, so
+// the same query has the same response.
+func (c *CacheControl) RequestBody(r *http.Request) ([]byte, error) {
+	query := r.URL.Query()
+	method := query.Get("method")
+	if method == "" {
+		return nil, errors.New("missing method query parameter")
+	}
+	params := json.RawMessage("[]")
+	if raw := query.Get("params"); raw != "" {
+		var list []json.RawMessage
+		if err := json.Unmarshal([]byte(raw), &list); err != nil {
+			return nil, errors.New("params query parameter must be a JSON array")
+		}
+		params = json.RawMessage(raw)
+	}
+	return json.Marshal(&RPCReq{
+		JSONRPC: JSONRPCVersion,
+		Method:  method,
+		Params:  params,
+		ID:      json.RawMessage("1"),
+	})
+}
+
+// Session starts tracking the responses of a request.
+func (c *CacheControl) Session(ctx context.Context) context.Context {
+	if c == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, ContextKeyCacheControl, &cacheControlSession{ // nolint:staticcheck
+		eligible:  make(map[int]bool),
+		immutable: make(map[int]bool),
+	})
+}
+
+// Request records whether the request of a batch element may be immutable.
+// It must be called before the request is forwarded.
+func (c *CacheControl) Request(ctx context.Context, elem batchElem) {
+	session := getCacheControlSession(ctx)
+	if session == nil || !cacheControlEligible(elem.Req) {
+		return
+	}
+	session.mu.Lock()
+	session.eligible[elem.Index] = true
+	session.mu.Unlock()
+}
+
+// Response records whether the response to a batch element is immutable.
+// ctx carries the finalized block of the backend group that served it.
+func (c *CacheControl) Response(ctx context.Context, elem batchElem, res *RPCRes) {
+	session := getCacheControlSession(ctx)
+	if session == nil {
+		return
+	}
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	session.immutable[elem.Index] = session.eligible[elem.Index] && cacheControlImmutable(ctx, elem.Req, res)
+}
+
+// SetHeaders sets the Cache-Control header, and the ETag of immutable
+// responses. It reports whether it answered a GET request whose If-None-Match
+// matches the ETag with 304 Not Modified, in which case the response must not
+// be written.
+func (c *CacheControl) SetHeaders(ctx context.Context, w http.ResponseWriter, r *http.Request, responses []*RPCRes, isBatch bool) bool {
+	session := getCacheControlSession(ctx)
+	if session == nil {
+		return false
+	}
+	session.mu.Lock()
+	immutable := len(responses) > 0
+	for i := range responses {
+		immutable = immutable && session.immutable[i]
+	}
+	session.mu.Unlock()
+
+	if !immutable {
+		w.Header().Set("Cache-Control", "no-store")
+		return false
+	}
+	var body []byte
+	var err error
+	if isBatch {
+		body, err = json.Marshal(responses)
+	} else {
+		body, err = json.Marshal(responses[0])
+	}
+	if err != nil {
+		w.Header().Set("Cache-Control", "no-store")
+		return false
+	}
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	w.Header().Set("ETag", etag)
+	if r.Method != http.MethodGet {
+		w.Header().Set("Cache-Control", c.header)
+		return false
+	}
+	w.Header().Set("Cache-Control", c.publicHeader)
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return true
+	}
+	return false
+}
+
+// etagMatches reports whether an If-None-Match header lists etag, weakly
+// compared.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+func getCacheControlSession(ctx context.Context) *cacheControlSession {
+	session, _ := ctx.Value(ContextKeyCacheControl).(*cacheControlSession)
+	return session
+}
+
+func cacheControlEligible(req *RPCReq) bool {
+	if cacheControlStaticMethods[req.Method] {
+		return true
+	}
+	if req.Method == "debug_getRawReceipts" {
+		number, ok := immutableBlockParam(req, 0)
+		return ok && number == nil
+	}
+	return immutableCacheable(req)
+}
+
+func cacheControlImmutable(ctx context.Context, req *RPCReq, res *RPCRes) bool {
+	if res == nil || res.IsError() || res.Result == nil {
+		return false
+	}
+	if cacheControlStaticMethods[req.Method] || req.Method == "debug_getRawReceipts" {
+		return true
+	}
+	return isImmutableResponse(ctx, req, res)
+}
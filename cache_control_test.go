@@ -0,0 +1,125 @@
+package proxyd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheControl(t *testing.T) {
+	cc := NewCacheControl(CacheControlConfig{Enabled: true, MaxAge: TOMLDuration(3600e9)})
+
+	req := func(method string, params ...interface{}) *RPCReq {
+		return &RPCReq{JSONRPC: "2.0", Method: method, Params: mustMarshalJSON(params), ID: json.RawMessage("1")}
+	}
+	res := func(result interface{}) *RPCRes {
+		return &RPCRes{JSONRPC: "2.0", Result: result, ID: json.RawMessage("1")}
+	}
+	get := httptest.NewRequest("GET", "/?method=eth_getBlockByNumber", nil)
+	headers := func(reqs []*RPCReq, responses []*RPCRes, rewrite func(*RPCReq)) (string, string) {
+		ctx := cc.Session(context.Background())
+		finalCtx := WithFinalizedBlock(ctx, 100)
+		for i, r := range reqs {
+			cc.Request(ctx, batchElem{Req: r, Index: i})
+		}
+		for i, r := range reqs {
+			if rewrite != nil {
+				rewrite(r)
+			}
+			cc.Response(finalCtx, batchElem{Req: r, Index: i}, responses[i])
+		}
+		w := httptest.NewRecorder()
+		require.False(t, cc.SetHeaders(ctx, w, get, responses, len(reqs) > 1))
+		return w.Header().Get("Cache-Control"), w.Header().Get("ETag")
+	}
+
+	block := res(map[string]interface{}{"number": "0x64"})
+	control, etag := headers([]*RPCReq{req("eth_getBlockByNumber", "0x64", false)}, []*RPCRes{block}, nil)
+	require.Equal(t, "public, max-age=3600, immutable", control)
+	require.NotEmpty(t, etag)
+
+	// the ETag is the same for the same response
+	_, etag2 := headers([]*RPCReq{req("eth_getBlockByNumber", "0x64", false)}, []*RPCRes{block}, nil)
+	require.Equal(t, etag, etag2)
+
+	// only GET responses are public, and a matching If-None-Match is answered
+	// with 304
+	session := func() context.Context {
+		ctx := cc.Session(context.Background())
+		r := req("eth_getBlockByNumber", "0x64", false)
+		cc.Request(ctx, batchElem{Req: r})
+		cc.Response(WithFinalizedBlock(ctx, 100), batchElem{Req: r}, block)
+		return ctx
+	}
+	w := httptest.NewRecorder()
+	require.False(t, cc.SetHeaders(session(), w, httptest.NewRequest("POST", "/", nil), []*RPCRes{block}, false))
+	require.Equal(t, "max-age=3600, immutable", w.Header().Get("Cache-Control"))
+	require.Equal(t, etag, w.Header().Get("ETag"))
+	conditional := httptest.NewRequest("GET", "/?method=eth_getBlockByNumber", nil)
+	conditional.Header.Set("If-None-Match", `"other", W/`+etag)
+	w = httptest.NewRecorder()
+	require.True(t, cc.SetHeaders(session(), w, conditional, []*RPCRes{block}, false))
+	require.Equal(t, 304, w.Code)
+	require.Equal(t, etag, w.Header().Get("ETag"))
+	conditional.Header.Set("If-None-Match", `"other"`)
+	require.False(t, cc.SetHeaders(session(), httptest.NewRecorder(), conditional, []*RPCRes{block}, false))
+
+	// blocks after finality aren't immutable
+	control, etag = headers([]*RPCReq{req("eth_getBlockByNumber", "0x65", false)}, []*RPCRes{block}, nil)
+	require.Equal(t, "no-store", control)
+	require.Empty(t, etag)
+
+	// nor are tags, even once rewritten to a final block
+	control, _ = headers([]*RPCReq{req("eth_getBlockByNumber", "finalized", false)}, []*RPCRes{block}, func(r *RPCReq) {
+		r.Params = mustMarshalJSON([]interface{}{"0x64", false})
+	})
+	require.Equal(t, "no-store", control)
+
+	// nor errors and null results
+	control, _ = headers([]*RPCReq{req("eth_getBlockByHash", "0xb903239f8543d04b5dc1ba6579132b143087c68db1b2168786408fcbce568238", false)}, []*RPCRes{res(nil)}, nil)
+	require.Equal(t, "no-store", control)
+
+	// a batch is immutable if all its responses are
+	control, _ = headers(
+		[]*RPCReq{req("eth_chainId"), req("eth_getCode", "0x0000000000000000000000000000000000000001", "0x1")},
+		[]*RPCRes{res("0x1"), res("0x6080")},
+		nil,
+	)
+	require.Equal(t, "public, max-age=3600, immutable", control)
+	control, _ = headers(
+		[]*RPCReq{req("eth_chainId"), req("eth_blockNumber")},
+		[]*RPCRes{res("0x1"), res("0x64")},
+		nil,
+	)
+	require.Equal(t, "no-store", control)
+
+	// disabled
+	require.Nil(t, NewCacheControl(CacheControlConfig{}))
+	w = httptest.NewRecorder()
+	var disabled *CacheControl
+	ctx := disabled.Session(context.Background())
+	disabled.Request(ctx, batchElem{Req: req("eth_chainId")})
+	disabled.Response(ctx, batchElem{Req: req("eth_chainId")}, res("0x1"))
+	require.False(t, disabled.SetHeaders(ctx, w, get, []*RPCRes{res("0x1")}, false))
+	require.Empty(t, w.Header().Get("Cache-Control"))
+}
+
+func TestCacheControlRequestBody(t *testing.T) {
+	cc := NewCacheControl(CacheControlConfig{Enabled: true})
+
+	body, err := cc.RequestBody(httptest.NewRequest("GET", `/?method=eth_getBlockByNumber&params=%5B%220x64%22,false%5D`, nil))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"jsonrpc":"2.0","method":"eth_getBlockByNumber","params":["0x64",false],"id":1}`, string(body))
+
+	body, err = cc.RequestBody(httptest.NewRequest("GET", "/?method=eth_chainId", nil))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"jsonrpc":"2.0","method":"eth_chainId","params":[],"id":1}`, string(body))
+
+	_, err = cc.RequestBody(httptest.NewRequest("GET", "/", nil))
+	require.Error(t, err)
+	_, err = cc.RequestBody(httptest.NewRequest("GET", `/?method=eth_chainId&params=%7B%7D`, nil))
+	require.Error(t, err)
+}
@@ -0,0 +1,271 @@
+package proxyd
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	cacheWarmingRedisKey            = "cache_warming"
+	defaultCacheWarmingPollInterval = 250 * time.Millisecond
+	defaultCacheWarmingTTL          = time.Minute
+	cacheWarmingTimeout             = 5 * time.Second
+)
+
+// defaultCacheWarmingRequests are what clients typically poll on every block.
+var defaultCacheWarmingRequests = []CacheWarmingRequestConfig{
+	{Method: "eth_getBlockByNumber", Params: `["latest",false]`},
+	{Method: "eth_getBlockByNumber", Params: `["latest",true]`},
+	{Method: "eth_getBlockReceipts", Params: `["latest"]`},
+	{Method: "eth_feeHistory", Params: `["0x5","latest",[]]`},
+}
+
+// cacheWarmingRequest is a warmed request, with its params decoded so that
+// the "latest" tag can be replaced by the head.
+type cacheWarmingRequest struct {
+	method string
+	params []interface{}
+	// latest is the canonical encoding of params.
+	latest string
+}
+
+// at returns the canonical encoding of the params with "latest" replaced by
+// the block number.
+func (r *cacheWarmingRequest) at(head hexutil.Uint64) string {
+	params := make([]interface{}, len(r.params))
+	for i, param := range r.params {
+		if param == "latest" {
+			param = head.String()
+		}
+		params[i] = param
+	}
+	return string(mustMarshalJSON(params))
+}
+
+// canonicalParams re-encodes params so that equal params compare equal
+// regardless of whitespace and key order.
+func canonicalParams(params json.RawMessage) (string, []interface{}, bool) {
+	var decoded []interface{}
+	if err := json.Unmarshal(params, &decoded); err != nil {
+		return "", nil, false
+	}
+	return string(mustMarshalJSON(decoded)), decoded, true
+}
+
+// CacheWarmer fetches what clients poll on every block into Redis as soon as
+// the consensus of a backend group moves to a new head, so that the first
+// wave of polls for the block is served from cache on all proxyd instances.
+// Per head, the instance that takes the head's lock in Redis does the warming.
+// Warmed responses are keyed by head, and serve requests with the "latest"
+// tag and with the head's number alike, until the next head. Blocks are also
+// stored in the regular cache by hash.
+type CacheWarmer struct {
+	group     *BackendGroup
+	client    redis.UniversalClient
+	prefix    string
+	instance  string
+	interval  time.Duration
+	ttl       time.Duration
+	requests  []*cacheWarmingRequest
+	hashCache RPCCache
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+func NewCacheWarmer(cfg CacheWarmingConfig, group *BackendGroup, client redis.UniversalClient, namespace string, hashCache RPCCache) (*CacheWarmer, error) {
+	w := &CacheWarmer{
+		group:     group,
+		client:    client,
+		prefix:    cacheWarmingRedisKey + ":" + group.Name,
+		interval:  time.Duration(cfg.PollInterval),
+		ttl:       time.Duration(cfg.TTL),
+		hashCache: hashCache,
+		stop:      make(chan struct{}),
+	}
+	if namespace != "" {
+		w.prefix = namespace + ":" + w.prefix
+	}
+	if w.interval == 0 {
+		w.interval = defaultCacheWarmingPollInterval
+	}
+	if w.ttl == 0 {
+		w.ttl = defaultCacheWarmingTTL
+	}
+	id := make([]byte, 8)
+	_, _ = rand.Read(id)
+	w.instance = hex.EncodeToString(id)
+
+	requests := cfg.Requests
+	if len(requests) == 0 {
+		requests = defaultCacheWarmingRequests
+	}
+	for _, req := range requests {
+		latest, params, ok := canonicalParams(json.RawMessage(req.Params))
+		if !ok {
+			return nil, fmt.Errorf("invalid params of warmed %s: %s", req.Method, req.Params)
+		}
+		w.requests = append(w.requests, &cacheWarmingRequest{method: req.Method, params: params, latest: latest})
+	}
+	return w, nil
+}
+
+// Start warms the cache on new heads until Stop is called.
+func (w *CacheWarmer) Start() {
+	if w == nil {
+		return
+	}
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+		var last hexutil.Uint64
+		for {
+			select {
+			case <-w.stop:
+				return
+			case <-ticker.C:
+				head := w.group.Consensus.GetLatestBlockNumber()
+				if head > last {
+					last = head
+					w.warm(head)
+				}
+			}
+		}
+	}()
+}
+
+func (w *CacheWarmer) Stop() {
+	if w == nil {
+		return
+	}
+	close(w.stop)
+	w.wg.Wait()
+}
+
+func (w *CacheWarmer) key(head hexutil.Uint64, req *cacheWarmingRequest) string {
+	return fmt.Sprintf("%s:%d:%s:%s", w.prefix, head, req.method, req.latest)
+}
+
+// warm fetches the warmed requests at head, unless another instance already
+// is.
+func (w *CacheWarmer) warm(head hexutil.Uint64) {
+	ctx, cancel := context.WithTimeout(context.Background(), cacheWarmingTimeout)
+	defer cancel()
+
+	lock := fmt.Sprintf("%s:%d:lock", w.prefix, head)
+	acquired, err := w.client.SetNX(ctx, lock, w.instance, w.ttl).Result()
+	if err != nil {
+		log.Warn("error taking cache warming lock", "backend_group", w.group.Name, "head", head, "err", err)
+		return
+	}
+	if !acquired {
+		return
+	}
+
+	reqs := make([]*RPCReq, len(w.requests))
+	for i, req := range w.requests {
+		reqs[i] = &RPCReq{
+			JSONRPC: JSONRPCVersion,
+			Method:  req.method,
+			Params:  json.RawMessage(req.at(head)),
+			ID:      json.RawMessage(fmt.Sprintf("%d", i+1)),
+		}
+	}
+	ctx = context.WithValue(ctx, ContextKeyReqID, "cache_warming") // nolint:staticcheck
+	res, _, err := w.group.Forward(ctx, reqs, true)
+	if err != nil {
+		log.Warn("error warming cache", "backend_group", w.group.Name, "head", head, "err", err)
+		return
+	}
+
+	pipe := w.client.Pipeline()
+	for i, req := range w.requests {
+		if res[i] == nil || res[i].IsError() || res[i].Result == nil {
+			continue
+		}
+		result := mustMarshalJSON(res[i].Result)
+		pipe.Set(ctx, w.key(head, req), result, w.ttl)
+		RecordCacheWarming(req.method, "warmed")
+
+		if req.method == "eth_getBlockByNumber" && w.hashCache != nil {
+			w.putByHash(ctx, req, result, res[i])
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Warn("error storing warmed cache", "backend_group", w.group.Name, "head", head, "err", err)
+	}
+}
+
+// putByHash also caches a warmed block for eth_getBlockByHash.
+func (w *CacheWarmer) putByHash(ctx context.Context, req *cacheWarmingRequest, result json.RawMessage, res *RPCRes) {
+	var block struct {
+		Hash string `json:"hash"`
+	}
+	if err := json.Unmarshal(result, &block); err != nil || block.Hash == "" || len(req.params) != 2 {
+		return
+	}
+	byHash := &RPCReq{
+		JSONRPC: JSONRPCVersion,
+		Method:  "eth_getBlockByHash",
+		Params:  mustMarshalJSON([]interface{}{block.Hash, req.params[1]}),
+		ID:      res.ID,
+	}
+	if err := w.hashCache.PutRPC(ctx, byHash, res); err != nil {
+		log.Warn("error caching warmed block by hash", "backend_group", w.group.Name, "err", err)
+	}
+}
+
+// Lookup returns the warmed response to req, routed to group, if any.
+func (w *CacheWarmer) Lookup(ctx context.Context, group string, req *RPCReq) *RPCRes {
+	if w == nil || group != w.group.Name {
+		return nil
+	}
+	var warmed *cacheWarmingRequest
+	var params string
+	head := w.group.Consensus.GetLatestBlockNumber()
+	for _, candidate := range w.requests {
+		if candidate.method != req.Method {
+			continue
+		}
+		if params == "" {
+			var ok bool
+			if params, _, ok = canonicalParams(req.Params); !ok {
+				return nil
+			}
+		}
+		if params == candidate.latest || params == candidate.at(head) {
+			warmed = candidate
+			break
+		}
+	}
+	if warmed == nil {
+		return nil
+	}
+
+	result, err := w.client.Get(ctx, w.key(head, warmed)).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			log.Warn("error reading warmed cache", "req_id", GetReqID(ctx), "err", err)
+		}
+		RecordCacheWarming(req.Method, "miss")
+		return nil
+	}
+	RecordCacheWarming(req.Method, "hit")
+	return &RPCRes{
+		JSONRPC: JSONRPCVersion,
+		Result:  json.RawMessage(result),
+		ID:      req.ID,
+	}
+}
@@ -0,0 +1,75 @@
+package proxyd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/alicebob/miniredis"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/semaphore"
+)
+
+func TestCacheWarmer(t *testing.T) {
+	var calls atomic.Int32
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req RPCReq
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		calls.Add(1)
+		_, _ = fmt.Fprintf(w, `{"jsonrpc":"2.0","result":{"hash":"0xbeef","params":%s},"id":%s}`, req.Params, req.ID)
+	}))
+	defer node.Close()
+
+	redisServer, err := miniredis.Run()
+	require.NoError(t, err)
+	defer redisServer.Close()
+	client := redis.NewClient(&redis.Options{Addr: redisServer.Addr()})
+
+	be := NewBackend("node", node.URL, "", semaphore.NewWeighted(10))
+	bg := &BackendGroup{Name: "main", Backends: []*Backend{be}}
+	tracker := NewInMemoryConsensusTracker()
+	tracker.SetLatestBlockNumber(100)
+	bg.Consensus = NewConsensusPoller(bg, WithAsyncHandler(NewNoopAsyncHandler()), WithTracker(tracker))
+	bg.Consensus.consensusGroup = []*Backend{be}
+
+	cfg := CacheWarmingConfig{Enabled: true, BackendGroup: "main", Requests: []CacheWarmingRequestConfig{
+		{Method: "eth_getBlockByNumber", Params: `["latest", false]`},
+	}}
+	w, err := NewCacheWarmer(cfg, bg, client, "proxyd", nil)
+	require.NoError(t, err)
+	other, err := NewCacheWarmer(cfg, bg, client, "proxyd", nil)
+	require.NoError(t, err)
+
+	req := func(params string) *RPCReq {
+		return &RPCReq{JSONRPC: JSONRPCVersion, Method: "eth_getBlockByNumber", Params: json.RawMessage(params), ID: json.RawMessage("7")}
+	}
+	ctx := context.Background()
+	require.Nil(t, w.Lookup(ctx, "main", req(`["latest",false]`)))
+
+	// only one instance warms a head
+	w.warm(100)
+	other.warm(100)
+	require.EqualValues(t, 1, calls.Load())
+
+	// the head is served by tag and by number, on every instance
+	for _, params := range []string{`["latest",false]`, `[ "0x64", false ]`} {
+		res := other.Lookup(ctx, "main", req(params))
+		require.NotNil(t, res)
+		require.Equal(t, json.RawMessage("7"), res.ID)
+		require.JSONEq(t, `{"hash":"0xbeef","params":["0x64",false]}`, string(res.Result.(json.RawMessage)))
+	}
+	require.Nil(t, w.Lookup(ctx, "main", req(`["latest",true]`)))
+	require.Nil(t, w.Lookup(ctx, "other", req(`["latest",false]`)))
+
+	// until the next head
+	tracker.SetLatestBlockNumber(101)
+	require.Nil(t, w.Lookup(ctx, "main", req(`["latest",false]`)))
+
+	var disabled *CacheWarmer
+	require.Nil(t, disabled.Lookup(ctx, "main", req(`["latest",false]`)))
+}
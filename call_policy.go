@@ -0,0 +1,150 @@
+package proxyd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+const (
+	CallPolicyModeAllow = "allow"
+	CallPolicyModeDeny  = "deny"
+)
+
+// CallPolicy restricts the contracts and functions eth_call and
+// eth_estimateGas may call. A call matches if its target is one of the
+// addresses and its selector one of the selectors, an empty list matching
+// anything. In allow mode only matching calls are served, in deny mode
+// matching calls are rejected.
+type CallPolicy struct {
+	name      string
+	allow     bool
+	addresses map[common.Address]bool
+	selectors map[string]bool
+}
+
+// CallPolicies applies call policies by authentication alias, or by the rate
+// limit tier of the request's auth policy. An alias's policy takes
+// precedence over its tier's.
+type CallPolicies struct {
+	byAlias map[string]*CallPolicy
+	byTier  map[string]*CallPolicy
+}
+
+// NewCallPolicies returns nil if no call policy is configured. tiers are the
+// [rate_limit_tiers] policies may apply to.
+func NewCallPolicies(cfg map[string]CallPolicyConfig, tiers map[string]RateLimitConfig) (*CallPolicies, error) {
+	if len(cfg) == 0 {
+		return nil, nil
+	}
+	p := &CallPolicies{
+		byAlias: make(map[string]*CallPolicy),
+		byTier:  make(map[string]*CallPolicy),
+	}
+	for _, name := range sortedKeys(cfg) {
+		pc := cfg[name]
+		policy, err := newCallPolicy(name, pc)
+		if err != nil {
+			return nil, err
+		}
+		for _, alias := range pc.Aliases {
+			if other := p.byAlias[alias]; other != nil {
+				return nil, fmt.Errorf("alias %s has call policies %s and %s", alias, other.name, name)
+			}
+			p.byAlias[alias] = policy
+		}
+		for _, tier := range pc.Tiers {
+			if _, ok := tiers[tier]; !ok {
+				return nil, fmt.Errorf("call policy %s has undefined rate limit tier %s", name, tier)
+			}
+			if other := p.byTier[tier]; other != nil {
+				return nil, fmt.Errorf("rate limit tier %s has call policies %s and %s", tier, other.name, name)
+			}
+			p.byTier[tier] = policy
+		}
+	}
+	return p, nil
+}
+
+func newCallPolicy(name string, cfg CallPolicyConfig) (*CallPolicy, error) {
+	policy := &CallPolicy{
+		name:      name,
+		addresses: make(map[common.Address]bool, len(cfg.Addresses)),
+		selectors: make(map[string]bool, len(cfg.Selectors)),
+	}
+	switch cfg.Mode {
+	case CallPolicyModeAllow:
+		policy.allow = true
+	case CallPolicyModeDeny:
+	default:
+		return nil, fmt.Errorf("call policy %s has invalid mode %q, must be %s or %s", name, cfg.Mode, CallPolicyModeAllow, CallPolicyModeDeny)
+	}
+	for _, address := range cfg.Addresses {
+		if !common.IsHexAddress(address) {
+			return nil, fmt.Errorf("call policy %s has invalid address %s", name, address)
+		}
+		policy.addresses[common.HexToAddress(address)] = true
+	}
+	for _, selector := range cfg.Selectors {
+		b, err := hexutil.Decode(selector)
+		if err != nil || len(b) != 4 {
+			return nil, fmt.Errorf("call policy %s has invalid selector %s, must be 4 hex bytes", name, selector)
+		}
+		policy.selectors[strings.ToLower(selector)] = true
+	}
+	return policy, nil
+}
+
+// Check returns ErrCallNotAllowed if the policy of the request of ctx
+// rejects an eth_call or eth_estimateGas request.
+func (p *CallPolicies) Check(ctx context.Context, req *RPCReq) error {
+	if p == nil || (req.Method != "eth_call" && req.Method != "eth_estimateGas") {
+		return nil
+	}
+	policy := p.byAlias[GetAuthCtx(ctx)]
+	if policy == nil {
+		if authPolicy := GetAuthPolicy(ctx); authPolicy != nil && authPolicy.tier != nil {
+			policy = p.byTier[authPolicy.tier.name]
+		}
+	}
+	if policy == nil {
+		return nil
+	}
+	if policy.matches(req) != policy.allow {
+		RecordCallPolicyRejection(policy.name)
+		return ErrCallNotAllowed
+	}
+	return nil
+}
+
+func (p *CallPolicy) matches(req *RPCReq) bool {
+	var params []json.RawMessage
+	if err := json.Unmarshal(req.Params, &params); err != nil || len(params) == 0 {
+		return false
+	}
+	var call struct {
+		To    *common.Address `json:"to"`
+		Data  hexutil.Bytes   `json:"data"`
+		Input hexutil.Bytes   `json:"input"`
+	}
+	if err := json.Unmarshal(params[0], &call); err != nil {
+		return false
+	}
+	if len(p.addresses) > 0 && (call.To == nil || !p.addresses[*call.To]) {
+		return false
+	}
+	if len(p.selectors) > 0 {
+		data := call.Input
+		if len(data) == 0 {
+			data = call.Data
+		}
+		if len(data) < 4 || !p.selectors[hexutil.Encode(data[:4])] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,65 @@
+package proxyd
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCallPolicies(t *testing.T) {
+	policies, err := NewCallPolicies(map[string]CallPolicyConfig{
+		"app": {
+			Mode:      CallPolicyModeAllow,
+			Addresses: []string{"0x00000000000000000000000000000000000000aa"},
+			Aliases:   []string{"app"},
+		},
+		"no_transfers": {
+			Mode:      CallPolicyModeDeny,
+			Selectors: []string{"0xa9059cbb"},
+			Tiers:     []string{"free"},
+		},
+	}, map[string]RateLimitConfig{"free": {}})
+	require.NoError(t, err)
+
+	call := func(method, params string) *RPCReq {
+		return &RPCReq{Method: method, Params: json.RawMessage(params)}
+	}
+	appCtx := context.WithValue(context.Background(), ContextKeyAuth, "app") // nolint:staticcheck
+	freeCtx := WithAuthPolicy(
+		context.WithValue(context.Background(), ContextKeyAuth, "other"), // nolint:staticcheck
+		&AuthPolicy{Alias: "other", tier: &rateLimitTier{name: "free"}},
+	)
+
+	require.NoError(t, policies.Check(appCtx, call("eth_call", `[{"to":"0x00000000000000000000000000000000000000AA","data":"0xa9059cbb"},"latest"]`)))
+	require.ErrorIs(t, policies.Check(appCtx, call("eth_call", `[{"to":"0x00000000000000000000000000000000000000bb"},"latest"]`)), ErrCallNotAllowed)
+	require.ErrorIs(t, policies.Check(appCtx, call("eth_estimateGas", `[{"data":"0x6000"}]`)), ErrCallNotAllowed)
+	// other methods aren't restricted
+	require.NoError(t, policies.Check(appCtx, call("eth_chainId", `[]`)))
+
+	require.ErrorIs(t, policies.Check(freeCtx, call("eth_call", `[{"to":"0x00000000000000000000000000000000000000bb","input":"0xa9059cbb0000"},"latest"]`)), ErrCallNotAllowed)
+	require.NoError(t, policies.Check(freeCtx, call("eth_call", `[{"to":"0x00000000000000000000000000000000000000bb","input":"0x70a08231"},"latest"]`)))
+
+	// without a policy
+	require.NoError(t, policies.Check(context.Background(), call("eth_call", `[{"to":"0x00000000000000000000000000000000000000bb"},"latest"]`)))
+	require.NoError(t, (*CallPolicies)(nil).Check(appCtx, call("eth_call", `[]`)))
+}
+
+func TestCallPoliciesInvalid(t *testing.T) {
+	for name, cfg := range map[string]CallPolicyConfig{
+		"mode":     {Mode: "block"},
+		"address":  {Mode: CallPolicyModeAllow, Addresses: []string{"0x01"}},
+		"selector": {Mode: CallPolicyModeAllow, Selectors: []string{"0xa9059c"}},
+		"tier":     {Mode: CallPolicyModeAllow, Tiers: []string{"missing"}},
+	} {
+		_, err := NewCallPolicies(map[string]CallPolicyConfig{"p": cfg}, nil)
+		require.Error(t, err, name)
+	}
+
+	_, err := NewCallPolicies(map[string]CallPolicyConfig{
+		"a": {Mode: CallPolicyModeAllow, Aliases: []string{"app"}},
+		"b": {Mode: CallPolicyModeDeny, Aliases: []string{"app"}},
+	}, nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,178 @@
+package proxyd
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	defaultCapabilityProbingInterval = 5 * time.Minute
+	capabilityProbeTimeout           = 10 * time.Second
+)
+
+// backendCapabilities are the methods a backend was found to serve. Modules
+// are the namespaces rpc_modules returned, or nil if the backend doesn't
+// implement it, and unsupported are the trial methods it doesn't know.
+type backendCapabilities struct {
+	modules     map[string]bool
+	unsupported map[string]bool
+}
+
+// CapabilityProber asks the backends of every group, on startup and then on
+// an interval, which RPC modules they serve, and tries the configured methods
+// on them. Groups skip backends that would answer "method not found" for a
+// request, unless no backend serves it.
+type CapabilityProber struct {
+	groups   map[string]*BackendGroup
+	interval time.Duration
+	methods  []string
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+func NewCapabilityProber(cfg CapabilityProbingConfig, groups map[string]*BackendGroup) *CapabilityProber {
+	p := &CapabilityProber{
+		groups:   groups,
+		interval: time.Duration(cfg.Interval),
+		methods:  cfg.Methods,
+		stop:     make(chan struct{}),
+	}
+	if p.interval == 0 {
+		p.interval = defaultCapabilityProbingInterval
+	}
+	return p
+}
+
+// Start probes the backends until Stop is called.
+func (p *CapabilityProber) Start() {
+	if p == nil {
+		return
+	}
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		p.update()
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.stop:
+				return
+			case <-ticker.C:
+				p.update()
+			}
+		}
+	}()
+}
+
+func (p *CapabilityProber) Stop() {
+	if p == nil {
+		return
+	}
+	close(p.stop)
+	p.wg.Wait()
+}
+
+func (p *CapabilityProber) update() {
+	probed := make(map[*Backend]bool)
+	for _, bg := range p.groups {
+		for _, be := range bg.members() {
+			if probed[be] {
+				continue
+			}
+			probed[be] = true
+			if caps := p.probe(be); caps != nil {
+				be.capabilitiesMu.Lock()
+				be.capabilities = caps
+				be.capabilitiesMu.Unlock()
+			}
+		}
+	}
+}
+
+// probe returns the capabilities of the backend, or nil if it couldn't be
+// reached.
+func (p *CapabilityProber) probe(be *Backend) *backendCapabilities {
+	caps := &backendCapabilities{unsupported: make(map[string]bool)}
+
+	ctx, cancel := context.WithTimeout(context.Background(), capabilityProbeTimeout)
+	defer cancel()
+	var res RPCRes
+	err := be.ForwardRPC(ctx, &res, "67", "rpc_modules")
+	var rpcErr *RPCErr
+	switch {
+	case errors.As(err, &rpcErr):
+		// modules are unknown
+	case err != nil:
+		log.Warn("error probing backend capabilities", "name", be.Name, "err", err)
+		return nil
+	default:
+		modules, _ := res.Result.(map[string]interface{})
+		caps.modules = make(map[string]bool, len(modules))
+		for module := range modules {
+			caps.modules[module] = true
+		}
+	}
+
+	for _, method := range p.methods {
+		ctx, cancel := context.WithTimeout(context.Background(), capabilityProbeTimeout)
+		err := be.ForwardRPC(ctx, &res, "67", method)
+		cancel()
+		if errors.As(err, &rpcErr) && rpcErr.Code == notFoundRpcError {
+			caps.unsupported[method] = true
+		} else if err != nil && !errors.As(err, &rpcErr) {
+			log.Warn("error probing backend method", "name", be.Name, "method", method, "err", err)
+			return nil
+		}
+	}
+	return caps
+}
+
+// Supports returns whether the backend serves the method, as far as it's
+// known.
+func (b *Backend) Supports(method string) bool {
+	b.capabilitiesMu.RLock()
+	caps := b.capabilities
+	b.capabilitiesMu.RUnlock()
+	if caps == nil || method == ConsensusGetReceiptsMethod {
+		return true
+	}
+	if caps.unsupported[method] {
+		return false
+	}
+	if caps.modules == nil {
+		return true
+	}
+	module, _, _ := strings.Cut(method, "_")
+	return caps.modules[module]
+}
+
+// supportingBackends returns the backends that serve every request, or all
+// of them if none does.
+func supportingBackends(backends []*Backend, rpcReqs []*RPCReq) []*Backend {
+	supporting := make([]*Backend, 0, len(backends))
+	for _, be := range backends {
+		supported := true
+		for _, req := range rpcReqs {
+			if !be.Supports(req.Method) {
+				supported = false
+				break
+			}
+		}
+		if supported {
+			supporting = append(supporting, be)
+		} else {
+			RecordCapabilitySkip(be.Name)
+		}
+	}
+	if len(supporting) == 0 {
+		return backends
+	}
+	return supporting
+}
@@ -0,0 +1,64 @@
+package proxyd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/semaphore"
+)
+
+func TestCapabilityProber(t *testing.T) {
+	// answers the methods it has results for, and "method not found" to the
+	// others
+	node := func(results map[string]string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var req RPCReq
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			if result, ok := results[req.Method]; ok {
+				_, _ = fmt.Fprintf(w, `{"jsonrpc":"2.0","result":%s,"id":%s}`, result, req.ID)
+				return
+			}
+			_, _ = fmt.Fprintf(w, `{"jsonrpc":"2.0","error":{"code":-32601,"message":"method not found"},"id":%s}`, req.ID)
+		}))
+	}
+	geth := node(map[string]string{"rpc_modules": `{"eth":"1.0","debug":"1.0"}`})
+	defer geth.Close()
+	reth := node(map[string]string{"trace_block": `[]`})
+	defer reth.Close()
+
+	sem := semaphore.NewWeighted(10)
+	a := NewBackend("geth", geth.URL, "", sem)
+	b := NewBackend("reth", reth.URL, "", sem)
+	c := NewBackend("down", "http://127.0.0.1:1", "", sem, WithMaxRetries(0))
+	group := &BackendGroup{Name: "main", Backends: []*Backend{a, b, c}}
+
+	// unprobed backends serve everything
+	require.True(t, a.Supports("trace_block"))
+
+	p := NewCapabilityProber(CapabilityProbingConfig{Methods: []string{"trace_block"}}, map[string]*BackendGroup{"main": group})
+	p.update()
+
+	require.True(t, a.Supports("eth_call"))
+	require.True(t, a.Supports("debug_traceTransaction"))
+	require.False(t, a.Supports("trace_block"))
+	require.False(t, a.Supports("txpool_content"))
+	require.True(t, a.Supports(ConsensusGetReceiptsMethod))
+
+	// modules are unknown without rpc_modules
+	require.True(t, b.Supports("txpool_content"))
+	require.True(t, b.Supports("trace_block"))
+
+	// unreachable backends are left as they were
+	require.True(t, c.Supports("trace_block"))
+
+	req := func(method string) *RPCReq { return &RPCReq{Method: method} }
+	require.Equal(t, []*Backend{b, c}, supportingBackends(group.Backends, []*RPCReq{req("eth_call"), req("trace_block")}))
+	require.Equal(t, []*Backend{a, b, c}, supportingBackends(group.Backends, []*RPCReq{req("eth_call")}))
+
+	// if none does, all of them are tried
+	require.Equal(t, []*Backend{a}, supportingBackends([]*Backend{a}, []*RPCReq{req("trace_block")}))
+}
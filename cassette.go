@@ -0,0 +1,242 @@
+package proxyd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	CassetteModeRecord = "record"
+	CassetteModeReplay = "replay"
+)
+
+// Cassette records the responses of backends to disk and replays them, so
+// tests of proxyd's consumers are deterministic and need no live chain. Each
+// response is stored in a file named after the hash of the request it
+// answered, ignoring request IDs, so the same request replays the same
+// response whatever its ID. In replay mode requests never reach the backends
+// and those that weren't recorded fail.
+type Cassette struct {
+	mode string
+	dir  string
+}
+
+// cassetteEntry is a recorded response. IDs are replaced by their index in
+// the request, so they can be mapped to those of a replayed request.
+type cassetteEntry struct {
+	Request  json.RawMessage `json:"request"`
+	Status   int             `json:"status"`
+	Response json.RawMessage `json:"response"`
+}
+
+func NewCassette(cfg CassetteConfig) (*Cassette, error) {
+	switch cfg.Mode {
+	case "":
+		return nil, nil
+	case CassetteModeRecord, CassetteModeReplay:
+	default:
+		return nil, fmt.Errorf("invalid cassette mode %s", cfg.Mode)
+	}
+	if cfg.Dir == "" {
+		return nil, fmt.Errorf("cassette.dir must be set")
+	}
+	log.Warn("using cassette", "mode", cfg.Mode, "dir", cfg.Dir)
+	return &Cassette{mode: cfg.Mode, dir: cfg.Dir}, nil
+}
+
+func (c *Cassette) path(backend string, key string) string {
+	return filepath.Join(c.dir, backend, key+".json")
+}
+
+// normalizeCassetteRequest returns a request body with its IDs replaced by
+// their index, and the IDs.
+func normalizeCassetteRequest(body []byte) (json.RawMessage, []json.RawMessage, error) {
+	batched := IsBatch(body)
+	var reqs []map[string]json.RawMessage
+	if batched {
+		if err := json.Unmarshal(body, &reqs); err != nil {
+			return nil, nil, err
+		}
+	} else {
+		var req map[string]json.RawMessage
+		if err := json.Unmarshal(body, &req); err != nil {
+			return nil, nil, err
+		}
+		reqs = append(reqs, req)
+	}
+	ids := make([]json.RawMessage, len(reqs))
+	for i, req := range reqs {
+		ids[i] = req["id"]
+		req["id"] = json.RawMessage(strconv.Itoa(i))
+	}
+	// maps marshal with sorted keys and RawMessages compacted, so equal
+	// requests normalize the same
+	var normalized []byte
+	var err error
+	if batched {
+		normalized, err = json.Marshal(reqs)
+	} else {
+		normalized, err = json.Marshal(reqs[0])
+	}
+	return normalized, ids, err
+}
+
+// mapCassetteResponseIDs replaces the IDs of a response body using mapID.
+// Bodies that aren't JSON-RPC responses are returned as is.
+func mapCassetteResponseIDs(body []byte, mapID func(json.RawMessage) json.RawMessage) []byte {
+	batched := IsBatch(body)
+	var ress []map[string]json.RawMessage
+	if batched {
+		if err := json.Unmarshal(body, &ress); err != nil {
+			return body
+		}
+	} else {
+		var res map[string]json.RawMessage
+		if err := json.Unmarshal(body, &res); err != nil || res == nil {
+			return body
+		}
+		ress = append(ress, res)
+	}
+	for _, res := range ress {
+		res["id"] = mapID(res["id"])
+	}
+	var out []byte
+	var err error
+	if batched {
+		out, err = json.Marshal(ress)
+	} else {
+		out, err = json.Marshal(ress[0])
+	}
+	if err != nil {
+		return body
+	}
+	return out
+}
+
+// cassetteTransport records or replays a backend's HTTP requests.
+type cassetteTransport struct {
+	next     http.RoundTripper
+	backend  string
+	cassette *Cassette
+}
+
+func (t *cassetteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	normalized, ids, err := normalizeCassetteRequest(body)
+	if err != nil {
+		return nil, fmt.Errorf("cassette can't parse request: %w", err)
+	}
+	sum := sha256.Sum256(normalized)
+	key := hex.EncodeToString(sum[:])
+	path := t.cassette.path(t.backend, key)
+
+	if t.cassette.mode == CassetteModeReplay {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			RecordCassetteRequest(t.backend, "miss")
+			return nil, fmt.Errorf("no recorded response for request %s: %w", key, err)
+		}
+		var entry cassetteEntry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return nil, fmt.Errorf("invalid cassette entry %s: %w", path, err)
+		}
+		RecordCassetteRequest(t.backend, "hit")
+		var raw string
+		if json.Unmarshal(entry.Response, &raw) == nil {
+			entry.Response = json.RawMessage(raw)
+		}
+		resBody := mapCassetteResponseIDs(entry.Response, func(id json.RawMessage) json.RawMessage {
+			if i, err := strconv.Atoi(string(id)); err == nil && i >= 0 && i < len(ids) {
+				return ids[i]
+			}
+			return id
+		})
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", entry.Status, http.StatusText(entry.Status)),
+			StatusCode:    entry.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        http.Header{"Content-Type": []string{"application/json"}},
+			Body:          io.NopCloser(bytes.NewReader(resBody)),
+			ContentLength: int64(len(resBody)),
+			Request:       req,
+		}, nil
+	}
+
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	res, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	resBody, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = io.NopCloser(bytes.NewReader(resBody))
+
+	indexes := make(map[string]int, len(ids))
+	for i, id := range ids {
+		indexes[string(id)] = i
+	}
+	entry := cassetteEntry{
+		Request: normalized,
+		Status:  res.StatusCode,
+		Response: mapCassetteResponseIDs(resBody, func(id json.RawMessage) json.RawMessage {
+			if i, ok := indexes[string(id)]; ok {
+				return json.RawMessage(strconv.Itoa(i))
+			}
+			return id
+		}),
+	}
+	if err := t.cassette.write(path, entry); err != nil {
+		// the response is still good, only the recording failed
+		log.Error("error recording cassette entry", "backend", t.backend, "path", path, "err", err)
+	} else {
+		RecordCassetteRequest(t.backend, "recorded")
+	}
+	return res, nil
+}
+
+func (c *Cassette) write(path string, entry cassetteEntry) error {
+	if !json.Valid(entry.Response) {
+		// keep bodies that aren't JSON, like gateway errors, as strings
+		entry.Response = mustMarshalJSON(string(entry.Response))
+	}
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	// write then rename, so concurrent replays never read half an entry
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".entry-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
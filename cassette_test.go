@@ -0,0 +1,66 @@
+package proxyd
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCassette(t *testing.T) {
+	dir := t.TempDir()
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if IsBatch(body) {
+			// out of order, as backends may answer batches
+			_, _ = w.Write([]byte(`[{"jsonrpc":"2.0","result":"0x2","id":"b"},{"jsonrpc":"2.0","result":"0x1","id":"a"}]`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","result":"0x1","id":7}`))
+	}))
+
+	post := func(c *Cassette, body string) (string, error) {
+		client := &http.Client{Transport: &cassetteTransport{next: http.DefaultTransport, backend: "node", cassette: c}}
+		res, err := client.Post(upstream.URL, "application/json", strings.NewReader(body))
+		if err != nil {
+			return "", err
+		}
+		defer res.Body.Close()
+		out, err := io.ReadAll(res.Body)
+		return string(out), err
+	}
+
+	recorder, err := NewCassette(CassetteConfig{Mode: CassetteModeRecord, Dir: dir})
+	require.NoError(t, err)
+	res, err := post(recorder, `{"jsonrpc":"2.0","method":"eth_chainId","id":7}`)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"jsonrpc":"2.0","result":"0x1","id":7}`, res)
+	_, err = post(recorder, `[{"jsonrpc":"2.0","method":"eth_chainId","id":"a"},{"jsonrpc":"2.0","method":"eth_blockNumber","id":"b"}]`)
+	require.NoError(t, err)
+	entries, err := os.ReadDir(filepath.Join(dir, "node"))
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	upstream.Close()
+	player, err := NewCassette(CassetteConfig{Mode: CassetteModeReplay, Dir: dir})
+	require.NoError(t, err)
+	res, err = post(player, `{"jsonrpc":"2.0","id":"x","method":"eth_chainId"}`)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"jsonrpc":"2.0","result":"0x1","id":"x"}`, res)
+	res, err = post(player, `[{"jsonrpc":"2.0","method":"eth_chainId","id":1},{"jsonrpc":"2.0","method":"eth_blockNumber","id":2}]`)
+	require.NoError(t, err)
+	require.JSONEq(t, `[{"jsonrpc":"2.0","result":"0x2","id":2},{"jsonrpc":"2.0","result":"0x1","id":1}]`, res)
+
+	_, err = post(player, `{"jsonrpc":"2.0","method":"eth_gasPrice","id":1}`)
+	require.ErrorContains(t, err, "no recorded response")
+
+	_, err = NewCassette(CassetteConfig{Mode: "rewind", Dir: dir})
+	require.Error(t, err)
+	_, err = NewCassette(CassetteConfig{Mode: CassetteModeReplay})
+	require.Error(t, err)
+}
@@ -0,0 +1,142 @@
+package proxyd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// ClientFlavor is the execution client a backend runs, whose known
+// differences from geth proxyd smooths over so a group mixing clients
+// presents a uniform API.
+type ClientFlavor string
+
+const (
+	ClientFlavorGeth       ClientFlavor = "geth"
+	ClientFlavorErigon     ClientFlavor = "erigon"
+	ClientFlavorReth       ClientFlavor = "reth"
+	ClientFlavorNethermind ClientFlavor = "nethermind"
+)
+
+// flavorErrorCodes map the error codes of a client to geth's.
+var flavorErrorCodes = map[ClientFlavor]map[int]int{
+	ClientFlavorNethermind: {
+		// execution reverted
+		-32015: 3,
+		// resource not found, e.g. header not found
+		-32001: -32000,
+	},
+}
+
+// nullAsEmptyMethods return lists, which some clients encode as null when
+// empty.
+var nullAsEmptyMethods = map[string]bool{
+	"eth_getLogs":          true,
+	"eth_getFilterLogs":    true,
+	"eth_getFilterChanges": true,
+	"eth_accounts":         true,
+}
+
+// callTracerQuantities are the quantities of callTracer frames, which clients
+// encode with leading zeros or in upper case.
+var callTracerQuantities = []string{"gas", "gasUsed", "value"}
+
+func ParseClientFlavor(s string) (ClientFlavor, error) {
+	switch flavor := ClientFlavor(strings.ToLower(s)); flavor {
+	case "", ClientFlavorGeth, ClientFlavorErigon, ClientFlavorReth, ClientFlavorNethermind:
+		return flavor, nil
+	}
+	return "", fmt.Errorf("invalid client_flavor %q, must be one of geth, erigon, reth or nethermind", s)
+}
+
+func WithClientFlavor(flavor ClientFlavor) BackendOpt {
+	return func(b *Backend) {
+		b.clientFlavor = flavor
+	}
+}
+
+// normalizeResponses rewrites the responses of a backend of the flavor, in
+// place, to what geth would answer.
+func normalizeResponses(flavor ClientFlavor, reqs []*RPCReq, res []*RPCRes) {
+	if flavor == "" || flavor == ClientFlavorGeth {
+		return
+	}
+	for i, rr := range res {
+		if rr == nil {
+			continue
+		}
+		if rr.IsError() {
+			normalizeErrorCode(flavor, rr.Error)
+			continue
+		}
+		if i >= len(reqs) {
+			continue
+		}
+		switch method := reqs[i].Method; {
+		case nullAsEmptyMethods[method]:
+			if rr.Result == nil {
+				rr.Result = emptyArrayResponse
+			}
+		case method == "eth_getBlockByNumber" || method == "eth_getBlockByHash":
+			if block, ok := rr.Result.(map[string]interface{}); ok {
+				for _, field := range []string{"transactions", "uncles"} {
+					if v, ok := block[field]; ok && v == nil {
+						block[field] = emptyArrayResponse
+					}
+				}
+			}
+		case strings.HasPrefix(method, "debug_trace"):
+			normalizeCallFrame(rr.Result)
+		}
+	}
+}
+
+func normalizeErrorCode(flavor ClientFlavor, rpcErr *RPCErr) {
+	if code, ok := flavorErrorCodes[flavor][rpcErr.Code]; ok {
+		rpcErr.Code = code
+	}
+}
+
+// normalizeCallFrame canonicalizes the quantities and addresses of callTracer
+// frames, found anywhere in a debug_trace* result.
+func normalizeCallFrame(v interface{}) {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		if t, ok := val["type"].(string); ok && val["from"] != nil {
+			val["type"] = strings.ToUpper(t)
+			for _, field := range callTracerQuantities {
+				if q, ok := val[field].(string); ok {
+					if n, err := hexutil.DecodeBig(canonicalHex(q)); err == nil {
+						val[field] = hexutil.EncodeBig(n)
+					}
+				}
+			}
+			for _, field := range []string{"from", "to"} {
+				if addr, ok := val[field].(string); ok {
+					val[field] = strings.ToLower(addr)
+				}
+			}
+		}
+		for _, child := range val {
+			normalizeCallFrame(child)
+		}
+	case []interface{}:
+		for _, child := range val {
+			normalizeCallFrame(child)
+		}
+	}
+}
+
+// canonicalHex strips the leading zeros hexutil rejects.
+func canonicalHex(q string) string {
+	q = strings.ToLower(q)
+	if !strings.HasPrefix(q, "0x") {
+		return q
+	}
+	digits := strings.TrimLeft(q[2:], "0")
+	if digits == "" {
+		digits = "0"
+	}
+	return "0x" + digits
+}
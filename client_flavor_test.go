@@ -0,0 +1,71 @@
+package proxyd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeResponses(t *testing.T) {
+	req := func(method string) *RPCReq {
+		return &RPCReq{JSONRPC: "2.0", Method: method, ID: json.RawMessage("1")}
+	}
+	decode := func(s string) interface{} {
+		var v interface{}
+		require.NoError(t, json.Unmarshal([]byte(s), &v))
+		return v
+	}
+
+	reqs := []*RPCReq{
+		req("eth_call"),
+		req("eth_getLogs"),
+		req("eth_getBlockByNumber"),
+		req("debug_traceTransaction"),
+		req("eth_getBalance"),
+	}
+	newRes := func() []*RPCRes {
+		return []*RPCRes{
+			{JSONRPC: "2.0", Error: &RPCErr{Code: -32015, Message: "execution reverted"}, ID: json.RawMessage("1")},
+			{JSONRPC: "2.0", Result: nil, ID: json.RawMessage("1")},
+			{JSONRPC: "2.0", Result: decode(`{"number":"0x1","transactions":null,"uncles":null}`), ID: json.RawMessage("1")},
+			{JSONRPC: "2.0", Result: decode(`{"type":"call","from":"0xAbC","to":"0xDeF","gas":"0x0010","value":"0x","calls":[{"type":"staticcall","from":"0xDeF","gasUsed":"0x00"}]}`), ID: json.RawMessage("1")},
+			{JSONRPC: "2.0", Result: "0x00", ID: json.RawMessage("1")},
+		}
+	}
+
+	// geth is the reference
+	res := newRes()
+	normalizeResponses(ClientFlavorGeth, reqs, res)
+	require.Equal(t, newRes(), res)
+
+	res = newRes()
+	normalizeResponses(ClientFlavorNethermind, reqs, res)
+	require.Equal(t, 3, res[0].Error.Code)
+	require.Equal(t, emptyArrayResponse, res[1].Result)
+	block := res[2].Result.(map[string]interface{})
+	require.Equal(t, emptyArrayResponse, block["transactions"])
+	require.Equal(t, emptyArrayResponse, block["uncles"])
+	trace, err := json.Marshal(res[3].Result)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"type":"CALL","from":"0xabc","to":"0xdef","gas":"0x10","value":"0x0","calls":[{"type":"STATICCALL","from":"0xdef","gasUsed":"0x0"}]}`, string(trace))
+	// other results are left as they are
+	require.Equal(t, "0x00", res[4].Result)
+
+	// error codes are only mapped for the client using them
+	res = newRes()
+	normalizeResponses(ClientFlavorReth, reqs, res)
+	require.Equal(t, -32015, res[0].Error.Code)
+	require.Equal(t, emptyArrayResponse, res[1].Result)
+}
+
+func TestParseClientFlavor(t *testing.T) {
+	flavor, err := ParseClientFlavor("Erigon")
+	require.NoError(t, err)
+	require.Equal(t, ClientFlavorErigon, flavor)
+	flavor, err = ParseClientFlavor("")
+	require.NoError(t, err)
+	require.Equal(t, ClientFlavor(""), flavor)
+	_, err = ParseClientFlavor("besu")
+	require.Error(t, err)
+}
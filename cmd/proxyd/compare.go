@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/ethereum-optimism/infra/proxyd"
+)
+
+// runCompare implements `proxyd compare [flags] <capture file>` and returns
+// the process exit code.
+func runCompare(args []string) int {
+	fs := flag.NewFlagSet("compare", flag.ExitOnError)
+	configPath := fs.String("config", "", "start proxyd with this config to reach the backend groups")
+	groupA := fs.String("a", "", "first backend group to compare")
+	groupB := fs.String("b", "", "second backend group to compare")
+	concurrency := fs.Int("concurrency", 16, "maximum requests in flight")
+	maxDiffs := fs.Int("max-diffs", 100, "maximum differing responses to include in the report")
+	jsonOut := fs.Bool("json", false, "print the report as JSON")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: proxyd compare -config <config file> -a <backend group> -b <backend group> [flags] <capture file>")
+		fmt.Fprintln(fs.Output(), "The capture file holds proxyd JSON logs with server.enable_request_log set, or one JSON-RPC request per line.")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 || *configPath == "" || *groupA == "" || *groupB == "" {
+		fs.Usage()
+		return 2
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return 1
+	}
+	defer f.Close()
+
+	config, _, err := proxyd.LoadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error reading config:", err)
+		return 1
+	}
+	// keep proxyd's logs from burying the report
+	proxyd.SetLogLevel(slog.LevelWarn)
+	srv, shutdown, err := proxyd.Start(config)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error starting proxyd:", err)
+		return 1
+	}
+	defer shutdown()
+	a, b := srv.BackendGroups[*groupA], srv.BackendGroups[*groupB]
+	if a == nil || b == nil {
+		fmt.Fprintln(os.Stderr, "error: -a and -b must be backend groups of the config")
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	report, err := proxyd.Compare(ctx, f, proxyd.CompareOptions{
+		A:           a,
+		B:           b,
+		NameA:       *groupA,
+		NameB:       *groupB,
+		Concurrency: *concurrency,
+		MaxDiffs:    *maxDiffs,
+	})
+	if report != nil {
+		if *jsonOut {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			_ = enc.Encode(report)
+		} else {
+			printCompareReport(report)
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return 1
+	}
+	return 0
+}
+
+func printCompareReport(report *proxyd.CompareReport) {
+	fmt.Printf("requests:    %d\n", report.Requests)
+	fmt.Printf("skipped:     %d\n", report.Skipped)
+	fmt.Printf("differences: %d\n", report.Differences)
+	for _, side := range []*proxyd.CompareSide{report.A, report.B} {
+		fmt.Printf("%s: errors %d, latency p50 %s, p95 %s, p99 %s\n", side.Name, side.Errors, side.P50, side.P95, side.P99)
+	}
+
+	methods := make([]string, 0, len(report.Methods))
+	for method := range report.Methods {
+		methods = append(methods, method)
+	}
+	// most divergent first
+	sort.Slice(methods, func(i, j int) bool {
+		mi, mj := report.Methods[methods[i]], report.Methods[methods[j]]
+		if mi.Differences != mj.Differences {
+			return mi.Differences > mj.Differences
+		}
+		return methods[i] < methods[j]
+	})
+	fmt.Println()
+	fmt.Printf("%-40s %10s %12s %14s %14s\n", "method", "requests", "differences", "avg latency a", "avg latency b")
+	for _, method := range methods {
+		stats := report.Methods[method]
+		fmt.Printf("%-40s %10d %12d %14s %14s\n", method, stats.Requests, stats.Differences, stats.LatencyA/time.Duration(stats.Requests), stats.LatencyB/time.Duration(stats.Requests))
+	}
+
+	for _, diff := range report.Diffs {
+		fmt.Println()
+		fmt.Printf("request: %s\n", diff.Request)
+		fmt.Printf("  %s (%s): %s\n", report.A.Name, diff.LatencyA, diff.A)
+		fmt.Printf("  %s (%s): %s\n", report.B.Name, diff.LatencyB, diff.B)
+	}
+}
@@ -12,8 +12,6 @@ import (
 	"strings"
 	"syscall"
 
-	"github.com/BurntSushi/toml"
-
 	"github.com/ethereum/go-ethereum/log"
 
 	"github.com/ethereum-optimism/infra/proxyd"
@@ -26,6 +24,22 @@ var (
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "validate" {
+		os.Exit(runValidate(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		os.Exit(runReplay(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "compare" {
+		os.Exit(runCompare(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "mock" {
+		os.Exit(runMock(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "migrate-config" {
+		os.Exit(runMigrateConfig(os.Args[2:]))
+	}
+
 	// Set up logger with a default INFO level in case we fail to parse flags.
 	// Otherwise the final critical log won't show what the parsing error was.
 	proxyd.SetLogLevel(slog.LevelInfo)
@@ -33,13 +47,26 @@ func main() {
 	log.Info("starting proxyd", "version", GitVersion, "commit", GitCommit, "date", GitDate)
 
 	if len(os.Args) < 2 {
-		log.Crit("must specify a config file on the command line")
+		log.Crit("must specify config files or directories on the command line, validate <config file> to check them, replay <capture file> to replay requests, compare <capture file> to compare backend groups, mock <scenario file> to serve a mock backend, or migrate-config <config file> to upgrade a config")
 	}
 
-	config := new(proxyd.Config)
-	if _, err := toml.DecodeFile(os.Args[1], config); err != nil {
+	config, unknown, err := proxyd.LoadConfig(os.Args[1:]...)
+	if err != nil {
 		log.Crit("error reading config file", "err", err)
 	}
+	for _, key := range unknown {
+		log.Warn("unknown config key", "key", key)
+	}
+	deprecated := proxyd.DeprecatedConfigKeys(config)
+	for _, d := range deprecated {
+		log.Warn("deprecated config key", "key", d.Key, "replacement", d.Replacement)
+	}
+	if len(deprecated) > 0 {
+		log.Warn("run proxyd migrate-config to upgrade the config", "config_version", max(config.ConfigVersion, 1), "current_version", proxyd.ConfigSchemaVersion)
+	}
+	for _, w := range proxyd.ConfigWarnings(config) {
+		log.Warn(w)
+	}
 
 	// update log level from config
 	logLevel, err := LevelFromString(config.Server.LogLevel)
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/ethereum-optimism/infra/proxyd"
+)
+
+// runMigrateConfig implements `proxyd migrate-config [flags] <config.toml>...`
+// and returns the process exit code.
+func runMigrateConfig(args []string) int {
+	fs := flag.NewFlagSet("migrate-config", flag.ExitOnError)
+	write := fs.Bool("w", false, "write the upgraded config back to the file instead of printing it")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: proxyd migrate-config [flags] <config.toml>...\n")
+		fmt.Fprintf(fs.Output(), "upgrades config files to config_version %d. Comments aren't preserved.\n", proxyd.ConfigSchemaVersion)
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() == 0 || (fs.NArg() > 1 && !*write) {
+		fs.Usage()
+		return 2
+	}
+
+	for _, path := range fs.Args() {
+		raw := make(map[string]interface{})
+		if _, err := toml.DecodeFile(path, &raw); err != nil {
+			fmt.Fprintf(os.Stderr, "error reading %s: %v\n", path, err)
+			return 1
+		}
+		changes, err := proxyd.MigrateConfig(raw)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error migrating %s: %v\n", path, err)
+			return 1
+		}
+		for _, change := range changes {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", path, change)
+		}
+
+		var buf bytes.Buffer
+		if err := toml.NewEncoder(&buf).Encode(raw); err != nil {
+			fmt.Fprintf(os.Stderr, "error encoding %s: %v\n", path, err)
+			return 1
+		}
+		if !*write {
+			_, _ = os.Stdout.Write(buf.Bytes())
+			continue
+		}
+		if len(changes) == 0 {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error writing %s: %v\n", path, err)
+			return 1
+		}
+		if err := os.WriteFile(path, buf.Bytes(), info.Mode().Perm()); err != nil {
+			fmt.Fprintf(os.Stderr, "error writing %s: %v\n", path, err)
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/ethereum-optimism/infra/proxyd/pkg/mock"
+)
+
+// runMock implements `proxyd mock [flags] <scenario file>` and returns the
+// process exit code.
+func runMock(args []string) int {
+	fs := flag.NewFlagSet("mock", flag.ExitOnError)
+	addr := fs.String("addr", "127.0.0.1:8645", "address to serve the mock backend on")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: proxyd mock [flags] <scenario file>")
+		fmt.Fprintln(fs.Output(), "The scenario file is a YAML list of rules scripting the responses to each method.")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	scenario, err := mock.LoadScenario(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return 1
+	}
+
+	srv := &http.Server{Addr: *addr, Handler: scenario}
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		_ = srv.Close()
+	}()
+	fmt.Printf("serving mock backend on %s\n", *addr)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return 1
+	}
+	return 0
+}
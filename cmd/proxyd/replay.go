@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/ethereum-optimism/infra/proxyd"
+)
+
+// runReplay implements `proxyd replay [flags] <capture file>` and returns the
+// process exit code.
+func runReplay(args []string) int {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	configPath := fs.String("config", "", "start proxyd with this config and replay against it")
+	target := fs.String("target", "", "RPC URL to replay against, defaults to the rpc_host and rpc_port of -config")
+	baseline := fs.String("baseline", "", "RPC URL to compare the target's responses with")
+	speed := fs.Float64("speed", 1, "replay speed relative to the captured timing, 0 to send as fast as possible")
+	concurrency := fs.Int("concurrency", 16, "maximum requests in flight")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: proxyd replay [flags] <capture file>")
+		fmt.Fprintln(fs.Output(), "The capture file holds proxyd JSON logs with server.enable_request_log set, or one JSON-RPC request per line.")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 || (*configPath == "" && *target == "") {
+		fs.Usage()
+		return 2
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return 1
+	}
+	defer f.Close()
+
+	if *configPath != "" {
+		config, _, err := proxyd.LoadConfig(*configPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "error reading config:", err)
+			return 1
+		}
+		// keep proxyd's logs from burying the report
+		proxyd.SetLogLevel(slog.LevelWarn)
+		_, shutdown, err := proxyd.Start(config)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "error starting proxyd:", err)
+			return 1
+		}
+		defer shutdown()
+		if *target == "" {
+			host := config.Server.RPCHost
+			if host == "" || host == "0.0.0.0" {
+				host = "127.0.0.1"
+			}
+			*target = fmt.Sprintf("http://%s:%d", host, config.Server.RPCPort)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	report, err := proxyd.Replay(ctx, f, proxyd.ReplayOptions{
+		Target:      *target,
+		Baseline:    *baseline,
+		Speed:       *speed,
+		Concurrency: *concurrency,
+	})
+	if report != nil {
+		fmt.Printf("requests:  %d\n", report.Requests)
+		fmt.Printf("skipped:   %d\n", report.Skipped)
+		fmt.Printf("errors:    %d\n", report.Errors)
+		if *baseline != "" {
+			fmt.Printf("divergent: %d\n", report.Divergent)
+		}
+		fmt.Printf("latency:   p50 %s, p95 %s, p99 %s\n", report.Percentile(50), report.Percentile(95), report.Percentile(99))
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return 1
+	}
+	return 0
+}
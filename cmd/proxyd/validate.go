@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/ethereum-optimism/infra/proxyd"
+)
+
+// runValidate implements `proxyd validate [flags] <config.toml>...` and returns
+// the process exit code.
+func runValidate(args []string) int {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	checkBackends := fs.Bool("check-backends", false, "check that every backend accepts connections")
+	strict := fs.Bool("strict", false, "treat unknown config keys as errors")
+	quiet := fs.Bool("quiet", false, "don't print the effective configuration")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: proxyd validate [flags] <config.toml|conf.d>...")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	config, unknown, errs := proxyd.ValidateConfigFiles(fs.Args(), *checkBackends)
+	for _, key := range unknown {
+		if *strict {
+			errs = append(errs, fmt.Errorf("unknown config key %s", key))
+		} else {
+			fmt.Fprintln(os.Stderr, "warning: unknown config key", key)
+		}
+	}
+	if config != nil {
+		for _, d := range proxyd.DeprecatedConfigKeys(config) {
+			fmt.Fprintln(os.Stderr, "warning:", d)
+		}
+		for _, w := range proxyd.ConfigWarnings(config) {
+			fmt.Fprintln(os.Stderr, "warning:", w)
+		}
+	}
+	for _, err := range errs {
+		fmt.Fprintln(os.Stderr, "error:", err)
+	}
+	name := strings.Join(fs.Args(), ", ")
+	if len(errs) > 0 {
+		fmt.Fprintf(os.Stderr, "%s is invalid: %d error(s)\n", name, len(errs))
+		return 1
+	}
+
+	if !*quiet {
+		if err := toml.NewEncoder(os.Stdout).Encode(proxyd.RedactConfig(config)); err != nil {
+			fmt.Fprintln(os.Stderr, "error printing config:", err)
+			return 1
+		}
+	}
+	fmt.Fprintf(os.Stderr, "%s is valid\n", name)
+	return 0
+}
@@ -0,0 +1,223 @@
+package proxyd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+const defaultCompareMaxDiffs = 100
+
+// CompareTarget forwards requests to the nodes being compared, usually a
+// *BackendGroup.
+type CompareTarget interface {
+	Forward(ctx context.Context, rpcReqs []*RPCReq, isBatch bool) ([]*RPCRes, string, error)
+}
+
+// CompareOptions configures a comparison of two backend groups.
+type CompareOptions struct {
+	A, B CompareTarget
+	// NameA and NameB label A and B in the report.
+	NameA, NameB string
+	Concurrency  int
+	// MaxDiffs caps the differences kept in the report, 100 by default.
+	// They're all counted regardless.
+	MaxDiffs int
+}
+
+// CompareReport summarizes a comparison. It marshals to JSON.
+type CompareReport struct {
+	Requests    int                            `json:"requests"`
+	Skipped     int                            `json:"skipped"`
+	Differences int                            `json:"differences"`
+	A           *CompareSide                   `json:"a"`
+	B           *CompareSide                   `json:"b"`
+	Methods     map[string]*CompareMethodStats `json:"methods"`
+	Diffs       []*CompareDiff                 `json:"diffs"`
+}
+
+// CompareSide sums up the responses of one of the compared groups.
+type CompareSide struct {
+	Name   string `json:"name"`
+	Errors int    `json:"errors"`
+	// Latencies of the requests answered, sorted.
+	Latencies []time.Duration `json:"-"`
+	P50       time.Duration   `json:"p50_ns"`
+	P95       time.Duration   `json:"p95_ns"`
+	P99       time.Duration   `json:"p99_ns"`
+}
+
+type CompareMethodStats struct {
+	Requests    int           `json:"requests"`
+	Differences int           `json:"differences"`
+	LatencyA    time.Duration `json:"total_latency_a_ns"`
+	LatencyB    time.Duration `json:"total_latency_b_ns"`
+}
+
+// CompareDiff is a request the groups answered differently. The responses
+// hold the result or error of each, or the error forwarding to it.
+type CompareDiff struct {
+	Request  json.RawMessage `json:"request"`
+	A        json.RawMessage `json:"a"`
+	B        json.RawMessage `json:"b"`
+	LatencyA time.Duration   `json:"latency_a_ns"`
+	LatencyB time.Duration   `json:"latency_b_ns"`
+}
+
+type compareResult struct {
+	res     json.RawMessage
+	err     error
+	latency time.Duration
+}
+
+// Compare sends each request captured in r, in the formats Replay reads, to
+// both A and B and reports the responses that differ and the latency of
+// each. The requests of batches are compared one by one, and request IDs are
+// ignored.
+func Compare(ctx context.Context, r io.Reader, opts CompareOptions) (*CompareReport, error) {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = defaultReplayConcurrency
+	}
+	if opts.MaxDiffs <= 0 {
+		opts.MaxDiffs = defaultCompareMaxDiffs
+	}
+
+	report := &CompareReport{
+		A:       &CompareSide{Name: opts.NameA},
+		B:       &CompareSide{Name: opts.NameB},
+		Methods: make(map[string]*CompareMethodStats),
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, opts.Concurrency)
+
+	record := func(raw json.RawMessage, method string, a, b compareResult) {
+		mu.Lock()
+		defer mu.Unlock()
+		stats := report.Methods[method]
+		if stats == nil {
+			stats = &CompareMethodStats{}
+			report.Methods[method] = stats
+		}
+		stats.Requests++
+		for _, side := range []struct {
+			side   *CompareSide
+			result compareResult
+		}{{report.A, a}, {report.B, b}} {
+			if side.result.err != nil {
+				side.side.Errors++
+			} else {
+				side.side.Latencies = append(side.side.Latencies, side.result.latency)
+			}
+		}
+		stats.LatencyA += a.latency
+		stats.LatencyB += b.latency
+		if equalJSON(a.res, b.res) {
+			return
+		}
+		report.Differences++
+		stats.Differences++
+		if len(report.Diffs) < opts.MaxDiffs {
+			report.Diffs = append(report.Diffs, &CompareDiff{
+				Request:  raw,
+				A:        a.res,
+				B:        b.res,
+				LatencyA: a.latency,
+				LatencyB: b.latency,
+			})
+		}
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxReplayLineSize)
+scan:
+	for scanner.Scan() {
+		captured, ok := parseCapturedRequest(scanner.Bytes())
+		if !ok {
+			report.Skipped++
+			continue
+		}
+		raws := []json.RawMessage{captured.body}
+		if IsBatch(captured.body) {
+			var err error
+			if raws, err = ParseBatchRPCReq(captured.body); err != nil {
+				report.Skipped++
+				continue
+			}
+		}
+		for _, raw := range raws {
+			req, err := ParseRPCReq(raw)
+			if err != nil {
+				report.Skipped++
+				continue
+			}
+			report.Requests++
+
+			select {
+			case <-ctx.Done():
+				break scan
+			case sem <- struct{}{}:
+			}
+			wg.Add(1)
+			go func(raw json.RawMessage, req *RPCReq) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				var a, b compareResult
+				var sides sync.WaitGroup
+				sides.Add(2)
+				go func() {
+					defer sides.Done()
+					a = compareForward(ctx, opts.A, req)
+				}()
+				go func() {
+					defer sides.Done()
+					b = compareForward(ctx, opts.B, req)
+				}()
+				sides.Wait()
+				record(raw, req.Method, a, b)
+			}(raw, req)
+		}
+	}
+	wg.Wait()
+
+	for _, side := range []*CompareSide{report.A, report.B} {
+		sort.Slice(side.Latencies, func(i, j int) bool {
+			return side.Latencies[i] < side.Latencies[j]
+		})
+		side.P50 = percentile(side.Latencies, 50)
+		side.P95 = percentile(side.Latencies, 95)
+		side.P99 = percentile(side.Latencies, 99)
+	}
+	if err := ctx.Err(); err != nil {
+		return report, err
+	}
+	return report, scanner.Err()
+}
+
+// compareForward returns what target answers req with, without the ID: its
+// result or error, or the error forwarding to it.
+func compareForward(ctx context.Context, target CompareTarget, req *RPCReq) compareResult {
+	start := time.Now()
+	ress, _, err := target.Forward(ctx, []*RPCReq{req}, false)
+	latency := time.Since(start)
+	if err == nil && len(ress) != 1 {
+		err = ErrBackendUnexpectedJSONRPC
+	}
+	if err != nil {
+		return compareResult{
+			res:     mustMarshalJSON(map[string]string{"forward_error": err.Error()}),
+			err:     err,
+			latency: latency,
+		}
+	}
+	res := ress[0]
+	out := map[string]interface{}{"result": res.Result}
+	if res.Error != nil {
+		out = map[string]interface{}{"error": res.Error}
+	}
+	return compareResult{res: mustMarshalJSON(out), latency: latency}
+}
@@ -0,0 +1,89 @@
+package proxyd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type compareTargetFunc func(req *RPCReq) (*RPCRes, error)
+
+func (f compareTargetFunc) Forward(ctx context.Context, rpcReqs []*RPCReq, isBatch bool) ([]*RPCRes, string, error) {
+	res, err := f(rpcReqs[0])
+	if err != nil {
+		return nil, "", err
+	}
+	return []*RPCRes{res}, "", nil
+}
+
+func TestCompare(t *testing.T) {
+	geth := compareTargetFunc(func(req *RPCReq) (*RPCRes, error) {
+		switch req.Method {
+		case "eth_chainId":
+			return &RPCRes{JSONRPC: JSONRPCVersion, Result: "0x1", ID: req.ID}, nil
+		case "eth_getLogs":
+			return &RPCRes{JSONRPC: JSONRPCVersion, Error: &RPCErr{Code: -32005, Message: "query returned more than 10000 results"}, ID: req.ID}, nil
+		}
+		return &RPCRes{JSONRPC: JSONRPCVersion, Result: json.RawMessage(`{"a":1,"b":2}`), ID: req.ID}, nil
+	})
+	reth := compareTargetFunc(func(req *RPCReq) (*RPCRes, error) {
+		switch req.Method {
+		case "eth_chainId":
+			// IDs don't matter
+			return &RPCRes{JSONRPC: JSONRPCVersion, Result: "0x1", ID: json.RawMessage(`"other"`)}, nil
+		case "eth_getLogs":
+			return &RPCRes{JSONRPC: JSONRPCVersion, Error: &RPCErr{Code: -32602, Message: "query exceeds max results 10000"}, ID: req.ID}, nil
+		case "eth_syncing":
+			return nil, errors.New("connection refused")
+		}
+		return &RPCRes{JSONRPC: JSONRPCVersion, Result: json.RawMessage(`{"b":2,"a":1}`), ID: req.ID}, nil
+	})
+
+	capture := strings.Join([]string{
+		`{"jsonrpc":"2.0","method":"eth_chainId","params":[],"id":1}`,
+		`[{"jsonrpc":"2.0","method":"eth_getLogs","params":[{}],"id":1},{"jsonrpc":"2.0","method":"eth_getBlockByNumber","params":["0x1",false],"id":2}]`,
+		`{"jsonrpc":"2.0","method":"eth_syncing","params":[],"id":1}`,
+		`{"level":"INFO","msg":"started proxyd"}`,
+	}, "\n")
+
+	report, err := Compare(context.Background(), strings.NewReader(capture), CompareOptions{
+		A:     geth,
+		B:     reth,
+		NameA: "geth",
+		NameB: "reth",
+	})
+	require.NoError(t, err)
+	require.Equal(t, 4, report.Requests)
+	require.Equal(t, 1, report.Skipped)
+	require.Equal(t, 2, report.Differences)
+	require.Equal(t, 0, report.A.Errors)
+	require.Equal(t, 1, report.B.Errors)
+	require.Len(t, report.A.Latencies, 4)
+	require.Len(t, report.B.Latencies, 3)
+	require.Equal(t, 1, report.Methods["eth_getLogs"].Differences)
+	require.Equal(t, 0, report.Methods["eth_getBlockByNumber"].Differences)
+	require.Equal(t, 1, report.Methods["eth_syncing"].Differences)
+	require.Len(t, report.Diffs, 2)
+
+	var diff *CompareDiff
+	for _, d := range report.Diffs {
+		if strings.Contains(string(d.Request), "eth_getLogs") {
+			diff = d
+		}
+	}
+	require.NotNil(t, diff)
+	require.JSONEq(t, `{"error":{"code":-32005,"message":"query returned more than 10000 results"}}`, string(diff.A))
+	require.JSONEq(t, `{"error":{"code":-32602,"message":"query exceeds max results 10000"}}`, string(diff.B))
+
+	report, err = Compare(context.Background(), strings.NewReader(capture), CompareOptions{A: geth, B: reth, MaxDiffs: 1})
+	require.NoError(t, err)
+	require.Equal(t, 2, report.Differences)
+	require.Len(t, report.Diffs, 1)
+
+	_, err = json.Marshal(report)
+	require.NoError(t, err)
+}
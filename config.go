@@ -11,18 +11,31 @@ import (
 )
 
 type ServerConfig struct {
-	RPCHost           string `toml:"rpc_host"`
-	RPCPort           int    `toml:"rpc_port"`
-	WSHost            string `toml:"ws_host"`
-	WSPort            int    `toml:"ws_port"`
-	MaxBodySizeBytes  int64  `toml:"max_body_size_bytes"`
-	MaxConcurrentRPCs int64  `toml:"max_concurrent_rpcs"`
+	RPCHost string `toml:"rpc_host"`
+	RPCPort int    `toml:"rpc_port"`
+	WSHost  string `toml:"ws_host"`
+	WSPort  int    `toml:"ws_port"`
+	// RPCUnixSocket and WSUnixSocket make the servers listen on a Unix domain
+	// socket instead of host/port. UnixSocketMode is an octal permission string, e.g. "0660".
+	RPCUnixSocket  string `toml:"rpc_unix_socket"`
+	WSUnixSocket   string `toml:"ws_unix_socket"`
+	UnixSocketMode string `toml:"unix_socket_mode"`
+	// SystemdSocketActivation serves on the sockets passed by systemd, matched by
+	// FileDescriptorName= "rpc" and "ws". These take precedence over all other listener settings.
+	SystemdSocketActivation bool `toml:"systemd_socket_activation"`
+
+	MaxBodySizeBytes  int64 `toml:"max_body_size_bytes"`
+	MaxConcurrentRPCs int64 `toml:"max_concurrent_rpcs"`
 	// DisableConcurrentRequestSemaphore=true allows unlimited concurrent RPC requests. This takes precedence over MaxConcurrentRPCs.
 	DisableConcurrentRequestSemaphore bool   `toml:"disable_concurrent_request_semaphore"`
 	LogLevel                          string `toml:"log_level"`
 
 	// TimeoutSeconds specifies the maximum time spent serving an HTTP request. Note that isn't used for websocket connections
 	TimeoutSeconds int `toml:"timeout_seconds"`
+	// MethodTimeouts overrides TimeoutSeconds, and the backends' response
+	// timeout, for methods or method prefixes ending in "*", e.g.
+	// "debug_*" = "120s". Batches get the longest timeout of their methods.
+	MethodTimeouts map[string]TOMLDuration `toml:"method_timeouts"`
 
 	MaxUpstreamBatchSize int `toml:"max_upstream_batch_size"`
 
@@ -31,12 +44,66 @@ type ServerConfig struct {
 	EnablePprof           bool `toml:"enable_pprof"`
 	EnableXServedByHeader bool `toml:"enable_served_by_header"`
 	AllowAllOrigins       bool `toml:"allow_all_origins"`
+
+	// TLSCertFile and TLSKeyFile make proxyd terminate TLS on the RPC and WS
+	// listeners. Virtual hosts can bring their own certificates.
+	TLSCertFile string `toml:"tls_cert_file"`
+	TLSKeyFile  string `toml:"tls_key_file"`
+
+	// ReadHeaderTimeout bounds how long clients may take to send a request's
+	// headers. Defaults to 10s.
+	ReadHeaderTimeout TOMLDuration `toml:"read_header_timeout"`
+	// MaxHeaderBytes bounds the size of a request's headers. Defaults to 1MB.
+	MaxHeaderBytes int `toml:"max_header_bytes"`
+	// MaxHeaderCount bounds the number of header values of a request.
+	MaxHeaderCount int `toml:"max_header_count"`
+	// MinBodyBytesPerSecond is the average rate request bodies must be sent
+	// at, after a 2s grace period.
+	MinBodyBytesPerSecond int `toml:"min_body_bytes_per_second"`
+	// MaxConnsPerIP bounds the open connections of each client IP, as seen by
+	// proxyd rather than in X-Forwarded-For.
+	MaxConnsPerIP int `toml:"max_conns_per_ip"`
 }
 
 type CacheConfig struct {
-	Enabled       bool         `toml:"enabled"`
-	UseInmemCache bool         `toml:"use_inmem_cache"`
-	TTL           TOMLDuration `toml:"ttl"`
+	Enabled       bool                 `toml:"enabled"`
+	UseInmemCache bool                 `toml:"use_inmem_cache"`
+	TTL           TOMLDuration         `toml:"ttl"`
+	Immutable     ImmutableCacheConfig `toml:"immutable"`
+	Warming       CacheWarmingConfig   `toml:"warming"`
+}
+
+// CacheWarmingConfig fetches what clients poll on every block into Redis on
+// new heads, see CacheWarmer.
+type CacheWarmingConfig struct {
+	Enabled bool `toml:"enabled"`
+	// BackendGroup is the consensus aware group whose heads are warmed.
+	BackendGroup string `toml:"backend_group"`
+	// Requests are warmed at every head. Defaults to eth_getBlockByNumber
+	// with and without transactions, eth_getBlockReceipts and eth_feeHistory
+	// of the last 5 blocks, at "latest".
+	Requests []CacheWarmingRequestConfig `toml:"requests"`
+	// PollInterval is how often the group is checked for a new head.
+	// Defaults to 250ms.
+	PollInterval TOMLDuration `toml:"poll_interval"`
+	// TTL is how long warmed responses are kept. Defaults to 1m.
+	TTL TOMLDuration `toml:"ttl"`
+}
+
+type CacheWarmingRequestConfig struct {
+	Method string `toml:"method"`
+	// Params is the JSON array of params, using the "latest" tag for the
+	// head, e.g. ["0x5","latest",[25,75]].
+	Params string `toml:"params"`
+}
+
+// ImmutableCacheConfig caches the responses that can never change in memory,
+// without TTL. See immutableRPCCache.
+type ImmutableCacheConfig struct {
+	Enabled bool `toml:"enabled"`
+	// MaxEntries bounds the entries kept, evicting the least recently used.
+	// Defaults to 10000.
+	MaxEntries int `toml:"max_entries"`
 }
 
 type RedisConfig struct {
@@ -50,6 +117,49 @@ type RedisConfig struct {
 	ReadURL          string `toml:"read_url"`
 	FallbackToMemory bool   `toml:"fallback_to_memory"`
 	RedisCluster     bool   `toml:"redis_cluster"`
+	// FallbackRateLimitDivisor divides the limits of the in-memory rate
+	// limiters standing in for the Redis ones while Redis is down, without
+	// fallback_to_memory. Typically the number of instances. Defaults to 2.
+	FallbackRateLimitDivisor int `toml:"fallback_rate_limit_divisor"`
+
+	// If `sentinel_master_name` is set, the master of that name is discovered
+	// through the Redis Sentinels at SentinelAddrs, and the url only provides
+	// the credentials and database, e.g. "redis://:<password>@mymaster/0".
+	SentinelMasterName string   `toml:"sentinel_master_name"`
+	SentinelAddrs      []string `toml:"sentinel_addrs"`
+	SentinelPassword   string   `toml:"sentinel_password"`
+
+	// TLS is used with rediss:// urls, or with any url if set.
+	TLSCAFile             string `toml:"tls_ca_file"`
+	TLSCertFile           string `toml:"tls_cert_file"`
+	TLSKeyFile            string `toml:"tls_key_file"`
+	TLSInsecureSkipVerify bool   `toml:"tls_insecure_skip_verify"`
+
+	PoolSize     int          `toml:"pool_size"`
+	MinIdleConns int          `toml:"min_idle_conns"`
+	PoolTimeout  TOMLDuration `toml:"pool_timeout"`
+	DialTimeout  TOMLDuration `toml:"dial_timeout"`
+	ReadTimeout  TOMLDuration `toml:"read_timeout"`
+	WriteTimeout TOMLDuration `toml:"write_timeout"`
+	// OperationTimeout bounds each command, including waiting for a
+	// connection and retries.
+	OperationTimeout TOMLDuration `toml:"operation_timeout"`
+
+	DegradedMode RedisDegradedModeConfig `toml:"degraded_mode"`
+}
+
+// RedisDegradedModeConfig sets what happens while Redis is unavailable,
+// either "fail_open" or "fail_closed". Redis is considered unavailable
+// from a failed health check until one succeeds.
+type RedisDegradedModeConfig struct {
+	// Cache defaults to fail_open, serving requests from the backends.
+	// fail_closed rejects requests that would've been looked up in the cache.
+	Cache string `toml:"cache"`
+	// SenderLimits defaults to fail_closed, rejecting transactions that can't
+	// be counted against sender_rate_limit. fail_open lets them through.
+	SenderLimits string `toml:"sender_limits"`
+	// HealthCheckInterval defaults to 5s.
+	HealthCheckInterval TOMLDuration `toml:"health_check_interval"`
 }
 
 type MetricsConfig struct {
@@ -87,6 +197,10 @@ func (t *TOMLDuration) UnmarshalText(b []byte) error {
 	return nil
 }
 
+func (t TOMLDuration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(t).String()), nil
+}
+
 type BackendOptions struct {
 	// Deprecated: Use ResponseTimeoutMilliseconds instead. Note this field will be overridden if `ResponseTimeoutMilliseconds` is also set.
 	ResponseTimeoutSeconds      int          `toml:"response_timeout_seconds"`
@@ -100,19 +214,22 @@ type BackendOptions struct {
 }
 
 type BackendConfig struct {
-	Username              string            `toml:"username"`
-	Password              string            `toml:"password"`
-	RPCURL                string            `toml:"rpc_url"`
-	WSURL                 string            `toml:"ws_url"`
-	WSPort                int               `toml:"ws_port"`
-	MaxRPS                int               `toml:"max_rps"`
-	MaxWSConns            int               `toml:"max_ws_conns"`
-	CAFile                string            `toml:"ca_file"`
-	ClientCertFile        string            `toml:"client_cert_file"`
-	ClientKeyFile         string            `toml:"client_key_file"`
-	StripTrailingXFF      bool              `toml:"strip_trailing_xff"`
-	AllowedDynamicHeaders []string          `toml:"allowed_dynamic_headers"`
-	Headers               map[string]string `toml:"headers"`
+	Username         string `toml:"username"`
+	Password         string `toml:"password"`
+	RPCURL           string `toml:"rpc_url"`
+	IPCPath          string `toml:"ipc_path"`
+	WSURL            string `toml:"ws_url"`
+	WSPort           int    `toml:"ws_port"`
+	MaxRPS           int    `toml:"max_rps"`
+	MaxWSConns       int    `toml:"max_ws_conns"`
+	CAFile           string `toml:"ca_file"`
+	ClientCertFile   string `toml:"client_cert_file"`
+	ClientKeyFile    string `toml:"client_key_file"`
+	StripTrailingXFF bool   `toml:"strip_trailing_xff"`
+	// Deprecated: Use HeaderPolicy.Forward instead. Entries are merged into it.
+	AllowedDynamicHeaders []string           `toml:"allowed_dynamic_headers"`
+	HeaderPolicy          HeaderPolicyConfig `toml:"header_policy"`
+	Headers               map[string]string  `toml:"headers"`
 
 	Weight int `toml:"weight"`
 
@@ -126,6 +243,41 @@ type BackendConfig struct {
 	ConsensusSkipPeerCountCheck bool   `toml:"consensus_skip_peer_count"`
 	ConsensusForcedCandidate    bool   `toml:"consensus_forced_candidate"`
 	ConsensusReceiptsTarget     string `toml:"consensus_receipts_target"`
+
+	// ClientFlavor is the execution client of the backend: geth, erigon,
+	// reth or nethermind. Responses of clients other than geth are
+	// normalized to what geth would answer.
+	ClientFlavor string `toml:"client_flavor"`
+
+	// ConductorURL is the op-conductor RPC of a sequencer, and LeaderURL a
+	// generic endpoint answering 200 only on the leader. Either makes writes
+	// to the backend's groups go to the current leader, see LeaderTracker.
+	ConductorURL string `toml:"conductor_url"`
+	LeaderURL    string `toml:"leader_url"`
+
+	// RollupRPCURL is the rollup RPC of the op-node of an OP Stack backend,
+	// whose sync status is checked against L1, see DerivationMonitor.
+	RollupRPCURL string `toml:"rollup_rpc_url"`
+
+	Maintenance []MaintenanceWindowConfig `toml:"maintenance"`
+}
+
+// MaintenanceWindowConfig is a recurring window during which a backend is
+// excluded from routing.
+type MaintenanceWindowConfig struct {
+	// Schedule is a five field cron expression, in UTC, for the start of
+	// the window.
+	Schedule string       `toml:"schedule"`
+	Duration TOMLDuration `toml:"duration"`
+}
+
+// HeaderPolicyConfig configures which client request headers are passed on
+// to a backend. Hop-by-hop headers are never forwarded, and a "*" forward
+// entry passes on everything except credentials and headers proxyd sets itself.
+type HeaderPolicyConfig struct {
+	Forward []string          `toml:"forward"`
+	Strip   []string          `toml:"strip"`
+	Rename  map[string]string `toml:"rename"`
 }
 
 type BackendsConfig map[string]*BackendConfig
@@ -176,6 +328,24 @@ type BackendGroupConfig struct {
 
 	MulticallRPCErrorCheck bool `toml:"multicall_rpc_error_check"`
 
+	// ReceiptAggregation asks the other backends of the group for receipts
+	// the serving backend returns null for.
+	ReceiptAggregation bool `toml:"receipt_aggregation"`
+
+	// BlockReceiptsMinBatch serves batches with at least this many
+	// eth_getTransactionReceipt requests for the same block with one
+	// eth_getBlockReceipts request. Disabled if 0.
+	BlockReceiptsMinBatch int `toml:"block_receipts_min_batch"`
+
+	// RollupConsensus serves optimism_outputAtBlock and optimism_syncStatus
+	// from every backend of the group, see forwardRollupConsensus.
+	RollupConsensus bool `toml:"rollup_consensus"`
+
+	Inflight InflightConfig `toml:"inflight"`
+	// LatestTranslation keeps backends of groups without consensus that lag
+	// the group head from serving a stale tip as latest, see HeadTracker.
+	LatestTranslation *LatestTranslationConfig `toml:"latest_translation"`
+
 	/*
 		Deprecated: Use routing_strategy config to create a consensus_aware proxyd instance
 	*/
@@ -189,12 +359,79 @@ type BackendGroupConfig struct {
 	ConsensusMaxBlockRange      uint64       `toml:"consensus_max_block_range"`
 	ConsensusMinPeerCount       int          `toml:"consensus_min_peer_count"`
 
+	// ConsensusQuorum resolves latest to the highest block that this many
+	// backends have, rather than the lowest latest block of the group.
+	// Backends without it leave the consensus group until they catch up.
+	ConsensusQuorum int `toml:"consensus_quorum"`
+
 	ConsensusHA                  bool         `toml:"consensus_ha"`
 	ConsensusHAHeartbeatInterval TOMLDuration `toml:"consensus_ha_heartbeat_interval"`
 	ConsensusHALockPeriod        TOMLDuration `toml:"consensus_ha_lock_period"`
 	ConsensusHARedis             RedisConfig  `toml:"consensus_ha_redis"`
 
 	Fallbacks []string `toml:"fallbacks"`
+
+	RequestRewrites  []*RequestRewriteConfig `toml:"request_rewrites"`
+	ResponseRewrites ResponseRewriteConfig   `toml:"response_rewrites"`
+
+	Discovery *DiscoveryConfig `toml:"discovery"`
+}
+
+// DiscoveryConfig adds a backend to the group for every endpoint of a
+// Kubernetes service or DNS SRV record, see BackendDiscovery.
+type DiscoveryConfig struct {
+	// Template is the backend whose settings discovered backends copy. The
+	// host and port of its rpc_url, and the host of its ws_url, are replaced
+	// by each endpoint's.
+	Template string `toml:"template"`
+
+	// KubernetesService selects the EndpointSlices of a service. Alternatively
+	// KubernetesLabelSelector selects EndpointSlices by label.
+	KubernetesService       string `toml:"kubernetes_service"`
+	KubernetesLabelSelector string `toml:"kubernetes_label_selector"`
+	// KubernetesNamespace defaults to the namespace of the proxyd pod.
+	KubernetesNamespace string `toml:"kubernetes_namespace"`
+	// KubernetesAPIURL is only needed outside of the cluster.
+	KubernetesAPIURL string `toml:"kubernetes_api_url"`
+	// PortName selects the EndpointSlice port. Defaults to the first one.
+	PortName string `toml:"port_name"`
+
+	// SRV is a DNS SRV name such as _rpc._tcp.geth.default.svc.cluster.local.
+	SRV string `toml:"srv"`
+
+	Interval TOMLDuration `toml:"interval"`
+}
+
+// RequestRewriteConfig is a declarative rewrite applied to requests for Method
+// before a backend group forwards them.
+type RequestRewriteConfig struct {
+	Method string `toml:"method"`
+	// RenameTo forwards the request under a different method name.
+	RenameTo string `toml:"rename_to"`
+	// DefaultParams fills in positional params the client omitted.
+	DefaultParams []interface{} `toml:"default_params"`
+	// BlockTags replaces block tags found at position BlockParam, e.g. { pending = "latest" }.
+	BlockParam *int              `toml:"block_param"`
+	BlockTags  map[string]string `toml:"block_tags"`
+	// URLPath, ForwardPath and ForwardQuery control the backend URL. URLPath
+	// is appended to the backend URL and takes precedence over the client path.
+	URLPath      string `toml:"url_path"`
+	ForwardPath  bool   `toml:"forward_path"`
+	ForwardQuery bool   `toml:"forward_query"`
+}
+
+// ResponseRewriteConfig transforms successful responses before they are
+// returned to clients.
+type ResponseRewriteConfig struct {
+	// Overrides replaces the result of a method, e.g. web3_clientVersion.
+	Overrides map[string]string `toml:"overrides"`
+	// MaskFields removes fields that identify the backend from result objects.
+	MaskFields []string `toml:"mask_fields"`
+	// NullAsEmpty returns [] instead of null for the listed methods, which
+	// evens out differences between client implementations.
+	NullAsEmpty []string `toml:"null_as_empty"`
+	// InjectServedBy adds a servedBy field naming the backend to each response.
+	InjectServedBy bool `toml:"inject_served_by"`
 }
 
 type BackendGroupsConfig map[string]*BackendGroupConfig
@@ -214,28 +451,867 @@ type SenderRateLimitConfig struct {
 	Interval        TOMLDuration
 	Limit           int
 	AllowedChainIds []*big.Int `toml:"allowed_chain_ids"`
+	// ChainLimits overrides Limit for interop transactions with messages
+	// from the given chain IDs, so that each chain gets a budget of its own.
+	// Only used by interop_validation.sender_rate_limit.
+	ChainLimits map[string]int `toml:"chain_limits"`
+}
+
+// ReadYourWritesConfig routes the reads of recent senders to the backend that
+// accepted their transaction, see ReadYourWrites.
+type ReadYourWritesConfig struct {
+	Enabled bool `toml:"enabled"`
+	// Window is how long reads follow the transaction. Defaults to 10s.
+	Window TOMLDuration `toml:"window"`
+}
+
+// TxDedupConfig answers identical transaction submissions with the result of
+// the first one, see TxDedup.
+type TxDedupConfig struct {
+	Enabled bool `toml:"enabled"`
+	// Window is how long a submission is remembered. Defaults to 30s.
+	Window TOMLDuration `toml:"window"`
+}
+
+// EstimateGasConfig adjusts eth_estimateGas handling, see EstimateGas.
+type EstimateGasConfig struct {
+	Enabled bool `toml:"enabled"`
+	// CacheTTL is how long an estimate is reused for identical calls. Zero
+	// disables caching.
+	CacheTTL TOMLDuration `toml:"cache_ttl"`
+	// Multiplier scales returned estimates, e.g. 1.2 to add 20% headroom.
+	Multiplier float64 `toml:"multiplier"`
+	// Ceiling caps returned estimates. Zero disables the cap.
+	Ceiling uint64 `toml:"ceiling"`
+	// Backend is the backend estimates are sent to first. The rest of the
+	// group serves them when it is unavailable.
+	Backend string `toml:"backend"`
+}
+
+// EarlyReturnConfig answers transaction submissions of trusted callers with
+// the transaction hash before forwarding them, see EarlyReturn.
+type EarlyReturnConfig struct {
+	Enabled bool `toml:"enabled"`
+	// Callers are the auth aliases or API key IDs trusted with early returns.
+	Callers []string `toml:"callers"`
+	// Timeout bounds forwarding in the background. Defaults to 30s.
+	Timeout TOMLDuration `toml:"timeout"`
+	// StatusTTL is how long the outcome of forwarding is kept. Defaults to
+	// 10m.
+	StatusTTL TOMLDuration `toml:"status_ttl"`
+}
+
+// TxPreferencesConfig keeps the transaction preferences of users, see
+// TxPreferenceStore.
+type TxPreferencesConfig struct {
+	Enabled bool `toml:"enabled"`
+}
+
+// CapabilityProbingConfig probes the methods each backend serves, see
+// CapabilityProber.
+type CapabilityProbingConfig struct {
+	Enabled bool `toml:"enabled"`
+	// Interval between probes. Defaults to 5m.
+	Interval TOMLDuration `toml:"interval"`
+	// Methods are tried on each backend, without params, and skipped on
+	// backends that answer "method not found".
+	Methods []string `toml:"methods"`
+}
+
+// LatestTranslationConfig tracks the heads of the backends of a group, see
+// HeadTracker.
+type LatestTranslationConfig struct {
+	// MaxLag is how many blocks a backend may lag the group head and still be
+	// tried first. Defaults to 4.
+	MaxLag uint64 `toml:"max_lag"`
+	// Interval is how often heads are polled. Defaults to 2s.
+	Interval TOMLDuration `toml:"interval"`
+}
+
+// HealthGossipConfig shares backend health between proxyd instances over
+// Redis pub/sub, see HealthGossip.
+type HealthGossipConfig struct {
+	Enabled bool `toml:"enabled"`
+	// Interval is how often the backends this instance finds failing are
+	// published. Defaults to 5s.
+	Interval TOMLDuration `toml:"interval"`
+	// ReportTTL is how long backends reported unhealthy by peers are tried
+	// last. Reported bans last until the ban ends. Defaults to 15s.
+	ReportTTL TOMLDuration `toml:"report_ttl"`
+}
+
+// LeaderElectionConfig checks which backends are sequencer leaders, see
+// LeaderTracker.
+type LeaderElectionConfig struct {
+	// Interval between checks. Defaults to 500ms.
+	Interval TOMLDuration `toml:"interval"`
+}
+
+// DerivationHealthConfig checks the derivation of OP Stack backends against
+// L1, see DerivationMonitor.
+type DerivationHealthConfig struct {
+	// L1RPCURL is the L1 source, required to check anything.
+	L1RPCURL string `toml:"l1_rpc_url"`
+	// MaxSafeLag and MaxFinalizedLag are the L1 blocks the L1 origins of the
+	// safe and finalized heads may be behind the L1 head and finalized
+	// block. Both default to 300.
+	MaxSafeLag      uint64 `toml:"max_safe_lag"`
+	MaxFinalizedLag uint64 `toml:"max_finalized_lag"`
+	// Interval between checks. Defaults to 30s.
+	Interval TOMLDuration `toml:"interval"`
+}
+
+// RoutingRuleConfig routes requests for a method whose params meet all the
+// conditions to another backend group, see RoutingRules.
+type RoutingRuleConfig struct {
+	Method       string `toml:"method"`
+	BackendGroup string `toml:"backend_group"`
+	// HeadGroup is the consensus aware group whose latest block older_than
+	// and newer_than are relative to. Defaults to the group the method is
+	// mapped to.
+	HeadGroup string                   `toml:"head_group"`
+	When      []RoutingConditionConfig `toml:"when"`
+}
+
+// RoutingConditionConfig holds when the param at Param, e.g. "0.fromBlock",
+// meets all of the predicates set.
+type RoutingConditionConfig struct {
+	Param string `toml:"param"`
+	// OlderThan and NewerThan hold for a block number or tag more, or at
+	// most, this many blocks behind the latest block.
+	OlderThan uint64 `toml:"older_than"`
+	NewerThan uint64 `toml:"newer_than"`
+	Equals    string `toml:"equals"`
+	Matches   string `toml:"matches"`
+}
+
+// InflightConfig bounds the requests in flight, see InflightLimiter. At the
+// top level it bounds client requests, and in a backend group the requests
+// the group forwards.
+type InflightConfig struct {
+	// MaxRequests of 0 doesn't limit.
+	MaxRequests int `toml:"max_requests"`
+	// MaxQueue is how many requests may wait for a slot. 0 rejects requests
+	// over the limit right away.
+	MaxQueue int `toml:"max_queue"`
+	// QueueTimeout defaults to 1s.
+	QueueTimeout TOMLDuration `toml:"queue_timeout"`
+}
+
+// WSCompressionConfig negotiates permessage-deflate on websocket connections.
+type WSCompressionConfig struct {
+	// Client offers compression to clients, Upstream asks backends for it.
+	Client   bool `toml:"client"`
+	Upstream bool `toml:"upstream"`
+	// Level is the flate compression level, from -2 (Huffman only) to 9.
+	// Defaults to 1, the fastest.
+	Level int `toml:"level"`
+	// Threshold is the size in bytes below which messages are sent
+	// uncompressed. Defaults to 512.
+	Threshold int `toml:"threshold"`
+}
+
+// WSSendBufferConfig queues the messages for each websocket client, so that a
+// client slow to read doesn't hold up reading from its backend connection.
+type WSSendBufferConfig struct {
+	// Size is the number of messages queued per client. Zero writes messages
+	// to the client as they are read.
+	Size int `toml:"size"`
+	// Policy applies when the queue of a client is full: disconnect closes
+	// the connection, and skip drops subscription notifications and then
+	// sends a proxyd_notificationsDropped notification with their count.
+	// Defaults to disconnect.
+	Policy string `toml:"policy"`
+}
+
+// WSReplayConfig keeps recent newHeads and logs notifications for clients
+// subscribing again with a cursor, see WSReplay.
+type WSReplayConfig struct {
+	Enabled bool `toml:"enabled"`
+	// Size is the number of notifications kept per subscription. Defaults to
+	// 1024.
+	Size int `toml:"size"`
+	// TTL is how long the notifications of a subscription are kept after the
+	// last one, in Redis. Defaults to 1h.
+	TTL TOMLDuration `toml:"ttl"`
+}
+
+// WSNotificationBatchingConfig lets websocket clients opt in to receiving
+// subscription notifications in JSON-RPC batches with the
+// batch_notifications=true query parameter, see wsNotificationBatcher.
+type WSNotificationBatchingConfig struct {
+	Enabled bool `toml:"enabled"`
+	// FlushInterval is how long a notification may wait for others. Defaults
+	// to 20ms.
+	FlushInterval TOMLDuration `toml:"flush_interval"`
+	// MaxSize is the most notifications in a batch. Defaults to 100.
+	MaxSize int `toml:"max_size"`
+}
+
+// VirtualHostConfig routes the requests for one host name, see VirtualHost.
+type VirtualHostConfig struct {
+	// RPCMethodMappings replace the top-level rpc_method_mappings.
+	RPCMethodMappings map[string]string `toml:"rpc_method_mappings"`
+	// WSBackendGroup replaces the top-level ws_backend_group.
+	WSBackendGroup string `toml:"ws_backend_group"`
+	// TLSCertFile and TLSKeyFile are served to clients asking for the host
+	// by SNI, when proxyd terminates TLS.
+	TLSCertFile string `toml:"tls_cert_file"`
+	TLSKeyFile  string `toml:"tls_key_file"`
+}
+
+// TenantConfig isolates the requests of one product served by a shared
+// proxyd, see Tenant. Requests are assigned to the tenant whose API key is in
+// the X-API-Key header or the first segment of the path, or else whose host is
+// in the Host header.
+type TenantConfig struct {
+	APIKeys []string `toml:"api_keys"`
+	Hosts   []string `toml:"hosts"`
+	// BackendGroups are reserved for the tenant: only its method mappings may
+	// use them.
+	BackendGroups []string `toml:"backend_groups"`
+	// RPCMethodMappings replace the top-level rpc_method_mappings.
+	RPCMethodMappings map[string]string `toml:"rpc_method_mappings"`
+	// RateLimit replaces the top-level base and per-method rate limits.
+	RateLimit RateLimitConfig `toml:"rate_limit"`
+	// CacheKeyPrefix namespaces the tenant's cache entries. Defaults to the
+	// tenant's name.
+	CacheKeyPrefix string `toml:"cache_key_prefix"`
+}
+
+// AuthPolicyConfig applies to the requests of one authentication alias, see
+// AuthPolicy.
+type AuthPolicyConfig struct {
+	// RateLimitTier names the [rate_limit_tiers] entry limiting the alias's
+	// requests, counted per alias rather than per IP.
+	RateLimitTier string `toml:"rate_limit_tier"`
+	// AllowedMethods restricts the alias to these methods when set.
+	AllowedMethods []string `toml:"allowed_methods"`
+	// BackendGroup serves all the alias's requests instead of the groups of
+	// the method mappings.
+	BackendGroup string `toml:"backend_group"`
+	// Headers are set on the requests forwarded to backends.
+	Headers map[string]string `toml:"headers"`
+	// DebugHeader adds the X-Proxyd-Debug header to the alias's HTTP
+	// responses when enable_served_by_header is set.
+	DebugHeader bool `toml:"debug_header"`
+}
+
+// CallPolicyConfig restricts the targets of eth_call and eth_estimateGas for
+// some aliases or rate limit tiers, see CallPolicy.
+type CallPolicyConfig struct {
+	// Mode is "allow", serving only calls to the addresses and selectors, or
+	// "deny", rejecting them.
+	Mode      string   `toml:"mode"`
+	Addresses []string `toml:"addresses"`
+	// Selectors are 4-byte function selectors, e.g. "0xa9059cbb".
+	Selectors []string `toml:"selectors"`
+	// Aliases and Tiers are the authentication aliases and rate limit tiers
+	// the policy applies to.
+	Aliases []string `toml:"aliases"`
+	Tiers   []string `toml:"tiers"`
+}
+
+// SelectorsConfig labels and routes requests by the function their calldata
+// calls, see Selectors.
+type SelectorsConfig struct {
+	// File names selectors, one "0xa9059cbb transfer(address,uint256)" per
+	// line.
+	File string `toml:"file"`
+	// Names maps selectors to names, in addition to the file's.
+	Names map[string]string `toml:"names"`
+	// Rules apply to the requests calling a selector, by its name or the
+	// selector itself.
+	Rules map[string]SelectorRuleConfig `toml:"rules"`
+}
+
+type SelectorRuleConfig struct {
+	// BackendGroup serves the requests calling the selector.
+	BackendGroup string `toml:"backend_group"`
+	// RateLimit is how many requests calling the selector all clients
+	// together may send per RateLimitInterval.
+	RateLimit         int          `toml:"rate_limit"`
+	RateLimitInterval TOMLDuration `toml:"rate_limit_interval"`
+}
+
+// SenderReputationConfig scores transaction senders, see SenderReputation.
+type SenderReputationConfig struct {
+	Enabled bool `toml:"enabled"`
+	// HalfLife is how long it takes events to count half as much. Defaults
+	// to 1h.
+	HalfLife TOMLDuration `toml:"half_life"`
+	// MinScore is the score, between 0 and 1, below which senders are
+	// treated as low reputation. Defaults to 0.3. Unknown senders score 0.5.
+	MinScore float64 `toml:"min_score"`
+	// LowScoreRateLimit limits the submissions of each low reputation
+	// sender, in addition to the sender rate limit.
+	LowScoreRateLimit RateLimitMethodOverride `toml:"low_score_rate_limit"`
+	// LowScoreBackendGroup serves the submissions of low reputation senders.
+	LowScoreBackendGroup string `toml:"low_score_backend_group"`
+}
+
+// UsageExportConfig writes hourly per-key usage reports, see UsageExporter.
+type UsageExportConfig struct {
+	Enabled bool `toml:"enabled"`
+	// URL is where reports are written: s3://bucket/prefix,
+	// gs://bucket/prefix or file:///dir.
+	URL string `toml:"url"`
+	// Region is the region of the S3 bucket.
+	Region string `toml:"region"`
+	// Instance names this process in the reports. Defaults to the hostname.
+	Instance string `toml:"instance"`
+	// ComputeUnits is how many compute units a request for each method
+	// costs. Other methods cost DefaultComputeUnits, which defaults to 1.
+	ComputeUnits        map[string]uint64 `toml:"compute_units"`
+	DefaultComputeUnits uint64            `toml:"default_compute_units"`
+}
+
+// APIKeysConfig issues authentication keys at runtime, see KeyStore. Keys are
+// stored in Redis and managed on the metrics listener under /admin/keys.
+type APIKeysConfig struct {
+	Enabled bool `toml:"enabled"`
+	// AdminToken is the bearer token of the key management endpoints.
+	AdminToken string `toml:"admin_token"`
+	// CacheTTL is how long looked up keys are cached, and so how long a key
+	// revoked on another instance keeps working. Defaults to 10s.
+	CacheTTL TOMLDuration `toml:"cache_ttl"`
+}
+
+// SignerClassConfig treats the requests signed by its signers, in the
+// X-Flashbots-Signature header, differently, see SignerClass.
+type SignerClassConfig struct {
+	Signers []string `toml:"signers"`
+	// Priority is high, normal or low. High priority requests are never load
+	// shed, low priority ones are shed along with the low priority methods.
+	Priority string `toml:"priority"`
+	// RateLimit replaces the base and per-method rate limits, counted per
+	// signer rather than per IP.
+	RateLimit RateLimitConfig `toml:"rate_limit"`
+	// RPCMethodMappings override the top-level rpc_method_mappings.
+	RPCMethodMappings map[string]string `toml:"rpc_method_mappings"`
+}
+
+// HMACAuthConfig authenticates internal clients signing requests with shared
+// keys, see HMACAuth.
+type HMACAuthConfig struct {
+	// Keys maps key IDs, which become the auth alias of the requests they
+	// sign, to shared keys. Keys may reference secrets.
+	Keys map[string]string `toml:"keys"`
+	// MaxClockSkew is how far a signature's timestamp may be from proxyd's
+	// clock. Defaults to 30s.
+	MaxClockSkew TOMLDuration `toml:"max_clock_skew"`
+}
+
+// ReplayProtectionConfig rejects signed requests whose signature was
+// already used, see ReplayGuard. HMAC signatures are always checked, and
+// their clock skew tolerance is hmac_auth.max_clock_skew.
+type ReplayProtectionConfig struct {
+	Enabled bool `toml:"enabled"`
+	// FlashbotsSignatures also checks verified X-Flashbots-Signature
+	// headers, remembering them for FlashbotsWindow, 1m by default.
+	FlashbotsSignatures bool         `toml:"flashbots_signatures"`
+	FlashbotsWindow     TOMLDuration `toml:"flashbots_window"`
+}
+
+// IPPrivacyConfig pseudonymizes client IPs, see IPHasher.
+type IPPrivacyConfig struct {
+	Enabled bool `toml:"enabled"`
+	// Secret keys the hashes. It must be shared by instances sharing rate
+	// limits, and may reference a secret.
+	Secret string `toml:"secret"`
+	// RotationInterval is how often the hashing key changes. Defaults to
+	// 24h.
+	RotationInterval TOMLDuration `toml:"rotation_interval"`
+}
+
+// TrafficAnalysisConfig tracks the heaviest methods and clients, see
+// TrafficAnalyzer.
+type TrafficAnalysisConfig struct {
+	Enabled bool `toml:"enabled"`
+	// Window is the period counts cover, at least one and at most two
+	// windows. Defaults to 1m.
+	Window TOMLDuration `toml:"window"`
+	// Capacity is how many items of each kind are counted, bounding memory.
+	// Defaults to 1000.
+	Capacity int `toml:"capacity"`
+	// AdminToken enables the /admin/top endpoint on the metrics listener,
+	// for requests with it as Bearer token. May reference a secret.
+	AdminToken string `toml:"admin_token"`
+}
+
+// TxAuditConfig keeps an audit record of every transaction submission, see
+// TxAuditLog.
+type TxAuditConfig struct {
+	Enabled bool `toml:"enabled"`
+	// Sink is "redis", a stream in the Redis of the redis config, or "file".
+	Sink string `toml:"sink"`
+	// StreamMaxLen bounds the Redis stream, approximately. Defaults to
+	// 1000000 records.
+	StreamMaxLen int64 `toml:"stream_max_len"`
+	// File is appended JSON lines, rotated to File.1, File.2 and so on when
+	// it reaches MaxFileSizeMB, 100 by default, keeping MaxBackups, 10 by
+	// default.
+	File          string `toml:"file"`
+	MaxFileSizeMB int    `toml:"max_file_size_mb"`
+	MaxBackups    int    `toml:"max_backups"`
+	// AdminToken enables the /admin/txs endpoint on the metrics listener,
+	// for requests with it as Bearer token. May reference a secret.
+	AdminToken string `toml:"admin_token"`
+}
+
+// RateLimitExemptionsConfig lists the clients bypassing the frontend and
+// sender rate limits, see RateLimitExemptions.
+type RateLimitExemptionsConfig struct {
+	RateLimitExemptionList
+	// AdminToken enables the /admin/exemptions endpoint on the metrics
+	// listener, for requests with it as Bearer token. May reference a secret.
+	AdminToken string `toml:"admin_token"`
+}
+
+type RateLimitExemptionList struct {
+	// CIDRs are matched against the client IP, e.g. "10.0.0.0/8" or
+	// "192.0.2.1/32".
+	CIDRs []string `toml:"cidrs" json:"cidrs"`
+	// Keys are auth aliases or API key IDs.
+	Keys []string `toml:"keys" json:"keys"`
+	// Signers are addresses of verified X-Flashbots-Signature signers.
+	Signers []string `toml:"signers" json:"signers"`
+}
+
+// ResponseSigningConfig signs RPC responses, see ResponseSigner.
+type ResponseSigningConfig struct {
+	Enabled bool `toml:"enabled"`
+	// PrivateKey is the hex secp256k1 key responses are signed with. It may
+	// reference a secret.
+	PrivateKey string `toml:"private_key"`
+	// Header carries the signature. Defaults to X-Proxyd-Response-Signature.
+	Header string `toml:"header"`
+}
+
+// StrictJSONRPCConfig rejects requests violating the JSON-RPC 2.0 spec, see
+// StrictJSONRPC.
+type StrictJSONRPCConfig struct {
+	Enabled bool `toml:"enabled"`
+	// WarnOnly logs and counts violations without rejecting the requests.
+	WarnOnly bool `toml:"warn_only"`
+}
+
+// IPFilterConfig blocks requests by client IP, see IPFilter.
+type IPFilterConfig struct {
+	// Allow and Deny are IPs or CIDRs.
+	Allow []string `toml:"allow"`
+	Deny  []string `toml:"deny"`
+	// File and URL are lists with an "allow <ip or cidr>" or
+	// "deny <ip or cidr>" rule per line, added to Allow and Deny.
+	File string `toml:"file"`
+	URL  string `toml:"url"`
+	// RefreshInterval is how often File and URL are read again. Defaults to
+	// 1m.
+	RefreshInterval TOMLDuration `toml:"refresh_interval"`
+}
+
+// MaintenanceModeConfig answers requests with an error during planned
+// outages, see MaintenanceMode. The global notice applies to all requests,
+// Groups' notices to those routed to the backend groups.
+type MaintenanceModeConfig struct {
+	MaintenanceNoticeConfig
+	Groups map[string]MaintenanceNoticeConfig `toml:"groups"`
+	// AdminToken enables the /admin/maintenance endpoint on the metrics
+	// listener, for requests with it as Bearer token. May reference a secret.
+	AdminToken string `toml:"admin_token"`
+}
+
+// DeprecatedMethodConfig sunsets a method, see MethodDeprecations.
+type DeprecatedMethodConfig struct {
+	// Replacement is the method clients should migrate to, if any.
+	Replacement string `toml:"replacement"`
+	// Message explains the error once the method is removed. Defaults to the
+	// method being deprecated.
+	Message string `toml:"message"`
+	// Cutover is when the method stops working. Deprecated methods without
+	// one keep working.
+	Cutover *time.Time `toml:"cutover"`
+}
+
+type MaintenanceNoticeConfig struct {
+	Enabled bool `toml:"enabled" json:"enabled"`
+	// Message is the error message. Defaults to "service under planned
+	// maintenance".
+	Message string `toml:"message" json:"message,omitempty"`
+	// RestoreAt is when service is expected back, sent in the error's data.
+	RestoreAt *time.Time `toml:"restore_at" json:"restore_at,omitempty"`
+	// Methods limits the notice to some methods. Defaults to all.
+	Methods []string `toml:"methods" json:"methods,omitempty"`
+	// Code is the error code. Defaults to -32037.
+	Code int `toml:"code" json:"code,omitempty"`
+}
+
+// FaultInjectionConfig makes backends fail on purpose, see FaultInjector. It
+// must never be enabled in production.
+type FaultInjectionConfig struct {
+	Enabled bool `toml:"enabled"`
+	// AdminToken enables the /admin/faults endpoint on the metrics listener,
+	// for requests with it as Bearer token. May reference a secret.
+	AdminToken string                 `toml:"admin_token"`
+	Backends   map[string]FaultConfig `toml:"backends"`
+}
+
+// FaultConfig sets the percentage of a backend's HTTP requests affected by
+// each fault. Faults are rolled independently, latency first.
+type FaultConfig struct {
+	LatencyPercent float64      `toml:"latency_percent" json:"latency_percent,omitempty"`
+	Latency        TOMLDuration `toml:"latency" json:"latency,omitempty"`
+	// ErrorPercent of requests get an ErrorStatus response, 503 by default,
+	// without reaching the backend.
+	ErrorPercent float64 `toml:"error_percent" json:"error_percent,omitempty"`
+	ErrorStatus  int     `toml:"error_status" json:"error_status,omitempty"`
+	// TruncatePercent of responses lose the second half of their body.
+	TruncatePercent float64 `toml:"truncate_percent" json:"truncate_percent,omitempty"`
+	// ResetPercent of requests fail as if the connection was reset.
+	ResetPercent float64 `toml:"reset_percent" json:"reset_percent,omitempty"`
+}
+
+// CassetteConfig records backend responses or replays them, see Cassette.
+type CassetteConfig struct {
+	// Mode is "record" or "replay". Disabled if empty.
+	Mode string `toml:"mode"`
+	// Dir holds a directory of recorded responses per backend.
+	Dir string `toml:"dir"`
+}
+
+// CacheControlConfig sets Cache-Control and ETag headers on HTTP responses,
+// see CacheControl.
+type CacheControlConfig struct {
+	Enabled bool `toml:"enabled"`
+	// MaxAge is how long caches keep immutable responses. Defaults to 1 year.
+	MaxAge TOMLDuration `toml:"max_age"`
+}
+
+// BinaryEncodingConfig serves JSON-RPC encoded as MessagePack on /msgpack, and
+// as CBOR on /cbor, over HTTP and websockets.
+type BinaryEncodingConfig struct {
+	MessagePack bool `toml:"msgpack"`
+	CBOR        bool `toml:"cbor"`
+}
+
+// LoadSheddingConfig rejects requests under resource pressure, see
+// LoadShedder. Thresholds left at 0 aren't checked.
+type LoadSheddingConfig struct {
+	Enabled             bool   `toml:"enabled"`
+	MaxHeapBytes        uint64 `toml:"max_heap_bytes"`
+	MaxGoroutines       int    `toml:"max_goroutines"`
+	MaxInflightRequests int    `toml:"max_inflight_requests"`
+	// Interval of heap and goroutine sampling. Defaults to 1s.
+	Interval TOMLDuration `toml:"interval"`
+	// RetryAfter is sent to shed clients. Defaults to 5s.
+	RetryAfter TOMLDuration `toml:"retry_after"`
+	// ShedPrefixes are the low priority methods. Defaults to debug_ and
+	// trace_.
+	ShedPrefixes []string `toml:"shed_prefixes"`
+	// ProtectedMethods are never shed. Defaults to eth_sendRawTransaction
+	// and eth_sendRawTransactionConditional.
+	ProtectedMethods []string `toml:"protected_methods"`
+}
+
+// BackendScoringConfig scores backends and orders groups by the scores, see
+// BackendScorer.
+type BackendScoringConfig struct {
+	Enabled bool `toml:"enabled"`
+	// Interval defaults to 10s.
+	Interval TOMLDuration `toml:"interval"`
+}
+
+// NotFoundRetryConfig retries null responses for blocks and transactions
+// that are known to exist, see NotFoundRetry.
+type NotFoundRetryConfig struct {
+	Enabled bool `toml:"enabled"`
+	// MaxRetries defaults to 3.
+	MaxRetries int `toml:"max_retries"`
+	// Backoff is multiplied by the attempt, plus up to one Backoff of jitter.
+	// Defaults to 100ms.
+	Backoff TOMLDuration `toml:"backoff"`
+	// TxWindow is how long forwarded transactions are retried for. Defaults
+	// to 1m.
+	TxWindow TOMLDuration `toml:"tx_window"`
+}
+
+// AffinityConfig pins clients to a backend for stateful methods, see
+// BackendAffinity.
+type AffinityConfig struct {
+	Methods []string `toml:"methods"`
+	// Cookie identifies clients by a cookie of this name, set by proxyd.
+	// Otherwise they're identified by auth alias and IP.
+	Cookie string `toml:"cookie"`
+	// TTL unpins clients that haven't used a stateful method for this long.
+	// Defaults to 10m.
+	TTL TOMLDuration `toml:"ttl"`
+}
+
+// FiltersConfig serves the filter API from proxyd, see FilterManager. The
+// filter methods must be in rpc_method_mappings, which picks the backend
+// group they poll.
+type FiltersConfig struct {
+	Enabled bool `toml:"enabled"`
+	// Timeout removes filters that aren't polled for this long. Defaults to
+	// 5m.
+	Timeout TOMLDuration `toml:"timeout"`
+	// MaxFilters bounds the installed filters. Defaults to 10000.
+	MaxFilters int `toml:"max_filters"`
+}
+
+// GasOracleConfig answers eth_gasPrice, eth_maxPriorityFeePerGas and
+// eth_feeHistory once per block for all clients, see GasOracle.
+type GasOracleConfig struct {
+	Enabled bool `toml:"enabled"`
+	// BlockTime is how long responses are kept for backend groups without
+	// consensus_aware routing. Defaults to 2s.
+	BlockTime TOMLDuration `toml:"block_time"`
+	// Interval is how often new blocks are checked for, to warm the
+	// responses. Defaults to 250ms.
+	Interval TOMLDuration `toml:"interval"`
+}
+
+// PendingNoncesConfig serves eth_getTransactionCount for the pending block
+// from proxyd's own view of pending nonces, see PendingNonces.
+type PendingNoncesConfig struct {
+	Enabled bool `toml:"enabled"`
+	// TTL forgets a sender's pending nonce once it hasn't risen for this
+	// long. Defaults to 1m.
+	TTL TOMLDuration `toml:"ttl"`
+}
+
+// PendingTxLimitConfig caps the raw transactions per sender that have been
+// submitted but not mined yet, see PendingTxLimiter. Requires Redis.
+type PendingTxLimitConfig struct {
+	Enabled    bool `toml:"enabled"`
+	MaxPending int  `toml:"max_pending"`
+	// TTL forgets a sender's pending transactions once it hasn't submitted
+	// any for this long. Defaults to 10m.
+	TTL TOMLDuration `toml:"ttl"`
+}
+
+// TxFeeFilterConfig bounds the fees of raw transactions relative to the base
+// fee of the latest consensus block of the eth_sendRawTransaction backend
+// group, which needs consensus_aware routing. Each bound is a multiple of the
+// base fee, and 0 disables it. Transactions are let through while the base
+// fee is unknown.
+type TxFeeFilterConfig struct {
+	MinFeeCapMultiplier float64 `toml:"min_fee_cap_multiplier"`
+	MaxFeeCapMultiplier float64 `toml:"max_fee_cap_multiplier"`
+	MinTipCapMultiplier float64 `toml:"min_tip_cap_multiplier"`
+	MaxTipCapMultiplier float64 `toml:"max_tip_cap_multiplier"`
+}
+
+// BlobTxConfig restricts EIP-4844 blob transactions submitted through
+// eth_sendRawTransaction and eth_sendRawTransactionConditional.
+type BlobTxConfig struct {
+	// MaxBlobs rejects transactions carrying more blobs. 0 means no limit.
+	MaxBlobs int `toml:"max_blobs"`
+	// MaxBlobFeeCap rejects transactions whose max fee per blob gas, in wei,
+	// is higher.
+	MaxBlobFeeCap *big.Int `toml:"max_blob_fee_cap"`
+	// BackendGroup forwards blob transactions to this backend group instead
+	// of the one their method is mapped to.
+	BackendGroup string `toml:"backend_group"`
 }
 
 type Config struct {
-	WSBackendGroup           string                  `toml:"ws_backend_group"`
-	Server                   ServerConfig            `toml:"server"`
-	Cache                    CacheConfig             `toml:"cache"`
-	Redis                    RedisConfig             `toml:"redis"`
-	Metrics                  MetricsConfig           `toml:"metrics"`
-	RateLimit                RateLimitConfig         `toml:"rate_limit"`
-	HighPrioRateLimit        RateLimitConfig         `toml:"high_prio_rate_limit"`
-	HighPrioSigners          []string                `toml:"high_prio_signers"`
-	BackendOptions           BackendOptions          `toml:"backend"`
-	Backends                 BackendsConfig          `toml:"backends"`
-	BatchConfig              BatchConfig             `toml:"batch"`
-	Authentication           map[string]string       `toml:"authentication"`
-	BackendGroups            BackendGroupsConfig     `toml:"backend_groups"`
-	RPCMethodMappings        map[string]string       `toml:"rpc_method_mappings"`
-	WSMethodWhitelist        []string                `toml:"ws_method_whitelist"`
-	VerifyFlashbotsSignature bool                    `toml:"verify_flashbots_signature"`
-	WhitelistErrorMessage    string                  `toml:"whitelist_error_message"`
-	SenderRateLimit          SenderRateLimitConfig   `toml:"sender_rate_limit"`
-	InteropValidationConfig  InteropValidationConfig `toml:"interop_validation"`
+	// ConfigVersion is the schema version the config is written for, see
+	// ConfigSchemaVersion. Configs without it are version 1.
+	ConfigVersion int `toml:"config_version"`
+
+	WSBackendGroup           string                            `toml:"ws_backend_group"`
+	Server                   ServerConfig                      `toml:"server"`
+	Cache                    CacheConfig                       `toml:"cache"`
+	Redis                    RedisConfig                       `toml:"redis"`
+	Metrics                  MetricsConfig                     `toml:"metrics"`
+	RateLimit                RateLimitConfig                   `toml:"rate_limit"`
+	HighPrioRateLimit        RateLimitConfig                   `toml:"high_prio_rate_limit"`
+	HighPrioSigners          []string                          `toml:"high_prio_signers"`
+	BackendOptions           BackendOptions                    `toml:"backend"`
+	Backends                 BackendsConfig                    `toml:"backends"`
+	BatchConfig              BatchConfig                       `toml:"batch"`
+	Authentication           map[string]string                 `toml:"authentication"`
+	BackendGroups            BackendGroupsConfig               `toml:"backend_groups"`
+	RPCMethodMappings        map[string]string                 `toml:"rpc_method_mappings"`
+	WSMethodWhitelist        []string                          `toml:"ws_method_whitelist"`
+	VerifyFlashbotsSignature bool                              `toml:"verify_flashbots_signature"`
+	WhitelistErrorMessage    string                            `toml:"whitelist_error_message"`
+	SenderRateLimit          SenderRateLimitConfig             `toml:"sender_rate_limit"`
+	InteropValidationConfig  InteropValidationConfig           `toml:"interop_validation"`
+	Plugins                  []*PluginConfig                   `toml:"plugins"`
+	Policy                   PolicyConfig                      `toml:"policy"`
+	Events                   EventsConfig                      `toml:"events"`
+	Webhooks                 WebhooksConfig                    `toml:"webhooks"`
+	Secrets                  SecretsConfig                     `toml:"secrets"`
+	RemoteConfig             RemoteConfigConfig                `toml:"remote_config"`
+	FeatureFlags             FeatureFlagsConfig                `toml:"feature_flags"`
+	BlobTx                   BlobTxConfig                      `toml:"blob_tx"`
+	TxFeeFilter              TxFeeFilterConfig                 `toml:"tx_fee_filter"`
+	PendingTxLimit           PendingTxLimitConfig              `toml:"pending_tx_limit"`
+	PendingNonces            PendingNoncesConfig               `toml:"pending_nonces"`
+	GasOracle                GasOracleConfig                   `toml:"gas_oracle"`
+	Filters                  FiltersConfig                     `toml:"filters"`
+	Affinity                 AffinityConfig                    `toml:"affinity"`
+	ReadYourWrites           ReadYourWritesConfig              `toml:"read_your_writes"`
+	NotFoundRetry            NotFoundRetryConfig               `toml:"not_found_retry"`
+	BackendScoring           BackendScoringConfig              `toml:"backend_scoring"`
+	LoadShedding             LoadSheddingConfig                `toml:"load_shedding"`
+	Inflight                 InflightConfig                    `toml:"inflight"`
+	WSCompression            WSCompressionConfig               `toml:"ws_compression"`
+	WSSendBuffer             WSSendBufferConfig                `toml:"ws_send_buffer"`
+	WSReplay                 WSReplayConfig                    `toml:"ws_replay"`
+	WSNotificationBatching   WSNotificationBatchingConfig      `toml:"ws_notification_batching"`
+	BinaryEncoding           BinaryEncodingConfig              `toml:"binary_encoding"`
+	Tenants                  map[string]TenantConfig           `toml:"tenants"`
+	VirtualHosts             map[string]VirtualHostConfig      `toml:"virtual_hosts"`
+	RateLimitTiers           map[string]RateLimitConfig        `toml:"rate_limit_tiers"`
+	AuthPolicies             map[string]AuthPolicyConfig       `toml:"auth_policies"`
+	CallPolicies             map[string]CallPolicyConfig       `toml:"call_policies"`
+	Selectors                SelectorsConfig                   `toml:"selectors"`
+	SenderReputation         SenderReputationConfig            `toml:"sender_reputation"`
+	UsageExport              UsageExportConfig                 `toml:"usage_export"`
+	APIKeys                  APIKeysConfig                     `toml:"api_keys"`
+	HMACAuth                 HMACAuthConfig                    `toml:"hmac_auth"`
+	SignerClasses            map[string]SignerClassConfig      `toml:"signer_classes"`
+	StrictJSONRPC            StrictJSONRPCConfig               `toml:"strict_jsonrpc"`
+	IPFilter                 IPFilterConfig                    `toml:"ip_filter"`
+	MaintenanceMode          MaintenanceModeConfig             `toml:"maintenance_mode"`
+	DeprecatedMethods        map[string]DeprecatedMethodConfig `toml:"deprecated_methods"`
+	FaultInjection           FaultInjectionConfig              `toml:"fault_injection"`
+	Cassette                 CassetteConfig                    `toml:"cassette"`
+	CacheControl             CacheControlConfig                `toml:"cache_control"`
+	TxDedup                  TxDedupConfig                     `toml:"tx_dedup"`
+	EstimateGas              EstimateGasConfig                 `toml:"estimate_gas"`
+	EarlyReturn              EarlyReturnConfig                 `toml:"early_return"`
+	CapabilityProbing        CapabilityProbingConfig           `toml:"capability_probing"`
+	// WritesGroup and ReadsGroup serve the built-in write and read methods
+	// that aren't in rpc_method_mappings, see SplitReadsWrites.
+	WritesGroup string `toml:"writes_group"`
+	ReadsGroup  string `toml:"reads_group"`
+	// RollupGroup serves the built-in rollup methods that aren't in
+	// rpc_method_mappings, see MapRollupMethods.
+	RollupGroup string `toml:"rollup_group"`
+
+	LeaderElection      LeaderElectionConfig      `toml:"leader_election"`
+	DerivationHealth    DerivationHealthConfig    `toml:"derivation_health"`
+	RoutingRules        []RoutingRuleConfig       `toml:"routing_rules"`
+	TxPreferences       TxPreferencesConfig       `toml:"tx_preferences"`
+	ResponseSigning     ResponseSigningConfig     `toml:"response_signing"`
+	ReplayProtection    ReplayProtectionConfig    `toml:"replay_protection"`
+	RateLimitExemptions RateLimitExemptionsConfig `toml:"rate_limit_exemptions"`
+	TxAudit             TxAuditConfig             `toml:"tx_audit"`
+	IPPrivacy           IPPrivacyConfig           `toml:"ip_privacy"`
+	TrafficAnalysis     TrafficAnalysisConfig     `toml:"traffic_analysis"`
+	HealthGossip        HealthGossipConfig        `toml:"health_gossip"`
+
+	// localTables are the merged local config files, before the remote
+	// config, when read by LoadConfig.
+	localTables map[string]interface{}
+}
+
+// FeatureFlagsConfig gradually rolls out risky behaviors, see FeatureFlags.
+type FeatureFlagsConfig struct {
+	Flags map[string]*FeatureFlagConfig `toml:"flags"`
+	// Redis reads flags from the feature_flags hash in Redis, which take
+	// precedence over Flags. The hash maps flag names to JSON encoded
+	// FeatureFlagConfig values.
+	Redis           bool         `toml:"redis"`
+	RefreshInterval TOMLDuration `toml:"refresh_interval"`
+}
+
+// FeatureFlagConfig enables a flag for a percentage of keys, where a key is
+// the auth alias of a request or else the client IP. Keys and ExcludeKeys
+// target specific keys regardless of the percentage.
+type FeatureFlagConfig struct {
+	RolloutPercent float64  `toml:"rollout_percent" json:"rollout_percent"`
+	Keys           []string `toml:"keys" json:"keys"`
+	ExcludeKeys    []string `toml:"exclude_keys" json:"exclude_keys"`
+}
+
+// RemoteConfigConfig points at a config document in Consul KV, etcd, S3 or
+// GCS, see RemoteConfigWatcher.
+type RemoteConfigConfig struct {
+	// URL is consul://host:port/key, etcd://host:port/key, s3://bucket/key or
+	// gs://bucket/key.
+	URL          string       `toml:"url"`
+	PollInterval TOMLDuration `toml:"poll_interval"`
+	// TLS connects to Consul or etcd over https.
+	TLS bool `toml:"tls"`
+	// Token is the Consul ACL token. May be read from the environment.
+	Token string `toml:"token"`
+	// Region of the S3 bucket.
+	Region string `toml:"region"`
+}
+
+// SecretsConfig configures the secret managers that config values may
+// reference, see SecretStore.
+type SecretsConfig struct {
+	Vault VaultConfig `toml:"vault"`
+	AWS   AWSConfig   `toml:"aws"`
+	// RefreshInterval is how often referenced secrets are fetched again.
+	RefreshInterval TOMLDuration `toml:"refresh_interval"`
+}
+
+type VaultConfig struct {
+	// Address and Token default to $VAULT_ADDR and $VAULT_TOKEN.
+	Address   string `toml:"address"`
+	Token     string `toml:"token"`
+	Namespace string `toml:"namespace"`
+}
+
+type AWSConfig struct {
+	// Region defaults to the region of the AWS environment.
+	Region string `toml:"region"`
+}
+
+// WebhooksConfig configures signed webhook notifications for operational
+// events, see WebhookNotifier.
+type WebhooksConfig struct {
+	URLs []string `toml:"urls"`
+	// Secret signs each body with HMAC-SHA256. May reference a secret.
+	Secret string `toml:"secret"`
+	// Events limits the event types sent. All types are sent by default.
+	Events         []string     `toml:"events"`
+	MaxRetries     int          `toml:"max_retries"`
+	RetryBackoff   TOMLDuration `toml:"retry_backoff"`
+	DeadLetterFile string       `toml:"dead_letter_file"`
+	// ConsensusStallThreshold is how long the consensus block may stay the
+	// same before a consensus_stalled event is sent.
+	ConsensusStallThreshold TOMLDuration `toml:"consensus_stall_threshold"`
+}
+
+// EventsConfig configures the stream of per-request telemetry events.
+type EventsConfig struct {
+	// Sink is either kafka or nats.
+	Sink string `toml:"sink"`
+	// URLs are the Kafka brokers or NATS servers.
+	URLs []string `toml:"urls"`
+	// Topic is the Kafka topic or NATS subject.
+	Topic         string       `toml:"topic"`
+	BufferSize    int          `toml:"buffer_size"`
+	BatchSize     int          `toml:"batch_size"`
+	FlushInterval TOMLDuration `toml:"flush_interval"`
+}
+
+// PolicyConfig configures an external policy service that is asked to allow,
+// deny or annotate requests for Methods, see PolicyClient.
+type PolicyConfig struct {
+	URL     string       `toml:"url"`
+	Methods []string     `toml:"methods"`
+	Timeout TOMLDuration `toml:"timeout"`
+	// FailOpen allows requests when the policy service can't be reached.
+	// By default they are rejected.
+	FailOpen bool `toml:"fail_open"`
+}
+
+// PluginConfig loads a Lua script whose hooks run during the request
+// lifecycle, see PluginHost.
+type PluginConfig struct {
+	Name string `toml:"name"`
+	Path string `toml:"path"`
+	// Timeout bounds each hook call. Defaults to 100ms.
+	Timeout TOMLDuration `toml:"timeout"`
 }
 
 type InteropValidationConfig struct {
@@ -245,6 +1321,65 @@ type InteropValidationConfig struct {
 	ReqSizeLimit                      int                       `toml:"req_size_limit"`
 	AccessListSizeLimit               int                       `toml:"access_list_size_limit"`
 	RateLimit                         SenderRateLimitConfig     `toml:"sender_rate_limit"`
+	// Quorum is the number of supervisors that must accept an access list
+	// with the quorum strategy. Defaults to a majority.
+	Quorum int `toml:"quorum"`
+	// HedgeDelay is how long the hedged strategy waits for a supervisor
+	// before also asking the next one.
+	HedgeDelay time.Duration `toml:"hedge_delay"`
+	// CircuitBreakerThreshold is the number of consecutive failures after
+	// which the circuit-breaker strategy skips a supervisor for
+	// CircuitBreakerCooldown.
+	CircuitBreakerThreshold int           `toml:"circuit_breaker_threshold"`
+	CircuitBreakerCooldown  time.Duration `toml:"circuit_breaker_cooldown"`
+	// BanPeriod is how long the failover strategy skips a failing
+	// supervisor, unless a health check, every HealthCheckInterval, finds it
+	// working again.
+	BanPeriod           time.Duration `toml:"ban_period"`
+	HealthCheckInterval time.Duration `toml:"health_check_interval"`
+	// FailOpen forwards transactions when no supervisor can be reached,
+	// instead of rejecting them.
+	FailOpen bool                         `toml:"fail_open"`
+	Cache    InteropValidationCacheConfig `toml:"cache"`
+	// StaticChecks rejects access lists with messages that can't be valid
+	// before asking a supervisor, see InteropStaticChecker.
+	StaticChecks InteropStaticChecksConfig `toml:"static_checks"`
+	// Mode is "enforce", the default, or "advisory". In advisory mode
+	// transactions are forwarded right away and validated in the background,
+	// only recording the result. Requests whose Origin header matches one of
+	// EnforcedOrigins or AdvisoryOrigins use that mode instead.
+	Mode            InteropValidationMode `toml:"mode"`
+	EnforcedOrigins []string              `toml:"enforced_origins"`
+	AdvisoryOrigins []string              `toml:"advisory_origins"`
+	// AdminToken enables the /admin/interop endpoint on the metrics listener,
+	// for requests with it as Bearer token, to override Strategy, Urls,
+	// FailOpen and Mode at runtime, see InteropAdmin. May reference a secret.
+	AdminToken string `toml:"admin_token"`
+}
+
+// InteropValidationCacheConfig caches validation verdicts per access list
+// until the next block. The latest block is taken from the consensus of the
+// backend group serving eth_sendRawTransaction if it is consensus aware, and
+// otherwise assumed to advance every BlockTime.
+type InteropValidationCacheConfig struct {
+	Enabled    bool          `toml:"enabled"`
+	BlockTime  time.Duration `toml:"block_time"`
+	MaxEntries int           `toml:"max_entries"`
+}
+
+// InteropStaticChecksConfig bounds the executing messages of interop
+// transactions, see InteropStaticChecker.
+type InteropStaticChecksConfig struct {
+	Enabled bool `toml:"enabled"`
+	// ChainIDs are the chains messages may come from. Defaults to any chain.
+	ChainIDs []uint64 `toml:"chain_ids"`
+	// MessageExpiryWindow is how old messages may be. Defaults to 7 days.
+	MessageExpiryWindow time.Duration `toml:"message_expiry_window"`
+	// MaxFutureSkew is how far in the future message timestamps may be, to
+	// allow for clock skew. Defaults to 5m.
+	MaxFutureSkew time.Duration `toml:"max_future_skew"`
+	// MaxLogIndex is the highest log index of messages. Defaults to 262144.
+	MaxLogIndex uint32 `toml:"max_log_index"`
 }
 
 type InteropValidationStrategy string
@@ -254,6 +1389,17 @@ const (
 	FirstSupervisorStrategy          InteropValidationStrategy = "first-supervisor"
 	MulticallStrategy                InteropValidationStrategy = "multicall"
 	HealthAwareLoadBalancingStrategy InteropValidationStrategy = "health-aware-load-balancing"
+	QuorumStrategy                   InteropValidationStrategy = "quorum"
+	HedgedStrategy                   InteropValidationStrategy = "hedged"
+	CircuitBreakerStrategy           InteropValidationStrategy = "circuit-breaker"
+	FailoverStrategy                 InteropValidationStrategy = "failover"
+)
+
+type InteropValidationMode string
+
+const (
+	InteropValidationEnforce  InteropValidationMode = "enforce"
+	InteropValidationAdvisory InteropValidationMode = "advisory"
 )
 
 func ReadFromEnvOrConfig(value string) (string, error) {
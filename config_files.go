@@ -0,0 +1,133 @@
+package proxyd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// LoadConfig reads and merges one or more config files. A directory path
+// loads every *.toml file in it in lexical order, so a conf.d directory can be
+// ordered with numeric prefixes. Later files take precedence: tables are
+// merged key by key, while other values, including arrays, replace what came
+// before. If the result sets remote_config.url, the remote document is merged
+// last. LoadConfig also returns the keys that don't correspond to any config
+// option. Configs of a newer config_version than ConfigSchemaVersion are
+// rejected.
+func LoadConfig(paths ...string) (*Config, []string, error) {
+	if len(paths) == 0 {
+		return nil, nil, fmt.Errorf("no config files given")
+	}
+
+	files, err := expandConfigPaths(paths)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	merged := make(map[string]interface{})
+	for _, file := range files {
+		raw := make(map[string]interface{})
+		if _, err := toml.DecodeFile(file, &raw); err != nil {
+			return nil, nil, wrapErr(err, "error reading config file "+file)
+		}
+		mergeConfigTables(merged, raw)
+	}
+
+	config, md, err := decodeConfigTables(merged)
+	if err != nil {
+		return nil, nil, err
+	}
+	// the remote config takes precedence over local files
+	local := make(map[string]interface{})
+	mergeConfigTables(local, merged)
+	if config.RemoteConfig.URL != "" {
+		data, err := fetchRemoteConfig(config.RemoteConfig)
+		if err != nil {
+			return nil, nil, err
+		}
+		raw := make(map[string]interface{})
+		if _, err := toml.Decode(string(data), &raw); err != nil {
+			return nil, nil, wrapErr(err, "error reading remote config")
+		}
+		mergeConfigTables(merged, raw)
+		if config, md, err = decodeConfigTables(merged); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	config.localTables = local
+
+	if config.ConfigVersion > ConfigSchemaVersion {
+		return nil, nil, fmt.Errorf("config_version %d is newer than the supported version %d", config.ConfigVersion, ConfigSchemaVersion)
+	}
+
+	var unknown []string
+	for _, key := range md.Undecoded() {
+		unknown = append(unknown, key.String())
+	}
+	return config, unknown, nil
+}
+
+func decodeConfigTables(tables map[string]interface{}) (*Config, toml.MetaData, error) {
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(tables); err != nil {
+		return nil, toml.MetaData{}, wrapErr(err, "error merging config files")
+	}
+	config := new(Config)
+	md, err := toml.NewDecoder(&buf).Decode(config)
+	if err != nil {
+		return nil, toml.MetaData{}, wrapErr(err, "error reading config")
+	}
+	return config, md, nil
+}
+
+func expandConfigPaths(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		var dirFiles []string
+		for _, entry := range entries {
+			if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".toml") {
+				continue
+			}
+			dirFiles = append(dirFiles, filepath.Join(path, entry.Name()))
+		}
+		sort.Strings(dirFiles)
+		files = append(files, dirFiles...)
+	}
+	return files, nil
+}
+
+// mergeConfigTables deep-merges src into dst.
+func mergeConfigTables(dst, src map[string]interface{}) {
+	for k, v := range src {
+		srcTable, ok := v.(map[string]interface{})
+		if !ok {
+			dst[k] = v
+			continue
+		}
+		dstTable, ok := dst[k].(map[string]interface{})
+		if !ok {
+			dstTable = make(map[string]interface{})
+			dst[k] = dstTable
+		}
+		mergeConfigTables(dstTable, srcTable)
+	}
+}
@@ -0,0 +1,82 @@
+package proxyd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfigMerge(t *testing.T) {
+	dir := t.TempDir()
+	confd := filepath.Join(dir, "conf.d")
+	require.NoError(t, os.Mkdir(confd, 0o700))
+
+	write := func(path, data string) {
+		require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+	}
+	write(filepath.Join(dir, "proxyd.toml"), `
+[server]
+rpc_port = 8545
+max_body_size_bytes = 1000
+
+[backends.infura]
+rpc_url = "https://mainnet.infura.io"
+max_rps = 10
+
+[backend_groups.main]
+backends = ["infura"]
+
+[rpc_method_mappings]
+eth_chainId = "main"
+`)
+	write(filepath.Join(confd, "20-overrides.toml"), `
+[backends.infura]
+max_rps = 30
+
+[backend_groups.main]
+backends = ["infura", "alchemy"]
+`)
+	write(filepath.Join(confd, "10-backends.toml"), `
+[backends.infura]
+max_rps = 20
+
+[backends.alchemy]
+rpc_url = "https://eth-mainnet.alchemyapi.io"
+
+[rpc_method_mappings]
+eth_call = "main"
+`)
+	write(filepath.Join(confd, "notes.txt"), "not a config file")
+	write(filepath.Join(dir, "local.toml"), `
+[server]
+rpc_port = 9545
+unknown_option = true
+`)
+
+	config, unknown, err := LoadConfig(filepath.Join(dir, "proxyd.toml"), confd, filepath.Join(dir, "local.toml"))
+	require.NoError(t, err)
+	require.Equal(t, []string{"server.unknown_option"}, unknown)
+
+	require.Equal(t, 9545, config.Server.RPCPort)
+	require.Equal(t, int64(1000), config.Server.MaxBodySizeBytes)
+	require.Equal(t, "https://mainnet.infura.io", config.Backends["infura"].RPCURL)
+	require.Equal(t, 30, config.Backends["infura"].MaxRPS)
+	require.Equal(t, "https://eth-mainnet.alchemyapi.io", config.Backends["alchemy"].RPCURL)
+	require.Equal(t, []string{"infura", "alchemy"}, config.BackendGroups["main"].Backends)
+	require.Equal(t, map[string]string{"eth_chainId": "main", "eth_call": "main"}, config.RPCMethodMappings)
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	_, _, err := LoadConfig()
+	require.Error(t, err)
+
+	_, _, err = LoadConfig(filepath.Join(t.TempDir(), "missing.toml"))
+	require.Error(t, err)
+
+	path := filepath.Join(t.TempDir(), "bad.toml")
+	require.NoError(t, os.WriteFile(path, []byte("[server\n"), 0o600))
+	_, _, err = LoadConfig(path)
+	require.ErrorContains(t, err, path)
+}
@@ -0,0 +1,165 @@
+package proxyd
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ConfigSchemaVersion is the config schema version of this proxyd. Configs
+// of older versions are still read, deprecated options included, and can be
+// upgraded with `proxyd migrate-config`.
+const ConfigSchemaVersion = 2
+
+// ConfigDeprecation is a deprecated option set in a config.
+type ConfigDeprecation struct {
+	Key         string
+	Replacement string
+}
+
+func (d ConfigDeprecation) String() string {
+	return fmt.Sprintf("%s is deprecated, use %s instead", d.Key, d.Replacement)
+}
+
+// DeprecatedConfigKeys returns the deprecated options set in config, in the
+// order of their keys.
+func DeprecatedConfigKeys(config *Config) []ConfigDeprecation {
+	var deprecated []ConfigDeprecation
+	if config.BackendOptions.ResponseTimeoutSeconds != 0 {
+		deprecated = append(deprecated, ConfigDeprecation{
+			Key:         "backend.response_timeout_seconds",
+			Replacement: "backend.response_timeout_milliseconds",
+		})
+	}
+	for name, backend := range config.Backends {
+		if len(backend.AllowedDynamicHeaders) > 0 {
+			deprecated = append(deprecated, ConfigDeprecation{
+				Key:         "backends." + name + ".allowed_dynamic_headers",
+				Replacement: "backends." + name + ".header_policy.forward",
+			})
+		}
+	}
+	for name, bg := range config.BackendGroups {
+		if bg.ConsensusAware {
+			deprecated = append(deprecated, ConfigDeprecation{
+				Key:         "backend_groups." + name + ".consensus_aware",
+				Replacement: "backend_groups." + name + ".routing_strategy",
+			})
+		}
+	}
+	sort.Slice(deprecated, func(i, j int) bool {
+		return deprecated[i].Key < deprecated[j].Key
+	})
+	return deprecated
+}
+
+// configMigrations[i] upgrades a config from version i+1 to i+2, returning
+// what it changed.
+var configMigrations = []func(raw map[string]interface{}) []string{
+	migrateConfigV1,
+}
+
+// configVersion returns the version of a raw config, 1 if it isn't set.
+func configVersion(raw map[string]interface{}) (int, error) {
+	val, ok := raw["config_version"]
+	if !ok {
+		return 1, nil
+	}
+	version, ok := val.(int64)
+	if !ok || version < 1 {
+		return 0, fmt.Errorf("invalid config_version %v", val)
+	}
+	return int(version), nil
+}
+
+// MigrateConfig upgrades a raw config, as decoded from a TOML file, to
+// ConfigSchemaVersion in place, and returns what it changed. A config of a
+// newer version is an error.
+func MigrateConfig(raw map[string]interface{}) ([]string, error) {
+	version, err := configVersion(raw)
+	if err != nil {
+		return nil, err
+	}
+	if version > ConfigSchemaVersion {
+		return nil, fmt.Errorf("config_version %d is newer than the supported version %d", version, ConfigSchemaVersion)
+	}
+
+	var changes []string
+	for ; version < ConfigSchemaVersion; version++ {
+		changes = append(changes, configMigrations[version-1](raw)...)
+	}
+	if _, ok := raw["config_version"]; !ok || len(changes) > 0 {
+		changes = append(changes, fmt.Sprintf("set config_version to %d", ConfigSchemaVersion))
+	}
+	raw["config_version"] = int64(ConfigSchemaVersion)
+	return changes, nil
+}
+
+// migrateConfigV1 replaces the options deprecated in version 1.
+func migrateConfigV1(raw map[string]interface{}) []string {
+	var changes []string
+
+	if backend, ok := raw["backend"].(map[string]interface{}); ok {
+		if secs, ok := backend["response_timeout_seconds"].(int64); ok {
+			delete(backend, "response_timeout_seconds")
+			// response_timeout_milliseconds already took precedence
+			if _, ok := backend["response_timeout_milliseconds"]; !ok {
+				backend["response_timeout_milliseconds"] = secs * 1000
+			}
+			changes = append(changes, "replaced backend.response_timeout_seconds with backend.response_timeout_milliseconds")
+		}
+	}
+
+	backends, _ := raw["backends"].(map[string]interface{})
+	for _, name := range sortedKeys(backends) {
+		backend, ok := backends[name].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		headers, ok := backend["allowed_dynamic_headers"].([]interface{})
+		if !ok {
+			continue
+		}
+		delete(backend, "allowed_dynamic_headers")
+		policy, ok := backend["header_policy"].(map[string]interface{})
+		if !ok {
+			policy = make(map[string]interface{})
+			backend["header_policy"] = policy
+		}
+		forward, _ := policy["forward"].([]interface{})
+		for _, header := range headers {
+			if !containsValue(forward, header) {
+				forward = append(forward, header)
+			}
+		}
+		policy["forward"] = forward
+		changes = append(changes, fmt.Sprintf("moved backends.%s.allowed_dynamic_headers to backends.%s.header_policy.forward", name, name))
+	}
+
+	groups, _ := raw["backend_groups"].(map[string]interface{})
+	for _, name := range sortedKeys(groups) {
+		group, ok := groups[name].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		consensusAware, ok := group["consensus_aware"].(bool)
+		if !ok {
+			continue
+		}
+		delete(group, "consensus_aware")
+		if _, ok := group["routing_strategy"]; consensusAware && !ok {
+			group["routing_strategy"] = string(ConsensusAwareRoutingStrategy)
+		}
+		changes = append(changes, fmt.Sprintf("replaced backend_groups.%s.consensus_aware with backend_groups.%s.routing_strategy", name, name))
+	}
+
+	return changes
+}
+
+func containsValue(values []interface{}, value interface{}) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
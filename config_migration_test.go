@@ -0,0 +1,74 @@
+package proxyd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateConfig(t *testing.T) {
+	raw := make(map[string]interface{})
+	_, err := toml.Decode(`
+[backend]
+response_timeout_seconds = 5
+
+[backends.infura]
+rpc_url = "https://mainnet.infura.io"
+allowed_dynamic_headers = ["x-flashbots-signature", "x-request-id"]
+
+[backends.infura.header_policy]
+forward = ["x-request-id"]
+
+[backends.alchemy]
+rpc_url = "https://eth-mainnet.alchemyapi.io"
+
+[backend_groups.main]
+backends = ["infura", "alchemy"]
+consensus_aware = true
+
+[backend_groups.fallback]
+backends = ["alchemy"]
+consensus_aware = false
+`, &raw)
+	require.NoError(t, err)
+
+	config, _, err := decodeConfigTables(raw)
+	require.NoError(t, err)
+	require.Equal(t, []ConfigDeprecation{
+		{Key: "backend.response_timeout_seconds", Replacement: "backend.response_timeout_milliseconds"},
+		{Key: "backend_groups.main.consensus_aware", Replacement: "backend_groups.main.routing_strategy"},
+		{Key: "backends.infura.allowed_dynamic_headers", Replacement: "backends.infura.header_policy.forward"},
+	}, DeprecatedConfigKeys(config))
+
+	changes, err := MigrateConfig(raw)
+	require.NoError(t, err)
+	require.Len(t, changes, 5)
+
+	config, md, err := decodeConfigTables(raw)
+	require.NoError(t, err)
+	require.Empty(t, md.Undecoded())
+	require.Empty(t, DeprecatedConfigKeys(config))
+	require.Equal(t, ConfigSchemaVersion, config.ConfigVersion)
+	require.Equal(t, 5000, config.BackendOptions.ResponseTimeoutMilliseconds)
+	require.Equal(t, []string{"x-request-id", "x-flashbots-signature"}, config.Backends["infura"].HeaderPolicy.Forward)
+	require.Equal(t, ConsensusAwareRoutingStrategy, config.BackendGroups["main"].RoutingStrategy)
+	require.Empty(t, config.BackendGroups["fallback"].RoutingStrategy)
+
+	// already current
+	changes, err = MigrateConfig(raw)
+	require.NoError(t, err)
+	require.Empty(t, changes)
+
+	_, err = MigrateConfig(map[string]interface{}{"config_version": int64(ConfigSchemaVersion + 1)})
+	require.Error(t, err)
+}
+
+func TestLoadConfigNewerVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "proxyd.toml")
+	require.NoError(t, os.WriteFile(path, []byte("config_version = 99\n"), 0o600))
+	_, _, err := LoadConfig(path)
+	require.ErrorContains(t, err, "newer than the supported version")
+}
@@ -3,6 +3,8 @@ package proxyd
 import (
 	"context"
 	"fmt"
+	"math/big"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -39,7 +41,18 @@ type ConsensusPoller struct {
 	maxUpdateThreshold time.Duration
 	maxBlockLag        uint64
 	maxBlockRange      uint64
+	quorum             int
 	interval           time.Duration
+
+	webhooks *WebhookNotifier
+
+	baseFeeMux sync.RWMutex
+	baseFee    *big.Int
+
+	// only accessed from the consensus update loop
+	lastConsensusAdvance time.Time
+	consensusStalled     bool
+	usingFallbacks       bool
 }
 
 type backendState struct {
@@ -56,6 +69,8 @@ type backendState struct {
 	lastUpdate time.Time
 
 	bannedUntil time.Time
+	// banned is cleared once an expired ban has been noticed
+	banned bool
 }
 
 func (bs *backendState) IsBanned() bool {
@@ -254,6 +269,14 @@ func WithMaxBlockRange(maxBlockRange uint64) ConsensusOpt {
 	}
 }
 
+// WithQuorum resolves latest to the highest block that quorum backends have,
+// instead of the lowest latest block of the consensus candidates.
+func WithQuorum(quorum int) ConsensusOpt {
+	return func(cp *ConsensusPoller) {
+		cp.quorum = quorum
+	}
+}
+
 func WithMinPeerCount(minPeerCount uint64) ConsensusOpt {
 	return func(cp *ConsensusPoller) {
 		cp.minPeerCount = minPeerCount
@@ -266,6 +289,12 @@ func WithPollerInterval(interval time.Duration) ConsensusOpt {
 	}
 }
 
+func WithWebhookNotifier(webhooks *WebhookNotifier) ConsensusOpt {
+	return func(cp *ConsensusPoller) {
+		cp.webhooks = webhooks
+	}
+}
+
 func NewConsensusPoller(bg *BackendGroup, opts ...ConsensusOpt) *ConsensusPoller {
 	ctx, cancelFunc := context.WithCancel(context.Background())
 
@@ -311,6 +340,13 @@ func (cp *ConsensusPoller) UpdateBackend(ctx context.Context, be *Backend) {
 		log.Debug("skipping backend - banned", "backend", be.Name)
 		return
 	}
+	cp.clearExpiredBan(be)
+
+	// backends in maintenance aren't polled, so they aren't banned for it
+	if be.InMaintenance() {
+		log.Debug("skipping backend - in maintenance", "backend", be.Name)
+		return
+	}
 
 	// if backend is not healthy state we'll only resume checking it after ban
 	if !be.IsHealthy() && !be.forcedCandidate {
@@ -341,7 +377,7 @@ func (cp *ConsensusPoller) UpdateBackend(ctx context.Context, be *Backend) {
 		RecordConsensusBackendPeerCount(be, peerCount)
 	}
 
-	latestBlockNumber, latestBlockHash, err := cp.fetchBlock(ctx, be, "latest")
+	latestBlockNumber, latestBlockHash, _, err := cp.fetchBlock(ctx, be, "latest")
 	if err != nil {
 		log.Warn("error updating backend - latest block will not be updated", "name", be.Name, "err", err)
 		return
@@ -352,7 +388,7 @@ func (cp *ConsensusPoller) UpdateBackend(ctx context.Context, be *Backend) {
 		return
 	}
 
-	safeBlockNumber, _, err := cp.fetchBlock(ctx, be, "safe")
+	safeBlockNumber, _, _, err := cp.fetchBlock(ctx, be, "safe")
 	if err != nil {
 		log.Warn("error updating backend - safe block will not be updated", "name", be.Name, "err", err)
 		return
@@ -364,7 +400,7 @@ func (cp *ConsensusPoller) UpdateBackend(ctx context.Context, be *Backend) {
 		return
 	}
 
-	finalizedBlockNumber, _, err := cp.fetchBlock(ctx, be, "finalized")
+	finalizedBlockNumber, _, _, err := cp.fetchBlock(ctx, be, "finalized")
 	if err != nil {
 		log.Warn("error updating backend - finalized block will not be updated", "name", be.Name, "err", err)
 		return
@@ -479,6 +515,7 @@ func (cp *ConsensusPoller) UpdateBackendGroupConsensus(ctx context.Context) {
 	// the proposed block needs have the same hash in the entire consensus group
 	proposedBlock := lowestLatestBlock
 	proposedBlockHash := lowestLatestBlockHash
+	var proposedBaseFee *big.Int
 	hasConsensus := false
 	broken := false
 
@@ -486,12 +523,18 @@ func (cp *ConsensusPoller) UpdateBackendGroupConsensus(ctx context.Context) {
 		log.Debug("validating consensus on block", "lowestLatestBlock", lowestLatestBlock)
 	}
 
-	// if there is a block to propose, check if it is the same in all backends
-	if proposedBlock > 0 {
+	// in quorum mode, the backends that don't have the proposed block leave
+	// the consensus group instead of holding it back
+	members := candidates
+	if cp.quorum > 0 && len(candidates) > cp.quorum {
+		proposedBlock, proposedBaseFee, members, broken = cp.quorumConsensus(ctx, candidates, currentConsensusBlockNumber)
+		hasConsensus = len(members) > 0
+	} else if proposedBlock > 0 {
+		// if there is a block to propose, check if it is the same in all backends
 		for !hasConsensus {
 			allAgreed := true
 			for be := range candidates {
-				actualBlockNumber, actualBlockHash, err := cp.fetchBlock(ctx, be, proposedBlock.String())
+				actualBlockNumber, actualBlockHash, actualBaseFee, err := cp.fetchBlock(ctx, be, proposedBlock.String())
 				if err != nil {
 					log.Warn("error updating backend", "name", be.Name, "err", err)
 					continue
@@ -513,6 +556,7 @@ func (cp *ConsensusPoller) UpdateBackendGroupConsensus(ctx context.Context) {
 					allAgreed = false
 					break
 				}
+				proposedBaseFee = actualBaseFee
 			}
 			if allAgreed {
 				hasConsensus = true
@@ -520,11 +564,20 @@ func (cp *ConsensusPoller) UpdateBackendGroupConsensus(ctx context.Context) {
 				// walk one block behind and try again
 				proposedBlock -= 1
 				proposedBlockHash = ""
+				proposedBaseFee = nil
 				log.Debug("no consensus, now trying", "block:", proposedBlock)
 			}
 		}
 	}
 
+	cp.checkConsensusStall(proposedBlock)
+
+	if hasConsensus && proposedBaseFee != nil {
+		cp.baseFeeMux.Lock()
+		cp.baseFee = proposedBaseFee
+		cp.baseFeeMux.Unlock()
+	}
+
 	if broken {
 		// propagate event to other interested parts, such as cache invalidator
 		for _, l := range cp.listeners {
@@ -546,7 +599,7 @@ func (cp *ConsensusPoller) UpdateBackendGroupConsensus(ctx context.Context) {
 	consensusBackendsNames := make([]string, 0, len(candidates))
 	filteredBackendsNames := make([]string, 0, len(cp.backendGroup.Backends))
 	for _, be := range cp.backendGroup.Backends {
-		_, exist := candidates[be]
+		_, exist := members[be]
 		if exist {
 			group = append(group, be)
 			consensusBackendsNames = append(consensusBackendsNames, be.Name)
@@ -573,6 +626,79 @@ func (cp *ConsensusPoller) UpdateBackendGroupConsensus(ctx context.Context) {
 		"filteredBackends", strings.Join(filteredBackendsNames, ", "))
 }
 
+// quorumConsensus finds the highest block that at least quorum candidates
+// have with the same hash, so that one fast backend briefly ahead during
+// propagation doesn't move latest back and forth. It returns the block, its
+// base fee and the candidates that have it.
+func (cp *ConsensusPoller) quorumConsensus(ctx context.Context, candidates map[*Backend]*backendState, current hexutil.Uint64) (hexutil.Uint64, *big.Int, map[*Backend]*backendState, bool) {
+	heights := make([]hexutil.Uint64, 0, len(candidates))
+	for _, bs := range candidates {
+		heights = append(heights, bs.latestBlockNumber)
+	}
+	sort.Slice(heights, func(i, j int) bool { return heights[i] > heights[j] })
+
+	broken := false
+	for block := heights[cp.quorum-1]; block > 0; block-- {
+		voters := make(map[string][]*Backend)
+		baseFees := make(map[string]*big.Int)
+		for be, bs := range candidates {
+			if bs.latestBlockNumber < block {
+				continue
+			}
+			number, hash, baseFee, err := cp.fetchBlock(ctx, be, block.String())
+			if err != nil {
+				log.Warn("error updating backend", "name", be.Name, "err", err)
+				continue
+			}
+			if number != block {
+				continue
+			}
+			voters[hash] = append(voters[hash], be)
+			baseFees[hash] = baseFee
+		}
+
+		var agreed string
+		for hash, backends := range voters {
+			if len(backends) >= cp.quorum && (agreed == "" || len(backends) > len(voters[agreed])) {
+				agreed = hash
+			}
+		}
+		if agreed != "" {
+			members := make(map[*Backend]*backendState, len(voters[agreed]))
+			for _, be := range voters[agreed] {
+				members[be] = candidates[be]
+			}
+			return block, baseFees[agreed], members, broken
+		}
+
+		if block <= current {
+			log.Warn("no quorum on consensus block", "block", block, "quorum", cp.quorum)
+			broken = true
+		}
+		log.Debug("no quorum, now trying", "block:", block-1)
+	}
+	return 0, nil, nil, broken
+}
+
+// KnowsBlockHash reports whether a backend of the group reported the block
+// hash as its latest block.
+func (cp *ConsensusPoller) KnowsBlockHash(hash string) bool {
+	for _, be := range cp.backendGroup.Backends {
+		if _, latestHash := cp.GetBackendState(be).GetLatestBlock(); latestHash != "" && strings.EqualFold(latestHash, hash) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetBaseFee returns the base fee of the latest consensus block, or nil if
+// it isn't known yet.
+func (cp *ConsensusPoller) GetBaseFee() *big.Int {
+	cp.baseFeeMux.RLock()
+	defer cp.baseFeeMux.RUnlock()
+	return cp.baseFee
+}
+
 // IsBanned checks if a specific backend is banned
 func (cp *ConsensusPoller) IsBanned(be *Backend) bool {
 	bs := cp.backendState[be]
@@ -599,11 +725,18 @@ func (cp *ConsensusPoller) Ban(be *Backend) {
 	defer bs.backendStateMux.Unlock()
 	bs.backendStateMux.Lock()
 	bs.bannedUntil = time.Now().Add(cp.banPeriod)
+	bs.banned = true
 
 	// when we ban a node, we give it the chance to start from any block when it is back
 	bs.latestBlockNumber = 0
 	bs.safeBlockNumber = 0
 	bs.finalizedBlockNumber = 0
+
+	cp.webhooks.Notify(WebhookEventBackendBanned, map[string]interface{}{
+		"backend_group": cp.backendGroup.Name,
+		"backend":       be.Name,
+		"banned_until":  bs.bannedUntil,
+	})
 }
 
 // Unban removes any bans from the backends
@@ -612,6 +745,56 @@ func (cp *ConsensusPoller) Unban(be *Backend) {
 	defer bs.backendStateMux.Unlock()
 	bs.backendStateMux.Lock()
 	bs.bannedUntil = time.Now().Add(-10 * time.Hour)
+	if bs.banned {
+		bs.banned = false
+		cp.notifyUnbanned(be)
+	}
+}
+
+// clearExpiredBan notifies once a ban on the backend has run out.
+func (cp *ConsensusPoller) clearExpiredBan(be *Backend) {
+	bs := cp.backendState[be]
+	defer bs.backendStateMux.Unlock()
+	bs.backendStateMux.Lock()
+	if bs.banned && !bs.IsBanned() {
+		bs.banned = false
+		cp.notifyUnbanned(be)
+	}
+}
+
+func (cp *ConsensusPoller) notifyUnbanned(be *Backend) {
+	cp.webhooks.Notify(WebhookEventBackendUnbanned, map[string]interface{}{
+		"backend_group": cp.backendGroup.Name,
+		"backend":       be.Name,
+	})
+}
+
+// checkConsensusStall notifies when the consensus block hasn't advanced
+// within the stall threshold, and again once it does.
+func (cp *ConsensusPoller) checkConsensusStall(proposedBlock hexutil.Uint64) {
+	if cp.webhooks == nil {
+		return
+	}
+	now := time.Now()
+	if proposedBlock > cp.GetLatestBlockNumber() || cp.lastConsensusAdvance.IsZero() {
+		cp.lastConsensusAdvance = now
+		if cp.consensusStalled {
+			cp.consensusStalled = false
+			cp.webhooks.Notify(WebhookEventConsensusRecovered, map[string]interface{}{
+				"backend_group": cp.backendGroup.Name,
+				"block":         proposedBlock,
+			})
+		}
+		return
+	}
+	if !cp.consensusStalled && now.Sub(cp.lastConsensusAdvance) > cp.webhooks.stallThreshold {
+		cp.consensusStalled = true
+		cp.webhooks.Notify(WebhookEventConsensusStalled, map[string]interface{}{
+			"backend_group": cp.backendGroup.Name,
+			"block":         cp.GetLatestBlockNumber(),
+			"since":         cp.lastConsensusAdvance,
+		})
+	}
 }
 
 // Reset reset all backend states
@@ -621,20 +804,24 @@ func (cp *ConsensusPoller) Reset() {
 	}
 }
 
-// fetchBlock is a convenient wrapper to make a request to get a block directly from the backend
-func (cp *ConsensusPoller) fetchBlock(ctx context.Context, be *Backend, block string) (blockNumber hexutil.Uint64, blockHash string, err error) {
+// fetchBlock is a convenient wrapper to make a request to get a block directly from the backend.
+// baseFee is nil for blocks without one.
+func (cp *ConsensusPoller) fetchBlock(ctx context.Context, be *Backend, block string) (blockNumber hexutil.Uint64, blockHash string, baseFee *big.Int, err error) {
 	var rpcRes RPCRes
 	err = be.ForwardRPC(ctx, &rpcRes, "67", "eth_getBlockByNumber", block, false)
 	if err != nil {
-		return 0, "", err
+		return 0, "", nil, err
 	}
 
 	jsonMap, ok := rpcRes.Result.(map[string]interface{})
 	if !ok {
-		return 0, "", fmt.Errorf("unexpected response to eth_getBlockByNumber on backend %s", be.Name)
+		return 0, "", nil, fmt.Errorf("unexpected response to eth_getBlockByNumber on backend %s", be.Name)
 	}
 	blockNumber = hexutil.Uint64(hexutil.MustDecodeUint64(jsonMap["number"].(string)))
 	blockHash = jsonMap["hash"].(string)
+	if s, ok := jsonMap["baseFeePerGas"].(string); ok {
+		baseFee, _ = hexutil.DecodeBig(s)
+	}
 
 	return
 }
@@ -735,12 +922,28 @@ func (cp *ConsensusPoller) getConsensusCandidates() map[*Backend]*backendState {
 
 	RecordHealthyCandidates(cp.backendGroup, len(healthyPrimaries))
 	if len(healthyPrimaries) > 0 {
+		cp.setUsingFallbacks(false)
 		return healthyPrimaries
 	}
 
+	cp.setUsingFallbacks(len(cp.backendGroup.Fallbacks()) > 0)
 	return cp.FilterCandidates(cp.backendGroup.Fallbacks())
 }
 
+func (cp *ConsensusPoller) setUsingFallbacks(using bool) {
+	if using == cp.usingFallbacks {
+		return
+	}
+	cp.usingFallbacks = using
+	event := WebhookEventFallbackDeactivated
+	if using {
+		event = WebhookEventFallbackActivated
+	}
+	cp.webhooks.Notify(event, map[string]interface{}{
+		"backend_group": cp.backendGroup.Name,
+	})
+}
+
 // filterCandidates find out what backends are the candidates to be in the consensus group
 // and create a copy of current their state
 //
@@ -758,6 +961,9 @@ func (cp *ConsensusPoller) FilterCandidates(backends []*Backend) map[*Backend]*b
 	for _, be := range backends {
 
 		bs := cp.GetBackendState(be)
+		if be.InMaintenance() {
+			continue
+		}
 		if be.forcedCandidate {
 			candidates[be] = bs
 			continue
@@ -0,0 +1,36 @@
+package proxyd
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RequestTimeoutHeader carries the milliseconds left before the client gives
+// up on a request. proxyd shortens its own timeout to it, and sets it on
+// forwarded requests so cooperating backends can stop working on requests
+// nobody waits for anymore.
+const RequestTimeoutHeader = "X-Request-Timeout"
+
+// withClientDeadline bounds ctx by the client's X-Request-Timeout, if set.
+func withClientDeadline(ctx context.Context, header http.Header) (context.Context, context.CancelFunc) {
+	ms, err := strconv.ParseInt(header.Get(RequestTimeoutHeader), 10, 64)
+	if err != nil || ms <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, time.Duration(ms)*time.Millisecond)
+}
+
+// setRequestTimeout sets X-Request-Timeout to the time left before ctx's
+// deadline, or timeout if that's sooner. A timeout of 0 doesn't apply.
+func setRequestTimeout(ctx context.Context, header http.Header, timeout time.Duration) {
+	if deadline, ok := ctx.Deadline(); ok && (timeout == 0 || time.Until(deadline) < timeout) {
+		timeout = time.Until(deadline)
+	}
+	if timeout == 0 {
+		return
+	}
+	ms := max(timeout.Milliseconds(), 1)
+	header.Set(RequestTimeoutHeader, strconv.FormatInt(ms, 10))
+}
@@ -0,0 +1,84 @@
+package proxyd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+const DebugHeader = "X-Proxyd-Debug"
+
+// requestDebug counts the backend attempts of a request whose alias opted
+// into the debug header.
+type requestDebug struct {
+	// attempts are all requests sent to backends, forwards the requests
+	// forwarded to backend groups. Attempts beyond one per forward are
+	// retries, of the same or the next backend.
+	attempts atomic.Int32
+	forwards atomic.Int32
+}
+
+func withRequestDebug(ctx context.Context) (context.Context, *requestDebug) {
+	debug := new(requestDebug)
+	return context.WithValue(ctx, ContextKeyRequestDebug, debug), debug // nolint:staticcheck
+}
+
+func getRequestDebug(ctx context.Context) *requestDebug {
+	debug, _ := ctx.Value(ContextKeyRequestDebug).(*requestDebug)
+	return debug
+}
+
+// countBackendForward counts a request forwarded to a backend group.
+func countBackendForward(ctx context.Context) {
+	if debug := getRequestDebug(ctx); debug != nil {
+		debug.forwards.Add(1)
+	}
+}
+
+// countBackendAttempt counts a request sent to a backend.
+func countBackendAttempt(ctx context.Context) {
+	if debug := getRequestDebug(ctx); debug != nil {
+		debug.attempts.Add(1)
+	}
+}
+
+func (d *requestDebug) retries() int32 {
+	return max(d.attempts.Load()-d.forwards.Load(), 0)
+}
+
+// setDebugHeader sets the X-Proxyd-Debug header, a structured field
+// dictionary (RFC 8941) of the backends that served the request, whether the
+// response came from cache, the number of retries, and the latest block of
+// the consensus of the serving groups, if any are consensus aware, e.g.
+//
+//	X-Proxyd-Debug: backend="main/node1", cache=miss, retries=1, consensus_height=1234
+func (s *Server) setDebugHeader(ctx context.Context, w http.ResponseWriter, servedBy string, cached bool) {
+	debug := getRequestDebug(ctx)
+	if debug == nil {
+		return
+	}
+	cache := "miss"
+	if cached {
+		cache = "hit"
+	}
+	fields := []string{
+		fmt.Sprintf("backend=%q", servedBy),
+		"cache=" + cache,
+		fmt.Sprintf("retries=%d", debug.retries()),
+	}
+	var height hexutil.Uint64
+	for _, backend := range strings.Split(servedBy, ", ") {
+		group, _, _ := strings.Cut(backend, "/")
+		if bg := s.BackendGroups[group]; bg != nil && bg.Consensus != nil {
+			height = max(height, bg.Consensus.GetLatestBlockNumber())
+		}
+	}
+	if height > 0 {
+		fields = append(fields, fmt.Sprintf("consensus_height=%d", uint64(height)))
+	}
+	w.Header().Set(DebugHeader, strings.Join(fields, ", "))
+}
@@ -0,0 +1,55 @@
+package proxyd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/semaphore"
+)
+
+func TestDebugHeader(t *testing.T) {
+	var calls atomic.Int32
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the first attempt fails
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","result":"0x1","id":1}`))
+	}))
+	defer node.Close()
+
+	be := NewBackend("node", node.URL, "", semaphore.NewWeighted(10), WithMaxRetries(1))
+	bg := &BackendGroup{Name: "main", Backends: []*Backend{be}}
+	tracker := NewInMemoryConsensusTracker()
+	tracker.SetLatestBlockNumber(1234)
+	bg.Consensus = NewConsensusPoller(bg, WithAsyncHandler(NewNoopAsyncHandler()), WithTracker(tracker))
+	bg.Consensus.consensusGroup = []*Backend{be}
+	s := &Server{BackendGroups: map[string]*BackendGroup{"main": bg}}
+
+	ctx, debug := withRequestDebug(context.Background())
+	req := &RPCReq{JSONRPC: JSONRPCVersion, Method: "eth_chainId", ID: json.RawMessage("1")}
+	_, servedBy, err := bg.Forward(ctx, []*RPCReq{req}, false)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, debug.retries())
+
+	w := httptest.NewRecorder()
+	s.setDebugHeader(ctx, w, servedBy, false)
+	require.Equal(t, `backend="main/node", cache=miss, retries=1, consensus_height=1234`, w.Header().Get(DebugHeader))
+
+	// only requests of aliases that opted in get the header
+	w = httptest.NewRecorder()
+	s.setDebugHeader(context.Background(), w, servedBy, true)
+	require.Empty(t, w.Header().Get(DebugHeader))
+
+	// cached responses without consensus aware groups
+	ctx, _ = withRequestDebug(context.Background())
+	w = httptest.NewRecorder()
+	s.setDebugHeader(ctx, w, "other/node", true)
+	require.Equal(t, `backend="other/node", cache=hit, retries=0`, w.Header().Get(DebugHeader))
+}
@@ -0,0 +1,144 @@
+package proxyd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DeprecatedMethodsHeader = "X-Proxyd-Deprecated"
+	SunsetHeader            = "Sunset"
+)
+
+// MethodDeprecations sunsets methods gracefully. Until its cutover, a
+// deprecated method keeps working, and HTTP responses to requests calling it
+// carry an X-Proxyd-Deprecated header naming it and its replacement, e.g.
+//
+//	X-Proxyd-Deprecated: eth_foo; replacement="eth_bar"; cutover="2025-01-01T00:00:00Z"
+//
+// along with a Sunset header (RFC 8594) with the earliest cutover. From its
+// cutover on the method is answered with ErrMethodRemoved. Requests for
+// deprecated methods are counted per auth alias, so the callers still using
+// them can be found before they break.
+type MethodDeprecations struct {
+	methods map[string]*deprecatedMethod
+	now     func() time.Time
+}
+
+type deprecatedMethod struct {
+	name        string
+	replacement string
+	message     string
+	cutover     *time.Time
+}
+
+// NewMethodDeprecations returns nil if no methods are deprecated.
+func NewMethodDeprecations(cfg map[string]DeprecatedMethodConfig) *MethodDeprecations {
+	if len(cfg) == 0 {
+		return nil
+	}
+	d := &MethodDeprecations{
+		methods: make(map[string]*deprecatedMethod, len(cfg)),
+		now:     time.Now,
+	}
+	for method, mc := range cfg {
+		message := mc.Message
+		if message == "" {
+			message = fmt.Sprintf("%s is deprecated", method)
+		}
+		d.methods[method] = &deprecatedMethod{
+			name:        method,
+			replacement: mc.Replacement,
+			message:     message,
+			cutover:     mc.Cutover,
+		}
+	}
+	return d
+}
+
+// Check returns ErrMethodRemoved if method is past its cutover. Otherwise,
+// deprecated methods are noted in ctx for SetHeaders.
+func (d *MethodDeprecations) Check(ctx context.Context, method string) error {
+	if d == nil {
+		return nil
+	}
+	m := d.methods[method]
+	if m == nil {
+		return nil
+	}
+	if m.cutover != nil && !d.now().Before(*m.cutover) {
+		RecordDeprecatedMethodRequest(ctx, method, "blocked")
+		err := ErrMethodRemoved.Clone()
+		err.Message = fmt.Sprintf("%s: %s", ErrMethodRemoved.Message, m.message)
+		data := map[string]string{"cutover": m.cutover.UTC().Format(time.RFC3339)}
+		if m.replacement != "" {
+			data["replacement"] = m.replacement
+		}
+		err.Data = mustMarshalJSON(data)
+		return err
+	}
+	RecordDeprecatedMethodRequest(ctx, method, "warned")
+	if notices, ok := ctx.Value(ContextKeyDeprecations).(*deprecationNotices); ok {
+		notices.add(m)
+	}
+	return nil
+}
+
+// deprecationNotices are the deprecated methods a request called.
+type deprecationNotices struct {
+	mu      sync.Mutex
+	methods map[string]*deprecatedMethod
+}
+
+func (n *deprecationNotices) add(m *deprecatedMethod) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.methods[m.name] = m
+}
+
+// WithNotices returns a ctx in which Check notes the deprecated methods the
+// request calls.
+func (d *MethodDeprecations) WithNotices(ctx context.Context) context.Context {
+	if d == nil {
+		return ctx
+	}
+	notices := &deprecationNotices{methods: make(map[string]*deprecatedMethod)}
+	return context.WithValue(ctx, ContextKeyDeprecations, notices) // nolint:staticcheck
+}
+
+// SetHeaders warns about the deprecated methods the request of ctx called.
+func (d *MethodDeprecations) SetHeaders(ctx context.Context, w http.ResponseWriter) {
+	notices, ok := ctx.Value(ContextKeyDeprecations).(*deprecationNotices)
+	if !ok {
+		return
+	}
+	notices.mu.Lock()
+	defer notices.mu.Unlock()
+	if len(notices.methods) == 0 {
+		return
+	}
+	var entries []string
+	var sunset *time.Time
+	for _, name := range sortedKeys(notices.methods) {
+		m := notices.methods[name]
+		entry := m.name
+		if m.replacement != "" {
+			entry += fmt.Sprintf("; replacement=%q", m.replacement)
+		}
+		if m.cutover != nil {
+			entry += fmt.Sprintf("; cutover=%q", m.cutover.UTC().Format(time.RFC3339))
+			if sunset == nil || m.cutover.Before(*sunset) {
+				sunset = m.cutover
+			}
+		}
+		entries = append(entries, entry)
+	}
+	w.Header().Set(DeprecatedMethodsHeader, strings.Join(entries, ", "))
+	if sunset != nil {
+		w.Header().Set(SunsetHeader, sunset.UTC().Format(http.TimeFormat))
+	}
+}
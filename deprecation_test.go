@@ -0,0 +1,56 @@
+package proxyd
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMethodDeprecations(t *testing.T) {
+	require.Nil(t, NewMethodDeprecations(nil))
+
+	cutover := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	later := cutover.Add(30 * 24 * time.Hour)
+	d := NewMethodDeprecations(map[string]DeprecatedMethodConfig{
+		"eth_getWork":    {Replacement: "eth_blockNumber", Message: "mining is no longer supported", Cutover: &cutover},
+		"eth_submitWork": {Cutover: &later},
+		"eth_coinbase":   {},
+	})
+	now := cutover.Add(-time.Hour)
+	d.now = func() time.Time { return now }
+
+	// before the cutover, deprecated methods work with a warning
+	ctx := d.WithNotices(context.Background())
+	require.NoError(t, d.Check(ctx, "eth_chainId"))
+	require.NoError(t, d.Check(ctx, "eth_submitWork"))
+	require.NoError(t, d.Check(ctx, "eth_getWork"))
+	require.NoError(t, d.Check(ctx, "eth_coinbase"))
+	w := httptest.NewRecorder()
+	d.SetHeaders(ctx, w)
+	require.Equal(t, `eth_coinbase, eth_getWork; replacement="eth_blockNumber"; cutover="2026-06-01T00:00:00Z", eth_submitWork; cutover="2026-07-01T00:00:00Z"`, w.Header().Get(DeprecatedMethodsHeader))
+	require.Equal(t, "Mon, 01 Jun 2026 00:00:00 GMT", w.Header().Get(SunsetHeader))
+
+	// requests without deprecated methods get no headers
+	ctx = d.WithNotices(context.Background())
+	require.NoError(t, d.Check(ctx, "eth_chainId"))
+	w = httptest.NewRecorder()
+	d.SetHeaders(ctx, w)
+	require.Empty(t, w.Header())
+
+	// from the cutover on they are blocked
+	now = cutover
+	err := d.Check(ctx, "eth_getWork")
+	require.Error(t, err)
+	rpcErr := err.(*RPCErr)
+	require.Equal(t, ErrMethodRemoved.Code, rpcErr.Code)
+	require.Equal(t, 410, rpcErr.HTTPErrorCode)
+	require.Equal(t, "rpc method has been removed: mining is no longer supported", rpcErr.Message)
+	require.JSONEq(t, `{"cutover":"2026-06-01T00:00:00Z","replacement":"eth_blockNumber"}`, string(rpcErr.Data))
+	require.NoError(t, d.Check(ctx, "eth_submitWork"))
+
+	var disabled *MethodDeprecations
+	require.NoError(t, disabled.Check(context.Background(), "eth_getWork"))
+}
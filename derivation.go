@@ -0,0 +1,295 @@
+package proxyd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	defaultDerivationInterval        = 30 * time.Second
+	defaultDerivationMaxSafeLag      = 300
+	defaultDerivationMaxFinalizedLag = 300
+)
+
+// derivationState is the derivation progress of an OP Stack backend,
+// reported by the rollup RPC of its op-node.
+type derivationState struct {
+	rollupURL string
+
+	mu      sync.RWMutex
+	stalled bool
+}
+
+func WithRollupRPC(rollupURL string) BackendOpt {
+	return func(b *Backend) {
+		if rollupURL != "" {
+			b.derivation = &derivationState{rollupURL: rollupURL}
+		}
+	}
+}
+
+// DerivationStalled returns whether the backend's safe or finalized head
+// lagged too far behind L1 when last checked.
+func (b *Backend) DerivationStalled() bool {
+	if b.derivation == nil {
+		return false
+	}
+	b.derivation.mu.RLock()
+	defer b.derivation.mu.RUnlock()
+	return b.derivation.stalled
+}
+
+// DerivationMonitor checks on an interval how far the safe and finalized
+// heads of OP Stack backends lag behind L1, as the L1 blocks their L1
+// origins are behind the L1 head and finalized block. Backends whose
+// derivation stalled are degraded for requests using the "safe" or
+// "finalized" tags, since they'd answer them with stale state.
+type DerivationMonitor struct {
+	l1URL           string
+	backends        []*Backend
+	maxSafeLag      uint64
+	maxFinalizedLag uint64
+	interval        time.Duration
+	client          *http.Client
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewDerivationMonitor returns nil if there's no L1 source or no backend
+// has a rollup RPC.
+func NewDerivationMonitor(cfg DerivationHealthConfig, l1URL string, backends map[string]*Backend) *DerivationMonitor {
+	if l1URL == "" {
+		return nil
+	}
+	m := &DerivationMonitor{
+		l1URL:           l1URL,
+		maxSafeLag:      cfg.MaxSafeLag,
+		maxFinalizedLag: cfg.MaxFinalizedLag,
+		interval:        time.Duration(cfg.Interval),
+		stop:            make(chan struct{}),
+	}
+	for _, be := range backends {
+		if be.derivation != nil {
+			m.backends = append(m.backends, be)
+		}
+	}
+	if len(m.backends) == 0 {
+		return nil
+	}
+	if m.maxSafeLag == 0 {
+		m.maxSafeLag = defaultDerivationMaxSafeLag
+	}
+	if m.maxFinalizedLag == 0 {
+		m.maxFinalizedLag = defaultDerivationMaxFinalizedLag
+	}
+	if m.interval == 0 {
+		m.interval = defaultDerivationInterval
+	}
+	m.client = &http.Client{Timeout: 5 * time.Second}
+	return m
+}
+
+// Start checks the backends until Stop is called.
+func (m *DerivationMonitor) Start() {
+	if m == nil {
+		return
+	}
+	m.update()
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-m.stop:
+				return
+			case <-ticker.C:
+				m.update()
+			}
+		}
+	}()
+}
+
+func (m *DerivationMonitor) Stop() {
+	if m == nil {
+		return
+	}
+	close(m.stop)
+	m.wg.Wait()
+}
+
+// syncStatus is the part of op-node's optimism_syncStatus used here.
+type syncStatus struct {
+	SafeL2 struct {
+		L1Origin struct {
+			Number uint64 `json:"number"`
+		} `json:"l1origin"`
+	} `json:"safe_l2"`
+	FinalizedL2 struct {
+		L1Origin struct {
+			Number uint64 `json:"number"`
+		} `json:"l1origin"`
+	} `json:"finalized_l2"`
+}
+
+func (m *DerivationMonitor) update() {
+	ctx, cancel := context.WithTimeout(context.Background(), m.interval)
+	defer cancel()
+
+	// without L1 there's nothing to compare with, so the backends are left
+	// as they were
+	l1Head, err := m.l1BlockNumber(ctx, "latest")
+	if err != nil {
+		log.Warn("error fetching L1 head for derivation health", "err", err)
+		return
+	}
+	l1Finalized, err := m.l1BlockNumber(ctx, "finalized")
+	if err != nil {
+		log.Warn("error fetching L1 finalized block for derivation health", "err", err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, be := range m.backends {
+		wg.Add(1)
+		go func(be *Backend) {
+			defer wg.Done()
+			// backends whose op-node can't be reached can't be trusted to
+			// be deriving either
+			stalled := true
+			var status syncStatus
+			if err := callRPC(ctx, m.client, be.derivation.rollupURL, "optimism_syncStatus", &status); err != nil {
+				log.Warn("error fetching sync status", "name", be.Name, "err", err)
+			} else {
+				safeLag := lag(l1Head, status.SafeL2.L1Origin.Number)
+				finalizedLag := lag(l1Finalized, status.FinalizedL2.L1Origin.Number)
+				RecordBackendDerivationLag(be, "safe", safeLag)
+				RecordBackendDerivationLag(be, "finalized", finalizedLag)
+				stalled = safeLag > m.maxSafeLag || finalizedLag > m.maxFinalizedLag
+			}
+
+			d := be.derivation
+			d.mu.Lock()
+			changed := d.stalled != stalled
+			d.stalled = stalled
+			d.mu.Unlock()
+			if changed {
+				log.Info("backend derivation health changed", "name", be.Name, "stalled", stalled)
+			}
+			RecordBackendDerivationStalled(be, stalled)
+		}(be)
+	}
+	wg.Wait()
+}
+
+func (m *DerivationMonitor) l1BlockNumber(ctx context.Context, tag string) (uint64, error) {
+	var block struct {
+		Number hexutil.Uint64 `json:"number"`
+	}
+	if err := callRPC(ctx, m.client, m.l1URL, "eth_getBlockByNumber", &block, tag, false); err != nil {
+		return 0, err
+	}
+	return uint64(block.Number), nil
+}
+
+func lag(head, block uint64) uint64 {
+	if block >= head {
+		return 0
+	}
+	return head - block
+}
+
+// callRPC calls a JSON-RPC method out of band of the backends' request path,
+// decoding its result into result.
+func callRPC(ctx context.Context, client *http.Client, url string, method string, result interface{}, params ...interface{}) error {
+	if params == nil {
+		params = []interface{}{}
+	}
+	rawParams, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	body := mustMarshalJSON(&RPCReq{JSONRPC: JSONRPCVersion, Method: method, Params: rawParams, ID: json.RawMessage("1")})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	var rpcRes struct {
+		Result json.RawMessage `json:"result"`
+		Error  *RPCErr         `json:"error"`
+	}
+	if err := json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(&rpcRes); err != nil {
+		return err
+	}
+	if rpcRes.Error != nil {
+		return fmt.Errorf("%s: %w", method, rpcRes.Error)
+	}
+	return json.Unmarshal(rpcRes.Result, result)
+}
+
+// derivationOrderedBackends moves the backends whose derivation stalled to
+// the end for requests using the "safe" or "finalized" tags, so they're only
+// used as a last resort.
+func derivationOrderedBackends(backends []*Backend, rpcReqs []*RPCReq) []*Backend {
+	tagged := false
+	for _, req := range rpcReqs {
+		if usesSafeOrFinalizedTag(req.Params) {
+			tagged = true
+			break
+		}
+	}
+	if !tagged {
+		return backends
+	}
+	healthy := make([]*Backend, 0, len(backends))
+	var stalled []*Backend
+	for _, be := range backends {
+		if be.DerivationStalled() {
+			stalled = append(stalled, be)
+		} else {
+			healthy = append(healthy, be)
+		}
+	}
+	return append(healthy, stalled...)
+}
+
+// usesSafeOrFinalizedTag returns whether any param, or field of a param
+// object such as an eth_getLogs filter, is the "safe" or "finalized" tag.
+func usesSafeOrFinalizedTag(params json.RawMessage) bool {
+	var p []interface{}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return false
+	}
+	for _, v := range p {
+		switch val := v.(type) {
+		case string:
+			if val == "safe" || val == "finalized" {
+				return true
+			}
+		case map[string]interface{}:
+			for _, field := range val {
+				if s, ok := field.(string); ok && (s == "safe" || s == "finalized") {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
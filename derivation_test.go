@@ -0,0 +1,55 @@
+package proxyd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/semaphore"
+)
+
+func TestDerivationMonitor(t *testing.T) {
+	l1 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req RPCReq
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		number := "0x3e8" // 1000
+		if string(req.Params) == `["finalized",false]` {
+			number = "0x384" // 900
+		}
+		_, _ = fmt.Fprintf(w, `{"jsonrpc":"2.0","result":{"number":%q},"id":1}`, number)
+	}))
+	defer l1.Close()
+	opNode := func(safeOrigin, finalizedOrigin uint64) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = fmt.Fprintf(w, `{"jsonrpc":"2.0","result":{"safe_l2":{"number":5,"l1origin":{"number":%d}},"finalized_l2":{"number":3,"l1origin":{"number":%d}}},"id":1}`, safeOrigin, finalizedOrigin)
+		}))
+	}
+	healthy := opNode(990, 890)
+	defer healthy.Close()
+	stalled := opNode(800, 890)
+	defer stalled.Close()
+
+	sem := semaphore.NewWeighted(10)
+	a := NewBackend("stalled", "http://127.0.0.1:1", "", sem, WithRollupRPC(stalled.URL))
+	b := NewBackend("healthy", "http://127.0.0.1:1", "", sem, WithRollupRPC(healthy.URL))
+	c := NewBackend("down", "http://127.0.0.1:1", "", sem, WithRollupRPC("http://127.0.0.1:1"))
+	d := NewBackend("unchecked", "http://127.0.0.1:1", "", sem)
+
+	require.Nil(t, NewDerivationMonitor(DerivationHealthConfig{}, "", map[string]*Backend{"a": a}))
+	m := NewDerivationMonitor(DerivationHealthConfig{MaxSafeLag: 100, MaxFinalizedLag: 100}, l1.URL, map[string]*Backend{"a": a, "b": b, "c": c, "d": d})
+	m.update()
+
+	require.True(t, a.DerivationStalled())
+	require.False(t, b.DerivationStalled())
+	require.True(t, c.DerivationStalled())
+	require.False(t, d.DerivationStalled())
+
+	req := func(params string) *RPCReq { return &RPCReq{Method: "eth_call", Params: json.RawMessage(params)} }
+	backends := []*Backend{a, b, c, d}
+	require.Equal(t, []*Backend{b, d, a, c}, derivationOrderedBackends(backends, []*RPCReq{req(`[{},"safe"]`)}))
+	require.Equal(t, []*Backend{b, d, a, c}, derivationOrderedBackends(backends, []*RPCReq{req(`[{"fromBlock":"0x1","toBlock":"finalized"}]`)}))
+	require.Equal(t, backends, derivationOrderedBackends(backends, []*RPCReq{req(`[{},"latest"]`)}))
+}
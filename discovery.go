@@ -0,0 +1,364 @@
+package proxyd
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"golang.org/x/sync/semaphore"
+)
+
+const (
+	defaultDiscoveryInterval = 10 * time.Second
+	discoveryTimeout         = 10 * time.Second
+
+	serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+)
+
+// endpointResolver returns the host:port of every endpoint that should
+// receive traffic.
+type endpointResolver interface {
+	Resolve(ctx context.Context) ([]string, error)
+}
+
+// backendTemplate holds the resolved settings of a configured backend, so
+// that discovered backends can be created like it.
+type backendTemplate struct {
+	rpcURL string
+	wsURL  string
+	opts   []BackendOpt
+}
+
+// BackendDiscovery keeps a backend group in sync with the endpoints of a
+// Kubernetes service or DNS SRV record. Each endpoint becomes a backend of its
+// own, so health is tracked per pod. Backends statically configured for the
+// group are kept.
+type BackendDiscovery struct {
+	group      *BackendGroup
+	resolver   endpointResolver
+	newBackend func(addr string) (*Backend, error)
+	static     []*Backend
+	interval   time.Duration
+
+	backends map[string]*Backend
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+func NewBackendDiscovery(group *BackendGroup, cfg *DiscoveryConfig, template *backendTemplate, sem *semaphore.Weighted) (*BackendDiscovery, error) {
+	resolver, err := newEndpointResolver(cfg)
+	if err != nil {
+		return nil, err
+	}
+	newBackend := func(addr string) (*Backend, error) {
+		rpcURL, err := replaceURLHost(template.rpcURL, addr, true)
+		if err != nil {
+			return nil, err
+		}
+		wsURL, err := replaceURLHost(template.wsURL, addr, false)
+		if err != nil {
+			return nil, err
+		}
+		return NewBackend(cfg.Template+"/"+addr, rpcURL, wsURL, sem, template.opts...), nil
+	}
+	return newBackendDiscovery(group, cfg, resolver, newBackend), nil
+}
+
+func newBackendDiscovery(group *BackendGroup, cfg *DiscoveryConfig, resolver endpointResolver, newBackend func(string) (*Backend, error)) *BackendDiscovery {
+	d := &BackendDiscovery{
+		group:      group,
+		resolver:   resolver,
+		newBackend: newBackend,
+		static:     group.members(),
+		interval:   defaultDiscoveryInterval,
+		backends:   make(map[string]*Backend),
+		stop:       make(chan struct{}),
+	}
+	if cfg.Interval > 0 {
+		d.interval = time.Duration(cfg.Interval)
+	}
+	return d
+}
+
+func newEndpointResolver(cfg *DiscoveryConfig) (endpointResolver, error) {
+	if err := checkDiscoveryConfig(cfg); err != nil {
+		return nil, err
+	}
+	if cfg.SRV != "" {
+		return &srvResolver{name: cfg.SRV}, nil
+	}
+	return newKubernetesResolver(cfg)
+}
+
+func checkDiscoveryConfig(cfg *DiscoveryConfig) error {
+	kubernetes := cfg.KubernetesService != "" || cfg.KubernetesLabelSelector != ""
+	switch {
+	case cfg.SRV != "" && kubernetes:
+		return errors.New("discovery srv and kubernetes settings are mutually exclusive")
+	case cfg.SRV == "" && !kubernetes:
+		return errors.New("discovery needs either srv or kubernetes_service/kubernetes_label_selector")
+	}
+	return nil
+}
+
+// Start resolves the endpoints once, then keeps them up to date in the
+// background until Stop is called.
+func (d *BackendDiscovery) Start() {
+	if err := d.refresh(); err != nil {
+		log.Error("error discovering backends", "backend_group", d.group.Name, "err", err)
+	}
+
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		ticker := time.NewTicker(d.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-d.stop:
+				return
+			case <-ticker.C:
+				if err := d.refresh(); err != nil {
+					log.Error("error discovering backends", "backend_group", d.group.Name, "err", err)
+				}
+			}
+		}
+	}()
+}
+
+func (d *BackendDiscovery) Stop() {
+	close(d.stop)
+	d.wg.Wait()
+}
+
+// refresh replaces the discovered backends of the group. Backends of
+// endpoints that are still present are kept along with their health.
+func (d *BackendDiscovery) refresh() error {
+	ctx, cancel := context.WithTimeout(context.Background(), discoveryTimeout)
+	defer cancel()
+	addrs, err := d.resolver.Resolve(ctx)
+	if err != nil {
+		RecordDiscoveryError(d.group.Name)
+		return err
+	}
+
+	next := make(map[string]*Backend, len(addrs))
+	for _, addr := range addrs {
+		if b := d.backends[addr]; b != nil {
+			next[addr] = b
+			continue
+		}
+		b, err := d.newBackend(addr)
+		if err != nil {
+			return err
+		}
+		log.Info("discovered backend", "backend_group", d.group.Name, "name", b.Name)
+		next[addr] = b
+	}
+	for addr, b := range d.backends {
+		if next[addr] == nil {
+			log.Info("removed discovered backend", "backend_group", d.group.Name, "name", b.Name)
+		}
+	}
+	d.backends = next
+
+	members := make([]*Backend, 0, len(d.static)+len(next))
+	members = append(members, d.static...)
+	for _, addr := range sortedKeys(next) {
+		members = append(members, next[addr])
+	}
+	d.group.SetBackends(members)
+	RecordDiscoveredBackends(d.group.Name, len(next))
+	return nil
+}
+
+// replaceURLHost points raw at addr. If withPort is false only the host is
+// replaced and the port of raw is kept.
+func replaceURLHost(raw, addr string, withPort bool) (string, error) {
+	if raw == "" {
+		return "", nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", err
+	}
+	if withPort {
+		u.Host = addr
+	} else {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return "", err
+		}
+		if port := u.Port(); port != "" {
+			u.Host = net.JoinHostPort(host, port)
+		} else {
+			u.Host = host
+			if strings.Contains(host, ":") {
+				u.Host = "[" + host + "]"
+			}
+		}
+	}
+	return u.String(), nil
+}
+
+type srvResolver struct {
+	name string
+}
+
+func (r *srvResolver) Resolve(ctx context.Context) ([]string, error) {
+	_, records, err := net.DefaultResolver.LookupSRV(ctx, "", "", r.name)
+	if err != nil {
+		return nil, err
+	}
+	addrs := make([]string, 0, len(records))
+	for _, rec := range records {
+		target := strings.TrimSuffix(rec.Target, ".")
+		addrs = append(addrs, net.JoinHostPort(target, strconv.Itoa(int(rec.Port))))
+	}
+	sort.Strings(addrs)
+	return addrs, nil
+}
+
+// kubernetesResolver lists the ready endpoints of EndpointSlices through the
+// Kubernetes API, authenticating with the pod's service account.
+type kubernetesResolver struct {
+	url       string
+	portName  string
+	tokenFile string
+	client    *http.Client
+}
+
+func newKubernetesResolver(cfg *DiscoveryConfig) (*kubernetesResolver, error) {
+	apiURL := cfg.KubernetesAPIURL
+	if apiURL == "" {
+		host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+		if host == "" || port == "" {
+			return nil, errors.New("not running in kubernetes, set discovery.kubernetes_api_url")
+		}
+		apiURL = "https://" + net.JoinHostPort(host, port)
+	}
+
+	namespace := cfg.KubernetesNamespace
+	if namespace == "" {
+		namespace = "default"
+		if data, err := os.ReadFile(serviceAccountDir + "/namespace"); err == nil {
+			namespace = strings.TrimSpace(string(data))
+		}
+	}
+	selector := cfg.KubernetesLabelSelector
+	if selector == "" {
+		selector = "kubernetes.io/service-name=" + cfg.KubernetesService
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if ca, err := os.ReadFile(serviceAccountDir + "/ca.crt"); err == nil {
+		pool := x509.NewCertPool()
+		pool.AppendCertsFromPEM(ca)
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+
+	r := &kubernetesResolver{
+		url: fmt.Sprintf("%s/apis/discovery.k8s.io/v1/namespaces/%s/endpointslices?labelSelector=%s",
+			strings.TrimSuffix(apiURL, "/"), url.PathEscape(namespace), url.QueryEscape(selector)),
+		portName: cfg.PortName,
+		client:   &http.Client{Timeout: discoveryTimeout, Transport: transport},
+	}
+	if cfg.KubernetesAPIURL == "" {
+		r.tokenFile = serviceAccountDir + "/token"
+	}
+	return r, nil
+}
+
+type endpointSliceList struct {
+	Items []struct {
+		Endpoints []struct {
+			Addresses  []string `json:"addresses"`
+			Conditions struct {
+				Ready *bool `json:"ready"`
+			} `json:"conditions"`
+		} `json:"endpoints"`
+		Ports []struct {
+			Name *string `json:"name"`
+			Port *int32  `json:"port"`
+		} `json:"ports"`
+	} `json:"items"`
+}
+
+func (r *kubernetesResolver) Resolve(ctx context.Context) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if r.tokenFile != "" {
+		// service account tokens are rotated, so read it every time
+		token, err := os.ReadFile(r.tokenFile)
+		if err != nil {
+			return nil, wrapErr(err, "error reading service account token")
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+	res, err := r.client.Do(req)
+	if err != nil {
+		return nil, wrapErr(err, "error listing endpointslices")
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("kubernetes api returned status %d", res.StatusCode)
+	}
+
+	var list endpointSliceList
+	if err := json.NewDecoder(res.Body).Decode(&list); err != nil {
+		return nil, wrapErr(err, "error decoding endpointslices")
+	}
+
+	seen := make(map[string]bool)
+	var addrs []string
+	for _, slice := range list.Items {
+		var port int32
+		for _, p := range slice.Ports {
+			if p.Port == nil {
+				continue
+			}
+			name := ""
+			if p.Name != nil {
+				name = *p.Name
+			}
+			if r.portName == "" || name == r.portName {
+				port = *p.Port
+				break
+			}
+		}
+		if port == 0 {
+			continue
+		}
+		for _, ep := range slice.Endpoints {
+			// a missing ready condition means ready
+			if ep.Conditions.Ready != nil && !*ep.Conditions.Ready {
+				continue
+			}
+			for _, ip := range ep.Addresses {
+				addr := net.JoinHostPort(ip, strconv.Itoa(int(port)))
+				if !seen[addr] {
+					seen[addr] = true
+					addrs = append(addrs, addr)
+				}
+			}
+		}
+	}
+	sort.Strings(addrs)
+	return addrs, nil
+}
@@ -0,0 +1,81 @@
+package proxyd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKubernetesDiscovery(t *testing.T) {
+	var mu sync.Mutex
+	slices := `{"items":[
+		{"endpoints":[
+			{"addresses":["10.0.0.1"],"conditions":{"ready":true}},
+			{"addresses":["10.0.0.2"],"conditions":{"ready":false}},
+			{"addresses":["10.0.0.3"]}
+		],"ports":[{"name":"metrics","port":6060},{"name":"rpc","port":8545}]}
+	]}`
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/apis/discovery.k8s.io/v1/namespaces/nodes/endpointslices", r.URL.Path)
+		require.Equal(t, "kubernetes.io/service-name=geth", r.URL.Query().Get("labelSelector"))
+		mu.Lock()
+		defer mu.Unlock()
+		_, _ = w.Write([]byte(slices))
+	}))
+	defer api.Close()
+
+	static := NewBackend("static", "http://static:8545", "", nil)
+	bg := &BackendGroup{Name: "main", Backends: []*Backend{static}}
+	template := &backendTemplate{rpcURL: "http://geth:8545/rpc", wsURL: "ws://geth:8546"}
+	d, err := NewBackendDiscovery(bg, &DiscoveryConfig{
+		Template:            "geth",
+		KubernetesService:   "geth",
+		KubernetesNamespace: "nodes",
+		KubernetesAPIURL:    api.URL,
+		PortName:            "rpc",
+	}, template, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, d.refresh())
+	members := bg.members()
+	require.Len(t, members, 3)
+	require.Equal(t, "static", members[0].Name)
+	require.Equal(t, "geth/10.0.0.1:8545", members[1].Name)
+	require.Equal(t, "http://10.0.0.1:8545/rpc", members[1].rpcURL)
+	require.Equal(t, "ws://10.0.0.1:8546", members[1].wsURL)
+	require.Equal(t, "geth/10.0.0.3:8545", members[2].Name)
+	require.Len(t, bg.Primaries(), 3)
+
+	// pods that stay keep their backend, and its health
+	first := members[1]
+	mu.Lock()
+	slices = `{"items":[{"endpoints":[
+		{"addresses":["10.0.0.1"]},
+		{"addresses":["10.0.0.4"]}
+	],"ports":[{"name":"rpc","port":8545}]}]}`
+	mu.Unlock()
+	require.NoError(t, d.refresh())
+	members = bg.members()
+	require.Len(t, members, 3)
+	require.Same(t, first, members[1])
+	require.Equal(t, "geth/10.0.0.4:8545", members[2].Name)
+
+	// a failed lookup keeps the current backends
+	api.Close()
+	require.Error(t, d.refresh())
+	require.Len(t, bg.members(), 3)
+}
+
+func TestDiscoveryConfig(t *testing.T) {
+	require.Error(t, checkDiscoveryConfig(&DiscoveryConfig{}))
+	require.Error(t, checkDiscoveryConfig(&DiscoveryConfig{SRV: "_rpc._tcp.geth", KubernetesService: "geth"}))
+	require.NoError(t, checkDiscoveryConfig(&DiscoveryConfig{SRV: "_rpc._tcp.geth"}))
+	require.NoError(t, checkDiscoveryConfig(&DiscoveryConfig{KubernetesLabelSelector: "app=geth"}))
+
+	u, err := replaceURLHost("wss://geth/ws", "[fd00::1]:8545", false)
+	require.NoError(t, err)
+	require.Equal(t, "wss://[fd00::1]/ws", u)
+}
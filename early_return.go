@@ -0,0 +1,171 @@
+package proxyd
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+const (
+	EarlyReturnStatusMethod = "proxyd_getTransactionStatus"
+
+	EarlyReturnPending  = "pending"
+	EarlyReturnAccepted = "accepted"
+	EarlyReturnFailed   = "failed"
+
+	defaultEarlyReturnTimeout   = 30 * time.Second
+	defaultEarlyReturnStatusTTL = 10 * time.Minute
+	earlyReturnMemoryLimit      = 100000
+)
+
+// EarlyReturn answers transaction submissions of trusted callers with the
+// transaction hash, computed locally, as soon as they pass proxyd's checks,
+// and forwards them in the background. Callers confirm the outcome with the
+// proxyd_getTransactionStatus method, which takes the hash.
+type EarlyReturn struct {
+	callers   map[string]bool
+	timeout   time.Duration
+	statusTTL time.Duration
+
+	mu       sync.Mutex
+	statuses *lru.Cache
+
+	// forwards tracks the transactions being forwarded in the background
+	forwards sync.WaitGroup
+}
+
+// EarlyReturnStatus is the outcome of forwarding a transaction.
+type EarlyReturnStatus struct {
+	Status  string `json:"status"`
+	Backend string `json:"backend,omitempty"`
+	Error   string `json:"error,omitempty"`
+
+	expires time.Time
+}
+
+func NewEarlyReturn(cfg EarlyReturnConfig) *EarlyReturn {
+	e := &EarlyReturn{
+		callers:   make(map[string]bool, len(cfg.Callers)),
+		timeout:   time.Duration(cfg.Timeout),
+		statusTTL: time.Duration(cfg.StatusTTL),
+	}
+	for _, caller := range cfg.Callers {
+		e.callers[caller] = true
+	}
+	if e.timeout == 0 {
+		e.timeout = defaultEarlyReturnTimeout
+	}
+	if e.statusTTL == 0 {
+		e.statusTTL = defaultEarlyReturnStatusTTL
+	}
+	e.statuses, _ = lru.New(earlyReturnMemoryLimit)
+	return e
+}
+
+// Applies returns whether the caller of the request is trusted with early
+// returns.
+func (e *EarlyReturn) Applies(ctx context.Context) bool {
+	return e != nil && e.callers[GetAuthCtx(ctx)]
+}
+
+// Forward forwards the transaction to the backend group in the background,
+// and returns its hash. Once the backends answer, done is called with their
+// response, or an error response if forwarding failed, and the backend that
+// served it.
+func (e *EarlyReturn) Forward(ctx context.Context, req *RPCReq, tx *types.Transaction, bg *BackendGroup, done func(ctx context.Context, res *RPCRes, servedBy string)) *RPCRes {
+	hash := tx.Hash()
+	e.setStatus(hash, EarlyReturnStatus{Status: EarlyReturnPending})
+	RecordEarlyReturn(EarlyReturnPending)
+
+	e.forwards.Add(1)
+	go func() {
+		defer e.forwards.Done()
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), e.timeout)
+		defer cancel()
+		status := EarlyReturnStatus{Status: EarlyReturnAccepted}
+		res, servedBy, err := bg.Forward(ctx, []*RPCReq{req}, false)
+		var final *RPCRes
+		switch {
+		case err != nil:
+			status = EarlyReturnStatus{Status: EarlyReturnFailed, Error: err.Error()}
+			final = NewRPCErrorRes(req.ID, err)
+		case len(res) != 1:
+			status = EarlyReturnStatus{Status: EarlyReturnFailed, Error: "unexpected response"}
+			final = NewRPCErrorRes(req.ID, ErrBackendBadResponse)
+		default:
+			if res[0].IsError() {
+				status = EarlyReturnStatus{Status: EarlyReturnFailed, Error: res[0].Error.Message}
+			}
+			final = res[0]
+		}
+		if _, backend, ok := strings.Cut(servedBy, "/"); ok {
+			status.Backend = backend
+		}
+		if status.Status == EarlyReturnFailed {
+			log.Warn("early returned transaction failed", "req_id", GetReqID(ctx), "hash", hash, "err", status.Error)
+		}
+		e.setStatus(hash, status)
+		RecordEarlyReturn(status.Status)
+		if done != nil {
+			done(ctx, final, servedBy)
+		}
+	}()
+
+	return &RPCRes{
+		JSONRPC: JSONRPCVersion,
+		Result:  hash,
+		ID:      req.ID,
+	}
+}
+
+// Handles returns whether the method is served by EarlyReturn.
+func (e *EarlyReturn) Handles(method string) bool {
+	return e != nil && method == EarlyReturnStatusMethod
+}
+
+// Status answers proxyd_getTransactionStatus with the outcome of forwarding
+// a transaction, or null if it's unknown or expired. Only trusted callers
+// may check statuses.
+func (e *EarlyReturn) Status(ctx context.Context, req *RPCReq) *RPCRes {
+	if !e.Applies(ctx) {
+		return NewRPCErrorRes(req.ID, ErrMethodNotWhitelisted)
+	}
+	var params []common.Hash
+	if err := json.Unmarshal(req.Params, &params); err != nil || len(params) != 1 {
+		return NewRPCErrorRes(req.ID, ErrInvalidParams("expected a transaction hash"))
+	}
+	res := &RPCRes{JSONRPC: JSONRPCVersion, ID: req.ID}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if val, ok := e.statuses.Get(params[0]); ok {
+		status := val.(EarlyReturnStatus)
+		if time.Now().Before(status.expires) {
+			res.Result = status
+		} else {
+			e.statuses.Remove(params[0])
+		}
+	}
+	return res
+}
+
+// Shutdown waits for the transactions being forwarded in the background.
+func (e *EarlyReturn) Shutdown() {
+	if e == nil {
+		return
+	}
+	e.forwards.Wait()
+}
+
+func (e *EarlyReturn) setStatus(hash common.Hash, status EarlyReturnStatus) {
+	status.expires = time.Now().Add(e.statusTTL)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.statuses.Add(hash, status)
+}
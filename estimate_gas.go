@@ -0,0 +1,131 @@
+package proxyd
+
+import (
+	"crypto/sha256"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+const estimateGasMemoryLimit = 10000
+
+// EstimateGas adjusts eth_estimateGas handling: estimates are sent to a
+// designated backend first, scaled by a multiplier and capped at a ceiling,
+// and reused for identical calls for a short while.
+type EstimateGas struct {
+	backend    string
+	multiplier float64
+	ceiling    uint64
+	ttl        time.Duration
+
+	mu     sync.Mutex
+	recent *lru.Cache
+}
+
+type cachedEstimate struct {
+	result  interface{}
+	expires time.Time
+}
+
+func NewEstimateGas(cfg EstimateGasConfig) *EstimateGas {
+	e := &EstimateGas{
+		backend:    cfg.Backend,
+		multiplier: cfg.Multiplier,
+		ceiling:    cfg.Ceiling,
+		ttl:        time.Duration(cfg.CacheTTL),
+	}
+	if e.ttl > 0 {
+		e.recent, _ = lru.New(estimateGasMemoryLimit)
+	}
+	return e
+}
+
+// Applies reports whether req is an estimate handled by e.
+func (e *EstimateGas) Applies(req *RPCReq) bool {
+	return e != nil && req.Method == "eth_estimateGas"
+}
+
+// Backend returns the backend estimates are sent to first, if any.
+func (e *EstimateGas) Backend() string {
+	if e == nil {
+		return ""
+	}
+	return e.backend
+}
+
+// Lookup returns the estimate for an earlier call identical to req in the
+// backend group, if any, with the ID of req.
+func (e *EstimateGas) Lookup(group string, req *RPCReq) *RPCRes {
+	if !e.Applies(req) || e.recent == nil {
+		return nil
+	}
+	key := estimateGasKey(group, req)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	val, ok := e.recent.Get(key)
+	if !ok {
+		return nil
+	}
+	est := val.(cachedEstimate)
+	if time.Now().After(est.expires) {
+		e.recent.Remove(key)
+		return nil
+	}
+	RecordEstimateGasCacheHit()
+	return &RPCRes{
+		JSONRPC: JSONRPCVersion,
+		Result:  est.result,
+		ID:      req.ID,
+	}
+}
+
+// Response clamps the estimate in res and remembers it for identical calls.
+func (e *EstimateGas) Response(group string, req *RPCReq, res *RPCRes) {
+	if !e.Applies(req) || res == nil || res.IsError() {
+		return
+	}
+	res.Result = e.clamp(res.Result)
+	if e.recent == nil {
+		return
+	}
+	est := cachedEstimate{result: res.Result, expires: time.Now().Add(e.ttl)}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.recent.Add(estimateGasKey(group, req), est)
+}
+
+// clamp scales a hex quantity estimate by the multiplier and caps it at the
+// ceiling. Results that aren't hex quantities are returned unchanged.
+func (e *EstimateGas) clamp(result interface{}) interface{} {
+	s, ok := result.(string)
+	if !ok {
+		return result
+	}
+	gas, err := hexutil.DecodeUint64(s)
+	if err != nil {
+		return result
+	}
+	clamped := gas
+	if e.multiplier > 0 {
+		scaled := float64(gas) * e.multiplier
+		if scaled >= float64(^uint64(0)) {
+			clamped = ^uint64(0)
+		} else {
+			clamped = uint64(scaled)
+		}
+	}
+	if e.ceiling > 0 && clamped > e.ceiling {
+		clamped = e.ceiling
+	}
+	if clamped == gas {
+		return result
+	}
+	RecordEstimateGasClamped()
+	return hexutil.EncodeUint64(clamped)
+}
+
+func estimateGasKey(group string, req *RPCReq) [sha256.Size]byte {
+	return sha256.Sum256(append([]byte(group+":"), req.Params...))
+}
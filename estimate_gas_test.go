@@ -0,0 +1,61 @@
+package proxyd
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimateGas(t *testing.T) {
+	e := NewEstimateGas(EstimateGasConfig{
+		CacheTTL:   TOMLDuration(50 * time.Millisecond),
+		Multiplier: 1.5,
+		Ceiling:    60000,
+		Backend:    "accurate",
+	})
+	require.Equal(t, "accurate", e.Backend())
+
+	estimate := func(id, params string) *RPCReq {
+		return &RPCReq{JSONRPC: "2.0", Method: "eth_estimateGas", Params: json.RawMessage(params), ID: json.RawMessage(id)}
+	}
+
+	// estimates are scaled by the multiplier
+	req := estimate("1", `[{"to":"0x01"}]`)
+	require.Nil(t, e.Lookup("main", req))
+	res := &RPCRes{JSONRPC: "2.0", Result: "0x5208", ID: req.ID}
+	e.Response("main", req, res)
+	require.Equal(t, "0x7b0c", res.Result)
+
+	// identical calls get the clamped estimate with their own ID
+	cached := e.Lookup("main", estimate("2", `[{"to":"0x01"}]`))
+	require.NotNil(t, cached)
+	require.Equal(t, "0x7b0c", cached.Result)
+	require.Equal(t, json.RawMessage("2"), cached.ID)
+
+	// others are forwarded
+	require.Nil(t, e.Lookup("other", estimate("3", `[{"to":"0x01"}]`)))
+	require.Nil(t, e.Lookup("main", estimate("4", `[{"to":"0x02"}]`)))
+	require.Nil(t, e.Lookup("main", &RPCReq{JSONRPC: "2.0", Method: "eth_call", Params: json.RawMessage(`[{"to":"0x01"}]`), ID: json.RawMessage("5")}))
+
+	// and capped at the ceiling
+	big := estimate("6", `[{"to":"0x03"}]`)
+	res = &RPCRes{JSONRPC: "2.0", Result: "0xc350", ID: big.ID}
+	e.Response("main", big, res)
+	require.Equal(t, "0xea60", res.Result)
+
+	// errors are neither changed nor remembered
+	failed := estimate("7", `[{"to":"0x04"}]`)
+	e.Response("main", failed, NewRPCErrorRes(failed.ID, ErrInternal))
+	require.Nil(t, e.Lookup("main", failed))
+
+	// nor are estimates past the TTL
+	time.Sleep(60 * time.Millisecond)
+	require.Nil(t, e.Lookup("main", req))
+
+	var disabled *EstimateGas
+	disabled.Response("main", req, res)
+	require.Nil(t, disabled.Lookup("main", req))
+	require.Empty(t, disabled.Backend())
+}
@@ -0,0 +1,245 @@
+package proxyd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/nats-io/nats.go"
+	"github.com/segmentio/kafka-go"
+)
+
+const (
+	EventSinkKafka = "kafka"
+	EventSinkNATS  = "nats"
+
+	defaultEventBufferSize    = 10000
+	defaultEventBatchSize     = 100
+	defaultEventFlushInterval = time.Second
+	eventPublishTimeout       = 10 * time.Second
+)
+
+// RequestEvent is the telemetry record published for each RPC request.
+type RequestEvent struct {
+	Time      time.Time `json:"time"`
+	ReqID     string    `json:"req_id"`
+	Method    string    `json:"method"`
+	Key       string    `json:"key"`
+	Backend   string    `json:"backend,omitempty"`
+	Status    string    `json:"status"`
+	LatencyMS int64     `json:"latency_ms"`
+	TxHash    string    `json:"tx_hash,omitempty"`
+}
+
+// EventSink delivers batches of encoded events to a message broker.
+type EventSink interface {
+	Publish(ctx context.Context, events [][]byte) error
+	Close() error
+}
+
+// EventPublisher batches request events and hands them to an EventSink in the
+// background. When the buffer is full events are dropped rather than slowing
+// down requests.
+type EventPublisher struct {
+	sink          EventSink
+	events        chan *RequestEvent
+	batchSize     int
+	flushInterval time.Duration
+	done          chan struct{}
+	closeOnce     sync.Once
+}
+
+func NewEventPublisher(cfg EventsConfig) (*EventPublisher, error) {
+	if len(cfg.URLs) == 0 {
+		return nil, fmt.Errorf("events.urls must not be empty")
+	}
+	if cfg.Topic == "" {
+		return nil, fmt.Errorf("events.topic must not be empty")
+	}
+
+	var sink EventSink
+	switch cfg.Sink {
+	case EventSinkKafka:
+		sink = newKafkaEventSink(cfg.URLs, cfg.Topic)
+	case EventSinkNATS:
+		var err error
+		sink, err = newNATSEventSink(cfg.URLs, cfg.Topic)
+		if err != nil {
+			return nil, wrapErr(err, "error connecting to nats")
+		}
+	default:
+		return nil, fmt.Errorf("invalid events.sink %q, must be %s or %s", cfg.Sink, EventSinkKafka, EventSinkNATS)
+	}
+	return newEventPublisher(sink, cfg), nil
+}
+
+func newEventPublisher(sink EventSink, cfg EventsConfig) *EventPublisher {
+	p := &EventPublisher{
+		sink:          sink,
+		events:        make(chan *RequestEvent, defaultEventBufferSize),
+		batchSize:     defaultEventBatchSize,
+		flushInterval: defaultEventFlushInterval,
+		done:          make(chan struct{}),
+	}
+	if cfg.BufferSize > 0 {
+		p.events = make(chan *RequestEvent, cfg.BufferSize)
+	}
+	if cfg.BatchSize > 0 {
+		p.batchSize = cfg.BatchSize
+	}
+	if cfg.FlushInterval > 0 {
+		p.flushInterval = time.Duration(cfg.FlushInterval)
+	}
+	go p.run()
+	return p
+}
+
+// Publish queues an event without blocking.
+func (p *EventPublisher) Publish(ev *RequestEvent) {
+	select {
+	case p.events <- ev:
+	default:
+		RecordEventsDropped(1)
+	}
+}
+
+// PublishRequests queues an event for each request that was answered.
+func (p *EventPublisher) PublishRequests(ctx context.Context, reqs []*RPCReq, res []*RPCRes, backends []string, latency time.Duration) {
+	if p == nil {
+		return
+	}
+
+	now := time.Now()
+	for i, req := range reqs {
+		if req == nil || res[i] == nil {
+			continue
+		}
+		ev := &RequestEvent{
+			Time:      now,
+			ReqID:     GetReqID(ctx),
+			Method:    req.Method,
+			Key:       GetAuthCtx(ctx),
+			Backend:   backends[i],
+			Status:    "ok",
+			LatencyMS: latency.Milliseconds(),
+		}
+		if res[i].IsError() {
+			ev.Status = strconv.Itoa(res[i].Error.Code)
+		} else if req.Method == "eth_sendRawTransaction" || req.Method == "eth_sendRawTransactionConditional" {
+			ev.TxHash, _ = res[i].Result.(string)
+		}
+		p.Publish(ev)
+	}
+}
+
+// Close flushes queued events and closes the sink.
+func (p *EventPublisher) Close() {
+	p.closeOnce.Do(func() {
+		close(p.events)
+		<-p.done
+		if err := p.sink.Close(); err != nil {
+			log.Error("error closing event sink", "err", err)
+		}
+	})
+}
+
+func (p *EventPublisher) run() {
+	defer close(p.done)
+
+	ticker := time.NewTicker(p.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([][]byte, 0, p.batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), eventPublishTimeout)
+		defer cancel()
+		if err := p.sink.Publish(ctx, batch); err != nil {
+			log.Error("error publishing request events", "count", len(batch), "err", err)
+			RecordEventsDropped(len(batch))
+		} else {
+			RecordEventsPublished(len(batch))
+		}
+		batch = make([][]byte, 0, p.batchSize)
+	}
+
+	for {
+		select {
+		case ev, ok := <-p.events:
+			if !ok {
+				flush()
+				return
+			}
+			data, err := json.Marshal(ev)
+			if err != nil {
+				log.Error("error encoding request event", "err", err)
+				continue
+			}
+			batch = append(batch, data)
+			if len(batch) >= p.batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+type kafkaEventSink struct {
+	writer *kafka.Writer
+}
+
+func newKafkaEventSink(brokers []string, topic string) *kafkaEventSink {
+	return &kafkaEventSink{
+		writer: &kafka.Writer{
+			Addr:     kafka.TCP(brokers...),
+			Topic:    topic,
+			Balancer: &kafka.LeastBytes{},
+		},
+	}
+}
+
+func (s *kafkaEventSink) Publish(ctx context.Context, events [][]byte) error {
+	msgs := make([]kafka.Message, len(events))
+	for i, ev := range events {
+		msgs[i] = kafka.Message{Value: ev}
+	}
+	return s.writer.WriteMessages(ctx, msgs...)
+}
+
+func (s *kafkaEventSink) Close() error {
+	return s.writer.Close()
+}
+
+type natsEventSink struct {
+	conn    *nats.Conn
+	subject string
+}
+
+func newNATSEventSink(servers []string, subject string) (*natsEventSink, error) {
+	conn, err := nats.Connect(strings.Join(servers, ","))
+	if err != nil {
+		return nil, err
+	}
+	return &natsEventSink{conn: conn, subject: subject}, nil
+}
+
+func (s *natsEventSink) Publish(ctx context.Context, events [][]byte) error {
+	for _, ev := range events {
+		if err := s.conn.Publish(s.subject, ev); err != nil {
+			return err
+		}
+	}
+	return s.conn.FlushWithContext(ctx)
+}
+
+func (s *natsEventSink) Close() error {
+	return s.conn.Drain()
+}
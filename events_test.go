@@ -0,0 +1,111 @@
+package proxyd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type memoryEventSink struct {
+	mu      sync.Mutex
+	batches [][][]byte
+	err     error
+	closed  bool
+}
+
+func (s *memoryEventSink) Publish(ctx context.Context, events [][]byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	s.batches = append(s.batches, events)
+	return nil
+}
+
+func (s *memoryEventSink) Close() error {
+	s.closed = true
+	return nil
+}
+
+func (s *memoryEventSink) events(t *testing.T) []RequestEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []RequestEvent
+	for _, batch := range s.batches {
+		for _, data := range batch {
+			var ev RequestEvent
+			require.NoError(t, json.Unmarshal(data, &ev))
+			out = append(out, ev)
+		}
+	}
+	return out
+}
+
+func TestEventPublisherPublishRequests(t *testing.T) {
+	sink := &memoryEventSink{}
+	pub := newEventPublisher(sink, EventsConfig{BatchSize: 2, FlushInterval: TOMLDuration(time.Hour)})
+
+	ctx := context.WithValue(context.Background(), ContextKeyReqID, "abc") // nolint:staticcheck
+	reqs := []*RPCReq{
+		{Method: "eth_sendRawTransaction"},
+		{Method: "eth_call"},
+		nil,
+	}
+	res := []*RPCRes{
+		NewRPCRes(json.RawMessage("1"), "0xdead"),
+		NewRPCErrorRes(json.RawMessage("2"), ErrOverRateLimit),
+		NewRPCErrorRes(nil, ErrParseErr),
+	}
+	pub.PublishRequests(ctx, reqs, res, []string{"main/infura", "", ""}, 25*time.Millisecond)
+
+	// a full batch is flushed without waiting for the interval
+	require.Eventually(t, func() bool {
+		return len(sink.events(t)) == 2
+	}, time.Second, 10*time.Millisecond)
+
+	evs := sink.events(t)
+	require.Equal(t, "eth_sendRawTransaction", evs[0].Method)
+	require.Equal(t, "abc", evs[0].ReqID)
+	require.Equal(t, "none", evs[0].Key)
+	require.Equal(t, "main/infura", evs[0].Backend)
+	require.Equal(t, "ok", evs[0].Status)
+	require.Equal(t, int64(25), evs[0].LatencyMS)
+	require.Equal(t, "0xdead", evs[0].TxHash)
+	require.Equal(t, "-32016", evs[1].Status)
+	require.Empty(t, evs[1].TxHash)
+
+	// a partial batch is flushed on close
+	pub.Publish(&RequestEvent{Method: "eth_chainId"})
+	pub.Close()
+	require.Len(t, sink.events(t), 3)
+	require.True(t, sink.closed)
+}
+
+func TestEventPublisherDropsWhenFull(t *testing.T) {
+	sink := &memoryEventSink{err: errors.New("unavailable")}
+	pub := newEventPublisher(sink, EventsConfig{BufferSize: 1, BatchSize: 1, FlushInterval: TOMLDuration(time.Hour)})
+	for i := 0; i < 100; i++ {
+		require.NotPanics(t, func() { pub.Publish(&RequestEvent{Method: "eth_call"}) })
+	}
+	pub.Close()
+	require.Empty(t, sink.events(t))
+}
+
+func TestNewEventPublisherValidation(t *testing.T) {
+	_, err := NewEventPublisher(EventsConfig{Sink: "kafka", Topic: "requests"})
+	require.Error(t, err)
+	_, err = NewEventPublisher(EventsConfig{Sink: "kafka", URLs: []string{"localhost:9092"}})
+	require.Error(t, err)
+	_, err = NewEventPublisher(EventsConfig{Sink: "pulsar", URLs: []string{"localhost:6650"}, Topic: "requests"})
+	require.Error(t, err)
+
+	pub, err := NewEventPublisher(EventsConfig{Sink: "kafka", URLs: []string{"localhost:9092"}, Topic: "requests"})
+	require.NoError(t, err)
+	pub.Close()
+}
@@ -0,0 +1,196 @@
+package proxyd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const defaultFaultErrorStatus = http.StatusServiceUnavailable
+
+// FaultInjector makes backends misbehave on purpose for a share of their HTTP
+// requests, to test how clients and alerting cope, e.g. in staging. Each
+// backend's faults are set in the config or at runtime through the admin
+// endpoint, but only if fault injection is enabled at startup.
+type FaultInjector struct {
+	backends   map[string]bool
+	adminToken secretValue
+
+	mu     sync.RWMutex
+	faults map[string]FaultConfig
+}
+
+func NewFaultInjector(cfg FaultInjectionConfig, backends map[string]*BackendConfig) (*FaultInjector, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	f := &FaultInjector{
+		backends: make(map[string]bool, len(backends)),
+		faults:   make(map[string]FaultConfig, len(cfg.Backends)),
+	}
+	f.adminToken.Store(cfg.AdminToken)
+	for name := range backends {
+		f.backends[name] = true
+	}
+	for _, name := range sortedKeys(cfg.Backends) {
+		if err := f.Set(name, cfg.Backends[name]); err != nil {
+			return nil, err
+		}
+	}
+	log.Warn("fault injection is enabled, backends may fail on purpose")
+	return f, nil
+}
+
+func checkFaultConfig(fc FaultConfig) error {
+	for _, pct := range []float64{fc.LatencyPercent, fc.ErrorPercent, fc.TruncatePercent, fc.ResetPercent} {
+		if pct < 0 || pct > 100 {
+			return fmt.Errorf("fault percentages must be between 0 and 100")
+		}
+	}
+	if fc.Latency < 0 {
+		return fmt.Errorf("fault latency must be >= 0")
+	}
+	if fc.ErrorStatus != 0 && (fc.ErrorStatus < 100 || fc.ErrorStatus > 599) {
+		return fmt.Errorf("invalid fault error status %d", fc.ErrorStatus)
+	}
+	return nil
+}
+
+// Set replaces a backend's faults. The zero FaultConfig clears them.
+func (f *FaultInjector) Set(backend string, fc FaultConfig) error {
+	if !f.backends[backend] {
+		return fmt.Errorf("undefined backend %s", backend)
+	}
+	if err := checkFaultConfig(fc); err != nil {
+		return fmt.Errorf("faults of backend %s: %w", backend, err)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if fc == (FaultConfig{}) {
+		delete(f.faults, backend)
+	} else {
+		f.faults[backend] = fc
+	}
+	return nil
+}
+
+func (f *FaultInjector) get(backend string) (FaultConfig, bool) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	fc, ok := f.faults[backend]
+	return fc, ok
+}
+
+func rollFault(pct float64) bool {
+	return pct > 0 && rand.Float64()*100 < pct
+}
+
+// faultTransport injects a backend's faults into its HTTP requests.
+type faultTransport struct {
+	next    http.RoundTripper
+	backend string
+	faults  *FaultInjector
+}
+
+func (t *faultTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	fc, ok := t.faults.get(t.backend)
+	if !ok {
+		return t.next.RoundTrip(req)
+	}
+
+	if rollFault(fc.LatencyPercent) {
+		RecordInjectedFault(t.backend, "latency")
+		timer := time.NewTimer(time.Duration(fc.Latency))
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		}
+	}
+	if rollFault(fc.ResetPercent) {
+		RecordInjectedFault(t.backend, "reset")
+		return nil, fmt.Errorf("injected fault: %w", syscall.ECONNRESET)
+	}
+	if rollFault(fc.ErrorPercent) {
+		RecordInjectedFault(t.backend, "error")
+		status := fc.ErrorStatus
+		if status == 0 {
+			status = defaultFaultErrorStatus
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+			StatusCode:    status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        http.Header{"Content-Type": []string{"text/plain"}},
+			Body:          io.NopCloser(strings.NewReader("injected fault")),
+			ContentLength: int64(len("injected fault")),
+			Request:       req,
+		}, nil
+	}
+
+	res, err := t.next.RoundTrip(req)
+	if err != nil || !rollFault(fc.TruncatePercent) {
+		return res, err
+	}
+	RecordInjectedFault(t.backend, "truncate")
+	body, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = io.NopCloser(bytes.NewReader(body[:len(body)/2]))
+	res.ContentLength = -1
+	res.Header.Del("Content-Length")
+	return res, nil
+}
+
+// ServeHTTP serves the fault injection endpoints:
+//
+//	GET /admin/faults               lists the faults by backend
+//	PUT /admin/faults/{backend}     replaces a backend's faults
+//	DELETE /admin/faults/{backend}  clears a backend's faults
+func (f *FaultInjector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !checkAdminToken(w, r, f.adminToken.Load()) {
+		return
+	}
+
+	backend := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/admin/faults"), "/")
+	switch {
+	case backend == "" && r.Method == http.MethodGet:
+		f.mu.RLock()
+		faults := make(map[string]FaultConfig, len(f.faults))
+		for name, fc := range f.faults {
+			faults[name] = fc
+		}
+		f.mu.RUnlock()
+		writeAdminJSON(w, http.StatusOK, faults)
+	case backend != "" && (r.Method == http.MethodPut || r.Method == http.MethodDelete):
+		var fc FaultConfig
+		if r.Method == http.MethodPut {
+			if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&fc); err != nil {
+				http.Error(w, "invalid request body", http.StatusBadRequest)
+				return
+			}
+		}
+		if err := f.Set(backend, fc); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Warn("set injected faults", "backend", backend, "faults", fc)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,93 @@
+package proxyd
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFaultTransport(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","result":"0x1","id":1}`))
+	}))
+	defer upstream.Close()
+
+	f, err := NewFaultInjector(FaultInjectionConfig{Enabled: true}, map[string]*BackendConfig{"node": {}})
+	require.NoError(t, err)
+	client := &http.Client{Transport: &faultTransport{next: http.DefaultTransport, backend: "node", faults: f}}
+
+	get := func() (*http.Response, string, error) {
+		res, err := client.Get(upstream.URL)
+		if err != nil {
+			return nil, "", err
+		}
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		return res, string(body), err
+	}
+
+	res, body, err := get()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, `{"jsonrpc":"2.0","result":"0x1","id":1}`, body)
+
+	require.NoError(t, f.Set("node", FaultConfig{ErrorPercent: 100, ErrorStatus: 502}))
+	res, _, err = get()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadGateway, res.StatusCode)
+
+	require.NoError(t, f.Set("node", FaultConfig{TruncatePercent: 100}))
+	_, body, err = get()
+	require.NoError(t, err)
+	require.Equal(t, `{"jsonrpc":"2.0","r`, body)
+
+	require.NoError(t, f.Set("node", FaultConfig{ResetPercent: 100}))
+	_, _, err = get()
+	require.True(t, errors.Is(err, syscall.ECONNRESET))
+
+	require.NoError(t, f.Set("node", FaultConfig{LatencyPercent: 100, Latency: TOMLDuration(50 * time.Millisecond)}))
+	start := time.Now()
+	_, _, err = get()
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+
+	require.Error(t, f.Set("node", FaultConfig{ErrorPercent: 101}))
+	require.Error(t, f.Set("undefined", FaultConfig{ErrorPercent: 1}))
+}
+
+func TestFaultInjectorAdmin(t *testing.T) {
+	f, err := NewFaultInjector(FaultInjectionConfig{Enabled: true, AdminToken: "admin"}, map[string]*BackendConfig{"node": {}})
+	require.NoError(t, err)
+
+	do := func(method, path, token, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		f.ServeHTTP(w, r)
+		return w
+	}
+
+	require.Equal(t, http.StatusUnauthorized, do("PUT", "/admin/faults/node", "wrong", `{"error_percent":10}`).Code)
+	require.Equal(t, http.StatusBadRequest, do("PUT", "/admin/faults/undefined", "admin", `{"error_percent":10}`).Code)
+	require.Equal(t, http.StatusBadRequest, do("PUT", "/admin/faults/node", "admin", `{"reset_percent":-1}`).Code)
+	require.Equal(t, http.StatusMethodNotAllowed, do("PUT", "/admin/faults", "admin", `{}`).Code)
+
+	require.Equal(t, http.StatusNoContent, do("PUT", "/admin/faults/node", "admin", `{"latency_percent":50,"latency":"250ms"}`).Code)
+	w := do("GET", "/admin/faults", "admin", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var faults map[string]FaultConfig
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &faults))
+	require.Equal(t, FaultConfig{LatencyPercent: 50, Latency: TOMLDuration(250 * time.Millisecond)}, faults["node"])
+
+	require.Equal(t, http.StatusNoContent, do("DELETE", "/admin/faults/node", "admin", "").Code)
+	_, ok := f.get("node")
+	require.False(t, ok)
+}
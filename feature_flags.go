@@ -0,0 +1,227 @@
+package proxyd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// FeatureURLForwarding carries the client's URL path and query over to
+	// backends for methods with a URL rewrite.
+	FeatureURLForwarding = "url_forwarding"
+	// FeatureCache serves responses from, and stores them in, the RPC cache.
+	FeatureCache = "cache"
+	// FeatureStrictInteropValidation rejects transactions that fail interop
+	// access list validation. Otherwise the failure is only logged.
+	FeatureStrictInteropValidation = "strict_interop_validation"
+
+	featureFlagsRedisKey             = "feature_flags"
+	defaultFeatureFlagsRefreshPeriod = 30 * time.Second
+)
+
+// defaultFeatureFlags are the flag values when a flag isn't configured, which
+// keep the existing behavior.
+var defaultFeatureFlags = map[string]bool{
+	FeatureURLForwarding:           true,
+	FeatureCache:                   true,
+	FeatureStrictInteropValidation: true,
+}
+
+type featureFlag struct {
+	// rollout is the enabled share of keys, in hundredths of a percent.
+	rollout     uint32
+	keys        map[string]bool
+	excludeKeys map[string]bool
+}
+
+func newFeatureFlag(name string, cfg *FeatureFlagConfig) (*featureFlag, error) {
+	if cfg.RolloutPercent < 0 || cfg.RolloutPercent > 100 {
+		return nil, fmt.Errorf("rollout_percent of feature flag %s must be between 0 and 100", name)
+	}
+	f := &featureFlag{
+		rollout:     uint32(cfg.RolloutPercent * 100),
+		keys:        make(map[string]bool, len(cfg.Keys)),
+		excludeKeys: make(map[string]bool, len(cfg.ExcludeKeys)),
+	}
+	for _, key := range cfg.Keys {
+		f.keys[key] = true
+	}
+	for _, key := range cfg.ExcludeKeys {
+		f.excludeKeys[key] = true
+	}
+	return f, nil
+}
+
+// enabled buckets keys by a hash of the flag name and key, so a key keeps its
+// value as the rollout grows, and different flags enable different keys.
+func (f *featureFlag) enabled(name, key string) bool {
+	if f.excludeKeys[key] {
+		return false
+	}
+	if f.keys[key] {
+		return true
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(name))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(key))
+	return h.Sum32()%10000 < f.rollout
+}
+
+// FeatureFlags decides per request whether risky behaviors are enabled, so
+// they can be ramped up by percentage or for specific keys. A nil
+// *FeatureFlags enables every flag that is on by default.
+type FeatureFlags struct {
+	static map[string]*featureFlag
+
+	redisClient redis.UniversalClient
+	redisKey    string
+	interval    time.Duration
+
+	mu     sync.RWMutex
+	remote map[string]*featureFlag
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+func NewFeatureFlags(cfg FeatureFlagsConfig, redisClient redis.UniversalClient, namespace string) (*FeatureFlags, error) {
+	if cfg.Redis && redisClient == nil {
+		return nil, fmt.Errorf("feature_flags.redis requires a redis config")
+	}
+	ff := &FeatureFlags{
+		static:   make(map[string]*featureFlag, len(cfg.Flags)),
+		interval: time.Duration(cfg.RefreshInterval),
+		stop:     make(chan struct{}),
+	}
+	for name, flagCfg := range cfg.Flags {
+		f, err := newFeatureFlag(name, flagCfg)
+		if err != nil {
+			return nil, err
+		}
+		ff.static[name] = f
+	}
+	if cfg.Redis {
+		ff.redisClient = redisClient
+		ff.redisKey = featureFlagsRedisKey
+		if namespace != "" {
+			ff.redisKey = namespace + ":" + featureFlagsRedisKey
+		}
+	}
+	if ff.interval == 0 {
+		ff.interval = defaultFeatureFlagsRefreshPeriod
+	}
+	return ff, nil
+}
+
+// Enabled reports whether the flag is on for the request. Requests are keyed
+// by their auth alias, or by client IP if unauthenticated.
+func (ff *FeatureFlags) Enabled(ctx context.Context, name string) bool {
+	if ff == nil {
+		return defaultFeatureFlags[name]
+	}
+	key := GetAuthCtx(ctx)
+	if key == "none" {
+		key = GetXForwardedFor(ctx)
+	}
+	enabled := ff.EnabledForKey(name, key)
+	RecordFeatureFlagEvaluation(name, enabled)
+	return enabled
+}
+
+func (ff *FeatureFlags) EnabledForKey(name, key string) bool {
+	if ff == nil {
+		return defaultFeatureFlags[name]
+	}
+	ff.mu.RLock()
+	f := ff.remote[name]
+	ff.mu.RUnlock()
+	if f == nil {
+		f = ff.static[name]
+	}
+	if f == nil {
+		return defaultFeatureFlags[name]
+	}
+	return f.enabled(name, key)
+}
+
+// Start loads the flags from Redis, then reloads them in the background until
+// Stop is called. It does nothing if flags aren't read from Redis.
+func (ff *FeatureFlags) Start() {
+	if ff == nil || ff.redisClient == nil {
+		return
+	}
+	if err := ff.refresh(); err != nil {
+		log.Error("error loading feature flags", "err", err)
+	}
+
+	ff.wg.Add(1)
+	go func() {
+		defer ff.wg.Done()
+		ticker := time.NewTicker(ff.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ff.stop:
+				return
+			case <-ticker.C:
+				if err := ff.refresh(); err != nil {
+					log.Error("error loading feature flags", "err", err)
+				}
+			}
+		}
+	}()
+}
+
+func (ff *FeatureFlags) Stop() {
+	if ff == nil || ff.redisClient == nil {
+		return
+	}
+	close(ff.stop)
+	ff.wg.Wait()
+}
+
+// refresh replaces the flags read from Redis. On error the previous flags are
+// kept.
+func (ff *FeatureFlags) refresh() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	values, err := ff.redisClient.HGetAll(ctx, ff.redisKey).Result()
+	if err != nil {
+		RecordRedisError("FeatureFlags")
+		return wrapErr(err, "error reading feature flags from redis")
+	}
+
+	remote := make(map[string]*featureFlag, len(values))
+	for name, value := range values {
+		var cfg FeatureFlagConfig
+		if err := json.Unmarshal([]byte(value), &cfg); err != nil {
+			return fmt.Errorf("invalid feature flag %s in redis: %w", name, err)
+		}
+		f, err := newFeatureFlag(name, &cfg)
+		if err != nil {
+			return err
+		}
+		remote[name] = f
+	}
+
+	ff.mu.Lock()
+	ff.remote = remote
+	ff.mu.Unlock()
+	return nil
+}
+
+func GetFeatureFlags(ctx context.Context) *FeatureFlags {
+	ff, ok := ctx.Value(ContextKeyFeatureFlags).(*FeatureFlags)
+	if !ok {
+		return nil
+	}
+	return ff
+}
@@ -0,0 +1,97 @@
+package proxyd
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/alicebob/miniredis"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFeatureFlags(t *testing.T) {
+	ff, err := NewFeatureFlags(FeatureFlagsConfig{
+		Flags: map[string]*FeatureFlagConfig{
+			FeatureCache:         {RolloutPercent: 25, Keys: []string{"canary"}, ExcludeKeys: []string{"vip"}},
+			FeatureURLForwarding: {RolloutPercent: 0},
+		},
+	}, nil, "")
+	require.NoError(t, err)
+
+	enabled := 0
+	for i := 0; i < 10000; i++ {
+		if ff.EnabledForKey(FeatureCache, fmt.Sprintf("10.0.%d.%d", i/256, i%256)) {
+			enabled++
+		}
+	}
+	require.InDelta(t, 2500, enabled, 250)
+	require.True(t, ff.EnabledForKey(FeatureCache, "canary"))
+	require.False(t, ff.EnabledForKey(FeatureCache, "vip"))
+	require.False(t, ff.EnabledForKey(FeatureURLForwarding, "canary"))
+	// unconfigured flags keep their default
+	require.True(t, ff.EnabledForKey(FeatureStrictInteropValidation, "canary"))
+
+	// requests are keyed by auth alias, then client IP
+	ctx := context.WithValue(context.Background(), ContextKeyAuth, "canary") // nolint:staticcheck
+	require.True(t, ff.Enabled(ctx, FeatureCache))
+	ctx = context.WithValue(context.Background(), ContextKeyXForwardedFor, "vip") // nolint:staticcheck
+	require.False(t, ff.Enabled(ctx, FeatureCache))
+
+	var nilFlags *FeatureFlags
+	require.True(t, nilFlags.Enabled(ctx, FeatureURLForwarding))
+
+	_, err = NewFeatureFlags(FeatureFlagsConfig{
+		Flags: map[string]*FeatureFlagConfig{FeatureCache: {RolloutPercent: 101}},
+	}, nil, "")
+	require.Error(t, err)
+	_, err = NewFeatureFlags(FeatureFlagsConfig{Redis: true}, nil, "")
+	require.Error(t, err)
+}
+
+func TestFeatureFlagsRedis(t *testing.T) {
+	redisServer, err := miniredis.Run()
+	require.NoError(t, err)
+	defer redisServer.Close()
+
+	redisClient := redis.NewClient(&redis.Options{
+		Addr: fmt.Sprintf("127.0.0.1:%s", redisServer.Port()),
+	})
+
+	ff, err := NewFeatureFlags(FeatureFlagsConfig{
+		Flags: map[string]*FeatureFlagConfig{FeatureCache: {RolloutPercent: 100}},
+		Redis: true,
+	}, redisClient, "proxyd")
+	require.NoError(t, err)
+
+	redisServer.HSet("proxyd:feature_flags", FeatureCache, `{"rollout_percent":0,"keys":["canary"]}`)
+	require.NoError(t, ff.refresh())
+	require.False(t, ff.EnabledForKey(FeatureCache, "other"))
+	require.True(t, ff.EnabledForKey(FeatureCache, "canary"))
+
+	// invalid flags keep the previous ones
+	redisServer.HSet("proxyd:feature_flags", FeatureCache, `{"rollout_percent":200}`)
+	require.Error(t, ff.refresh())
+	require.True(t, ff.EnabledForKey(FeatureCache, "canary"))
+
+	// without the redis flag the config applies again
+	redisServer.HDel("proxyd:feature_flags", FeatureCache)
+	require.NoError(t, ff.refresh())
+	require.True(t, ff.EnabledForKey(FeatureCache, "other"))
+}
+
+func TestURLForwardingFeatureFlag(t *testing.T) {
+	ff, err := NewFeatureFlags(FeatureFlagsConfig{
+		Flags: map[string]*FeatureFlagConfig{FeatureURLForwarding: {Keys: []string{"canary"}}},
+	}, nil, "")
+	require.NoError(t, err)
+
+	reqs := []*RPCReq{{Method: "eth_sendRawTransaction"}}
+	ctx := context.WithValue(context.Background(), ContextKeyFeatureFlags, ff) // nolint:staticcheck
+	ctx = context.WithValue(ctx, ContextKeyPath, "/fast")                      // nolint:staticcheck
+	ctx = context.WithValue(ctx, ContextKeyRawQuery, "hint=hash")              // nolint:staticcheck
+	require.Equal(t, "http://backend", buildBackendURL("http://backend", reqs, ctx))
+
+	ctx = context.WithValue(ctx, ContextKeyAuth, "canary") // nolint:staticcheck
+	require.Equal(t, "http://backend/fast?hint=hash", buildBackendURL("http://backend", reqs, ctx))
+}
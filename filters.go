@@ -0,0 +1,367 @@
+package proxyd
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	defaultFilterTimeout = 5 * time.Minute
+	defaultMaxFilters    = 10000
+	// bounds the blocks returned by one poll of a block filter
+	maxFilterBlocks = 256
+)
+
+var filterMethods = map[string]bool{
+	"eth_newFilter":        true,
+	"eth_newBlockFilter":   true,
+	"eth_getFilterChanges": true,
+	"eth_getFilterLogs":    true,
+	"eth_uninstallFilter":  true,
+}
+
+type filterKind int
+
+const (
+	logFilter filterKind = iota
+	blockFilter
+)
+
+type filter struct {
+	kind filterKind
+	// criteria of log filters, as sent by the client
+	criteria map[string]interface{}
+	// lastBlock is the last block the client has seen the changes of
+	lastBlock uint64
+	lastPoll  time.Time
+}
+
+// FilterManager serves the filter API from state kept in proxyd, polling
+// backends with eth_blockNumber, eth_getLogs and eth_getBlockByNumber. Unlike
+// filters installed on a backend, the filters stay valid when requests move
+// to other backends. Filters that aren't polled for the timeout are removed,
+// like geth does.
+type FilterManager struct {
+	timeout    time.Duration
+	maxFilters int
+
+	mu      sync.Mutex
+	filters map[string]*filter
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+func NewFilterManager(cfg FiltersConfig) *FilterManager {
+	m := &FilterManager{
+		timeout:    time.Duration(cfg.Timeout),
+		maxFilters: cfg.MaxFilters,
+		filters:    make(map[string]*filter),
+		stop:       make(chan struct{}),
+	}
+	if m.timeout == 0 {
+		m.timeout = defaultFilterTimeout
+	}
+	if m.maxFilters == 0 {
+		m.maxFilters = defaultMaxFilters
+	}
+	return m
+}
+
+func (m *FilterManager) Handles(method string) bool {
+	return m != nil && filterMethods[method]
+}
+
+// Handle answers a filter request, using bg for the backend requests.
+func (m *FilterManager) Handle(ctx context.Context, req *RPCReq, bg *BackendGroup) *RPCRes {
+	result, err := m.handle(ctx, req, bg)
+	if err != nil {
+		log.Debug("error handling filter request", "method", req.Method, "req_id", GetReqID(ctx), "err", err)
+		return NewRPCErrorRes(req.ID, err)
+	}
+	return NewRPCRes(req.ID, result)
+}
+
+func (m *FilterManager) handle(ctx context.Context, req *RPCReq, bg *BackendGroup) (interface{}, error) {
+	switch req.Method {
+	case "eth_newFilter":
+		var params []map[string]interface{}
+		if err := json.Unmarshal(req.Params, &params); err != nil || len(params) != 1 {
+			return nil, ErrInvalidParams("expected a filter criteria object")
+		}
+		if _, ok := params[0]["blockHash"]; ok {
+			return nil, ErrInvalidParams("blockHash is not supported by filters")
+		}
+		return m.install(ctx, bg, &filter{kind: logFilter, criteria: params[0]})
+	case "eth_newBlockFilter":
+		return m.install(ctx, bg, &filter{kind: blockFilter})
+	case "eth_uninstallFilter":
+		id, err := filterIDParam(req)
+		if err != nil {
+			return nil, err
+		}
+		m.mu.Lock()
+		_, ok := m.filters[id]
+		delete(m.filters, id)
+		m.mu.Unlock()
+		RecordActiveFilters(m.count())
+		return ok, nil
+	case "eth_getFilterChanges":
+		id, err := filterIDParam(req)
+		if err != nil {
+			return nil, err
+		}
+		return m.changes(ctx, bg, id)
+	case "eth_getFilterLogs":
+		id, err := filterIDParam(req)
+		if err != nil {
+			return nil, err
+		}
+		f, ok := m.get(id)
+		if !ok || f.kind != logFilter {
+			return nil, ErrFilterNotFound
+		}
+		return callBackendGroup(ctx, bg, "eth_getLogs", f.criteria)
+	default:
+		return nil, ErrMethodNotWhitelisted
+	}
+}
+
+func (m *FilterManager) install(ctx context.Context, bg *BackendGroup, f *filter) (interface{}, error) {
+	head, err := latestBlockNumber(ctx, bg)
+	if err != nil {
+		return nil, err
+	}
+	f.lastBlock = head
+	f.lastPoll = time.Now()
+
+	id, err := newFilterID()
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	if len(m.filters) >= m.maxFilters {
+		m.mu.Unlock()
+		return nil, ErrTooManyFilters
+	}
+	m.filters[id] = f
+	m.mu.Unlock()
+	RecordActiveFilters(m.count())
+	return id, nil
+}
+
+// changes returns the logs or block hashes since the last poll of the filter.
+// Concurrent polls of a filter may return the same changes.
+func (m *FilterManager) changes(ctx context.Context, bg *BackendGroup, id string) (interface{}, error) {
+	f, ok := m.get(id)
+	if !ok {
+		return nil, ErrFilterNotFound
+	}
+	head, err := latestBlockNumber(ctx, bg)
+	if err != nil {
+		return nil, err
+	}
+
+	from := f.lastBlock + 1
+	var result interface{}
+	switch f.kind {
+	case logFilter:
+		result = []interface{}{}
+		to := head
+		if n, ok := criteriaBlock(f.criteria, "toBlock"); ok && n < to {
+			to = n
+		}
+		if n, ok := criteriaBlock(f.criteria, "fromBlock"); ok && n > from {
+			from = n
+		}
+		if from <= to {
+			criteria := make(map[string]interface{}, len(f.criteria))
+			for k, v := range f.criteria {
+				criteria[k] = v
+			}
+			criteria["fromBlock"] = hexutil.EncodeUint64(from)
+			criteria["toBlock"] = hexutil.EncodeUint64(to)
+			result, err = callBackendGroup(ctx, bg, "eth_getLogs", criteria)
+			if err != nil {
+				return nil, err
+			}
+		}
+	case blockFilter:
+		if head >= maxFilterBlocks && from < head-maxFilterBlocks+1 {
+			from = head - maxFilterBlocks + 1
+		}
+		result, err = blockHashes(ctx, bg, from, head)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	m.mu.Lock()
+	if installed := m.filters[id]; installed != nil && head > installed.lastBlock {
+		installed.lastBlock = head
+	}
+	m.mu.Unlock()
+	return result, nil
+}
+
+// get returns a copy of the filter, and keeps it from timing out.
+func (m *FilterManager) get(id string) (filter, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f := m.filters[id]
+	if f == nil {
+		return filter{}, false
+	}
+	f.lastPoll = time.Now()
+	return *f, true
+}
+
+func (m *FilterManager) count() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.filters)
+}
+
+// Start removes filters that time out until Stop is called.
+func (m *FilterManager) Start() {
+	if m == nil {
+		return
+	}
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		ticker := time.NewTicker(m.timeout / 5)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-m.stop:
+				return
+			case <-ticker.C:
+				m.mu.Lock()
+				for id, f := range m.filters {
+					if time.Since(f.lastPoll) > m.timeout {
+						delete(m.filters, id)
+					}
+				}
+				m.mu.Unlock()
+				RecordActiveFilters(m.count())
+			}
+		}
+	}()
+}
+
+func (m *FilterManager) Stop() {
+	if m == nil {
+		return
+	}
+	close(m.stop)
+	m.wg.Wait()
+}
+
+func filterIDParam(req *RPCReq) (string, error) {
+	var params []string
+	if err := json.Unmarshal(req.Params, &params); err != nil || len(params) != 1 {
+		return "", ErrInvalidParams("expected a filter id")
+	}
+	return params[0], nil
+}
+
+func newFilterID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return hexutil.Encode(b[:]), nil
+}
+
+// criteriaBlock returns a numeric block of the criteria. Block tags such as
+// latest don't bound the filter.
+func criteriaBlock(criteria map[string]interface{}, key string) (uint64, bool) {
+	s, ok := criteria[key].(string)
+	if !ok {
+		return 0, false
+	}
+	n, err := hexutil.DecodeUint64(s)
+	return n, err == nil
+}
+
+func latestBlockNumber(ctx context.Context, bg *BackendGroup) (uint64, error) {
+	result, err := callBackendGroup(ctx, bg, "eth_blockNumber")
+	if err != nil {
+		return 0, err
+	}
+	s, ok := result.(string)
+	if !ok {
+		return 0, fmt.Errorf("unexpected eth_blockNumber result %v", result)
+	}
+	return hexutil.DecodeUint64(s)
+}
+
+func blockHashes(ctx context.Context, bg *BackendGroup, from, to uint64) ([]interface{}, error) {
+	hashes := make([]interface{}, 0)
+	if from > to {
+		return hashes, nil
+	}
+	reqs := make([]*RPCReq, 0, to-from+1)
+	for n := from; n <= to; n++ {
+		params, err := json.Marshal([]interface{}{hexutil.EncodeUint64(n), false})
+		if err != nil {
+			return nil, err
+		}
+		reqs = append(reqs, &RPCReq{
+			JSONRPC: JSONRPCVersion,
+			Method:  "eth_getBlockByNumber",
+			Params:  params,
+			ID:      json.RawMessage(fmt.Sprintf("%d", len(reqs)+1)),
+		})
+	}
+	res, _, err := bg.Forward(ctx, reqs, true)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range res {
+		if r.IsError() {
+			return nil, r.Error
+		}
+		block, ok := r.Result.(map[string]interface{})
+		if !ok {
+			// not produced yet by the backend that answered
+			break
+		}
+		hashes = append(hashes, block["hash"])
+	}
+	return hashes, nil
+}
+
+// callBackendGroup makes a request to a backend group on proxyd's behalf and
+// returns its result.
+func callBackendGroup(ctx context.Context, bg *BackendGroup, method string, params ...interface{}) (interface{}, error) {
+	if params == nil {
+		params = []interface{}{}
+	}
+	rawParams, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	req := &RPCReq{
+		JSONRPC: JSONRPCVersion,
+		Method:  method,
+		Params:  rawParams,
+		ID:      json.RawMessage(`1`),
+	}
+	res, _, err := bg.Forward(ctx, []*RPCReq{req}, false)
+	if err != nil {
+		return nil, err
+	}
+	if res[0].IsError() {
+		return nil, res[0].Error
+	}
+	return res[0].Result, nil
+}
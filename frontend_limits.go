@@ -0,0 +1,180 @@
+package proxyd
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	defaultReadHeaderTimeout = 10 * time.Second
+	// bodies are only held to the minimum rate after this long, so small
+	// bodies aren't cut off by a slow start
+	bodyReadGracePeriod = 2 * time.Second
+)
+
+// FrontendLimits protects the RPC and WS servers from clients tying up
+// connections cheaply: headers or bodies sent slowly, header bombs, and many
+// connections from one IP.
+type FrontendLimits struct {
+	readHeaderTimeout time.Duration
+	maxHeaderBytes    int
+	maxHeaderCount    int
+	minBodyRate       int
+	maxConnsPerIP     int
+}
+
+func NewFrontendLimits(cfg ServerConfig) *FrontendLimits {
+	l := &FrontendLimits{
+		readHeaderTimeout: time.Duration(cfg.ReadHeaderTimeout),
+		maxHeaderBytes:    cfg.MaxHeaderBytes,
+		maxHeaderCount:    cfg.MaxHeaderCount,
+		minBodyRate:       cfg.MinBodyBytesPerSecond,
+		maxConnsPerIP:     cfg.MaxConnsPerIP,
+	}
+	if l.readHeaderTimeout == 0 {
+		l.readHeaderTimeout = defaultReadHeaderTimeout
+	}
+	return l
+}
+
+// configure sets the limits net/http enforces itself.
+func (l *FrontendLimits) configure(srv *http.Server) {
+	if l == nil {
+		return
+	}
+	srv.ReadHeaderTimeout = l.readHeaderTimeout
+	srv.MaxHeaderBytes = l.maxHeaderBytes
+}
+
+// Handler enforces the header count and the minimum body rate.
+func (l *FrontendLimits) Handler(h http.Handler) http.Handler {
+	if l == nil {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if l.maxHeaderCount > 0 {
+			var count int
+			for _, values := range r.Header {
+				count += len(values)
+			}
+			if count > l.maxHeaderCount {
+				RecordFrontendLimitRejection("header_count")
+				httpResponseCodesTotal.WithLabelValues(strconv.Itoa(http.StatusRequestHeaderFieldsTooLarge)).Inc()
+				http.Error(w, "too many headers", http.StatusRequestHeaderFieldsTooLarge)
+				return
+			}
+		}
+		if l.minBodyRate > 0 && r.Body != nil && r.Body != http.NoBody {
+			r.Body = &minRateBody{
+				ReadCloser: r.Body,
+				rc:         http.NewResponseController(w),
+				rate:       l.minBodyRate,
+				start:      time.Now(),
+			}
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// Listener caps the connections of each client IP.
+func (l *FrontendLimits) Listener(ln net.Listener) net.Listener {
+	if l == nil || l.maxConnsPerIP <= 0 || ln == nil {
+		return ln
+	}
+	return &connLimitListener{
+		Listener: ln,
+		max:      l.maxConnsPerIP,
+		conns:    make(map[string]int),
+	}
+}
+
+// minRateBody fails reads once the body is sent slower, on average, than its
+// rate. The connection's read deadline is pushed back as bytes arrive, so a
+// client sending nothing at all is cut off too.
+type minRateBody struct {
+	io.ReadCloser
+	rc    *http.ResponseController
+	rate  int
+	start time.Time
+	read  int64
+}
+
+func (b *minRateBody) Read(p []byte) (int, error) {
+	allowed := bodyReadGracePeriod + time.Duration(b.read+1)*time.Second/time.Duration(b.rate)
+	_ = b.rc.SetReadDeadline(b.start.Add(allowed))
+	n, err := b.ReadCloser.Read(p)
+	b.read += int64(n)
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		RecordFrontendLimitRejection("slow_body")
+		return n, ErrRequestBodyTooSlow
+	}
+	if err != nil {
+		// net/http keeps reading the connection once the body is done
+		_ = b.rc.SetReadDeadline(time.Time{})
+	}
+	return n, err
+}
+
+type connLimitListener struct {
+	net.Listener
+	max int
+
+	mu    sync.Mutex
+	conns map[string]int
+}
+
+func (l *connLimitListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		// unix socket peers have no IP to limit
+		addr, ok := conn.RemoteAddr().(*net.TCPAddr)
+		if !ok {
+			return conn, nil
+		}
+		ip := addr.IP.String()
+		if !l.acquire(ip) {
+			RecordFrontendLimitRejection("conns_per_ip")
+			conn.Close()
+			continue
+		}
+		return &limitedConn{Conn: conn, release: sync.OnceFunc(func() { l.release(ip) })}, nil
+	}
+}
+
+func (l *connLimitListener) acquire(ip string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.conns[ip] >= l.max {
+		return false
+	}
+	l.conns[ip]++
+	return true
+}
+
+func (l *connLimitListener) release(ip string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.conns[ip]--
+	if l.conns[ip] <= 0 {
+		delete(l.conns, ip)
+	}
+}
+
+type limitedConn struct {
+	net.Conn
+	release func()
+}
+
+func (c *limitedConn) Close() error {
+	c.release()
+	return c.Conn.Close()
+}
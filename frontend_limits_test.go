@@ -0,0 +1,97 @@
+package proxyd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFrontendLimitsHeaderCount(t *testing.T) {
+	limits := NewFrontendLimits(ServerConfig{MaxHeaderCount: 2})
+	h := limits.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	r := httptest.NewRequest("POST", "/", nil)
+	r.Header.Add("A", "1")
+	r.Header.Add("A", "2")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	r.Header.Add("B", "3")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	require.Equal(t, http.StatusRequestHeaderFieldsTooLarge, w.Code)
+}
+
+func TestFrontendLimitsSlowBody(t *testing.T) {
+	limits := NewFrontendLimits(ServerConfig{MinBodyBytesPerSecond: 1000})
+	errs := make(chan error, 1)
+	srv := httptest.NewUnstartedServer(limits.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := io.ReadAll(r.Body)
+		errs <- err
+	})))
+	srv.Start()
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = fmt.Fprintf(conn, "POST / HTTP/1.1\r\nHost: proxyd\r\nContent-Length: 10000\r\n\r\nslow")
+	require.NoError(t, err)
+
+	select {
+	case err := <-errs:
+		require.ErrorIs(t, err, ErrRequestBodyTooSlow)
+	case <-time.After(5 * time.Second):
+		t.Fatal("slow body wasn't cut off")
+	}
+
+	// bodies sent in time are read normally
+	res, err := http.Post(srv.URL, "application/json", http.NoBody)
+	require.NoError(t, err)
+	res.Body.Close()
+	require.NoError(t, <-errs)
+}
+
+func TestFrontendLimitsConnsPerIP(t *testing.T) {
+	limits := NewFrontendLimits(ServerConfig{MaxConnsPerIP: 1})
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.Listener = limits.Listener(srv.Listener)
+	srv.Start()
+	defer srv.Close()
+
+	roundTrip := func(conn net.Conn) error {
+		if _, err := fmt.Fprintf(conn, "GET / HTTP/1.1\r\nHost: proxyd\r\n\r\n"); err != nil {
+			return err
+		}
+		res, err := http.ReadResponse(bufio.NewReader(conn), nil)
+		if err != nil {
+			return err
+		}
+		return res.Body.Close()
+	}
+
+	first, err := net.Dial("tcp", srv.Listener.Addr().String())
+	require.NoError(t, err)
+	require.NoError(t, roundTrip(first))
+
+	second, err := net.Dial("tcp", srv.Listener.Addr().String())
+	require.NoError(t, err)
+	require.Error(t, roundTrip(second))
+	second.Close()
+
+	first.Close()
+	require.Eventually(t, func() bool {
+		third, err := net.Dial("tcp", srv.Listener.Addr().String())
+		require.NoError(t, err)
+		defer third.Close()
+		return roundTrip(third) == nil
+	}, time.Second, 10*time.Millisecond)
+}
@@ -3,12 +3,17 @@ package proxyd
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/ethereum/go-ethereum/log"
 	"github.com/redis/go-redis/v9"
 )
 
+const defaultFallbackRateLimitDivisor = 2
+
 type FrontendRateLimiter interface {
 	// Take consumes a key, and a maximum number of requests
 	// per time interval. It returns a boolean denoting if
@@ -147,22 +152,60 @@ func truncateNow(dur time.Duration) int64 {
 // If the primary rate limiter fails, due to an unexpected error, the secondary
 // rate limiter will be used. This is useful to reduce reliance on a single Redis
 // instance for rate limiting. If both fail, the request is not let through.
+// It switches back to the primary as soon as it succeeds again.
 type FallbackRateLimiter struct {
+	name      string
 	primary   FrontendRateLimiter
 	secondary FrontendRateLimiter
+	fallback  atomic.Bool
 }
 
 func NewFallbackRateLimiter(primary FrontendRateLimiter, secondary FrontendRateLimiter) FrontendRateLimiter {
+	return newNamedFallbackRateLimiter("", primary, secondary)
+}
+
+// newNamedFallbackRateLimiter labels the fallback metrics with name.
+func newNamedFallbackRateLimiter(name string, primary FrontendRateLimiter, secondary FrontendRateLimiter) *FallbackRateLimiter {
 	return &FallbackRateLimiter{
+		name:      name,
 		primary:   primary,
 		secondary: secondary,
 	}
 }
 
 func (r *FallbackRateLimiter) Take(ctx context.Context, key string) (bool, error) {
-	if ok, err := r.primary.Take(ctx, key); err != nil {
-		return r.secondary.Take(ctx, key)
-	} else {
-		return ok, err
+	ok, err := r.primary.Take(ctx, key)
+	if err == nil {
+		if r.fallback.Swap(false) {
+			log.Info("primary rate limiter recovered", "limiter", r.name)
+			RecordRateLimitFallback(r.name, false)
+		}
+		return ok, nil
+	}
+	if !r.fallback.Swap(true) {
+		log.Warn("primary rate limiter failed, falling back", "limiter", r.name, "err", err)
+		RecordRateLimitFallback(r.name, true)
+	}
+	rateLimitFallbackTakesTotal.WithLabelValues(r.name).Inc()
+	return r.secondary.Take(ctx, key)
+}
+
+// localFallbackLimit is the limit of the in-memory limiter standing in for a
+// Redis limiter of max while Redis is down. Every instance counts on its
+// own, so it's divided, but it lets at least one request through.
+func localFallbackLimit(max int, divisor int) int {
+	if divisor <= 0 {
+		divisor = defaultFallbackRateLimitDivisor
+	}
+	if limit := max / divisor; limit > 0 {
+		return limit
 	}
+	return 1
+}
+
+// isSenderLimit returns whether the limiter prefix is that of a sender
+// limit, which follows redis.degraded_mode.sender_limits rather than falling
+// back to memory.
+func isSenderLimit(prefix string) bool {
+	return prefix == "senders" || strings.HasPrefix(prefix, "interop_senders")
 }
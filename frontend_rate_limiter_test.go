@@ -84,3 +84,50 @@ func TestFallbackRateLimiter(t *testing.T) {
 		require.False(t, ok)
 	}
 }
+
+func TestFallbackRateLimiterRecovers(t *testing.T) {
+	redisServer, err := miniredis.Run()
+	require.NoError(t, err)
+	defer redisServer.Close()
+
+	redisClient := redis.NewClient(&redis.Options{
+		Addr: fmt.Sprintf("127.0.0.1:%s", redisServer.Port()),
+	})
+
+	frl := newNamedFallbackRateLimiter(
+		"main",
+		NewRedisFrontendRateLimiter(redisClient, time.Minute, 4, "main"),
+		NewMemoryFrontendRateLimit(time.Minute, localFallbackLimit(4, 0)),
+	)
+	ctx := context.Background()
+
+	ok, err := frl.Take(ctx, "foo")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.False(t, frl.fallback.Load())
+
+	// the local limiter is more conservative than the Redis one
+	redisServer.Close()
+	for i := 0; i < 4; i++ {
+		ok, err := frl.Take(ctx, "foo")
+		require.NoError(t, err)
+		require.Equal(t, i < 2, ok)
+	}
+	require.True(t, frl.fallback.Load())
+
+	// connections broken by the restart may fail once more
+	require.NoError(t, redisServer.Restart())
+	require.Eventually(t, func() bool {
+		_, _ = frl.Take(ctx, "bar")
+		return !frl.fallback.Load()
+	}, time.Second, 10*time.Millisecond)
+	ok, err = frl.Take(ctx, "foo")
+	require.NoError(t, err)
+	require.True(t, ok)
+}
+
+func TestLocalFallbackLimit(t *testing.T) {
+	require.Equal(t, 50, localFallbackLimit(100, 0))
+	require.Equal(t, 25, localFallbackLimit(100, 4))
+	require.Equal(t, 1, localFallbackLimit(1, 2))
+}
@@ -0,0 +1,230 @@
+package proxyd
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	defaultGasOracleBlockTime = 2 * time.Second
+	defaultGasOracleInterval  = 250 * time.Millisecond
+	gasOracleFetchTimeout     = 5 * time.Second
+	// bounds the distinct requests warmed per backend group
+	maxGasOracleEntries = 256
+)
+
+var gasOracleMethods = map[string]bool{
+	"eth_gasPrice":             true,
+	"eth_maxPriorityFeePerGas": true,
+	"eth_feeHistory":           true,
+}
+
+// GasOracle answers fee requests once per block for every client. Responses
+// are kept per backend group until the group's consensus moves to the next
+// block, or blockTime passes for groups without consensus tracking. The
+// requests seen in a block are fetched again as soon as the next one starts,
+// so that pollers find them warm.
+type GasOracle struct {
+	blockTime time.Duration
+	interval  time.Duration
+	groups    map[string]*gasOracleGroup
+	inflight  singleflight.Group
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+type gasOracleGroup struct {
+	name string
+	bg   *BackendGroup
+
+	mu      sync.Mutex
+	block   uint64
+	results map[string]*RPCRes
+	// requests seen in the current block, to warm in the next one
+	seen map[string]*RPCReq
+}
+
+func NewGasOracle(cfg GasOracleConfig, backendGroups map[string]*BackendGroup, methodMappings map[string]string) *GasOracle {
+	o := &GasOracle{
+		blockTime: time.Duration(cfg.BlockTime),
+		interval:  time.Duration(cfg.Interval),
+		groups:    make(map[string]*gasOracleGroup),
+		stop:      make(chan struct{}),
+	}
+	if o.blockTime == 0 {
+		o.blockTime = defaultGasOracleBlockTime
+	}
+	if o.interval == 0 {
+		o.interval = defaultGasOracleInterval
+	}
+	for method := range gasOracleMethods {
+		name := methodMappings[method]
+		if bg := backendGroups[name]; bg != nil && o.groups[name] == nil {
+			o.groups[name] = &gasOracleGroup{
+				name:    name,
+				bg:      bg,
+				results: make(map[string]*RPCRes),
+				seen:    make(map[string]*RPCReq),
+			}
+		}
+	}
+	return o
+}
+
+func (o *GasOracle) Handles(method string) bool {
+	return o != nil && gasOracleMethods[method]
+}
+
+// Get answers a fee request for the backend group. It returns a nil response
+// if the group isn't one the oracle serves.
+func (o *GasOracle) Get(ctx context.Context, req *RPCReq, group string) (*RPCRes, error) {
+	g := o.groups[group]
+	if g == nil {
+		return nil, nil
+	}
+	key := req.Method + string(req.Params)
+	block := o.currentBlock(g)
+
+	g.mu.Lock()
+	warm := g.advance(block)
+	res := g.results[key]
+	if len(g.seen) < maxGasOracleEntries {
+		g.seen[key] = req
+	}
+	g.mu.Unlock()
+	if warm != nil {
+		o.warm(g, block, warm)
+	}
+
+	if res != nil {
+		RecordGasOracleRequest(req.Method, true)
+		return withID(res, req.ID), nil
+	}
+	RecordGasOracleRequest(req.Method, false)
+
+	res, err := o.fetch(ctx, g, block, key, req)
+	if err != nil {
+		return nil, err
+	}
+	return withID(res, req.ID), nil
+}
+
+// Start warms the requests of the previous block when a block starts, even
+// if no client asks right away. Stop ends it.
+func (o *GasOracle) Start() {
+	if o == nil {
+		return
+	}
+	o.wg.Add(1)
+	go func() {
+		defer o.wg.Done()
+		ticker := time.NewTicker(o.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-o.stop:
+				return
+			case <-ticker.C:
+				for _, g := range o.groups {
+					block := o.currentBlock(g)
+					g.mu.Lock()
+					warm := g.advance(block)
+					g.mu.Unlock()
+					if warm != nil {
+						o.warm(g, block, warm)
+					}
+				}
+			}
+		}
+	}()
+}
+
+func (o *GasOracle) Stop() {
+	if o == nil {
+		return
+	}
+	close(o.stop)
+	o.wg.Wait()
+}
+
+// advance moves the group to block, returning the requests to warm if it
+// changed. g.mu must be held.
+func (g *gasOracleGroup) advance(block uint64) map[string]*RPCReq {
+	if block == g.block {
+		return nil
+	}
+	warm := g.seen
+	g.block = block
+	g.results = make(map[string]*RPCRes)
+	g.seen = make(map[string]*RPCReq)
+	return warm
+}
+
+func (o *GasOracle) warm(g *gasOracleGroup, block uint64, reqs map[string]*RPCReq) {
+	for key, req := range reqs {
+		o.wg.Add(1)
+		go func(key string, req *RPCReq) {
+			defer o.wg.Done()
+			if _, err := o.fetch(context.Background(), g, block, key, req); err != nil {
+				log.Debug("error warming gas oracle", "method", req.Method, "backend_group", g.name, "err", err)
+			}
+		}(key, req)
+	}
+}
+
+// fetch forwards the request once for everyone asking during the block, and
+// keeps successful responses for the block.
+func (o *GasOracle) fetch(ctx context.Context, g *gasOracleGroup, block uint64, key string, req *RPCReq) (*RPCRes, error) {
+	v, err, _ := o.inflight.Do(g.name+":"+strconv.FormatUint(block, 10)+":"+key, func() (interface{}, error) {
+		// the response is shared, so one client going away mustn't fail it
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), gasOracleFetchTimeout)
+		defer cancel()
+		fwd := &RPCReq{
+			JSONRPC: JSONRPCVersion,
+			Method:  req.Method,
+			Params:  req.Params,
+			ID:      json.RawMessage(`1`),
+		}
+		res, _, err := g.bg.Forward(ctx, []*RPCReq{fwd}, false)
+		if err != nil {
+			return nil, err
+		}
+		if !res[0].IsError() {
+			g.mu.Lock()
+			if g.block == block {
+				g.results[key] = res[0]
+			}
+			g.mu.Unlock()
+		}
+		return res[0], nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*RPCRes), nil
+}
+
+func (o *GasOracle) currentBlock(g *gasOracleGroup) uint64 {
+	if g.bg.Consensus != nil {
+		if block := uint64(g.bg.Consensus.GetLatestBlockNumber()); block > 0 {
+			return block
+		}
+	}
+	return uint64(time.Now().UnixNano() / int64(o.blockTime))
+}
+
+func withID(res *RPCRes, id json.RawMessage) *RPCRes {
+	return &RPCRes{
+		JSONRPC: res.JSONRPC,
+		Result:  res.Result,
+		Error:   res.Error,
+		ID:      id,
+	}
+}
@@ -0,0 +1,180 @@
+package proxyd
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	defaultLatestTranslationMaxLag   = 4
+	defaultLatestTranslationInterval = 2 * time.Second
+)
+
+// HeadTracker polls the heads of the backends of a group without consensus,
+// so that a backend lagging the group head doesn't serve a stale tip as
+// "latest". Requests sent to a lagging backend have "latest" translated into
+// the common height, the lowest head of the backends within max_lag of the
+// group head, which the backend has. Backends lagging further are tried last.
+// Consensus groups rewrite tags to the consensus height instead.
+type HeadTracker struct {
+	bg       *BackendGroup
+	maxLag   uint64
+	interval time.Duration
+
+	mu    sync.RWMutex
+	heads map[string]uint64
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+func NewHeadTracker(bg *BackendGroup, cfg LatestTranslationConfig) *HeadTracker {
+	t := &HeadTracker{
+		bg:       bg,
+		maxLag:   cfg.MaxLag,
+		interval: time.Duration(cfg.Interval),
+		heads:    make(map[string]uint64),
+		stop:     make(chan struct{}),
+	}
+	if t.maxLag == 0 {
+		t.maxLag = defaultLatestTranslationMaxLag
+	}
+	if t.interval == 0 {
+		t.interval = defaultLatestTranslationInterval
+	}
+	return t
+}
+
+// Start polls the heads until Stop is called.
+func (t *HeadTracker) Start() {
+	if t == nil {
+		return
+	}
+	t.update()
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		ticker := time.NewTicker(t.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-t.stop:
+				return
+			case <-ticker.C:
+				t.update()
+			}
+		}
+	}()
+}
+
+func (t *HeadTracker) Stop() {
+	if t == nil {
+		return
+	}
+	close(t.stop)
+	t.wg.Wait()
+}
+
+func (t *HeadTracker) update() {
+	var wg sync.WaitGroup
+	for _, be := range t.bg.members() {
+		wg.Add(1)
+		go func(be *Backend) {
+			defer wg.Done()
+			head, err := t.fetchHead(be)
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if err != nil {
+				log.Warn("error fetching backend head", "name", be.Name, "err", err)
+				delete(t.heads, be.Name)
+				return
+			}
+			t.heads[be.Name] = head
+		}(be)
+	}
+	wg.Wait()
+}
+
+func (t *HeadTracker) fetchHead(be *Backend) (uint64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), t.interval)
+	defer cancel()
+	var res RPCRes
+	if err := be.ForwardRPC(ctx, &res, "68", "eth_blockNumber"); err != nil {
+		return 0, err
+	}
+	s, _ := res.Result.(string)
+	return hexutil.DecodeUint64(s)
+}
+
+// heights returns the group head and the common height.
+func (t *HeadTracker) heights() (head uint64, common uint64) {
+	for _, h := range t.heads {
+		if h > head {
+			head = h
+		}
+	}
+	common = head
+	for _, h := range t.heads {
+		if head-h <= t.maxLag && h < common {
+			common = h
+		}
+	}
+	return head, common
+}
+
+// Order moves the backends lagging the group head by more than max_lag last.
+func (t *HeadTracker) Order(backends []*Backend) []*Backend {
+	if t == nil {
+		return backends
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	head, _ := t.heights()
+	current := make([]*Backend, 0, len(backends))
+	var stale []*Backend
+	for _, be := range backends {
+		if h, ok := t.heads[be.Name]; ok && head-h > t.maxLag {
+			stale = append(stale, be)
+		} else {
+			current = append(current, be)
+		}
+	}
+	return append(current, stale...)
+}
+
+// Translate returns the requests to send to the backend, with "latest"
+// translated into the common height if the backend lags the group head.
+func (t *HeadTracker) Translate(be *Backend, rpcReqs []*RPCReq) []*RPCReq {
+	if t == nil {
+		return rpcReqs
+	}
+	t.mu.RLock()
+	h, ok := t.heads[be.Name]
+	head, common := t.heights()
+	t.mu.RUnlock()
+	if !ok || h >= head {
+		return rpcReqs
+	}
+
+	rctx := RewriteContext{latest: hexutil.Uint64(common), latestOnly: true}
+	translated := make([]*RPCReq, len(rpcReqs))
+	changed := false
+	for i, req := range rpcReqs {
+		cp := *req
+		if rw, _ := RewriteRequest(rctx, &cp, nil); rw == RewriteOverrideRequest {
+			translated[i] = &cp
+			changed = true
+		} else {
+			translated[i] = req
+		}
+	}
+	if !changed {
+		return rpcReqs
+	}
+	RecordLatestTranslation(t.bg.Name, be.Name)
+	return translated
+}
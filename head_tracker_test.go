@@ -0,0 +1,50 @@
+package proxyd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeadTracker(t *testing.T) {
+	a, b, c, d := &Backend{Name: "a"}, &Backend{Name: "b"}, &Backend{Name: "c"}, &Backend{Name: "d"}
+	bg := &BackendGroup{Name: "main", Backends: []*Backend{a, b, c, d}}
+	ht := NewHeadTracker(bg, LatestTranslationConfig{MaxLag: 2})
+	ht.heads = map[string]uint64{"a": 100, "b": 99, "c": 90}
+
+	// backends lagging by more than max_lag are tried last, and backends with
+	// unknown heads aren't moved
+	require.Equal(t, []*Backend{a, b, d, c}, ht.Order([]*Backend{c, a, b, d}))
+
+	req := func(method, params string) *RPCReq {
+		return &RPCReq{JSONRPC: "2.0", Method: method, Params: json.RawMessage(params), ID: json.RawMessage("1")}
+	}
+	reqs := []*RPCReq{
+		req("eth_call", `[{"to":"0x01"},"latest"]`),
+		req("eth_getBalance", `["0x01"]`),
+		req("eth_getBlockByNumber", `["finalized",false]`),
+		req("eth_getBlockByNumber", `["0x6e",false]`),
+		req("eth_getLogs", `[{"fromBlock":"0x1","toBlock":"latest"}]`),
+		req("eth_chainId", `[]`),
+	}
+
+	// the head and backends with unknown heads get the requests as is
+	require.Equal(t, reqs, ht.Translate(a, reqs))
+	require.Equal(t, reqs, ht.Translate(d, reqs))
+
+	// lagging backends get latest translated into the common height, without
+	// changing the requests of other backends
+	translated := ht.Translate(b, reqs)
+	require.JSONEq(t, `[{"to":"0x01"},{"blockNumber":"0x63"}]`, string(translated[0].Params))
+	require.JSONEq(t, `["0x01",{"blockNumber":"0x63"}]`, string(translated[1].Params))
+	require.Same(t, reqs[2], translated[2])
+	require.Same(t, reqs[3], translated[3])
+	require.JSONEq(t, `[{"fromBlock":"0x1","toBlock":"0x63"}]`, string(translated[4].Params))
+	require.Same(t, reqs[5], translated[5])
+	require.JSONEq(t, `[{"to":"0x01"},"latest"]`, string(reqs[0].Params))
+
+	var disabled *HeadTracker
+	require.Equal(t, reqs, disabled.Translate(b, reqs))
+	require.Equal(t, []*Backend{c, a}, disabled.Order([]*Backend{c, a}))
+}
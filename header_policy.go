@@ -0,0 +1,150 @@
+package proxyd
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// ForwardAllHeaders may be used in a header policy's forward list to pass on
+// every client header that isn't stripped.
+const ForwardAllHeaders = "*"
+
+var (
+	// hopByHopHeaders only make sense for a single connection and are never
+	// forwarded, see RFC 9110 section 7.6.1.
+	hopByHopHeaders = []string{
+		"Connection",
+		"Keep-Alive",
+		"Proxy-Authenticate",
+		"Proxy-Authorization",
+		"Proxy-Connection",
+		"Te",
+		"Trailer",
+		"Transfer-Encoding",
+		"Upgrade",
+	}
+
+	// defaultStrippedHeaders are not passed on by a wildcard forward, either
+	// because they carry client credentials or because proxyd sets them itself.
+	// They can still be forwarded by listing them explicitly.
+	defaultStrippedHeaders = []string{
+		"Authorization",
+		"Cookie",
+		"Host",
+		"Content-Length",
+		"Content-Type",
+		"Accept-Encoding",
+		"X-Forwarded-For",
+	}
+
+	// defaultForwardedHeaders are forwarded to every backend unless stripped.
+	defaultForwardedHeaders = []string{
+		DefaultOpTxProxyAuthHeader,
+	}
+)
+
+// HeaderPolicy decides which client request headers are passed on to a
+// backend, and under which name.
+type HeaderPolicy struct {
+	// forward maps canonical client header names to backend header names
+	forward    map[string]string
+	forwardAll bool
+	strip      map[string]bool
+}
+
+func NewHeaderPolicy(cfg HeaderPolicyConfig) (*HeaderPolicy, error) {
+	p := &HeaderPolicy{
+		forward: make(map[string]string),
+		strip:   make(map[string]bool),
+	}
+
+	for _, h := range hopByHopHeaders {
+		p.strip[h] = true
+	}
+	for _, h := range cfg.Strip {
+		p.strip[http.CanonicalHeaderKey(h)] = true
+	}
+
+	add := func(from, to string) {
+		from = http.CanonicalHeaderKey(from)
+		if !p.strip[from] {
+			p.forward[from] = http.CanonicalHeaderKey(to)
+		}
+	}
+
+	for _, h := range defaultForwardedHeaders {
+		add(h, h)
+	}
+	for _, h := range cfg.Forward {
+		if h == ForwardAllHeaders {
+			p.forwardAll = true
+			continue
+		}
+		if isHopByHopHeader(h) {
+			return nil, fmt.Errorf("cannot forward hop-by-hop header %s", h)
+		}
+		add(h, h)
+	}
+	for from, to := range cfg.Rename {
+		if isHopByHopHeader(from) || isHopByHopHeader(to) {
+			return nil, fmt.Errorf("cannot rename hop-by-hop header %s", from)
+		}
+		if to == "" {
+			return nil, fmt.Errorf("empty rename target for header %s", from)
+		}
+		add(from, to)
+	}
+
+	if p.forwardAll {
+		for _, h := range defaultStrippedHeaders {
+			if _, ok := p.forward[h]; !ok {
+				p.strip[h] = true
+			}
+		}
+	}
+
+	return p, nil
+}
+
+func isHopByHopHeader(h string) bool {
+	h = http.CanonicalHeaderKey(h)
+	for _, hop := range hopByHopHeaders {
+		if h == hop {
+			return true
+		}
+	}
+	return false
+}
+
+// CapturedHeaders returns the client headers the frontend must capture for
+// this policy. A policy that forwards all headers returns ForwardAllHeaders.
+func (p *HeaderPolicy) CapturedHeaders() []string {
+	if p.forwardAll {
+		return []string{ForwardAllHeaders}
+	}
+	headers := make([]string, 0, len(p.forward))
+	for h := range p.forward {
+		headers = append(headers, h)
+	}
+	return headers
+}
+
+// Apply copies the permitted client headers onto the outgoing request headers.
+func (p *HeaderPolicy) Apply(clientHeaders http.Header, out http.Header) {
+	for name, values := range clientHeaders {
+		name = http.CanonicalHeaderKey(name)
+		if p.strip[name] {
+			continue
+		}
+		target, ok := p.forward[name]
+		if !ok {
+			if !p.forwardAll {
+				continue
+			}
+			target = name
+		}
+		for _, value := range values {
+			out.Add(target, value)
+		}
+	}
+}
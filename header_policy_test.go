@@ -0,0 +1,93 @@
+package proxyd
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeaderPolicyApply(t *testing.T) {
+	client := http.Header{}
+	client.Set("X-Flashbots-Signature", "sig")
+	client.Set("X-Optimism-Signature", "op")
+	client.Set("X-Client-Id", "abc")
+	client.Set("Authorization", "Bearer secret")
+	client.Set("Connection", "keep-alive")
+	client.Set("User-Agent", "test")
+
+	tests := []struct {
+		name     string
+		cfg      HeaderPolicyConfig
+		expected http.Header
+	}{
+		{
+			name: "defaults",
+			cfg:  HeaderPolicyConfig{},
+			expected: http.Header{
+				"X-Optimism-Signature": {"op"},
+			},
+		},
+		{
+			name: "forward",
+			cfg:  HeaderPolicyConfig{Forward: []string{"x-flashbots-signature"}},
+			expected: http.Header{
+				"X-Flashbots-Signature": {"sig"},
+				"X-Optimism-Signature":  {"op"},
+			},
+		},
+		{
+			name: "strip wins over forward and defaults",
+			cfg: HeaderPolicyConfig{
+				Forward: []string{"X-Flashbots-Signature"},
+				Strip:   []string{"X-Flashbots-Signature", "X-Optimism-Signature"},
+			},
+			expected: http.Header{},
+		},
+		{
+			name: "rename",
+			cfg:  HeaderPolicyConfig{Rename: map[string]string{"X-Client-Id": "X-Upstream-Client"}},
+			expected: http.Header{
+				"X-Optimism-Signature": {"op"},
+				"X-Upstream-Client":    {"abc"},
+			},
+		},
+		{
+			name: "forward all strips hop-by-hop and sensitive headers",
+			cfg:  HeaderPolicyConfig{Forward: []string{"*"}, Strip: []string{"User-Agent"}},
+			expected: http.Header{
+				"X-Flashbots-Signature": {"sig"},
+				"X-Optimism-Signature":  {"op"},
+				"X-Client-Id":           {"abc"},
+			},
+		},
+		{
+			name: "forward all with explicit sensitive header",
+			cfg:  HeaderPolicyConfig{Forward: []string{"*", "Authorization"}, Strip: []string{"User-Agent"}},
+			expected: http.Header{
+				"X-Flashbots-Signature": {"sig"},
+				"X-Optimism-Signature":  {"op"},
+				"X-Client-Id":           {"abc"},
+				"Authorization":         {"Bearer secret"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := NewHeaderPolicy(tt.cfg)
+			require.NoError(t, err)
+			out := http.Header{}
+			policy.Apply(client, out)
+			require.Equal(t, tt.expected, out)
+		})
+	}
+}
+
+func TestHeaderPolicyRejectsHopByHop(t *testing.T) {
+	_, err := NewHeaderPolicy(HeaderPolicyConfig{Forward: []string{"connection"}})
+	require.Error(t, err)
+
+	_, err = NewHeaderPolicy(HeaderPolicyConfig{Rename: map[string]string{"X-Foo": "Upgrade"}})
+	require.Error(t, err)
+}
@@ -0,0 +1,240 @@
+package proxyd
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	healthGossipRedisChannel     = "backend_health"
+	defaultHealthGossipInterval  = 5 * time.Second
+	defaultHealthGossipReportTTL = 15 * time.Second
+	healthGossipPublishTimeout   = time.Second
+
+	healthGossipReasonUnhealthy = "unhealthy"
+	healthGossipReasonBanned    = "banned"
+)
+
+// peerReport is until when peers reported a backend failing.
+type peerReport struct {
+	mu    sync.RWMutex
+	until time.Time
+}
+
+// PeerReported returns whether another proxyd instance recently reported the
+// backend unhealthy or banned.
+func (b *Backend) PeerReported() bool {
+	b.peerReport.mu.RLock()
+	defer b.peerReport.mu.RUnlock()
+	return time.Now().Before(b.peerReport.until)
+}
+
+func (b *Backend) reportedByPeer(until time.Time) {
+	b.peerReport.mu.Lock()
+	defer b.peerReport.mu.Unlock()
+	if until.After(b.peerReport.until) {
+		b.peerReport.until = until
+	}
+}
+
+// peerOrderedBackends moves the backends that peers reported failing last,
+// so that a backend failing for one instance is avoided by the fleet before
+// each instance finds out on its own.
+func peerOrderedBackends(backends []*Backend) []*Backend {
+	ordered := make([]*Backend, 0, len(backends))
+	var reported []*Backend
+	for _, be := range backends {
+		if be.PeerReported() {
+			reported = append(reported, be)
+		} else {
+			ordered = append(ordered, be)
+		}
+	}
+	if len(reported) == 0 {
+		return backends
+	}
+	return append(ordered, reported...)
+}
+
+type healthGossipMessage struct {
+	Instance string                        `json:"instance"`
+	Backends map[string]healthGossipReport `json:"backends"`
+}
+
+type healthGossipReport struct {
+	Reason string `json:"reason"`
+	// Until is in unix milliseconds.
+	Until int64 `json:"until"`
+}
+
+// HealthGossip shares the backends each proxyd instance finds unhealthy, or
+// its consensus pollers banned, with the rest of the fleet over Redis pub/sub.
+// Reported backends are tried last until the report expires: unhealthy
+// backends for ReportTTL, banned ones until their ban ends.
+type HealthGossip struct {
+	client    redis.UniversalClient
+	channel   string
+	instance  string
+	interval  time.Duration
+	ttl       time.Duration
+	backends  map[string]*Backend
+	consensus []*ConsensusPoller
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+func NewHealthGossip(cfg HealthGossipConfig, client redis.UniversalClient, namespace string, backends map[string]*Backend, groups map[string]*BackendGroup) *HealthGossip {
+	g := &HealthGossip{
+		client:   client,
+		channel:  healthGossipRedisChannel,
+		interval: time.Duration(cfg.Interval),
+		ttl:      time.Duration(cfg.ReportTTL),
+		backends: backends,
+		stop:     make(chan struct{}),
+	}
+	if namespace != "" {
+		g.channel = namespace + ":" + healthGossipRedisChannel
+	}
+	if g.interval == 0 {
+		g.interval = defaultHealthGossipInterval
+	}
+	if g.ttl == 0 {
+		g.ttl = defaultHealthGossipReportTTL
+	}
+	id := make([]byte, 8)
+	_, _ = rand.Read(id)
+	g.instance = hex.EncodeToString(id)
+	for _, name := range sortedKeys(groups) {
+		if cp := groups[name].Consensus; cp != nil {
+			g.consensus = append(g.consensus, cp)
+		}
+	}
+	return g
+}
+
+// Start publishes and receives reports until Stop is called.
+func (g *HealthGossip) Start() {
+	if g == nil {
+		return
+	}
+	g.wg.Add(2)
+	go func() {
+		defer g.wg.Done()
+		ticker := time.NewTicker(g.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-g.stop:
+				return
+			case <-ticker.C:
+				g.publish()
+			}
+		}
+	}()
+	go func() {
+		defer g.wg.Done()
+		g.subscribe()
+	}()
+}
+
+func (g *HealthGossip) Stop() {
+	if g == nil {
+		return
+	}
+	close(g.stop)
+	g.wg.Wait()
+}
+
+// observations returns the backends this instance finds failing.
+func (g *HealthGossip) observations() map[string]healthGossipReport {
+	now := time.Now()
+	reports := make(map[string]healthGossipReport)
+	for name, be := range g.backends {
+		if !be.IsHealthy() {
+			reports[name] = healthGossipReport{
+				Reason: healthGossipReasonUnhealthy,
+				Until:  now.Add(g.ttl).UnixMilli(),
+			}
+		}
+	}
+	for _, cp := range g.consensus {
+		for be := range cp.backendState {
+			if !cp.IsBanned(be) {
+				continue
+			}
+			until := cp.BannedUntil(be).UnixMilli()
+			if report, ok := reports[be.Name]; !ok || report.Until < until {
+				reports[be.Name] = healthGossipReport{Reason: healthGossipReasonBanned, Until: until}
+			}
+		}
+	}
+	return reports
+}
+
+func (g *HealthGossip) publish() {
+	reports := g.observations()
+	if len(reports) == 0 {
+		return
+	}
+	data, err := json.Marshal(healthGossipMessage{Instance: g.instance, Backends: reports})
+	if err != nil {
+		log.Error("error encoding backend health gossip", "err", err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), healthGossipPublishTimeout)
+	defer cancel()
+	if err := g.client.Publish(ctx, g.channel, data).Err(); err != nil {
+		log.Warn("error publishing backend health gossip", "err", err)
+	}
+}
+
+// subscribe receives reports until Stop is called. The subscription
+// reconnects on its own if Redis goes away.
+func (g *HealthGossip) subscribe() {
+	pubsub := g.client.Subscribe(context.Background(), g.channel)
+	defer pubsub.Close()
+	msgs := pubsub.Channel()
+	for {
+		select {
+		case <-g.stop:
+			return
+		case msg, ok := <-msgs:
+			if !ok {
+				return
+			}
+			g.receive([]byte(msg.Payload))
+		}
+	}
+}
+
+// receive applies the reports of another instance.
+func (g *HealthGossip) receive(data []byte) {
+	var msg healthGossipMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		log.Warn("error decoding backend health gossip", "err", err)
+		return
+	}
+	if msg.Instance == g.instance {
+		return
+	}
+	for name, report := range msg.Backends {
+		be := g.backends[name]
+		if be == nil {
+			continue
+		}
+		if report.Reason != healthGossipReasonUnhealthy && report.Reason != healthGossipReasonBanned {
+			continue
+		}
+		be.reportedByPeer(time.UnixMilli(report.Until))
+		log.Debug("peer reported backend", "backend", name, "reason", report.Reason, "peer", msg.Instance)
+		RecordHealthGossipReport(name, report.Reason)
+	}
+}
@@ -0,0 +1,57 @@
+package proxyd
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthGossip(t *testing.T) {
+	newBackends := func() map[string]*Backend {
+		return map[string]*Backend{
+			"a": NewBackend("a", "http://a:8545", "", nil),
+			"b": NewBackend("b", "http://b:8545", "", nil),
+			"c": NewBackend("c", "http://c:8545", "", nil),
+		}
+	}
+	local, remote := newBackends(), newBackends()
+	g := NewHealthGossip(HealthGossipConfig{ReportTTL: TOMLDuration(time.Minute)}, nil, "", local, nil)
+	peer := NewHealthGossip(HealthGossipConfig{}, nil, "", remote, nil)
+
+	// b fails every request this instance sends it
+	for i := 0; i < 10; i++ {
+		local["b"].networkRequestsSlidingWindow.Incr()
+		local["b"].intermittentErrorsSlidingWindow.Incr()
+	}
+	reports := g.observations()
+	require.Len(t, reports, 1)
+	require.Equal(t, healthGossipReasonUnhealthy, reports["b"].Reason)
+
+	data, err := json.Marshal(healthGossipMessage{Instance: g.instance, Backends: reports})
+	require.NoError(t, err)
+
+	// instances ignore their own reports
+	g.receive(data)
+	require.False(t, local["b"].PeerReported())
+
+	// peers try the reported backend last, without it failing for them
+	ordered := []*Backend{remote["b"], remote["a"], remote["c"]}
+	require.Equal(t, ordered, peerOrderedBackends(ordered))
+	peer.receive(data)
+	require.True(t, remote["b"].IsHealthy())
+	require.True(t, remote["b"].PeerReported())
+	require.Equal(t, []*Backend{remote["a"], remote["c"], remote["b"]}, peerOrderedBackends(ordered))
+
+	// reports expire, and unknown backends or reasons are ignored
+	expired, err := json.Marshal(healthGossipMessage{Instance: g.instance, Backends: map[string]healthGossipReport{
+		"a": {Reason: healthGossipReasonBanned, Until: time.Now().Add(-time.Second).UnixMilli()},
+		"c": {Reason: "bogus", Until: time.Now().Add(time.Minute).UnixMilli()},
+		"d": {Reason: healthGossipReasonUnhealthy, Until: time.Now().Add(time.Minute).UnixMilli()},
+	}})
+	require.NoError(t, err)
+	peer.receive(expired)
+	require.False(t, remote["a"].PeerReported())
+	require.False(t, remote["c"].PeerReported())
+}
@@ -0,0 +1,133 @@
+package proxyd
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	// HMACAuthHeader carries "<key id>:<unix timestamp>:<signature>", where
+	// the signature is the hex encoded HMAC-SHA256, under the shared key, of
+	// "<unix timestamp>:<hex encoded SHA-256 of the body>".
+	HMACAuthHeader = "X-Proxyd-Signature"
+
+	defaultHMACMaxClockSkew = 30 * time.Second
+)
+
+// HMACAuth authenticates internal clients that sign their requests with a
+// shared key instead of an Ethereum key. A valid signature authenticates the
+// request as the key's ID, like an [authentication] secret would. The
+// timestamp bounds how long a captured request can be replayed.
+type HMACAuth struct {
+	maxClockSkew time.Duration
+
+	mu   sync.RWMutex
+	keys map[string][]byte
+}
+
+func NewHMACAuth(cfg HMACAuthConfig, secrets *SecretStore) (*HMACAuth, error) {
+	if len(cfg.Keys) == 0 {
+		return nil, nil
+	}
+	h := &HMACAuth{
+		keys:         make(map[string][]byte, len(cfg.Keys)),
+		maxClockSkew: time.Duration(cfg.MaxClockSkew),
+	}
+	if h.maxClockSkew == 0 {
+		h.maxClockSkew = defaultHMACMaxClockSkew
+	}
+	for id, key := range cfg.Keys {
+		if err := checkHMACKeyID(id); err != nil {
+			return nil, err
+		}
+		resolved, err := secrets.Resolve(key)
+		if err != nil {
+			return nil, err
+		}
+		if resolved == "" {
+			return nil, fmt.Errorf("hmac key %s is empty", id)
+		}
+		h.keys[id] = []byte(resolved)
+		id := id
+		secrets.Watch(key, func(value string) {
+			h.setKey(id, value)
+		})
+	}
+	return h, nil
+}
+
+// setKey replaces a key after its secret is refreshed.
+func (h *HMACAuth) setKey(id, key string) {
+	if key == "" {
+		log.Error("ignoring empty refreshed hmac key", "id", id)
+		return
+	}
+	h.mu.Lock()
+	h.keys[id] = []byte(key)
+	h.mu.Unlock()
+}
+
+func checkHMACKeyID(id string) error {
+	if id == "" || id == "none" || strings.Contains(id, ":") {
+		return fmt.Errorf("invalid hmac key id %q", id)
+	}
+	return nil
+}
+
+// Signed reports whether r carries an HMAC signature proxyd should verify.
+func (h *HMACAuth) Signed(r *http.Request) bool {
+	return h != nil && r.Header.Get(HMACAuthHeader) != ""
+}
+
+// Verify checks the signature of body and returns the ID of the key that
+// signed it.
+func (h *HMACAuth) Verify(header string, body []byte) (string, error) {
+	parts := strings.SplitN(header, ":", 3)
+	if len(parts) != 3 {
+		return "", fmt.Errorf("%w: malformed header", ErrInvalidSignature)
+	}
+	id, timestamp, signature := parts[0], parts[1], parts[2]
+	h.mu.RLock()
+	key := h.keys[id]
+	h.mu.RUnlock()
+	if key == nil {
+		return "", fmt.Errorf("%w: unknown key %s", ErrInvalidSignature, id)
+	}
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("%w: malformed timestamp", ErrInvalidSignature)
+	}
+	if skew := time.Since(time.Unix(unix, 0)).Abs(); skew > h.maxClockSkew {
+		return "", fmt.Errorf("%w: %w: timestamp off by %s", ErrInvalidSignature, ErrExpiredSignature, skew)
+	}
+	sig, err := hex.DecodeString(signature)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidSignature, err)
+	}
+	if !hmac.Equal(sig, hmacSignature(key, timestamp, body)) {
+		return "", fmt.Errorf("%w: signature mismatch", ErrInvalidSignature)
+	}
+	return id, nil
+}
+
+// SignHMAC returns the X-Proxyd-Signature header signing body with key.
+func SignHMAC(id string, key []byte, timestamp time.Time, body []byte) string {
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+	return id + ":" + ts + ":" + hex.EncodeToString(hmacSignature(key, ts, body))
+}
+
+func hmacSignature(key []byte, timestamp string, body []byte) []byte {
+	bodyHash := sha256.Sum256(body)
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(timestamp + ":" + hex.EncodeToString(bodyHash[:])))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,52 @@
+package proxyd
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHMACAuth(t *testing.T) {
+	secrets, err := NewSecretStore(SecretsConfig{})
+	require.NoError(t, err)
+	h, err := NewHMACAuth(HMACAuthConfig{Keys: map[string]string{"indexer": "shared"}}, secrets)
+	require.NoError(t, err)
+
+	body := []byte(`{"jsonrpc":"2.0","method":"eth_chainId","id":1}`)
+	id, err := h.Verify(SignHMAC("indexer", []byte("shared"), time.Now(), body), body)
+	require.NoError(t, err)
+	require.Equal(t, "indexer", id)
+
+	invalid := map[string]string{
+		"tampered body":   SignHMAC("indexer", []byte("shared"), time.Now(), []byte("{}")),
+		"wrong key":       SignHMAC("indexer", []byte("other"), time.Now(), body),
+		"unknown key id":  SignHMAC("other", []byte("shared"), time.Now(), body),
+		"stale timestamp": SignHMAC("indexer", []byte("shared"), time.Now().Add(-time.Minute), body),
+		"future":          SignHMAC("indexer", []byte("shared"), time.Now().Add(time.Minute), body),
+		"malformed":       "indexer:deadbeef",
+		"bad timestamp":   "indexer:now:deadbeef",
+		"bad signature":   "indexer:1:xyz",
+	}
+	for name, header := range invalid {
+		t.Run(name, func(t *testing.T) {
+			_, err := h.Verify(header, body)
+			require.ErrorIs(t, err, ErrInvalidSignature)
+		})
+	}
+
+	_, err = h.Verify(SignHMAC("indexer", []byte("shared"), time.Now().Add(-time.Minute), body), body)
+	require.ErrorIs(t, err, ErrExpiredSignature)
+
+	r := httptest.NewRequest("POST", "/", nil)
+	require.False(t, h.Signed(r))
+	r.Header.Set(HMACAuthHeader, "indexer:1:00")
+	require.True(t, h.Signed(r))
+	require.False(t, (*HMACAuth)(nil).Signed(r))
+
+	_, err = NewHMACAuth(HMACAuthConfig{Keys: map[string]string{"a:b": "shared"}}, secrets)
+	require.Error(t, err)
+	_, err = NewHMACAuth(HMACAuthConfig{Keys: map[string]string{"indexer": ""}}, secrets)
+	require.Error(t, err)
+}
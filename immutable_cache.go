@@ -0,0 +1,174 @@
+package proxyd
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+const defaultImmutableCacheMaxEntries = 10000
+
+// immutableBlockParams is the index of the block number or hash param of the
+// methods whose responses never change once that block is final.
+var immutableBlockParams = map[string]int{
+	"eth_getBlockByNumber":                    0,
+	"eth_getBlockTransactionCountByNumber":    0,
+	"eth_getTransactionByBlockNumberAndIndex": 0,
+	"eth_getUncleByBlockNumberAndIndex":       0,
+	"eth_getUncleCountByBlockNumber":          0,
+	"eth_getBlockReceipts":                    0,
+	"eth_getCode":                             1,
+	"eth_getBalance":                          1,
+	"eth_getTransactionCount":                 1,
+	"eth_getStorageAt":                        2,
+}
+
+// immutableTxMethods look transactions up by hash. Their responses never
+// change once the block including the transaction is final.
+var immutableTxMethods = map[string]bool{
+	"eth_getTransactionByHash":  true,
+	"eth_getTransactionReceipt": true,
+}
+
+func WithFinalizedBlock(ctx context.Context, number hexutil.Uint64) context.Context {
+	return context.WithValue(ctx, ContextKeyFinalizedBlock, number) // nolint:staticcheck
+}
+
+// GetFinalizedBlock returns the finalized block of the backend group serving
+// a request, or 0 if it isn't known.
+func GetFinalizedBlock(ctx context.Context) hexutil.Uint64 {
+	number, _ := ctx.Value(ContextKeyFinalizedBlock).(hexutil.Uint64)
+	return number
+}
+
+// immutableRPCCache caches the responses that can never change, like blocks
+// and receipts older than finality or code at a historical block, in a tier
+// of its own without TTL, evicting the least recently used. Responses are only
+// stored if the backend group's consensus says their block is final, so
+// groups without consensus only cache state queried by block hash. Other
+// requests go to next.
+type immutableRPCCache struct {
+	next     RPCCache
+	handlers map[string]*StaticMethodHandler
+}
+
+func newImmutableRPCCache(next RPCCache, cache Cache) RPCCache {
+	handlers := make(map[string]*StaticMethodHandler, len(immutableBlockParams)+len(immutableTxMethods))
+	for method := range immutableBlockParams {
+		handlers[method] = &StaticMethodHandler{cache: cache, filterGet: immutableCacheable}
+	}
+	for method := range immutableTxMethods {
+		handlers[method] = &StaticMethodHandler{cache: cache}
+	}
+	return &immutableRPCCache{next: next, handlers: handlers}
+}
+
+func newImmutableCache(maxEntries int) Cache {
+	if maxEntries <= 0 {
+		maxEntries = defaultImmutableCacheMaxEntries
+	}
+	rep, _ := lru.New(maxEntries)
+	return &cache{rep}
+}
+
+// immutableBlockParam returns the block number of the param at index, nil if
+// it's a block hash, or false if it's a tag like latest, whose block changes.
+func immutableBlockParam(req *RPCReq, index int) (*uint64, bool) {
+	var params []json.RawMessage
+	if err := json.Unmarshal(req.Params, &params); err != nil || len(params) <= index {
+		return nil, false
+	}
+	var block rpc.BlockNumberOrHash
+	if err := json.Unmarshal(params[index], &block); err != nil {
+		return nil, false
+	}
+	if _, ok := block.Hash(); ok {
+		// a canonical block may stop being canonical before finality
+		return nil, !block.RequireCanonical
+	}
+	number, ok := block.Number()
+	if !ok || number < rpc.EarliestBlockNumber {
+		return nil, false
+	}
+	n := uint64(number.Int64())
+	return &n, true
+}
+
+// immutableCacheable returns whether req asks for data that may be immutable,
+// depending on the response and finality.
+func immutableCacheable(req *RPCReq) bool {
+	if index, ok := immutableBlockParams[req.Method]; ok {
+		_, ok := immutableBlockParam(req, index)
+		return ok
+	}
+	return immutableTxMethods[req.Method]
+}
+
+// isImmutableResponse returns whether res answers req with data that can
+// never change, given the finalized block in ctx.
+func isImmutableResponse(ctx context.Context, req *RPCReq, res *RPCRes) bool {
+	if res == nil || res.IsError() || res.Result == nil || !immutableCacheable(req) {
+		return false
+	}
+	if index, ok := immutableBlockParams[req.Method]; ok {
+		number, _ := immutableBlockParam(req, index)
+		return isFinal(ctx, number)
+	}
+	tx, ok := res.Result.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	hex, _ := tx["blockNumber"].(string)
+	number, err := hexutil.DecodeUint64(hex)
+	return err == nil && isFinal(ctx, &number)
+}
+
+// isFinal returns whether a block number, or hash if nil, is final.
+func isFinal(ctx context.Context, number *uint64) bool {
+	if number == nil {
+		return true
+	}
+	finalized := GetFinalizedBlock(ctx)
+	return finalized > 0 && *number <= uint64(finalized)
+}
+
+func (c *immutableRPCCache) GetRPC(ctx context.Context, req *RPCReq) (*RPCRes, error) {
+	handler := c.handlers[req.Method]
+	if handler == nil {
+		return c.next.GetRPC(ctx, req)
+	}
+	if !immutableCacheable(req) {
+		return nil, nil
+	}
+	res, err := handler.GetRPCMethod(ctx, req)
+	switch {
+	case err != nil:
+		RecordImmutableCache(req.Method, "error")
+	case res == nil:
+		RecordImmutableCache(req.Method, "miss")
+	default:
+		RecordImmutableCache(req.Method, "hit")
+	}
+	return res, err
+}
+
+func (c *immutableRPCCache) PutRPC(ctx context.Context, req *RPCReq, res *RPCRes) error {
+	handler := c.handlers[req.Method]
+	if handler == nil {
+		return c.next.PutRPC(ctx, req, res)
+	}
+	if !isImmutableResponse(ctx, req, res) {
+		if immutableCacheable(req) {
+			RecordImmutableCache(req.Method, "not_final")
+		}
+		return nil
+	}
+	if err := handler.PutRPCMethod(ctx, req, res); err != nil {
+		return err
+	}
+	RecordImmutableCache(req.Method, "stored")
+	return nil
+}
@@ -0,0 +1,87 @@
+package proxyd
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestImmutableRPCCache(t *testing.T) {
+	cache := newImmutableRPCCache(newRPCCache(newMemoryCache()), newImmutableCache(0))
+	ctx := context.Background()
+	finalCtx := WithFinalizedBlock(ctx, 100)
+
+	req := func(method string, params ...interface{}) *RPCReq {
+		return &RPCReq{JSONRPC: "2.0", Method: method, Params: mustMarshalJSON(params), ID: json.RawMessage("1")}
+	}
+	res := func(result interface{}) *RPCRes {
+		return &RPCRes{JSONRPC: "2.0", Result: result, ID: json.RawMessage("1")}
+	}
+	requireCached := func(ctx context.Context, r *RPCReq, cached bool) {
+		t.Helper()
+		got, err := cache.GetRPC(ctx, r)
+		require.NoError(t, err)
+		require.Equal(t, cached, got != nil)
+	}
+
+	// final blocks are cached
+	block := req("eth_getBlockByNumber", "0x64", false)
+	require.NoError(t, cache.PutRPC(finalCtx, block, res(map[string]interface{}{"number": "0x64"})))
+	requireCached(ctx, block, true)
+
+	// blocks that aren't final yet, or whose finality is unknown, aren't
+	pending := req("eth_getBlockByNumber", "0x65", false)
+	require.NoError(t, cache.PutRPC(finalCtx, pending, res(map[string]interface{}{"number": "0x65"})))
+	requireCached(finalCtx, pending, false)
+	unknown := req("eth_getBlockByNumber", "0x1", false)
+	require.NoError(t, cache.PutRPC(ctx, unknown, res(map[string]interface{}{"number": "0x1"})))
+	requireCached(finalCtx, unknown, false)
+
+	// tags never are
+	latest := req("eth_getBlockByNumber", "latest", false)
+	require.NoError(t, cache.PutRPC(finalCtx, latest, res(map[string]interface{}{"number": "0x64"})))
+	requireCached(finalCtx, latest, false)
+
+	// state at a block hash is, finality or not
+	code := req("eth_getCode", "0x0000000000000000000000000000000000000001", "0xb903239f8543d04b5dc1ba6579132b143087c68db1b2168786408fcbce568238")
+	require.NoError(t, cache.PutRPC(ctx, code, res("0x6080")))
+	requireCached(ctx, code, true)
+	canonical := req("eth_getCode", "0x0000000000000000000000000000000000000001", map[string]interface{}{
+		"blockHash":        "0xb903239f8543d04b5dc1ba6579132b143087c68db1b2168786408fcbce568238",
+		"requireCanonical": true,
+	})
+	require.NoError(t, cache.PutRPC(ctx, canonical, res("0x6080")))
+	requireCached(ctx, canonical, false)
+	storage := req("eth_getStorageAt", "0x0000000000000000000000000000000000000001", "0x0", "0x10")
+	require.NoError(t, cache.PutRPC(finalCtx, storage, res("0x01")))
+	requireCached(ctx, storage, true)
+
+	// transactions are cached once their block is final
+	receipt := req("eth_getTransactionReceipt", "0x88df016429689c079f3b2f6ad39fa052532c56795b733da78a91ebe6a713944b")
+	require.NoError(t, cache.PutRPC(finalCtx, receipt, res(map[string]interface{}{"blockNumber": "0x65"})))
+	requireCached(ctx, receipt, false)
+	require.NoError(t, cache.PutRPC(finalCtx, receipt, res(map[string]interface{}{"blockNumber": "0x63"})))
+	requireCached(ctx, receipt, true)
+
+	// other methods go to the TTL cache
+	chainID := req("eth_chainId")
+	require.NoError(t, cache.PutRPC(ctx, chainID, res("0x1")))
+	requireCached(ctx, chainID, true)
+	requireCached(ctx, req("eth_blockNumber"), false)
+}
+
+func TestImmutableCacheEviction(t *testing.T) {
+	cache := newImmutableCache(2)
+	ctx := context.Background()
+	for _, key := range []string{"a", "b", "c"} {
+		require.NoError(t, cache.Put(ctx, key, key))
+	}
+	val, err := cache.Get(ctx, "a")
+	require.NoError(t, err)
+	require.Empty(t, val)
+	val, err = cache.Get(ctx, "c")
+	require.NoError(t, err)
+	require.Equal(t, "c", val)
+}
@@ -0,0 +1,104 @@
+package proxyd
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const defaultInflightQueueTimeout = time.Second
+
+// InflightLimiter bounds the requests in flight. Requests over the limit
+// wait in a bounded queue for up to the queue timeout, and are rejected with
+// ErrOverloaded when the queue is full or the timeout passes.
+type InflightLimiter struct {
+	scope        string
+	slots        chan struct{}
+	maxQueue     int64
+	queueTimeout time.Duration
+
+	queued atomic.Int64
+}
+
+// NewInflightLimiter returns nil, which doesn't limit, if cfg sets no limit.
+func NewInflightLimiter(scope string, cfg InflightConfig) *InflightLimiter {
+	if cfg.MaxRequests <= 0 {
+		return nil
+	}
+	l := &InflightLimiter{
+		scope:        scope,
+		slots:        make(chan struct{}, cfg.MaxRequests),
+		maxQueue:     int64(cfg.MaxQueue),
+		queueTimeout: time.Duration(cfg.QueueTimeout),
+	}
+	if l.queueTimeout == 0 {
+		l.queueTimeout = defaultInflightQueueTimeout
+	}
+	return l
+}
+
+// Acquire takes a slot, waiting in the queue if needed. The returned func
+// releases it.
+func (l *InflightLimiter) Acquire(ctx context.Context) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	start := time.Now()
+	select {
+	case l.slots <- struct{}{}:
+		addStageTime(ctx, requestStageQueue, start)
+		RecordInflightRequests(l.scope, len(l.slots))
+		return l.release, nil
+	default:
+	}
+
+	if queued := l.queued.Add(1); queued > l.maxQueue {
+		l.queued.Add(-1)
+		RecordInflightRejection(l.scope, "queue_full")
+		log.Debug("rejected request, in-flight queue full", "scope", l.scope, "req_id", GetReqID(ctx))
+		return nil, ErrOverloaded
+	}
+	RecordInflightQueueDepth(l.scope, int(l.queued.Load()))
+	defer func() {
+		RecordInflightQueueDepth(l.scope, int(l.queued.Add(-1)))
+	}()
+
+	timer := time.NewTimer(l.queueTimeout)
+	defer timer.Stop()
+	select {
+	case l.slots <- struct{}{}:
+		addStageTime(ctx, requestStageQueue, start)
+		RecordInflightQueueWait(l.scope, time.Since(start))
+		RecordInflightRequests(l.scope, len(l.slots))
+		return l.release, nil
+	case <-timer.C:
+		RecordInflightQueueWait(l.scope, time.Since(start))
+		RecordInflightRejection(l.scope, "timeout")
+		log.Debug("rejected request, timed out in in-flight queue", "scope", l.scope, "req_id", GetReqID(ctx))
+		return nil, ErrOverloaded
+	case <-ctx.Done():
+		return nil, ErrContextCanceled
+	}
+}
+
+func (l *InflightLimiter) release() {
+	<-l.slots
+	RecordInflightRequests(l.scope, len(l.slots))
+}
+
+// Status returns how many requests are in flight and queued, and false if
+// there's no limit.
+func (l *InflightLimiter) Status() (InflightStatus, bool) {
+	if l == nil {
+		return InflightStatus{}, false
+	}
+	inflight := len(l.slots)
+	return InflightStatus{
+		Requests:   inflight,
+		Max:        cap(l.slots),
+		Queued:     l.queued.Load(),
+		Saturation: float64(inflight) / float64(cap(l.slots)),
+	}, true
+}
@@ -0,0 +1,59 @@
+package proxyd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInflightLimiter(t *testing.T) {
+	require.Nil(t, NewInflightLimiter("test", InflightConfig{}))
+	var unlimited *InflightLimiter
+	release, err := unlimited.Acquire(context.Background())
+	require.NoError(t, err)
+	release()
+
+	l := NewInflightLimiter("test", InflightConfig{
+		MaxRequests:  1,
+		MaxQueue:     1,
+		QueueTimeout: TOMLDuration(50 * time.Millisecond),
+	})
+	release, err = l.Acquire(context.Background())
+	require.NoError(t, err)
+
+	// the next request waits for the slot
+	acquired := make(chan error)
+	go func() {
+		release, err := l.Acquire(context.Background())
+		if err == nil {
+			defer release()
+		}
+		acquired <- err
+	}()
+	require.Eventually(t, func() bool {
+		return l.queued.Load() == 1
+	}, time.Second, time.Millisecond)
+
+	// and fills the queue
+	_, err = l.Acquire(context.Background())
+	require.ErrorIs(t, err, ErrOverloaded)
+
+	release()
+	require.NoError(t, <-acquired)
+
+	// requests time out in the queue
+	release, err = l.Acquire(context.Background())
+	require.NoError(t, err)
+	defer release()
+	start := time.Now()
+	_, err = l.Acquire(context.Background())
+	require.ErrorIs(t, err, ErrOverloaded)
+	require.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = l.Acquire(ctx)
+	require.ErrorIs(t, err, ErrContextCanceled)
+}
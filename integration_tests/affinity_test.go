@@ -0,0 +1,78 @@
+package integration_tests
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"os"
+	"testing"
+
+	"github.com/ethereum-optimism/infra/proxyd"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackendAffinity(t *testing.T) {
+	firstBackend := NewMockBackend(SingleResponseHandler(503, "unavailable"))
+	defer firstBackend.Close()
+	secondBackend := NewMockBackend(SingleResponseHandler(200, dummyRes))
+	defer secondBackend.Close()
+
+	require.NoError(t, os.Setenv("FIRST_BACKEND_RPC_URL", firstBackend.URL()))
+	require.NoError(t, os.Setenv("SECOND_BACKEND_RPC_URL", secondBackend.URL()))
+
+	config := ReadConfig("affinity")
+	_, shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+
+	jar, err := cookiejar.New(nil)
+	require.NoError(t, err)
+	client := &http.Client{Jar: jar}
+	send := func(method string) (*http.Response, []byte) {
+		body := []byte(`{"jsonrpc": "2.0", "method": "` + method + `", "params": [], "id": 999}`)
+		res, err := client.Post("http://127.0.0.1:8545", "application/json", bytes.NewReader(body))
+		require.NoError(t, err)
+		defer res.Body.Close()
+		resBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		return res, resBody
+	}
+
+	// the first backend is down, so the client is pinned to the second
+	res, body := send("eth_getFilterChanges")
+	require.Equal(t, 200, res.StatusCode)
+	RequireEqualJSON(t, []byte(dummyRes), body)
+	require.Empty(t, res.Header.Get(proxyd.AffinityRepinnedHeader))
+	require.Len(t, res.Cookies(), 1)
+	require.Equal(t, 1, len(secondBackend.Requests()))
+
+	// and stays there after the first backend recovers
+	firstBackend.Reset()
+	firstBackend.SetHandler(SingleResponseHandler(200, dummyRes))
+	for i := 0; i < 3; i++ {
+		res, _ = send("eth_getFilterChanges")
+		require.Equal(t, 200, res.StatusCode)
+		require.Empty(t, res.Header.Get(proxyd.AffinityRepinnedHeader))
+	}
+	require.Equal(t, 0, len(firstBackend.Requests()))
+	require.Equal(t, 4, len(secondBackend.Requests()))
+
+	// other methods aren't pinned
+	send("eth_chainId")
+	require.Equal(t, 1, len(firstBackend.Requests()))
+
+	// the client moves to the first backend once the second goes down
+	secondBackend.SetHandler(SingleResponseHandler(503, "unavailable"))
+	res, body = send("eth_getFilterChanges")
+	require.Equal(t, 200, res.StatusCode)
+	RequireEqualJSON(t, []byte(dummyRes), body)
+	require.Equal(t, "first", res.Header.Get(proxyd.AffinityRepinnedHeader))
+	require.Equal(t, 2, len(firstBackend.Requests()))
+
+	// and stays there
+	secondBackend.SetHandler(SingleResponseHandler(200, dummyRes))
+	res, _ = send("eth_getFilterChanges")
+	require.Empty(t, res.Header.Get(proxyd.AffinityRepinnedHeader))
+	require.Equal(t, 3, len(firstBackend.Requests()))
+}
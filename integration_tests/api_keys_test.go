@@ -0,0 +1,72 @@
+package integration_tests
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis"
+	"github.com/ethereum-optimism/infra/proxyd"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeys(t *testing.T) {
+	redisServer, err := miniredis.Run()
+	require.NoError(t, err)
+	defer redisServer.Close()
+
+	backend := NewMockBackend(SingleResponseHandler(200, goodResponse))
+	defer backend.Close()
+
+	require.NoError(t, os.Setenv("GOOD_BACKEND_RPC_URL", backend.URL()))
+	require.NoError(t, os.Setenv("REDIS_URL", fmt.Sprintf("redis://127.0.0.1:%s", redisServer.Port())))
+
+	usageDir := t.TempDir()
+	config := ReadConfig("api_keys")
+	config.UsageExport = proxyd.UsageExportConfig{Enabled: true, URL: "file://" + usageDir, Instance: "proxyd-0"}
+	_, shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+	var shutdownOnce sync.Once
+	defer shutdownOnce.Do(shutdown)
+
+	// keys issued by another instance are stored in the shared redis
+	redisClient := redis.NewClient(&redis.Options{Addr: fmt.Sprintf("127.0.0.1:%s", redisServer.Port())})
+	keys, err := proxyd.NewKeyStore(config.APIKeys, redisClient, config.Redis.Namespace, nil, nil)
+	require.NoError(t, err)
+	secret, key, err := keys.Create(context.Background(), "acme", "", nil)
+	require.NoError(t, err)
+
+	sendChainID := func(path string) int {
+		_, code, err := NewProxydClient("http://127.0.0.1:8545"+path).SendRPC("eth_chainId", nil)
+		require.NoError(t, err)
+		return code
+	}
+
+	require.Equal(t, 200, sendChainID("/static-secret"))
+	require.Equal(t, 200, sendChainID("/"+secret))
+	require.Equal(t, 401, sendChainID("/"+secret+"0"))
+	require.Equal(t, 401, sendChainID(""))
+	require.Equal(t, 2, len(backend.Requests()))
+
+	revoked, err := keys.Revoke(context.Background(), key.ID)
+	require.NoError(t, err)
+	require.True(t, revoked)
+	time.Sleep(10 * time.Millisecond)
+	require.Equal(t, 401, sendChainID("/"+secret))
+
+	// usage is reported by key rather than by owner
+	shutdownOnce.Do(shutdown)
+	reports, err := filepath.Glob(filepath.Join(usageDir, "*", "*", "*", "*", "proxyd-0-*.csv"))
+	require.NoError(t, err)
+	require.Len(t, reports, 1)
+	report, err := os.ReadFile(reports[0])
+	require.NoError(t, err)
+	require.Contains(t, string(report), ",proxyd-0,"+key.ID+",eth_chainId,1,0,1\n")
+	require.Contains(t, string(report), ",proxyd-0,static,eth_chainId,1,0,1\n")
+	require.NotContains(t, string(report), "acme")
+}
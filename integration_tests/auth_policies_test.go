@@ -0,0 +1,80 @@
+package integration_tests
+
+import (
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/ethereum-optimism/infra/proxyd"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthPolicies(t *testing.T) {
+	mainBackend := NewMockBackend(SingleResponseHandler(200, goodResponse))
+	defer mainBackend.Close()
+	partnerBackend := NewMockBackend(SingleResponseHandler(200, goodResponse))
+	defer partnerBackend.Close()
+
+	require.NoError(t, os.Setenv("MAIN_BACKEND_RPC_URL", mainBackend.URL()))
+	require.NoError(t, os.Setenv("PARTNER_BACKEND_RPC_URL", partnerBackend.URL()))
+
+	config := ReadConfig("auth_policies")
+	_, shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+
+	t.Run("requests without a secret are unauthorized", func(t *testing.T) {
+		_, code, err := NewProxydClient("http://127.0.0.1:8545").SendRPC("eth_chainId", nil)
+		require.NoError(t, err)
+		require.Equal(t, 401, code)
+	})
+
+	t.Run("aliases without a policy use the method mappings", func(t *testing.T) {
+		mainBackend.Reset()
+		partnerBackend.Reset()
+		client := NewProxydClient("http://127.0.0.1:8545/basic-secret")
+		_, code, err := client.SendRPC("eth_blockNumber", nil)
+		require.NoError(t, err)
+		require.Equal(t, 200, code)
+		require.Equal(t, 1, len(mainBackend.Requests()))
+		require.Equal(t, 0, len(partnerBackend.Requests()))
+	})
+
+	t.Run("policy routes allowed methods and sets headers", func(t *testing.T) {
+		mainBackend.Reset()
+		partnerBackend.Reset()
+		client := NewProxydClient("http://127.0.0.1:8545/partner-secret")
+		_, code, err := client.SendRPC("eth_chainId", nil)
+		require.NoError(t, err)
+		require.Equal(t, 200, code)
+		// allowed without a method mapping
+		_, code, err = client.SendRPC("eth_getBalance", nil)
+		require.NoError(t, err)
+		require.Equal(t, 200, code)
+		require.Equal(t, 0, len(mainBackend.Requests()))
+		require.Equal(t, 2, len(partnerBackend.Requests()))
+		require.Equal(t, "acme", partnerBackend.Requests()[0].Headers.Get("X-Partner"))
+
+		_, code, err = client.SendRPC("eth_blockNumber", nil)
+		require.NoError(t, err)
+		require.Equal(t, 403, code)
+		require.Equal(t, 2, len(partnerBackend.Requests()))
+	})
+
+	t.Run("rate limit tier applies per alias", func(t *testing.T) {
+		// the alias's clients share its budget, which doesn't count the
+		// requests of other aliases in the tier
+		clients := []*ProxydHTTPClient{
+			NewProxydClientWithHeaders("http://127.0.0.1:8545/metered-secret", http.Header{"X-Forwarded-For": []string{"10.0.0.1"}}),
+			NewProxydClientWithHeaders("http://127.0.0.1:8545/metered-secret", http.Header{"X-Forwarded-For": []string{"10.0.0.2"}}),
+		}
+		codes := make(map[int]int)
+		for i := 0; i < 4; i++ {
+			_, code, err := clients[i%2].SendRPC("eth_chainId", nil)
+			require.NoError(t, err)
+			codes[code]++
+		}
+		require.Equal(t, 2, codes[200])
+		require.Equal(t, 2, codes[429])
+	})
+}
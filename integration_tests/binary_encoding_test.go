@@ -0,0 +1,85 @@
+package integration_tests
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ethereum-optimism/infra/proxyd"
+	"github.com/fxamacker/cbor/v2"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func TestBinaryEncoding(t *testing.T) {
+	goodBackend := NewMockBackend(BatchedResponseHandler(200, goodResponse))
+	defer goodBackend.Close()
+	wsBackend := NewMockWSBackend(nil, func(conn *websocket.Conn, msgType int, data []byte) {
+		_ = conn.WriteMessage(websocket.TextMessage, []byte(`{"jsonrpc":"2.0","id":1,"result":"0x1"}`))
+	}, nil)
+	defer wsBackend.Close()
+
+	require.NoError(t, os.Setenv("GOOD_BACKEND_RPC_URL", goodBackend.URL()))
+	require.NoError(t, os.Setenv("GOOD_BACKEND_WS_URL", wsBackend.URL()))
+
+	config := ReadConfig("binary_encoding")
+	_, shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+
+	req := map[string]interface{}{"jsonrpc": "2.0", "id": 1, "method": "eth_chainId"}
+
+	t.Run("msgpack over HTTP", func(t *testing.T) {
+		body, err := msgpack.Marshal(req)
+		require.NoError(t, err)
+		res, err := http.Post("http://127.0.0.1:8545/msgpack", "application/msgpack", bytes.NewReader(body))
+		require.NoError(t, err)
+		defer res.Body.Close()
+		require.Equal(t, 200, res.StatusCode)
+		require.Equal(t, "application/msgpack", res.Header.Get("Content-Type"))
+
+		resBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		var rpcRes map[string]interface{}
+		require.NoError(t, msgpack.Unmarshal(resBody, &rpcRes))
+		require.Equal(t, "hello", rpcRes["result"])
+
+		// backends get JSON
+		reqs := goodBackend.Requests()
+		require.JSONEq(t, `{"jsonrpc":"2.0","id":1,"method":"eth_chainId","params":null}`, string(reqs[len(reqs)-1].Body))
+	})
+
+	t.Run("invalid msgpack", func(t *testing.T) {
+		res, err := http.Post("http://127.0.0.1:8545/msgpack", "application/msgpack", bytes.NewReader([]byte{0xc1}))
+		require.NoError(t, err)
+		defer res.Body.Close()
+		require.Equal(t, 400, res.StatusCode)
+	})
+
+	t.Run("cbor over websockets", func(t *testing.T) {
+		conn, _, err := websocket.DefaultDialer.Dial("ws://127.0.0.1:8546/cbor", nil) // nolint:bodyclose
+		require.NoError(t, err)
+		defer conn.Close()
+
+		body, err := cbor.Marshal(map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      1,
+			"method":  "eth_subscribe",
+			"params":  []string{"newHeads"},
+		})
+		require.NoError(t, err)
+		require.NoError(t, conn.WriteMessage(websocket.BinaryMessage, body))
+
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+		msgType, msg, err := conn.ReadMessage()
+		require.NoError(t, err)
+		require.Equal(t, websocket.BinaryMessage, msgType)
+		var rpcRes map[string]interface{}
+		require.NoError(t, cbor.Unmarshal(msg, &rpcRes))
+		require.Equal(t, "0x1", rpcRes["result"])
+	})
+}
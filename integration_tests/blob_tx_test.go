@@ -0,0 +1,75 @@
+package integration_tests
+
+import (
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/ethereum-optimism/infra/proxyd"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlobTxPolicy(t *testing.T) {
+	goodBackend := NewMockBackend(SingleResponseHandler(200, dummyRes))
+	defer goodBackend.Close()
+	blobBackend := NewMockBackend(SingleResponseHandler(200, dummyRes))
+	defer blobBackend.Close()
+
+	require.NoError(t, os.Setenv("GOOD_BACKEND_RPC_URL", goodBackend.URL()))
+	require.NoError(t, os.Setenv("BLOB_BACKEND_RPC_URL", blobBackend.URL()))
+
+	config := ReadConfig("blob_tx")
+	client := NewProxydClient("http://127.0.0.1:8545")
+	_, shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	signer := types.LatestSignerForChainID(big.NewInt(420))
+	blobTx := func(blobs int, blobFeeCap uint64) string {
+		tx, err := types.SignNewTx(key, signer, &types.BlobTx{
+			ChainID:    uint256.NewInt(420),
+			GasTipCap:  uint256.NewInt(1),
+			GasFeeCap:  uint256.NewInt(1),
+			Gas:        21000,
+			BlobFeeCap: uint256.NewInt(blobFeeCap),
+			BlobHashes: make([]common.Hash, blobs),
+		})
+		require.NoError(t, err)
+		raw, err := tx.MarshalBinary()
+		require.NoError(t, err)
+		return hexutil.Encode(raw)
+	}
+
+	// blob transactions go to the blob backend group
+	res, code, err := client.SendRequest(makeSendRawTransaction(blobTx(2, 1000000000)))
+	require.NoError(t, err)
+	require.Equal(t, 200, code)
+	RequireEqualJSON(t, []byte(dummyRes), res)
+	require.Equal(t, 1, len(blobBackend.Requests()))
+	require.Equal(t, 0, len(goodBackend.Requests()))
+
+	// other transactions keep their method mapping
+	res, code, err = client.SendRequest(makeSendRawTransaction(txHex1))
+	require.NoError(t, err)
+	require.Equal(t, 200, code)
+	RequireEqualJSON(t, []byte(dummyRes), res)
+	require.Equal(t, 1, len(goodBackend.Requests()))
+
+	res, code, err = client.SendRequest(makeSendRawTransaction(blobTx(3, 1)))
+	require.NoError(t, err)
+	require.Equal(t, 400, code)
+	RequireEqualJSON(t, []byte(`{"error":{"code":-32029,"message":"too many blobs in transaction"},"id":1,"jsonrpc":"2.0"}`), res)
+
+	res, code, err = client.SendRequest(makeSendRawTransaction(blobTx(1, 1000000001)))
+	require.NoError(t, err)
+	require.Equal(t, 400, code)
+	RequireEqualJSON(t, []byte(`{"error":{"code":-32030,"message":"blob fee cap too high"},"id":1,"jsonrpc":"2.0"}`), res)
+	require.Equal(t, 1, len(blobBackend.Requests()))
+}
@@ -0,0 +1,82 @@
+package integration_tests
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/ethereum-optimism/infra/proxyd"
+	"github.com/ethereum-optimism/infra/proxyd/pkg/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlockReceipts(t *testing.T) {
+	receipt := func(tx, block string) map[string]any {
+		return map[string]any{"transactionHash": tx, "blockHash": block}
+	}
+	scenario, err := mock.NewScenario(
+		mock.Rule{Method: "eth_getBlockByNumber", Params: []any{"0x1", false}, Response: mock.Response{
+			Result: map[string]any{"hash": "0xb1", "number": "0x1", "transactions": []any{"0x01", "0x02", "0x03"}},
+		}},
+		mock.Rule{Method: "eth_getBlockByNumber", Params: []any{"0x2", false}, Response: mock.Response{
+			Result: map[string]any{"hash": "0xb2", "number": "0x2", "transactions": []any{"0x04", "0x05"}},
+		}},
+		mock.Rule{Method: "eth_getBlockReceipts", Params: []any{"0x1"}, Response: mock.Response{
+			Result: []any{receipt("0x01", "0xb1"), receipt("0x02", "0xb1"), receipt("0x03", "0xb1")},
+		}},
+		mock.Rule{Method: "eth_getTransactionReceipt", Response: mock.Response{Result: receipt("0x09", "0xb9")}},
+	)
+	require.NoError(t, err)
+	goodBackend := NewMockBackend(scenario)
+	defer goodBackend.Close()
+
+	require.NoError(t, os.Setenv("GOOD_BACKEND_RPC_URL", goodBackend.URL()))
+
+	config := ReadConfig("block_receipts")
+	client := NewProxydClient("http://127.0.0.1:8545")
+	_, shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+
+	receipts := func(txs ...string) []proxyd.RPCRes {
+		t.Helper()
+		reqs := make([]*proxyd.RPCReq, len(txs))
+		for i, tx := range txs {
+			reqs[i] = NewRPCReq(string(rune('1'+i)), "eth_getTransactionReceipt", []interface{}{tx})
+		}
+		res, code, err := client.SendBatchRPC(reqs...)
+		require.NoError(t, err)
+		require.Equal(t, 200, code)
+		var rpcRes []proxyd.RPCRes
+		require.NoError(t, json.Unmarshal(res, &rpcRes))
+		require.Len(t, rpcRes, len(txs))
+		return rpcRes
+	}
+
+	for _, block := range []string{"0x1", "0x2"} {
+		_, code, err := client.SendRPC("eth_getBlockByNumber", []interface{}{block, false})
+		require.NoError(t, err)
+		require.Equal(t, 200, code)
+	}
+
+	// receipts of the same block are served with eth_getBlockReceipts
+	res := receipts("0x02", "0x09", "0x01")
+	require.Equal(t, 1, scenario.Calls("eth_getBlockReceipts"))
+	require.Equal(t, 1, scenario.Calls("eth_getTransactionReceipt"))
+	require.Equal(t, json.RawMessage("1"), res[0].ID)
+	require.Equal(t, "0x02", res[0].Result.(map[string]interface{})["transactionHash"])
+	require.Equal(t, json.RawMessage("2"), res[1].ID)
+	require.Equal(t, "0x09", res[1].Result.(map[string]interface{})["transactionHash"])
+	require.Equal(t, json.RawMessage("3"), res[2].ID)
+	require.Equal(t, "0x01", res[2].Result.(map[string]interface{})["transactionHash"])
+
+	// not for single receipts
+	receipts("0x03")
+	require.Equal(t, 1, scenario.Calls("eth_getBlockReceipts"))
+	require.Equal(t, 2, scenario.Calls("eth_getTransactionReceipt"))
+
+	// and they're requested one by one if eth_getBlockReceipts fails
+	receipts("0x04", "0x05")
+	require.Equal(t, 2, scenario.Calls("eth_getBlockReceipts"))
+	require.Equal(t, 4, scenario.Calls("eth_getTransactionReceipt"))
+}
@@ -0,0 +1,63 @@
+package integration_tests
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ethereum-optimism/infra/proxyd"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheControlGET(t *testing.T) {
+	goodBackend := NewMockBackend(SingleResponseHandler(200, `{"jsonrpc":"2.0","result":"0xa","id":1}`))
+	defer goodBackend.Close()
+
+	require.NoError(t, os.Setenv("GOOD_BACKEND_RPC_URL", goodBackend.URL()))
+
+	config := ReadConfig("cache_control")
+	_, shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+
+	get := func(query, ifNoneMatch string) (*http.Response, []byte) {
+		req, err := http.NewRequest("GET", "http://127.0.0.1:8545/?"+query, nil)
+		require.NoError(t, err)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		return res, body
+	}
+
+	res, body := get("method=eth_chainId", "")
+	require.Equal(t, 200, res.StatusCode)
+	require.Equal(t, "public, max-age=3600, immutable", res.Header.Get("Cache-Control"))
+	RequireEqualJSON(t, []byte(`{"jsonrpc":"2.0","result":"0xa","id":1}`), body)
+	etag := res.Header.Get("ETag")
+	require.NotEmpty(t, etag)
+
+	res, body = get("method=eth_chainId", etag)
+	require.Equal(t, 304, res.StatusCode)
+	require.Empty(t, body)
+
+	// mutable responses aren't stored
+	res, _ = get("method=eth_blockNumber&params=%5B%5D", "")
+	require.Equal(t, 200, res.StatusCode)
+	require.Equal(t, "no-store", res.Header.Get("Cache-Control"))
+
+	res, _ = get("params=%5B%5D", "")
+	require.Equal(t, 400, res.StatusCode)
+
+	// POST responses aren't public
+	postRes, err := http.Post("http://127.0.0.1:8545", "application/json", strings.NewReader(`{"jsonrpc":"2.0","method":"eth_chainId","params":[],"id":1}`))
+	require.NoError(t, err)
+	postRes.Body.Close()
+	require.Equal(t, "max-age=3600, immutable", postRes.Header.Get("Cache-Control"))
+}
@@ -0,0 +1,46 @@
+package integration_tests
+
+import (
+	"os"
+	"testing"
+
+	"github.com/ethereum-optimism/infra/proxyd"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCassette(t *testing.T) {
+	nodeBackend := NewMockBackend(SingleResponseHandler(200, goodResponse))
+	defer nodeBackend.Close()
+
+	require.NoError(t, os.Setenv("NODE_BACKEND_RPC_URL", nodeBackend.URL()))
+
+	config := ReadConfig("cassette")
+	config.Cassette.Dir = t.TempDir()
+	_, shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+
+	client := NewProxydClient("http://127.0.0.1:8545")
+	res, code, err := client.SendRPC("eth_chainId", nil)
+	require.NoError(t, err)
+	require.Equal(t, 200, code)
+	RequireEqualJSON(t, []byte(goodResponse), res)
+	require.Equal(t, 1, len(nodeBackend.Requests()))
+	shutdown()
+
+	// the backend is gone, but its recorded response is replayed
+	nodeBackend.Close()
+	config.Cassette.Mode = proxyd.CassetteModeReplay
+	_, shutdown, err = proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+
+	res, code, err = client.SendRPC("eth_chainId", nil)
+	require.NoError(t, err)
+	require.Equal(t, 200, code)
+	RequireEqualJSON(t, []byte(goodResponse), res)
+
+	// requests that weren't recorded fail
+	_, code, err = client.SendRPC("eth_blockNumber", nil)
+	require.NoError(t, err)
+	require.Equal(t, 503, code)
+}
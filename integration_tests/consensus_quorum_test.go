@@ -0,0 +1,101 @@
+package integration_tests
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/ethereum-optimism/infra/proxyd"
+	ms "github.com/ethereum-optimism/infra/proxyd/tools/mockserver/handler"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConsensusQuorum(t *testing.T) {
+	dir, err := os.Getwd()
+	require.NoError(t, err)
+	responses := path.Join(dir, "testdata/consensus_responses.yml")
+
+	handlers := make(map[string]*ms.MockedHandler)
+	for _, name := range []string{"node1", "node2", "node3"} {
+		h := &ms.MockedHandler{
+			Overrides:    []*ms.MethodTemplate{},
+			Autoload:     true,
+			AutoloadFile: responses,
+		}
+		node := NewMockBackend(http.HandlerFunc(h.Handler))
+		defer node.Close()
+		require.NoError(t, os.Setenv(strings.ToUpper(name)+"_URL", node.URL()))
+		handlers[name] = h
+	}
+
+	config := ReadConfig("consensus_quorum")
+	svr, shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+
+	bg := svr.BackendGroups["node"]
+	require.NotNil(t, bg.Consensus)
+	backends := make(map[string]*proxyd.Backend)
+	for _, be := range bg.Backends {
+		backends[be.Name] = be
+	}
+
+	ctx := context.Background()
+	update := func() {
+		for _, be := range bg.Backends {
+			bg.Consensus.UpdateBackend(ctx, be)
+		}
+		bg.Consensus.UpdateBackendGroupConsensus(ctx)
+	}
+	reset := func() {
+		for _, h := range handlers {
+			h.ResetOverrides()
+		}
+		bg.Consensus.Reset()
+	}
+	overrideBlock := func(node, blockRequest, number, hash string) {
+		handlers[node].AddOverride(&ms.MethodTemplate{
+			Method: "eth_getBlockByNumber",
+			Block:  blockRequest,
+			Response: buildResponse(map[string]string{
+				"number": number,
+				"hash":   hash,
+			}),
+		})
+	}
+
+	t.Run("one backend ahead doesn't move latest", func(t *testing.T) {
+		reset()
+		overrideBlock("node1", "latest", "0x102", "hash_0x102")
+		update()
+
+		require.Equal(t, "0x101", bg.Consensus.GetLatestBlockNumber().String())
+		require.Len(t, bg.Consensus.GetConsensusGroup(), 3)
+	})
+
+	t.Run("latest follows a quorum of backends", func(t *testing.T) {
+		reset()
+		overrideBlock("node1", "latest", "0x102", "hash_0x102")
+		overrideBlock("node2", "latest", "0x102", "hash_0x102")
+		update()
+
+		require.Equal(t, "0x102", bg.Consensus.GetLatestBlockNumber().String())
+		group := bg.Consensus.GetConsensusGroup()
+		require.Len(t, group, 2)
+		require.NotContains(t, group, backends["node3"])
+	})
+
+	t.Run("backends must agree on the block hash", func(t *testing.T) {
+		reset()
+		overrideBlock("node1", "latest", "0x102", "hash_0x102")
+		overrideBlock("node2", "latest", "0x102", "other_0x102")
+		overrideBlock("node2", "0x102", "0x102", "other_0x102")
+		update()
+
+		require.Equal(t, "0x101", bg.Consensus.GetLatestBlockNumber().String())
+		require.Len(t, bg.Consensus.GetConsensusGroup(), 3)
+	})
+}
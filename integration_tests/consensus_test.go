@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math/big"
 	"net/http"
 	"os"
 	"path"
@@ -181,6 +182,7 @@ func TestConsensus(t *testing.T) {
 		require.Equal(t, "0x101", bg.Consensus.GetLatestBlockNumber().String())
 		require.Equal(t, "0xe1", bg.Consensus.GetSafeBlockNumber().String())
 		require.Equal(t, "0xc1", bg.Consensus.GetFinalizedBlockNumber().String())
+		require.Equal(t, big.NewInt(1000000000), bg.Consensus.GetBaseFee())
 	})
 
 	t.Run("prevent using a backend with low peer count", func(t *testing.T) {
@@ -294,8 +296,8 @@ func TestConsensus(t *testing.T) {
 		useOnlyNode1()
 
 		// replace node1 handler with one that always returns 500
-		oldHandler := nodes["node1"].mockBackend.handler
-		defer func() { nodes["node1"].mockBackend.handler = oldHandler }()
+		oldHandler := nodes["node1"].mockBackend.Handler()
+		defer nodes["node1"].mockBackend.SetHandler(oldHandler)
 
 		nodes["node1"].mockBackend.SetHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(503)
@@ -645,8 +647,8 @@ func TestConsensus(t *testing.T) {
 		useOnlyNode1()
 
 		// replace node1 handler with one that adds a 500ms delay
-		oldHandler := nodes["node1"].mockBackend.handler
-		defer func() { nodes["node1"].mockBackend.handler = oldHandler }()
+		oldHandler := nodes["node1"].mockBackend.Handler()
+		defer nodes["node1"].mockBackend.SetHandler(oldHandler)
 
 		nodes["node1"].mockBackend.SetHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			time.Sleep(500 * time.Millisecond)
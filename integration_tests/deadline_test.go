@@ -0,0 +1,64 @@
+package integration_tests
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ethereum-optimism/infra/proxyd"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeadlinePropagation(t *testing.T) {
+	nodeBackend := NewMockBackend(SingleResponseHandler(200, goodResponse))
+	defer nodeBackend.Close()
+
+	require.NoError(t, os.Setenv("NODE_BACKEND_RPC_URL", nodeBackend.URL()))
+
+	config := ReadConfig("deadline")
+	_, shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+
+	requestTimeout := func() int {
+		reqs := nodeBackend.Requests()
+		ms, err := strconv.Atoi(reqs[len(reqs)-1].Headers.Get(proxyd.RequestTimeoutHeader))
+		require.NoError(t, err)
+		return ms
+	}
+
+	t.Run("forwards the time left of the server timeout", func(t *testing.T) {
+		client := NewProxydClient("http://127.0.0.1:8545")
+		_, code, err := client.SendRPC("eth_chainId", nil)
+		require.NoError(t, err)
+		require.Equal(t, 200, code)
+		ms := requestTimeout()
+		require.Greater(t, ms, 1000)
+		require.LessOrEqual(t, ms, 2000)
+	})
+
+	t.Run("client timeout shortens the deadline", func(t *testing.T) {
+		client := NewProxydClientWithHeaders("http://127.0.0.1:8545", http.Header{
+			proxyd.RequestTimeoutHeader: []string{"300"},
+		})
+		_, code, err := client.SendRPC("eth_chainId", nil)
+		require.NoError(t, err)
+		require.Equal(t, 200, code)
+		require.LessOrEqual(t, requestTimeout(), 300)
+
+		nodeBackend.SetHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(2 * time.Second):
+			}
+			SingleResponseHandler(200, goodResponse)(w, r)
+		}))
+		start := time.Now()
+		_, code, err = client.SendRPC("eth_chainId", nil)
+		require.NoError(t, err)
+		require.NotEqual(t, 200, code)
+		require.Less(t, time.Since(start), time.Second)
+	})
+}
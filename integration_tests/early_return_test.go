@@ -0,0 +1,150 @@
+package integration_tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ethereum-optimism/infra/proxyd"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEarlyReturn(t *testing.T) {
+	release := make(chan struct{})
+	goodBackend := NewMockBackend(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		SingleResponseHandler(200, dummyRes)(w, r)
+	}))
+	defer goodBackend.Close()
+
+	require.NoError(t, os.Setenv("GOOD_BACKEND_RPC_URL", goodBackend.URL()))
+
+	config := ReadConfig("early_return")
+	_, shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+
+	tx := new(types.Transaction)
+	require.NoError(t, tx.UnmarshalBinary(hexutil.MustDecode(txHex1)))
+
+	status := func(client *ProxydHTTPClient) map[string]interface{} {
+		t.Helper()
+		res, code, err := client.SendRPC(proxyd.EarlyReturnStatusMethod, []interface{}{tx.Hash().Hex()})
+		require.NoError(t, err)
+		require.Equal(t, 200, code)
+		var rpcRes struct {
+			Result map[string]interface{} `json:"result"`
+		}
+		require.NoError(t, json.Unmarshal(res, &rpcRes))
+		return rpcRes.Result
+	}
+
+	// trusted callers get the hash before the backend answers
+	internal := NewProxydClient("http://127.0.0.1:8545/internal-secret")
+	res, code, err := internal.SendRequest(makeSendRawTransaction(txHex1))
+	require.NoError(t, err)
+	require.Equal(t, 200, code)
+	var rpcRes proxyd.RPCRes
+	require.NoError(t, json.Unmarshal(res, &rpcRes))
+	require.Equal(t, tx.Hash().Hex(), rpcRes.Result)
+	require.Equal(t, "pending", status(internal)["status"])
+
+	close(release)
+	require.Eventually(t, func() bool {
+		return status(internal)["status"] == "accepted"
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, "good", status(internal)["backend"])
+	require.Equal(t, 1, len(goodBackend.Requests()))
+
+	// others wait for the backend
+	public := NewProxydClient("http://127.0.0.1:8545/public-secret")
+	res, code, err = public.SendRequest(makeSendRawTransaction(txHex1))
+	require.NoError(t, err)
+	require.Equal(t, 200, code)
+	require.NoError(t, json.Unmarshal(res, &rpcRes))
+	require.Equal(t, "dummy", rpcRes.Result)
+	require.Equal(t, 2, len(goodBackend.Requests()))
+
+	// and can't check statuses
+	res, _, err = public.SendRPC(proxyd.EarlyReturnStatusMethod, []interface{}{tx.Hash().Hex()})
+	require.NoError(t, err)
+	RequireEqualJSON(t, []byte(`{"jsonrpc":"2.0","error":{"code":-32601,"message":"rpc method is not whitelisted"},"id":999}`), res)
+	require.Equal(t, 2, len(goodBackend.Requests()))
+}
+
+func TestEarlyReturnShutdownWaitsForForwards(t *testing.T) {
+	release := make(chan struct{})
+	goodBackend := NewMockBackend(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		SingleResponseHandler(200, dummyRes)(w, r)
+	}))
+	defer goodBackend.Close()
+
+	require.NoError(t, os.Setenv("GOOD_BACKEND_RPC_URL", goodBackend.URL()))
+
+	config := ReadConfig("early_return")
+	_, shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+
+	internal := NewProxydClient("http://127.0.0.1:8545/internal-secret")
+	_, code, err := internal.SendRequest(makeSendRawTransaction(txHex1))
+	require.NoError(t, err)
+	require.Equal(t, 200, code)
+
+	done := make(chan struct{})
+	go func() {
+		shutdown()
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatal("shutdown returned before the transaction was forwarded")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("shutdown didn't return")
+	}
+	require.Equal(t, 1, len(goodBackend.Requests()))
+}
+
+func TestEarlyReturnObservesForwardOutcome(t *testing.T) {
+	release := make(chan struct{})
+	goodBackend := NewMockBackend(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		SingleResponseHandler(200, nonceErrorResponse)(w, r)
+	}))
+	defer goodBackend.Close()
+
+	require.NoError(t, os.Setenv("GOOD_BACKEND_RPC_URL", goodBackend.URL()))
+
+	config := ReadConfig("early_return")
+	config.TxDedup.Enabled = true
+	_, shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+
+	internal := NewProxydClient("http://127.0.0.1:8545/internal-secret")
+	_, code, err := internal.SendRequest(makeSendRawTransaction(txHex1))
+	require.NoError(t, err)
+	require.Equal(t, 200, code)
+	close(release)
+	require.Eventually(t, func() bool {
+		return len(goodBackend.Requests()) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// the backend rejected the transaction, so a retry isn't answered with
+	// the locally computed hash
+	public := NewProxydClient("http://127.0.0.1:8545/public-secret")
+	res, _, err := public.SendRequest(makeSendRawTransaction(txHex1))
+	require.NoError(t, err)
+	RequireEqualJSON(t, []byte(nonceErrorResponse), res)
+	require.Equal(t, 2, len(goodBackend.Requests()))
+}
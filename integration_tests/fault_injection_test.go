@@ -0,0 +1,46 @@
+package integration_tests
+
+import (
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ethereum-optimism/infra/proxyd"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFaultInjection(t *testing.T) {
+	nodeBackend := NewMockBackend(SingleResponseHandler(200, goodResponse))
+	defer nodeBackend.Close()
+
+	require.NoError(t, os.Setenv("NODE_BACKEND_RPC_URL", nodeBackend.URL()))
+
+	config := ReadConfig("fault_injection")
+	_, shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+
+	client := NewProxydClient("http://127.0.0.1:8545")
+
+	// every request fails before reaching the backend
+	res, code, err := client.SendRPC("eth_chainId", nil)
+	require.NoError(t, err)
+	require.Equal(t, 503, code)
+	RequireEqualJSON(t, []byte(`{"jsonrpc":"2.0","error":{"code":-32011,"message":"no backend is currently healthy to serve traffic"},"id":999}`), res)
+	require.Equal(t, 0, len(nodeBackend.Requests()))
+
+	req, err := http.NewRequest(http.MethodDelete, "http://127.0.0.1:9765/admin/faults/node", strings.NewReader(""))
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer admin")
+	adminRes, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	adminRes.Body.Close()
+	require.Equal(t, http.StatusNoContent, adminRes.StatusCode)
+
+	res, code, err = client.SendRPC("eth_chainId", nil)
+	require.NoError(t, err)
+	require.Equal(t, 200, code)
+	RequireEqualJSON(t, []byte(goodResponse), res)
+	require.Equal(t, 1, len(nodeBackend.Requests()))
+}
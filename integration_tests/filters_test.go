@@ -0,0 +1,137 @@
+package integration_tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ethereum-optimism/infra/proxyd"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/stretchr/testify/require"
+)
+
+// chainHandler answers like a node whose head is at *head, with one log per
+// block.
+func chainHandler(t *testing.T, head *atomic.Uint64) http.HandlerFunc {
+	answer := func(req *proxyd.RPCReq) *proxyd.RPCRes {
+		switch req.Method {
+		case "eth_blockNumber":
+			return proxyd.NewRPCRes(req.ID, hexutil.EncodeUint64(head.Load()))
+		case "eth_getBlockByNumber":
+			var params []interface{}
+			require.NoError(t, json.Unmarshal(req.Params, &params))
+			return proxyd.NewRPCRes(req.ID, map[string]string{"hash": "hash_" + params[0].(string)})
+		case "eth_getLogs":
+			var params []map[string]string
+			require.NoError(t, json.Unmarshal(req.Params, &params))
+			from, to := params[0]["fromBlock"], params[0]["toBlock"]
+			if from == "" {
+				from = "0x1"
+			}
+			if to == "" || to == "latest" {
+				to = hexutil.EncodeUint64(head.Load())
+			}
+			logs := make([]map[string]string, 0)
+			for n := hexutil.MustDecodeUint64(from); n <= hexutil.MustDecodeUint64(to); n++ {
+				logs = append(logs, map[string]string{"blockNumber": hexutil.EncodeUint64(n)})
+			}
+			return proxyd.NewRPCRes(req.ID, logs)
+		default:
+			return proxyd.NewRPCErrorRes(req.ID, proxyd.ErrMethodNotWhitelisted)
+		}
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		var out interface{}
+		if proxyd.IsBatch(body) {
+			var reqs []*proxyd.RPCReq
+			require.NoError(t, json.Unmarshal(body, &reqs))
+			var res []*proxyd.RPCRes
+			for _, req := range reqs {
+				res = append(res, answer(req))
+			}
+			out = res
+		} else {
+			var req proxyd.RPCReq
+			require.NoError(t, json.Unmarshal(body, &req))
+			out = answer(&req)
+		}
+		require.NoError(t, json.NewEncoder(w).Encode(out))
+	}
+}
+
+func TestFilters(t *testing.T) {
+	var head atomic.Uint64
+	head.Store(10)
+	firstBackend := NewMockBackend(chainHandler(t, &head))
+	defer firstBackend.Close()
+	secondBackend := NewMockBackend(chainHandler(t, &head))
+	defer secondBackend.Close()
+
+	require.NoError(t, os.Setenv("FIRST_BACKEND_RPC_URL", firstBackend.URL()))
+	require.NoError(t, os.Setenv("SECOND_BACKEND_RPC_URL", secondBackend.URL()))
+
+	config := ReadConfig("filters")
+	client := NewProxydClient("http://127.0.0.1:8545")
+	_, shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+
+	call := func(method string, params ...interface{}) (interface{}, *proxyd.RPCErr) {
+		if params == nil {
+			params = []interface{}{}
+		}
+		body, _, err := client.SendRPC(method, params)
+		require.NoError(t, err)
+		var res struct {
+			Result interface{}
+			Error  *proxyd.RPCErr
+		}
+		require.NoError(t, json.Unmarshal(body, &res))
+		return res.Result, res.Error
+	}
+
+	logFilter, rpcErr := call("eth_newFilter", map[string]interface{}{"address": "0x0000000000000000000000000000000000000001"})
+	require.Nil(t, rpcErr)
+	blockFilter, rpcErr := call("eth_newBlockFilter")
+	require.Nil(t, rpcErr)
+
+	changes, rpcErr := call("eth_getFilterChanges", logFilter)
+	require.Nil(t, rpcErr)
+	require.Empty(t, changes)
+
+	head.Store(12)
+	changes, rpcErr = call("eth_getFilterChanges", logFilter)
+	require.Nil(t, rpcErr)
+	require.Equal(t, []interface{}{
+		map[string]interface{}{"blockNumber": "0xb"},
+		map[string]interface{}{"blockNumber": "0xc"},
+	}, changes)
+	changes, rpcErr = call("eth_getFilterChanges", blockFilter)
+	require.Nil(t, rpcErr)
+	require.Equal(t, []interface{}{"hash_0xb", "hash_0xc"}, changes)
+
+	// filters outlive the backend they were installed through
+	firstBackend.Close()
+	head.Store(13)
+	changes, rpcErr = call("eth_getFilterChanges", blockFilter)
+	require.Nil(t, rpcErr)
+	require.Equal(t, []interface{}{"hash_0xd"}, changes)
+
+	logs, rpcErr := call("eth_getFilterLogs", logFilter)
+	require.Nil(t, rpcErr)
+	require.Len(t, logs, 13)
+
+	uninstalled, rpcErr := call("eth_uninstallFilter", logFilter)
+	require.Nil(t, rpcErr)
+	require.Equal(t, true, uninstalled)
+	_, rpcErr = call("eth_getFilterChanges", logFilter)
+	require.Equal(t, "filter not found", rpcErr.Message)
+	uninstalled, _ = call("eth_uninstallFilter", fmt.Sprint(logFilter))
+	require.Equal(t, false, uninstalled)
+}
@@ -0,0 +1,56 @@
+package integration_tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/ethereum-optimism/infra/proxyd"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlashbotsSignatureVerification(t *testing.T) {
+	backend := NewMockBackend(SingleResponseHandler(200, goodResponse))
+	defer backend.Close()
+	require.NoError(t, os.Setenv("GOOD_BACKEND_RPC_URL", backend.URL()))
+
+	privKey, err := crypto.HexToECDSA(testFlashbotsPrivKeyHex)
+	require.NoError(t, err)
+	addr := crypto.PubkeyToAddress(privKey.PublicKey)
+
+	body, err := json.Marshal(NewRPCReq("1", "eth_chainId", nil))
+	require.NoError(t, err)
+	sig, err := crypto.Sign(accounts.TextHash([]byte(crypto.Keccak256Hash(body).Hex())), privKey)
+	require.NoError(t, err)
+	validHeader := fmt.Sprintf("%s:%s", addr.Hex(), hexutil.Encode(sig))
+	// signed over another body
+	sig, err = crypto.Sign(accounts.TextHash([]byte(crypto.Keccak256Hash([]byte("{}")).Hex())), privKey)
+	require.NoError(t, err)
+	invalidHeader := fmt.Sprintf("%s:%s", addr.Hex(), hexutil.Encode(sig))
+
+	send := func(header string) int {
+		client := NewProxydClientWithHeaders("http://127.0.0.1:8545", http.Header{
+			proxyd.FlashbotsAuthHeader: []string{header},
+		})
+		_, code, err := client.SendRequest(body)
+		require.NoError(t, err)
+		return code
+	}
+
+	config := ReadConfig("flashbots_signature")
+	_, shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+
+	require.Equal(t, 403, send(invalidHeader))
+	require.Len(t, backend.Requests(), 0)
+
+	require.Equal(t, 200, send(validHeader))
+	require.Len(t, backend.Requests(), 1)
+	require.Equal(t, validHeader, backend.Requests()[0].Headers.Get(proxyd.FlashbotsAuthHeader))
+}
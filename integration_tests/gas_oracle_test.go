@@ -0,0 +1,76 @@
+package integration_tests
+
+import (
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ethereum-optimism/infra/proxyd"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGasOracle(t *testing.T) {
+	goodBackend := NewMockBackend(BatchedResponseHandler(200, `{"jsonrpc":"2.0","result":"0x3b9aca00","id":1}`))
+	defer goodBackend.Close()
+
+	require.NoError(t, os.Setenv("GOOD_BACKEND_RPC_URL", goodBackend.URL()))
+
+	config := ReadConfig("gas_oracle")
+	client := NewProxydClient("http://127.0.0.1:8545")
+	_, shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+
+	for i := 0; i < 3; i++ {
+		res, code, err := client.SendRPC("eth_gasPrice", nil)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, code)
+		RequireEqualJSON(t, []byte(`{"jsonrpc":"2.0","result":"0x3b9aca00","id":999}`), res)
+	}
+	require.Equal(t, 1, len(goodBackend.Requests()))
+
+	// fee history is kept per params
+	res, code, err := client.SendRPC("eth_feeHistory", []interface{}{"0x4", "latest", []int{25, 75}})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, code)
+	RequireEqualJSON(t, []byte(`{"jsonrpc":"2.0","result":"0x3b9aca00","id":999}`), res)
+	_, _, err = client.SendRPC("eth_feeHistory", []interface{}{"0x4", "latest", []int{25, 75}})
+	require.NoError(t, err)
+	_, _, err = client.SendRPC("eth_feeHistory", []interface{}{"0x8", "latest", []int{50}})
+	require.NoError(t, err)
+	require.Equal(t, 3, len(goodBackend.Requests()))
+
+	// other methods aren't affected
+	_, _, err = client.SendRPC("eth_chainId", nil)
+	require.NoError(t, err)
+	_, _, err = client.SendRPC("eth_chainId", nil)
+	require.NoError(t, err)
+	require.Equal(t, 5, len(goodBackend.Requests()))
+}
+
+func TestGasOracleWarming(t *testing.T) {
+	goodBackend := NewMockBackend(BatchedResponseHandler(200, `{"jsonrpc":"2.0","result":"0x3b9aca00","id":1}`))
+	defer goodBackend.Close()
+
+	require.NoError(t, os.Setenv("GOOD_BACKEND_RPC_URL", goodBackend.URL()))
+
+	config := ReadConfig("gas_oracle")
+	config.GasOracle.BlockTime = proxyd.TOMLDuration(300 * time.Millisecond)
+	config.GasOracle.Interval = proxyd.TOMLDuration(10 * time.Millisecond)
+	client := NewProxydClient("http://127.0.0.1:8545")
+	_, shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+
+	_, _, err = client.SendRPC("eth_gasPrice", nil)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(goodBackend.Requests()))
+
+	// the next block fetches it again without being asked, once
+	require.Eventually(t, func() bool {
+		return len(goodBackend.Requests()) == 2
+	}, time.Second, 10*time.Millisecond)
+	time.Sleep(400 * time.Millisecond)
+	require.Equal(t, 2, len(goodBackend.Requests()))
+}
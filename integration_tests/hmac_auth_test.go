@@ -0,0 +1,59 @@
+package integration_tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ethereum-optimism/infra/proxyd"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHMACAuth(t *testing.T) {
+	backend := NewMockBackend(SingleResponseHandler(200, goodResponse))
+	defer backend.Close()
+
+	require.NoError(t, os.Setenv("GOOD_BACKEND_RPC_URL", backend.URL()))
+	require.NoError(t, os.Setenv("INDEXER_HMAC_KEY", "shared"))
+
+	config := ReadConfig("hmac_auth")
+	_, shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+
+	body, err := json.Marshal(NewRPCReq("999", "eth_chainId", nil))
+	require.NoError(t, err)
+	send := func(url, signature string) int {
+		headers := make(http.Header)
+		if signature != "" {
+			headers.Set(proxyd.HMACAuthHeader, signature)
+		}
+		_, code, err := NewProxydClientWithHeaders(url, headers).SendRequest(body)
+		require.NoError(t, err)
+		return code
+	}
+
+	t.Run("signed requests are authenticated as the key", func(t *testing.T) {
+		backend.Reset()
+		require.Equal(t, 200, send("http://127.0.0.1:8545", proxyd.SignHMAC("indexer", []byte("shared"), time.Now(), body)))
+		require.Equal(t, 1, len(backend.Requests()))
+		require.Equal(t, "indexer", backend.Requests()[0].Headers.Get("X-Client"))
+	})
+
+	t.Run("invalid signatures are rejected", func(t *testing.T) {
+		backend.Reset()
+		require.Equal(t, 401, send("http://127.0.0.1:8545", proxyd.SignHMAC("indexer", []byte("wrong"), time.Now(), body)))
+		require.Equal(t, 401, send("http://127.0.0.1:8545", proxyd.SignHMAC("indexer", []byte("shared"), time.Now(), []byte("{}"))))
+		require.Equal(t, 401, send("http://127.0.0.1:8545", ""))
+		require.Equal(t, 0, len(backend.Requests()))
+	})
+
+	t.Run("authenticated paths keep working", func(t *testing.T) {
+		backend.Reset()
+		require.Equal(t, 200, send("http://127.0.0.1:8545/static-secret", ""))
+		require.Equal(t, 1, len(backend.Requests()))
+		require.Empty(t, backend.Requests()[0].Headers.Get("X-Client"))
+	})
+}
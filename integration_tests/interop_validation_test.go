@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"math"
 	"math/big"
+	"net/http"
 	"os"
 	"testing"
 	"time"
@@ -24,6 +25,8 @@ const dummyHealthyRes = `{"id": 123, "jsonrpc": "2.0", "result": "dummy"}`
 
 const errResTmpl = `{"error":{"code":%d,"message":"%s"},"id":1,"jsonrpc":"2.0"}`
 
+const rejectionResTmpl = `{"error":{"code":%d,"message":"%s","data":{"reason":"%s"}},"id":1,"jsonrpc":"2.0"}`
+
 func convertTxToReqParams(tx *types.Transaction) (string, error) {
 	var bytes hexutil.Bytes
 	bytes, err := tx.MarshalBinary()
@@ -103,8 +106,8 @@ func TestInteropValidation_NormalFlow(t *testing.T) {
 	config := ReadConfig("interop_validation")
 	config.SenderRateLimit.Limit = math.MaxInt // Don't perform rate limiting in this test since we're only testing interop validation.
 
-	expectedErrResp1 := fmt.Sprintf(errResTmpl, -320600, supervisorTypes.ErrConflict.Error())       // although the backend returns -32000, proxyd should correctly map it to -320600
-	expectedErrResp2 := fmt.Sprintf(errResTmpl, -321501, supervisorTypes.ErrDataCorruption.Error()) // although the backend returns -32000, proxyd should correctly map it to -321501
+	expectedErrResp1 := fmt.Sprintf(rejectionResTmpl, -320600, supervisorTypes.ErrConflict.Error(), proxyd.InteropReasonInvalidMessage)        // although the backend returns -32000, proxyd should correctly map it to -320600
+	expectedErrResp2 := fmt.Sprintf(rejectionResTmpl, -321501, supervisorTypes.ErrDataCorruption.Error(), proxyd.InteropReasonSupervisorState) // although the backend returns -32000, proxyd should correctly map it to -321501
 
 	type respDetails struct {
 		code         int
@@ -162,6 +165,42 @@ func TestInteropValidation_NormalFlow(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:     "quorum strategy with a majority of good urls",
+			strategy: proxyd.QuorumStrategy,
+			urls:     []string{badSupervisorUrl1, goodSupervisorUrl, goodSupervisorUrl},
+			expectedResp: respDetails{
+				code:         200,
+				jsonResponse: []byte(dummyHealthyRes),
+			},
+		},
+		{
+			name:     "quorum strategy without a majority of good urls",
+			strategy: proxyd.QuorumStrategy,
+			urls:     []string{badSupervisorUrl1, goodSupervisorUrl},
+			expectedResp: respDetails{
+				code:         409,
+				jsonResponse: []byte(expectedErrResp1),
+			},
+		},
+		{
+			name:     "hedged strategy with first url returning error",
+			strategy: proxyd.HedgedStrategy,
+			urls:     []string{badSupervisorUrl1, goodSupervisorUrl},
+			expectedResp: respDetails{
+				code:         409,
+				jsonResponse: []byte(expectedErrResp1),
+			},
+		},
+		{
+			name:     "circuit-breaker strategy with first url returning success",
+			strategy: proxyd.CircuitBreakerStrategy,
+			urls:     []string{goodSupervisorUrl, badSupervisorUrl1},
+			expectedResp: respDetails{
+				code:         200,
+				jsonResponse: []byte(dummyHealthyRes),
+			},
+		},
 	}
 
 	fakeInteropReqParams, err := convertTxToReqParams(fakeTxBuilder())
@@ -293,7 +332,7 @@ func TestInteropValidation_ReqSizeLimit(t *testing.T) {
 				require.Contains(t, string(observedResp), fmt.Sprintf("\"code\":%d", c.expectedRpcCode))
 			}
 
-			require.Equal(t, len(validatingBackend1.requests), c.expectedCallsToBackend)
+			require.Equal(t, len(validatingBackend1.Requests()), c.expectedCallsToBackend)
 		})
 	}
 }
@@ -369,7 +408,7 @@ func TestInteropValidation_AccessListSizeLimit(t *testing.T) {
 				require.Contains(t, string(observedResp), fmt.Sprintf("\"code\":%d", c.expectedRpcCode))
 			}
 
-			require.Equal(t, len(validatingBackend1.requests), c.expectedCallsToBackend)
+			require.Equal(t, len(validatingBackend1.Requests()), c.expectedCallsToBackend)
 		})
 	}
 }
@@ -486,7 +525,7 @@ func TestInteropValidation_Deduplication(t *testing.T) {
 	require.Equal(t, 413, observedCode, "the request should have failed because of the expectation of the deduplicated entries being 5")
 	require.Contains(t, string(observedResp), fmt.Sprintf("\"code\":%d", -32022))
 	require.Contains(t, string(observedResp), "access list out of bounds")
-	require.Equal(t, len(validatingBackend1.requests), 0) // no request was sent to the validating backend (supervisor) because the number of entries to be passed were found to be more than the size limit of 4
+	require.Equal(t, len(validatingBackend1.Requests()), 0) // no request was sent to the validating backend (supervisor) because the number of entries to be passed were found to be more than the size limit of 4
 
 	shutdown()
 	firstShutdownAlreadyCalled = true
@@ -501,7 +540,7 @@ func TestInteropValidation_Deduplication(t *testing.T) {
 	_, observedCode, err = client.SendRequest(sendRawTransaction)
 	require.NoError(t, err)
 	require.Equal(t, 200, observedCode, "the request should have succeeded because of the expectation of the deduplicated entries being 5")
-	require.Equal(t, len(validatingBackend1.requests), 1) // the success is represented by the fact that the request was sent to the validating backend (supervisor)
+	require.Equal(t, len(validatingBackend1.Requests()), 1) // the success is represented by the fact that the request was sent to the validating backend (supervisor)
 }
 
 func TestInteropValidation_StaticParseAccessPrevalidationCheck(t *testing.T) {
@@ -548,7 +587,7 @@ func TestInteropValidation_StaticParseAccessPrevalidationCheck(t *testing.T) {
 		require.Contains(t, string(observedResp), fmt.Sprintf("\"code\":%d", -32602))
 
 		// request failed aptly without needing to reach the validating backend (supervisor)
-		require.Equal(t, len(validatingBackend1.requests), 0)
+		require.Equal(t, len(validatingBackend1.Requests()), 0)
 	}
 
 	// subroutine for checking the good path
@@ -563,7 +602,7 @@ func TestInteropValidation_StaticParseAccessPrevalidationCheck(t *testing.T) {
 		_, observedCode, err := client.SendRequest(sendRawTransactionWithRightAccessList)
 		require.NoError(t, err)
 		require.Equal(t, 200, observedCode)
-		require.Equal(t, len(validatingBackend1.requests), 1)
+		require.Equal(t, len(validatingBackend1.Requests()), 1)
 	}
 
 }
@@ -613,7 +652,7 @@ func TestInteropValidation_SenderRateLimit(t *testing.T) {
 	require.NoError(t, err1)
 	require.Equal(t, 200, observedCode1)
 
-	require.Equal(t, len(validatingBackend1.requests), 1)
+	require.Equal(t, len(validatingBackend1.Requests()), 1)
 
 	// ensuring the second call failed due to rate limiting
 	require.NoError(t, err2)
@@ -622,7 +661,7 @@ func TestInteropValidation_SenderRateLimit(t *testing.T) {
 	require.Contains(t, string(observedResp2), fmt.Sprintf("\"code\":%d", -32017))
 
 	// ensuring that the second call didn't contribute to additional validating backend (supervisor) requests
-	require.Equal(t, len(validatingBackend1.requests), 1)
+	require.Equal(t, len(validatingBackend1.Requests()), 1)
 
 	// make a non-interop request to ensure that it succeeds despite the breaked rate limit depicting that the rate limit is not applied to non-interop requests
 	{
@@ -649,7 +688,7 @@ func TestInteropValidation_SenderRateLimit(t *testing.T) {
 	require.Equal(t, 200, observedCode3)
 
 	// ensuring that this call did contribute to additional validating backend (supervisor) requests due to being within the rate limit
-	require.Equal(t, len(validatingBackend1.requests), 2)
+	require.Equal(t, len(validatingBackend1.Requests()), 2)
 }
 
 func TestInteropValidation_HealthAwareLoadBalancingStrategy_SomeHealthyBackends(t *testing.T) {
@@ -678,8 +717,8 @@ func TestInteropValidation_HealthAwareLoadBalancingStrategy_SomeHealthyBackends(
 	unhealthyBackend3 := NewMockBackend(SingleResponseHandler(502, errResp5))
 	defer unhealthyBackend3.Close()
 
-	expectedErrResp1 := fmt.Sprintf(errResTmpl, -320600, supervisorTypes.ErrConflict.Error())       // although the backend returns -32000, proxyd should correctly map it to -320600
-	expectedErrResp2 := fmt.Sprintf(errResTmpl, -321501, supervisorTypes.ErrDataCorruption.Error()) // although the backend returns -32000, proxyd should correctly map it to -321501
+	expectedErrResp1 := fmt.Sprintf(rejectionResTmpl, -320600, supervisorTypes.ErrConflict.Error(), proxyd.InteropReasonInvalidMessage)        // although the backend returns -32000, proxyd should correctly map it to -320600
+	expectedErrResp2 := fmt.Sprintf(rejectionResTmpl, -321501, supervisorTypes.ErrDataCorruption.Error(), proxyd.InteropReasonSupervisorState) // although the backend returns -32000, proxyd should correctly map it to -321501
 
 	config := ReadConfig("interop_validation")
 	config.SenderRateLimit.Limit = math.MaxInt // Don't perform rate limiting in this test since we're only testing interop validation.
@@ -705,11 +744,11 @@ func TestInteropValidation_HealthAwareLoadBalancingStrategy_SomeHealthyBackends(
 	}
 
 	assertExpectations := func(t *testing.T, b BackendToRequestCountsExpectation) {
-		require.Equal(t, b.unhealthyBackend1, len(unhealthyBackend1.requests), "unhealthyBackend1 should have received %d requests", b.unhealthyBackend1)
-		require.Equal(t, b.unhealthyBackend2, len(unhealthyBackend2.requests), "unhealthyBackend2 should have received %d requests", b.unhealthyBackend2)
-		require.Equal(t, b.badHealthyBackend1, len(badHealthyBackend1.requests), "badHealthyBackend1 should have received %d requests", b.badHealthyBackend1)
-		require.Equal(t, b.badHealthyBackend2, len(badHealthyBackend2.requests), "badHealthyBackend2 should have received %d requests", b.badHealthyBackend2)
-		require.Equal(t, b.unhealthyBackend3, len(unhealthyBackend3.requests), "unhealthyBackend3 should have received %d requests", b.unhealthyBackend3)
+		require.Equal(t, b.unhealthyBackend1, len(unhealthyBackend1.Requests()), "unhealthyBackend1 should have received %d requests", b.unhealthyBackend1)
+		require.Equal(t, b.unhealthyBackend2, len(unhealthyBackend2.Requests()), "unhealthyBackend2 should have received %d requests", b.unhealthyBackend2)
+		require.Equal(t, b.badHealthyBackend1, len(badHealthyBackend1.Requests()), "badHealthyBackend1 should have received %d requests", b.badHealthyBackend1)
+		require.Equal(t, b.badHealthyBackend2, len(badHealthyBackend2.Requests()), "badHealthyBackend2 should have received %d requests", b.badHealthyBackend2)
+		require.Equal(t, b.unhealthyBackend3, len(unhealthyBackend3.Requests()), "unhealthyBackend3 should have received %d requests", b.unhealthyBackend3)
 	}
 
 	_, shutdown, err := proxyd.Start(config)
@@ -885,9 +924,9 @@ func TestInteropValidation_HealthAwareLoadBalancingStrategy_NoHealthyBackends_Cu
 	}
 
 	assertExpectations := func(t *testing.T, b BackendToRequestCountsExpectation) {
-		require.Equal(t, b.unhealthyBackend1, len(unhealthyBackend1.requests), "unhealthyBackend1 should have received %d requests", b.unhealthyBackend1)
-		require.Equal(t, b.unhealthyBackend2, len(unhealthyBackend2.requests), "unhealthyBackend2 should have received %d requests", b.unhealthyBackend2)
-		require.Equal(t, b.unhealthyBackend3, len(unhealthyBackend3.requests), "unhealthyBackend3 should have received %d requests", b.unhealthyBackend3)
+		require.Equal(t, b.unhealthyBackend1, len(unhealthyBackend1.Requests()), "unhealthyBackend1 should have received %d requests", b.unhealthyBackend1)
+		require.Equal(t, b.unhealthyBackend2, len(unhealthyBackend2.Requests()), "unhealthyBackend2 should have received %d requests", b.unhealthyBackend2)
+		require.Equal(t, b.unhealthyBackend3, len(unhealthyBackend3.Requests()), "unhealthyBackend3 should have received %d requests", b.unhealthyBackend3)
 	}
 
 	config.InteropValidationConfig.Strategy = proxyd.HealthAwareLoadBalancingStrategy
@@ -903,7 +942,7 @@ func TestInteropValidation_HealthAwareLoadBalancingStrategy_NoHealthyBackends_Cu
 	require.NoError(t, err)
 	defer shutdown()
 
-	expectedResp := `{"jsonrpc":"2.0","error":{"code":-32000,"message":"no healthy supervisor backends found"},"id":1}`
+	expectedResp := `{"jsonrpc":"2.0","error":{"code":-32000,"message":"no healthy supervisor backends found","data":{"reason":"supervisor_unavailable"}},"id":1}`
 
 	client := NewProxydClient("http://127.0.0.1:8545")
 
@@ -1011,3 +1050,257 @@ func TestInteropValidation_HealthAwareLoadBalancingStrategy_NoHealthyBackends_Cu
 		expectations.unhealthyBackend3 += 0
 	})
 }
+
+func TestInteropValidation_HedgedStrategy_SlowBackend(t *testing.T) {
+	goodBackend := NewMockBackend(SingleResponseHandler(200, dummyHealthyRes))
+	defer goodBackend.Close()
+
+	require.NoError(t, os.Setenv("GOOD_BACKEND_RPC_URL", goodBackend.URL()))
+
+	errResp := fmt.Sprintf(errResTmpl, -32000, supervisorTypes.ErrConflict.Error())
+	slowBackend := NewMockBackend(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(500 * time.Millisecond)
+		SingleResponseHandler(409, errResp)(w, r)
+	}))
+	defer slowBackend.Close()
+
+	fastBackend := NewMockBackend(SingleResponseHandler(200, dummyHealthyRes))
+	defer fastBackend.Close()
+
+	config := ReadConfig("interop_validation")
+	config.SenderRateLimit.Limit = math.MaxInt // Don't perform rate limiting in this test since we're only testing interop validation.
+	config.InteropValidationConfig.Strategy = proxyd.HedgedStrategy
+	config.InteropValidationConfig.HedgeDelay = 50 * time.Millisecond
+	config.InteropValidationConfig.Urls = []string{slowBackend.URL(), fastBackend.URL()}
+
+	_, shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+
+	fakeInteropReqParams, err := convertTxToReqParams(fakeTxBuilder())
+	require.NoError(t, err)
+
+	// the fast backend is asked after the hedge delay and answers first
+	client := NewProxydClient("http://127.0.0.1:8545")
+	start := time.Now()
+	observedResp, observedCode, err := client.SendRequest(makeSendRawTransaction(fakeInteropReqParams))
+	require.NoError(t, err)
+	require.Equal(t, 200, observedCode)
+	RequireEqualJSON(t, []byte(dummyHealthyRes), observedResp)
+	require.Less(t, time.Since(start), 400*time.Millisecond)
+	require.Len(t, fastBackend.Requests(), 1)
+}
+
+func TestInteropValidation_CircuitBreakerStrategy(t *testing.T) {
+	goodBackend := NewMockBackend(SingleResponseHandler(200, dummyHealthyRes))
+	defer goodBackend.Close()
+
+	require.NoError(t, os.Setenv("GOOD_BACKEND_RPC_URL", goodBackend.URL()))
+
+	errResp := fmt.Sprintf(errResTmpl, -32000, "unknown error")
+	unhealthyBackend := NewMockBackend(SingleResponseHandler(500, errResp))
+	defer unhealthyBackend.Close()
+
+	healthyBackend := NewMockBackend(SingleResponseHandler(200, dummyHealthyRes))
+	defer healthyBackend.Close()
+
+	config := ReadConfig("interop_validation")
+	config.SenderRateLimit.Limit = math.MaxInt // Don't perform rate limiting in this test since we're only testing interop validation.
+	config.InteropValidationConfig.Strategy = proxyd.CircuitBreakerStrategy
+	config.InteropValidationConfig.CircuitBreakerThreshold = 2
+	config.InteropValidationConfig.CircuitBreakerCooldown = time.Hour
+	config.InteropValidationConfig.Urls = []string{unhealthyBackend.URL(), healthyBackend.URL()}
+
+	_, shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+
+	fakeInteropReqParams, err := convertTxToReqParams(fakeTxBuilder())
+	require.NoError(t, err)
+
+	client := NewProxydClient("http://127.0.0.1:8545")
+	for i := 0; i < 5; i++ {
+		observedResp, observedCode, err := client.SendRequest(makeSendRawTransaction(fakeInteropReqParams))
+		require.NoError(t, err)
+		require.Equal(t, 200, observedCode, "iteration %d", i)
+		RequireEqualJSON(t, []byte(dummyHealthyRes), observedResp)
+	}
+
+	// the unhealthy backend is skipped once it failed twice in a row
+	require.Len(t, unhealthyBackend.Requests(), 2)
+	require.Len(t, healthyBackend.Requests(), 5)
+}
+
+func TestInteropValidation_AdvisoryMode(t *testing.T) {
+	goodBackend := NewMockBackend(SingleResponseHandler(200, dummyHealthyRes))
+	defer goodBackend.Close()
+
+	require.NoError(t, os.Setenv("GOOD_BACKEND_RPC_URL", goodBackend.URL()))
+
+	errResp := fmt.Sprintf(errResTmpl, -32000, supervisorTypes.ErrConflict.Error())
+	badValidatingBackend := NewMockBackend(SingleResponseHandler(409, errResp))
+	defer badValidatingBackend.Close()
+
+	config := ReadConfig("interop_validation")
+	config.SenderRateLimit.Limit = math.MaxInt // Don't perform rate limiting in this test since we're only testing interop validation.
+	config.InteropValidationConfig.Urls = []string{badValidatingBackend.URL()}
+	config.InteropValidationConfig.Mode = proxyd.InteropValidationAdvisory
+	config.InteropValidationConfig.EnforcedOrigins = []string{`^https://enforced\.example\.com$`}
+
+	_, shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+
+	fakeInteropReqParams, err := convertTxToReqParams(fakeTxBuilder())
+	require.NoError(t, err)
+
+	// the transaction is forwarded, and validated in the background
+	client := NewProxydClient("http://127.0.0.1:8545")
+	observedResp, observedCode, err := client.SendRequest(makeSendRawTransaction(fakeInteropReqParams))
+	require.NoError(t, err)
+	require.Equal(t, 200, observedCode)
+	RequireEqualJSON(t, []byte(dummyHealthyRes), observedResp)
+	require.Len(t, goodBackend.Requests(), 1)
+	require.Eventually(t, func() bool {
+		return len(badValidatingBackend.Requests()) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	// enforced origins are still rejected
+	enforcedClient := NewProxydClientWithHeaders("http://127.0.0.1:8545", http.Header{
+		"Origin": []string{"https://enforced.example.com"},
+	})
+	observedResp, observedCode, err = enforcedClient.SendRequest(makeSendRawTransaction(fakeInteropReqParams))
+	require.NoError(t, err)
+	require.Equal(t, 409, observedCode)
+	RequireEqualJSON(t, []byte(fmt.Sprintf(rejectionResTmpl, -320600, supervisorTypes.ErrConflict.Error(), proxyd.InteropReasonInvalidMessage)), observedResp)
+	require.Len(t, goodBackend.Requests(), 1)
+}
+
+func TestInteropValidation_FailoverStrategy(t *testing.T) {
+	goodBackend := NewMockBackend(SingleResponseHandler(200, dummyHealthyRes))
+	defer goodBackend.Close()
+
+	require.NoError(t, os.Setenv("GOOD_BACKEND_RPC_URL", goodBackend.URL()))
+
+	errResp := fmt.Sprintf(errResTmpl, -32000, "unknown error")
+	primarySupervisor := NewMockBackend(SingleResponseHandler(500, errResp))
+	defer primarySupervisor.Close()
+
+	secondarySupervisor := NewMockBackend(SingleResponseHandler(200, dummyHealthyRes))
+	defer secondarySupervisor.Close()
+
+	config := ReadConfig("interop_validation")
+	config.SenderRateLimit.Limit = math.MaxInt // Don't perform rate limiting in this test since we're only testing interop validation.
+	config.InteropValidationConfig.Strategy = proxyd.FailoverStrategy
+	config.InteropValidationConfig.BanPeriod = time.Hour
+	config.InteropValidationConfig.HealthCheckInterval = 50 * time.Millisecond
+	config.InteropValidationConfig.Urls = []string{primarySupervisor.URL(), secondarySupervisor.URL()}
+
+	_, shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+
+	fakeInteropReqParams, err := convertTxToReqParams(fakeTxBuilder())
+	require.NoError(t, err)
+	client := NewProxydClient("http://127.0.0.1:8545")
+
+	// the failing primary is banned and the secondary takes over
+	for i := 0; i < 3; i++ {
+		observedResp, observedCode, err := client.SendRequest(makeSendRawTransaction(fakeInteropReqParams))
+		require.NoError(t, err)
+		require.Equal(t, 200, observedCode, "iteration %d", i)
+		RequireEqualJSON(t, []byte(dummyHealthyRes), observedResp)
+	}
+
+	// once health checks find the primary working again, it is preferred again
+	primarySupervisor.SetHandler(SingleResponseHandler(200, dummyHealthyRes))
+	secondarySupervisor.SetHandler(SingleResponseHandler(409, fmt.Sprintf(errResTmpl, -32000, supervisorTypes.ErrConflict.Error())))
+	time.Sleep(200 * time.Millisecond)
+	observedResp, observedCode, err := client.SendRequest(makeSendRawTransaction(fakeInteropReqParams))
+	require.NoError(t, err)
+	require.Equal(t, 200, observedCode)
+	RequireEqualJSON(t, []byte(dummyHealthyRes), observedResp)
+}
+
+func TestInteropValidation_FailOpen(t *testing.T) {
+	goodBackend := NewMockBackend(SingleResponseHandler(200, dummyHealthyRes))
+	defer goodBackend.Close()
+
+	require.NoError(t, os.Setenv("GOOD_BACKEND_RPC_URL", goodBackend.URL()))
+
+	errResp := fmt.Sprintf(errResTmpl, -32000, "unknown error")
+	unreachableSupervisor := NewMockBackend(SingleResponseHandler(503, errResp))
+	defer unreachableSupervisor.Close()
+
+	errResp2 := fmt.Sprintf(errResTmpl, -32000, supervisorTypes.ErrConflict.Error())
+	badSupervisor := NewMockBackend(SingleResponseHandler(409, errResp2))
+	defer badSupervisor.Close()
+
+	config := ReadConfig("interop_validation")
+	config.SenderRateLimit.Limit = math.MaxInt // Don't perform rate limiting in this test since we're only testing interop validation.
+	config.InteropValidationConfig.FailOpen = true
+
+	fakeInteropReqParams, err := convertTxToReqParams(fakeTxBuilder())
+	require.NoError(t, err)
+	client := NewProxydClient("http://127.0.0.1:8545")
+
+	// transactions are forwarded when no supervisor can be reached
+	config.InteropValidationConfig.Urls = []string{unreachableSupervisor.URL()}
+	_, shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+	observedResp, observedCode, err := client.SendRequest(makeSendRawTransaction(fakeInteropReqParams))
+	require.NoError(t, err)
+	require.Equal(t, 200, observedCode)
+	RequireEqualJSON(t, []byte(dummyHealthyRes), observedResp)
+	shutdown()
+
+	// but not when a supervisor rejects them
+	config.InteropValidationConfig.Urls = []string{badSupervisor.URL()}
+	_, shutdown, err = proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+	_, observedCode, err = client.SendRequest(makeSendRawTransaction(fakeInteropReqParams))
+	require.NoError(t, err)
+	require.Equal(t, 409, observedCode)
+}
+
+func TestInteropValidation_PerChainSenderRateLimit(t *testing.T) {
+	goodBackend := NewMockBackend(SingleResponseHandler(200, dummyHealthyRes))
+	defer goodBackend.Close()
+
+	require.NoError(t, os.Setenv("GOOD_BACKEND_RPC_URL", goodBackend.URL()))
+
+	config := ReadConfig("interop_validation")
+	config.SenderRateLimit.Limit = math.MaxInt // Don't perform basic rate limiting in this test since we're only testing interop validation.
+
+	config.InteropValidationConfig.RateLimit.Enabled = true
+	config.InteropValidationConfig.RateLimit.Limit = 1
+	config.InteropValidationConfig.RateLimit.Interval = proxyd.TOMLDuration(2 * time.Second)
+	// the messages of the fake transaction come from chain 420120003
+	config.InteropValidationConfig.RateLimit.ChainLimits = map[string]int{"420120003": 3}
+
+	validatingBackend := NewMockBackend(SingleResponseHandler(200, dummyHealthyRes))
+	defer validatingBackend.Close()
+	config.InteropValidationConfig.Urls = []string{validatingBackend.URL()}
+
+	_, shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+
+	client := NewProxydClient("http://127.0.0.1:8545")
+	fakeInteropReqParams, err := convertTxToReqParams(fakeTxBuilder())
+	require.NoError(t, err)
+	interopSendRawTransaction := makeSendRawTransaction(fakeInteropReqParams)
+
+	for i := 0; i < 3; i++ {
+		_, observedCode, err := client.SendRequest(interopSendRawTransaction)
+		require.NoError(t, err)
+		require.Equal(t, 200, observedCode, "iteration %d", i)
+	}
+
+	observedResp, observedCode, err := client.SendRequest(interopSendRawTransaction)
+	require.NoError(t, err)
+	require.Equal(t, 429, observedCode)
+	require.Contains(t, string(observedResp), "sender is over rate limit")
+	require.Equal(t, 3, len(validatingBackend.Requests()))
+}
@@ -0,0 +1,47 @@
+package integration_tests
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum-optimism/infra/proxyd"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIPFilter(t *testing.T) {
+	backend := NewMockBackend(SingleResponseHandler(200, goodResponse))
+	defer backend.Close()
+
+	require.NoError(t, os.Setenv("GOOD_BACKEND_RPC_URL", backend.URL()))
+
+	path := filepath.Join(t.TempDir(), "ips.txt")
+	require.NoError(t, os.WriteFile(path, []byte("deny 10.1.0.0/16\n"), 0o600))
+
+	config := ReadConfig("ip_filter")
+	config.IPFilter.File = path
+	_, shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+
+	send := func(ip string) int {
+		client := NewProxydClientWithHeaders("http://127.0.0.1:8545", http.Header{"X-Forwarded-For": []string{ip}})
+		_, code, err := client.SendRPC("eth_chainId", nil)
+		require.NoError(t, err)
+		return code
+	}
+
+	require.Equal(t, 200, send("10.2.0.1"))
+	require.Equal(t, 403, send("10.0.0.1"))
+	require.Equal(t, 403, send("10.1.0.1"))
+	require.Equal(t, 403, send("192.168.0.1"))
+	require.Equal(t, 1, len(backend.Requests()))
+
+	// the file is read again without a restart
+	require.NoError(t, os.WriteFile(path, []byte("deny 10.2.0.0/16\n"), 0o600))
+	require.Eventually(t, func() bool {
+		return send("10.2.0.1") == 403 && send("10.1.0.1") == 200
+	}, 5*time.Second, 50*time.Millisecond)
+}
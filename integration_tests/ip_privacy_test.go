@@ -0,0 +1,57 @@
+package integration_tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum-optimism/infra/proxyd"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIPPrivacy(t *testing.T) {
+	goodBackend := NewMockBackend(SingleResponseHandler(200, dummyRes))
+	defer goodBackend.Close()
+
+	require.NoError(t, os.Setenv("GOOD_BACKEND_RPC_URL", goodBackend.URL()))
+
+	config := ReadConfig("ip_privacy")
+	config.TxAudit.File = filepath.Join(t.TempDir(), "txs.log")
+	_, shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+
+	client := NewProxydClient("http://127.0.0.1:8545")
+	_, code, err := client.SendRequest(makeSendRawTransaction(txHex1))
+	require.NoError(t, err)
+	require.Equal(t, 200, code)
+
+	t.Run("audit records pseudonymized ips", func(t *testing.T) {
+		var recs []*proxyd.TxAuditRecord
+		require.Eventually(t, func() bool {
+			req, err := http.NewRequest(http.MethodGet, "http://127.0.0.1:9768/admin/txs?ip=127.0.0.1", nil)
+			require.NoError(t, err)
+			req.Header.Set("Authorization", "Bearer admin")
+			res, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer res.Body.Close()
+			require.NoError(t, json.NewDecoder(res.Body).Decode(&recs))
+			return len(recs) == 1
+		}, time.Second, 10*time.Millisecond)
+		require.NotEqual(t, "127.0.0.1", recs[0].ClientIP)
+		require.Len(t, recs[0].ClientIP, 32)
+	})
+
+	t.Run("clients are still rate limited", func(t *testing.T) {
+		codes := make(map[int]int)
+		for i := 0; i < 5; i++ {
+			_, code, err := client.SendRPC("eth_chainId", nil)
+			require.NoError(t, err)
+			codes[code]++
+		}
+		require.NotZero(t, codes[429])
+	})
+}
@@ -0,0 +1,68 @@
+package integration_tests
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum-optimism/infra/proxyd"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSequencerLeader(t *testing.T) {
+	seq1 := NewMockBackend(SingleResponseHandler(200, dummyRes))
+	defer seq1.Close()
+	seq2 := NewMockBackend(SingleResponseHandler(200, dummyRes))
+	defer seq2.Close()
+
+	var seq2Leader atomic.Bool
+	seq1Health := NewMockBackend(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if seq2Leader.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer seq1Health.Close()
+	seq2Conductor := NewMockBackend(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, `{"jsonrpc":"2.0","result":%t,"id":1}`, seq2Leader.Load())
+	}))
+	defer seq2Conductor.Close()
+
+	require.NoError(t, os.Setenv("SEQ1_RPC_URL", seq1.URL()))
+	require.NoError(t, os.Setenv("SEQ2_RPC_URL", seq2.URL()))
+	require.NoError(t, os.Setenv("SEQ1_LEADER_URL", seq1Health.URL()))
+	require.NoError(t, os.Setenv("SEQ2_CONDUCTOR_URL", seq2Conductor.URL()))
+
+	config := ReadConfig("leader")
+	client := NewProxydClient("http://127.0.0.1:8545")
+	_, shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+
+	sendTx := func() {
+		t.Helper()
+		_, code, err := client.SendRequest(makeSendRawTransaction(txHex1))
+		require.NoError(t, err)
+		require.Equal(t, 200, code)
+	}
+
+	// writes go to the leader
+	sendTx()
+	require.Equal(t, 1, len(seq1.Requests()))
+	require.Equal(t, 0, len(seq2.Requests()))
+
+	// and follow it on failover
+	seq2Leader.Store(true)
+	time.Sleep(200 * time.Millisecond)
+	sendTx()
+	require.Equal(t, 1, len(seq1.Requests()))
+	require.Equal(t, 1, len(seq2.Requests()))
+
+	// reads go to any backend
+	_, code, err := client.SendRPC("eth_chainId", nil)
+	require.NoError(t, err)
+	require.Equal(t, 200, code)
+	require.Equal(t, 2, len(seq1.Requests()))
+}
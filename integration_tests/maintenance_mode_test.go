@@ -0,0 +1,69 @@
+package integration_tests
+
+import (
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ethereum-optimism/infra/proxyd"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaintenanceMode(t *testing.T) {
+	mainBackend := NewMockBackend(SingleResponseHandler(200, goodResponse))
+	defer mainBackend.Close()
+	archiveBackend := NewMockBackend(SingleResponseHandler(200, goodResponse))
+	defer archiveBackend.Close()
+
+	require.NoError(t, os.Setenv("MAIN_BACKEND_RPC_URL", mainBackend.URL()))
+	require.NoError(t, os.Setenv("ARCHIVE_BACKEND_RPC_URL", archiveBackend.URL()))
+
+	config := ReadConfig("maintenance_mode")
+	_, shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+
+	client := NewProxydClient("http://127.0.0.1:8545")
+	setNotice := func(path, body string) {
+		req, err := http.NewRequest(http.MethodPut, "http://127.0.0.1:9764/admin/maintenance"+path, strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer admin")
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		res.Body.Close()
+		require.Equal(t, http.StatusNoContent, res.StatusCode)
+	}
+
+	t.Run("configured group notice", func(t *testing.T) {
+		res, code, err := client.SendRPC("eth_getLogs", nil)
+		require.NoError(t, err)
+		require.Equal(t, 503, code)
+		RequireEqualJSON(t, []byte(`{"jsonrpc":"2.0","error":{"code":-32037,"message":"archive nodes are being upgraded","data":{"restore_at":"2026-10-16T04:00:00Z"}},"id":999}`), res)
+
+		_, code, err = client.SendRPC("eth_chainId", nil)
+		require.NoError(t, err)
+		require.Equal(t, 200, code)
+		require.Equal(t, 0, len(archiveBackend.Requests()))
+		require.Equal(t, 1, len(mainBackend.Requests()))
+	})
+
+	t.Run("notices are toggled at runtime", func(t *testing.T) {
+		mainBackend.Reset()
+		setNotice("", `{"enabled":true,"message":"upgrading","methods":["eth_chainId"]}`)
+		res, code, err := client.SendRPC("eth_chainId", nil)
+		require.NoError(t, err)
+		require.Equal(t, 503, code)
+		RequireEqualJSON(t, []byte(`{"jsonrpc":"2.0","error":{"code":-32037,"message":"upgrading"},"id":999}`), res)
+		require.Equal(t, 0, len(mainBackend.Requests()))
+
+		setNotice("", `{"enabled":false}`)
+		setNotice("/archive", `{"enabled":false}`)
+		_, code, err = client.SendRPC("eth_chainId", nil)
+		require.NoError(t, err)
+		require.Equal(t, 200, code)
+		_, code, err = client.SendRPC("eth_getLogs", nil)
+		require.NoError(t, err)
+		require.Equal(t, 200, code)
+	})
+}
@@ -0,0 +1,56 @@
+package integration_tests
+
+import (
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ethereum-optimism/infra/proxyd"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMethodTimeouts(t *testing.T) {
+	slowBackend := NewMockBackend(nil)
+	defer slowBackend.Close()
+
+	require.NoError(t, os.Setenv("SLOW_BACKEND_RPC_URL", slowBackend.URL()))
+
+	config := ReadConfig("method_timeouts")
+	client := NewProxydClient("http://127.0.0.1:8545")
+	_, shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+
+	slowBackend.SetHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// longer than the server and backend timeouts, shorter than debug_*
+		time.Sleep(2 * time.Second)
+		SingleResponseHandler(200, goodResponse)(w, r)
+	}))
+
+	t.Run("overridden method outlives the server timeout", func(t *testing.T) {
+		res, code, err := client.SendRPC("debug_traceTransaction", []interface{}{"0x1"})
+		require.NoError(t, err)
+		require.Equal(t, 200, code)
+		RequireEqualJSON(t, []byte(goodResponse), res)
+	})
+
+	t.Run("other methods keep the server timeout", func(t *testing.T) {
+		_, code, err := client.SendRPC("eth_chainId", nil)
+		require.NoError(t, err)
+		require.NotEqual(t, 200, code)
+	})
+
+	t.Run("batches get the longest timeout of their methods", func(t *testing.T) {
+		slowBackend.SetHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(2 * time.Second)
+			BatchedResponseHandler(200, goodResponse, goodResponse)(w, r)
+		}))
+		_, code, err := client.SendBatchRPC(
+			NewRPCReq("1", "eth_chainId", nil),
+			NewRPCReq("2", "debug_traceTransaction", []interface{}{"0x1"}),
+		)
+		require.NoError(t, err)
+		require.Equal(t, 200, code)
+	})
+}
@@ -1,8 +1,6 @@
 package integration_tests
 
 import (
-	"bytes"
-	"context"
 	"encoding/json"
 	"io"
 	"net/http"
@@ -11,21 +9,13 @@ import (
 	"sync"
 
 	"github.com/ethereum-optimism/infra/proxyd"
+	"github.com/ethereum-optimism/infra/proxyd/pkg/mock"
 	"github.com/gorilla/websocket"
 )
 
-type RecordedRequest struct {
-	Method  string
-	Headers http.Header
-	Body    []byte
-}
+type RecordedRequest = mock.RecordedRequest
 
-type MockBackend struct {
-	handler  http.Handler
-	server   *httptest.Server
-	mtx      sync.RWMutex
-	requests []*RecordedRequest
-}
+type MockBackend = mock.Backend
 
 func SingleResponseHandler(code int, response string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -205,56 +195,7 @@ func (h *BatchRPCResponseRouter) ServeHTTP(w http.ResponseWriter, r *http.Reques
 }
 
 func NewMockBackend(handler http.Handler) *MockBackend {
-	mb := &MockBackend{
-		handler: handler,
-	}
-	mb.server = httptest.NewServer(http.HandlerFunc(mb.wrappedHandler))
-	return mb
-}
-
-func (m *MockBackend) URL() string {
-	return m.server.URL
-}
-
-func (m *MockBackend) Close() {
-	m.server.Close()
-}
-
-func (m *MockBackend) SetHandler(handler http.Handler) {
-	m.mtx.Lock()
-	m.handler = handler
-	m.mtx.Unlock()
-}
-
-func (m *MockBackend) Reset() {
-	m.mtx.Lock()
-	m.requests = nil
-	m.mtx.Unlock()
-}
-
-func (m *MockBackend) Requests() []*RecordedRequest {
-	m.mtx.RLock()
-	defer m.mtx.RUnlock()
-	out := make([]*RecordedRequest, len(m.requests))
-	copy(out, m.requests)
-	return out
-}
-
-func (m *MockBackend) wrappedHandler(w http.ResponseWriter, r *http.Request) {
-	m.mtx.Lock()
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		panic(err)
-	}
-	clone := r.Clone(context.Background())
-	clone.Body = io.NopCloser(bytes.NewReader(body))
-	m.requests = append(m.requests, &RecordedRequest{
-		Method:  r.Method,
-		Headers: r.Header.Clone(),
-		Body:    body,
-	})
-	m.handler.ServeHTTP(w, clone)
-	m.mtx.Unlock()
+	return mock.NewBackend(handler)
 }
 
 type MockWSBackend struct {
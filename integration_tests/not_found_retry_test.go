@@ -0,0 +1,65 @@
+package integration_tests
+
+import (
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/ethereum-optimism/infra/proxyd"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotFoundRetry(t *testing.T) {
+	router := NewBatchRPCResponseRouter()
+	router.SetFallbackRoute("eth_sendRawTransaction", "0x1")
+	router.SetFallbackRoute("eth_getTransactionByHash", nil)
+	nodeBackend := NewMockBackend(router)
+	defer nodeBackend.Close()
+
+	require.NoError(t, os.Setenv("NODE_BACKEND_RPC_URL", nodeBackend.URL()))
+
+	config := ReadConfig("not_found_retry")
+	client := NewProxydClient("http://127.0.0.1:8545")
+	_, shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+
+	tx := new(types.Transaction)
+	require.NoError(t, tx.UnmarshalBinary(hexutil.MustDecode(txHex1)))
+
+	// unknown transactions aren't retried
+	res, code, err := client.SendRPC("eth_getTransactionByHash", []interface{}{tx.Hash().Hex()})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, code)
+	RequireEqualJSON(t, []byte(`{"jsonrpc": "2.0", "result": null, "id": 999}`), res)
+	require.Equal(t, 1, len(nodeBackend.Requests()))
+
+	_, code, err = client.SendRequest(makeSendRawTransaction(txHex1))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, code)
+
+	// forwarded transactions are, up to max_retries
+	nodeBackend.Reset()
+	res, code, err = client.SendRPC("eth_getTransactionByHash", []interface{}{tx.Hash().Hex()})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, code)
+	RequireEqualJSON(t, []byte(`{"jsonrpc": "2.0", "result": null, "id": 999}`), res)
+	require.Equal(t, 3, len(nodeBackend.Requests()))
+
+	// and answered once the backend has them
+	nodeBackend.Reset()
+	var calls int
+	nodeBackend.SetHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls++; calls > 1 {
+			router.SetRoute("eth_getTransactionByHash", "999", "tx")
+		}
+		router.ServeHTTP(w, r)
+	}))
+	res, code, err = client.SendRPC("eth_getTransactionByHash", []interface{}{tx.Hash().Hex()})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, code)
+	RequireEqualJSON(t, []byte(`{"jsonrpc": "2.0", "result": "tx", "id": 999}`), res)
+	require.Equal(t, 2, len(nodeBackend.Requests()))
+}
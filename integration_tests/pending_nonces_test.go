@@ -0,0 +1,48 @@
+package integration_tests
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/ethereum-optimism/infra/proxyd"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPendingNonces(t *testing.T) {
+	router := NewBatchRPCResponseRouter()
+	router.SetFallbackRoute("eth_sendRawTransaction", "0x1234")
+	router.SetFallbackRoute("eth_getTransactionCount", "0x2")
+	goodBackend := NewMockBackend(router)
+	defer goodBackend.Close()
+
+	require.NoError(t, os.Setenv("GOOD_BACKEND_RPC_URL", goodBackend.URL()))
+
+	config := ReadConfig("pending_nonces")
+	client := NewProxydClient("http://127.0.0.1:8545")
+	_, shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+
+	tx := new(types.Transaction)
+	require.NoError(t, tx.UnmarshalBinary(hexutil.MustDecode(txHex1)))
+	from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	require.NoError(t, err)
+	getPendingNonce := []byte(fmt.Sprintf(`{"jsonrpc":"2.0","method":"eth_getTransactionCount","params":["%s","pending"],"id":1}`, from.Hex()))
+
+	res, code, err := client.SendRequest(getPendingNonce)
+	require.NoError(t, err)
+	require.Equal(t, 200, code)
+	RequireEqualJSON(t, []byte(`{"jsonrpc":"2.0","result":"0x2","id":1}`), res)
+
+	// the backend hasn't seen the transaction yet
+	_, code, err = client.SendRequest(makeSendRawTransaction(txHex1))
+	require.NoError(t, err)
+	require.Equal(t, 200, code)
+	res, code, err = client.SendRequest(getPendingNonce)
+	require.NoError(t, err)
+	require.Equal(t, 200, code)
+	RequireEqualJSON(t, []byte(fmt.Sprintf(`{"jsonrpc":"2.0","result":"%s","id":1}`, hexutil.EncodeUint64(tx.Nonce()+1))), res)
+}
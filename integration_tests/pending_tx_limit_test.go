@@ -0,0 +1,129 @@
+package integration_tests
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+
+	"github.com/alicebob/miniredis"
+	"github.com/ethereum-optimism/infra/proxyd"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPendingTxLimitSkipsRejectedTxs(t *testing.T) {
+	redisServer, err := miniredis.Run()
+	require.NoError(t, err)
+	defer redisServer.Close()
+
+	backend := NewMockBackend(SingleResponseHandler(200, dummyRes))
+	defer backend.Close()
+
+	var deny atomic.Bool
+	deny.Store(true)
+	policyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		res := proxyd.PolicyResponse{Decision: proxyd.PolicyDecisionAllow}
+		if deny.Load() {
+			res = proxyd.PolicyResponse{Decision: proxyd.PolicyDecisionDeny, Reason: "denied"}
+		}
+		_ = json.NewEncoder(w).Encode(res)
+	}))
+	defer policyServer.Close()
+
+	require.NoError(t, os.Setenv("GOOD_BACKEND_RPC_URL", backend.URL()))
+	require.NoError(t, os.Setenv("REDIS_URL", fmt.Sprintf("redis://127.0.0.1:%s", redisServer.Port())))
+	require.NoError(t, os.Setenv("POLICY_URL", policyServer.URL))
+
+	config := ReadConfig("pending_tx_limit")
+	client := NewProxydClient("http://127.0.0.1:8545")
+	_, shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	raw := signPendingTestTx(t, key, 0)
+
+	// a transaction the policy service rejects doesn't hold its nonce
+	_, _, err = client.SendRequest(makeSendRawTransaction(hexutil.Encode(raw)))
+	require.NoError(t, err)
+	require.Equal(t, 0, len(backend.Requests()))
+	require.False(t, redisServer.Exists("proxyd:pending_txs:"+sender.Hex()))
+
+	deny.Store(false)
+	res, code, err := client.SendRequest(makeSendRawTransaction(hexutil.Encode(raw)))
+	require.NoError(t, err)
+	require.Equal(t, 200, code)
+	RequireEqualJSON(t, []byte(dummyRes), res)
+	members, err := redisServer.ZMembers("proxyd:pending_txs:" + sender.Hex())
+	require.NoError(t, err)
+	require.Equal(t, []string{"0"}, members)
+}
+
+func TestPendingTxLimitReleasesFailedTxs(t *testing.T) {
+	redisServer, err := miniredis.Run()
+	require.NoError(t, err)
+	defer redisServer.Close()
+
+	backend := NewMockBackend(SingleResponseHandler(200, nonceErrorResponse))
+	defer backend.Close()
+
+	policyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(proxyd.PolicyResponse{Decision: proxyd.PolicyDecisionAllow})
+	}))
+	defer policyServer.Close()
+
+	require.NoError(t, os.Setenv("GOOD_BACKEND_RPC_URL", backend.URL()))
+	require.NoError(t, os.Setenv("REDIS_URL", fmt.Sprintf("redis://127.0.0.1:%s", redisServer.Port())))
+	require.NoError(t, os.Setenv("POLICY_URL", policyServer.URL))
+
+	config := ReadConfig("pending_tx_limit")
+	client := NewProxydClient("http://127.0.0.1:8545")
+	_, shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+
+	// a transaction the backend rejects doesn't count against the cap
+	_, _, err = client.SendRequest(makeSendRawTransaction(hexutil.Encode(signPendingTestTx(t, key, 0))))
+	require.NoError(t, err)
+	require.Equal(t, 1, len(backend.Requests()))
+	require.False(t, redisServer.Exists("proxyd:pending_txs:"+sender.Hex()))
+
+	backend.SetHandler(SingleResponseHandler(200, dummyRes))
+	res, code, err := client.SendRequest(makeSendRawTransaction(hexutil.Encode(signPendingTestTx(t, key, 1))))
+	require.NoError(t, err)
+	require.Equal(t, 200, code)
+	RequireEqualJSON(t, []byte(dummyRes), res)
+	members, err := redisServer.ZMembers("proxyd:pending_txs:" + sender.Hex())
+	require.NoError(t, err)
+	require.Equal(t, []string{"1"}, members)
+}
+
+func signPendingTestTx(t *testing.T, key *ecdsa.PrivateKey, nonce uint64) []byte {
+	tx, err := types.SignNewTx(key, types.LatestSignerForChainID(big.NewInt(420)), &types.DynamicFeeTx{
+		ChainID:   big.NewInt(420),
+		Nonce:     nonce,
+		GasTipCap: big.NewInt(1),
+		GasFeeCap: big.NewInt(1),
+		Gas:       21000,
+		To:        &common.Address{1},
+	})
+	require.NoError(t, err)
+	raw, err := tx.MarshalBinary()
+	require.NoError(t, err)
+	return raw
+}
@@ -0,0 +1,72 @@
+package integration_tests
+
+import (
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ethereum-optimism/infra/proxyd"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimitExemptions(t *testing.T) {
+	goodBackend := NewMockBackend(SingleResponseHandler(200, dummyRes))
+	defer goodBackend.Close()
+
+	require.NoError(t, os.Setenv("GOOD_BACKEND_RPC_URL", goodBackend.URL()))
+
+	config := ReadConfig("rate_limit_exemptions")
+	_, shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+
+	client := NewProxydClient("http://127.0.0.1:8545")
+	setExemptions := func(body string) {
+		req, err := http.NewRequest(http.MethodPut, "http://127.0.0.1:9766/admin/exemptions", strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer admin")
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		res.Body.Close()
+		require.Equal(t, http.StatusNoContent, res.StatusCode)
+	}
+
+	t.Run("exempt cidr bypasses frontend and sender limits", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			_, code, err := client.SendRPC("eth_chainId", nil)
+			require.NoError(t, err)
+			require.Equal(t, 200, code)
+			res, code, err := client.SendRequest(makeSendRawTransaction(txHex1))
+			require.NoError(t, err)
+			require.Equal(t, 200, code)
+			RequireEqualJSON(t, []byte(dummyRes), res)
+		}
+	})
+
+	t.Run("exemptions are replaced at runtime", func(t *testing.T) {
+		setExemptions(`{"cidrs":["10.0.0.0/8"]}`)
+		codes := make(map[int]int)
+		for i := 0; i < 3; i++ {
+			_, code, err := client.SendRPC("eth_chainId", nil)
+			require.NoError(t, err)
+			codes[code]++
+		}
+		require.NotZero(t, codes[429])
+
+		setExemptions(`{"cidrs":["127.0.0.1/32"]}`)
+		_, code, err := client.SendRPC("eth_chainId", nil)
+		require.NoError(t, err)
+		require.Equal(t, 200, code)
+	})
+
+	t.Run("invalid exemptions are rejected", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPut, "http://127.0.0.1:9766/admin/exemptions", strings.NewReader(`{"signers":["nope"]}`))
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer admin")
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		res.Body.Close()
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+}
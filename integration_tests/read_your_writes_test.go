@@ -0,0 +1,64 @@
+package integration_tests
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ethereum-optimism/infra/proxyd"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadYourWrites(t *testing.T) {
+	firstBackend := NewMockBackend(SingleResponseHandler(503, "unavailable"))
+	defer firstBackend.Close()
+	secondBackend := NewMockBackend(SingleResponseHandler(200, dummyRes))
+	defer secondBackend.Close()
+
+	require.NoError(t, os.Setenv("FIRST_BACKEND_RPC_URL", firstBackend.URL()))
+	require.NoError(t, os.Setenv("SECOND_BACKEND_RPC_URL", secondBackend.URL()))
+
+	config := ReadConfig("read_your_writes")
+	client := NewProxydClient("http://127.0.0.1:8545")
+	_, shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+
+	tx := new(types.Transaction)
+	require.NoError(t, tx.UnmarshalBinary(hexutil.MustDecode(txHex1)))
+	from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	require.NoError(t, err)
+
+	// the second backend accepts the transaction
+	_, code, err := client.SendRequest(makeSendRawTransaction(txHex1))
+	require.NoError(t, err)
+	require.Equal(t, 200, code)
+	require.Equal(t, 1, len(secondBackend.Requests()))
+
+	firstBackend.Reset()
+	firstBackend.SetHandler(SingleResponseHandler(200, dummyRes))
+
+	// reads of the sender and the transaction follow it
+	_, code, err = client.SendRPC("eth_getTransactionCount", []interface{}{from.Hex(), "pending"})
+	require.NoError(t, err)
+	require.Equal(t, 200, code)
+	_, code, err = client.SendRPC("eth_getTransactionReceipt", []interface{}{tx.Hash().Hex()})
+	require.NoError(t, err)
+	require.Equal(t, 200, code)
+	require.Equal(t, 0, len(firstBackend.Requests()))
+	require.Equal(t, 3, len(secondBackend.Requests()))
+
+	// reads of other senders don't
+	_, _, err = client.SendRPC("eth_getTransactionCount", []interface{}{"0x0000000000000000000000000000000000000001", "pending"})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(firstBackend.Requests()))
+
+	// until the window passes
+	time.Sleep(1100 * time.Millisecond)
+	_, _, err = client.SendRPC("eth_getTransactionCount", []interface{}{from.Hex(), "pending"})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(firstBackend.Requests()))
+	require.Equal(t, 3, len(secondBackend.Requests()))
+}
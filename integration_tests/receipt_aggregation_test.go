@@ -0,0 +1,64 @@
+package integration_tests
+
+import (
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/ethereum-optimism/infra/proxyd"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReceiptAggregation(t *testing.T) {
+	firstRouter := NewBatchRPCResponseRouter()
+	firstRouter.SetFallbackRoute("eth_getTransactionReceipt", nil)
+	firstRouter.SetRoute("eth_getTransactionReceipt", "2", "receipt_2")
+	secondRouter := NewBatchRPCResponseRouter()
+	secondRouter.SetFallbackRoute("eth_getTransactionReceipt", nil)
+	thirdRouter := NewBatchRPCResponseRouter()
+	thirdRouter.SetFallbackRoute("eth_getTransactionReceipt", nil)
+	thirdRouter.SetRoute("eth_getTransactionReceipt", "1", "receipt_1")
+
+	firstBackend := NewMockBackend(firstRouter)
+	defer firstBackend.Close()
+	secondBackend := NewMockBackend(secondRouter)
+	defer secondBackend.Close()
+	thirdBackend := NewMockBackend(thirdRouter)
+	defer thirdBackend.Close()
+
+	require.NoError(t, os.Setenv("FIRST_BACKEND_RPC_URL", firstBackend.URL()))
+	require.NoError(t, os.Setenv("SECOND_BACKEND_RPC_URL", secondBackend.URL()))
+	require.NoError(t, os.Setenv("THIRD_BACKEND_RPC_URL", thirdBackend.URL()))
+
+	config := ReadConfig("receipt_aggregation")
+	client := NewProxydClient("http://127.0.0.1:8545")
+	_, shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+
+	// the receipt missing on the first backend is found on the third
+	res, code, err := client.SendRequest([]byte(`{"jsonrpc": "2.0", "method": "eth_getTransactionReceipt", "params": ["0x1"], "id": 1}`))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, code)
+	RequireEqualJSON(t, []byte(`{"jsonrpc": "2.0", "result": "receipt_1", "id": 1}`), res)
+	require.Equal(t, 1, len(firstBackend.Requests()))
+	require.Equal(t, 1, len(secondBackend.Requests()))
+	require.Equal(t, 1, len(thirdBackend.Requests()))
+
+	// receipts found on the serving backend aren't asked for again, and
+	// receipts no backend has stay null
+	res, code, err = client.SendBatchRPC(
+		NewRPCReq("1", "eth_getTransactionReceipt", []interface{}{"0x1"}),
+		NewRPCReq("2", "eth_getTransactionReceipt", []interface{}{"0x2"}),
+		NewRPCReq("3", "eth_getTransactionReceipt", []interface{}{"0x3"}),
+	)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, code)
+	RequireEqualJSON(t, []byte(`[
+		{"jsonrpc": "2.0", "result": "receipt_1", "id": 1},
+		{"jsonrpc": "2.0", "result": "receipt_2", "id": 2},
+		{"jsonrpc": "2.0", "result": null, "id": 3}
+	]`), res)
+	require.Equal(t, 2, thirdRouter.GetNumCalls("eth_getTransactionReceipt", "1"))
+	require.Equal(t, 1, firstRouter.GetNumCalls("eth_getTransactionReceipt", "2"))
+}
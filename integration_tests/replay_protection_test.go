@@ -0,0 +1,52 @@
+package integration_tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ethereum-optimism/infra/proxyd"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplayProtection(t *testing.T) {
+	backend := NewMockBackend(SingleResponseHandler(200, goodResponse))
+	defer backend.Close()
+
+	require.NoError(t, os.Setenv("GOOD_BACKEND_RPC_URL", backend.URL()))
+	require.NoError(t, os.Setenv("INDEXER_HMAC_KEY", "shared"))
+
+	config := ReadConfig("replay_protection")
+	_, shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+
+	body, err := json.Marshal(NewRPCReq("999", "eth_chainId", nil))
+	require.NoError(t, err)
+	send := func(signature string) (int, *proxyd.RPCErr) {
+		headers := make(http.Header)
+		headers.Set(proxyd.HMACAuthHeader, signature)
+		res, code, err := NewProxydClientWithHeaders("http://127.0.0.1:8545", headers).SendRequest(body)
+		require.NoError(t, err)
+		var rpcRes proxyd.RPCRes
+		require.NoError(t, json.Unmarshal(res, &rpcRes))
+		return code, rpcRes.Error
+	}
+
+	signature := proxyd.SignHMAC("indexer", []byte("shared"), time.Now(), body)
+	code, rpcErr := send(signature)
+	require.Equal(t, 200, code)
+	require.Nil(t, rpcErr)
+
+	code, rpcErr = send(signature)
+	require.Equal(t, 401, code)
+	require.Equal(t, proxyd.ErrReplayedSignature.Code, rpcErr.Code)
+
+	code, rpcErr = send(proxyd.SignHMAC("indexer", []byte("shared"), time.Now().Add(-time.Minute), body))
+	require.Equal(t, 401, code)
+	require.Equal(t, proxyd.ErrExpiredSignature.Code, rpcErr.Code)
+
+	require.Equal(t, 1, len(backend.Requests()))
+}
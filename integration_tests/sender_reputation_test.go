@@ -0,0 +1,56 @@
+package integration_tests
+
+import (
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/ethereum-optimism/infra/proxyd"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSenderReputationRoutesAfterSelectors(t *testing.T) {
+	goodBackend := NewMockBackend(SingleResponseHandler(200, dummyRes))
+	defer goodBackend.Close()
+	transfersBackend := NewMockBackend(SingleResponseHandler(200, dummyRes))
+	defer transfersBackend.Close()
+	lowBackend := NewMockBackend(SingleResponseHandler(200, dummyRes))
+	defer lowBackend.Close()
+
+	require.NoError(t, os.Setenv("GOOD_BACKEND_RPC_URL", goodBackend.URL()))
+	require.NoError(t, os.Setenv("TRANSFERS_BACKEND_RPC_URL", transfersBackend.URL()))
+	require.NoError(t, os.Setenv("LOW_BACKEND_RPC_URL", lowBackend.URL()))
+
+	config := ReadConfig("sender_reputation")
+	client := NewProxydClient("http://127.0.0.1:8545")
+	_, shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	tx, err := types.SignNewTx(key, types.LatestSignerForChainID(big.NewInt(420)), &types.DynamicFeeTx{
+		ChainID:   big.NewInt(420),
+		GasTipCap: big.NewInt(1),
+		GasFeeCap: big.NewInt(1),
+		Gas:       50000,
+		To:        &common.Address{1},
+		Data:      hexutil.MustDecode("0xa9059cbb"),
+	})
+	require.NoError(t, err)
+	raw, err := tx.MarshalBinary()
+	require.NoError(t, err)
+
+	// the low reputation group wins over the selector rule's
+	res, code, err := client.SendRequest(makeSendRawTransaction(hexutil.Encode(raw)))
+	require.NoError(t, err)
+	require.Equal(t, 200, code)
+	RequireEqualJSON(t, []byte(dummyRes), res)
+	require.Equal(t, 1, len(lowBackend.Requests()))
+	require.Equal(t, 0, len(transfersBackend.Requests()))
+	require.Equal(t, 0, len(goodBackend.Requests()))
+}
@@ -0,0 +1,70 @@
+package integration_tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/ethereum-optimism/infra/proxyd"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignerClasses(t *testing.T) {
+	mainBackend := NewMockBackend(SingleResponseHandler(200, goodResponse))
+	defer mainBackend.Close()
+	goldBackend := NewMockBackend(SingleResponseHandler(200, goodResponse))
+	defer goldBackend.Close()
+
+	require.NoError(t, os.Setenv("MAIN_BACKEND_RPC_URL", mainBackend.URL()))
+	require.NoError(t, os.Setenv("GOLD_BACKEND_RPC_URL", goldBackend.URL()))
+
+	config := ReadConfig("signer_classes")
+	_, shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+
+	privKey, err := crypto.HexToECDSA(testFlashbotsPrivKeyHex)
+	require.NoError(t, err)
+	addr := crypto.PubkeyToAddress(privKey.PublicKey)
+	body, err := json.Marshal(NewRPCReq("1", "eth_chainId", nil))
+	require.NoError(t, err)
+	sig, err := crypto.Sign(accounts.TextHash([]byte(crypto.Keccak256Hash(body).Hex())), privKey)
+	require.NoError(t, err)
+
+	send := func(xff string, signed bool) int {
+		headers := http.Header{"X-Forwarded-For": []string{xff}}
+		if signed {
+			headers.Set(proxyd.FlashbotsAuthHeader, fmt.Sprintf("%s:%s", addr.Hex(), hexutil.Encode(sig)))
+		}
+		_, code, err := NewProxydClientWithHeaders("http://127.0.0.1:8545", headers).SendRequest(body)
+		require.NoError(t, err)
+		return code
+	}
+
+	t.Run("unsigned requests use the top-level mappings", func(t *testing.T) {
+		mainBackend.Reset()
+		goldBackend.Reset()
+		require.Equal(t, 200, send("10.0.0.1", false))
+		require.Equal(t, 1, len(mainBackend.Requests()))
+		require.Equal(t, 0, len(goldBackend.Requests()))
+	})
+
+	t.Run("signer's class routes and rate limits per signer", func(t *testing.T) {
+		mainBackend.Reset()
+		goldBackend.Reset()
+		codes := make(map[int]int)
+		// the signer's budget is shared across IPs
+		for i := 0; i < 4; i++ {
+			codes[send(fmt.Sprintf("10.0.1.%d", i), true)]++
+		}
+		require.Equal(t, 2, codes[200])
+		require.Equal(t, 2, codes[429])
+		require.Equal(t, 0, len(mainBackend.Requests()))
+		require.Equal(t, 2, len(goldBackend.Requests()))
+	})
+}
@@ -0,0 +1,56 @@
+package integration_tests
+
+import (
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/ethereum-optimism/infra/proxyd"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStrictJSONRPC(t *testing.T) {
+	backend := NewMockBackend(BatchedResponseHandler(200, goodResponse))
+	defer backend.Close()
+
+	require.NoError(t, os.Setenv("GOOD_BACKEND_RPC_URL", backend.URL()))
+
+	config := ReadConfig("strict_jsonrpc")
+	client := NewProxydClient("http://127.0.0.1:8545")
+
+	t.Run("violations are rejected", func(t *testing.T) {
+		_, shutdown, err := proxyd.Start(config)
+		require.NoError(t, err)
+		defer shutdown()
+
+		backend.Reset()
+		res, code, err := client.SendRequest([]byte(`{"jsonrpc":"2.0","method":"eth_chainId","method":"eth_getBalance","id":1}`))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, code)
+		RequireEqualJSON(t, []byte(`{"jsonrpc":"2.0","error":{"code":-32600,"message":"duplicate key \"method\""},"id":null}`), res)
+
+		res, code, err = client.SendRequest([]byte(`{"jsonrpc":"2.0","method":"eth_getBalance","params":{"address":"0x0"},"id":1}`))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, code)
+		RequireEqualJSON(t, []byte(`{"jsonrpc":"2.0","error":{"code":-32602,"message":"eth_getBalance params must be an array"},"id":null}`), res)
+
+		res, code, err = client.SendRequest([]byte(`[{"jsonrpc":"2.0","method":"eth_chainId","id":999},{"jsonrpc":"2.0","method":"eth_chainId","id":true}]`))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, code)
+		RequireEqualJSON(t, []byte(`[{"jsonrpc":"2.0","result":"hello","id":999},{"jsonrpc":"2.0","error":{"code":-32600,"message":"id must be a string, number or null"},"id":null}]`), res)
+		require.Equal(t, 1, len(backend.Requests()))
+	})
+
+	t.Run("violations are forwarded in warn only mode", func(t *testing.T) {
+		config.StrictJSONRPC.WarnOnly = true
+		_, shutdown, err := proxyd.Start(config)
+		require.NoError(t, err)
+		defer shutdown()
+
+		backend.Reset()
+		_, code, err := client.SendRequest([]byte(`{"jsonrpc":"2.0","method":"eth_getBalance","params":{"address":"0x0"},"id":1}`))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, 1, len(backend.Requests()))
+	})
+}
@@ -0,0 +1,106 @@
+package integration_tests
+
+import (
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/ethereum-optimism/infra/proxyd"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTenants(t *testing.T) {
+	sharedBackend := NewMockBackend(SingleResponseHandler(200, goodResponse))
+	defer sharedBackend.Close()
+	acmeBackend := NewMockBackend(SingleResponseHandler(200, goodResponse))
+	defer acmeBackend.Close()
+	archiveBackend := NewMockBackend(SingleResponseHandler(200, goodResponse))
+	defer archiveBackend.Close()
+
+	require.NoError(t, os.Setenv("SHARED_BACKEND_RPC_URL", sharedBackend.URL()))
+	require.NoError(t, os.Setenv("ACME_BACKEND_RPC_URL", acmeBackend.URL()))
+	require.NoError(t, os.Setenv("ARCHIVE_BACKEND_RPC_URL", archiveBackend.URL()))
+
+	config := ReadConfig("tenants")
+	_, shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+
+	sendChainID := func(t *testing.T, client *ProxydHTTPClient) int {
+		_, code, err := client.SendRPC("eth_chainId", nil)
+		require.NoError(t, err)
+		return code
+	}
+
+	t.Run("requests without a tenant use the top-level mappings", func(t *testing.T) {
+		sharedBackend.Reset()
+		acmeBackend.Reset()
+		require.Equal(t, 200, sendChainID(t, NewProxydClient("http://127.0.0.1:8545")))
+		require.Equal(t, 1, len(sharedBackend.Requests()))
+		require.Equal(t, 0, len(acmeBackend.Requests()))
+	})
+
+	// distinct IPs keep the tenant's rate limit out of the way
+	tenantClients := map[string]*ProxydHTTPClient{
+		"api key header": NewProxydClientWithHeaders("http://127.0.0.1:8545", http.Header{
+			proxyd.APIKeyHeader: []string{"acme-key"},
+			"X-Forwarded-For":   []string{"10.0.0.1"},
+		}),
+		"api key path": NewProxydClientWithHeaders("http://127.0.0.1:8545/acme-key", http.Header{
+			"X-Forwarded-For": []string{"10.0.0.2"},
+		}),
+		"host": NewProxydClientWithHeaders("http://localhost:8545", http.Header{
+			"X-Forwarded-For": []string{"10.0.0.3"},
+		}),
+	}
+	for name, client := range tenantClients {
+		t.Run("tenant selected by "+name, func(t *testing.T) {
+			sharedBackend.Reset()
+			acmeBackend.Reset()
+			require.Equal(t, 200, sendChainID(t, client))
+			require.Equal(t, 0, len(sharedBackend.Requests()))
+			require.Equal(t, 1, len(acmeBackend.Requests()))
+
+			// the top-level mappings don't apply
+			res, _, err := client.SendRPC("eth_blockNumber", nil)
+			require.NoError(t, err)
+			RequireEqualJSON(t, []byte(`{"jsonrpc":"2.0","error":{"code":-32601,"message":"rpc method is not whitelisted"},"id":999}`), res)
+		})
+	}
+
+	t.Run("routing rules apply to tenant requests", func(t *testing.T) {
+		client := NewProxydClientWithHeaders("http://127.0.0.1:8545", http.Header{
+			proxyd.APIKeyHeader: []string{"acme-key"},
+			"X-Forwarded-For":   []string{"10.0.0.4"},
+		})
+		sharedBackend.Reset()
+		acmeBackend.Reset()
+		archiveBackend.Reset()
+		_, code, err := client.SendRPC("eth_getBlockByNumber", []interface{}{"0x0", false})
+		require.NoError(t, err)
+		require.Equal(t, 200, code)
+		_, code, err = client.SendRPC("eth_getBlockByNumber", []interface{}{"0x1", false})
+		require.NoError(t, err)
+		require.Equal(t, 200, code)
+		require.Equal(t, 1, len(archiveBackend.Requests()))
+		require.Equal(t, 1, len(acmeBackend.Requests()))
+		require.Equal(t, 0, len(sharedBackend.Requests()))
+	})
+
+	t.Run("tenants have their own rate limits", func(t *testing.T) {
+		client := NewProxydClientWithHeaders("http://127.0.0.1:8545", http.Header{
+			proxyd.APIKeyHeader: []string{"acme-key"},
+			"X-Forwarded-For":   []string{"1.2.3.4"},
+		})
+		codes := make([]int, 0, 3)
+		for i := 0; i < 3; i++ {
+			codes = append(codes, sendChainID(t, client))
+		}
+		require.Equal(t, []int{200, 200, 429}, codes)
+
+		shared := NewProxydClientWithHeaders("http://127.0.0.1:8545", http.Header{
+			"X-Forwarded-For": []string{"1.2.3.4"},
+		})
+		require.Equal(t, 200, sendChainID(t, shared))
+	})
+}
@@ -0,0 +1,51 @@
+package integration_tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/ethereum-optimism/infra/proxyd"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrafficAnalysis(t *testing.T) {
+	goodBackend := NewMockBackend(SingleResponseHandler(200, dummyRes))
+	defer goodBackend.Close()
+
+	require.NoError(t, os.Setenv("GOOD_BACKEND_RPC_URL", goodBackend.URL()))
+
+	config := ReadConfig("traffic_analysis")
+	_, shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+
+	client := NewProxydClient("http://127.0.0.1:8545")
+	for i := 0; i < 3; i++ {
+		_, code, err := client.SendRPC("eth_call", []interface{}{
+			map[string]string{"to": "0x0000000000000000000000000000000000000001"},
+			"latest",
+		})
+		require.NoError(t, err)
+		require.Equal(t, 200, code)
+	}
+	// blocked methods count too
+	_, _, err = client.SendRPC("eth_notWhitelisted", nil)
+	require.NoError(t, err)
+
+	req, err := http.NewRequest(http.MethodGet, "http://127.0.0.1:9769/admin/top?k=1", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer admin")
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, 200, res.StatusCode)
+
+	var top proxyd.TrafficTop
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&top))
+	require.Equal(t, []proxyd.TopItem{{Item: "eth_call", Count: 3}}, top.Methods)
+	require.Equal(t, []proxyd.TopItem{{Item: "127.0.0.1", Count: 4}}, top.IPs)
+	require.Empty(t, top.Keys)
+	require.Equal(t, []proxyd.TopItem{{Item: "0x0000000000000000000000000000000000000001", Count: 3}}, top.Contracts)
+}
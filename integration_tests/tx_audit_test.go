@@ -0,0 +1,62 @@
+package integration_tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum-optimism/infra/proxyd"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTxAudit(t *testing.T) {
+	goodBackend := NewMockBackend(SingleResponseHandler(200, dummyRes))
+	defer goodBackend.Close()
+
+	require.NoError(t, os.Setenv("GOOD_BACKEND_RPC_URL", goodBackend.URL()))
+
+	config := ReadConfig("tx_audit")
+	config.TxAudit.File = filepath.Join(t.TempDir(), "txs.log")
+	_, shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+
+	client := NewProxydClient("http://127.0.0.1:8545")
+	_, code, err := client.SendRequest(makeSendRawTransaction(txHex1))
+	require.NoError(t, err)
+	require.Equal(t, 200, code)
+	_, code, err = client.SendRPC("eth_chainId", nil)
+	require.NoError(t, err)
+	require.Equal(t, 200, code)
+
+	query := func(params string) []*proxyd.TxAuditRecord {
+		req, err := http.NewRequest(http.MethodGet, "http://127.0.0.1:9767/admin/txs"+params, nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer admin")
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		var recs []*proxyd.TxAuditRecord
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&recs))
+		return recs
+	}
+
+	var recs []*proxyd.TxAuditRecord
+	require.Eventually(t, func() bool {
+		recs = query("?ip=127.0.0.1")
+		return len(recs) == 1
+	}, time.Second, 10*time.Millisecond)
+	require.Equal(t, "eth_sendRawTransaction", recs[0].Method)
+	require.Equal(t, "main/good", recs[0].Backend)
+	require.Equal(t, "dummy", recs[0].Result)
+	require.NotEmpty(t, recs[0].TxHash)
+	require.NotEmpty(t, recs[0].Sender)
+
+	require.Len(t, query("?hash="+recs[0].TxHash), 1)
+	require.Len(t, query("?sender="+recs[0].Sender), 1)
+	require.Empty(t, query("?key=partner"))
+}
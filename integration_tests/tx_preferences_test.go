@@ -0,0 +1,80 @@
+package integration_tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/ethereum-optimism/infra/proxyd"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTxPreferences(t *testing.T) {
+	var mu sync.Mutex
+	var queries []string
+	goodBackend := NewMockBackend(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		queries = append(queries, r.URL.RawQuery)
+		mu.Unlock()
+		SingleResponseHandler(200, dummyRes)(w, r)
+	}))
+	defer goodBackend.Close()
+
+	require.NoError(t, os.Setenv("GOOD_BACKEND_RPC_URL", goodBackend.URL()))
+
+	config := ReadConfig("tx_preferences")
+	_, shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+
+	client := NewProxydClient("http://127.0.0.1:8545/wallet-secret")
+	lastQuery := func() string {
+		mu.Lock()
+		defer mu.Unlock()
+		return queries[len(queries)-1]
+	}
+	sendTx := func(client *ProxydHTTPClient) {
+		t.Helper()
+		_, code, err := client.SendRequest(makeSendRawTransaction(txHex1))
+		require.NoError(t, err)
+		require.Equal(t, 200, code)
+	}
+
+	// nothing stored yet
+	res, code, err := client.SendRPC(proxyd.TxPreferencesGetMethod, nil)
+	require.NoError(t, err)
+	require.Equal(t, 200, code)
+	RequireEqualJSON(t, []byte(`{"jsonrpc":"2.0","result":null,"id":999}`), res)
+	sendTx(client)
+	require.Equal(t, "", lastQuery())
+
+	prefs := map[string]interface{}{"builders": []string{"flashbots", "titan"}, "hints": []string{"hash"}, "fast": true}
+	res, code, err = client.SendRPC(proxyd.TxPreferencesSetMethod, []interface{}{prefs})
+	require.NoError(t, err)
+	require.Equal(t, 200, code)
+	RequireEqualJSON(t, []byte(`{"jsonrpc":"2.0","result":true,"id":999}`), res)
+
+	res, _, err = client.SendRPC(proxyd.TxPreferencesGetMethod, nil)
+	require.NoError(t, err)
+	var rpcRes struct {
+		Result proxyd.TxPreferences `json:"result"`
+	}
+	require.NoError(t, json.Unmarshal(res, &rpcRes))
+	require.Equal(t, []string{"flashbots", "titan"}, rpcRes.Result.Builders)
+
+	// submissions without a query string get the stored preferences
+	sendTx(client)
+	require.Equal(t, "builder=flashbots&builder=titan&fast=true&hint=hash", lastQuery())
+
+	// a query string of the request's own wins
+	sendTx(NewProxydClient("http://127.0.0.1:8545/wallet-secret?hint=calldata"))
+	require.Equal(t, "hint=calldata", lastQuery())
+
+	// null clears the preferences
+	_, _, err = client.SendRPC(proxyd.TxPreferencesSetMethod, []interface{}{nil})
+	require.NoError(t, err)
+	sendTx(client)
+	require.Equal(t, "", lastQuery())
+}
@@ -16,6 +16,7 @@ const (
 	invalidJSONRPCVersionResponse = `{"error":{"code":-32600,"message":"invalid JSON-RPC version"},"id":null,"jsonrpc":"2.0"}`
 	invalidIDResponse             = `{"error":{"code":-32600,"message":"invalid ID"},"id":null,"jsonrpc":"2.0"}`
 	invalidMethodResponse         = `{"error":{"code":-32600,"message":"no method specified"},"id":null,"jsonrpc":"2.0"}`
+	invalidMethodNameResponse     = `{"error":{"code":-32600,"message":"invalid method name"},"id":null,"jsonrpc":"2.0"}`
 	invalidBatchLenResponse       = `{"error":{"code":-32600,"message":"must specify at least one batch call"},"id":null,"jsonrpc":"2.0"}`
 )
 
@@ -85,6 +86,12 @@ func TestSingleRPCValidation(t *testing.T) {
 			invalidMethodResponse,
 			400,
 		},
+		{
+			"absurd method name",
+			"{\"jsonrpc\": \"2.0\", \"method\": \"eth_call\\nlvl=info msg=forged\", \"params\": [42, 23], \"id\": 1}",
+			invalidMethodNameResponse,
+			400,
+		},
 		{
 			"not whitelisted method",
 			"{\"jsonrpc\": \"2.0\", \"method\": \"subtract\", \"params\": [42, 23], \"id\": 999}",
@@ -0,0 +1,51 @@
+package integration_tests
+
+import (
+	"os"
+	"testing"
+
+	"github.com/ethereum-optimism/infra/proxyd"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVirtualHosts(t *testing.T) {
+	mainnetBackend := NewMockBackend(SingleResponseHandler(200, goodResponse))
+	defer mainnetBackend.Close()
+	sepoliaBackend := NewMockBackend(SingleResponseHandler(200, goodResponse))
+	defer sepoliaBackend.Close()
+
+	require.NoError(t, os.Setenv("MAINNET_BACKEND_RPC_URL", mainnetBackend.URL()))
+	require.NoError(t, os.Setenv("SEPOLIA_BACKEND_RPC_URL", sepoliaBackend.URL()))
+
+	config := ReadConfig("virtual_hosts")
+	_, shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+
+	t.Run("other hosts use the top-level mappings", func(t *testing.T) {
+		mainnetBackend.Reset()
+		sepoliaBackend.Reset()
+		_, code, err := NewProxydClient("http://127.0.0.1:8545").SendRPC("eth_chainId", nil)
+		require.NoError(t, err)
+		require.Equal(t, 200, code)
+		require.Equal(t, 1, len(mainnetBackend.Requests()))
+		require.Equal(t, 0, len(sepoliaBackend.Requests()))
+	})
+
+	t.Run("virtual host uses its own mappings", func(t *testing.T) {
+		mainnetBackend.Reset()
+		sepoliaBackend.Reset()
+		client := NewProxydClient("http://localhost:8545")
+		_, code, err := client.SendRPC("eth_chainId", nil)
+		require.NoError(t, err)
+		require.Equal(t, 200, code)
+		require.Equal(t, 0, len(mainnetBackend.Requests()))
+		require.Equal(t, 1, len(sepoliaBackend.Requests()))
+
+		// methods the virtual host doesn't map aren't served
+		_, code, err = client.SendRPC("eth_blockNumber", nil)
+		require.NoError(t, err)
+		require.Equal(t, 403, code)
+		require.Equal(t, 0, len(mainnetBackend.Requests()))
+	})
+}
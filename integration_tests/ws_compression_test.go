@@ -0,0 +1,68 @@
+package integration_tests
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum-optimism/infra/proxyd"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWSCompression(t *testing.T) {
+	logs := fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"result":"%s"}`, strings.Repeat("0x00", 1024))
+
+	upstreamExtensions := make(chan string, 1)
+	upgrader := websocket.Upgrader{EnableCompression: true}
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamExtensions <- r.Header.Get("Sec-WebSocket-Extensions")
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(logs)); err != nil {
+				return
+			}
+		}
+	}))
+	defer backend.Close()
+
+	require.NoError(t, os.Setenv("GOOD_BACKEND_RPC_URL", strings.Replace(backend.URL, "http://", "ws://", 1)))
+
+	config := ReadConfig("ws_compression")
+	_, shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+
+	dialer := websocket.Dialer{EnableCompression: true}
+	conn, res, err := dialer.Dial("ws://127.0.0.1:8546", nil) // nolint:bodyclose
+	require.NoError(t, err)
+	defer conn.Close()
+	require.Contains(t, res.Header.Get("Sec-WebSocket-Extensions"), "permessage-deflate")
+
+	require.NoError(t, conn.WriteMessage(
+		websocket.TextMessage,
+		[]byte(`{"id": 1, "method": "eth_subscribe", "params": ["logs"]}`),
+	))
+	select {
+	case ext := <-upstreamExtensions:
+		require.Contains(t, ext, "permessage-deflate")
+	case <-time.After(5 * time.Second):
+		t.Fatal("backend wasn't dialed")
+	}
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	_, msg, err := conn.ReadMessage()
+	require.NoError(t, err)
+	require.Equal(t, logs, string(msg))
+}
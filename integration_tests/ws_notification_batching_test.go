@@ -0,0 +1,67 @@
+package integration_tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ethereum-optimism/infra/proxyd"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWSNotificationBatching(t *testing.T) {
+	backend := NewMockWSBackend(nil, func(conn *websocket.Conn, msgType int, data []byte) {
+		var req proxyd.RPCReq
+		require.NoError(t, json.Unmarshal(data, &req))
+		require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,"result":"0x1"}`, req.ID))))
+		for _, number := range []string{"0x10", "0x11", "0x12"} {
+			require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf(
+				`{"jsonrpc":"2.0","method":"eth_subscription","params":{"subscription":"0x1","result":{"number":"%s"}}}`, number))))
+		}
+	}, nil)
+	defer backend.Close()
+
+	require.NoError(t, os.Setenv("GOOD_BACKEND_RPC_URL", backend.URL()))
+
+	config := ReadConfig("ws_notification_batching")
+	_, shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+
+	subscribe := func(url string, n int) []string {
+		received := make(chan string, 4)
+		client, err := NewProxydWSClient(url, func(msgType int, data []byte) {
+			received <- string(data)
+		}, nil)
+		require.NoError(t, err)
+		defer client.HardClose()
+		require.NoError(t, client.WriteMessage(websocket.TextMessage, []byte(`{"jsonrpc":"2.0","id":1,"method":"eth_subscribe","params":["newHeads"]}`)))
+
+		var msgs []string
+		for len(msgs) < n {
+			select {
+			case msg := <-received:
+				msgs = append(msgs, msg)
+			case <-time.After(10 * time.Second):
+				t.Fatalf("timed out after %d messages", len(msgs))
+			}
+		}
+		return msgs
+	}
+
+	// clients that opt in get the notifications in one batch
+	msgs := subscribe("ws://127.0.0.1:8546?batch_notifications=true", 2)
+	require.Equal(t, `{"jsonrpc":"2.0","id":1,"result":"0x1"}`, msgs[0])
+	require.JSONEq(t, `[
+		{"jsonrpc":"2.0","method":"eth_subscription","params":{"subscription":"0x1","result":{"number":"0x10"}}},
+		{"jsonrpc":"2.0","method":"eth_subscription","params":{"subscription":"0x1","result":{"number":"0x11"}}},
+		{"jsonrpc":"2.0","method":"eth_subscription","params":{"subscription":"0x1","result":{"number":"0x12"}}}
+	]`, msgs[1])
+
+	// others one by one
+	msgs = subscribe("ws://127.0.0.1:8546", 4)
+	require.JSONEq(t, `{"jsonrpc":"2.0","method":"eth_subscription","params":{"subscription":"0x1","result":{"number":"0x12"}}}`, msgs[3])
+}
@@ -0,0 +1,90 @@
+package integration_tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum-optimism/infra/proxyd"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWSReplay(t *testing.T) {
+	var subs atomic.Int32
+	forwarded := make(chan string, 3)
+	// heads by subscription, the second one being proxyd's own once the
+	// client is gone
+	heads := map[string][]string{
+		"0x1": {"0x10", "0x11", "0x12"},
+		"0x2": {"0x13"},
+	}
+	kept := make(chan struct{})
+	backend := NewMockWSBackend(nil, func(conn *websocket.Conn, msgType int, data []byte) {
+		var req proxyd.RPCReq
+		require.NoError(t, json.Unmarshal(data, &req))
+		forwarded <- string(req.Params)
+		sub := fmt.Sprintf("0x%d", subs.Add(1))
+		require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,"result":"%s"}`, req.ID, sub))))
+		for _, number := range heads[sub] {
+			require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf(
+				`{"jsonrpc":"2.0","method":"eth_subscription","params":{"subscription":"%s","result":{"number":"%s"}}}`, sub, number))))
+		}
+		if sub == "0x2" {
+			close(kept)
+		}
+	}, nil)
+	defer backend.Close()
+
+	require.NoError(t, os.Setenv("GOOD_BACKEND_RPC_URL", backend.URL()))
+
+	config := ReadConfig("ws_replay")
+	_, shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+
+	subscribe := func(req string, n int) []string {
+		received := make(chan string, n)
+		client, err := NewProxydWSClient("ws://127.0.0.1:8546", func(msgType int, data []byte) {
+			received <- string(data)
+		}, nil)
+		require.NoError(t, err)
+		defer client.HardClose()
+		require.NoError(t, client.WriteMessage(websocket.TextMessage, []byte(req)))
+
+		var msgs []string
+		for len(msgs) < n {
+			select {
+			case msg := <-received:
+				msgs = append(msgs, msg)
+			case <-time.After(10 * time.Second):
+				t.Fatalf("timed out after %d messages", len(msgs))
+			}
+		}
+		return msgs
+	}
+
+	msgs := subscribe(`{"jsonrpc":"2.0","id":1,"method":"eth_subscribe","params":["newHeads"]}`, 4)
+	require.Equal(t, `{"jsonrpc":"2.0","id":1,"result":"0x1"}`, msgs[0])
+	require.Equal(t, `["newHeads"]`, <-forwarded)
+
+	// heads keep being recorded once the client is gone
+	select {
+	case <-kept:
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for proxyd to keep recording")
+	}
+	require.Equal(t, `["newHeads"]`, <-forwarded)
+	time.Sleep(100 * time.Millisecond)
+
+	// the client resubscribing with a cursor gets the kept heads after it,
+	// for its new subscription
+	msgs = subscribe(`{"jsonrpc":"2.0","id":2,"method":"eth_subscribe","params":["newHeads",{"replayFrom":{"blockNumber":"0x11"}}]}`, 3)
+	require.Equal(t, `["newHeads"]`, <-forwarded)
+	require.Equal(t, `{"jsonrpc":"2.0","id":2,"result":"0x3"}`, msgs[0])
+	require.JSONEq(t, `{"jsonrpc":"2.0","method":"eth_subscription","params":{"subscription":"0x3","result":{"number":"0x12"}}}`, msgs[1])
+	require.JSONEq(t, `{"jsonrpc":"2.0","method":"eth_subscription","params":{"subscription":"0x3","result":{"number":"0x13"}}}`, msgs[2])
+}
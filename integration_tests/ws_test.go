@@ -122,6 +122,12 @@ func TestWS(t *testing.T) {
 			"{\"jsonrpc\":\"2.0\",\"result\":[],\"id\":1}",
 			"{\"jsonrpc\": \"2.0\", \"method\": \"eth_accounts\", \"id\": 1}",
 		},
+		{
+			"removed RPC",
+			"}",
+			"{\"jsonrpc\":\"2.0\",\"error\":{\"code\":-32044,\"message\":\"rpc method has been removed: mining is no longer supported\",\"data\":{\"cutover\":\"2020-01-01T00:00:00Z\"}},\"id\":1}",
+			"{\"id\": 1, \"method\": \"eth_getWork\", \"params\": []}",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
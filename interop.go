@@ -3,8 +3,10 @@ package proxyd
 import (
 	"context"
 	"fmt"
+	"regexp"
 	"time"
 
+	"github.com/ethereum-optimism/optimism/op-service/eth"
 	supervisorTypes "github.com/ethereum-optimism/optimism/op-supervisor/supervisor/types"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -56,10 +58,200 @@ func getInteropExecutingDescriptorTimestamp() uint64 {
 	return uint64(time.Now().Unix() + 1000)
 }
 
-func (s *Server) rateLimitInteropSender(ctx context.Context, tx *types.Transaction) error {
+// rateLimitInteropSender takes from the limiter of every chain the messages
+// in the access list come from. Chains without a limit of their own share the
+// default interop sender limit.
+func (s *Server) rateLimitInteropSender(ctx context.Context, tx *types.Transaction, interopAccessList []common.Hash) error {
 	if s.interopSenderLim == nil {
 		log.Warn("interop sender rate limiter is not enabled, skipping", "req_id", GetReqID(ctx))
 		return nil
 	}
-	return s.genericRateLimitSender(ctx, tx, s.interopSenderLim)
+
+	lims := make([]FrontendRateLimiter, 0, 1)
+	useDefault := len(s.interopSenderChainLims) == 0
+	if !useDefault {
+		seen := make(map[eth.ChainID]bool)
+		for _, chainID := range interopAccessListChainIDs(interopAccessList) {
+			if seen[chainID] {
+				continue
+			}
+			seen[chainID] = true
+			if lim, ok := s.interopSenderChainLims[chainID]; ok {
+				lims = append(lims, lim)
+			} else {
+				useDefault = true
+			}
+		}
+		// malformed access lists are rejected by validation later on
+		if len(seen) == 0 {
+			useDefault = true
+		}
+	}
+	if useDefault {
+		lims = append(lims, s.interopSenderLim)
+	}
+
+	for _, lim := range lims {
+		if err := s.genericRateLimitSender(ctx, tx, lim); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// interopAccessListChainIDs returns the chain IDs of the messages in the
+// access list, up to the first entry that can't be parsed.
+func interopAccessListChainIDs(entries []common.Hash) []eth.ChainID {
+	var chainIDs []eth.ChainID
+	for len(entries) > 0 {
+		remaining, access, err := supervisorTypes.ParseAccess(entries)
+		if err != nil {
+			break
+		}
+		chainIDs = append(chainIDs, access.ChainID)
+		entries = remaining
+	}
+	return chainIDs
+}
+
+// Reasons interop validation rejects transactions for. They label
+// interop_validations_total and are sent in the data of rejection errors, so
+// rejections can be told apart from other errors.
+const (
+	InteropReasonInvalidMessage        = "invalid_message"
+	InteropReasonFutureData            = "future_data"
+	InteropReasonUnknownChain          = "unknown_chain"
+	InteropReasonOutOfScope            = "out_of_scope"
+	InteropReasonSupervisorState       = "supervisor_state"
+	InteropReasonMalformedAccessList   = "malformed_access_list"
+	InteropReasonAccessListTooLarge    = "access_list_too_large"
+	InteropReasonRequestTooLarge       = "request_too_large"
+	InteropReasonRateLimited           = "rate_limited"
+	InteropReasonSupervisorUnavailable = "supervisor_unavailable"
+	InteropReasonOther                 = "other"
+)
+
+// interopRejectionReasons maps the codes of interopRPCErrorMap, and of the
+// access list checks of the strategies, to rejection reasons.
+var interopRejectionReasons = map[int]string{
+	-320400:                              InteropReasonSupervisorState, // uninitialized
+	-320500:                              InteropReasonFutureData,      // skipped
+	-320501:                              InteropReasonUnknownChain,
+	-320600:                              InteropReasonInvalidMessage,  // conflict
+	-320601:                              InteropReasonInvalidMessage,  // ineffective
+	-320900:                              InteropReasonFutureData,      // out of order
+	-320901:                              InteropReasonFutureData,      // awaiting replacement block
+	-321000:                              InteropReasonSupervisorState, // stopped
+	-321100:                              InteropReasonOutOfScope,
+	-321200:                              InteropReasonSupervisorState, // before the first block in the db
+	-321401:                              InteropReasonFutureData,
+	-321500:                              InteropReasonSupervisorState, // missed data
+	-321501:                              InteropReasonSupervisorState, // data corruption
+	-32602:                               InteropReasonMalformedAccessList,
+	ErrInteropAccessListOutOfBounds.Code: InteropReasonAccessListTooLarge,
+}
+
+// interopRejectionReason categorizes an error of interop validation. Errors
+// that aren't verdicts about the access list mean no supervisor could be
+// used.
+func interopRejectionReason(err error) string {
+	rpcErr, ok := err.(*RPCErr)
+	if !ok || !isInteropVerdict(err) {
+		return InteropReasonSupervisorUnavailable
+	}
+	if reason, ok := interopRejectionReasons[rpcErr.Code]; ok {
+		return reason
+	}
+	return InteropReasonOther
+}
+
+// interopRejection records the rejection of an interop transaction for err,
+// and returns the error answering it, whose data holds the reason.
+func interopRejection(err error, reason string) error {
+	RecordInteropValidation("rejected", reason)
+
+	var rpcErr *RPCErr
+	if rr, ok := err.(*RPCErr); ok {
+		rpcErr = rr.Clone()
+	} else {
+		rpcErr = &RPCErr{
+			Code:    JSONRPCErrorInternal,
+			Message: err.Error(),
+		}
+	}
+	data := map[string]interface{}{"reason": reason}
+	if rr, ok := err.(*RPCErr); ok && len(rr.Data) > 0 {
+		data["supervisor_data"] = rr.Data
+	}
+	rpcErr.Data = mustMarshalJSON(data)
+	return rpcErr
+}
+
+func compileOriginPatterns(origins []string) ([]*regexp.Regexp, error) {
+	patterns := make([]*regexp.Regexp, 0, len(origins))
+	for _, origin := range origins {
+		pattern, err := regexp.Compile(origin)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
+
+// isInteropAdvisory reports whether interop validation only records its
+// result for the request instead of rejecting invalid transactions.
+func (s *Server) isInteropAdvisory(ctx context.Context) bool {
+	origin, _ := ctx.Value(ContextKeyOrigin).(string)
+	for _, pat := range s.interopEnforcedOrigins {
+		if pat.MatchString(origin) {
+			return false
+		}
+	}
+	for _, pat := range s.interopAdvisoryOrigins {
+		if pat.MatchString(origin) {
+			return true
+		}
+	}
+	return s.interopConfig().Mode == InteropValidationAdvisory
+}
+
+// interopConfig returns the interop validation settings in effect, which may
+// be overridden at runtime, see InteropAdmin.
+func (s *Server) interopConfig() InteropValidationConfig {
+	if s.interopAdmin == nil {
+		return s.interopValidatingConfig
+	}
+	return s.interopAdmin.Config()
+}
+
+// validateInteropAdvisory validates the access list in the background and
+// records the verdict, so the false positive rate can be measured before
+// validation is enforced.
+func (s *Server) validateInteropAdvisory(ctx context.Context, tx *types.Transaction, interopAccessList []common.Hash) {
+	select {
+	case s.interopAdvisorySem <- struct{}{}:
+	default:
+		log.Warn("too many advisory interop validations in flight, skipping", "req_id", GetReqID(ctx), "tx_hash", tx.Hash())
+		RecordInteropAdvisoryValidation("skipped")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), interopAdvisoryValidationTimeout)
+	go func() {
+		defer func() { <-s.interopAdvisorySem }()
+		defer cancel()
+
+		err := s.interopStrategy.ValidateAccessList(ctx, interopAccessList)
+		switch {
+		case err == nil:
+			RecordInteropAdvisoryValidation("valid")
+		case isInteropVerdict(err):
+			log.Info("advisory interop access list validation failed", "req_id", GetReqID(ctx), "tx_hash", tx.Hash(), "error", err)
+			RecordInteropAdvisoryValidation("invalid")
+		default:
+			log.Warn("advisory interop access list validation errored", "req_id", GetReqID(ctx), "tx_hash", tx.Hash(), "error", err)
+			RecordInteropAdvisoryValidation("error")
+		}
+	}()
 }
@@ -0,0 +1,276 @@
+package proxyd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/redis/go-redis/v9"
+)
+
+const interopOverrideRedisKey = "interop_validation_override"
+
+// InteropOverride replaces some interop validation settings of the config at
+// runtime. Unset fields keep their configured value.
+type InteropOverride struct {
+	Strategy InteropValidationStrategy `json:"strategy,omitempty"`
+	Urls     []string                  `json:"urls,omitempty"`
+	FailOpen *bool                     `json:"fail_open,omitempty"`
+	Mode     InteropValidationMode     `json:"mode,omitempty"`
+}
+
+// InteropAdmin validates interop access lists with the configured strategy,
+// which operators can swap at runtime through the admin endpoint, e.g. to
+// route around a misbehaving supervisor during an incident. The strategy,
+// supervisor urls, fail_open and mode can be overridden. Overrides are logged
+// and last until restart, unless persisted to Redis, from where they're read
+// back on startup.
+type InteropAdmin struct {
+	base       InteropValidationConfig
+	build      func(InteropValidationConfig) (InteropStrategy, *failoverStrategyImpl, error)
+	adminToken secretValue
+
+	redisClient redis.UniversalClient
+	redisKey    string
+
+	mu        sync.RWMutex
+	started   bool
+	cfg       InteropValidationConfig
+	override  *InteropOverride
+	persisted bool
+	strategy  InteropStrategy
+	failover  *failoverStrategyImpl
+}
+
+// NewInteropAdmin builds the strategy of cfg, or of the override persisted in
+// Redis if any, with build.
+func NewInteropAdmin(cfg InteropValidationConfig, build func(InteropValidationConfig) (InteropStrategy, *failoverStrategyImpl, error), redisClient redis.UniversalClient, namespace string) (*InteropAdmin, error) {
+	a := &InteropAdmin{
+		base:        cfg,
+		build:       build,
+		redisClient: redisClient,
+		redisKey:    interopOverrideRedisKey,
+	}
+	a.adminToken.Store(cfg.AdminToken)
+	if namespace != "" {
+		a.redisKey = namespace + ":" + interopOverrideRedisKey
+	}
+
+	if override := a.loadOverride(); override != nil {
+		if err := a.Set(override, false); err != nil {
+			log.Error("ignoring invalid persisted interop validation override", "err", err)
+		} else {
+			a.persisted = true
+			log.Warn("applied persisted interop validation override", "strategy", a.cfg.Strategy, "urls", a.cfg.Urls, "fail_open", a.cfg.FailOpen, "mode", a.cfg.Mode)
+			return a, nil
+		}
+	}
+	if err := a.Set(nil, false); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (a *InteropAdmin) loadOverride() *InteropOverride {
+	if a.redisClient == nil {
+		return nil
+	}
+	data, err := a.redisClient.Get(context.Background(), a.redisKey).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil
+	}
+	if err != nil {
+		log.Error("error reading persisted interop validation override", "err", err)
+		return nil
+	}
+	override := new(InteropOverride)
+	if err := json.Unmarshal(data, override); err != nil {
+		log.Error("error decoding persisted interop validation override", "err", err)
+		return nil
+	}
+	return override
+}
+
+// Set applies override to the configured settings, or reverts to them if
+// override is nil, and swaps in the resulting strategy. Validations in flight
+// finish with the previous strategy.
+func (a *InteropAdmin) Set(override *InteropOverride, persist bool) error {
+	cfg := a.base
+	if override != nil {
+		if override.Strategy != "" {
+			cfg.Strategy = override.Strategy
+		}
+		if override.Urls != nil {
+			cfg.Urls = override.Urls
+		}
+		if override.FailOpen != nil {
+			cfg.FailOpen = *override.FailOpen
+		}
+		if override.Mode != "" {
+			cfg.Mode = override.Mode
+		}
+	}
+	switch cfg.Mode {
+	case "", InteropValidationEnforce, InteropValidationAdvisory:
+	default:
+		return fmt.Errorf("invalid interop validation mode: %s", cfg.Mode)
+	}
+	setInteropStrategyDefaults(&cfg)
+	strategy, failover, err := a.build(cfg)
+	if err != nil {
+		return err
+	}
+
+	if persist {
+		if a.redisClient == nil {
+			return errors.New("persisting interop validation overrides requires a redis config")
+		}
+		if override == nil {
+			err = a.redisClient.Del(context.Background(), a.redisKey).Err()
+		} else {
+			err = a.redisClient.Set(context.Background(), a.redisKey, mustMarshalJSON(override), 0).Err()
+		}
+		if err != nil {
+			return fmt.Errorf("error persisting interop validation override: %w", err)
+		}
+	}
+
+	a.mu.Lock()
+	prev := a.failover
+	a.cfg = cfg
+	a.override = override
+	if persist {
+		a.persisted = override != nil
+	}
+	a.strategy = strategy
+	a.failover = failover
+	if a.started && failover != nil {
+		failover.Start()
+	}
+	started := a.started
+	a.mu.Unlock()
+	if started && prev != nil {
+		prev.Stop()
+	}
+	return nil
+}
+
+// Start starts the health checks of the failover strategy.
+func (a *InteropAdmin) Start() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.started = true
+	if a.failover != nil {
+		a.failover.Start()
+	}
+}
+
+func (a *InteropAdmin) Stop() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.started && a.failover != nil {
+		a.failover.Stop()
+	}
+	a.started = false
+}
+
+// Config returns the settings in effect.
+func (a *InteropAdmin) Config() InteropValidationConfig {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.cfg
+}
+
+func (a *InteropAdmin) ValidateAccessList(ctx context.Context, interopAccessList []common.Hash) error {
+	a.mu.RLock()
+	strategy := a.strategy
+	a.mu.RUnlock()
+	return strategy.ValidateAccessList(ctx, interopAccessList)
+}
+
+type interopAdminStatus struct {
+	Strategy  InteropValidationStrategy `json:"strategy"`
+	Urls      []string                  `json:"urls"`
+	FailOpen  bool                      `json:"fail_open"`
+	Mode      InteropValidationMode     `json:"mode"`
+	Override  *InteropOverride          `json:"override,omitempty"`
+	Persisted bool                      `json:"persisted"`
+}
+
+// ServeHTTP serves the interop validation endpoints:
+//
+//	GET    /admin/interop                lists the settings in effect
+//	PUT    /admin/interop[?persist=true] overrides them with an InteropOverride
+//	DELETE /admin/interop[?persist=true] reverts to the configured settings
+//
+// With persist, the override is also stored in, or removed from, Redis.
+func (a *InteropAdmin) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !checkAdminToken(w, r, a.adminToken.Load()) {
+		return
+	}
+
+	var persist bool
+	if v := r.URL.Query().Get("persist"); v != "" {
+		var err error
+		if persist, err = strconv.ParseBool(v); err != nil {
+			http.Error(w, "invalid persist", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var override *InteropOverride
+	switch r.Method {
+	case http.MethodGet:
+		a.mu.RLock()
+		status := interopAdminStatus{
+			Strategy:  a.cfg.Strategy,
+			Urls:      a.cfg.Urls,
+			FailOpen:  a.cfg.FailOpen,
+			Mode:      a.cfg.Mode,
+			Override:  a.override,
+			Persisted: a.persisted,
+		}
+		a.mu.RUnlock()
+		writeAdminJSON(w, http.StatusOK, status)
+		return
+	case http.MethodPut:
+		override = new(InteropOverride)
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(override); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+	case http.MethodDelete:
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	prev := a.Config()
+	if err := a.Set(override, persist); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	cfg := a.Config()
+	log.Warn(
+		"changed interop validation settings",
+		"remote_addr", r.RemoteAddr,
+		"persist", persist,
+		"reverted", override == nil,
+		"prev_strategy", prev.Strategy,
+		"strategy", cfg.Strategy,
+		"prev_urls", prev.Urls,
+		"urls", cfg.Urls,
+		"prev_fail_open", prev.FailOpen,
+		"fail_open", cfg.FailOpen,
+		"prev_mode", prev.Mode,
+		"mode", cfg.Mode,
+	)
+	RecordInteropAdminChange(override == nil, persist)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,95 @@
+package proxyd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeInteropStrategy struct {
+	cfg InteropValidationConfig
+}
+
+func (s *fakeInteropStrategy) ValidateAccessList(ctx context.Context, interopAccessList []common.Hash) error {
+	return fmt.Errorf("validated with %s", s.cfg.Strategy)
+}
+
+func buildFakeInteropStrategy(cfg InteropValidationConfig) (InteropStrategy, *failoverStrategyImpl, error) {
+	if cfg.Strategy == "bogus" {
+		return nil, nil, fmt.Errorf("invalid interop validating strategy: %s", cfg.Strategy)
+	}
+	return &fakeInteropStrategy{cfg: cfg}, nil, nil
+}
+
+func TestInteropAdmin(t *testing.T) {
+	redisServer, err := miniredis.Run()
+	require.NoError(t, err)
+	defer redisServer.Close()
+	redisClient := redis.NewClient(&redis.Options{
+		Addr: fmt.Sprintf("127.0.0.1:%s", redisServer.Port()),
+	})
+
+	cfg := InteropValidationConfig{
+		Urls:       []string{"http://supervisor-1"},
+		Strategy:   FirstSupervisorStrategy,
+		AdminToken: "secret",
+	}
+	a, err := NewInteropAdmin(cfg, buildFakeInteropStrategy, redisClient, "ns")
+	require.NoError(t, err)
+	validatedWith := func(a *InteropAdmin) string {
+		return a.ValidateAccessList(context.Background(), nil).Error()
+	}
+	require.Equal(t, "validated with first-supervisor", validatedWith(a))
+
+	do := func(method, target, body, token string) int {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		a.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	require.Equal(t, http.StatusUnauthorized, do(http.MethodPut, "/admin/interop", `{"strategy":"quorum"}`, "wrong"))
+	require.Equal(t, http.StatusBadRequest, do(http.MethodPut, "/admin/interop", `{"strategy":"bogus"}`, "secret"))
+	require.Equal(t, http.StatusBadRequest, do(http.MethodPut, "/admin/interop", `{"mode":"lenient"}`, "secret"))
+	require.Equal(t, "validated with first-supervisor", validatedWith(a))
+
+	// not persisted, so a restart reverts to the config
+	require.Equal(t, http.StatusNoContent, do(http.MethodPut, "/admin/interop", `{"strategy":"quorum","fail_open":true}`, "secret"))
+	require.Equal(t, "validated with quorum", validatedWith(a))
+	require.True(t, a.Config().FailOpen)
+	require.Equal(t, cfg.Urls, a.Config().Urls)
+	restarted, err := NewInteropAdmin(cfg, buildFakeInteropStrategy, redisClient, "ns")
+	require.NoError(t, err)
+	require.Equal(t, "validated with first-supervisor", validatedWith(restarted))
+
+	// persisted overrides survive restarts until reverted
+	urls := `["http://supervisor-2","http://supervisor-3"]`
+	require.Equal(t, http.StatusNoContent, do(http.MethodPut, "/admin/interop?persist=true", `{"strategy":"multicall","urls":`+urls+`,"mode":"advisory"}`, "secret"))
+	restarted, err = NewInteropAdmin(cfg, buildFakeInteropStrategy, redisClient, "ns")
+	require.NoError(t, err)
+	require.Equal(t, "validated with multicall", validatedWith(restarted))
+	require.Equal(t, []string{"http://supervisor-2", "http://supervisor-3"}, restarted.Config().Urls)
+	require.Equal(t, InteropValidationAdvisory, restarted.Config().Mode)
+	require.False(t, restarted.Config().FailOpen)
+
+	require.Equal(t, http.StatusNoContent, do(http.MethodDelete, "/admin/interop?persist=true", "", "secret"))
+	require.Equal(t, "validated with first-supervisor", validatedWith(a))
+	restarted, err = NewInteropAdmin(cfg, buildFakeInteropStrategy, redisClient, "ns")
+	require.NoError(t, err)
+	require.Equal(t, "validated with first-supervisor", validatedWith(restarted))
+
+	// persisting needs redis
+	a, err = NewInteropAdmin(cfg, buildFakeInteropStrategy, nil, "")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadRequest, do(http.MethodPut, "/admin/interop?persist=true", `{"strategy":"quorum"}`, "secret"))
+	require.Equal(t, "validated with first-supervisor", validatedWith(a))
+}
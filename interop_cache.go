@@ -0,0 +1,120 @@
+package proxyd
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	defaultInteropCacheBlockTime  = 2 * time.Second
+	defaultInteropCacheMaxEntries = 10000
+)
+
+// cachingInteropStrategy caches the verdicts of another strategy for
+// identical access lists until the next block, so that resubmissions of the
+// same cross-chain messages don't reach the supervisors again. Concurrent
+// checks of the same access list share one supervisor request. Supervisor
+// failures aren't cached.
+type cachingInteropStrategy struct {
+	InteropStrategy
+
+	// blockNumber returns the latest block, if known. Otherwise blocks are
+	// assumed to be blockTime apart.
+	blockNumber func() (uint64, bool)
+	blockTime   time.Duration
+	maxEntries  int
+
+	mu       sync.Mutex
+	block    uint64
+	verdicts map[common.Hash]error
+	inflight singleflight.Group
+}
+
+func NewCachingInteropStrategy(strategy InteropStrategy, cfg InteropValidationCacheConfig, blockNumber func() (uint64, bool)) *cachingInteropStrategy {
+	c := &cachingInteropStrategy{
+		InteropStrategy: strategy,
+		blockNumber:     blockNumber,
+		blockTime:       cfg.BlockTime,
+		maxEntries:      cfg.MaxEntries,
+		verdicts:        make(map[common.Hash]error),
+	}
+	if c.blockTime <= 0 {
+		c.blockTime = defaultInteropCacheBlockTime
+	}
+	if c.maxEntries <= 0 {
+		c.maxEntries = defaultInteropCacheMaxEntries
+	}
+	return c
+}
+
+func (c *cachingInteropStrategy) ValidateAccessList(ctx context.Context, interopAccessList []common.Hash) error {
+	key := interopAccessListKey(interopAccessList)
+	block := c.currentBlock()
+
+	c.mu.Lock()
+	if block != c.block {
+		c.block = block
+		c.verdicts = make(map[common.Hash]error)
+	}
+	verdict, ok := c.verdicts[key]
+	c.mu.Unlock()
+	if ok {
+		RecordInteropValidationCache("hit")
+		return verdict
+	}
+	RecordInteropValidationCache("miss")
+
+	_, err, _ := c.inflight.Do(strconv.FormatUint(block, 10)+key.Hex(), func() (interface{}, error) {
+		err := c.InteropStrategy.ValidateAccessList(ctx, interopAccessList)
+		if isInteropVerdict(err) {
+			c.store(block, key, err)
+		}
+		return nil, err
+	})
+	return err
+}
+
+func (c *cachingInteropStrategy) store(block uint64, key common.Hash, verdict error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if block != c.block {
+		return
+	}
+	if len(c.verdicts) >= c.maxEntries {
+		c.verdicts = make(map[common.Hash]error)
+	}
+	c.verdicts[key] = verdict
+}
+
+func (c *cachingInteropStrategy) currentBlock() uint64 {
+	if c.blockNumber != nil {
+		if block, ok := c.blockNumber(); ok {
+			return block
+		}
+	}
+	return uint64(time.Now().UnixNano() / int64(c.blockTime))
+}
+
+func interopAccessListKey(interopAccessList []common.Hash) common.Hash {
+	data := make([]byte, 0, len(interopAccessList)*common.HashLength)
+	for _, h := range interopAccessList {
+		data = append(data, h.Bytes()...)
+	}
+	return crypto.Keccak256Hash(data)
+}
+
+// isInteropVerdict reports whether err is an answer about the access list,
+// as opposed to a failure to reach or use the supervisors.
+func isInteropVerdict(err error) bool {
+	if err == nil {
+		return true
+	}
+	rpcErr, ok := err.(*RPCErr)
+	return ok && rpcErr.HTTPErrorCode > 0 && rpcErr.HTTPErrorCode < 500
+}
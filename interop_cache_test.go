@@ -0,0 +1,71 @@
+package proxyd
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+type countingInteropStrategy struct {
+	calls atomic.Int32
+	delay time.Duration
+	err   error
+}
+
+func (s *countingInteropStrategy) ValidateAccessList(ctx context.Context, interopAccessList []common.Hash) error {
+	s.calls.Add(1)
+	time.Sleep(s.delay)
+	return s.err
+}
+
+func TestCachingInteropStrategy(t *testing.T) {
+	inner := &countingInteropStrategy{err: ErrInteropAccessListOutOfBounds}
+	block := uint64(100)
+	cache := NewCachingInteropStrategy(inner, InteropValidationCacheConfig{}, func() (uint64, bool) {
+		return block, true
+	})
+	ctx := context.Background()
+	list := []common.Hash{{1}, {2}}
+
+	require.Equal(t, ErrInteropAccessListOutOfBounds, cache.ValidateAccessList(ctx, list))
+	require.Equal(t, ErrInteropAccessListOutOfBounds, cache.ValidateAccessList(ctx, list))
+	require.Equal(t, int32(1), inner.calls.Load())
+
+	// other messages are validated on their own
+	require.Equal(t, ErrInteropAccessListOutOfBounds, cache.ValidateAccessList(ctx, []common.Hash{{3}}))
+	require.Equal(t, int32(2), inner.calls.Load())
+
+	// verdicts only hold for the block
+	block++
+	inner.err = nil
+	require.NoError(t, cache.ValidateAccessList(ctx, list))
+	require.Equal(t, int32(3), inner.calls.Load())
+
+	// supervisor failures aren't cached
+	block++
+	inner.err = ParseInteropError(context.DeadlineExceeded)
+	require.Error(t, cache.ValidateAccessList(ctx, list))
+	require.Error(t, cache.ValidateAccessList(ctx, list))
+	require.Equal(t, int32(5), inner.calls.Load())
+}
+
+func TestCachingInteropStrategyConcurrent(t *testing.T) {
+	inner := &countingInteropStrategy{delay: 50 * time.Millisecond}
+	cache := NewCachingInteropStrategy(inner, InteropValidationCacheConfig{BlockTime: time.Hour}, nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			require.NoError(t, cache.ValidateAccessList(context.Background(), []common.Hash{{1}}))
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, int32(1), inner.calls.Load())
+}
@@ -0,0 +1,102 @@
+package proxyd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ethereum-optimism/optimism/op-service/eth"
+	supervisorTypes "github.com/ethereum-optimism/optimism/op-supervisor/supervisor/types"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	// defaultInteropMessageExpiryWindow is the message expiry window of the
+	// interop dependency set.
+	defaultInteropMessageExpiryWindow = 7 * 24 * time.Hour
+	defaultInteropMaxFutureSkew       = 5 * time.Minute
+	// defaultInteropMaxLogIndex is more logs than fit in a 100M gas block.
+	defaultInteropMaxLogIndex = 1 << 18
+)
+
+// InteropStaticChecker rejects access lists whose executing messages can't be
+// valid, before a supervisor is asked: messages from unknown chains, with
+// timestamps outside of the expiry window or in the future, or with log
+// indexes no block can have. Rejections use the codes the supervisor answers
+// the same messages with.
+type InteropStaticChecker struct {
+	chainIDs     map[eth.ChainID]bool
+	expiryWindow time.Duration
+	futureSkew   time.Duration
+	maxLogIndex  uint32
+	now          func() time.Time
+}
+
+// NewInteropStaticChecker returns nil if the checks are disabled.
+func NewInteropStaticChecker(cfg InteropStaticChecksConfig) *InteropStaticChecker {
+	if !cfg.Enabled {
+		return nil
+	}
+	c := &InteropStaticChecker{
+		expiryWindow: cfg.MessageExpiryWindow,
+		futureSkew:   cfg.MaxFutureSkew,
+		maxLogIndex:  cfg.MaxLogIndex,
+		now:          time.Now,
+	}
+	if len(cfg.ChainIDs) > 0 {
+		c.chainIDs = make(map[eth.ChainID]bool, len(cfg.ChainIDs))
+		for _, chainID := range cfg.ChainIDs {
+			c.chainIDs[eth.ChainIDFromUInt64(chainID)] = true
+		}
+	}
+	if c.expiryWindow == 0 {
+		c.expiryWindow = defaultInteropMessageExpiryWindow
+	}
+	if c.futureSkew == 0 {
+		c.futureSkew = defaultInteropMaxFutureSkew
+	}
+	if c.maxLogIndex == 0 {
+		c.maxLogIndex = defaultInteropMaxLogIndex
+	}
+	return c
+}
+
+// Check returns the error rejecting the access list, if any of its messages
+// can't be valid.
+func (c *InteropStaticChecker) Check(interopAccessList []common.Hash) error {
+	if c == nil {
+		return nil
+	}
+	now := c.now()
+	oldest := uint64(now.Add(-c.expiryWindow).Unix())
+	newest := uint64(now.Add(c.futureSkew).Unix())
+	for entries := interopAccessList; len(entries) > 0; {
+		remaining, access, err := supervisorTypes.ParseAccess(entries)
+		if err != nil {
+			RecordInteropStaticRejection("malformed")
+			return ParseInteropError(fmt.Errorf("failed to read data: %w", err))
+		}
+		entries = remaining
+
+		switch {
+		case c.chainIDs != nil && !c.chainIDs[access.ChainID]:
+			RecordInteropStaticRejection("unknown_chain")
+			return interopStaticError(supervisorTypes.ErrUnknownChain, "message from unknown chain %s", access.ChainID)
+		case access.Timestamp < oldest:
+			RecordInteropStaticRejection("expired")
+			return interopStaticError(supervisorTypes.ErrConflict, "message at timestamp %d (chain %s) has expired", access.Timestamp, access.ChainID)
+		case access.Timestamp > newest:
+			RecordInteropStaticRejection("future")
+			return interopStaticError(supervisorTypes.ErrFuture, "message at timestamp %d (chain %s) is in the future", access.Timestamp, access.ChainID)
+		case access.LogIndex > c.maxLogIndex:
+			RecordInteropStaticRejection("log_index")
+			return interopStaticError(supervisorTypes.ErrConflict, "message log index %d (chain %s) exceeds %d", access.LogIndex, access.ChainID, c.maxLogIndex)
+		}
+	}
+	return nil
+}
+
+func interopStaticError(supervisorErr error, format string, args ...interface{}) *RPCErr {
+	err := interopRPCErrorMap[supervisorErr].Clone()
+	err.Message = fmt.Sprintf(format+": %s", append(args, supervisorErr)...)
+	return err
+}
@@ -0,0 +1,67 @@
+package proxyd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum-optimism/optimism/op-service/eth"
+	supervisorTypes "github.com/ethereum-optimism/optimism/op-supervisor/supervisor/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInteropStaticChecker(t *testing.T) {
+	require.Nil(t, NewInteropStaticChecker(InteropStaticChecksConfig{}))
+
+	now := time.Unix(1_700_000_000, 0)
+	c := NewInteropStaticChecker(InteropStaticChecksConfig{
+		Enabled:  true,
+		ChainIDs: []uint64{10, 8453},
+	})
+	c.now = func() time.Time { return now }
+
+	access := func(chainID uint64, timestamp time.Time, logIndex uint32) supervisorTypes.Access {
+		return supervisorTypes.Access{
+			BlockNumber: 100,
+			Timestamp:   uint64(timestamp.Unix()),
+			LogIndex:    logIndex,
+			ChainID:     eth.ChainIDFromUInt64(chainID),
+			Checksum:    supervisorTypes.MessageChecksum{supervisorTypes.PrefixChecksum},
+		}
+	}
+	valid := access(10, now.Add(-time.Hour), 3)
+
+	tests := []struct {
+		name   string
+		access supervisorTypes.Access
+		code   int
+		reason string
+	}{
+		{"valid", valid, 0, ""},
+		{"slightly in the future", access(8453, now.Add(time.Minute), 0), 0, ""},
+		{"unknown chain", access(1, now, 0), -320501, InteropReasonUnknownChain},
+		{"expired", access(10, now.Add(-8*24*time.Hour), 0), -320600, InteropReasonInvalidMessage},
+		{"future", access(10, now.Add(time.Hour), 0), -321401, InteropReasonFutureData},
+		{"log index", access(10, now, 1<<20), -320600, InteropReasonInvalidMessage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the first message is fine, later ones are checked too
+			err := c.Check(supervisorTypes.EncodeAccessList([]supervisorTypes.Access{valid, tt.access}))
+			if tt.code == 0 {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			require.Equal(t, tt.code, err.(*RPCErr).Code)
+			require.Equal(t, tt.reason, interopRejectionReason(err))
+		})
+	}
+
+	err := c.Check([]common.Hash{{0xff}})
+	require.Error(t, err)
+	require.Equal(t, InteropReasonMalformedAccessList, interopRejectionReason(err))
+
+	var disabled *InteropStaticChecker
+	require.NoError(t, disabled.Check([]common.Hash{{0xff}}))
+}
@@ -257,6 +257,242 @@ func (s *healthAwareLoadBalancingStrategyImpl) ValidateAccessList(ctx context.Co
 	return ParseInteropError(fmt.Errorf("no healthy supervisor backends found"))
 }
 
+type quorumStrategyImpl struct {
+	*commonInteropStrategy
+	quorum int
+}
+
+// NewQuorumStrategy accepts an access list once quorum supervisors accept it.
+// A quorum of 0 requires a majority.
+func NewQuorumStrategy(urls []string, quorum int, opts ...commonStrategyOpt) *quorumStrategyImpl {
+	if quorum <= 0 {
+		quorum = len(urls)/2 + 1
+	}
+	return &quorumStrategyImpl{
+		commonInteropStrategy: NewCommonInteropStrategy(urls, opts...),
+		quorum:                quorum,
+	}
+}
+
+func (s *quorumStrategyImpl) ValidateAccessList(ctx context.Context, interopAccessList []common.Hash) error {
+	accessListToValidate, proceedFurther, err := s.preflightChecksAndCleanupAccessList(ctx, interopAccessList)
+	if err != nil {
+		return err
+	}
+
+	if !proceedFurther {
+		return nil
+	}
+
+	ctx = context.WithValue(ctx, ContextKeyInteropValidationStrategy, QuorumStrategy) // nolint:staticcheck
+	resultChan := make(chan error, len(s.urls))
+	for _, url := range s.urls {
+		go func(url string) {
+			_, _, err := performCheckAccessListOp(ctx, accessListToValidate, url)
+			resultChan <- err
+		}(url)
+	}
+
+	// Success: as soon as the quorum of backends respond successfully
+	// Failure: the first error response, as soon as the quorum can't be reached anymore
+	var successes, failures int
+	var firstErr error
+	for range len(s.urls) {
+		err := <-resultChan
+		if err == nil {
+			successes++
+			if successes >= s.quorum {
+				return nil
+			}
+			continue
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+		failures++
+		if failures > len(s.urls)-s.quorum {
+			break
+		}
+	}
+	if firstErr == nil {
+		firstErr = fmt.Errorf("interop validation quorum of %d not reached", s.quorum)
+	}
+	return ParseInteropError(firstErr)
+}
+
+type hedgedStrategyImpl struct {
+	*commonInteropStrategy
+	hedgeDelay time.Duration
+}
+
+// NewHedgedStrategy asks the supervisors in order, asking the next one
+// whenever the previous ones haven't responded within hedgeDelay or have
+// failed. The first response from a working supervisor wins.
+func NewHedgedStrategy(urls []string, hedgeDelay time.Duration, opts ...commonStrategyOpt) *hedgedStrategyImpl {
+	return &hedgedStrategyImpl{
+		commonInteropStrategy: NewCommonInteropStrategy(urls, opts...),
+		hedgeDelay:            hedgeDelay,
+	}
+}
+
+func (s *hedgedStrategyImpl) ValidateAccessList(ctx context.Context, interopAccessList []common.Hash) error {
+	accessListToValidate, proceedFurther, err := s.preflightChecksAndCleanupAccessList(ctx, interopAccessList)
+	if err != nil {
+		return err
+	}
+
+	if !proceedFurther {
+		return nil
+	}
+
+	ctx = context.WithValue(ctx, ContextKeyInteropValidationStrategy, HedgedStrategy) // nolint:staticcheck
+	// cancel the slower checks once there is an answer
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type checkResult struct {
+		httpCode int
+		err      error
+	}
+	resultChan := make(chan checkResult, len(s.urls))
+	next, pending := 0, 0
+	var hedge <-chan time.Time
+	launch := func() {
+		url := s.urls[next]
+		go func() {
+			httpCode, _, err := performCheckAccessListOp(ctx, accessListToValidate, url)
+			resultChan <- checkResult{httpCode, err}
+		}()
+		next++
+		pending++
+		hedge = nil
+		if next < len(s.urls) {
+			hedge = time.After(s.hedgeDelay)
+		}
+	}
+
+	launch()
+	var firstErr error
+	for pending > 0 {
+		select {
+		case <-hedge:
+			launch()
+		case res := <-resultChan:
+			pending--
+			// a validation error is as good an answer as a success, only a
+			// failing supervisor makes us wait for the others
+			if res.err == nil || res.httpCode < 500 {
+				return res.err
+			}
+			if firstErr == nil {
+				firstErr = res.err
+			}
+			if next < len(s.urls) {
+				launch()
+			}
+		}
+	}
+	return ParseInteropError(firstErr)
+}
+
+type circuitBreakerStrategyImpl struct {
+	*commonInteropStrategy
+	breakers []*supervisorCircuitBreaker
+}
+
+// NewCircuitBreakerStrategy asks the supervisors in order, skipping the ones
+// that failed threshold times in a row for the cooldown.
+func NewCircuitBreakerStrategy(urls []string, threshold int, cooldown time.Duration, opts ...commonStrategyOpt) *circuitBreakerStrategyImpl {
+	s := &circuitBreakerStrategyImpl{
+		commonInteropStrategy: NewCommonInteropStrategy(urls, opts...),
+		breakers:              make([]*supervisorCircuitBreaker, len(urls)),
+	}
+	for i, url := range urls {
+		s.breakers[i] = &supervisorCircuitBreaker{
+			url:       url,
+			threshold: threshold,
+			cooldown:  cooldown,
+		}
+	}
+	return s
+}
+
+func (s *circuitBreakerStrategyImpl) ValidateAccessList(ctx context.Context, interopAccessList []common.Hash) error {
+	accessListToValidate, proceedFurther, err := s.preflightChecksAndCleanupAccessList(ctx, interopAccessList)
+	if err != nil {
+		return err
+	}
+
+	if !proceedFurther {
+		return nil
+	}
+
+	ctx = context.WithValue(ctx, ContextKeyInteropValidationStrategy, CircuitBreakerStrategy) // nolint:staticcheck
+	for _, breaker := range s.breakers {
+		if !breaker.Allow() {
+			continue
+		}
+
+		httpCode, _, err := performCheckAccessListOp(ctx, accessListToValidate, breaker.url)
+		if err != nil && httpCode >= 500 {
+			breaker.RecordFailure()
+			continue
+		}
+
+		// the supervisor works, even if the access list is invalid
+		breaker.RecordSuccess()
+		return err
+	}
+
+	return ParseInteropError(fmt.Errorf("no healthy supervisor backends found"))
+}
+
+type supervisorCircuitBreaker struct {
+	url       string
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+}
+
+// Allow reports whether the supervisor may be asked. Once the cooldown has
+// passed, one request per cooldown is let through to probe the supervisor.
+func (b *supervisorCircuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < b.threshold {
+		return true
+	}
+	if time.Since(b.openedAt) < b.cooldown {
+		return false
+	}
+	b.openedAt = time.Now()
+	return true
+}
+
+func (b *supervisorCircuitBreaker) RecordFailure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if b.failures >= b.threshold {
+		if b.failures == b.threshold {
+			log.Warn("skipping failing interop validating backend", "url", b.url, "cooldown", b.cooldown)
+		}
+		b.openedAt = time.Now()
+	}
+}
+
+func (b *supervisorCircuitBreaker) RecordSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures >= b.threshold {
+		log.Info("interop validating backend recovered", "url", b.url)
+	}
+	b.failures = 0
+}
+
 type healthAwareBackend struct {
 	url                  string
 	lastUnhealthy        time.Time
@@ -298,11 +534,26 @@ func (b *healthAwareBackend) Validate(ctx context.Context, accessList []common.H
 	return httpCode, nil
 }
 
+// supervisorCheckOutcome labels the latency of a supervisor check by whether
+// the access list was valid, invalid, or couldn't be checked.
+func supervisorCheckOutcome(err error) string {
+	switch {
+	case err == nil:
+		return "valid"
+	case isInteropVerdict(err):
+		return "invalid"
+	default:
+		return "error"
+	}
+}
+
 func performCheckAccessListOp(ctx context.Context, accessList []common.Hash, url string) (int, string, error) {
 	validatingBackend := interop.NewInteropClient(url)
+	start := time.Now()
 	err := validatingBackend.CheckAccessList(ctx, accessList, interoptypes.CrossUnsafe, interoptypes.ExecutingDescriptor{
 		Timestamp: getInteropExecutingDescriptorTimestamp(),
 	})
+	dur := time.Since(start)
 
 	var httpCode int
 	var rpcErrorCode string
@@ -316,6 +567,7 @@ func performCheckAccessListOp(ctx context.Context, accessList []common.Hash, url
 
 		err = interopErr
 	}
+	RecordSupervisorRequestDuration(url, supervisorCheckOutcome(err), dur)
 
 	strategy, ok := ctx.Value(ContextKeyInteropValidationStrategy).(InteropValidationStrategy)
 	if !ok {
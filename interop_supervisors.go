@@ -0,0 +1,148 @@
+package proxyd
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const supervisorHealthCheckTimeout = 5 * time.Second
+
+// supervisor tracks whether an interop validating backend is banned.
+type supervisor struct {
+	url string
+
+	mu          sync.Mutex
+	bannedUntil time.Time
+}
+
+func (s *supervisor) IsBanned() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return time.Now().Before(s.bannedUntil)
+}
+
+func (s *supervisor) Ban(period time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !time.Now().Before(s.bannedUntil) {
+		log.Warn("banning interop validating backend", "url", s.url, "period", period)
+	}
+	s.bannedUntil = time.Now().Add(period)
+	RecordSupervisorBanned(s.url, true)
+}
+
+func (s *supervisor) Unban() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.bannedUntil.IsZero() {
+		return
+	}
+	log.Info("unbanning interop validating backend", "url", s.url)
+	s.bannedUntil = time.Time{}
+	RecordSupervisorBanned(s.url, false)
+}
+
+type failoverStrategyImpl struct {
+	*commonInteropStrategy
+	supervisors         []*supervisor
+	banPeriod           time.Duration
+	healthCheckInterval time.Duration
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewFailoverStrategy asks the supervisors in order, like the backends of a
+// backend group. Supervisors that fail are banned for banPeriod, and
+// supervisors are health checked every healthCheckInterval once Start is
+// called, banning or unbanning them.
+func NewFailoverStrategy(urls []string, banPeriod, healthCheckInterval time.Duration, opts ...commonStrategyOpt) *failoverStrategyImpl {
+	s := &failoverStrategyImpl{
+		commonInteropStrategy: NewCommonInteropStrategy(urls, opts...),
+		supervisors:           make([]*supervisor, len(urls)),
+		banPeriod:             banPeriod,
+		healthCheckInterval:   healthCheckInterval,
+		stop:                  make(chan struct{}),
+	}
+	for i, url := range urls {
+		s.supervisors[i] = &supervisor{url: url}
+	}
+	return s
+}
+
+func (s *failoverStrategyImpl) ValidateAccessList(ctx context.Context, interopAccessList []common.Hash) error {
+	accessListToValidate, proceedFurther, err := s.preflightChecksAndCleanupAccessList(ctx, interopAccessList)
+	if err != nil {
+		return err
+	}
+
+	if !proceedFurther {
+		return nil
+	}
+
+	ctx = context.WithValue(ctx, ContextKeyInteropValidationStrategy, FailoverStrategy) // nolint:staticcheck
+	for _, sup := range s.supervisors {
+		if sup.IsBanned() {
+			continue
+		}
+
+		httpCode, _, err := performCheckAccessListOp(ctx, accessListToValidate, sup.url)
+		if err != nil && httpCode >= 500 {
+			sup.Ban(s.banPeriod)
+			continue
+		}
+		return err
+	}
+
+	return ParseInteropError(fmt.Errorf("no healthy supervisor backends found"))
+}
+
+func (s *failoverStrategyImpl) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(s.healthCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				s.checkHealth()
+			}
+		}
+	}()
+}
+
+func (s *failoverStrategyImpl) Stop() {
+	close(s.stop)
+	s.wg.Wait()
+}
+
+// checkHealth validates an empty access list with every supervisor, which
+// succeeds without looking anything up if the supervisor is up.
+func (s *failoverStrategyImpl) checkHealth() {
+	ctx := context.WithValue(context.Background(), ContextKeyInteropValidationStrategy, FailoverStrategy) // nolint:staticcheck
+	ctx, cancel := context.WithTimeout(ctx, supervisorHealthCheckTimeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for _, sup := range s.supervisors {
+		wg.Add(1)
+		go func(sup *supervisor) {
+			defer wg.Done()
+			httpCode, _, err := performCheckAccessListOp(ctx, nil, sup.url)
+			if err != nil && httpCode >= 500 {
+				sup.Ban(s.banPeriod)
+				return
+			}
+			sup.Unban()
+		}(sup)
+	}
+	wg.Wait()
+}
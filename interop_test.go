@@ -0,0 +1,52 @@
+package proxyd
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	supervisorTypes "github.com/ethereum-optimism/optimism/op-supervisor/supervisor/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInteropRejectionReason(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		reason string
+	}{
+		{"conflict", ParseInteropError(supervisorTypes.ErrConflict), InteropReasonInvalidMessage},
+		{"future", ParseInteropError(supervisorTypes.ErrFuture), InteropReasonFutureData},
+		{"unknown chain", ParseInteropError(supervisorTypes.ErrUnknownChain), InteropReasonUnknownChain},
+		{"data corruption", ParseInteropError(supervisorTypes.ErrDataCorruption), InteropReasonSupervisorState},
+		{"unparseable access list", ParseInteropError(errors.New("failed to read data: bad entry")), InteropReasonMalformedAccessList},
+		{"access list too large", ErrInteropAccessListOutOfBounds, InteropReasonAccessListTooLarge},
+		{"unreachable", ParseInteropError(errors.New("connection refused")), InteropReasonSupervisorUnavailable},
+		{"no supervisors", supervisorTypes.ErrNoRPCSource, InteropReasonSupervisorUnavailable},
+		{"unknown verdict", &RPCErr{Code: -32000, HTTPErrorCode: 400}, InteropReasonOther},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.reason, interopRejectionReason(tt.err))
+		})
+	}
+}
+
+func TestInteropRejection(t *testing.T) {
+	verdict := ParseInteropError(supervisorTypes.ErrConflict)
+	rejection, ok := interopRejection(verdict, InteropReasonInvalidMessage).(*RPCErr)
+	require.True(t, ok)
+	require.Equal(t, verdict.Code, rejection.Code)
+	require.Equal(t, verdict.HTTPErrorCode, rejection.HTTPErrorCode)
+	require.JSONEq(t, `{"reason":"invalid_message"}`, string(rejection.Data))
+	require.Empty(t, verdict.Data)
+
+	withData := &RPCErr{Code: -32000, HTTPErrorCode: 400, Data: json.RawMessage(`{"detail":1}`)}
+	rejection = interopRejection(withData, InteropReasonOther).(*RPCErr)
+	require.JSONEq(t, `{"reason":"other","supervisor_data":{"detail":1}}`, string(rejection.Data))
+
+	rejection = interopRejection(supervisorTypes.ErrNoRPCSource, InteropReasonSupervisorUnavailable).(*RPCErr)
+	require.Equal(t, JSONRPCErrorInternal, rejection.Code)
+	require.Zero(t, rejection.HTTPErrorCode)
+	require.JSONEq(t, `{"reason":"supervisor_unavailable"}`, string(rejection.Data))
+}
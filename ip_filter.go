@@ -0,0 +1,288 @@
+package proxyd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	defaultIPFilterRefreshInterval = time.Minute
+	ipFilterFetchTimeout           = 10 * time.Second
+	// ipFilterRuleAllowlist labels requests blocked for not matching any
+	// allow rule
+	ipFilterRuleAllowlist = "allowlist"
+)
+
+type ipRule struct {
+	prefix netip.Prefix
+	// text labels the rule's metrics as configured
+	text string
+}
+
+type ipRules struct {
+	allow []ipRule
+	deny  []ipRule
+}
+
+// IPFilter blocks requests by client IP before they're parsed. Deny rules
+// take precedence over allow rules, and if there are any allow rules, only
+// the IPs they match get through. Rules are IPs or CIDRs, from the config and
+// from a file or URL that are read again periodically, so lists can change
+// without a restart.
+type IPFilter struct {
+	static   ipRules
+	file     string
+	url      string
+	client   *http.Client
+	interval time.Duration
+
+	mu    sync.RWMutex
+	rules ipRules
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+func NewIPFilter(cfg IPFilterConfig) (*IPFilter, error) {
+	if len(cfg.Allow) == 0 && len(cfg.Deny) == 0 && cfg.File == "" && cfg.URL == "" {
+		return nil, nil
+	}
+	f := &IPFilter{
+		file:     cfg.File,
+		url:      cfg.URL,
+		client:   &http.Client{Timeout: ipFilterFetchTimeout},
+		interval: time.Duration(cfg.RefreshInterval),
+		stop:     make(chan struct{}),
+	}
+	if f.interval == 0 {
+		f.interval = defaultIPFilterRefreshInterval
+	}
+	var err error
+	if f.static.allow, err = parseIPRules(cfg.Allow); err != nil {
+		return nil, err
+	}
+	if f.static.deny, err = parseIPRules(cfg.Deny); err != nil {
+		return nil, err
+	}
+	// unreadable lists fail startup rather than let everyone through
+	if err := f.refresh(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func parseIPRule(s string) (ipRule, error) {
+	if strings.Contains(s, "/") {
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return ipRule{}, fmt.Errorf("invalid ip filter rule %q: %w", s, err)
+		}
+		return ipRule{prefix: prefix.Masked(), text: s}, nil
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return ipRule{}, fmt.Errorf("invalid ip filter rule %q: %w", s, err)
+	}
+	return ipRule{prefix: netip.PrefixFrom(addr, addr.BitLen()), text: s}, nil
+}
+
+func parseIPRules(rules []string) ([]ipRule, error) {
+	parsed := make([]ipRule, 0, len(rules))
+	for _, s := range rules {
+		rule, err := parseIPRule(s)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, rule)
+	}
+	return parsed, nil
+}
+
+// parseIPRuleList parses a list with an "allow <ip or cidr>" or
+// "deny <ip or cidr>" rule per line. Blank lines and lines starting with #
+// are skipped.
+func parseIPRuleList(data []byte) (ipRules, error) {
+	var rules ipRules
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		action, value, _ := strings.Cut(line, " ")
+		rule, err := parseIPRule(strings.TrimSpace(value))
+		if err != nil {
+			return ipRules{}, fmt.Errorf("line %d: %w", n, err)
+		}
+		switch action {
+		case "allow":
+			rules.allow = append(rules.allow, rule)
+		case "deny":
+			rules.deny = append(rules.deny, rule)
+		default:
+			return ipRules{}, fmt.Errorf("line %d: rules must start with allow or deny", n)
+		}
+	}
+	return rules, scanner.Err()
+}
+
+// Check returns the rule blocking ip, or "" if it's allowed. IPs that can't
+// be parsed only get through if there are no allow rules.
+func (f *IPFilter) Check(ip string) string {
+	if f == nil {
+		return ""
+	}
+	addr, err := netip.ParseAddr(ip)
+	f.mu.RLock()
+	rules := f.rules
+	f.mu.RUnlock()
+	if err != nil {
+		if len(rules.allow) > 0 {
+			return ipFilterRuleAllowlist
+		}
+		return ""
+	}
+	addr = addr.Unmap()
+	for _, rule := range rules.deny {
+		if rule.prefix.Contains(addr) {
+			return rule.text
+		}
+	}
+	if len(rules.allow) == 0 {
+		return ""
+	}
+	for _, rule := range rules.allow {
+		if rule.prefix.Contains(addr) {
+			return ""
+		}
+	}
+	return ipFilterRuleAllowlist
+}
+
+// Start reloads the file and URL lists in the background until Stop is
+// called. It does nothing if there are none.
+func (f *IPFilter) Start() {
+	if f == nil || (f.file == "" && f.url == "") {
+		return
+	}
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+		ticker := time.NewTicker(f.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-f.stop:
+				return
+			case <-ticker.C:
+				if err := f.refresh(); err != nil {
+					log.Error("error loading ip filter rules", "err", err)
+				}
+			}
+		}
+	}()
+}
+
+func (f *IPFilter) Stop() {
+	if f == nil || (f.file == "" && f.url == "") {
+		return
+	}
+	close(f.stop)
+	f.wg.Wait()
+}
+
+// refresh replaces the rules read from the file and URL. On error the
+// previous rules are kept.
+func (f *IPFilter) refresh() error {
+	rules := ipRules{
+		allow: append([]ipRule(nil), f.static.allow...),
+		deny:  append([]ipRule(nil), f.static.deny...),
+	}
+	if f.file != "" {
+		data, err := os.ReadFile(f.file)
+		if err != nil {
+			return wrapErr(err, "error reading ip filter file")
+		}
+		if err := rules.add(data, f.file); err != nil {
+			return err
+		}
+	}
+	if f.url != "" {
+		data, err := f.fetch()
+		if err != nil {
+			return err
+		}
+		if err := rules.add(data, f.url); err != nil {
+			return err
+		}
+	}
+
+	f.mu.Lock()
+	f.rules = rules
+	f.mu.Unlock()
+	return nil
+}
+
+func (r *ipRules) add(data []byte, source string) error {
+	list, err := parseIPRuleList(data)
+	if err != nil {
+		return fmt.Errorf("invalid ip filter list %s: %w", source, err)
+	}
+	r.allow = append(r.allow, list.allow...)
+	r.deny = append(r.deny, list.deny...)
+	return nil
+}
+
+func (f *IPFilter) fetch() ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), ipFilterFetchTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := f.client.Do(req)
+	if err != nil {
+		return nil, wrapErr(err, "error fetching ip filter list")
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ip filter list url returned status %d", res.StatusCode)
+	}
+	return io.ReadAll(res.Body)
+}
+
+// filterIPs wraps a frontend handler so blocked clients are turned away
+// before their request is read. Clients are identified by IP as for rate
+// limiting.
+func (s *Server) filterIPs(h http.HandlerFunc) http.HandlerFunc {
+	if s.ipFilter == nil {
+		return h
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		ip := r.Header.Get(s.rateLimitHeader)
+		if ip == "" {
+			ip, _, _ = net.SplitHostPort(r.RemoteAddr)
+		}
+		if rule := s.ipFilter.Check(stripXFF(ip)); rule != "" {
+			log.Debug("blocked request by ip", "remote_ip", sanitizeLogValue(s.ipHasher.Hash(stripXFF(ip))), "rule", rule)
+			RecordIPFilterBlock(rule)
+			httpResponseCodesTotal.WithLabelValues(strconv.Itoa(http.StatusForbidden)).Inc()
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		h(w, r)
+	}
+}
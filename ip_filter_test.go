@@ -0,0 +1,76 @@
+package proxyd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIPFilter(t *testing.T) {
+	f, err := NewIPFilter(IPFilterConfig{
+		Allow: []string{"10.0.0.0/8", "2001:db8::/32"},
+		Deny:  []string{"10.0.0.1", "10.1.0.0/16"},
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, "", f.Check("10.2.3.4"))
+	require.Equal(t, "", f.Check("::ffff:10.2.3.4"))
+	require.Equal(t, "", f.Check("2001:db8::1"))
+	require.Equal(t, "10.0.0.1", f.Check("10.0.0.1"))
+	require.Equal(t, "10.1.0.0/16", f.Check("10.1.2.3"))
+	require.Equal(t, ipFilterRuleAllowlist, f.Check("192.168.0.1"))
+	require.Equal(t, ipFilterRuleAllowlist, f.Check("not an ip"))
+
+	f, err = NewIPFilter(IPFilterConfig{Deny: []string{"192.168.0.0/16"}})
+	require.NoError(t, err)
+	require.Equal(t, "", f.Check("10.0.0.1"))
+	require.Equal(t, "", f.Check("not an ip"))
+	require.Equal(t, "192.168.0.0/16", f.Check("192.168.1.1"))
+
+	_, err = NewIPFilter(IPFilterConfig{Deny: []string{"10.0.0.0/33"}})
+	require.Error(t, err)
+	_, err = NewIPFilter(IPFilterConfig{Allow: []string{"example.com"}})
+	require.Error(t, err)
+
+	var nilFilter *IPFilter
+	require.Equal(t, "", nilFilter.Check("10.0.0.1"))
+}
+
+func TestIPFilterLists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ips.txt")
+	require.NoError(t, os.WriteFile(path, []byte("# blocked\ndeny 10.0.0.0/24\n\nallow 10.0.0.0/8\n"), 0o600))
+
+	var list atomic.Value
+	list.Store("deny 10.9.9.9\n")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(list.Load().(string)))
+	}))
+	defer srv.Close()
+
+	f, err := NewIPFilter(IPFilterConfig{File: path, URL: srv.URL})
+	require.NoError(t, err)
+	require.Equal(t, "10.0.0.0/24", f.Check("10.0.0.5"))
+	require.Equal(t, "10.9.9.9", f.Check("10.9.9.9"))
+	require.Equal(t, "", f.Check("10.1.0.1"))
+	require.Equal(t, ipFilterRuleAllowlist, f.Check("192.168.0.1"))
+
+	list.Store("allow 192.168.0.0/16\n")
+	require.NoError(t, f.refresh())
+	require.Equal(t, "", f.Check("10.9.9.9"))
+	require.Equal(t, "", f.Check("192.168.0.1"))
+
+	// invalid lists keep the previous rules
+	list.Store("block 10.0.0.1\n")
+	require.Error(t, f.refresh())
+	require.Equal(t, "", f.Check("192.168.0.1"))
+
+	require.NoError(t, os.Remove(path))
+	require.Error(t, f.refresh())
+	_, err = NewIPFilter(IPFilterConfig{File: path})
+	require.Error(t, err)
+}
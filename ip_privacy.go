@@ -0,0 +1,93 @@
+package proxyd
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const defaultIPPrivacyRotationInterval = 24 * time.Hour
+
+// IPHasher pseudonymizes client IPs before they're logged, stored or used as
+// rate limit keys, with an HMAC keyed by a key derived from the secret for
+// the current rotation period. Instances sharing the secret hash alike, so
+// shared rate limits keep working, and a client's hash changes every period,
+// so it can't be followed across periods. Limits of a client are reset when
+// the key rotates.
+type IPHasher struct {
+	rotation time.Duration
+
+	mu     sync.Mutex
+	secret []byte
+	period int64
+	key    []byte
+}
+
+// NewIPHasher returns nil if IP privacy is disabled. A nil IPHasher leaves
+// IPs as they are.
+func NewIPHasher(cfg IPPrivacyConfig, secrets *SecretStore) (*IPHasher, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	secret, err := secrets.Resolve(cfg.Secret)
+	if err != nil {
+		return nil, err
+	}
+	if secret == "" {
+		return nil, errors.New("ip_privacy.secret must be set")
+	}
+	h := &IPHasher{
+		rotation: time.Duration(cfg.RotationInterval),
+		secret:   []byte(secret),
+		period:   -1,
+	}
+	secrets.Watch(cfg.Secret, h.setSecret)
+	if h.rotation == 0 {
+		h.rotation = defaultIPPrivacyRotationInterval
+	}
+	return h, nil
+}
+
+// Hash returns the pseudonym of ip.
+func (h *IPHasher) Hash(ip string) string {
+	if h == nil || ip == "" {
+		return ip
+	}
+	mac := hmac.New(sha256.New, h.currentKey(time.Now()))
+	mac.Write([]byte(ip))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// setSecret replaces the secret after it is refreshed. Like a key rotation,
+// it changes the pseudonyms of all clients.
+func (h *IPHasher) setSecret(secret string) {
+	if secret == "" {
+		log.Error("ignoring empty refreshed ip_privacy.secret")
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.secret = []byte(secret)
+	h.period = -1
+}
+
+func (h *IPHasher) currentKey(now time.Time) []byte {
+	period := now.UnixNano() / int64(h.rotation)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if period != h.period {
+		var buf [8]byte
+		binary.BigEndian.PutUint64(buf[:], uint64(period))
+		mac := hmac.New(sha256.New, h.secret)
+		mac.Write(buf[:])
+		h.key = mac.Sum(nil)
+		h.period = period
+	}
+	return h.key
+}
@@ -0,0 +1,41 @@
+package proxyd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIPHasher(t *testing.T) {
+	var disabled *IPHasher
+	require.Equal(t, "192.0.2.1", disabled.Hash("192.0.2.1"))
+
+	secrets, err := NewSecretStore(SecretsConfig{})
+	require.NoError(t, err)
+	cfg := IPPrivacyConfig{Enabled: true, Secret: "secret", RotationInterval: TOMLDuration(time.Hour)}
+	h, err := NewIPHasher(cfg, secrets)
+	require.NoError(t, err)
+	other, err := NewIPHasher(cfg, secrets)
+	require.NoError(t, err)
+
+	hash := h.Hash("192.0.2.1")
+	require.Len(t, hash, 32)
+	require.NotContains(t, hash, "192.0.2.1")
+	require.Equal(t, hash, h.Hash("192.0.2.1"))
+	require.Equal(t, hash, other.Hash("192.0.2.1"))
+	require.NotEqual(t, hash, h.Hash("192.0.2.2"))
+	require.Empty(t, h.Hash(""))
+
+	now := time.Now()
+	require.Equal(t, h.currentKey(now), other.currentKey(now))
+	require.NotEqual(t, h.currentKey(now), h.currentKey(now.Add(time.Hour)))
+
+	cfg.Secret = "other"
+	rekeyed, err := NewIPHasher(cfg, secrets)
+	require.NoError(t, err)
+	require.NotEqual(t, hash, rekeyed.Hash("192.0.2.1"))
+
+	_, err = NewIPHasher(IPPrivacyConfig{Enabled: true}, secrets)
+	require.Error(t, err)
+}
@@ -0,0 +1,80 @@
+package proxyd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+)
+
+// ipcBackendURL is the placeholder URL used for requests to IPC backends.
+// The ipcTransport ignores it, but http.NewRequest needs a parseable URL.
+const ipcBackendURL = "http://ipc"
+
+// ipcTransport is an http.RoundTripper that writes JSON-RPC request bodies
+// to a geth-style IPC endpoint (a Unix domain socket, or a named pipe on
+// Windows) and wraps the reply in a synthetic HTTP response. This lets IPC
+// backends share the semaphore, retry and health handling of HTTP backends.
+type ipcTransport struct {
+	path string
+}
+
+func newIPCTransport(path string) *ipcTransport {
+	return &ipcTransport{path: path}
+}
+
+func (t *ipcTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, wrapErr(err, "error reading ipc request body")
+		}
+	}
+
+	conn, err := dialIPC(ctx, t.path)
+	if err != nil {
+		return nil, wrapErr(err, "error dialing ipc endpoint")
+	}
+	defer conn.Close()
+
+	// Unblock reads and writes as soon as the request is canceled or times out.
+	stop := context.AfterFunc(ctx, func() {
+		conn.Close()
+	})
+	defer stop()
+
+	if _, err := conn.Write(body); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, wrapErr(err, "error writing ipc request")
+	}
+
+	// IPC is a stream of JSON values rather than framed messages, so
+	// read exactly one value back, be it a single response or a batch.
+	var raw json.RawMessage
+	if err := json.NewDecoder(conn).Decode(&raw); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, wrapErr(err, "error reading ipc response")
+	}
+
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(raw)),
+		ContentLength: int64(len(raw)),
+		Request:       req,
+	}, nil
+}
@@ -0,0 +1,94 @@
+//go:build !windows
+
+package proxyd
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/semaphore"
+)
+
+func TestIPCBackendForward(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "geth.ipc")
+	ln, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	defer ln.Close()
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				var req RPCReq
+				if err := json.NewDecoder(conn).Decode(&req); err != nil {
+					return
+				}
+				_ = json.NewEncoder(conn).Encode(NewRPCRes(req.ID, "0x1"))
+			}(conn)
+		}
+	}()
+
+	b := NewBackend("ipc", ipcBackendURL, "", semaphore.NewWeighted(1), WithIPCPath(path))
+	res, err := b.Forward(context.Background(), []*RPCReq{{
+		JSONRPC: JSONRPCVersion,
+		Method:  "eth_chainId",
+		ID:      json.RawMessage("1"),
+	}}, false)
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	require.Equal(t, "0x1", res[0].Result)
+	require.Equal(t, json.RawMessage("1"), res[0].ID)
+}
+
+func TestIPCBackendTimeout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "geth.ipc")
+	ln, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	defer ln.Close()
+
+	// accept connections but never reply
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	b := NewBackend("ipc", ipcBackendURL, "", nil,
+		WithIPCPath(path),
+		WithTimeout(50*time.Millisecond),
+	)
+	_, err = b.Forward(context.Background(), []*RPCReq{{
+		JSONRPC: JSONRPCVersion,
+		Method:  "eth_chainId",
+		ID:      json.RawMessage("1"),
+	}}, false)
+	require.Error(t, err)
+	require.Greater(t, b.intermittentErrorsSlidingWindow.Sum(), float64(0))
+}
+
+func TestIPCBackendTLSConfig(t *testing.T) {
+	// TLS settings don't apply to IPC backends, whatever the option order
+	b := NewBackend("ipc", ipcBackendURL, "", nil,
+		WithIPCPath(filepath.Join(t.TempDir(), "geth.ipc")),
+		WithTLSConfig(&tls.Config{}),
+	)
+	require.IsType(t, newIPCTransport(""), b.client.Transport)
+
+	errs := checkBackendURLs("ipc", &BackendConfig{ClientCertFile: "cert.pem", ClientKeyFile: "key.pem"}, backendURLs{ipcPath: "/var/run/geth.ipc"}, false)
+	require.Len(t, errs, 1)
+	require.ErrorContains(t, errs[0], "cannot be used with ipc_path")
+}
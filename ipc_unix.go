@@ -0,0 +1,14 @@
+//go:build !windows
+
+package proxyd
+
+import (
+	"context"
+	"net"
+)
+
+// dialIPC connects to a Unix domain socket, such as geth.ipc.
+func dialIPC(ctx context.Context, path string) (net.Conn, error) {
+	var d net.Dialer
+	return d.DialContext(ctx, "unix", path)
+}
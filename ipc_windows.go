@@ -0,0 +1,15 @@
+//go:build windows
+
+package proxyd
+
+import (
+	"context"
+	"net"
+
+	"github.com/Microsoft/go-winio"
+)
+
+// dialIPC connects to a named pipe, such as \\.\pipe\geth.ipc.
+func dialIPC(ctx context.Context, path string) (net.Conn, error) {
+	return winio.DialPipeContext(ctx, path)
+}
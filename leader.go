@@ -0,0 +1,183 @@
+package proxyd
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const defaultLeaderElectionInterval = 500 * time.Millisecond
+
+// leaderCheck is where a backend of a replicated sequencer group tells
+// whether it's the leader: the op-conductor RPC of the sequencer, or a
+// generic health endpoint answering 200 only on the leader.
+type leaderCheck struct {
+	conductorURL string
+	leaderURL    string
+
+	mu     sync.RWMutex
+	leader bool
+}
+
+func WithLeaderCheck(conductorURL, leaderURL string) BackendOpt {
+	return func(b *Backend) {
+		if conductorURL != "" || leaderURL != "" {
+			b.leaderCheck = &leaderCheck{conductorURL: conductorURL, leaderURL: leaderURL}
+		}
+	}
+}
+
+// IsLeader returns whether the backend was the sequencer leader when last
+// checked.
+func (b *Backend) IsLeader() bool {
+	if b.leaderCheck == nil {
+		return false
+	}
+	b.leaderCheck.mu.RLock()
+	defer b.leaderCheck.mu.RUnlock()
+	return b.leaderCheck.leader
+}
+
+// LeaderTracker checks on an interval which backend of each replicated
+// sequencer group is the leader. Groups send writes to the leader only, so
+// that they follow it within a block on failover. While no leader is known,
+// writes go to every backend as usual.
+type LeaderTracker struct {
+	backends []*Backend
+	interval time.Duration
+	client   *http.Client
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewLeaderTracker returns nil if no backend has a leader check.
+func NewLeaderTracker(cfg LeaderElectionConfig, backends map[string]*Backend) *LeaderTracker {
+	t := &LeaderTracker{
+		interval: time.Duration(cfg.Interval),
+		stop:     make(chan struct{}),
+	}
+	for _, be := range backends {
+		if be.leaderCheck != nil {
+			t.backends = append(t.backends, be)
+		}
+	}
+	if len(t.backends) == 0 {
+		return nil
+	}
+	if t.interval == 0 {
+		t.interval = defaultLeaderElectionInterval
+	}
+	t.client = &http.Client{Timeout: t.interval}
+	return t
+}
+
+// Start checks the leaders until Stop is called.
+func (t *LeaderTracker) Start() {
+	if t == nil {
+		return
+	}
+	t.update()
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		ticker := time.NewTicker(t.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-t.stop:
+				return
+			case <-ticker.C:
+				t.update()
+			}
+		}
+	}()
+}
+
+func (t *LeaderTracker) Stop() {
+	if t == nil {
+		return
+	}
+	close(t.stop)
+	t.wg.Wait()
+}
+
+func (t *LeaderTracker) update() {
+	var wg sync.WaitGroup
+	for _, be := range t.backends {
+		wg.Add(1)
+		go func(be *Backend) {
+			defer wg.Done()
+			leader, err := t.check(be.leaderCheck)
+			if err != nil {
+				log.Warn("error checking sequencer leader", "name", be.Name, "err", err)
+			}
+			lc := be.leaderCheck
+			lc.mu.Lock()
+			changed := lc.leader != leader
+			lc.leader = leader
+			lc.mu.Unlock()
+			if changed {
+				log.Info("sequencer leadership changed", "name", be.Name, "leader", leader)
+			}
+			RecordBackendLeader(be, leader)
+		}(be)
+	}
+	wg.Wait()
+}
+
+// check asks the backend whether it's the leader. Backends that can't be
+// reached aren't.
+func (t *LeaderTracker) check(lc *leaderCheck) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), t.interval)
+	defer cancel()
+
+	if lc.leaderURL != "" {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, lc.leaderURL, nil)
+		if err != nil {
+			return false, err
+		}
+		res, err := t.client.Do(req)
+		if err != nil {
+			return false, err
+		}
+		defer res.Body.Close()
+		_, _ = io.Copy(io.Discard, res.Body)
+		return res.StatusCode == http.StatusOK, nil
+	}
+
+	var leader bool
+	if err := callRPC(ctx, t.client, lc.conductorURL, "conductor_leader", &leader); err != nil {
+		return false, err
+	}
+	return leader, nil
+}
+
+// leaderBackends returns the leaders among the backends for writes, or the
+// backends as they are if there are no writes or no leader is known.
+func leaderBackends(backends []*Backend, rpcReqs []*RPCReq) []*Backend {
+	writes := false
+	for _, req := range rpcReqs {
+		if isWriteMethod(req.Method) {
+			writes = true
+			break
+		}
+	}
+	if !writes {
+		return backends
+	}
+	leaders := make([]*Backend, 0, 1)
+	for _, be := range backends {
+		if be.IsLeader() {
+			leaders = append(leaders, be)
+		}
+	}
+	if len(leaders) == 0 {
+		return backends
+	}
+	return leaders
+}
@@ -0,0 +1,110 @@
+package proxyd
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	// systemdListenFDsStart is the first file descriptor passed by systemd,
+	// see sd_listen_fds(3).
+	systemdListenFDsStart = 3
+
+	// SystemdRPCSocketName and SystemdWSSocketName are the FileDescriptorName=
+	// values proxyd looks for in its systemd socket units.
+	SystemdRPCSocketName = "rpc"
+	SystemdWSSocketName  = "ws"
+)
+
+// SystemdListeners returns the sockets passed to this process through systemd
+// socket activation, keyed by their FileDescriptorName. It returns an empty map
+// if the process was not socket activated.
+func SystemdListeners() (map[string]net.Listener, error) {
+	listeners := make(map[string]net.Listener)
+	if os.Getenv("LISTEN_PID") != strconv.Itoa(os.Getpid()) {
+		return listeners, nil
+	}
+
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil {
+		return nil, wrapErr(err, "invalid LISTEN_FDS")
+	}
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+
+	// don't pass the sockets on to any child processes
+	_ = os.Unsetenv("LISTEN_PID")
+	_ = os.Unsetenv("LISTEN_FDS")
+	_ = os.Unsetenv("LISTEN_FDNAMES")
+
+	for i := 0; i < count; i++ {
+		name := fmt.Sprintf("fd%d", i)
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+		f := os.NewFile(uintptr(systemdListenFDsStart+i), name)
+		ln, err := net.FileListener(f)
+		_ = f.Close()
+		if err != nil {
+			return nil, wrapErr(err, fmt.Sprintf("error using systemd socket %s", name))
+		}
+		listeners[name] = ln
+	}
+
+	return listeners, nil
+}
+
+// newFrontendListener creates the listener for one of the frontend servers.
+// A systemd activated socket takes precedence over a Unix socket path, which
+// in turn takes precedence over host and port. It returns a nil listener if
+// the server is not enabled.
+func newFrontendListener(activated net.Listener, socketPath string, socketMode os.FileMode, host string, port int) (net.Listener, error) {
+	if activated != nil {
+		return activated, nil
+	}
+
+	if socketPath != "" {
+		// remove a stale socket left behind by an unclean shutdown, but never
+		// anything else a mistyped path may point to
+		if info, err := os.Lstat(socketPath); err == nil {
+			if info.Mode()&os.ModeSocket == 0 {
+				return nil, fmt.Errorf("%s exists and is not a unix socket", socketPath)
+			}
+			if err := os.Remove(socketPath); err != nil {
+				return nil, wrapErr(err, "error removing stale unix socket")
+			}
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return nil, wrapErr(err, "error checking unix socket path")
+		}
+		ln, err := net.Listen("unix", socketPath)
+		if err != nil {
+			return nil, err
+		}
+		if socketMode != 0 {
+			if err := os.Chmod(socketPath, socketMode); err != nil {
+				ln.Close()
+				return nil, wrapErr(err, "error setting unix socket mode")
+			}
+		}
+		return ln, nil
+	}
+
+	if port == 0 {
+		return nil, nil
+	}
+	return net.Listen("tcp", fmt.Sprintf("%s:%d", host, port))
+}
+
+func parseUnixSocketMode(mode string) (os.FileMode, error) {
+	if mode == "" {
+		return 0, nil
+	}
+	m, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid unix_socket_mode %q: %w", mode, err)
+	}
+	return os.FileMode(m), nil
+}
@@ -0,0 +1,92 @@
+//go:build !windows
+
+package proxyd
+
+import (
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewFrontendListenerUnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rpc.sock")
+	// simulate a stale socket left behind by a previous process
+	stale, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	require.NoError(t, err)
+	stale.SetUnlinkOnClose(false)
+	require.NoError(t, stale.Close())
+
+	ln, err := newFrontendListener(nil, path, 0o660, "127.0.0.1", 0)
+	require.NoError(t, err)
+	defer ln.Close()
+	require.Equal(t, "unix", ln.Addr().Network())
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o660), info.Mode().Perm())
+
+	go func() {
+		_ = http.Serve(ln, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("OK"))
+		}))
+	}()
+
+	client := http.Client{Transport: &http.Transport{
+		Dial: func(_, _ string) (net.Conn, error) {
+			return net.Dial("unix", path)
+		},
+	}}
+	res, err := client.Get("http://unix/healthz")
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+}
+
+func TestNewFrontendListenerNotSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rpc.sock")
+	require.NoError(t, os.WriteFile(path, []byte("data"), 0o600))
+
+	_, err := newFrontendListener(nil, path, 0, "127.0.0.1", 0)
+	require.ErrorContains(t, err, "is not a unix socket")
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "data", string(data))
+}
+
+func TestNewFrontendListenerPrecedence(t *testing.T) {
+	activated, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer activated.Close()
+
+	ln, err := newFrontendListener(activated, filepath.Join(t.TempDir(), "rpc.sock"), 0, "127.0.0.1", 8545)
+	require.NoError(t, err)
+	require.Equal(t, activated, ln)
+
+	ln, err = newFrontendListener(nil, "", 0, "127.0.0.1", 0)
+	require.NoError(t, err)
+	require.Nil(t, ln)
+}
+
+func TestSystemdListenersNotActivated(t *testing.T) {
+	t.Setenv("LISTEN_PID", "")
+	listeners, err := SystemdListeners()
+	require.NoError(t, err)
+	require.Empty(t, listeners)
+}
+
+func TestParseUnixSocketMode(t *testing.T) {
+	mode, err := parseUnixSocketMode("0660")
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o660), mode)
+
+	mode, err = parseUnixSocketMode("")
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0), mode)
+
+	_, err = parseUnixSocketMode("rw-rw----")
+	require.Error(t, err)
+}
@@ -0,0 +1,222 @@
+package proxyd
+
+import (
+	"net/http"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	defaultLoadSheddingInterval   = time.Second
+	defaultLoadSheddingRetryAfter = 5 * time.Second
+	// past this share of a threshold, everything but the protected methods
+	// is shed
+	loadSheddingCriticalPressure = 1.5
+)
+
+var (
+	defaultShedPrefixes     = []string{"debug_", "trace_"}
+	defaultProtectedMethods = []string{"eth_sendRawTransaction", "eth_sendRawTransactionConditional"}
+)
+
+type loadSheddingLevel int32
+
+const (
+	loadSheddingNone loadSheddingLevel = iota
+	// shed the low priority methods
+	loadSheddingLow
+	// shed all but the protected methods
+	loadSheddingAll
+)
+
+// LoadShedder rejects requests while proxyd is under resource pressure, so
+// that transaction submission keeps working during overload. Pressure is the
+// highest ratio of heap size, goroutines or in-flight requests to their
+// thresholds. From a pressure of 1, the low priority methods are shed. From
+// 1.5, all methods but the protected ones are.
+type LoadShedder struct {
+	maxHeapBytes  uint64
+	maxGoroutines int
+	maxInflight   int64
+	interval      time.Duration
+	retryAfter    string
+	shedPrefixes  []string
+	protected     map[string]bool
+
+	inflight atomic.Int64
+	// pressure of the heap and goroutines, sampled on the interval
+	sampled atomic.Uint64
+	level   atomic.Int32
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+func NewLoadShedder(cfg LoadSheddingConfig) *LoadShedder {
+	l := &LoadShedder{
+		maxHeapBytes:  cfg.MaxHeapBytes,
+		maxGoroutines: cfg.MaxGoroutines,
+		maxInflight:   int64(cfg.MaxInflightRequests),
+		interval:      time.Duration(cfg.Interval),
+		shedPrefixes:  cfg.ShedPrefixes,
+		protected:     make(map[string]bool),
+		stop:          make(chan struct{}),
+	}
+	if l.interval == 0 {
+		l.interval = defaultLoadSheddingInterval
+	}
+	retryAfter := time.Duration(cfg.RetryAfter)
+	if retryAfter == 0 {
+		retryAfter = defaultLoadSheddingRetryAfter
+	}
+	l.retryAfter = strconv.Itoa(int(retryAfter.Seconds()))
+	if l.shedPrefixes == nil {
+		l.shedPrefixes = defaultShedPrefixes
+	}
+	protected := cfg.ProtectedMethods
+	if protected == nil {
+		protected = defaultProtectedMethods
+	}
+	for _, method := range protected {
+		l.protected[method] = true
+	}
+	return l
+}
+
+// Start samples the heap and goroutines until Stop is called.
+func (l *LoadShedder) Start() {
+	if l == nil {
+		return
+	}
+	l.sample()
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+		ticker := time.NewTicker(l.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-l.stop:
+				return
+			case <-ticker.C:
+				l.sample()
+			}
+		}
+	}()
+}
+
+func (l *LoadShedder) Stop() {
+	if l == nil {
+		return
+	}
+	close(l.stop)
+	l.wg.Wait()
+}
+
+func (l *LoadShedder) sample() {
+	var pressure float64
+	if l.maxHeapBytes > 0 {
+		var stats runtime.MemStats
+		runtime.ReadMemStats(&stats)
+		pressure = float64(stats.HeapAlloc) / float64(l.maxHeapBytes)
+	}
+	if l.maxGoroutines > 0 {
+		pressure = max(pressure, float64(runtime.NumGoroutine())/float64(l.maxGoroutines))
+	}
+	l.sampled.Store(uint64(pressure * 1000))
+	l.updateLevel()
+}
+
+// Begin counts a request in flight until the returned func is called.
+func (l *LoadShedder) Begin() func() {
+	if l == nil {
+		return func() {}
+	}
+	l.inflight.Add(1)
+	l.updateLevel()
+	return func() {
+		l.inflight.Add(-1)
+	}
+}
+
+func (l *LoadShedder) updateLevel() {
+	pressure := float64(l.sampled.Load()) / 1000
+	if l.maxInflight > 0 {
+		pressure = max(pressure, float64(l.inflight.Load())/float64(l.maxInflight))
+	}
+	level := loadSheddingNone
+	switch {
+	case pressure >= loadSheddingCriticalPressure:
+		level = loadSheddingAll
+	case pressure >= 1:
+		level = loadSheddingLow
+	}
+	if prev := loadSheddingLevel(l.level.Swap(int32(level))); prev != level {
+		log.Warn("load shedding level changed", "level", level, "previous", prev, "pressure", pressure)
+		RecordLoadSheddingLevel(int(level))
+	}
+}
+
+// Level returns the current load shedding level: 0 sheds nothing, 1 the low
+// priority methods and 2 all but the protected ones.
+func (l *LoadShedder) Level() int {
+	if l == nil {
+		return 0
+	}
+	return int(l.level.Load())
+}
+
+// Shed reports whether a request for the method must be rejected.
+func (l *LoadShedder) Shed(method string) bool {
+	if l == nil || l.protected[method] {
+		return false
+	}
+	switch loadSheddingLevel(l.level.Load()) {
+	case loadSheddingAll:
+		RecordLoadShedRequest(method)
+		return true
+	case loadSheddingLow:
+		for _, prefix := range l.shedPrefixes {
+			if strings.HasPrefix(method, prefix) {
+				RecordLoadShedRequest(method)
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ShedPriority is Shed for requests of a priority class: high priority
+// requests are never shed, and low priority ones are shed along with the low
+// priority methods.
+func (l *LoadShedder) ShedPriority(method string, priority requestPriority) bool {
+	switch priority {
+	case priorityHigh:
+		return false
+	case priorityLow:
+		if l != nil && !l.protected[method] && loadSheddingLevel(l.level.Load()) >= loadSheddingLow {
+			RecordLoadShedRequest(method)
+			return true
+		}
+	}
+	return l.Shed(method)
+}
+
+// SetRetryAfter tells clients when to retry if any of the responses was shed.
+func (l *LoadShedder) SetRetryAfter(w http.ResponseWriter, responses []*RPCRes) {
+	if l == nil {
+		return
+	}
+	for _, res := range responses {
+		if res != nil && res.Error != nil && res.Error.Code == ErrOverloaded.Code {
+			w.Header().Set("Retry-After", l.retryAfter)
+			return
+		}
+	}
+}
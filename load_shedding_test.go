@@ -0,0 +1,62 @@
+package proxyd
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadShedder(t *testing.T) {
+	l := NewLoadShedder(LoadSheddingConfig{
+		MaxInflightRequests: 2,
+		RetryAfter:          TOMLDuration(10 * time.Second),
+	})
+
+	end1 := l.Begin()
+	require.False(t, l.Shed("debug_traceTransaction"))
+	require.False(t, l.Shed("eth_call"))
+
+	// at the threshold, low priority methods are shed
+	end2 := l.Begin()
+	require.True(t, l.Shed("debug_traceTransaction"))
+	require.True(t, l.Shed("trace_block"))
+	require.False(t, l.Shed("eth_call"))
+	require.False(t, l.Shed("eth_sendRawTransaction"))
+	require.True(t, l.ShedPriority("eth_call", priorityLow))
+	require.False(t, l.ShedPriority("eth_sendRawTransaction", priorityLow))
+	require.False(t, l.ShedPriority("debug_traceTransaction", priorityHigh))
+
+	// past the critical pressure, everything but the protected methods is
+	end3 := l.Begin()
+	require.True(t, l.Shed("eth_call"))
+	require.False(t, l.Shed("eth_sendRawTransaction"))
+	require.False(t, l.Shed("eth_sendRawTransactionConditional"))
+	require.False(t, l.ShedPriority("eth_call", priorityHigh))
+
+	rec := httptest.NewRecorder()
+	l.SetRetryAfter(rec, []*RPCRes{NewRPCRes(nil, "0x1"), NewRPCErrorRes(nil, ErrOverloaded)})
+	require.Equal(t, "10", rec.Header().Get("Retry-After"))
+	rec = httptest.NewRecorder()
+	l.SetRetryAfter(rec, []*RPCRes{NewRPCRes(nil, "0x1")})
+	require.Empty(t, rec.Header().Get("Retry-After"))
+
+	end3()
+	end2()
+	end1()
+	l.Begin()()
+	require.False(t, l.Shed("debug_traceTransaction"))
+
+	var nilShedder *LoadShedder
+	nilShedder.Begin()()
+	require.False(t, nilShedder.Shed("debug_traceTransaction"))
+}
+
+func TestLoadShedderSampling(t *testing.T) {
+	// the test binary runs more than two goroutines
+	l := NewLoadShedder(LoadSheddingConfig{MaxGoroutines: 1, ProtectedMethods: []string{"eth_chainId"}})
+	l.sample()
+	require.True(t, l.Shed("eth_call"))
+	require.False(t, l.Shed("eth_chainId"))
+}
@@ -0,0 +1,187 @@
+package proxyd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// maxMaintenanceDuration bounds windows, which are found by looking back
+// minute by minute for their start.
+const maxMaintenanceDuration = 7 * 24 * time.Hour
+
+// cronSchedule is a standard five field cron expression: minute, hour, day
+// of month, month and day of week. Fields are *, numbers, ranges and lists,
+// with optional steps, such as */15 or 1-5/2. As in cron, a time matches if
+// either day field does when both are restricted.
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	domAny, dowAny                bool
+}
+
+var cronFieldBounds = [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 6}}
+
+func parseCronSchedule(expr string) (*cronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression %q must have 5 fields", expr)
+	}
+	var bits [5]uint64
+	for i, field := range fields {
+		b, err := parseCronField(field, cronFieldBounds[i][0], cronFieldBounds[i][1])
+		if err != nil {
+			return nil, fmt.Errorf("cron expression %q: %w", expr, err)
+		}
+		bits[i] = b
+	}
+	return &cronSchedule{
+		minute: bits[0],
+		hour:   bits[1],
+		dom:    bits[2],
+		month:  bits[3],
+		dow:    bits[4],
+		domAny: fields[2] == "*",
+		dowAny: fields[4] == "*",
+	}, nil
+}
+
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepStr); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q", part)
+			}
+		}
+		lo, hi := min, max
+		if rng != "*" {
+			loStr, hiStr, isRange := strings.Cut(rng, "-")
+			var err error
+			if lo, err = strconv.Atoi(loStr); err != nil {
+				return 0, fmt.Errorf("invalid value %q", part)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = strconv.Atoi(hiStr); err != nil {
+					return 0, fmt.Errorf("invalid value %q", part)
+				}
+			} else if hasStep {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("value %q out of range %d-%d", part, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func (c *cronSchedule) matches(t time.Time) bool {
+	if c.minute&(1<<uint(t.Minute())) == 0 || c.hour&(1<<uint(t.Hour())) == 0 || c.month&(1<<uint(t.Month())) == 0 {
+		return false
+	}
+	domMatch := c.dom&(1<<uint(t.Day())) != 0
+	dowMatch := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domAny || c.dowAny {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// maintenanceWindow starts at the times of a schedule, in UTC, and lasts for
+// a duration.
+type maintenanceWindow struct {
+	schedule *cronSchedule
+	duration time.Duration
+}
+
+func (w maintenanceWindow) active(now time.Time) bool {
+	now = now.UTC().Truncate(time.Minute)
+	for start := now; now.Sub(start) < w.duration; start = start.Add(-time.Minute) {
+		if w.schedule.matches(start) {
+			return true
+		}
+	}
+	return false
+}
+
+func newMaintenanceWindows(cfgs []MaintenanceWindowConfig) ([]maintenanceWindow, error) {
+	windows := make([]maintenanceWindow, 0, len(cfgs))
+	for _, cfg := range cfgs {
+		schedule, err := parseCronSchedule(cfg.Schedule)
+		if err != nil {
+			return nil, err
+		}
+		duration := time.Duration(cfg.Duration)
+		if duration <= 0 || duration > maxMaintenanceDuration {
+			return nil, fmt.Errorf("maintenance duration of %q must be between 1m and %s", cfg.Schedule, maxMaintenanceDuration)
+		}
+		windows = append(windows, maintenanceWindow{schedule: schedule, duration: duration})
+	}
+	return windows, nil
+}
+
+// backendMaintenance keeps whether a backend is in a maintenance window,
+// evaluated once a minute.
+type backendMaintenance struct {
+	windows []maintenanceWindow
+
+	mu        sync.Mutex
+	minute    time.Time
+	active    bool
+	evaluated bool
+}
+
+func WithMaintenanceWindows(windows []maintenanceWindow) BackendOpt {
+	return func(b *Backend) {
+		if len(windows) > 0 {
+			b.maintenance = &backendMaintenance{windows: windows}
+		}
+	}
+}
+
+// InMaintenance reports whether the backend is in one of its maintenance
+// windows, during which it's excluded from routing.
+func (b *Backend) InMaintenance() bool {
+	m := b.maintenance
+	if m == nil {
+		return false
+	}
+	now := time.Now().UTC()
+	minute := now.Truncate(time.Minute)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.evaluated && minute.Equal(m.minute) {
+		return m.active
+	}
+	active := false
+	for _, w := range m.windows {
+		if w.active(now) {
+			active = true
+			break
+		}
+	}
+	if m.evaluated && active != m.active {
+		if active {
+			log.Info("backend entered maintenance window, draining", "name", b.Name)
+		} else {
+			log.Info("backend left maintenance window, restoring", "name", b.Name)
+		}
+	}
+	if !m.evaluated || active != m.active {
+		RecordBackendMaintenance(b, active)
+	}
+	m.minute, m.active, m.evaluated = minute, active, true
+	return active
+}
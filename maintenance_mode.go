@@ -0,0 +1,185 @@
+package proxyd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const defaultMaintenanceMessage = "service under planned maintenance"
+
+// maintenanceGroupLabel labels the metrics and listing of a notice. The
+// global notice is keyed by an empty group.
+func maintenanceGroupLabel(group string) string {
+	if group == "" {
+		return "global"
+	}
+	return group
+}
+
+// MaintenanceMode answers the requests affected by a planned outage with an
+// error telling clients what's going on and when service is expected back,
+// rather than letting them time out on the backends. A notice applies to all
+// requests or to those routed to one backend group, optionally only for some
+// methods. Notices can be changed at runtime through the admin endpoint or
+// remote config.
+type MaintenanceMode struct {
+	groups     map[string]*BackendGroup
+	adminToken secretValue
+
+	mu      sync.RWMutex
+	notices map[string]*maintenanceNotice
+}
+
+type maintenanceNotice struct {
+	cfg     MaintenanceNoticeConfig
+	err     *RPCErr
+	methods map[string]bool
+}
+
+func NewMaintenanceMode(cfg MaintenanceModeConfig, groups map[string]*BackendGroup) (*MaintenanceMode, error) {
+	m := &MaintenanceMode{groups: groups}
+	m.adminToken.Store(cfg.AdminToken)
+	if err := m.Set(cfg); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func newMaintenanceNotice(cfg MaintenanceNoticeConfig) *maintenanceNotice {
+	message := cfg.Message
+	if message == "" {
+		message = defaultMaintenanceMessage
+	}
+	err := &RPCErr{
+		Code:          ErrMaintenance.Code,
+		Message:       message,
+		HTTPErrorCode: ErrMaintenance.HTTPErrorCode,
+	}
+	if cfg.Code != 0 {
+		err.Code = cfg.Code
+	}
+	if cfg.RestoreAt != nil {
+		err.Data = mustMarshalJSON(map[string]time.Time{"restore_at": cfg.RestoreAt.UTC()})
+	}
+	notice := &maintenanceNotice{cfg: cfg, err: err}
+	if len(cfg.Methods) > 0 {
+		notice.methods = make(map[string]bool, len(cfg.Methods))
+		for _, method := range cfg.Methods {
+			notice.methods[method] = true
+		}
+	}
+	return notice
+}
+
+// Set replaces all notices.
+func (m *MaintenanceMode) Set(cfg MaintenanceModeConfig) error {
+	notices := make(map[string]*maintenanceNotice)
+	if cfg.Enabled {
+		notices[""] = newMaintenanceNotice(cfg.MaintenanceNoticeConfig)
+	}
+	for group, gcfg := range cfg.Groups {
+		if m.groups[group] == nil {
+			return fmt.Errorf("maintenance_mode has undefined backend group %s", group)
+		}
+		if gcfg.Enabled {
+			notices[group] = newMaintenanceNotice(gcfg)
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for group := range m.notices {
+		if notices[group] == nil {
+			maintenanceModeGauge.WithLabelValues(maintenanceGroupLabel(group)).Set(0)
+		}
+	}
+	for group := range notices {
+		maintenanceModeGauge.WithLabelValues(maintenanceGroupLabel(group)).Set(1)
+	}
+	m.notices = notices
+	return nil
+}
+
+// SetNotice replaces the notice of one backend group, or the global notice
+// if group is empty.
+func (m *MaintenanceMode) SetNotice(group string, cfg MaintenanceNoticeConfig) error {
+	if group != "" && m.groups[group] == nil {
+		return fmt.Errorf("undefined backend group %s", group)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	notices := make(map[string]*maintenanceNotice, len(m.notices)+1)
+	for k, v := range m.notices {
+		notices[k] = v
+	}
+	if cfg.Enabled {
+		notices[group] = newMaintenanceNotice(cfg)
+		maintenanceModeGauge.WithLabelValues(maintenanceGroupLabel(group)).Set(1)
+	} else {
+		delete(notices, group)
+		maintenanceModeGauge.WithLabelValues(maintenanceGroupLabel(group)).Set(0)
+	}
+	m.notices = notices
+	return nil
+}
+
+// Check returns the error answering a request for method, routed to group,
+// if it's under maintenance.
+func (m *MaintenanceMode) Check(method, group string) *RPCErr {
+	if m == nil {
+		return nil
+	}
+	m.mu.RLock()
+	notices := m.notices
+	m.mu.RUnlock()
+	for _, name := range []string{"", group} {
+		if notice := notices[name]; notice != nil && (notice.methods == nil || notice.methods[method]) {
+			return notice.err
+		}
+	}
+	return nil
+}
+
+// ServeHTTP serves the maintenance endpoints:
+//
+//	GET /admin/maintenance           lists the enabled notices by group
+//	PUT /admin/maintenance           replaces the global notice
+//	PUT /admin/maintenance/{group}   replaces a backend group's notice
+func (m *MaintenanceMode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !checkAdminToken(w, r, m.adminToken.Load()) {
+		return
+	}
+
+	group := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/admin/maintenance"), "/")
+	switch r.Method {
+	case http.MethodGet:
+		m.mu.RLock()
+		notices := make(map[string]MaintenanceNoticeConfig, len(m.notices))
+		for group, notice := range m.notices {
+			notices[maintenanceGroupLabel(group)] = notice.cfg
+		}
+		m.mu.RUnlock()
+		writeAdminJSON(w, http.StatusOK, notices)
+	case http.MethodPut:
+		var cfg MaintenanceNoticeConfig
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&cfg); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := m.SetNotice(group, cfg); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Info("set maintenance notice", "group", group, "enabled", cfg.Enabled, "restore_at", cfg.RestoreAt)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,88 @@
+package proxyd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaintenanceMode(t *testing.T) {
+	var cfg MaintenanceModeConfig
+	_, err := toml.Decode(`
+admin_token = "admin"
+[groups.archive]
+enabled = true
+message = "archive nodes are being upgraded"
+restore_at = 2026-10-16T04:00:00Z
+methods = ["eth_getLogs"]
+`, &cfg)
+	require.NoError(t, err)
+
+	groups := map[string]*BackendGroup{"main": {Name: "main"}, "archive": {Name: "archive"}}
+	m, err := NewMaintenanceMode(cfg, groups)
+	require.NoError(t, err)
+
+	require.Nil(t, m.Check("eth_chainId", "archive"))
+	require.Nil(t, m.Check("eth_getLogs", "main"))
+	rpcErr := m.Check("eth_getLogs", "archive")
+	require.NotNil(t, rpcErr)
+	require.Equal(t, -32037, rpcErr.Code)
+	require.Equal(t, 503, rpcErr.HTTPErrorCode)
+	require.Equal(t, "archive nodes are being upgraded", rpcErr.Message)
+	require.JSONEq(t, `{"restore_at":"2026-10-16T04:00:00Z"}`, string(rpcErr.Data))
+
+	require.NoError(t, m.SetNotice("", MaintenanceNoticeConfig{Enabled: true, Code: -32099}))
+	rpcErr = m.Check("eth_chainId", "main")
+	require.NotNil(t, rpcErr)
+	require.Equal(t, -32099, rpcErr.Code)
+	require.Equal(t, defaultMaintenanceMessage, rpcErr.Message)
+	require.Nil(t, rpcErr.Data)
+
+	require.NoError(t, m.SetNotice("", MaintenanceNoticeConfig{}))
+	require.Nil(t, m.Check("eth_chainId", "main"))
+	require.NotNil(t, m.Check("eth_getLogs", "archive"))
+
+	require.Error(t, m.SetNotice("undefined", MaintenanceNoticeConfig{Enabled: true}))
+	_, err = NewMaintenanceMode(MaintenanceModeConfig{Groups: map[string]MaintenanceNoticeConfig{"undefined": {}}}, groups)
+	require.Error(t, err)
+
+	require.NoError(t, m.Set(MaintenanceModeConfig{}))
+	require.Nil(t, m.Check("eth_getLogs", "archive"))
+}
+
+func TestMaintenanceModeAdmin(t *testing.T) {
+	m, err := NewMaintenanceMode(MaintenanceModeConfig{AdminToken: "admin"}, map[string]*BackendGroup{"main": {Name: "main"}})
+	require.NoError(t, err)
+
+	do := func(method, path, token, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, r)
+		return w
+	}
+
+	require.Equal(t, http.StatusUnauthorized, do("PUT", "/admin/maintenance", "wrong", `{"enabled":true}`).Code)
+	require.Equal(t, http.StatusBadRequest, do("PUT", "/admin/maintenance/undefined", "admin", `{"enabled":true}`).Code)
+	require.Equal(t, http.StatusBadRequest, do("PUT", "/admin/maintenance", "admin", `{`).Code)
+
+	require.Equal(t, http.StatusNoContent, do("PUT", "/admin/maintenance/main", "admin", `{"enabled":true,"restore_at":"2026-10-16T04:00:00Z"}`).Code)
+	require.NotNil(t, m.Check("eth_chainId", "main"))
+
+	w := do("GET", "/admin/maintenance", "admin", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var notices map[string]MaintenanceNoticeConfig
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &notices))
+	require.Len(t, notices, 1)
+	require.True(t, notices["main"].Enabled)
+	require.Equal(t, time.Date(2026, 10, 16, 4, 0, 0, 0, time.UTC), notices["main"].RestoreAt.UTC())
+
+	require.Equal(t, http.StatusNoContent, do("PUT", "/admin/maintenance/main", "admin", `{"enabled":false}`).Code)
+	require.Nil(t, m.Check("eth_chainId", "main"))
+}
@@ -0,0 +1,64 @@
+package proxyd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCronSchedule(t *testing.T) {
+	for _, expr := range []string{"* * * * *", "0 3 * * 0", "*/15 1-5 1,15 * 1-5/2", "30 2 * 6 *"} {
+		_, err := parseCronSchedule(expr)
+		require.NoError(t, err, expr)
+	}
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "5-1 * * * *", "*/0 * * * *", "a * * * *"} {
+		_, err := parseCronSchedule(expr)
+		require.Error(t, err, expr)
+	}
+
+	// Sundays at 03:00
+	c, err := parseCronSchedule("0 3 * * 0")
+	require.NoError(t, err)
+	require.True(t, c.matches(time.Date(2024, 6, 2, 3, 0, 0, 0, time.UTC)))
+	require.False(t, c.matches(time.Date(2024, 6, 3, 3, 0, 0, 0, time.UTC)))
+	require.False(t, c.matches(time.Date(2024, 6, 2, 3, 1, 0, 0, time.UTC)))
+
+	// either day field matches when both are restricted
+	c, err = parseCronSchedule("0 0 1 * 1")
+	require.NoError(t, err)
+	require.True(t, c.matches(time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)))
+	require.True(t, c.matches(time.Date(2024, 6, 3, 0, 0, 0, 0, time.UTC)))
+	require.False(t, c.matches(time.Date(2024, 6, 4, 0, 0, 0, 0, time.UTC)))
+}
+
+func TestMaintenanceWindow(t *testing.T) {
+	windows, err := newMaintenanceWindows([]MaintenanceWindowConfig{
+		{Schedule: "50 23 * * *", Duration: TOMLDuration(30 * time.Minute)},
+	})
+	require.NoError(t, err)
+	w := windows[0]
+
+	// windows run past midnight
+	require.False(t, w.active(time.Date(2024, 6, 1, 23, 49, 59, 0, time.UTC)))
+	require.True(t, w.active(time.Date(2024, 6, 1, 23, 50, 0, 0, time.UTC)))
+	require.True(t, w.active(time.Date(2024, 6, 2, 0, 19, 59, 0, time.UTC)))
+	require.False(t, w.active(time.Date(2024, 6, 2, 0, 20, 0, 0, time.UTC)))
+
+	_, err = newMaintenanceWindows([]MaintenanceWindowConfig{{Schedule: "0 * * * *"}})
+	require.Error(t, err)
+
+	b := NewBackend("b", "http://b:8545", "", nil)
+	require.False(t, b.InMaintenance())
+	windows, err = newMaintenanceWindows([]MaintenanceWindowConfig{
+		{Schedule: "* * * * *", Duration: TOMLDuration(time.Minute)},
+	})
+	require.NoError(t, err)
+	b.Override(WithMaintenanceWindows(windows))
+	require.True(t, b.InMaintenance())
+
+	bg := &BackendGroup{Name: "main", Backends: []*Backend{b, NewBackend("a", "http://a:8545", "", nil)}}
+	ordered := bg.orderedBackendsForRequest()
+	require.Len(t, ordered, 1)
+	require.Equal(t, "a", ordered[0].Name)
+}
@@ -0,0 +1,99 @@
+package proxyd
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MethodTimeouts overrides the request timeout for some methods. Keys are
+// either method names or prefixes ending in "*", like "debug_*". An exact
+// name takes precedence over prefixes, and longer prefixes over shorter ones.
+type MethodTimeouts struct {
+	exact    map[string]time.Duration
+	prefixes []methodTimeoutPrefix
+	max      time.Duration
+}
+
+type methodTimeoutPrefix struct {
+	prefix  string
+	timeout time.Duration
+}
+
+func NewMethodTimeouts(cfg map[string]TOMLDuration) *MethodTimeouts {
+	if len(cfg) == 0 {
+		return nil
+	}
+	m := &MethodTimeouts{exact: make(map[string]time.Duration)}
+	for key, timeout := range cfg {
+		d := time.Duration(timeout)
+		m.max = max(m.max, d)
+		if prefix, ok := strings.CutSuffix(key, "*"); ok {
+			m.prefixes = append(m.prefixes, methodTimeoutPrefix{prefix: prefix, timeout: d})
+			continue
+		}
+		m.exact[key] = d
+	}
+	sort.Slice(m.prefixes, func(i, j int) bool {
+		return len(m.prefixes[i].prefix) > len(m.prefixes[j].prefix)
+	})
+	return m
+}
+
+// Lookup returns the timeout configured for method.
+func (m *MethodTimeouts) Lookup(method string) (time.Duration, bool) {
+	if m == nil {
+		return 0, false
+	}
+	if d, ok := m.exact[method]; ok {
+		return d, true
+	}
+	for _, p := range m.prefixes {
+		if strings.HasPrefix(method, p.prefix) {
+			return p.timeout, true
+		}
+	}
+	return 0, false
+}
+
+// Timeout returns the timeout for a request, or a batch, calling methods:
+// the longest timeout of any of them, where methods without an override take
+// def.
+func (m *MethodTimeouts) Timeout(def time.Duration, methods ...string) time.Duration {
+	if m == nil || len(methods) == 0 {
+		return def
+	}
+	var timeout time.Duration
+	for _, method := range methods {
+		d, ok := m.Lookup(method)
+		if !ok {
+			d = def
+		}
+		timeout = max(timeout, d)
+	}
+	return timeout
+}
+
+// Max returns the longest timeout any request can get.
+func (m *MethodTimeouts) Max(def time.Duration) time.Duration {
+	if m == nil {
+		return def
+	}
+	return max(def, m.max)
+}
+
+// requestMethods returns the methods called by reqs, skipping those that
+// don't parse.
+func requestMethods(reqs []json.RawMessage) []string {
+	methods := make([]string, 0, len(reqs))
+	for _, raw := range reqs {
+		var req struct {
+			Method string `json:"method"`
+		}
+		if json.Unmarshal(raw, &req) == nil {
+			methods = append(methods, req.Method)
+		}
+	}
+	return methods
+}
@@ -0,0 +1,35 @@
+package proxyd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMethodTimeouts(t *testing.T) {
+	require.Nil(t, NewMethodTimeouts(nil))
+
+	m := NewMethodTimeouts(map[string]TOMLDuration{
+		"debug_*":                TOMLDuration(60 * time.Second),
+		"debug_trace*":           TOMLDuration(120 * time.Second),
+		"debug_traceTransaction": TOMLDuration(30 * time.Second),
+		"eth_blockNumber":        TOMLDuration(5 * time.Second),
+	})
+
+	d, ok := m.Lookup("debug_traceTransaction")
+	require.True(t, ok)
+	require.Equal(t, 30*time.Second, d)
+	d, _ = m.Lookup("debug_traceBlockByNumber")
+	require.Equal(t, 120*time.Second, d)
+	d, _ = m.Lookup("debug_getRawBlock")
+	require.Equal(t, 60*time.Second, d)
+	_, ok = m.Lookup("eth_call")
+	require.False(t, ok)
+
+	require.Equal(t, 5*time.Second, m.Timeout(10*time.Second, "eth_blockNumber"))
+	require.Equal(t, 10*time.Second, m.Timeout(10*time.Second, "eth_blockNumber", "eth_call"))
+	require.Equal(t, 120*time.Second, m.Timeout(10*time.Second, "eth_call", "debug_traceCall"))
+	require.Equal(t, 10*time.Second, m.Timeout(10*time.Second))
+	require.Equal(t, 120*time.Second, m.Max(10*time.Second))
+}
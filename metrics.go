@@ -24,6 +24,13 @@ const (
 	SourceClient  = "client"
 	SourceBackend = "backend"
 	MethodUnknown = "unknown"
+
+	RejectReasonTooLarge      = "too_large"
+	RejectReasonBadJSON       = "bad_json"
+	RejectReasonMethodBlocked = "method_blocked"
+	RejectReasonRateLimited   = "rate_limited"
+	RejectReasonBatchTooBig   = "batch_too_big"
+	RejectReasonAuthFailed    = "auth_failed"
 )
 
 var PayloadSizeBuckets = []float64{10, 50, 100, 500, 1000, 5000, 10000, 100000, 1000000}
@@ -70,6 +77,78 @@ var (
 		"strategy",
 	})
 
+	interopValidationCacheTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "interop_validation_cache_total",
+		Help:      "Count of interop validation cache lookups by outcome.",
+	}, []string{
+		"outcome",
+	})
+
+	interopValidationsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "interop_validations_total",
+		Help:      "Count of enforced interop validations by outcome and rejection reason.",
+	}, []string{
+		"outcome",
+		"reason",
+	})
+
+	interopMessagesCheckedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "interop_messages_checked_total",
+		Help:      "Count of executing messages in the access lists of interop transactions.",
+	})
+
+	interopStaticRejectionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "interop_static_rejections_total",
+		Help:      "Count of interop access lists rejected without asking a supervisor, by failed check.",
+	}, []string{
+		"check",
+	})
+
+	interopAdvisoryValidationsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "interop_advisory_validations_total",
+		Help:      "Count of interop validations in advisory mode by outcome.",
+	}, []string{
+		"outcome",
+	})
+
+	supervisorRequestDurationSumm = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: MetricsNamespace,
+		Name:      "supervisor_request_duration_milliseconds",
+		Help:      "Histogram of interop validating backend request durations, in milliseconds.",
+		Buckets:   MillisecondDurationBuckets,
+	}, []string{
+		"supervisor_url",
+		"outcome",
+	})
+
+	supervisorBanned = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: MetricsNamespace,
+		Name:      "supervisor_banned",
+		Help:      "Bool gauge for banned interop validating backends.",
+	}, []string{
+		"supervisor_url",
+	})
+
+	interopValidationFailOpenTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "interop_validation_fail_open_total",
+		Help:      "Count of transactions forwarded because no interop validating backend could be reached.",
+	})
+
+	interopAdminChangesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "interop_admin_changes_total",
+		Help:      "Count of interop validation settings changed through the admin endpoint.",
+	}, []string{
+		"action",
+		"persisted",
+	})
+
 	rpcErrorsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
 		Namespace: MetricsNamespace,
 		Name:      "rpc_errors_total",
@@ -119,6 +198,39 @@ var (
 		"backend_name",
 	})
 
+	wsSlowConsumersTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "ws_slow_consumers_total",
+		Help:      "Count of websocket clients disconnected, or notifications dropped, because the client's send buffer was full.",
+	}, []string{
+		"backend_name",
+		"policy",
+	})
+
+	wsReplayedNotificationsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "ws_replayed_notifications_total",
+		Help:      "Count of subscription notifications replayed to websocket clients subscribing with a cursor.",
+	}, []string{
+		"backend_name",
+	})
+
+	wsNotificationBatchSize = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: MetricsNamespace,
+		Name:      "ws_notification_batch_size",
+		Help:      "Histogram of the number of notifications in batches sent to websocket clients.",
+		Buckets:   []float64{1, 2, 5, 10, 25, 50, 100, 250},
+	})
+
+	healthGossipReportsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "health_gossip_reports_total",
+		Help:      "Count of backends reported failing by other proxyd instances.",
+	}, []string{
+		"backend_name",
+		"reason",
+	})
+
 	unserviceableRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
 		Namespace: MetricsNamespace,
 		Name:      "unserviceable_requests_total",
@@ -136,6 +248,15 @@ var (
 		"status_code",
 	})
 
+	requestStageDurationHist = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: MetricsNamespace,
+		Name:      "request_stage_duration_seconds",
+		Help:      "Histogram of the time HTTP RPC requests spend in each stage, in seconds.",
+		Buckets:   prometheus.ExponentialBuckets(0.0001, 4, 10),
+	}, []string{
+		"stage",
+	})
+
 	httpRequestDurationSumm = promauto.NewSummary(prometheus.SummaryOpts{
 		Namespace:  MetricsNamespace,
 		Name:       "http_request_duration_seconds",
@@ -195,6 +316,24 @@ var (
 		"method",
 	})
 
+	immutableCacheRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "immutable_cache_requests_total",
+		Help:      "Count of immutable cache lookups and stores, by method and outcome.",
+	}, []string{
+		"method",
+		"outcome",
+	})
+
+	cacheWarmingRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "cache_warming_requests_total",
+		Help:      "Count of warmed cache stores and lookups, by method and outcome.",
+	}, []string{
+		"method",
+		"outcome",
+	})
+
 	cacheErrorsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
 		Namespace: MetricsNamespace,
 		Name:      "cache_errors_total",
@@ -209,6 +348,180 @@ var (
 		Help:      "Count of total batch RPC short-circuits.",
 	})
 
+	canceledRPCRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "canceled_rpc_requests_total",
+		Help:      "Count of requests, or batch items, canceled because the client went away, by whether they were in flight upstream or not sent yet.",
+	}, []string{
+		"method_name",
+		"stage",
+	})
+
+	tenantRPCRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "tenant_rpc_requests_total",
+		Help:      "Count of whitelisted requests of each tenant.",
+	}, []string{
+		"tenant",
+		"method_name",
+		"backend_group",
+	})
+
+	signerClassRPCRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "signer_class_rpc_requests_total",
+		Help:      "Count of whitelisted requests signed by signers of each signer class.",
+	}, []string{
+		"signer_class",
+		"method_name",
+		"backend_group",
+	})
+
+	flashbotsSignaturesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "flashbots_signatures_total",
+		Help:      "Count of X-Flashbots-Signature headers verified by proxyd, by whether they were valid.",
+	}, []string{
+		"valid",
+	})
+
+	cassetteRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "cassette_requests_total",
+		Help:      "Count of backend requests recorded to or replayed from the cassette, by backend and outcome.",
+	}, []string{
+		"backend_name",
+		"outcome",
+	})
+
+	injectedFaultsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "injected_faults_total",
+		Help:      "Count of faults injected into backend requests, by backend and fault.",
+	}, []string{
+		"backend_name",
+		"fault",
+	})
+
+	estimateGasCacheHitsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "estimate_gas_cache_hits_total",
+		Help:      "Count of eth_estimateGas requests answered with the estimate for an identical earlier call.",
+	})
+
+	estimateGasClampedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "estimate_gas_clamped_total",
+		Help:      "Count of eth_estimateGas results changed by the multiplier or ceiling.",
+	})
+
+	txDedupHitsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "tx_dedup_hits_total",
+		Help:      "Count of transaction submissions answered with the result of an identical earlier one.",
+	}, []string{
+		"method",
+	})
+
+	earlyReturnTransactionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "early_return_transactions_total",
+		Help:      "Count of early returned transactions, by outcome of forwarding them: pending when returned, then accepted or failed.",
+	}, []string{
+		"outcome",
+	})
+
+	maintenanceModeGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: MetricsNamespace,
+		Name:      "maintenance_mode",
+		Help:      "Whether a maintenance notice is enabled, globally or for a backend group.",
+	}, []string{
+		"backend_group",
+	})
+
+	ipFilterBlockedRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "ip_filter_blocked_requests_total",
+		Help:      "Count of requests blocked by the IP filter, by the rule blocking them.",
+	}, []string{
+		"rule",
+	})
+
+	frontendLimitRejectionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "frontend_limit_rejections_total",
+		Help:      "Count of requests and connections rejected by the frontend DoS limits, by limit.",
+	}, []string{
+		"reason",
+	})
+
+	deprecatedMethodRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "deprecated_method_requests_total",
+		Help:      "Count of requests for deprecated methods, by auth alias and whether they were warned or blocked.",
+	}, []string{
+		"auth",
+		"method",
+		"outcome",
+	})
+
+	selectorRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "selector_requests_total",
+		Help:      "Count of eth_call, eth_estimateGas and eth_sendRawTransaction requests by the name of the selector they call.",
+	}, []string{
+		"method",
+		"selector",
+	})
+
+	selectorRateLimitedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "selector_rate_limited_total",
+		Help:      "Count of requests rejected by the rate limit of a selector rule.",
+	}, []string{
+		"selector",
+	})
+
+	senderReputationEventsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "sender_reputation_events_total",
+		Help:      "Count of events scored into sender reputations, by event.",
+	}, []string{
+		"event",
+	})
+
+	lowReputationSubmissionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "low_reputation_submissions_total",
+		Help:      "Count of submissions of low reputation senders that were limited, routed or deprioritized.",
+	}, []string{
+		"action",
+	})
+
+	usageExportsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "usage_exports_total",
+		Help:      "Count of hourly usage reports that were uploaded, failed to upload or dropped.",
+	}, []string{
+		"outcome",
+	})
+
+	rejectedRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "rejected_requests_total",
+		Help:      "Count of requests proxyd rejected before forwarding them, by reason.",
+	}, []string{
+		"reason",
+	})
+
+	strictJSONRPCViolationsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "strict_jsonrpc_violations_total",
+		Help:      "Count of requests violating the JSON-RPC 2.0 spec in strict mode, whether or not they were rejected.",
+	}, []string{
+		"reason",
+	})
+
 	rpcSpecialErrors = []string{
 		"nonce too low",
 		"gas price too high",
@@ -425,6 +738,56 @@ var (
 		"backend_name",
 	})
 
+	backendScore = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: MetricsNamespace,
+		Name:      "backend_score",
+		Help:      "Composite health score per backend, from 0 to 1",
+	}, []string{
+		"backend_name",
+	})
+
+	backendInMaintenance = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: MetricsNamespace,
+		Name:      "backend_in_maintenance",
+		Help:      "Bool gauge for backends in a maintenance window",
+	}, []string{
+		"backend_name",
+	})
+
+	backendDerivationLag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: MetricsNamespace,
+		Name:      "backend_derivation_lag",
+		Help:      "L1 blocks the L1 origin of a backend's safe or finalized head is behind L1",
+	}, []string{
+		"backend_name",
+		"head",
+	})
+
+	backendDerivationStalled = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: MetricsNamespace,
+		Name:      "backend_derivation_stalled",
+		Help:      "Bool gauge for backends whose derivation lags too far behind L1",
+	}, []string{
+		"backend_name",
+	})
+
+	backendLeader = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: MetricsNamespace,
+		Name:      "backend_leader",
+		Help:      "Bool gauge for backends that are the sequencer leader",
+	}, []string{
+		"backend_name",
+	})
+
+	latestTranslationsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "latest_translations_total",
+		Help:      "Count of requests to a backend lagging its group head with latest translated into the common height.",
+	}, []string{
+		"backend_group_name",
+		"backend_name",
+	})
+
 	degradedBackends = promauto.NewGaugeVec(prometheus.GaugeOpts{
 		Namespace: MetricsNamespace,
 		Name:      "backend_degraded",
@@ -477,54 +840,383 @@ var (
 		"backend_name",
 		"error",
 	})
-)
 
-func RecordRedisError(source string) {
-	redisErrorsTotal.WithLabelValues(source).Inc()
-}
+	pluginErrorsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "plugin_errors_total",
+		Help:      "Count of plugin hook errors.",
+	}, []string{
+		"plugin",
+		"hook",
+	})
 
-func RecordRPCError(ctx context.Context, backendName, method string, err error) {
-	rpcErr, ok := err.(*RPCErr)
-	var code int
-	if ok {
-		MaybeRecordSpecialRPCError(ctx, backendName, method, rpcErr)
-		code = rpcErr.Code
-	} else {
-		code = -1
-	}
+	policyDecisionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "policy_decisions_total",
+		Help:      "Count of policy service decisions, including errors.",
+	}, []string{
+		"decision",
+	})
 
-	rpcErrorsTotal.WithLabelValues(GetAuthCtx(ctx), backendName, method, strconv.Itoa(code)).Inc()
-}
+	policyCalloutDurationSumm = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: MetricsNamespace,
+		Name:      "policy_callout_duration_milliseconds",
+		Help:      "Histogram of policy service callout durations, in milliseconds.",
+		Buckets:   MillisecondDurationBuckets,
+	})
 
-func RecordWSMessage(ctx context.Context, backendName, source string) {
-	wsMessagesTotal.WithLabelValues(GetAuthCtx(ctx), backendName, source).Inc()
-}
+	eventsPublishedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "events_published_total",
+		Help:      "Count of request events published to the event stream.",
+	})
 
-func RecordUnserviceableRequest(ctx context.Context, source string) {
-	unserviceableRequestsTotal.WithLabelValues(GetAuthCtx(ctx), source).Inc()
-}
+	eventsDroppedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "events_dropped_total",
+		Help:      "Count of request events dropped because the buffer was full or publishing failed.",
+	})
 
-func RecordRPCForward(ctx context.Context, backendName, method, source string) {
-	rpcForwardsTotal.WithLabelValues(GetAuthCtx(ctx), backendName, method, source).Inc()
-}
+	webhookDeliveriesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "webhook_deliveries_total",
+		Help:      "Count of webhook deliveries by event type and outcome.",
+	}, []string{
+		"type",
+		"outcome",
+	})
 
-func MaybeRecordSpecialRPCError(ctx context.Context, backendName, method string, rpcErr *RPCErr) {
-	errMsg := strings.ToLower(rpcErr.Message)
-	for _, errStr := range rpcSpecialErrors {
-		if strings.Contains(errMsg, errStr) {
-			rpcSpecialErrorsTotal.WithLabelValues(GetAuthCtx(ctx), backendName, method, errStr).Inc()
-			return
-		}
-	}
-}
+	secretRefreshesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "secret_refreshes_total",
+		Help:      "Count of secret refreshes by provider and status.",
+	}, []string{
+		"provider",
+		"status",
+	})
 
-func RecordRequestPayloadSize(ctx context.Context, payloadSize int) {
-	requestPayloadSizesGauge.WithLabelValues(GetAuthCtx(ctx)).Observe(float64(payloadSize))
-}
+	remoteConfigUpdatesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "remote_config_updates_total",
+		Help:      "Count of remote config updates by status.",
+	}, []string{
+		"status",
+	})
 
-func RecordResponsePayloadSize(ctx context.Context, payloadSize int) {
-	responsePayloadSizesGauge.WithLabelValues(GetAuthCtx(ctx)).Observe(float64(payloadSize))
-}
+	discoveredBackendsGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: MetricsNamespace,
+		Name:      "discovered_backends",
+		Help:      "Number of backends discovered for a backend group.",
+	}, []string{
+		"backend_group_name",
+	})
+
+	discoveryErrorsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "discovery_errors_total",
+		Help:      "Count of failed backend discovery attempts.",
+	}, []string{
+		"backend_group_name",
+	})
+
+	featureFlagEvaluationsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "feature_flag_evaluations_total",
+		Help:      "Count of feature flag evaluations by flag and result.",
+	}, []string{
+		"flag",
+		"enabled",
+	})
+
+	blobTransactionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "blob_transactions_total",
+		Help:      "Count of blob transactions submitted by whether they were accepted.",
+	}, []string{
+		"accepted",
+	})
+
+	blobsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "blobs_total",
+		Help:      "Count of blobs in submitted blob transactions by whether they were accepted.",
+	}, []string{
+		"accepted",
+	})
+
+	txFeeRejectionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "tx_fee_rejections_total",
+		Help:      "Count of raw transactions rejected by the fee filter.",
+	}, []string{
+		"fee",
+		"reason",
+	})
+
+	pendingTxLimitExceededTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "pending_tx_limit_exceeded_total",
+		Help:      "Count of raw transactions rejected because the sender had too many pending transactions.",
+	})
+
+	pendingNonceOverridesTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "pending_nonce_overrides_total",
+		Help:      "Count of eth_getTransactionCount pending responses raised to the pending nonce known to proxyd.",
+	})
+
+	gasOracleRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "gas_oracle_requests_total",
+		Help:      "Count of fee requests answered by the gas oracle by method and whether they were warm.",
+	}, []string{
+		"method",
+		"hit",
+	})
+
+	activeFiltersGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: MetricsNamespace,
+		Name:      "active_filters",
+		Help:      "Gauge of filters installed in proxyd.",
+	})
+
+	affinityRepinsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "affinity_repins_total",
+		Help:      "Count of clients moved to another backend because their pinned backend couldn't serve them.",
+	}, []string{
+		"backend_group",
+	})
+
+	readYourWritesRoutesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "read_your_writes_routes_total",
+		Help:      "Count of reads routed to the backend that accepted the sender's recent transaction.",
+	}, []string{
+		"method",
+	})
+
+	receiptAggregationsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "receipt_aggregations_total",
+		Help:      "Count of null receipts asked from the other backends of the group, by whether one had it.",
+	}, []string{
+		"backend_group",
+		"found",
+	})
+
+	routingRuleMatchesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "routing_rule_matches_total",
+		Help:      "Count of requests routed by a routing rule, by the backend group they were routed to.",
+	}, []string{
+		"method",
+		"backend_group",
+	})
+
+	txPreferencesAppliedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "tx_preferences_applied_total",
+		Help:      "Count of transactions submitted with stored preferences, by whether they were the sender's or the key's.",
+	}, []string{
+		"owner",
+	})
+
+	signatureReplaysTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "signature_replays_total",
+		Help:      "Count of signed requests rejected for reusing a signature, by signature scheme.",
+	}, []string{
+		"scheme",
+	})
+
+	txAuditDroppedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "tx_audit_dropped_total",
+		Help:      "Count of tx audit records dropped because the buffer was full or the sink failed.",
+	})
+
+	rateLimitExemptionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "rate_limit_exemptions_total",
+		Help:      "Count of requests bypassing the rate limits, by why they're exempt.",
+	}, []string{
+		"reason",
+	})
+
+	callPolicyRejectionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "call_policy_rejections_total",
+		Help:      "Count of eth_call and eth_estimateGas requests rejected by a call policy, by policy.",
+	}, []string{
+		"policy",
+	})
+
+	rateLimitFallback = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: MetricsNamespace,
+		Name:      "rate_limit_fallback",
+		Help:      "Bool gauge for rate limiters that fell back to memory because their primary, Redis, failed",
+	}, []string{
+		"limiter",
+	})
+
+	rateLimitFallbackTakesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "rate_limit_fallback_takes_total",
+		Help:      "Count of rate limits taken from the in-memory fallback limiter",
+	}, []string{
+		"limiter",
+	})
+
+	redisDegraded = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: MetricsNamespace,
+		Name:      "redis_degraded",
+		Help:      "Bool gauge for whether Redis is unavailable and proxyd is in degraded mode",
+	})
+
+	rollupConsensusTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "rollup_consensus_total",
+		Help:      "Count of rollup methods served from every backend of the group, by whether they agreed.",
+	}, []string{
+		"backend_group",
+		"method",
+		"agreed",
+	})
+
+	blockReceiptsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "block_receipts_total",
+		Help:      "Count of eth_getTransactionReceipt requests of the same block batched into eth_getBlockReceipts, by whether it served them or they fell back to individual requests.",
+	}, []string{
+		"backend_group",
+		"outcome",
+	})
+
+	capabilitySkipsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "capability_skips_total",
+		Help:      "Count of requests that skipped a backend probed not to serve one of their methods.",
+	}, []string{
+		"backend_name",
+	})
+
+	notFoundRetriesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "not_found_retries_total",
+		Help:      "Count of null responses for known blocks and transactions retried, by whether a retry found them.",
+	}, []string{
+		"method",
+		"found",
+	})
+
+	loadSheddingLevelGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: MetricsNamespace,
+		Name:      "load_shedding_level",
+		Help:      "Load shedding level: 0 for none, 1 for low priority methods, 2 for all but protected methods.",
+	})
+
+	loadShedRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "load_shed_requests_total",
+		Help:      "Count of requests rejected by load shedding.",
+	}, []string{
+		"method",
+	})
+
+	inflightRequests = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: MetricsNamespace,
+		Name:      "inflight_requests",
+		Help:      "Requests in flight, globally or per backend group.",
+	}, []string{
+		"scope",
+	})
+
+	inflightQueueDepth = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: MetricsNamespace,
+		Name:      "inflight_queue_depth",
+		Help:      "Requests waiting for an in-flight slot, globally or per backend group.",
+	}, []string{
+		"scope",
+	})
+
+	inflightQueueWait = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: MetricsNamespace,
+		Name:      "inflight_queue_wait_milliseconds",
+		Help:      "Histogram of the time requests waited for an in-flight slot, in milliseconds.",
+		Buckets:   MillisecondDurationBuckets,
+	}, []string{
+		"scope",
+	})
+
+	inflightRejectionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "inflight_rejections_total",
+		Help:      "Count of requests rejected for lack of an in-flight slot.",
+	}, []string{
+		"scope",
+		"reason",
+	})
+
+	rawTransactionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "raw_transactions_total",
+		Help:      "Count of raw transactions decoded by transaction type.",
+	}, []string{
+		"tx_type",
+	})
+)
+
+func RecordRedisError(source string) {
+	redisErrorsTotal.WithLabelValues(source).Inc()
+}
+
+func RecordRPCError(ctx context.Context, backendName, method string, err error) {
+	rpcErr, ok := err.(*RPCErr)
+	var code int
+	if ok {
+		MaybeRecordSpecialRPCError(ctx, backendName, method, rpcErr)
+		code = rpcErr.Code
+	} else {
+		code = -1
+	}
+
+	rpcErrorsTotal.WithLabelValues(GetAuthCtx(ctx), backendName, method, strconv.Itoa(code)).Inc()
+}
+
+func RecordWSMessage(ctx context.Context, backendName, source string) {
+	wsMessagesTotal.WithLabelValues(GetAuthCtx(ctx), backendName, source).Inc()
+}
+
+func RecordUnserviceableRequest(ctx context.Context, source string) {
+	unserviceableRequestsTotal.WithLabelValues(GetAuthCtx(ctx), source).Inc()
+}
+
+func RecordRPCForward(ctx context.Context, backendName, method, source string) {
+	rpcForwardsTotal.WithLabelValues(GetAuthCtx(ctx), backendName, method, source).Inc()
+}
+
+func MaybeRecordSpecialRPCError(ctx context.Context, backendName, method string, rpcErr *RPCErr) {
+	errMsg := strings.ToLower(rpcErr.Message)
+	for _, errStr := range rpcSpecialErrors {
+		if strings.Contains(errMsg, errStr) {
+			rpcSpecialErrorsTotal.WithLabelValues(GetAuthCtx(ctx), backendName, method, errStr).Inc()
+			return
+		}
+	}
+}
+
+func RecordRequestPayloadSize(ctx context.Context, payloadSize int) {
+	requestPayloadSizesGauge.WithLabelValues(GetAuthCtx(ctx)).Observe(float64(payloadSize))
+}
+
+func RecordResponsePayloadSize(ctx context.Context, payloadSize int) {
+	responsePayloadSizesGauge.WithLabelValues(GetAuthCtx(ctx)).Observe(float64(payloadSize))
+}
+
+func RecordCacheWarming(method, outcome string) {
+	cacheWarmingRequestsTotal.WithLabelValues(method, outcome).Inc()
+}
 
 func RecordCacheHit(method string) {
 	cacheHitsTotal.WithLabelValues(method).Inc()
@@ -538,6 +1230,10 @@ func RecordCacheError(method string) {
 	cacheErrorsTotal.WithLabelValues(method).Inc()
 }
 
+func RecordImmutableCache(method, outcome string) {
+	immutableCacheRequestsTotal.WithLabelValues(method, outcome).Inc()
+}
+
 func RecordBatchSize(size int) {
 	batchSizeHistogram.Observe(float64(size))
 }
@@ -629,6 +1325,46 @@ func RecordConsensusBackendUpdateDelay(b *Backend, lastUpdate time.Time) {
 	consensusUpdateDelayBackend.WithLabelValues(b.Name).Set(float64(delay.Milliseconds()))
 }
 
+func RecordBackendMaintenance(b *Backend, active bool) {
+	backendInMaintenance.WithLabelValues(b.Name).Set(boolToFloat64(active))
+}
+
+func RecordBackendLeader(b *Backend, leader bool) {
+	backendLeader.WithLabelValues(b.Name).Set(boolToFloat64(leader))
+}
+
+func RecordWSSlowConsumer(backendName, policy string) {
+	wsSlowConsumersTotal.WithLabelValues(backendName, policy).Inc()
+}
+
+func RecordWSReplay(backendName string, notifications int) {
+	wsReplayedNotificationsTotal.WithLabelValues(backendName).Add(float64(notifications))
+}
+
+func RecordWSNotificationBatch(size int) {
+	wsNotificationBatchSize.Observe(float64(size))
+}
+
+func RecordHealthGossipReport(backendName, reason string) {
+	healthGossipReportsTotal.WithLabelValues(backendName, reason).Inc()
+}
+
+func RecordLatestTranslation(group, backend string) {
+	latestTranslationsTotal.WithLabelValues(group, backend).Inc()
+}
+
+func RecordBackendDerivationLag(b *Backend, head string, lag uint64) {
+	backendDerivationLag.WithLabelValues(b.Name, head).Set(float64(lag))
+}
+
+func RecordBackendDerivationStalled(b *Backend, stalled bool) {
+	backendDerivationStalled.WithLabelValues(b.Name).Set(boolToFloat64(stalled))
+}
+
+func RecordBackendScore(b *Backend, score float64) {
+	backendScore.WithLabelValues(b.Name).Set(score)
+}
+
 func RecordBackendNetworkLatencyAverageSlidingWindow(b *Backend, avgLatency time.Duration) {
 	avgLatencyBackend.WithLabelValues(b.Name).Set(float64(avgLatency.Milliseconds()))
 	degradedBackends.WithLabelValues(b.Name).Set(boolToFloat64(b.IsDegraded()))
@@ -650,6 +1386,293 @@ func RecordBackendGroupMulticallCompletion(bg *BackendGroup, backendName string,
 	backendGroupMulticallCompletionCounter.WithLabelValues(bg.Name, backendName, error).Inc()
 }
 
+func RecordPluginError(plugin, hook string) {
+	pluginErrorsTotal.WithLabelValues(plugin, hook).Inc()
+}
+
+func RecordPolicyDecision(decision string) {
+	policyDecisionsTotal.WithLabelValues(decision).Inc()
+}
+
+func RecordEventsPublished(count int) {
+	eventsPublishedTotal.Add(float64(count))
+}
+
+func RecordEventsDropped(count int) {
+	eventsDroppedTotal.Add(float64(count))
+}
+
+func RecordWebhookDelivery(eventType, outcome string) {
+	webhookDeliveriesTotal.WithLabelValues(eventType, outcome).Inc()
+}
+
+func RecordSecretRefresh(provider, status string) {
+	secretRefreshesTotal.WithLabelValues(provider, status).Inc()
+}
+
+func RecordRemoteConfigUpdate(status string) {
+	remoteConfigUpdatesTotal.WithLabelValues(status).Inc()
+}
+
+func RecordDiscoveredBackends(group string, count int) {
+	discoveredBackendsGauge.WithLabelValues(group).Set(float64(count))
+}
+
+func RecordDiscoveryError(group string) {
+	discoveryErrorsTotal.WithLabelValues(group).Inc()
+}
+
+func RecordFeatureFlagEvaluation(flag string, enabled bool) {
+	featureFlagEvaluationsTotal.WithLabelValues(flag, strconv.FormatBool(enabled)).Inc()
+}
+
+func RecordRawTransaction(txType string) {
+	rawTransactionsTotal.WithLabelValues(txType).Inc()
+}
+
+func RecordBlobTransaction(blobs int, accepted bool) {
+	blobTransactionsTotal.WithLabelValues(strconv.FormatBool(accepted)).Inc()
+	blobsTotal.WithLabelValues(strconv.FormatBool(accepted)).Add(float64(blobs))
+}
+
+func RecordTxFeeRejection(fee, reason string) {
+	txFeeRejectionsTotal.WithLabelValues(fee, reason).Inc()
+}
+
+func RecordPendingTxLimitExceeded() {
+	pendingTxLimitExceededTotal.Inc()
+}
+
+func RecordPendingNonceOverride() {
+	pendingNonceOverridesTotal.Inc()
+}
+
+func RecordGasOracleRequest(method string, hit bool) {
+	gasOracleRequestsTotal.WithLabelValues(method, strconv.FormatBool(hit)).Inc()
+}
+
+func RecordActiveFilters(count int) {
+	activeFiltersGauge.Set(float64(count))
+}
+
+func RecordAffinityRepin(group string) {
+	affinityRepinsTotal.WithLabelValues(group).Inc()
+}
+
+func RecordReadYourWritesRoute(method string) {
+	readYourWritesRoutesTotal.WithLabelValues(method).Inc()
+}
+
+func RecordBlockReceipts(group, outcome string, count int) {
+	blockReceiptsTotal.WithLabelValues(group, outcome).Add(float64(count))
+}
+
+func RecordCapabilitySkip(backendName string) {
+	capabilitySkipsTotal.WithLabelValues(backendName).Inc()
+}
+
+func RecordReceiptAggregation(group string, found bool) {
+	receiptAggregationsTotal.WithLabelValues(group, strconv.FormatBool(found)).Inc()
+}
+
+func RecordRoutingRule(method string, group string) {
+	routingRuleMatchesTotal.WithLabelValues(method, group).Inc()
+}
+
+func RecordTxPreferencesApplied(owner string) {
+	txPreferencesAppliedTotal.WithLabelValues(owner).Inc()
+}
+
+func RecordSignatureReplay(scheme string) {
+	signatureReplaysTotal.WithLabelValues(scheme).Inc()
+}
+
+func RecordTxAuditDropped(count int) {
+	txAuditDroppedTotal.Add(float64(count))
+}
+
+func RecordCallPolicyRejection(policy string) {
+	callPolicyRejectionsTotal.WithLabelValues(policy).Inc()
+}
+
+func RecordRateLimitExemption(reason string) {
+	rateLimitExemptionsTotal.WithLabelValues(reason).Inc()
+}
+
+func RecordRateLimitFallback(limiter string, fallback bool) {
+	rateLimitFallback.WithLabelValues(limiter).Set(boolToFloat64(fallback))
+}
+
+func RecordRedisDegraded(degraded bool) {
+	redisDegraded.Set(boolToFloat64(degraded))
+}
+
+func RecordRollupConsensus(group string, method string, agreed bool) {
+	rollupConsensusTotal.WithLabelValues(group, method, strconv.FormatBool(agreed)).Inc()
+}
+
+func RecordNotFoundRetry(method string, found bool) {
+	notFoundRetriesTotal.WithLabelValues(method, strconv.FormatBool(found)).Inc()
+}
+
+func RecordLoadSheddingLevel(level int) {
+	loadSheddingLevelGauge.Set(float64(level))
+}
+
+func RecordLoadShedRequest(method string) {
+	loadShedRequestsTotal.WithLabelValues(method).Inc()
+}
+
+func RecordRequestStage(stage string, d time.Duration) {
+	requestStageDurationHist.WithLabelValues(stage).Observe(d.Seconds())
+}
+
+func RecordInflightRequests(scope string, n int) {
+	inflightRequests.WithLabelValues(scope).Set(float64(n))
+}
+
+func RecordInflightQueueDepth(scope string, n int) {
+	inflightQueueDepth.WithLabelValues(scope).Set(float64(n))
+}
+
+func RecordInflightQueueWait(scope string, wait time.Duration) {
+	inflightQueueWait.WithLabelValues(scope).Observe(float64(wait.Milliseconds()))
+}
+
+func RecordInflightRejection(scope, reason string) {
+	inflightRejectionsTotal.WithLabelValues(scope, reason).Inc()
+}
+
+func RecordCanceledRPCRequest(method, stage string) {
+	canceledRPCRequestsTotal.WithLabelValues(method, stage).Inc()
+}
+
+func RecordTenantRPCRequest(ctx context.Context, method, group string) {
+	tenant := GetTenant(ctx)
+	if tenant == nil {
+		return
+	}
+	tenantRPCRequestsTotal.WithLabelValues(tenant.Name, method, group).Inc()
+}
+
+func RecordSignerClassRPCRequest(ctx context.Context, method, group string) {
+	class := GetSignerClass(ctx)
+	if class == nil {
+		return
+	}
+	signerClassRPCRequestsTotal.WithLabelValues(class.Name, method, group).Inc()
+}
+
+func RecordFlashbotsSignature(valid bool) {
+	flashbotsSignaturesTotal.WithLabelValues(strconv.FormatBool(valid)).Inc()
+}
+
+func RecordStrictJSONRPCViolation(reason string) {
+	strictJSONRPCViolationsTotal.WithLabelValues(reason).Inc()
+}
+
+func RecordFrontendLimitRejection(reason string) {
+	frontendLimitRejectionsTotal.WithLabelValues(reason).Inc()
+}
+
+// RecordRejectedRequest counts a request rejected before forwarding, with one
+// of the RejectReason constants as reason.
+func RecordRejectedRequest(reason string) {
+	rejectedRequestsTotal.WithLabelValues(reason).Inc()
+}
+
+func RecordDeprecatedMethodRequest(ctx context.Context, method, outcome string) {
+	deprecatedMethodRequestsTotal.WithLabelValues(GetAuthCtx(ctx), method, outcome).Inc()
+}
+
+func RecordSelectorRequest(method, selector string) {
+	selectorRequestsTotal.WithLabelValues(method, selector).Inc()
+}
+
+func RecordSelectorRateLimited(selector string) {
+	selectorRateLimitedTotal.WithLabelValues(selector).Inc()
+}
+
+func RecordSenderReputationEvent(event string) {
+	senderReputationEventsTotal.WithLabelValues(event).Inc()
+}
+
+func RecordLowReputationSubmission(action string) {
+	lowReputationSubmissionsTotal.WithLabelValues(action).Inc()
+}
+
+func RecordUsageExport(outcome string) {
+	usageExportsTotal.WithLabelValues(outcome).Inc()
+}
+
+func RecordIPFilterBlock(rule string) {
+	ipFilterBlockedRequestsTotal.WithLabelValues(rule).Inc()
+}
+
+func RecordCassetteRequest(backendName, outcome string) {
+	cassetteRequestsTotal.WithLabelValues(backendName, outcome).Inc()
+}
+
+func RecordTxDedupHit(method string) {
+	txDedupHitsTotal.WithLabelValues(method).Inc()
+}
+
+func RecordEstimateGasCacheHit() {
+	estimateGasCacheHitsTotal.Inc()
+}
+
+func RecordEstimateGasClamped() {
+	estimateGasClampedTotal.Inc()
+}
+
+func RecordEarlyReturn(outcome string) {
+	earlyReturnTransactionsTotal.WithLabelValues(outcome).Inc()
+}
+
+func RecordInjectedFault(backendName, fault string) {
+	injectedFaultsTotal.WithLabelValues(backendName, fault).Inc()
+}
+
+func RecordInteropValidationCache(outcome string) {
+	interopValidationCacheTotal.WithLabelValues(outcome).Inc()
+}
+
+func RecordInteropValidation(outcome, reason string) {
+	interopValidationsTotal.WithLabelValues(outcome, reason).Inc()
+}
+
+func RecordInteropMessagesChecked(n int) {
+	interopMessagesCheckedTotal.Add(float64(n))
+}
+
+func RecordInteropStaticRejection(check string) {
+	interopStaticRejectionsTotal.WithLabelValues(check).Inc()
+}
+
+func RecordInteropAdvisoryValidation(outcome string) {
+	interopAdvisoryValidationsTotal.WithLabelValues(outcome).Inc()
+}
+
+func RecordSupervisorRequestDuration(url, outcome string, dur time.Duration) {
+	supervisorRequestDurationSumm.WithLabelValues(url, outcome).Observe(float64(dur.Milliseconds()))
+}
+
+func RecordSupervisorBanned(url string, banned bool) {
+	supervisorBanned.WithLabelValues(url).Set(boolToFloat64(banned))
+}
+
+func RecordInteropValidationFailOpen() {
+	interopValidationFailOpenTotal.Inc()
+}
+
+func RecordInteropAdminChange(reverted, persisted bool) {
+	action := "override"
+	if reverted {
+		action = "revert"
+	}
+	interopAdminChangesTotal.WithLabelValues(action, strconv.FormatBool(persisted)).Inc()
+}
+
 func boolToFloat64(b bool) float64 {
 	if b {
 		return 1
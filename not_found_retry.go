@@ -0,0 +1,142 @@
+package proxyd
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+const (
+	defaultNotFoundMaxRetries = 3
+	defaultNotFoundBackoff    = 100 * time.Millisecond
+	defaultNotFoundTxWindow   = time.Minute
+	notFoundTxMemoryLimit     = 100000
+)
+
+// NotFoundRetry retries requests for blocks and transactions that are known
+// to exist but that the serving backend returned null for, because they
+// haven't reached it yet. Blocks are known to exist up to the consensus
+// latest block, or if a backend reported them as its latest block.
+// Transactions are known to exist if proxyd forwarded them recently.
+type NotFoundRetry struct {
+	maxRetries int
+	backoff    time.Duration
+	txWindow   time.Duration
+
+	mu  sync.Mutex
+	txs *lru.Cache
+}
+
+func NewNotFoundRetry(cfg NotFoundRetryConfig) *NotFoundRetry {
+	n := &NotFoundRetry{
+		maxRetries: cfg.MaxRetries,
+		backoff:    time.Duration(cfg.Backoff),
+		txWindow:   time.Duration(cfg.TxWindow),
+	}
+	if n.maxRetries == 0 {
+		n.maxRetries = defaultNotFoundMaxRetries
+	}
+	if n.backoff == 0 {
+		n.backoff = defaultNotFoundBackoff
+	}
+	if n.txWindow == 0 {
+		n.txWindow = defaultNotFoundTxWindow
+	}
+	n.txs, _ = lru.New(notFoundTxMemoryLimit)
+	return n
+}
+
+// ObserveTx records that a transaction was forwarded.
+func (n *NotFoundRetry) ObserveTx(tx *types.Transaction) {
+	if n == nil {
+		return
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.txs.Add(strings.ToLower(tx.Hash().Hex()), time.Now().Add(n.txWindow))
+}
+
+// Retry forwards the requests of elems that got a null response again, with
+// a jittered backoff, as long as they are known to exist. It returns the
+// responses with the ones found replaced.
+func (n *NotFoundRetry) Retry(ctx context.Context, bg *BackendGroup, elems []batchElem, res []*RPCRes) []*RPCRes {
+	if n == nil {
+		return res
+	}
+	missing := make(map[string]int)
+	for i, elem := range elems {
+		if i < len(res) && !res[i].IsError() && res[i].Result == nil && n.knownToExist(bg, elem.Req) {
+			missing[string(elem.Req.ID)] = i
+		}
+	}
+
+	for attempt := 1; attempt <= n.maxRetries && len(missing) > 0; attempt++ {
+		jitter := time.Duration(rand.Int63n(int64(n.backoff)))
+		select {
+		case <-ctx.Done():
+			return res
+		case <-time.After(time.Duration(attempt)*n.backoff + jitter):
+		}
+
+		reqs := make([]*RPCReq, 0, len(missing))
+		for _, i := range missing {
+			reqs = append(reqs, elems[i].Req)
+		}
+		retried, _, err := bg.Forward(ctx, reqs, true)
+		if err != nil {
+			continue
+		}
+		for _, r := range retried {
+			i, ok := missing[string(r.ID)]
+			if !ok || r.IsError() || r.Result == nil {
+				continue
+			}
+			res[i] = r
+			delete(missing, string(r.ID))
+			RecordNotFoundRetry(elems[i].Req.Method, true)
+		}
+	}
+	for _, i := range missing {
+		RecordNotFoundRetry(elems[i].Req.Method, false)
+	}
+	return res
+}
+
+func (n *NotFoundRetry) knownToExist(bg *BackendGroup, req *RPCReq) bool {
+	var params []interface{}
+	if err := json.Unmarshal(req.Params, &params); err != nil || len(params) == 0 {
+		return false
+	}
+	param, ok := params[0].(string)
+	if !ok {
+		return false
+	}
+
+	switch req.Method {
+	case "eth_getBlockByNumber":
+		if bg.Consensus == nil {
+			return false
+		}
+		switch param {
+		case "latest", "safe", "finalized":
+			return true
+		}
+		block, err := hexutil.DecodeUint64(param)
+		return err == nil && block <= uint64(bg.Consensus.GetLatestBlockNumber())
+	case "eth_getBlockByHash":
+		return bg.Consensus != nil && bg.Consensus.KnowsBlockHash(param)
+	case "eth_getTransactionByHash":
+		n.mu.Lock()
+		defer n.mu.Unlock()
+		expires, ok := n.txs.Get(strings.ToLower(param))
+		return ok && time.Now().Before(expires.(time.Time))
+	}
+	return false
+}
@@ -0,0 +1,153 @@
+package proxyd
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	pendingNoncesRedisKey    = "pending_nonces"
+	defaultPendingNoncesTTL  = time.Minute
+	pendingNoncesMemoryLimit = 100000
+)
+
+// raisePendingNonceScript stores the nonce if it's higher than the stored one,
+// and returns the higher of the two.
+var raisePendingNonceScript = redis.NewScript(`
+local cur = tonumber(redis.call('GET', KEYS[1]))
+local nonce = tonumber(ARGV[1])
+if cur == nil or nonce > cur then
+	redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+	return nonce
+end
+return cur
+`)
+
+type pendingNonce struct {
+	nonce   uint64
+	expires time.Time
+}
+
+// PendingNonces keeps the highest pending nonce seen per sender, from the
+// transactions it forwarded and the eth_getTransactionCount "pending" answers
+// of backends, and answers with it when a backend reports a lower one. This
+// keeps the pending nonce from going backwards when a group's backends
+// disagree. A nonce is forgotten once it hasn't risen for the TTL, so that
+// dropped transactions don't hold it up for long. The nonces are shared in
+// Redis if configured.
+type PendingNonces struct {
+	ttl time.Duration
+
+	redisClient redis.UniversalClient
+	prefix      string
+
+	mu    sync.Mutex
+	local *lru.Cache
+}
+
+func NewPendingNonces(cfg PendingNoncesConfig, redisClient redis.UniversalClient, namespace string) *PendingNonces {
+	p := &PendingNonces{
+		ttl:         time.Duration(cfg.TTL),
+		redisClient: redisClient,
+		prefix:      pendingNoncesRedisKey,
+	}
+	if namespace != "" {
+		p.prefix = namespace + ":" + pendingNoncesRedisKey
+	}
+	if p.ttl == 0 {
+		p.ttl = defaultPendingNoncesTTL
+	}
+	if redisClient == nil {
+		p.local, _ = lru.New(pendingNoncesMemoryLimit)
+	}
+	return p
+}
+
+// Raise records that nonce is pending for addr, and returns the highest
+// pending nonce known.
+func (p *PendingNonces) Raise(ctx context.Context, addr common.Address, nonce uint64) (uint64, error) {
+	if p.redisClient != nil {
+		key := p.prefix + ":" + addr.Hex()
+		res, err := raisePendingNonceScript.Run(ctx, p.redisClient, []string{key}, strconv.FormatUint(nonce, 10), p.ttl.Milliseconds()).Int64()
+		if err != nil {
+			RecordRedisError("PendingNonces")
+			return nonce, err
+		}
+		return uint64(res), nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if val, ok := p.local.Get(addr); ok {
+		cur := val.(pendingNonce)
+		if cur.nonce >= nonce && time.Now().Before(cur.expires) {
+			return cur.nonce, nil
+		}
+	}
+	p.local.Add(addr, pendingNonce{nonce: nonce, expires: time.Now().Add(p.ttl)})
+	return nonce, nil
+}
+
+// ObserveTx records the nonce following a forwarded transaction.
+func (p *PendingNonces) ObserveTx(ctx context.Context, tx *types.Transaction) {
+	if p == nil {
+		return
+	}
+	from, err := txSender(tx)
+	if err != nil {
+		return
+	}
+	if _, err := p.Raise(ctx, from, tx.Nonce()+1); err != nil {
+		log.Error("error recording pending nonce", "err", err, "req_id", GetReqID(ctx))
+	}
+}
+
+// RewriteResponse replaces the result of eth_getTransactionCount for the
+// pending block with the highest pending nonce known. Other responses are
+// returned as is.
+func (p *PendingNonces) RewriteResponse(ctx context.Context, req *RPCReq, res *RPCRes) *RPCRes {
+	if p == nil || req.Method != "eth_getTransactionCount" || res.IsError() {
+		return res
+	}
+	var params []string
+	if err := json.Unmarshal(req.Params, &params); err != nil || len(params) != 2 || !strings.EqualFold(params[1], "pending") {
+		return res
+	}
+	if !common.IsHexAddress(params[0]) {
+		return res
+	}
+	result, ok := res.Result.(string)
+	if !ok {
+		return res
+	}
+	nonce, err := hexutil.DecodeUint64(result)
+	if err != nil {
+		return res
+	}
+
+	pending, err := p.Raise(ctx, common.HexToAddress(params[0]), nonce)
+	if err != nil {
+		log.Error("error reading pending nonce", "err", err, "req_id", GetReqID(ctx))
+		return res
+	}
+	if pending == nonce {
+		return res
+	}
+	RecordPendingNonceOverride()
+	return &RPCRes{
+		JSONRPC: res.JSONRPC,
+		Result:  hexutil.EncodeUint64(pending),
+		ID:      res.ID,
+	}
+}
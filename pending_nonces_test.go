@@ -0,0 +1,61 @@
+package proxyd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/alicebob/miniredis"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPendingNonces(t *testing.T) {
+	redisServer, err := miniredis.Run()
+	require.NoError(t, err)
+	defer redisServer.Close()
+
+	redisClient := redis.NewClient(&redis.Options{
+		Addr: fmt.Sprintf("127.0.0.1:%s", redisServer.Port()),
+	})
+
+	for name, p := range map[string]*PendingNonces{
+		"memory": NewPendingNonces(PendingNoncesConfig{}, nil, ""),
+		"redis":  NewPendingNonces(PendingNoncesConfig{}, redisClient, "proxyd"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			addr := common.Address{1}
+			req := &RPCReq{
+				Method: "eth_getTransactionCount",
+				Params: json.RawMessage(fmt.Sprintf(`["%s","pending"]`, addr.Hex())),
+			}
+
+			// the first backend answer is taken as is
+			res := p.RewriteResponse(ctx, req, NewRPCRes(nil, "0x5"))
+			require.Equal(t, "0x5", res.Result)
+
+			// a backend that is behind doesn't move the nonce back
+			res = p.RewriteResponse(ctx, req, NewRPCRes(nil, "0x3"))
+			require.Equal(t, "0x5", res.Result)
+
+			// forwarded transactions move it forward
+			_, err := p.Raise(ctx, addr, 7)
+			require.NoError(t, err)
+			res = p.RewriteResponse(ctx, req, NewRPCRes(nil, "0x5"))
+			require.Equal(t, "0x7", res.Result)
+
+			// other blocks and errors are left alone
+			latest := &RPCReq{
+				Method: "eth_getTransactionCount",
+				Params: json.RawMessage(fmt.Sprintf(`["%s","latest"]`, addr.Hex())),
+			}
+			res = p.RewriteResponse(ctx, latest, NewRPCRes(nil, "0x4"))
+			require.Equal(t, "0x4", res.Result)
+			errRes := NewRPCErrorRes(nil, ErrInternal)
+			require.Equal(t, errRes, p.RewriteResponse(ctx, req, errRes))
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package proxyd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	pendingTxsRedisKey     = "pending_txs"
+	defaultPendingTxsTTL   = 10 * time.Minute
+	pendingTxsNonceTimeout = 5 * time.Second
+)
+
+// NonceFunc returns the nonce of the latest block for a sender, i.e. the
+// nonce of its next transaction that hasn't been mined.
+type NonceFunc func(ctx context.Context, addr common.Address) (uint64, error)
+
+// PendingTxLimiter caps the transactions a sender has submitted that haven't
+// been mined yet. The nonces of pending transactions are kept per sender in a
+// Redis sorted set. Once a sender is at the cap, its mined nonces are dropped
+// from the set before deciding. Senders whose transactions never land are
+// forgotten after the TTL.
+type PendingTxLimiter struct {
+	client     redis.UniversalClient
+	prefix     string
+	maxPending int
+	ttl        time.Duration
+	nonceAt    NonceFunc
+}
+
+func NewPendingTxLimiter(cfg PendingTxLimitConfig, client redis.UniversalClient, namespace string, nonceAt NonceFunc) *PendingTxLimiter {
+	l := &PendingTxLimiter{
+		client:     client,
+		prefix:     pendingTxsRedisKey,
+		maxPending: cfg.MaxPending,
+		ttl:        time.Duration(cfg.TTL),
+		nonceAt:    nonceAt,
+	}
+	if namespace != "" {
+		l.prefix = namespace + ":" + pendingTxsRedisKey
+	}
+	if l.ttl == 0 {
+		l.ttl = defaultPendingTxsTTL
+	}
+	return l
+}
+
+// Take records a pending transaction and reports whether its nonce wasn't
+// pending already, or returns ErrTooManyPendingTxs if the sender is at the
+// cap. Replacing a pending transaction doesn't count against the cap.
+func (l *PendingTxLimiter) Take(ctx context.Context, from common.Address, nonce uint64) (bool, error) {
+	if l == nil {
+		return false, nil
+	}
+	key := fmt.Sprintf("%s:%s", l.prefix, from.Hex())
+	member := strconv.FormatUint(nonce, 10)
+
+	var added, count *redis.IntCmd
+	_, err := l.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		added = pipe.ZAdd(ctx, key, redis.Z{Score: float64(nonce), Member: member})
+		count = pipe.ZCard(ctx, key)
+		pipe.Expire(ctx, key, l.ttl)
+		return nil
+	})
+	if err != nil {
+		RecordRedisError("PendingTxLimiter")
+		log.Error("error tracking pending transaction", "err", err, "req_id", GetReqID(ctx))
+		return false, ErrInternal
+	}
+	if added.Val() == 0 {
+		return false, nil
+	}
+	if count.Val() <= int64(l.maxPending) {
+		return true, nil
+	}
+
+	// At the cap, forget the transactions that have been mined since.
+	nonceCtx, cancel := context.WithTimeout(ctx, pendingTxsNonceTimeout)
+	defer cancel()
+	mined, err := l.nonceAt(nonceCtx, from)
+	if err != nil {
+		log.Warn("error getting sender nonce", "sender", from.Hex(), "err", err, "req_id", GetReqID(ctx))
+	} else if mined > 0 {
+		if err := l.client.ZRemRangeByScore(ctx, key, "-inf", "("+strconv.FormatUint(mined, 10)).Err(); err != nil {
+			RecordRedisError("PendingTxLimiter")
+			log.Error("error pruning pending transactions", "err", err, "req_id", GetReqID(ctx))
+		}
+	}
+	if nonce < mined {
+		// it will fail on its own, there's nothing to track
+		return true, nil
+	}
+
+	pending, err := l.client.ZCard(ctx, key).Result()
+	if err != nil {
+		RecordRedisError("PendingTxLimiter")
+		log.Error("error counting pending transactions", "err", err, "req_id", GetReqID(ctx))
+		return false, ErrInternal
+	}
+	if pending <= int64(l.maxPending) {
+		return true, nil
+	}
+
+	if err := l.client.ZRem(ctx, key, member).Err(); err != nil {
+		RecordRedisError("PendingTxLimiter")
+	}
+	log.Debug("sender has too many pending transactions", "sender", from.Hex(), "pending", pending-1, "req_id", GetReqID(ctx))
+	RecordPendingTxLimitExceeded()
+	return false, ErrTooManyPendingTxs
+}
+
+// Release forgets a transaction Take recorded, after its submission failed.
+func (l *PendingTxLimiter) Release(ctx context.Context, from common.Address, nonce uint64) {
+	if l == nil {
+		return
+	}
+	key := fmt.Sprintf("%s:%s", l.prefix, from.Hex())
+	if err := l.client.ZRem(ctx, key, strconv.FormatUint(nonce, 10)).Err(); err != nil {
+		RecordRedisError("PendingTxLimiter")
+		log.Error("error releasing pending transaction", "err", err, "req_id", GetReqID(ctx))
+	}
+}
+
+// backendGroupNonceAt gets sender nonces from a backend group.
+func backendGroupNonceAt(bg *BackendGroup) NonceFunc {
+	return func(ctx context.Context, addr common.Address) (uint64, error) {
+		result, err := callBackendGroup(ctx, bg, "eth_getTransactionCount", addr.Hex(), "latest")
+		if err != nil {
+			return 0, err
+		}
+		nonceHex, ok := result.(string)
+		if !ok {
+			return 0, fmt.Errorf("unexpected eth_getTransactionCount result %v", result)
+		}
+		return hexutil.DecodeUint64(nonceHex)
+	}
+}
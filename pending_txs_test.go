@@ -0,0 +1,65 @@
+package proxyd
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/alicebob/miniredis"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPendingTxLimiter(t *testing.T) {
+	redisServer, err := miniredis.Run()
+	require.NoError(t, err)
+	defer redisServer.Close()
+
+	redisClient := redis.NewClient(&redis.Options{
+		Addr: fmt.Sprintf("127.0.0.1:%s", redisServer.Port()),
+	})
+
+	var mined uint64
+	nonceCalls := 0
+	lim := NewPendingTxLimiter(PendingTxLimitConfig{MaxPending: 2}, redisClient, "proxyd", func(ctx context.Context, addr common.Address) (uint64, error) {
+		nonceCalls++
+		return mined, nil
+	})
+	ctx := context.Background()
+	alice := common.Address{1}
+	bob := common.Address{2}
+
+	take := func(addr common.Address, nonce uint64) error {
+		_, err := lim.Take(ctx, addr, nonce)
+		return err
+	}
+
+	require.NoError(t, take(alice, 0))
+	require.NoError(t, take(alice, 1))
+	require.Equal(t, ErrTooManyPendingTxs, take(alice, 2))
+	// replacements and other senders aren't limited
+	taken, err := lim.Take(ctx, alice, 1)
+	require.NoError(t, err)
+	require.False(t, taken)
+	require.NoError(t, take(bob, 0))
+	require.Equal(t, 1, nonceCalls)
+
+	// released transactions don't count against the cap
+	lim.Release(ctx, alice, 1)
+	require.NoError(t, take(alice, 2))
+	require.Equal(t, 1, nonceCalls)
+
+	// once earlier transactions land there's room again
+	mined = 1
+	require.NoError(t, take(alice, 3))
+	require.Equal(t, ErrTooManyPendingTxs, take(alice, 4))
+	members, err := redisServer.ZMembers("proxyd:pending_txs:" + alice.Hex())
+	require.NoError(t, err)
+	require.Equal(t, []string{"2", "3"}, members)
+
+	var nilLim *PendingTxLimiter
+	_, err = nilLim.Take(ctx, alice, 3)
+	require.NoError(t, err)
+	nilLim.Release(ctx, alice, 3)
+}
@@ -0,0 +1,89 @@
+// Package mock serves canned JSON-RPC responses in place of real nodes, to
+// test proxyd and its clients without them.
+package mock
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+)
+
+type RecordedRequest struct {
+	Method  string
+	Headers http.Header
+	Body    []byte
+}
+
+// Backend is an HTTP server that records the requests it gets and hands them
+// to a handler, usually a Scenario, one at a time.
+type Backend struct {
+	handler  http.Handler
+	server   *httptest.Server
+	mtx      sync.RWMutex
+	requests []*RecordedRequest
+}
+
+// NewBackend starts a Backend on a random local port.
+func NewBackend(handler http.Handler) *Backend {
+	mb := &Backend{
+		handler: handler,
+	}
+	mb.server = httptest.NewServer(http.HandlerFunc(mb.wrappedHandler))
+	return mb
+}
+
+func (m *Backend) URL() string {
+	return m.server.URL
+}
+
+func (m *Backend) Close() {
+	m.server.Close()
+}
+
+func (m *Backend) Handler() http.Handler {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+	return m.handler
+}
+
+func (m *Backend) SetHandler(handler http.Handler) {
+	m.mtx.Lock()
+	m.handler = handler
+	m.mtx.Unlock()
+}
+
+func (m *Backend) Reset() {
+	m.mtx.Lock()
+	m.requests = nil
+	m.mtx.Unlock()
+}
+
+func (m *Backend) Requests() []*RecordedRequest {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+	out := make([]*RecordedRequest, len(m.requests))
+	copy(out, m.requests)
+	return out
+}
+
+func (m *Backend) wrappedHandler(w http.ResponseWriter, r *http.Request) {
+	m.mtx.Lock()
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		m.mtx.Unlock()
+		http.Error(w, "error reading request body", http.StatusBadRequest)
+		return
+	}
+	clone := r.Clone(context.Background())
+	clone.Body = io.NopCloser(bytes.NewReader(body))
+	m.requests = append(m.requests, &RecordedRequest{
+		Method:  r.Method,
+		Headers: r.Header.Clone(),
+		Body:    body,
+	})
+	m.handler.ServeHTTP(w, clone)
+	m.mtx.Unlock()
+}
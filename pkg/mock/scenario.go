@@ -0,0 +1,206 @@
+package mock
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/ethereum-optimism/infra/proxyd"
+	"gopkg.in/yaml.v3"
+)
+
+// Response is what a Scenario answers a request with: a result, or an error,
+// after Delay. A Status other than 200 answers with Body and that HTTP status
+// instead, like a failing node or load balancer would.
+type Response struct {
+	Result any            `yaml:"result"`
+	Error  *ResponseError `yaml:"error"`
+	Delay  time.Duration  `yaml:"delay"`
+	Status int            `yaml:"status"`
+	Body   string         `yaml:"body"`
+}
+
+type ResponseError struct {
+	Code    int    `yaml:"code"`
+	Message string `yaml:"message"`
+	Data    any    `yaml:"data"`
+}
+
+// Rule scripts the responses to a method, "*" for any. The first call gets
+// the first response, the second call the second one and so on, and once
+// they're used up the last one is repeated, or they start over if Loop is
+// set. A rule with a single response can set its fields inline.
+type Rule struct {
+	Method string `yaml:"method"`
+	// Params limits the rule to requests with these params.
+	Params    any        `yaml:"params"`
+	Responses []Response `yaml:"responses"`
+	Loop      bool       `yaml:"loop"`
+	Response  `yaml:",inline"`
+}
+
+type scriptedRule struct {
+	Rule
+	params any
+	calls  int
+}
+
+// Scenario is an http.Handler answering JSON-RPC requests, batched or not,
+// as scripted by its rules. The first rule matching a request's method and
+// params answers it. Requests no rule matches get a method not found error.
+type Scenario struct {
+	mtx   sync.Mutex
+	rules []*scriptedRule
+	calls map[string]int
+}
+
+func NewScenario(rules ...Rule) (*Scenario, error) {
+	s := &Scenario{calls: make(map[string]int)}
+	for i, r := range rules {
+		if r.Method == "" {
+			return nil, fmt.Errorf("rule %d has no method", i)
+		}
+		if len(r.Responses) == 0 {
+			r.Responses = []Response{r.Response}
+		}
+		sr := &scriptedRule{Rule: r}
+		if r.Params != nil {
+			// compare params as decoded from JSON, whatever they were
+			// decoded from here
+			raw, err := json.Marshal(r.Params)
+			if err != nil {
+				return nil, fmt.Errorf("invalid params of rule %d: %w", i, err)
+			}
+			if err := json.Unmarshal(raw, &sr.params); err != nil {
+				return nil, fmt.Errorf("invalid params of rule %d: %w", i, err)
+			}
+		}
+		s.rules = append(s.rules, sr)
+	}
+	return s, nil
+}
+
+// LoadScenario reads a YAML list of rules.
+func LoadScenario(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rules []Rule
+	if err := yaml.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("invalid scenario %s: %w", path, err)
+	}
+	return NewScenario(rules...)
+}
+
+// Calls returns how many requests for method were answered.
+func (s *Scenario) Calls(method string) int {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.calls[method]
+}
+
+func (s *Scenario) next(req *proxyd.RPCReq) *Response {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.calls[req.Method]++
+
+	var params any
+	if len(req.Params) > 0 {
+		_ = json.Unmarshal(req.Params, &params)
+	}
+	for _, r := range s.rules {
+		if r.Method != "*" && r.Method != req.Method {
+			continue
+		}
+		if r.params != nil && !reflect.DeepEqual(r.params, params) {
+			continue
+		}
+		i := r.calls
+		r.calls++
+		if i >= len(r.Responses) {
+			if r.Loop {
+				i %= len(r.Responses)
+			} else {
+				i = len(r.Responses) - 1
+			}
+		}
+		return &r.Responses[i]
+	}
+	return &Response{Error: &ResponseError{
+		Code:    -32601,
+		Message: fmt.Sprintf("the method %s does not exist/is not available", req.Method),
+	}}
+}
+
+func (s *Scenario) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "error reading request body", http.StatusBadRequest)
+		return
+	}
+
+	batched := proxyd.IsBatch(body)
+	raws := []json.RawMessage{body}
+	if batched {
+		if raws, err = proxyd.ParseBatchRPCReq(body); err != nil {
+			writeJSON(w, http.StatusBadRequest, proxyd.NewRPCErrorRes(nil, proxyd.ErrParseErr))
+			return
+		}
+	}
+
+	var delay time.Duration
+	var failed *Response
+	out := make([]*proxyd.RPCRes, 0, len(raws))
+	for _, raw := range raws {
+		req, err := proxyd.ParseRPCReq(raw)
+		if err != nil {
+			out = append(out, proxyd.NewRPCErrorRes(nil, proxyd.ErrParseErr))
+			continue
+		}
+		res := s.next(req)
+		delay = max(delay, res.Delay)
+		if res.Status != 0 && res.Status != http.StatusOK && failed == nil {
+			failed = res
+		}
+		rpcRes := &proxyd.RPCRes{JSONRPC: proxyd.JSONRPCVersion, ID: req.ID}
+		if res.Error != nil {
+			rpcRes.Error = &proxyd.RPCErr{Code: res.Error.Code, Message: res.Error.Message}
+			if res.Error.Data != nil {
+				rpcRes.Error.Data, _ = json.Marshal(res.Error.Data)
+			}
+		} else {
+			rpcRes.Result = res.Result
+		}
+		out = append(out, rpcRes)
+	}
+
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	if failed != nil {
+		w.WriteHeader(failed.Status)
+		_, _ = io.WriteString(w, failed.Body)
+		return
+	}
+	if batched {
+		writeJSON(w, http.StatusOK, out)
+	} else {
+		writeJSON(w, http.StatusOK, out[0])
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,86 @@
+package mock
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testScenario = `
+- method: eth_chainId
+  result: "0x1"
+- method: eth_getBalance
+  params: ["0x0000000000000000000000000000000000000001", "latest"]
+  result: "0x64"
+- method: eth_blockNumber
+  responses:
+    - result: "0x1"
+    - error:
+        code: -32000
+        message: header not found
+    - status: 503
+      body: upstream unavailable
+      delay: 50ms
+- method: eth_gasPrice
+  loop: true
+  responses:
+    - result: "0x1"
+    - result: "0x2"
+`
+
+func post(t *testing.T, url, body string) (int, string) {
+	res, err := http.Post(url, "application/json", strings.NewReader(body))
+	require.NoError(t, err)
+	defer res.Body.Close()
+	out, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	return res.StatusCode, strings.TrimSpace(string(out))
+}
+
+func TestScenario(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scenario.yml")
+	require.NoError(t, os.WriteFile(path, []byte(testScenario), 0o600))
+	scenario, err := LoadScenario(path)
+	require.NoError(t, err)
+	backend := NewBackend(scenario)
+	defer backend.Close()
+
+	code, body := post(t, backend.URL(), `{"jsonrpc":"2.0","method":"eth_chainId","id":7}`)
+	require.Equal(t, 200, code)
+	require.JSONEq(t, `{"jsonrpc":"2.0","result":"0x1","id":7}`, body)
+
+	_, body = post(t, backend.URL(), `{"jsonrpc":"2.0","method":"eth_getBalance","params":["0x0000000000000000000000000000000000000001","latest"],"id":1}`)
+	require.JSONEq(t, `{"jsonrpc":"2.0","result":"0x64","id":1}`, body)
+	_, body = post(t, backend.URL(), `{"jsonrpc":"2.0","method":"eth_getBalance","params":["0x0000000000000000000000000000000000000002","latest"],"id":1}`)
+	require.JSONEq(t, `{"jsonrpc":"2.0","error":{"code":-32601,"message":"the method eth_getBalance does not exist/is not available"},"id":1}`, body)
+
+	_, body = post(t, backend.URL(), `{"jsonrpc":"2.0","method":"eth_blockNumber","id":1}`)
+	require.JSONEq(t, `{"jsonrpc":"2.0","result":"0x1","id":1}`, body)
+	_, body = post(t, backend.URL(), `{"jsonrpc":"2.0","method":"eth_blockNumber","id":1}`)
+	require.JSONEq(t, `{"jsonrpc":"2.0","error":{"code":-32000,"message":"header not found"},"id":1}`, body)
+	for i := 0; i < 2; i++ {
+		start := time.Now()
+		code, body = post(t, backend.URL(), `{"jsonrpc":"2.0","method":"eth_blockNumber","id":1}`)
+		require.Equal(t, 503, code)
+		require.Equal(t, "upstream unavailable", body)
+		require.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+	}
+
+	_, body = post(t, backend.URL(), `[{"jsonrpc":"2.0","method":"eth_gasPrice","id":1},{"jsonrpc":"2.0","method":"eth_gasPrice","id":2},{"jsonrpc":"2.0","method":"eth_gasPrice","id":3}]`)
+	require.JSONEq(t, `[{"jsonrpc":"2.0","result":"0x1","id":1},{"jsonrpc":"2.0","result":"0x2","id":2},{"jsonrpc":"2.0","result":"0x1","id":3}]`, body)
+
+	require.Equal(t, 4, scenario.Calls("eth_blockNumber"))
+	require.Equal(t, 3, scenario.Calls("eth_gasPrice"))
+	require.Equal(t, 8, len(backend.Requests()))
+}
+
+func TestScenarioInvalidRules(t *testing.T) {
+	_, err := NewScenario(Rule{Response: Response{Result: "0x1"}})
+	require.Error(t, err)
+}
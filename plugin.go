@@ -0,0 +1,479 @@
+package proxyd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	lua "github.com/yuin/gopher-lua"
+	"github.com/yuin/gopher-lua/parse"
+)
+
+const (
+	PluginHookPreRouting   = "pre_routing"
+	PluginHookPreForward   = "pre_forward"
+	PluginHookPostResponse = "post_response"
+
+	defaultPluginTimeout = 100 * time.Millisecond
+)
+
+var pluginHooks = []string{
+	PluginHookPreRouting,
+	PluginHookPreForward,
+	PluginHookPostResponse,
+}
+
+// luaNull stands in for JSON null inside Lua tables, which cannot hold nil.
+// Scripts see it as proxyd.null.
+var luaNull = &lua.LUserData{}
+
+// maxLuaInteger is the largest integer Lua numbers, which are float64, hold
+// exactly. Larger integers, such as wei amounts, are passed to scripts as
+// opaque userdata holding their json.Number, so they are sent on verbatim.
+const maxLuaInteger = 1 << 53
+
+// PluginHost runs the configured Lua plugins at each stage of the request
+// lifecycle. Plugins run in configuration order.
+//
+// A plugin script defines any of the following global functions:
+//
+//	pre_routing(req)         before the method is mapped to a backend group
+//	pre_forward(req, group)  after rate limiting, before the cache and backends
+//	post_response(req, res)  before the response is returned to the client
+//
+// The request hooks may modify req.method and req.params in place, and may
+// return a table with a group field to route the request to another backend
+// group, or with a result or error field to respond without forwarding.
+// post_response may modify res.result and res.error in place.
+type PluginHost struct {
+	plugins []*luaPlugin
+}
+
+type luaPlugin struct {
+	name    string
+	timeout time.Duration
+	proto   *lua.FunctionProto
+	hooks   map[string]bool
+	// LStates are not safe for concurrent use, so each call borrows one
+	states sync.Pool
+}
+
+func NewPluginHost(cfgs []*PluginConfig) (*PluginHost, error) {
+	host := &PluginHost{}
+	names := make(map[string]bool, len(cfgs))
+	for _, cfg := range cfgs {
+		if cfg.Name == "" {
+			return nil, fmt.Errorf("plugin must have a name")
+		}
+		if names[cfg.Name] {
+			return nil, fmt.Errorf("duplicate plugin %s", cfg.Name)
+		}
+		names[cfg.Name] = true
+
+		p, err := newLuaPlugin(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("error loading plugin %s: %w", cfg.Name, err)
+		}
+		host.plugins = append(host.plugins, p)
+	}
+	return host, nil
+}
+
+func newLuaPlugin(cfg *PluginConfig) (*luaPlugin, error) {
+	src, err := os.ReadFile(cfg.Path)
+	if err != nil {
+		return nil, err
+	}
+	chunk, err := parse.Parse(strings.NewReader(string(src)), cfg.Path)
+	if err != nil {
+		return nil, err
+	}
+	proto, err := lua.Compile(chunk, cfg.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &luaPlugin{
+		name:    cfg.Name,
+		timeout: time.Duration(cfg.Timeout),
+		proto:   proto,
+		hooks:   make(map[string]bool),
+	}
+	if p.timeout == 0 {
+		p.timeout = defaultPluginTimeout
+	}
+
+	L, err := p.newState()
+	if err != nil {
+		return nil, err
+	}
+	for _, hook := range pluginHooks {
+		if L.GetGlobal(hook).Type() == lua.LTFunction {
+			p.hooks[hook] = true
+		}
+	}
+	if len(p.hooks) == 0 {
+		L.Close()
+		return nil, fmt.Errorf("script defines none of %s", strings.Join(pluginHooks, ", "))
+	}
+	p.states.Put(L)
+	return p, nil
+}
+
+// newState creates a sandboxed interpreter without file, OS or module access
+// and runs the plugin script in it.
+func (p *luaPlugin) newState() (*lua.LState, error) {
+	L := lua.NewState(lua.Options{SkipOpenLibs: true})
+	for _, lib := range []struct {
+		name string
+		fn   lua.LGFunction
+	}{
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.StringLibName, lua.OpenString},
+		{lua.MathLibName, lua.OpenMath},
+	} {
+		L.Push(L.NewFunction(lib.fn))
+		L.Push(lua.LString(lib.name))
+		L.Call(1, 0)
+	}
+	for _, name := range []string{"dofile", "loadfile", "module", "require"} {
+		L.SetGlobal(name, lua.LNil)
+	}
+
+	mod := L.NewTable()
+	mod.RawSetString("null", luaNull)
+	mod.RawSetString("log", L.NewFunction(func(L *lua.LState) int {
+		log.Info("plugin log", "plugin", p.name, "msg", L.CheckString(1))
+		return 0
+	}))
+	L.SetGlobal("proxyd", mod)
+
+	L.Push(L.NewFunctionFromProto(p.proto))
+	if err := L.PCall(0, lua.MultRet, nil); err != nil {
+		L.Close()
+		return nil, err
+	}
+	return L, nil
+}
+
+// call runs a hook and returns its first return value.
+func (p *luaPlugin) call(ctx context.Context, hook string, args ...lua.LValue) (lua.LValue, error) {
+	L, _ := p.states.Get().(*lua.LState)
+	if L == nil {
+		var err error
+		if L, err = p.newState(); err != nil {
+			return nil, err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	L.SetContext(ctx)
+
+	err := L.CallByParam(lua.P{
+		Fn:      L.GetGlobal(hook),
+		NRet:    1,
+		Protect: true,
+	}, args...)
+	if err != nil {
+		// the interpreter may be left mid-call, so don't reuse it
+		L.Close()
+		return nil, err
+	}
+	ret := L.Get(-1)
+	L.Pop(1)
+	L.RemoveContext()
+	p.states.Put(L)
+	return ret, nil
+}
+
+// PreRouting runs the pre_routing hooks. It returns the request to route, the
+// backend group chosen by a plugin if any, or a response that ends the request.
+func (h *PluginHost) PreRouting(ctx context.Context, req *RPCReq) (*RPCReq, string, *RPCRes) {
+	return h.runRequestHooks(ctx, PluginHookPreRouting, req, "")
+}
+
+// PreForward runs the pre_forward hooks. It returns the request to forward,
+// the backend group to forward it to, or a response that ends the request.
+func (h *PluginHost) PreForward(ctx context.Context, req *RPCReq, group string) (*RPCReq, string, *RPCRes) {
+	return h.runRequestHooks(ctx, PluginHookPreForward, req, group)
+}
+
+func (h *PluginHost) runRequestHooks(ctx context.Context, hook string, req *RPCReq, group string) (*RPCReq, string, *RPCRes) {
+	if h == nil {
+		return req, group, nil
+	}
+
+	for _, p := range h.plugins {
+		if !p.hooks[hook] {
+			continue
+		}
+
+		reqTbl := reqToLua(req)
+		params, err := json.Marshal(luaToJSON(reqTbl.RawGetString("params")))
+		if err != nil {
+			return req, group, p.hookFailed(ctx, hook, req, err)
+		}
+		args := []lua.LValue{reqTbl}
+		if hook == PluginHookPreForward {
+			args = append(args, lua.LString(group))
+		}
+		ret, err := p.call(ctx, hook, args...)
+		if err != nil {
+			return req, group, p.hookFailed(ctx, hook, req, err)
+		}
+		rewritten, err := reqFromLua(req, reqTbl, params)
+		if err != nil {
+			return req, group, p.hookFailed(ctx, hook, req, err)
+		}
+		req = rewritten
+
+		tbl, ok := ret.(*lua.LTable)
+		if !ok {
+			continue
+		}
+		if res, err := resFromLua(req.ID, tbl); err != nil {
+			return req, group, p.hookFailed(ctx, hook, req, err)
+		} else if res != nil {
+			return req, group, res
+		}
+		if g, ok := tbl.RawGetString("group").(lua.LString); ok && g != "" {
+			group = string(g)
+		}
+	}
+	return req, group, nil
+}
+
+// PostResponse runs the post_response hooks and returns the response to send
+// to the client.
+func (h *PluginHost) PostResponse(ctx context.Context, req *RPCReq, res *RPCRes) *RPCRes {
+	if h == nil {
+		return res
+	}
+
+	for _, p := range h.plugins {
+		if !p.hooks[PluginHookPostResponse] {
+			continue
+		}
+
+		resTbl, err := resToLua(res)
+		if err != nil {
+			return p.hookFailed(ctx, PluginHookPostResponse, req, err)
+		}
+		result, err := json.Marshal(luaToJSON(resTbl.RawGetString("result")))
+		if err != nil {
+			return p.hookFailed(ctx, PluginHookPostResponse, req, err)
+		}
+		if _, err := p.call(ctx, PluginHookPostResponse, reqToLua(req), resTbl); err != nil {
+			return p.hookFailed(ctx, PluginHookPostResponse, req, err)
+		}
+		rewritten, err := resFromLua(res.ID, resTbl)
+		if err != nil {
+			return p.hookFailed(ctx, PluginHookPostResponse, req, err)
+		}
+		if rewritten == nil {
+			rewritten = NewRPCRes(res.ID, nil)
+		}
+		// keep results the plugin left alone as they were
+		if !res.IsError() && !rewritten.IsError() {
+			if after, err := json.Marshal(rewritten.Result); err == nil && bytes.Equal(after, result) {
+				rewritten.Result = res.Result
+			}
+		}
+		// keep the HTTP status and data of errors the plugin left alone
+		if res.IsError() && rewritten.IsError() &&
+			res.Error.Code == rewritten.Error.Code && res.Error.Message == rewritten.Error.Message {
+			rewritten.Error = res.Error
+		}
+		rewritten.ServedBy = res.ServedBy
+		res = rewritten
+	}
+	return res
+}
+
+func (p *luaPlugin) hookFailed(ctx context.Context, hook string, req *RPCReq, err error) *RPCRes {
+	log.Error("plugin hook failed",
+		"plugin", p.name,
+		"hook", hook,
+		"method", req.Method,
+		"req_id", GetReqID(ctx),
+		"err", err,
+	)
+	RecordPluginError(p.name, hook)
+	return NewRPCErrorRes(req.ID, ErrPluginFailed)
+}
+
+func reqToLua(req *RPCReq) *lua.LTable {
+	tbl := newLuaTable()
+	tbl.RawSetString("jsonrpc", lua.LString(req.JSONRPC))
+	tbl.RawSetString("method", lua.LString(req.Method))
+	tbl.RawSetString("params", rawToLua(req.Params))
+	tbl.RawSetString("id", rawToLua(req.ID))
+	return tbl
+}
+
+// reqFromLua returns a copy of req with the method and params from tbl.
+// params is the encoding of tbl's params before the hook ran; if the hook
+// left them alone the original params are kept byte for byte. The id can't
+// be changed by plugins.
+func reqFromLua(req *RPCReq, tbl *lua.LTable, params []byte) (*RPCReq, error) {
+	method, ok := tbl.RawGetString("method").(lua.LString)
+	if !ok || method == "" {
+		return nil, fmt.Errorf("req.method must be a non-empty string")
+	}
+
+	rewritten := *req
+	rewritten.Method = string(method)
+	raw, err := json.Marshal(luaToJSON(tbl.RawGetString("params")))
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(raw, params) {
+		rewritten.Params = raw
+	}
+	return &rewritten, nil
+}
+
+func resToLua(res *RPCRes) (*lua.LTable, error) {
+	tbl := newLuaTable()
+	tbl.RawSetString("id", rawToLua(res.ID))
+	if res.IsError() {
+		errTbl := newLuaTable()
+		errTbl.RawSetString("code", lua.LNumber(res.Error.Code))
+		errTbl.RawSetString("message", lua.LString(res.Error.Message))
+		tbl.RawSetString("error", errTbl)
+		return tbl, nil
+	}
+
+	result, err := json.Marshal(res.Result)
+	if err != nil {
+		return nil, err
+	}
+	tbl.RawSetString("result", rawToLua(result))
+	return tbl, nil
+}
+
+// resFromLua builds a response from the result or error field of tbl. It
+// returns nil if tbl has neither.
+func resFromLua(id json.RawMessage, tbl *lua.LTable) (*RPCRes, error) {
+	if errVal := tbl.RawGetString("error"); errVal != lua.LNil {
+		errTbl, ok := errVal.(*lua.LTable)
+		if !ok {
+			return nil, fmt.Errorf("error must be a table")
+		}
+		code, ok := errTbl.RawGetString("code").(lua.LNumber)
+		if !ok {
+			return nil, fmt.Errorf("error.code must be a number")
+		}
+		return NewRPCErrorRes(id, &RPCErr{
+			Code:    int(code),
+			Message: lua.LVAsString(errTbl.RawGetString("message")),
+		}), nil
+	}
+	if result := tbl.RawGetString("result"); result != lua.LNil {
+		return NewRPCRes(id, luaToJSON(result)), nil
+	}
+	return nil, nil
+}
+
+func newLuaTable() *lua.LTable {
+	return &lua.LTable{Metatable: lua.LNil}
+}
+
+func rawToLua(raw json.RawMessage) lua.LValue {
+	if len(raw) == 0 {
+		return lua.LNil
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return lua.LNil
+	}
+	return jsonToLua(v)
+}
+
+func jsonToLua(v interface{}) lua.LValue {
+	switch val := v.(type) {
+	case nil:
+		return luaNull
+	case bool:
+		return lua.LBool(val)
+	case json.Number:
+		if !strings.ContainsAny(string(val), ".eE") {
+			if i, err := val.Int64(); err != nil || i <= -maxLuaInteger || i >= maxLuaInteger {
+				return &lua.LUserData{Value: val}
+			}
+		}
+		f, _ := val.Float64()
+		return lua.LNumber(f)
+	case string:
+		return lua.LString(val)
+	case []interface{}:
+		tbl := newLuaTable()
+		for _, item := range val {
+			tbl.Append(jsonToLua(item))
+		}
+		return tbl
+	case map[string]interface{}:
+		tbl := newLuaTable()
+		for k, item := range val {
+			tbl.RawSetString(k, jsonToLua(item))
+		}
+		return tbl
+	default:
+		return lua.LNil
+	}
+}
+
+// luaToJSON converts a Lua value to its JSON equivalent. Tables with only
+// sequential integer keys, including empty tables, become arrays.
+func luaToJSON(v lua.LValue) interface{} {
+	switch val := v.(type) {
+	case lua.LBool:
+		return bool(val)
+	case lua.LNumber:
+		f := float64(val)
+		if f == math.Trunc(f) && math.Abs(f) < maxLuaInteger {
+			return int64(f)
+		}
+		return f
+	case lua.LString:
+		return string(val)
+	case *lua.LTable:
+		n := val.Len()
+		isArray := true
+		val.ForEach(func(k, _ lua.LValue) {
+			if num, ok := k.(lua.LNumber); !ok || float64(num) != math.Trunc(float64(num)) || int(num) < 1 || int(num) > n {
+				isArray = false
+			}
+		})
+		if isArray {
+			arr := make([]interface{}, 0, n)
+			for i := 1; i <= n; i++ {
+				arr = append(arr, luaToJSON(val.RawGetInt(i)))
+			}
+			return arr
+		}
+		obj := make(map[string]interface{})
+		val.ForEach(func(k, item lua.LValue) {
+			obj[lua.LVAsString(k)] = luaToJSON(item)
+		})
+		return obj
+	case *lua.LUserData:
+		if n, ok := val.Value.(json.Number); ok {
+			return n
+		}
+		return nil
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,200 @@
+package proxyd
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writePlugin(t *testing.T, src string) string {
+	path := filepath.Join(t.TempDir(), "plugin.lua")
+	require.NoError(t, os.WriteFile(path, []byte(src), 0o600))
+	return path
+}
+
+func TestPluginHostRequestHooks(t *testing.T) {
+	host, err := NewPluginHost([]*PluginConfig{{
+		Name: "test",
+		Path: writePlugin(t, `
+function pre_routing(req)
+  if req.method == "eth_blockNumberCompat" then
+    req.method = "eth_blockNumber"
+  end
+  if req.method == "eth_chainId" then
+    return { result = "0xa" }
+  end
+  if req.method == "debug_traceTransaction" then
+    return { error = { code = -32601, message = "tracing disabled" } }
+  end
+  if req.method == "eth_getLogs" then
+    return { group = "archive" }
+  end
+end
+
+function pre_forward(req, group)
+  if group == "main" and req.params[2] == "pending" then
+    req.params[2] = "latest"
+  end
+end
+`),
+	}})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	req := &RPCReq{JSONRPC: "2.0", Method: "eth_blockNumberCompat", ID: json.RawMessage("1")}
+	out, group, res := host.PreRouting(ctx, req)
+	require.Nil(t, res)
+	require.Empty(t, group)
+	require.Equal(t, "eth_blockNumber", out.Method)
+	require.Empty(t, out.Params, "omitted params must stay omitted")
+	require.Equal(t, "eth_blockNumberCompat", req.Method, "original must not be mutated")
+
+	_, _, res = host.PreRouting(ctx, &RPCReq{JSONRPC: "2.0", Method: "eth_chainId", ID: json.RawMessage("2")})
+	require.Equal(t, NewRPCRes(json.RawMessage("2"), "0xa"), res)
+
+	_, _, res = host.PreRouting(ctx, &RPCReq{JSONRPC: "2.0", Method: "debug_traceTransaction", ID: json.RawMessage("3")})
+	require.True(t, res.IsError())
+	require.Equal(t, -32601, res.Error.Code)
+	require.Equal(t, "tracing disabled", res.Error.Message)
+
+	_, group, res = host.PreRouting(ctx, &RPCReq{JSONRPC: "2.0", Method: "eth_getLogs", Params: json.RawMessage(`[{}]`), ID: json.RawMessage("4")})
+	require.Nil(t, res)
+	require.Equal(t, "archive", group)
+
+	out, group, res = host.PreForward(ctx, &RPCReq{
+		JSONRPC: "2.0",
+		Method:  "eth_getBalance",
+		Params:  json.RawMessage(`["0x1", "pending", null, 5]`),
+		ID:      json.RawMessage("5"),
+	}, "main")
+	require.Nil(t, res)
+	require.Equal(t, "main", group)
+	require.JSONEq(t, `["0x1","latest",null,5]`, string(out.Params))
+}
+
+func TestPluginHostKeepsLargeIntegers(t *testing.T) {
+	host, err := NewPluginHost([]*PluginConfig{{
+		Name: "test",
+		Path: writePlugin(t, `
+function pre_forward(req, group)
+  if req.method == "eth_estimateGasCompat" then
+    req.method = "eth_estimateGas"
+  end
+  if req.method == "eth_call" then
+    req.params[2] = "latest"
+  end
+end
+
+function post_response(req, res)
+end
+`),
+	}})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	params := `[{"value": 1180591620717411303424, "gas":21000}, "pending"]`
+	out, _, res := host.PreForward(ctx, &RPCReq{
+		JSONRPC: "2.0",
+		Method:  "eth_estimateGasCompat",
+		Params:  json.RawMessage(params),
+		ID:      json.RawMessage("1"),
+	}, "main")
+	require.Nil(t, res)
+	require.Equal(t, params, string(out.Params), "untouched params must be kept verbatim")
+
+	out, _, res = host.PreForward(ctx, &RPCReq{
+		JSONRPC: "2.0",
+		Method:  "eth_call",
+		Params:  json.RawMessage(params),
+		ID:      json.RawMessage("2"),
+	}, "main")
+	require.Nil(t, res)
+	require.JSONEq(t, `[{"value":1180591620717411303424,"gas":21000},"latest"]`, string(out.Params))
+	require.Contains(t, string(out.Params), "1180591620717411303424")
+
+	result := json.RawMessage(`{"balance":18446744073709551617}`)
+	res = host.PostResponse(ctx, out, NewRPCRes(json.RawMessage("2"), result))
+	require.Equal(t, result, res.Result)
+}
+
+func TestPluginHostPostResponse(t *testing.T) {
+	host, err := NewPluginHost([]*PluginConfig{{
+		Name: "test",
+		Path: writePlugin(t, `
+function post_response(req, res)
+  if req.method == "web3_clientVersion" then
+    res.result = "proxyd"
+  end
+  if req.method == "eth_getBlockByNumber" and res.result ~= proxyd.null then
+    res.result.miner = nil
+  end
+end
+`),
+	}})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	res := host.PostResponse(ctx,
+		&RPCReq{Method: "web3_clientVersion", ID: json.RawMessage("1")},
+		NewRPCRes(json.RawMessage("1"), "Geth/v1.13.0"),
+	)
+	require.Equal(t, NewRPCRes(json.RawMessage("1"), "proxyd"), res)
+
+	res = host.PostResponse(ctx,
+		&RPCReq{Method: "eth_getBlockByNumber", ID: json.RawMessage("2")},
+		NewRPCRes(json.RawMessage("2"), map[string]interface{}{"number": "0x1", "miner": "0xabc"}),
+	)
+	require.Equal(t, map[string]interface{}{"number": "0x1"}, res.Result)
+
+	res = host.PostResponse(ctx,
+		&RPCReq{Method: "eth_getBlockByNumber", ID: json.RawMessage("3")},
+		NewRPCRes(json.RawMessage("3"), nil),
+	)
+	require.Nil(t, res.Result)
+
+	res = host.PostResponse(ctx,
+		&RPCReq{Method: "eth_call", ID: json.RawMessage("4")},
+		NewRPCErrorRes(json.RawMessage("4"), ErrOverRateLimit),
+	)
+	require.Equal(t, ErrOverRateLimit, res.Error, "untouched errors must be preserved")
+}
+
+func TestPluginHostFailures(t *testing.T) {
+	_, err := NewPluginHost([]*PluginConfig{{Name: "empty", Path: writePlugin(t, `x = 1`)}})
+	require.Error(t, err)
+
+	_, err = NewPluginHost([]*PluginConfig{{Name: "syntax", Path: writePlugin(t, `function pre_routing(`)}})
+	require.Error(t, err)
+
+	_, err = NewPluginHost([]*PluginConfig{{Name: "sandbox", Path: writePlugin(t, `
+function pre_routing(req) end
+dofile("/etc/passwd")
+`)}})
+	require.Error(t, err)
+
+	host, err := NewPluginHost([]*PluginConfig{{
+		Name:    "slow",
+		Timeout: TOMLDuration(10 * time.Millisecond),
+		Path: writePlugin(t, `
+function pre_routing(req)
+  if req.method == "loop" then
+    while true do end
+  end
+  error("boom")
+end
+`),
+	}})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	for _, method := range []string{"loop", "eth_chainId"} {
+		_, _, res := host.PreRouting(ctx, &RPCReq{JSONRPC: "2.0", Method: method, ID: json.RawMessage("1")})
+		require.NotNil(t, res)
+		require.Equal(t, ErrPluginFailed, res.Error)
+	}
+}
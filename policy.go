@@ -0,0 +1,214 @@
+package proxyd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	PolicyDecisionAllow    = "allow"
+	PolicyDecisionDeny     = "deny"
+	PolicyDecisionAnnotate = "annotate"
+
+	defaultPolicyTimeout = 200 * time.Millisecond
+	maxPolicyResponseLen = 64 * 1024
+)
+
+// PolicyRequest is the body proxyd posts to the policy service. IP is the
+// client's IP, or its pseudonym in IP privacy mode.
+type PolicyRequest struct {
+	Method     string `json:"method"`
+	ParamsHash string `json:"params_hash"`
+	Sender     string `json:"sender,omitempty"`
+	Signer     string `json:"signer,omitempty"`
+	Key        string `json:"key"`
+	IP         string `json:"ip"`
+	ReqID      string `json:"req_id"`
+}
+
+// PolicyResponse is the decision returned by the policy service. Annotate
+// allows the request and logs the annotations alongside it.
+type PolicyResponse struct {
+	Decision    string            `json:"decision"`
+	Reason      string            `json:"reason,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// PolicyClient asks an external policy service whether a request may proceed.
+type PolicyClient struct {
+	url      string
+	methods  map[string]bool
+	timeout  time.Duration
+	failOpen bool
+	client   *http.Client
+}
+
+func NewPolicyClient(cfg PolicyConfig) (*PolicyClient, error) {
+	if len(cfg.Methods) == 0 {
+		return nil, fmt.Errorf("policy.methods must not be empty")
+	}
+	c := &PolicyClient{
+		url:      cfg.URL,
+		methods:  make(map[string]bool, len(cfg.Methods)),
+		timeout:  time.Duration(cfg.Timeout),
+		failOpen: cfg.FailOpen,
+		client:   &http.Client{},
+	}
+	if c.timeout == 0 {
+		c.timeout = defaultPolicyTimeout
+	}
+	for _, m := range cfg.Methods {
+		c.methods[m] = true
+	}
+	return c, nil
+}
+
+// Check returns an RPC error if the request must be rejected. Requests for
+// methods that aren't configured are always allowed.
+func (c *PolicyClient) Check(ctx context.Context, req *RPCReq) error {
+	if c == nil || !c.methods[req.Method] {
+		return nil
+	}
+
+	start := time.Now()
+	decision, err := c.decide(ctx, req)
+	policyCalloutDurationSumm.Observe(float64(time.Since(start)) / float64(time.Millisecond))
+	if err != nil {
+		RecordPolicyDecision("error")
+		log.Warn("error calling policy service",
+			"method", req.Method,
+			"req_id", GetReqID(ctx),
+			"fail_open", c.failOpen,
+			"err", err,
+		)
+		if c.failOpen {
+			return nil
+		}
+		return ErrPolicyUnavailable
+	}
+	RecordPolicyDecision(decision.Decision)
+
+	switch decision.Decision {
+	case PolicyDecisionAllow:
+		return nil
+	case PolicyDecisionAnnotate:
+		log.Info("policy annotated request",
+			"method", req.Method,
+			"req_id", GetReqID(ctx),
+			"reason", decision.Reason,
+			"annotations", decision.Annotations,
+		)
+		return nil
+	default:
+		log.Debug("policy denied request",
+			"method", req.Method,
+			"req_id", GetReqID(ctx),
+			"reason", decision.Reason,
+		)
+		if decision.Reason == "" {
+			return ErrPolicyDenied
+		}
+		err := *ErrPolicyDenied
+		err.Message = fmt.Sprintf("%s: %s", ErrPolicyDenied.Message, decision.Reason)
+		return &err
+	}
+}
+
+// CheckAll checks the requests of a batch concurrently, so that a batch takes
+// at most one policy timeout. It returns the error of each request by index;
+// nil requests are skipped.
+func (c *PolicyClient) CheckAll(ctx context.Context, reqs []*RPCReq) []error {
+	errs := make([]error, len(reqs))
+	if c == nil {
+		return errs
+	}
+	var wg sync.WaitGroup
+	for i, req := range reqs {
+		if req == nil || !c.methods[req.Method] {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, req *RPCReq) {
+			defer wg.Done()
+			errs[i] = c.Check(ctx, req)
+		}(i, req)
+	}
+	wg.Wait()
+	return errs
+}
+
+func (c *PolicyClient) decide(ctx context.Context, req *RPCReq) (*PolicyResponse, error) {
+	body, err := json.Marshal(&PolicyRequest{
+		Method:     req.Method,
+		ParamsHash: crypto.Keccak256Hash(req.Params).Hex(),
+		Sender:     policySender(ctx, req),
+		Signer:     policySigner(ctx),
+		Key:        GetAuthCtx(ctx),
+		IP:         GetClientIP(ctx),
+		ReqID:      GetReqID(ctx),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	httpRes, err := c.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer httpRes.Body.Close()
+	if httpRes.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("policy service returned status %d", httpRes.StatusCode)
+	}
+
+	var decision PolicyResponse
+	if err := json.NewDecoder(io.LimitReader(httpRes.Body, maxPolicyResponseLen)).Decode(&decision); err != nil {
+		return nil, wrapErr(err, "invalid policy response")
+	}
+	switch decision.Decision {
+	case PolicyDecisionAllow, PolicyDecisionDeny, PolicyDecisionAnnotate:
+		return &decision, nil
+	default:
+		return nil, fmt.Errorf("unknown policy decision %q", decision.Decision)
+	}
+}
+
+// policySender returns the sender of a raw transaction, or an empty string for
+// other methods and transactions it can't decode.
+func policySender(ctx context.Context, req *RPCReq) string {
+	if req.Method != "eth_sendRawTransaction" && req.Method != "eth_sendRawTransactionConditional" {
+		return ""
+	}
+	tx, err := convertSendReqToSendTx(ctx, req)
+	if err != nil {
+		return ""
+	}
+	from, err := txSender(tx)
+	if err != nil {
+		return ""
+	}
+	return from.Hex()
+}
+
+func policySigner(ctx context.Context) string {
+	if signer, ok := GetSigner(ctx); ok {
+		return signer.Hex()
+	}
+	return ""
+}
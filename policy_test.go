@@ -0,0 +1,139 @@
+package proxyd
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicyClientCheck(t *testing.T) {
+	var got PolicyRequest
+	policySrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		var res PolicyResponse
+		switch got.Method {
+		case "eth_call":
+			res = PolicyResponse{Decision: PolicyDecisionAllow}
+		case "eth_estimateGas":
+			res = PolicyResponse{Decision: PolicyDecisionAnnotate, Annotations: map[string]string{"risk": "low"}}
+		case "eth_sendRawTransaction":
+			res = PolicyResponse{Decision: PolicyDecisionDeny, Reason: "sanctioned sender"}
+		case "eth_getLogs":
+			time.Sleep(100 * time.Millisecond)
+		default:
+			res = PolicyResponse{Decision: "maybe"}
+		}
+		_ = json.NewEncoder(w).Encode(res)
+	}))
+	defer policySrv.Close()
+
+	client, err := NewPolicyClient(PolicyConfig{
+		URL:     policySrv.URL,
+		Methods: []string{"eth_call", "eth_estimateGas", "eth_sendRawTransaction", "eth_getLogs", "eth_sign"},
+		Timeout: TOMLDuration(20 * time.Millisecond),
+	})
+	require.NoError(t, err)
+
+	ctx := context.WithValue(context.Background(), ContextKeyAuth, "alice")        // nolint:staticcheck
+	ctx = context.WithValue(ctx, ContextKeyXForwardedFor, "203.0.113.7, 10.0.0.1") // nolint:staticcheck
+	ctx = context.WithValue(ctx, ContextKeyClientIP, "203.0.113.7")                // nolint:staticcheck
+
+	require.NoError(t, client.Check(ctx, &RPCReq{Method: "eth_call", Params: json.RawMessage(`[]`)}))
+	require.Equal(t, "alice", got.Key)
+	require.Equal(t, "203.0.113.7", got.IP)
+	require.Equal(t, "0x518674ab2b227e5f11e9084f615d57663cde47bce1ba168b4c19c7ee22a73d70", got.ParamsHash)
+	require.Empty(t, got.Sender)
+	require.Empty(t, got.Signer)
+
+	signer := common.HexToAddress("0x3c44cdddb6a900fa2b585dd299e03d12fa4293bc")
+	signedCtx := context.WithValue(ctx, ContextKeySigner, signer) // nolint:staticcheck
+	require.NoError(t, client.Check(signedCtx, &RPCReq{Method: "eth_estimateGas"}))
+	require.Equal(t, signer.Hex(), got.Signer)
+
+	// methods that aren't configured skip the callout
+	got = PolicyRequest{}
+	require.NoError(t, client.Check(ctx, &RPCReq{Method: "eth_chainId"}))
+	require.Empty(t, got.Method)
+
+	err = client.Check(ctx, &RPCReq{
+		Method: "eth_sendRawTransaction",
+		Params: json.RawMessage(`["0x1234"]`),
+	})
+	rpcErr, ok := err.(*RPCErr)
+	require.True(t, ok)
+	require.Equal(t, ErrPolicyDenied.Code, rpcErr.Code)
+	require.Equal(t, "request denied by policy: sanctioned sender", rpcErr.Message)
+
+	require.Equal(t, ErrPolicyUnavailable, client.Check(ctx, &RPCReq{Method: "eth_getLogs"}))
+	require.Equal(t, ErrPolicyUnavailable, client.Check(ctx, &RPCReq{Method: "eth_sign"}))
+
+	client.failOpen = true
+	require.NoError(t, client.Check(ctx, &RPCReq{Method: "eth_getLogs"}))
+}
+
+func TestPolicyClientCheckAll(t *testing.T) {
+	policySrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req PolicyRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		time.Sleep(30 * time.Millisecond)
+		decision := PolicyDecisionAllow
+		if req.Method == "eth_sendRawTransaction" {
+			decision = PolicyDecisionDeny
+		}
+		_ = json.NewEncoder(w).Encode(PolicyResponse{Decision: decision})
+	}))
+	defer policySrv.Close()
+
+	client, err := NewPolicyClient(PolicyConfig{
+		URL:     policySrv.URL,
+		Methods: []string{"eth_call", "eth_sendRawTransaction"},
+		Timeout: TOMLDuration(time.Second),
+	})
+	require.NoError(t, err)
+
+	reqs := []*RPCReq{{Method: "eth_call"}, nil, {Method: "eth_chainId"}, {Method: "eth_sendRawTransaction"}}
+	for i := 0; i < 6; i++ {
+		reqs = append(reqs, &RPCReq{Method: "eth_call"})
+	}
+	start := time.Now()
+	errs := client.CheckAll(context.Background(), reqs)
+	require.Less(t, time.Since(start), 200*time.Millisecond, "callouts must run concurrently")
+	require.Len(t, errs, len(reqs))
+	for i, err := range errs {
+		if i == 3 {
+			require.Equal(t, ErrPolicyDenied, err)
+			continue
+		}
+		require.NoError(t, err)
+	}
+
+	var nilClient *PolicyClient
+	require.Equal(t, make([]error, 2), nilClient.CheckAll(context.Background(), reqs[:2]))
+}
+
+func TestPolicySender(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	signer := types.LatestSignerForChainID(big.NewInt(10))
+	tx, err := types.SignNewTx(key, signer, &types.DynamicFeeTx{ChainID: big.NewInt(10), Gas: 21000})
+	require.NoError(t, err)
+	raw, err := tx.MarshalBinary()
+	require.NoError(t, err)
+	params := mustMarshalJSON([]string{hexutil.Encode(raw)})
+
+	ctx := context.Background()
+	from := crypto.PubkeyToAddress(key.PublicKey).Hex()
+	require.Equal(t, from, policySender(ctx, &RPCReq{Method: "eth_sendRawTransaction", Params: params}))
+	require.Empty(t, policySender(ctx, &RPCReq{Method: "eth_call", Params: params}))
+	require.Empty(t, policySender(ctx, &RPCReq{Method: "eth_sendRawTransaction", Params: json.RawMessage(`["0x1234"]`)}))
+}
@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"log/slog"
 	"math"
+	"math/big"
+	"net"
 	"net/http"
 	"os"
 	"time"
@@ -24,30 +26,25 @@ func SetLogLevel(logLevel slog.Leveler) {
 }
 
 func Start(config *Config) (*Server, func(), error) {
-	if len(config.Backends) == 0 {
-		return nil, nil, errors.New("must define at least one backend")
-	}
-	if len(config.BackendGroups) == 0 {
-		return nil, nil, errors.New("must define at least one backend group")
-	}
-	if len(config.RPCMethodMappings) == 0 {
-		return nil, nil, errors.New("must define at least one RPC method mapping")
+	if errs := checkConfig(config); len(errs) > 0 {
+		return nil, nil, errs[0]
 	}
+	config.RPCMethodMappings = SplitReadsWrites(config.RPCMethodMappings, config.WritesGroup, config.ReadsGroup)
+	config.RPCMethodMappings = MapRollupMethods(config.RPCMethodMappings, config.RollupGroup)
 
-	for authKey := range config.Authentication {
-		if authKey == "none" {
-			return nil, nil, errors.New("cannot use none as an auth key")
-		}
+	secrets, err := NewSecretStore(config.Secrets)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	// redis primary client
 	var redisClient redis.UniversalClient
 	if config.Redis.URL != "" {
-		rURL, err := ReadFromEnvOrConfig(config.Redis.URL)
+		rURL, err := secrets.Resolve(config.Redis.URL)
 		if err != nil {
 			return nil, nil, err
 		}
-		redisClient, err = NewRedisClient(rURL, config.Redis.RedisCluster)
+		redisClient, err = NewRedisClient(rURL, config.Redis)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -64,14 +61,11 @@ func Start(config *Config) (*Server, func(), error) {
 	// if read endpoint is not set, use primary endpoint
 	redisReadClient := redisClient
 	if config.Redis.ReadURL != "" {
-		if redisClient == nil {
-			return nil, nil, errors.New("must specify a Redis primary URL. only read endpoint is set")
-		}
-		rURL, err := ReadFromEnvOrConfig(config.Redis.ReadURL)
+		rURL, err := secrets.Resolve(config.Redis.ReadURL)
 		if err != nil {
 			return nil, nil, err
 		}
-		redisReadClient, err = NewRedisClient(rURL, config.Redis.RedisCluster)
+		redisReadClient, err = NewRedisClient(rURL, config.Redis)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -85,9 +79,7 @@ func Start(config *Config) (*Server, func(), error) {
 		}
 	}
 
-	if redisClient == nil && config.RateLimit.UseRedis {
-		return nil, nil, errors.New("must specify a Redis URL if UseRedis is true in rate limit config")
-	}
+	redisHealth := NewRedisHealth(redisClient, config.Redis.DegradedMode)
 
 	// While modifying shared globals is a bad practice, the alternative
 	// is to clone these errors on every invocation. This is inefficient.
@@ -103,15 +95,6 @@ func Start(config *Config) (*Server, func(), error) {
 		ErrTooManyBatchRequests.Message = config.BatchConfig.ErrorMessage
 	}
 
-	if config.SenderRateLimit.Enabled {
-		if config.SenderRateLimit.Limit <= 0 {
-			return nil, nil, errors.New("limit in sender_rate_limit must be > 0")
-		}
-		if time.Duration(config.SenderRateLimit.Interval) < time.Second {
-			return nil, nil, errors.New("interval in sender_rate_limit must be >= 1s")
-		}
-	}
-
 	allowedDynamicHeaderSet := make(map[string]struct{})
 	maxConcurrentRPCs := config.Server.MaxConcurrentRPCs
 	var rpcRequestSemaphore *semaphore.Weighted
@@ -126,21 +109,42 @@ func Start(config *Config) (*Server, func(), error) {
 		log.Info("Using max concurrent RPCs of", "maxConcurrentRPCs", maxConcurrentRPCs)
 	}
 
+	methodTimeouts := NewMethodTimeouts(config.Server.MethodTimeouts)
+
+	faultConfig := config.FaultInjection
+	faultConfig.AdminToken, err = secrets.Resolve(faultConfig.AdminToken)
+	if err != nil {
+		return nil, nil, err
+	}
+	faultInjector, err := NewFaultInjector(faultConfig, config.Backends)
+	if err != nil {
+		return nil, nil, err
+	}
+	if faultInjector != nil {
+		secrets.Watch(config.FaultInjection.AdminToken, faultInjector.adminToken.Store)
+	}
+	cassette, err := NewCassette(config.Cassette)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	backendNames := make([]string, 0)
 	backendsByName := make(map[string]*Backend)
+	backendTemplates := make(map[string]*backendTemplate)
+	wsBackends := wsBackendNames(config)
 	for name, cfg := range config.Backends {
 		opts := make([]BackendOpt, 0)
 
-		rpcURL, err := ReadFromEnvOrConfig(cfg.RPCURL)
+		urls, err := resolveBackendURLs(cfg, secrets)
 		if err != nil {
-			return nil, nil, err
+			return nil, nil, fmt.Errorf("backend %s %w", name, err)
 		}
-		wsURL, err := ReadFromEnvOrConfig(cfg.WSURL)
-		if err != nil {
-			return nil, nil, err
+		if errs := checkBackendURLs(name, cfg, urls, wsBackends[name]); len(errs) > 0 {
+			return nil, nil, errs[0]
 		}
-		if rpcURL == "" {
-			return nil, nil, fmt.Errorf("must define an RPC URL for backend %s", name)
+		rpcURL, wsURL, ipcPath := urls.rpc, urls.ws, urls.ipcPath
+		if ipcPath != "" {
+			rpcURL = ipcBackendURL
 		}
 
 		if config.BackendOptions.ResponseTimeoutMilliseconds != 0 {
@@ -150,6 +154,9 @@ func Start(config *Config) (*Server, func(), error) {
 			timeout := secondsToDuration(config.BackendOptions.ResponseTimeoutSeconds)
 			opts = append(opts, WithTimeout(timeout))
 		}
+		if methodTimeouts != nil {
+			opts = append(opts, WithMethodTimeouts(methodTimeouts))
+		}
 		if config.BackendOptions.MaxRetries != 0 {
 			opts = append(opts, WithMaxRetries(config.BackendOptions.MaxRetries))
 		}
@@ -174,8 +181,11 @@ func Start(config *Config) (*Server, func(), error) {
 		if cfg.MaxWSConns != 0 {
 			opts = append(opts, WithMaxWSConns(cfg.MaxWSConns))
 		}
+		if config.WSCompression.Client || config.WSCompression.Upstream {
+			opts = append(opts, WithWSCompression(config.WSCompression))
+		}
 		if cfg.Password != "" {
-			passwordVal, err := ReadFromEnvOrConfig(cfg.Password)
+			passwordVal, err := secrets.Resolve(cfg.Password)
 			if err != nil {
 				return nil, nil, err
 			}
@@ -184,7 +194,7 @@ func Start(config *Config) (*Server, func(), error) {
 
 		headers := map[string]string{}
 		for headerName, headerValue := range cfg.Headers {
-			headerValue, err := ReadFromEnvOrConfig(headerValue)
+			headerValue, err := secrets.Resolve(headerValue)
 			if err != nil {
 				return nil, nil, err
 			}
@@ -193,6 +203,14 @@ func Start(config *Config) (*Server, func(), error) {
 		}
 		opts = append(opts, WithHeaders(headers))
 
+		policyCfg := cfg.HeaderPolicy
+		policyCfg.Forward = append(policyCfg.Forward, cfg.AllowedDynamicHeaders...)
+		headerPolicy, err := NewHeaderPolicy(policyCfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		opts = append(opts, WithHeaderPolicy(headerPolicy))
+
 		tlsConfig, err := configureBackendTLS(cfg)
 		if err != nil {
 			return nil, nil, err
@@ -201,6 +219,10 @@ func Start(config *Config) (*Server, func(), error) {
 			log.Info("using custom TLS config for backend", "name", name)
 			opts = append(opts, WithTLSConfig(tlsConfig))
 		}
+		if ipcPath != "" {
+			log.Info("using ipc transport for backend", "name", name, "ipc_path", ipcPath)
+			opts = append(opts, WithIPCPath(ipcPath))
+		}
 		if cfg.StripTrailingXFF {
 			opts = append(opts, WithStrippedTrailingXFF())
 		}
@@ -218,6 +240,14 @@ func Start(config *Config) (*Server, func(), error) {
 		opts = append(opts, WithConsensusForcedCandidate(cfg.ConsensusForcedCandidate))
 		opts = append(opts, WithWeight(cfg.Weight))
 
+		maintenance, err := newMaintenanceWindows(cfg.Maintenance)
+		if err != nil {
+			return nil, nil, err
+		}
+		opts = append(opts, WithMaintenanceWindows(maintenance))
+		opts = append(opts, WithLeaderCheck(urls.conductor, urls.leader))
+		opts = append(opts, WithRollupRPC(urls.rollupRPC))
+
 		receiptsTarget, err := ReadFromEnvOrConfig(cfg.ConsensusReceiptsTarget)
 		if err != nil {
 			return nil, nil, err
@@ -227,13 +257,36 @@ func Start(config *Config) (*Server, func(), error) {
 			return nil, nil, err
 		}
 		opts = append(opts, WithConsensusReceiptTarget(receiptsTarget))
+		flavor, err := ParseClientFlavor(cfg.ClientFlavor)
+		if err != nil {
+			return nil, nil, err
+		}
+		opts = append(opts, WithClientFlavor(flavor))
+		if cassette != nil {
+			opts = append(opts, WithCassette(cassette))
+		}
+		if faultInjector != nil {
+			// last, to wrap the transport the other options set up
+			opts = append(opts, WithFaultInjector(faultInjector))
+		}
 
 		back := NewBackend(name, rpcURL, wsURL, rpcRequestSemaphore, opts...)
+		if ipcPath == "" {
+			backendTemplates[name] = &backendTemplate{rpcURL: rpcURL, wsURL: wsURL, opts: opts}
+		}
+		secrets.Watch(cfg.RPCURL, back.SetRPCURL)
+		secrets.Watch(cfg.WSURL, back.SetWSURL)
+		secrets.Watch(cfg.Password, back.SetAuthPassword)
+		for headerName, headerValue := range cfg.Headers {
+			headerName := headerName
+			secrets.Watch(headerValue, func(value string) {
+				back.SetHeader(headerName, value)
+			})
+		}
 
-		for _, header := range cfg.AllowedDynamicHeaders {
+		for _, header := range headerPolicy.CapturedHeaders() {
 			allowedDynamicHeaderSet[header] = struct{}{}
 		}
-		back.forwardRequestHeaders = cfg.AllowedDynamicHeaders
 
 		backendNames = append(backendNames, name)
 		backendsByName[name] = back
@@ -254,10 +307,7 @@ func Start(config *Config) (*Server, func(), error) {
 		config.InteropValidationConfig.Strategy = defaultInteropValidationStrategy
 	}
 
-	if config.InteropValidationConfig.LoadBalancingUnhealthinessTimeout == 0 && config.InteropValidationConfig.Strategy == HealthAwareLoadBalancingStrategy {
-		log.Warn("no interop validation load balancing unhealthiness timeout provided for health aware strategy, using default timeout", "timeout", defaultInteropLoadBalancingUnhealthinessTimeout)
-		config.InteropValidationConfig.LoadBalancingUnhealthinessTimeout = defaultInteropLoadBalancingUnhealthinessTimeout
-	}
+	setInteropStrategyDefaults(&config.InteropValidationConfig)
 
 	if config.InteropValidationConfig.ReqSizeLimit == 0 {
 		log.Warn("no interop validation request size limit provided, using default size limit", "size_limit", defaultInteropReqSizeLimit)
@@ -273,14 +323,11 @@ func Start(config *Config) (*Server, func(), error) {
 	log.Info("configured interop validation strategy", "strategy", config.InteropValidationConfig.Strategy)
 
 	backendGroups := make(map[string]*BackendGroup)
+	var discoveries []*BackendDiscovery
 	for bgName, bg := range config.BackendGroups {
 		backends := make([]*Backend, 0)
 		fallbackBackends := make(map[string]bool)
-		fallbackCount := 0
 		for _, bName := range bg.Backends {
-			if backendsByName[bName] == nil {
-				return nil, nil, fmt.Errorf("backend %s is not defined", bName)
-			}
 			backends = append(backends, backendsByName[bName])
 
 			for _, fb := range bg.Fallbacks {
@@ -290,7 +337,6 @@ func Start(config *Config) (*Server, func(), error) {
 						"backend_name", bName,
 						"backend_group", bgName,
 					)
-					fallbackCount++
 				}
 			}
 
@@ -303,12 +349,13 @@ func Start(config *Config) (*Server, func(), error) {
 			}
 		}
 
-		if fallbackCount != len(bg.Fallbacks) {
-			return nil, nil,
-				fmt.Errorf(
-					"error: number of fallbacks instantiated (%d) did not match configured (%d) for backend group %s",
-					fallbackCount, len(bg.Fallbacks), bgName,
-				)
+		var requestRewriter *RequestRewriter
+		if len(bg.RequestRewrites) > 0 {
+			var err error
+			requestRewriter, err = NewRequestRewriter(bg.RequestRewrites)
+			if err != nil {
+				return nil, nil, err
+			}
 		}
 
 		backendGroups[bgName] = &BackendGroup{
@@ -318,37 +365,55 @@ func Start(config *Config) (*Server, func(), error) {
 			FallbackBackends:       fallbackBackends,
 			routingStrategy:        bg.RoutingStrategy,
 			multicallRPCErrorCheck: bg.MulticallRPCErrorCheck,
+			receiptAggregation:     bg.ReceiptAggregation,
+			blockReceipts:          NewBlockReceipts(bg.BlockReceiptsMinBatch),
+			rollupConsensus:        bg.RollupConsensus,
+			inflight:               NewInflightLimiter(bgName, bg.Inflight),
+			requestRewriter:        requestRewriter,
+			responseRewriter:       NewResponseRewriter(bg.ResponseRewrites),
+		}
+
+		if bg.LatestTranslation != nil {
+			backendGroups[bgName].heads = NewHeadTracker(backendGroups[bgName], *bg.LatestTranslation)
+		}
+
+		if bg.Discovery != nil {
+			template := backendTemplates[bg.Discovery.Template]
+			discovery, err := NewBackendDiscovery(backendGroups[bgName], bg.Discovery, template, rpcRequestSemaphore)
+			if err != nil {
+				return nil, nil, err
+			}
+			discoveries = append(discoveries, discovery)
 		}
 	}
 
 	var wsBackendGroup *BackendGroup
 	if config.WSBackendGroup != "" {
 		wsBackendGroup = backendGroups[config.WSBackendGroup]
-		if wsBackendGroup == nil {
-			return nil, nil, fmt.Errorf("ws backend group %s does not exist", config.WSBackendGroup)
-		}
 	}
 
-	if wsBackendGroup == nil && config.Server.WSPort != 0 {
-		return nil, nil, fmt.Errorf("a ws port was defined, but no ws group was defined")
+	virtualHosts, err := NewVirtualHosts(config.VirtualHosts, backendGroups)
+	if err != nil {
+		return nil, nil, err
 	}
-
-	for _, bg := range config.RPCMethodMappings {
-		if backendGroups[bg] == nil {
-			return nil, nil, fmt.Errorf("undefined backend group %s", bg)
-		}
+	tlsConfig, err := newServerTLSConfig(config.Server, virtualHosts)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	var resolvedAuth map[string]string
+	// the resolved secret of each reference, to replace when it's refreshed
+	authRefs := make(map[string]string)
 
 	if config.Authentication != nil {
 		resolvedAuth = make(map[string]string)
 		for secret, alias := range config.Authentication {
-			resolvedSecret, err := ReadFromEnvOrConfig(secret)
+			resolvedSecret, err := secrets.Resolve(secret)
 			if err != nil {
 				return nil, nil, err
 			}
 			resolvedAuth[resolvedSecret] = alias
+			authRefs[secret] = resolvedSecret
 		}
 	}
 
@@ -369,25 +434,44 @@ func Start(config *Config) (*Server, func(), error) {
 				if config.Cache.TTL != 0 {
 					ttl = time.Duration(config.Cache.TTL)
 				}
-				cache = newRedisCache(redisClient, redisReadClient, config.Redis.Namespace, ttl)
+				cache = newDegradedCache(newRedisCache(redisClient, redisReadClient, config.Redis.Namespace, ttl), redisHealth)
 
 				if config.Redis.FallbackToMemory {
 					cache = newFallbackCache(cache, newMemoryCache())
 				}
 			}
 		}
+		if len(config.Tenants) > 0 {
+			cache = newTenantCache(cache)
+		}
 		rpcCache = newRPCCache(newCacheWithCompression(cache))
+		if config.Cache.Immutable.Enabled {
+			var immutable Cache = newImmutableCache(config.Cache.Immutable.MaxEntries)
+			if len(config.Tenants) > 0 {
+				immutable = newTenantCache(immutable)
+			}
+			rpcCache = newImmutableRPCCache(rpcCache, newCacheWithCompression(immutable))
+		}
 	}
 
 	limiterFactory := func(dur time.Duration, max int, prefix string) FrontendRateLimiter {
 		if config.RateLimit.UseRedis || config.HighPrioRateLimit.UseRedis {
-			limiter := NewRedisFrontendRateLimiter(redisClient, dur, max, prefix)
+			limiter := newDegradedRateLimiter(NewRedisFrontendRateLimiter(redisClient, dur, max, prefix), redisHealth)
 
-			if config.Redis.FallbackToMemory {
-				limiter = NewFallbackRateLimiter(
+			switch {
+			case config.Redis.FallbackToMemory:
+				limiter = newNamedFallbackRateLimiter(
+					prefix,
 					limiter,
 					NewMemoryFrontendRateLimit(dur, max),
 				)
+			case !isSenderLimit(prefix):
+				// keep limiting, conservatively, while Redis is down
+				limiter = newNamedFallbackRateLimiter(
+					prefix,
+					limiter,
+					NewMemoryFrontendRateLimit(dur, localFallbackLimit(max, config.Redis.FallbackRateLimitDivisor)),
+				)
 			}
 
 			return limiter
@@ -396,32 +480,86 @@ func Start(config *Config) (*Server, func(), error) {
 		return NewMemoryFrontendRateLimit(dur, max)
 	}
 
-	var interopStrategy InteropStrategy
+	interopBlockNumber := func() (uint64, bool) {
+		bg := backendGroups[config.RPCMethodMappings["eth_sendRawTransaction"]]
+		if bg == nil || bg.Consensus == nil {
+			return 0, false
+		}
+		latest := uint64(bg.Consensus.GetLatestBlockNumber())
+		return latest, latest > 0
+	}
+	interopConfig := config.InteropValidationConfig
+	if interopConfig.AdminToken, err = secrets.Resolve(interopConfig.AdminToken); err != nil {
+		return nil, nil, err
+	}
+	interopAdmin, err := NewInteropAdmin(interopConfig, func(cfg InteropValidationConfig) (InteropStrategy, *failoverStrategyImpl, error) {
+		return newInteropStrategy(cfg, interopBlockNumber)
+	}, redisClient, config.Redis.Namespace)
+	if err != nil {
+		return nil, nil, err
+	}
+	secrets.Watch(config.InteropValidationConfig.AdminToken, interopAdmin.adminToken.Store)
+
+	var txFeeFilter *TxFeeFilter
+	if config.TxFeeFilter != (TxFeeFilterConfig{}) {
+		txFeeFilter = NewTxFeeFilter(config.TxFeeFilter, func() *big.Int {
+			bg := backendGroups[config.RPCMethodMappings["eth_sendRawTransaction"]]
+			if bg == nil || bg.Consensus == nil {
+				return nil
+			}
+			return bg.Consensus.GetBaseFee()
+		})
+	}
 
-	opts := CommonStrategyOpts(
-		WithReqSizeLimit(config.InteropValidationConfig.ReqSizeLimit),
-		WithAccessListSizeLimit(config.InteropValidationConfig.AccessListSizeLimit),
-	)
+	var pendingTxs *PendingTxLimiter
+	if config.PendingTxLimit.Enabled {
+		bg := backendGroups[config.RPCMethodMappings["eth_sendRawTransaction"]]
+		pendingTxs = NewPendingTxLimiter(config.PendingTxLimit, redisClient, config.Redis.Namespace, backendGroupNonceAt(bg))
+	}
 
-	switch config.InteropValidationConfig.Strategy {
-	case FirstSupervisorStrategy, EmptyStrategy:
-		interopStrategy = NewFirstSupervisorStrategy(
-			config.InteropValidationConfig.Urls,
-			opts...,
-		)
-	case MulticallStrategy:
-		interopStrategy = NewMulticallStrategy(
-			config.InteropValidationConfig.Urls,
-			opts...,
-		)
-	case HealthAwareLoadBalancingStrategy:
-		interopStrategy = NewHealthAwareLoadBalancingStrategy(
-			config.InteropValidationConfig.Urls,
-			config.InteropValidationConfig.LoadBalancingUnhealthinessTimeout,
-			opts...,
-		)
-	default:
-		return nil, nil, fmt.Errorf("invalid interop validating strategy: %s", config.InteropValidationConfig.Strategy)
+	var pendingNonces *PendingNonces
+	if config.PendingNonces.Enabled {
+		pendingNonces = NewPendingNonces(config.PendingNonces, redisClient, config.Redis.Namespace)
+	}
+
+	var gasOracle *GasOracle
+	if config.GasOracle.Enabled {
+		gasOracle = NewGasOracle(config.GasOracle, backendGroups, config.RPCMethodMappings)
+	}
+
+	var filters *FilterManager
+	if config.Filters.Enabled {
+		filters = NewFilterManager(config.Filters)
+	}
+
+	var txDedup *TxDedup
+	if config.TxDedup.Enabled {
+		txDedup = NewTxDedup(config.TxDedup)
+	}
+
+	var estimateGas *EstimateGas
+	if config.EstimateGas.Enabled {
+		estimateGas = NewEstimateGas(config.EstimateGas)
+	}
+
+	var readYourWrites *ReadYourWrites
+	if config.ReadYourWrites.Enabled {
+		readYourWrites = NewReadYourWrites(config.ReadYourWrites)
+	}
+
+	var loadShedder *LoadShedder
+	if config.LoadShedding.Enabled {
+		loadShedder = NewLoadShedder(config.LoadShedding)
+	}
+
+	var notFoundRetry *NotFoundRetry
+	if config.NotFoundRetry.Enabled {
+		notFoundRetry = NewNotFoundRetry(config.NotFoundRetry)
+	}
+
+	var affinity *BackendAffinity
+	if len(config.Affinity.Methods) > 0 {
+		affinity = NewBackendAffinity(config.Affinity)
 	}
 
 	highPrioSigners := make(map[common.Address]bool, len(config.HighPrioSigners))
@@ -429,6 +567,55 @@ func Start(config *Config) (*Server, func(), error) {
 		highPrioSigners[common.HexToAddress(s)] = true
 	}
 
+	var plugins *PluginHost
+	if len(config.Plugins) > 0 {
+		var err error
+		plugins, err = NewPluginHost(config.Plugins)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error loading plugins: %w", err)
+		}
+	}
+
+	var policy *PolicyClient
+	if config.Policy.URL != "" {
+		url, err := ReadFromEnvOrConfig(config.Policy.URL)
+		if err != nil {
+			return nil, nil, err
+		}
+		config.Policy.URL = url
+		policy, err = NewPolicyClient(config.Policy)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var featureFlags *FeatureFlags
+	if len(config.FeatureFlags.Flags) > 0 || config.FeatureFlags.Redis {
+		var err error
+		featureFlags, err = NewFeatureFlags(config.FeatureFlags, redisClient, config.Redis.Namespace)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var webhooks *WebhookNotifier
+	if len(config.Webhooks.URLs) > 0 {
+		var err error
+		webhooks, err = NewWebhookNotifier(config.Webhooks, secrets)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var events *EventPublisher
+	if config.Events.Sink != "" {
+		var err error
+		events, err = NewEventPublisher(config.Events)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
 	srv, err := NewServer(
 		backendGroups,
 		wsBackendGroup,
@@ -450,13 +637,167 @@ func Start(config *Config) (*Server, func(), error) {
 		config.BatchConfig.MaxSize,
 		limiterFactory,
 		config.InteropValidationConfig,
-		interopStrategy,
+		interopAdmin,
 		allowedDynamicHeaders,
 		config.VerifyFlashbotsSignature,
 	)
 	if err != nil {
 		return nil, nil, fmt.Errorf("error creating server: %w", err)
 	}
+	for ref, resolved := range authRefs {
+		current := resolved
+		secrets.Watch(ref, func(value string) {
+			srv.ReplaceAuthSecret(current, value)
+			current = value
+		})
+	}
+	srv.plugins = plugins
+	srv.policy = policy
+	srv.events = events
+	srv.webhooks = webhooks
+	srv.featureFlags = featureFlags
+	srv.blobTx = config.BlobTx
+	srv.txFeeFilter = txFeeFilter
+	srv.pendingTxs = pendingTxs
+	srv.pendingNonces = pendingNonces
+	srv.gasOracle = gasOracle
+	srv.filters = filters
+	srv.affinity = affinity
+	srv.writesGroup = config.WritesGroup
+	srv.readsGroup = config.ReadsGroup
+	srv.rollupGroup = config.RollupGroup
+	if config.TxPreferences.Enabled {
+		srv.txPreferences = NewTxPreferenceStore(redisClient, config.Redis.Namespace)
+	}
+	if config.WSReplay.Enabled {
+		srv.wsReplay = NewWSReplay(config.WSReplay, redisClient, config.Redis.Namespace)
+	}
+	srv.cacheControl = NewCacheControl(config.CacheControl)
+	srv.readYourWrites = readYourWrites
+	srv.txDedup = txDedup
+	srv.estimateGas = estimateGas
+	srv.deprecations = NewMethodDeprecations(config.DeprecatedMethods)
+	if warming := config.Cache.Warming; warming.Enabled {
+		srv.cacheWarmer, err = NewCacheWarmer(warming, backendGroups[warming.BackendGroup], redisClient, config.Redis.Namespace, rpcCache)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	if config.EarlyReturn.Enabled {
+		srv.earlyReturn = NewEarlyReturn(config.EarlyReturn)
+	}
+	srv.notFoundRetry = notFoundRetry
+	srv.loadShedder = loadShedder
+	srv.inflight = NewInflightLimiter("global", config.Inflight)
+	srv.methodTimeouts = methodTimeouts
+	srv.binaryCodecs = newBinaryCodecs(config.BinaryEncoding)
+	srv.tenants = NewTenants(config.Tenants, limiterFactory)
+	srv.virtualHosts = virtualHosts
+	rateLimitTiers := newRateLimitTiers(config.RateLimitTiers, limiterFactory)
+	srv.authPolicies, err = NewAuthPolicies(config.AuthPolicies, rateLimitTiers, backendGroups)
+	if err != nil {
+		return nil, nil, err
+	}
+	srv.callPolicies, err = NewCallPolicies(config.CallPolicies, config.RateLimitTiers)
+	if err != nil {
+		return nil, nil, err
+	}
+	apiKeysConfig := config.APIKeys
+	if apiKeysConfig.AdminToken, err = secrets.Resolve(apiKeysConfig.AdminToken); err != nil {
+		return nil, nil, err
+	}
+	srv.keyStore, err = NewKeyStore(apiKeysConfig, redisClient, config.Redis.Namespace, rateLimitTiers, srv.authPolicies)
+	if err != nil {
+		return nil, nil, err
+	}
+	if srv.keyStore != nil {
+		secrets.Watch(config.APIKeys.AdminToken, srv.keyStore.adminToken.Store)
+	}
+	srv.hmacAuth, err = NewHMACAuth(config.HMACAuth, secrets)
+	if err != nil {
+		return nil, nil, err
+	}
+	srv.cacheFailClosed = config.Redis.DegradedMode.Cache == RedisFailClosed
+	srv.senderLimitsFailOpen = config.Redis.DegradedMode.SenderLimits == RedisFailOpen
+	srv.replayGuard = NewReplayGuard(config.ReplayProtection, time.Duration(config.HMACAuth.MaxClockSkew), redisClient, config.Redis.Namespace)
+	srv.responseSigner, err = NewResponseSigner(config.ResponseSigning, secrets)
+	if err != nil {
+		return nil, nil, err
+	}
+	srv.selectors, err = NewSelectors(config.Selectors, backendGroups, limiterFactory)
+	if err != nil {
+		return nil, nil, err
+	}
+	srv.senderReputation = NewSenderReputation(config.SenderReputation, redisClient, config.Redis.Namespace, limiterFactory)
+	srv.usageExporter, err = NewUsageExporter(config.UsageExport)
+	if err != nil {
+		return nil, nil, err
+	}
+	srv.routingRules, err = NewRoutingRules(config.RoutingRules, backendGroups)
+	if err != nil {
+		return nil, nil, err
+	}
+	srv.signerClasses, err = NewSignerClasses(config.SignerClasses, backendGroups, limiterFactory)
+	if err != nil {
+		return nil, nil, err
+	}
+	srv.strictJSONRPC = NewStrictJSONRPC(config.StrictJSONRPC)
+	srv.frontendLimits = NewFrontendLimits(config.Server)
+	ipFilter, err := NewIPFilter(config.IPFilter)
+	if err != nil {
+		return nil, nil, err
+	}
+	srv.ipFilter = ipFilter
+	maintenanceConfig := config.MaintenanceMode
+	if maintenanceConfig.AdminToken, err = secrets.Resolve(maintenanceConfig.AdminToken); err != nil {
+		return nil, nil, err
+	}
+	srv.maintenance, err = NewMaintenanceMode(maintenanceConfig, backendGroups)
+	if err != nil {
+		return nil, nil, err
+	}
+	secrets.Watch(config.MaintenanceMode.AdminToken, srv.maintenance.adminToken.Store)
+	auditConfig := config.TxAudit
+	if auditConfig.AdminToken, err = secrets.Resolve(auditConfig.AdminToken); err != nil {
+		return nil, nil, err
+	}
+	srv.ipHasher, err = NewIPHasher(config.IPPrivacy, secrets)
+	if err != nil {
+		return nil, nil, err
+	}
+	srv.txAudit, err = NewTxAuditLog(auditConfig, redisClient, config.Redis.Namespace, srv.ipHasher)
+	if err != nil {
+		return nil, nil, err
+	}
+	if srv.txAudit != nil {
+		secrets.Watch(config.TxAudit.AdminToken, srv.txAudit.adminToken.Store)
+	}
+	trafficConfig := config.TrafficAnalysis
+	if trafficConfig.AdminToken, err = secrets.Resolve(trafficConfig.AdminToken); err != nil {
+		return nil, nil, err
+	}
+	srv.trafficAnalyzer = NewTrafficAnalyzer(trafficConfig)
+	if srv.trafficAnalyzer != nil {
+		secrets.Watch(config.TrafficAnalysis.AdminToken, srv.trafficAnalyzer.adminToken.Store)
+	}
+	exemptionsConfig := config.RateLimitExemptions
+	if exemptionsConfig.AdminToken, err = secrets.Resolve(exemptionsConfig.AdminToken); err != nil {
+		return nil, nil, err
+	}
+	srv.rateLimitExemptions, err = NewRateLimitExemptions(exemptionsConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	if srv.rateLimitExemptions != nil {
+		secrets.Watch(config.RateLimitExemptions.AdminToken, srv.rateLimitExemptions.adminToken.Store)
+	}
+
+	srv.interopAdmin = interopAdmin
+	srv.interopStaticChecker = NewInteropStaticChecker(config.InteropValidationConfig.StaticChecks)
+
+	srv.upgrader.EnableCompression = config.WSCompression.Client
+	srv.wsSendBuffer = config.WSSendBuffer
+	srv.wsNotificationBatching = config.WSNotificationBatching
 
 	// Enable to support browser websocket connections.
 	// See https://pkg.go.dev/github.com/gorilla/websocket#hdr-Origin_Considerations
@@ -466,11 +807,64 @@ func Start(config *Config) (*Server, func(), error) {
 		}
 	}
 
+	leaders := NewLeaderTracker(config.LeaderElection, backendsByName)
+
+	l1RPCURL, err := resolveL1RPCURL(config.DerivationHealth)
+	if err != nil {
+		return nil, nil, err
+	}
+	derivation := NewDerivationMonitor(config.DerivationHealth, l1RPCURL, backendsByName)
+
+	var gossip *HealthGossip
+	if config.HealthGossip.Enabled {
+		gossip = NewHealthGossip(config.HealthGossip, redisClient, config.Redis.Namespace, backendsByName, backendGroups)
+	}
+
+	var prober *CapabilityProber
+	if config.CapabilityProbing.Enabled {
+		prober = NewCapabilityProber(config.CapabilityProbing, backendGroups)
+	}
+
+	var scorer *BackendScorer
+	if config.BackendScoring.Enabled {
+		scorer = NewBackendScorer(config.BackendScoring, backendGroups)
+	}
+
 	if config.Metrics.Enabled {
 		addr := fmt.Sprintf("%s:%d", config.Metrics.Host, config.Metrics.Port)
+		mux := http.NewServeMux()
+		mux.Handle("/", promhttp.Handler())
+		if scorer != nil {
+			mux.Handle("/admin/backends", scorer)
+		}
+		mux.Handle("/admin/status", NewStatusReporter(srv, backendGroups, scorer))
+		if srv.keyStore != nil {
+			mux.Handle("/admin/keys", srv.keyStore)
+			mux.Handle("/admin/keys/", srv.keyStore)
+		}
+		if config.MaintenanceMode.AdminToken != "" {
+			mux.Handle("/admin/maintenance", srv.maintenance)
+			mux.Handle("/admin/maintenance/", srv.maintenance)
+		}
+		if srv.rateLimitExemptions != nil && config.RateLimitExemptions.AdminToken != "" {
+			mux.Handle("/admin/exemptions", srv.rateLimitExemptions)
+		}
+		if config.InteropValidationConfig.AdminToken != "" {
+			mux.Handle("/admin/interop", interopAdmin)
+		}
+		if srv.txAudit != nil && config.TxAudit.AdminToken != "" {
+			mux.Handle("/admin/txs", srv.txAudit)
+		}
+		if srv.trafficAnalyzer != nil {
+			mux.Handle("/admin/top", srv.trafficAnalyzer)
+		}
+		if faultInjector != nil && faultConfig.AdminToken != "" {
+			mux.Handle("/admin/faults", faultInjector)
+			mux.Handle("/admin/faults/", faultInjector)
+		}
 		log.Info("starting metrics server", "addr", addr)
 		go func() {
-			if err := http.ListenAndServe(addr, promhttp.Handler()); err != nil {
+			if err := http.ListenAndServe(addr, mux); err != nil {
 				log.Error("error starting metrics server", "err", err)
 			}
 		}()
@@ -481,9 +875,64 @@ func Start(config *Config) (*Server, func(), error) {
 	// encounter an error creating their servers
 	errTimer := time.NewTimer(10 * time.Millisecond)
 
-	if config.Server.RPCPort != 0 {
+	activatedListeners := make(map[string]net.Listener)
+	if config.Server.SystemdSocketActivation {
+		activatedListeners, err = SystemdListeners()
+		if err != nil {
+			return nil, nil, err
+		}
+		log.Info("using systemd socket activation", "sockets", len(activatedListeners))
+	}
+	socketMode, err := parseUnixSocketMode(config.Server.UnixSocketMode)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rpcListener, err := newFrontendListener(
+		activatedListeners[SystemdRPCSocketName],
+		config.Server.RPCUnixSocket,
+		socketMode,
+		config.Server.RPCHost,
+		config.Server.RPCPort,
+	)
+	if err != nil {
+		return nil, nil, wrapErr(err, "error creating RPC listener")
+	}
+	wsListener, err := newFrontendListener(
+		activatedListeners[SystemdWSSocketName],
+		config.Server.WSUnixSocket,
+		socketMode,
+		config.Server.WSHost,
+		config.Server.WSPort,
+	)
+	if err != nil {
+		if rpcListener != nil {
+			rpcListener.Close()
+		}
+		return nil, nil, wrapErr(err, "error creating WS listener")
+	}
+	if wsListener != nil && wsBackendGroup == nil {
+		wsListener.Close()
+		if rpcListener != nil {
+			rpcListener.Close()
+		}
+		return nil, nil, fmt.Errorf("a ws socket was provided, but no ws group was defined")
+	}
+	// connections over the cap are closed before the TLS handshake
+	rpcListener = srv.frontendLimits.Listener(rpcListener)
+	wsListener = srv.frontendLimits.Listener(wsListener)
+	if tlsConfig != nil {
+		if rpcListener != nil {
+			rpcListener = tls.NewListener(rpcListener, tlsConfig)
+		}
+		if wsListener != nil {
+			wsListener = tls.NewListener(wsListener, tlsConfig)
+		}
+	}
+
+	if rpcListener != nil {
 		go func() {
-			if err := srv.RPCListenAndServe(config.Server.RPCHost, config.Server.RPCPort); err != nil {
+			if err := srv.RPCServe(rpcListener); err != nil {
 				if errors.Is(err, http.ErrServerClosed) {
 					log.Info("RPC server shut down")
 					return
@@ -493,9 +942,9 @@ func Start(config *Config) (*Server, func(), error) {
 		}()
 	}
 
-	if config.Server.WSPort != 0 {
+	if wsListener != nil {
 		go func() {
-			if err := srv.WSListenAndServe(config.Server.WSHost, config.Server.WSPort); err != nil {
+			if err := srv.WSServe(wsListener); err != nil {
 				if errors.Is(err, http.ErrServerClosed) {
 					log.Info("WS server shut down")
 					return
@@ -521,6 +970,10 @@ func Start(config *Config) (*Server, func(), error) {
 
 			copts := make([]ConsensusOpt, 0)
 
+			if webhooks != nil {
+				copts = append(copts, WithWebhookNotifier(webhooks))
+			}
+
 			if bgcfg.ConsensusAsyncHandler == "noop" {
 				copts = append(copts, WithAsyncHandler(NewNoopAsyncHandler()))
 			}
@@ -536,6 +989,9 @@ func Start(config *Config) (*Server, func(), error) {
 			if bgcfg.ConsensusMinPeerCount > 0 {
 				copts = append(copts, WithMinPeerCount(uint64(bgcfg.ConsensusMinPeerCount)))
 			}
+			if bgcfg.ConsensusQuorum > 0 {
+				copts = append(copts, WithQuorum(bgcfg.ConsensusQuorum))
+			}
 			if bgcfg.ConsensusMaxBlockRange > 0 {
 				copts = append(copts, WithMaxBlockRange(bgcfg.ConsensusMaxBlockRange))
 			}
@@ -564,7 +1020,7 @@ func Start(config *Config) (*Server, func(), error) {
 				if bgcfg.ConsensusHAHeartbeatInterval > 0 {
 					topts = append(topts, WithHeartbeatInterval(time.Duration(bgcfg.ConsensusHAHeartbeatInterval)))
 				}
-				consensusHARedisClient, err := NewRedisClient(bgcfg.ConsensusHARedis.URL, bgcfg.ConsensusHARedis.RedisCluster)
+				consensusHARedisClient, err := NewRedisClient(bgcfg.ConsensusHARedis.URL, bgcfg.ConsensusHARedis)
 				if err != nil {
 					return nil, nil, err
 				}
@@ -585,18 +1041,174 @@ func Start(config *Config) (*Server, func(), error) {
 		}
 	}
 
+	secrets.Start()
+	featureFlags.Start()
+	interopAdmin.Start()
+	gasOracle.Start()
+	filters.Start()
+	scorer.Start()
+	prober.Start()
+	leaders.Start()
+	for _, bg := range backendGroups {
+		bg.heads.Start()
+	}
+	derivation.Start()
+	gossip.Start()
+	srv.cacheWarmer.Start()
+	srv.usageExporter.Start()
+	redisHealth.Start()
+	loadShedder.Start()
+	ipFilter.Start()
+	for _, discovery := range discoveries {
+		discovery.Start()
+	}
+
+	var remoteConfig *RemoteConfigWatcher
+	if config.RemoteConfig.URL != "" {
+		remoteConfig, err = NewRemoteConfigWatcher(config, srv, backendsByName)
+		if err != nil {
+			return nil, nil, err
+		}
+		remoteConfig.Start()
+	}
+
 	<-errTimer.C
 	log.Info("started proxyd")
 
 	shutdownFunc := func() {
 		log.Info("shutting down proxyd")
+		if remoteConfig != nil {
+			remoteConfig.Stop()
+		}
+		for _, discovery := range discoveries {
+			discovery.Stop()
+		}
 		srv.Shutdown()
+		interopAdmin.Stop()
+		gasOracle.Stop()
+		filters.Stop()
+		scorer.Stop()
+		prober.Stop()
+		leaders.Stop()
+		derivation.Stop()
+		gossip.Stop()
+		srv.cacheWarmer.Stop()
+		srv.usageExporter.Stop()
+		redisHealth.Stop()
+		loadShedder.Stop()
+		ipFilter.Stop()
+		featureFlags.Stop()
+		secrets.Stop()
 		log.Info("goodbye")
 	}
 
 	return srv, shutdownFunc, nil
 }
 
+// setInteropStrategyDefaults fills in the unset parameters of the configured
+// interop validation strategy.
+func setInteropStrategyDefaults(cfg *InteropValidationConfig) {
+	if cfg.LoadBalancingUnhealthinessTimeout == 0 && cfg.Strategy == HealthAwareLoadBalancingStrategy {
+		log.Warn("no interop validation load balancing unhealthiness timeout provided for health aware strategy, using default timeout", "timeout", defaultInteropLoadBalancingUnhealthinessTimeout)
+		cfg.LoadBalancingUnhealthinessTimeout = defaultInteropLoadBalancingUnhealthinessTimeout
+	}
+
+	if cfg.HedgeDelay == 0 && cfg.Strategy == HedgedStrategy {
+		log.Warn("no interop validation hedge delay provided for hedged strategy, using default delay", "delay", defaultInteropHedgeDelay)
+		cfg.HedgeDelay = defaultInteropHedgeDelay
+	}
+
+	if cfg.Strategy == CircuitBreakerStrategy {
+		if cfg.CircuitBreakerThreshold == 0 {
+			log.Warn("no interop validation circuit breaker threshold provided, using default threshold", "threshold", defaultInteropCircuitBreakerThreshold)
+			cfg.CircuitBreakerThreshold = defaultInteropCircuitBreakerThreshold
+		}
+		if cfg.CircuitBreakerCooldown == 0 {
+			log.Warn("no interop validation circuit breaker cooldown provided, using default cooldown", "cooldown", defaultInteropCircuitBreakerCooldown)
+			cfg.CircuitBreakerCooldown = defaultInteropCircuitBreakerCooldown
+		}
+	}
+
+	if cfg.Strategy == FailoverStrategy {
+		if cfg.BanPeriod == 0 {
+			log.Warn("no interop validation ban period provided for failover strategy, using default period", "period", defaultInteropBanPeriod)
+			cfg.BanPeriod = defaultInteropBanPeriod
+		}
+		if cfg.HealthCheckInterval == 0 {
+			log.Warn("no interop validation health check interval provided for failover strategy, using default interval", "interval", defaultInteropHealthCheckInterval)
+			cfg.HealthCheckInterval = defaultInteropHealthCheckInterval
+		}
+	}
+}
+
+// newInteropStrategy builds the interop validation strategy of cfg. The
+// failover strategy is also returned on its own, as it must be started.
+func newInteropStrategy(cfg InteropValidationConfig, blockNumber func() (uint64, bool)) (InteropStrategy, *failoverStrategyImpl, error) {
+	var interopStrategy InteropStrategy
+	var failover *failoverStrategyImpl
+
+	opts := CommonStrategyOpts(
+		WithReqSizeLimit(cfg.ReqSizeLimit),
+		WithAccessListSizeLimit(cfg.AccessListSizeLimit),
+	)
+
+	switch cfg.Strategy {
+	case FirstSupervisorStrategy, EmptyStrategy:
+		interopStrategy = NewFirstSupervisorStrategy(
+			cfg.Urls,
+			opts...,
+		)
+	case MulticallStrategy:
+		interopStrategy = NewMulticallStrategy(
+			cfg.Urls,
+			opts...,
+		)
+	case HealthAwareLoadBalancingStrategy:
+		interopStrategy = NewHealthAwareLoadBalancingStrategy(
+			cfg.Urls,
+			cfg.LoadBalancingUnhealthinessTimeout,
+			opts...,
+		)
+	case QuorumStrategy:
+		if cfg.Quorum > len(cfg.Urls) {
+			return nil, nil, fmt.Errorf("interop validation quorum %d exceeds the number of urls", cfg.Quorum)
+		}
+		interopStrategy = NewQuorumStrategy(
+			cfg.Urls,
+			cfg.Quorum,
+			opts...,
+		)
+	case HedgedStrategy:
+		interopStrategy = NewHedgedStrategy(
+			cfg.Urls,
+			cfg.HedgeDelay,
+			opts...,
+		)
+	case FailoverStrategy:
+		failover = NewFailoverStrategy(
+			cfg.Urls,
+			cfg.BanPeriod,
+			cfg.HealthCheckInterval,
+			opts...,
+		)
+		interopStrategy = failover
+	case CircuitBreakerStrategy:
+		interopStrategy = NewCircuitBreakerStrategy(
+			cfg.Urls,
+			cfg.CircuitBreakerThreshold,
+			cfg.CircuitBreakerCooldown,
+			opts...,
+		)
+	default:
+		return nil, nil, fmt.Errorf("invalid interop validating strategy: %s", cfg.Strategy)
+	}
+
+	if cfg.Cache.Enabled {
+		interopStrategy = NewCachingInteropStrategy(interopStrategy, cfg.Cache, blockNumber)
+	}
+	return interopStrategy, failover, nil
+}
+
 func validateReceiptsTarget(val string) (string, error) {
 	if val == "" {
 		val = ReceiptsTargetDebugGetRawReceipts
@@ -0,0 +1,130 @@
+package proxyd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// RateLimitExemptions lets health checkers, internal jobs and partner
+// integrations bypass the frontend and sender rate limits, by client IP,
+// auth key or verified signer. Globally limited methods stay limited, as for
+// exempt_origins. The list can be replaced at runtime through the admin
+// endpoint.
+type RateLimitExemptions struct {
+	adminToken secretValue
+
+	mu      sync.RWMutex
+	list    RateLimitExemptionList
+	nets    []*net.IPNet
+	keys    map[string]bool
+	signers map[common.Address]bool
+}
+
+// NewRateLimitExemptions returns nil if nothing is exempt and the list can't
+// be managed at runtime.
+func NewRateLimitExemptions(cfg RateLimitExemptionsConfig) (*RateLimitExemptions, error) {
+	list := cfg.RateLimitExemptionList
+	if len(list.CIDRs) == 0 && len(list.Keys) == 0 && len(list.Signers) == 0 && cfg.AdminToken == "" {
+		return nil, nil
+	}
+	e := &RateLimitExemptions{}
+	e.adminToken.Store(cfg.AdminToken)
+	if err := e.Set(list); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Set replaces the list.
+func (e *RateLimitExemptions) Set(list RateLimitExemptionList) error {
+	nets := make([]*net.IPNet, 0, len(list.CIDRs))
+	for _, cidr := range list.CIDRs {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return fmt.Errorf("invalid exempt cidr %s: %w", cidr, err)
+		}
+		nets = append(nets, ipNet)
+	}
+	keys := make(map[string]bool, len(list.Keys))
+	for _, key := range list.Keys {
+		keys[key] = true
+	}
+	signers := make(map[common.Address]bool, len(list.Signers))
+	for _, signer := range list.Signers {
+		if !common.IsHexAddress(signer) {
+			return fmt.Errorf("invalid exempt signer %s", signer)
+		}
+		signers[common.HexToAddress(signer)] = true
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.list = list
+	e.nets = nets
+	e.keys = keys
+	e.signers = signers
+	return nil
+}
+
+// Exempt returns whether the request of ctx bypasses the rate limits, and
+// why: "cidr", "key" or "signer".
+func (e *RateLimitExemptions) Exempt(ctx context.Context) (string, bool) {
+	if e == nil {
+		return "", false
+	}
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if signer, ok := GetSigner(ctx); ok && e.signers[signer] {
+		return "signer", true
+	}
+	if auth := GetAuthCtx(ctx); auth != "none" && e.keys[auth] {
+		return "key", true
+	}
+	if ip := net.ParseIP(stripXFF(GetXForwardedFor(ctx))); ip != nil {
+		for _, ipNet := range e.nets {
+			if ipNet.Contains(ip) {
+				return "cidr", true
+			}
+		}
+	}
+	return "", false
+}
+
+// ServeHTTP serves the exemption endpoints:
+//
+//	GET /admin/exemptions   lists the exemptions
+//	PUT /admin/exemptions   replaces them with {"cidrs", "keys", "signers"}
+func (e *RateLimitExemptions) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !checkAdminToken(w, r, e.adminToken.Load()) {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		e.mu.RLock()
+		list := e.list
+		e.mu.RUnlock()
+		writeAdminJSON(w, http.StatusOK, list)
+	case http.MethodPut:
+		var list RateLimitExemptionList
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&list); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := e.Set(list); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Info("set rate limit exemptions", "cidrs", len(list.CIDRs), "keys", len(list.Keys), "signers", len(list.Signers))
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
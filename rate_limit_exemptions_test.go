@@ -0,0 +1,62 @@
+package proxyd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimitExemptions(t *testing.T) {
+	signer := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	e, err := NewRateLimitExemptions(RateLimitExemptionsConfig{
+		RateLimitExemptionList: RateLimitExemptionList{
+			CIDRs:   []string{"10.0.0.0/8"},
+			Keys:    []string{"healthcheck"},
+			Signers: []string{signer.Hex()},
+		},
+	})
+	require.NoError(t, err)
+
+	ctx := func(xff, auth string, signer *common.Address) context.Context {
+		ctx := context.WithValue(context.Background(), ContextKeyXForwardedFor, xff) // nolint:staticcheck
+		ctx = context.WithValue(ctx, ContextKeyAuth, auth)                           // nolint:staticcheck
+		if signer != nil {
+			ctx = context.WithValue(ctx, ContextKeySigner, *signer) // nolint:staticcheck
+		}
+		return ctx
+	}
+	other := common.HexToAddress("0x2222222222222222222222222222222222222222")
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		reason string
+	}{
+		{"cidr", ctx("10.1.2.3, 192.0.2.1", "none", nil), "cidr"},
+		{"proxy ip isn't the client", ctx("192.0.2.1, 10.1.2.3", "none", nil), ""},
+		{"key", ctx("192.0.2.1", "healthcheck", nil), "key"},
+		{"signer", ctx("192.0.2.1", "none", &signer), "signer"},
+		{"other signer", ctx("192.0.2.1", "none", &other), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason, exempt := e.Exempt(tt.ctx)
+			require.Equal(t, tt.reason != "", exempt)
+			require.Equal(t, tt.reason, reason)
+		})
+	}
+
+	require.NoError(t, e.Set(RateLimitExemptionList{Keys: []string{"batch"}}))
+	_, exempt := e.Exempt(ctx("10.1.2.3", "healthcheck", nil))
+	require.False(t, exempt)
+
+	require.Error(t, e.Set(RateLimitExemptionList{CIDRs: []string{"10.0.0.0"}}))
+
+	disabled, err := NewRateLimitExemptions(RateLimitExemptionsConfig{})
+	require.NoError(t, err)
+	require.Nil(t, disabled)
+	_, exempt = disabled.Exempt(ctx("10.1.2.3", "none", nil))
+	require.False(t, exempt)
+}
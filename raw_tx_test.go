@@ -0,0 +1,69 @@
+package proxyd
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertSendReqToSendTxTypes(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	to := common.Address{0x01}
+	chainID := big.NewInt(10)
+
+	txs := map[string]types.TxData{
+		"legacy": &types.LegacyTx{Nonce: 1, GasPrice: big.NewInt(1), Gas: 21000, To: &to},
+		"access_list": &types.AccessListTx{
+			ChainID: chainID, Nonce: 1, GasPrice: big.NewInt(1), Gas: 21000, To: &to,
+			AccessList: types.AccessList{{Address: to, StorageKeys: []common.Hash{{0x02}}}},
+		},
+		"dynamic_fee": &types.DynamicFeeTx{ChainID: chainID, Nonce: 1, GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(1), Gas: 21000, To: &to},
+		"blob": &types.BlobTx{
+			ChainID: uint256.MustFromBig(chainID), Nonce: 1, GasTipCap: uint256.NewInt(1), GasFeeCap: uint256.NewInt(1),
+			Gas: 21000, To: to, BlobFeeCap: uint256.NewInt(1), BlobHashes: []common.Hash{{0x01}},
+		},
+		"set_code": &types.SetCodeTx{
+			ChainID: uint256.MustFromBig(chainID), Nonce: 1, GasTipCap: uint256.NewInt(1), GasFeeCap: uint256.NewInt(1),
+			Gas: 100000, To: to, AuthList: []types.SetCodeAuthorization{{ChainID: *uint256.MustFromBig(chainID), Address: to, Nonce: 2}},
+		},
+	}
+
+	for name, data := range txs {
+		t.Run(name, func(t *testing.T) {
+			tx, err := types.SignNewTx(key, types.LatestSignerForChainID(chainID), data)
+			require.NoError(t, err)
+			raw, err := tx.MarshalBinary()
+			require.NoError(t, err)
+
+			decoded, err := convertSendReqToSendTx(context.Background(), makeSendRawTxReq(raw))
+			require.NoError(t, err)
+			require.Equal(t, tx.Hash(), decoded.Hash())
+			from, err := txSender(decoded)
+			require.NoError(t, err)
+			require.Equal(t, sender, from)
+		})
+	}
+
+	_, err = convertSendReqToSendTx(context.Background(), makeSendRawTxReq([]byte{0x05, 0xc0}))
+	require.Equal(t, "transaction type 0x05 not supported", err.Error())
+
+	deposit, err := types.NewTx(&types.DepositTx{From: sender, To: &to, Gas: 21000}).MarshalBinary()
+	require.NoError(t, err)
+	_, err = convertSendReqToSendTx(context.Background(), makeSendRawTxReq(deposit))
+	require.Equal(t, "deposit transactions can't be submitted", err.Error())
+}
+
+func makeSendRawTxReq(raw []byte) *RPCReq {
+	params, _ := json.Marshal([]string{hexutil.Encode(raw)})
+	return &RPCReq{Method: "eth_sendRawTransaction", Params: params}
+}
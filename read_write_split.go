@@ -0,0 +1,86 @@
+package proxyd
+
+import "slices"
+
+// writeMethods change state or keep it on the node that served them, so
+// they go to writes_group, e.g. the sequencer of a rollup.
+var writeMethods = []string{
+	"eth_sendRawTransaction",
+	"eth_sendRawTransactionConditional",
+	"eth_sendTransaction",
+	"eth_sendBundle",
+	"eth_sendPrivateTransaction",
+	"eth_cancelPrivateTransaction",
+	"eth_newFilter",
+	"eth_newBlockFilter",
+	"eth_newPendingTransactionFilter",
+	"eth_getFilterChanges",
+	"eth_getFilterLogs",
+	"eth_uninstallFilter",
+}
+
+// readMethods only read chain state, so any node can serve them and they go
+// to reads_group.
+var readMethods = []string{
+	"web3_clientVersion",
+	"net_version",
+	"eth_chainId",
+	"eth_syncing",
+	"eth_blockNumber",
+	"eth_gasPrice",
+	"eth_maxPriorityFeePerGas",
+	"eth_feeHistory",
+	"eth_blobBaseFee",
+	"eth_call",
+	"eth_estimateGas",
+	"eth_createAccessList",
+	"eth_getBalance",
+	"eth_getCode",
+	"eth_getStorageAt",
+	"eth_getTransactionCount",
+	"eth_getProof",
+	"eth_getBlockByNumber",
+	"eth_getBlockByHash",
+	"eth_getBlockReceipts",
+	"eth_getBlockTransactionCountByNumber",
+	"eth_getBlockTransactionCountByHash",
+	"eth_getUncleCountByBlockNumber",
+	"eth_getUncleCountByBlockHash",
+	"eth_getUncleByBlockNumberAndIndex",
+	"eth_getUncleByBlockHashAndIndex",
+	"eth_getTransactionByHash",
+	"eth_getTransactionByBlockNumberAndIndex",
+	"eth_getTransactionByBlockHashAndIndex",
+	"eth_getTransactionReceipt",
+	"eth_getLogs",
+}
+
+// SplitReadsWrites returns the method mappings with the built-in write
+// methods mapped to writesGroup and the read methods to readsGroup, unless
+// they're mapped already or the group is empty.
+func SplitReadsWrites(mappings map[string]string, writesGroup, readsGroup string) map[string]string {
+	if writesGroup == "" && readsGroup == "" {
+		return mappings
+	}
+	split := make(map[string]string, len(mappings)+len(writeMethods)+len(readMethods))
+	for method, group := range mappings {
+		split[method] = group
+	}
+	mapDefaults := func(methods []string, group string) {
+		if group == "" {
+			return
+		}
+		for _, method := range methods {
+			if _, ok := split[method]; !ok {
+				split[method] = group
+			}
+		}
+	}
+	mapDefaults(writeMethods, writesGroup)
+	mapDefaults(readMethods, readsGroup)
+	return split
+}
+
+func isWriteMethod(method string) bool {
+	return slices.Contains(writeMethods, method)
+}
@@ -0,0 +1,31 @@
+package proxyd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitReadsWrites(t *testing.T) {
+	mappings := map[string]string{
+		"eth_call":        "archive",
+		"debug_traceCall": "archive",
+	}
+	split := SplitReadsWrites(mappings, "sequencer", "replicas")
+
+	// explicit mappings win
+	require.Equal(t, "archive", split["eth_call"])
+	require.Equal(t, "archive", split["debug_traceCall"])
+	require.Equal(t, "sequencer", split["eth_sendRawTransaction"])
+	require.Equal(t, "sequencer", split["eth_newFilter"])
+	require.Equal(t, "replicas", split["eth_getBalance"])
+	require.Equal(t, "replicas", split["eth_blockNumber"])
+	require.NotContains(t, split, "debug_traceTransaction")
+	require.Len(t, mappings, 2)
+
+	// either group can be left out
+	split = SplitReadsWrites(nil, "sequencer", "")
+	require.Equal(t, "sequencer", split["eth_sendRawTransaction"])
+	require.NotContains(t, split, "eth_getBalance")
+	require.Equal(t, mappings, SplitReadsWrites(mappings, "", ""))
+}
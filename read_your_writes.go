@@ -0,0 +1,118 @@
+package proxyd
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+const (
+	defaultReadYourWritesWindow = 10 * time.Second
+	readYourWritesMemoryLimit   = 100000
+)
+
+// readYourWritesMethods are the reads routed after a write, and whether
+// their first param is a transaction hash rather than an address.
+var readYourWritesMethods = map[string]bool{
+	"eth_getTransactionCount":   false,
+	"eth_getBalance":            false,
+	"eth_getTransactionReceipt": true,
+	"eth_getTransactionByHash":  true,
+}
+
+// ReadYourWrites routes the reads of a sender to the backend that accepted
+// its last transaction for a while, so that the sender sees the transaction
+// even if other backends haven't received it yet.
+type ReadYourWrites struct {
+	window time.Duration
+
+	mu     sync.Mutex
+	recent *lru.Cache
+}
+
+type recentWrite struct {
+	backend string
+	expires time.Time
+}
+
+func NewReadYourWrites(cfg ReadYourWritesConfig) *ReadYourWrites {
+	r := &ReadYourWrites{
+		window: time.Duration(cfg.Window),
+	}
+	if r.window == 0 {
+		r.window = defaultReadYourWritesWindow
+	}
+	r.recent, _ = lru.New(readYourWritesMemoryLimit)
+	return r
+}
+
+// Observe records that the backend, given as group/backend, accepted the
+// transaction.
+func (r *ReadYourWrites) Observe(tx *types.Transaction, servedBy string) {
+	if r == nil {
+		return
+	}
+	_, backend, ok := strings.Cut(servedBy, "/")
+	if !ok {
+		return
+	}
+	from, err := txSender(tx)
+	if err != nil {
+		return
+	}
+	write := recentWrite{backend: backend, expires: time.Now().Add(r.window)}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.recent.Add(strings.ToLower(from.Hex()), write)
+	r.recent.Add(strings.ToLower(tx.Hash().Hex()), write)
+}
+
+// Backend returns the backend that accepted a recent write the requests
+// read, if any.
+func (r *ReadYourWrites) Backend(elems []batchElem) string {
+	if r == nil {
+		return ""
+	}
+	for _, elem := range elems {
+		byHash, ok := readYourWritesMethods[elem.Req.Method]
+		if !ok {
+			continue
+		}
+		var params []interface{}
+		if err := json.Unmarshal(elem.Req.Params, &params); err != nil || len(params) == 0 {
+			continue
+		}
+		key, ok := params[0].(string)
+		if !ok {
+			continue
+		}
+		if byHash && len(key) != 2+2*common.HashLength || !byHash && !common.IsHexAddress(key) {
+			continue
+		}
+		if backend := r.lookup(strings.ToLower(key)); backend != "" {
+			RecordReadYourWritesRoute(elem.Req.Method)
+			return backend
+		}
+	}
+	return ""
+}
+
+func (r *ReadYourWrites) lookup(key string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	val, ok := r.recent.Get(key)
+	if !ok {
+		return ""
+	}
+	write := val.(recentWrite)
+	if time.Now().After(write.expires) {
+		r.recent.Remove(key)
+		return ""
+	}
+	return write.backend
+}
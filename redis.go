@@ -2,27 +2,188 @@ package proxyd
 
 import (
 	"context"
+	"crypto/tls"
+	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/redis/go-redis/v9"
 )
 
-func NewRedisClient(url string, enable_cluster bool) (redis.UniversalClient, error) {
-	if enable_cluster {
+const (
+	RedisFailOpen   = "fail_open"
+	RedisFailClosed = "fail_closed"
+
+	defaultRedisHealthCheckInterval = 5 * time.Second
+)
+
+// NewRedisClient connects to the Redis at url, or to the cluster or
+// sentinel-managed master configured in cfg.
+func NewRedisClient(url string, cfg RedisConfig) (redis.UniversalClient, error) {
+	tlsConfig, err := redisTLSConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var client redis.UniversalClient
+	switch {
+	case cfg.SentinelMasterName != "":
+		log.Info("Using sentinel redis client.", "master", cfg.SentinelMasterName)
+		opts, err := redis.ParseURL(url)
+		if err != nil {
+			return nil, err
+		}
+		tuneRedisOptions(opts, cfg, tlsConfig)
+		client = redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:            cfg.SentinelMasterName,
+			SentinelAddrs:         cfg.SentinelAddrs,
+			SentinelPassword:      cfg.SentinelPassword,
+			Username:              opts.Username,
+			Password:              opts.Password,
+			DB:                    opts.DB,
+			TLSConfig:             opts.TLSConfig,
+			PoolSize:              opts.PoolSize,
+			MinIdleConns:          opts.MinIdleConns,
+			PoolTimeout:           opts.PoolTimeout,
+			DialTimeout:           opts.DialTimeout,
+			ReadTimeout:           opts.ReadTimeout,
+			WriteTimeout:          opts.WriteTimeout,
+			ContextTimeoutEnabled: opts.ContextTimeoutEnabled,
+		})
+	case cfg.RedisCluster:
 		log.Info("Using cluster redis client.")
 		opts, err := redis.ParseClusterURL(url)
 		if err != nil {
 			return nil, err
 		}
-		return redis.NewClusterClient(opts), nil
-	} else {
+		tuneRedisClusterOptions(opts, cfg, tlsConfig)
+		client = redis.NewClusterClient(opts)
+	default:
 		log.Info("Using default redis client.")
 		opts, err := redis.ParseURL(url)
 		if err != nil {
 			return nil, err
 		}
-		return redis.NewClient(opts), nil
+		tuneRedisOptions(opts, cfg, tlsConfig)
+		client = redis.NewClient(opts)
+	}
+
+	if cfg.OperationTimeout > 0 {
+		client.AddHook(redisTimeoutHook{timeout: time.Duration(cfg.OperationTimeout)})
+	}
+	return client, nil
+}
+
+func redisTLSConfig(cfg RedisConfig) (*tls.Config, error) {
+	if cfg.TLSCAFile == "" && cfg.TLSCertFile == "" && !cfg.TLSInsecureSkipVerify {
+		return nil, nil
+	}
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.TLSCAFile != "" {
+		var err error
+		if tlsConfig, err = CreateTLSClient(cfg.TLSCAFile); err != nil {
+			return nil, err
+		}
+		tlsConfig.MinVersion = tls.VersionTLS12
+	}
+	if cfg.TLSCertFile != "" {
+		cert, err := ParseKeyPair(cfg.TLSCertFile, cfg.TLSKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	tlsConfig.InsecureSkipVerify = cfg.TLSInsecureSkipVerify
+	return tlsConfig, nil
+}
+
+// mergeRedisTLS keeps the server name a rediss:// url sets.
+func mergeRedisTLS(parsed *tls.Config, tlsConfig *tls.Config) *tls.Config {
+	if tlsConfig == nil {
+		return parsed
+	}
+	merged := tlsConfig.Clone()
+	if parsed != nil {
+		merged.ServerName = parsed.ServerName
+	}
+	return merged
+}
+
+func tuneRedisOptions(opts *redis.Options, cfg RedisConfig, tlsConfig *tls.Config) {
+	opts.TLSConfig = mergeRedisTLS(opts.TLSConfig, tlsConfig)
+	if cfg.PoolSize > 0 {
+		opts.PoolSize = cfg.PoolSize
+	}
+	if cfg.MinIdleConns > 0 {
+		opts.MinIdleConns = cfg.MinIdleConns
+	}
+	if cfg.PoolTimeout > 0 {
+		opts.PoolTimeout = time.Duration(cfg.PoolTimeout)
+	}
+	if cfg.DialTimeout > 0 {
+		opts.DialTimeout = time.Duration(cfg.DialTimeout)
+	}
+	if cfg.ReadTimeout > 0 {
+		opts.ReadTimeout = time.Duration(cfg.ReadTimeout)
+	}
+	if cfg.WriteTimeout > 0 {
+		opts.WriteTimeout = time.Duration(cfg.WriteTimeout)
+	}
+	if cfg.OperationTimeout > 0 {
+		opts.ContextTimeoutEnabled = true
+	}
+}
+
+func tuneRedisClusterOptions(opts *redis.ClusterOptions, cfg RedisConfig, tlsConfig *tls.Config) {
+	opts.TLSConfig = mergeRedisTLS(opts.TLSConfig, tlsConfig)
+	if cfg.PoolSize > 0 {
+		opts.PoolSize = cfg.PoolSize
+	}
+	if cfg.MinIdleConns > 0 {
+		opts.MinIdleConns = cfg.MinIdleConns
+	}
+	if cfg.PoolTimeout > 0 {
+		opts.PoolTimeout = time.Duration(cfg.PoolTimeout)
+	}
+	if cfg.DialTimeout > 0 {
+		opts.DialTimeout = time.Duration(cfg.DialTimeout)
+	}
+	if cfg.ReadTimeout > 0 {
+		opts.ReadTimeout = time.Duration(cfg.ReadTimeout)
+	}
+	if cfg.WriteTimeout > 0 {
+		opts.WriteTimeout = time.Duration(cfg.WriteTimeout)
+	}
+	if cfg.OperationTimeout > 0 {
+		opts.ContextTimeoutEnabled = true
+	}
+}
+
+// redisTimeoutHook bounds each command, or pipeline, including waiting for
+// a connection and retries.
+type redisTimeoutHook struct {
+	timeout time.Duration
+}
+
+func (h redisTimeoutHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h redisTimeoutHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		ctx, cancel := context.WithTimeout(ctx, h.timeout)
+		defer cancel()
+		return next(ctx, cmd)
+	}
+}
+
+func (h redisTimeoutHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		ctx, cancel := context.WithTimeout(ctx, h.timeout)
+		defer cancel()
+		return next(ctx, cmds)
 	}
 }
 
@@ -35,3 +196,141 @@ func CheckRedisConnection(client redis.UniversalClient) error {
 
 	return nil
 }
+
+// RedisHealth pings Redis periodically. While Redis is unavailable, the
+// cache and rate limiters using it fail with ErrRedisUnavailable right away
+// instead of waiting on Redis, and the degraded mode decides what happens
+// to the requests.
+type RedisHealth struct {
+	client   redis.UniversalClient
+	interval time.Duration
+	degraded atomic.Bool
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewRedisHealth returns nil without a client.
+func NewRedisHealth(client redis.UniversalClient, cfg RedisDegradedModeConfig) *RedisHealth {
+	if client == nil {
+		return nil
+	}
+	h := &RedisHealth{
+		client:   client,
+		interval: time.Duration(cfg.HealthCheckInterval),
+		stop:     make(chan struct{}),
+	}
+	if h.interval == 0 {
+		h.interval = defaultRedisHealthCheckInterval
+	}
+	return h
+}
+
+// Degraded returns whether the last health check failed.
+func (h *RedisHealth) Degraded() bool {
+	return h != nil && h.degraded.Load()
+}
+
+// Start checks Redis until Stop is called.
+func (h *RedisHealth) Start() {
+	if h == nil {
+		return
+	}
+	h.update()
+	h.wg.Add(1)
+	go func() {
+		defer h.wg.Done()
+		ticker := time.NewTicker(h.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-h.stop:
+				return
+			case <-ticker.C:
+				h.update()
+			}
+		}
+	}()
+}
+
+func (h *RedisHealth) Stop() {
+	if h == nil {
+		return
+	}
+	close(h.stop)
+	h.wg.Wait()
+}
+
+func (h *RedisHealth) update() {
+	ctx, cancel := context.WithTimeout(context.Background(), h.interval)
+	defer cancel()
+	err := h.client.Ping(ctx).Err()
+	degraded := err != nil
+	if h.degraded.Swap(degraded) != degraded {
+		if degraded {
+			log.Warn("redis is unavailable, entering degraded mode", "err", err)
+		} else {
+			log.Info("redis is available again, leaving degraded mode")
+		}
+	}
+	RecordRedisDegraded(degraded)
+}
+
+// degradedCache fails with ErrRedisUnavailable while Redis is unavailable,
+// and wraps the errors of the Redis cache in it.
+type degradedCache struct {
+	cache  Cache
+	health *RedisHealth
+}
+
+func newDegradedCache(cache Cache, health *RedisHealth) Cache {
+	if health == nil {
+		return cache
+	}
+	return &degradedCache{cache: cache, health: health}
+}
+
+func (c *degradedCache) Get(ctx context.Context, key string) (string, error) {
+	if c.health.Degraded() {
+		return "", ErrRedisUnavailable
+	}
+	val, err := c.cache.Get(ctx, key)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrRedisUnavailable, err)
+	}
+	return val, nil
+}
+
+func (c *degradedCache) Put(ctx context.Context, key string, value string) error {
+	if c.health.Degraded() {
+		return ErrRedisUnavailable
+	}
+	if err := c.cache.Put(ctx, key, value); err != nil {
+		return fmt.Errorf("%w: %w", ErrRedisUnavailable, err)
+	}
+	return nil
+}
+
+// degradedRateLimiter is degradedCache for rate limiters.
+type degradedRateLimiter struct {
+	limiter FrontendRateLimiter
+	health  *RedisHealth
+}
+
+func newDegradedRateLimiter(limiter FrontendRateLimiter, health *RedisHealth) FrontendRateLimiter {
+	if health == nil {
+		return limiter
+	}
+	return &degradedRateLimiter{limiter: limiter, health: health}
+}
+
+func (l *degradedRateLimiter) Take(ctx context.Context, key string) (bool, error) {
+	if l.health.Degraded() {
+		return false, ErrRedisUnavailable
+	}
+	ok, err := l.limiter.Take(ctx, key)
+	if err != nil {
+		return false, fmt.Errorf("%w: %w", ErrRedisUnavailable, err)
+	}
+	return ok, nil
+}
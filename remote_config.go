@@ -0,0 +1,431 @@
+package proxyd
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	defaultRemoteConfigPollInterval = 30 * time.Second
+	remoteConfigFetchTimeout        = 10 * time.Second
+	gcsEndpoint                     = "https://storage.googleapis.com"
+)
+
+// RemoteConfigSource fetches a TOML config document from a central store.
+type RemoteConfigSource interface {
+	Fetch(ctx context.Context) ([]byte, error)
+}
+
+// NewRemoteConfigSource returns the source for a consul://host:port/key,
+// etcd://host:port/key, s3://bucket/key or gs://bucket/key URL.
+func NewRemoteConfigSource(cfg RemoteConfigConfig) (RemoteConfigSource, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, wrapErr(err, "invalid remote_config.url")
+	}
+	key := strings.TrimPrefix(u.Path, "/")
+	if u.Host == "" || key == "" {
+		return nil, fmt.Errorf("remote_config.url must look like %s://host/key", u.Scheme)
+	}
+	token, err := ReadFromEnvOrConfig(cfg.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	scheme := "http"
+	if cfg.TLS {
+		scheme = "https"
+	}
+	client := &http.Client{Timeout: remoteConfigFetchTimeout}
+	switch u.Scheme {
+	case "consul":
+		return &consulConfigSource{
+			url:    fmt.Sprintf("%s://%s/v1/kv/%s?raw=true", scheme, u.Host, key),
+			token:  token,
+			client: client,
+		}, nil
+	case "etcd":
+		return &etcdConfigSource{
+			url:    fmt.Sprintf("%s://%s/v3/kv/range", scheme, u.Host),
+			key:    key,
+			client: client,
+		}, nil
+	case "s3", "gs":
+		return newObjectConfigSource(u.Scheme, u.Host, key, cfg.Region)
+	default:
+		return nil, fmt.Errorf("unsupported remote_config.url scheme %q, must be consul, etcd, s3 or gs", u.Scheme)
+	}
+}
+
+type consulConfigSource struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+func (s *consulConfigSource) Fetch(ctx context.Context) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if s.token != "" {
+		req.Header.Set("X-Consul-Token", s.token)
+	}
+	res, err := s.client.Do(req)
+	if err != nil {
+		return nil, wrapErr(err, "error fetching config from consul")
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("consul returned status %d", res.StatusCode)
+	}
+	return io.ReadAll(res.Body)
+}
+
+// etcdConfigSource reads a key through the etcd v3 JSON gateway.
+type etcdConfigSource struct {
+	url    string
+	key    string
+	client *http.Client
+}
+
+func (s *etcdConfigSource) Fetch(ctx context.Context) ([]byte, error) {
+	body, err := json.Marshal(map[string]string{
+		"key": base64.StdEncoding.EncodeToString([]byte(s.key)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := s.client.Do(req)
+	if err != nil {
+		return nil, wrapErr(err, "error fetching config from etcd")
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("etcd returned status %d", res.StatusCode)
+	}
+
+	var out struct {
+		Kvs []struct {
+			Value string `json:"value"`
+		} `json:"kvs"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&out); err != nil {
+		return nil, wrapErr(err, "error decoding etcd response")
+	}
+	if len(out.Kvs) == 0 {
+		return nil, fmt.Errorf("etcd key %s not found", s.key)
+	}
+	return base64.StdEncoding.DecodeString(out.Kvs[0].Value)
+}
+
+// objectConfigSource reads an S3 object, or a GCS object through the S3
+// compatible XML API using HMAC keys.
+type objectConfigSource struct {
+	client *s3.Client
+	bucket string
+	key    string
+}
+
+func newObjectConfigSource(scheme, bucket, key, region string) (*objectConfigSource, error) {
+	client, err := newObjectStoreClient(scheme, region)
+	if err != nil {
+		return nil, err
+	}
+	return &objectConfigSource{client: client, bucket: bucket, key: key}, nil
+}
+
+// newObjectStoreClient returns a client for S3, or for GCS through the S3
+// compatible XML API if scheme is gs.
+func newObjectStoreClient(scheme, region string) (*s3.Client, error) {
+	var opts []func(*awsconfig.LoadOptions) error
+	if region != "" {
+		opts = append(opts, awsconfig.WithRegion(region))
+	} else if scheme == "gs" {
+		opts = append(opts, awsconfig.WithRegion("auto"))
+	}
+	cfg, err := awsconfig.LoadDefaultConfig(context.Background(), opts...)
+	if err != nil {
+		return nil, wrapErr(err, "error loading aws config")
+	}
+	return s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if scheme == "gs" {
+			o.BaseEndpoint = aws.String(gcsEndpoint)
+		}
+	}), nil
+}
+
+func (s *objectConfigSource) Fetch(ctx context.Context) ([]byte, error) {
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.key),
+	})
+	if err != nil {
+		return nil, wrapErr(err, "error fetching config object")
+	}
+	defer out.Body.Close()
+	return io.ReadAll(out.Body)
+}
+
+func fetchRemoteConfig(cfg RemoteConfigConfig) ([]byte, error) {
+	source, err := NewRemoteConfigSource(cfg)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), remoteConfigFetchTimeout)
+	defer cancel()
+	return source.Fetch(ctx)
+}
+
+// RemoteConfigWatcher polls the remote config and applies the parts of it
+// that are safe to change while running: method mappings, frontend rate
+// limits and backend weights. The remote document is merged over the local
+// config files as at startup, and only the sections the remote document
+// defines, or defined before, are applied. Other changes are logged and take
+// effect on restart.
+type RemoteConfigWatcher struct {
+	source   RemoteConfigSource
+	interval time.Duration
+	srv      *Server
+	backends map[string]*Backend
+	// local are the local config tables the remote document is merged over
+	local map[string]interface{}
+
+	lastHash [sha256.Size]byte
+	last     map[string]interface{}
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewRemoteConfigWatcher watches config.RemoteConfig. The local files of
+// configs read by LoadConfig are merged under the remote document.
+func NewRemoteConfigWatcher(config *Config, srv *Server, backends map[string]*Backend) (*RemoteConfigWatcher, error) {
+	source, err := NewRemoteConfigSource(config.RemoteConfig)
+	if err != nil {
+		return nil, err
+	}
+	return newRemoteConfigWatcher(source, config, srv, backends), nil
+}
+
+func newRemoteConfigWatcher(source RemoteConfigSource, config *Config, srv *Server, backends map[string]*Backend) *RemoteConfigWatcher {
+	w := &RemoteConfigWatcher{
+		source:   source,
+		interval: defaultRemoteConfigPollInterval,
+		srv:      srv,
+		backends: backends,
+		local:    config.localTables,
+		stop:     make(chan struct{}),
+	}
+	if config.RemoteConfig.PollInterval > 0 {
+		w.interval = time.Duration(config.RemoteConfig.PollInterval)
+	}
+	return w
+}
+
+// Start polls the remote config in the background. The document at the time
+// of the first poll is assumed to be the one proxyd was started with.
+func (w *RemoteConfigWatcher) Start() {
+	if err := w.poll(false); err != nil {
+		log.Error("error fetching remote config", "err", err)
+	}
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-w.stop:
+				return
+			case <-ticker.C:
+				if err := w.poll(true); err != nil {
+					log.Error("error updating remote config", "err", err)
+					RecordRemoteConfigUpdate("error")
+				}
+			}
+		}
+	}()
+}
+
+func (w *RemoteConfigWatcher) Stop() {
+	close(w.stop)
+	w.wg.Wait()
+}
+
+func (w *RemoteConfigWatcher) poll(apply bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), remoteConfigFetchTimeout)
+	defer cancel()
+	data, err := w.source.Fetch(ctx)
+	if err != nil {
+		return err
+	}
+	hash := sha256.Sum256(data)
+	if hash == w.lastHash {
+		return nil
+	}
+
+	raw := make(map[string]interface{})
+	if _, err := toml.Decode(string(data), &raw); err != nil {
+		return wrapErr(err, "error parsing remote config")
+	}
+	if apply {
+		if err := w.apply(raw); err != nil {
+			return err
+		}
+		if keys := restartRequiredKeys(w.last, raw); len(keys) > 0 {
+			log.Warn("remote config changes require a restart", "keys", strings.Join(keys, ","))
+		}
+		log.Info("applied remote config")
+		RecordRemoteConfigUpdate("applied")
+	}
+	w.lastHash = hash
+	w.last = raw
+	return nil
+}
+
+// apply applies the remote document raw, merged over the local config like
+// LoadConfig does, so sections the document only partly sets keep their
+// local values.
+func (w *RemoteConfigWatcher) apply(raw map[string]interface{}) error {
+	merged := make(map[string]interface{})
+	mergeConfigTables(merged, w.local)
+	mergeConfigTables(merged, raw)
+	cfg, _, err := decodeConfigTables(merged)
+	if err != nil {
+		return wrapErr(err, "error parsing remote config")
+	}
+	// sections the remote document stopped setting go back to their local
+	// values
+	changed := func(keys ...string) bool {
+		return tableDefines(raw, keys...) || tableDefines(w.last, keys...)
+	}
+
+	weights := make(map[*Backend]int)
+	for name, bcfg := range cfg.Backends {
+		if !changed("backends", name, "weight") {
+			continue
+		}
+		b := w.backends[name]
+		if b == nil {
+			return fmt.Errorf("cannot set weight of undefined backend %s", name)
+		}
+		weights[b] = bcfg.Weight
+	}
+
+	if changed("rpc_method_mappings") {
+		if err := w.srv.SetRPCMethodMappings(cfg.RPCMethodMappings); err != nil {
+			return err
+		}
+	}
+	if changed("rate_limit") {
+		w.srv.SetRateLimits(cfg.RateLimit)
+	}
+	if changed("maintenance_mode") {
+		if err := w.srv.maintenance.Set(cfg.MaintenanceMode); err != nil {
+			return err
+		}
+	}
+	for b, weight := range weights {
+		b.SetWeight(weight)
+	}
+	return nil
+}
+
+// tableDefines reports whether the decoded TOML tables define the key at
+// path.
+func tableDefines(tables map[string]interface{}, path ...string) bool {
+	for i, key := range path {
+		v, ok := tables[key]
+		if !ok {
+			return false
+		}
+		if i == len(path)-1 {
+			return true
+		}
+		if tables, ok = v.(map[string]interface{}); !ok {
+			return false
+		}
+	}
+	return false
+}
+
+// restartRequiredKeys returns the top level sections, and backends, that
+// changed in ways that can't be applied while running.
+func restartRequiredKeys(prev, next map[string]interface{}) []string {
+	var keys []string
+	for _, k := range changedKeys(prev, next) {
+		switch k {
+		case "rpc_method_mappings", "rate_limit", "maintenance_mode", "remote_config":
+		case "backends":
+			prevBackends, _ := prev[k].(map[string]interface{})
+			nextBackends, _ := next[k].(map[string]interface{})
+			for _, name := range changedKeys(withoutWeights(prevBackends), withoutWeights(nextBackends)) {
+				keys = append(keys, "backends."+name)
+			}
+		default:
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// changedKeys returns the sorted keys whose values differ between a and b.
+func changedKeys(a, b map[string]interface{}) []string {
+	var keys []string
+	for k, v := range a {
+		if !reflect.DeepEqual(v, b[k]) {
+			keys = append(keys, k)
+		}
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func withoutWeights(backends map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(backends))
+	for name, v := range backends {
+		table, ok := v.(map[string]interface{})
+		if !ok {
+			out[name] = v
+			continue
+		}
+		copied := make(map[string]interface{}, len(table))
+		for k, v := range table {
+			if k != "weight" {
+				copied[k] = v
+			}
+		}
+		out[name] = copied
+	}
+	return out
+}
@@ -0,0 +1,185 @@
+package proxyd
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeConsul struct {
+	mu  sync.Mutex
+	doc string
+}
+
+func (c *fakeConsul) set(doc string) {
+	c.mu.Lock()
+	c.doc = doc
+	c.mu.Unlock()
+}
+
+func (c *fakeConsul) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/v1/kv/proxyd/config" || r.Header.Get("X-Consul-Token") != "token" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, _ = w.Write([]byte(c.doc))
+}
+
+func TestRemoteConfigWatcher(t *testing.T) {
+	consul := &fakeConsul{doc: `
+[server]
+rpc_port = 8545
+
+[backends.a]
+weight = 1
+`}
+	consulSrv := httptest.NewServer(consul)
+	defer consulSrv.Close()
+
+	path := filepath.Join(t.TempDir(), "proxyd.toml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+[rate_limit]
+base_rate = 1
+base_interval = "1m"
+
+[rpc_method_mappings]
+eth_chainId = "main"
+
+[remote_config]
+url = "consul://`+consulSrv.Listener.Addr().String()+`/proxyd/config"
+token = "token"
+`), 0o600))
+	config, _, err := LoadConfig(path)
+	require.NoError(t, err)
+
+	a := NewBackend("a", "http://localhost:1", "", nil, WithWeight(1))
+	b := NewBackend("b", "http://localhost:2", "", nil, WithWeight(1))
+	groups := map[string]*BackendGroup{
+		"main":    {Name: "main", Backends: []*Backend{a}},
+		"archive": {Name: "archive", Backends: []*Backend{b}},
+	}
+	srv, err := NewServer(
+		groups,
+		nil,
+		NewStringSetFromStrings(nil),
+		map[string]string{"eth_chainId": "main"},
+		0,
+		nil,
+		0,
+		0,
+		false,
+		nil,
+		RateLimitConfig{},
+		RateLimitConfig{},
+		make(map[common.Address]bool),
+		SenderRateLimitConfig{},
+		SenderRateLimitConfig{},
+		false,
+		0,
+		0,
+		func(dur time.Duration, max int, prefix string) FrontendRateLimiter {
+			return NewMemoryFrontendRateLimit(dur, max)
+		},
+		InteropValidationConfig{},
+		NewFirstSupervisorStrategy([]string{}),
+		nil,
+		false,
+	)
+	require.NoError(t, err)
+
+	w, err := NewRemoteConfigWatcher(config, srv, map[string]*Backend{"a": a, "b": b})
+	require.NoError(t, err)
+	require.NoError(t, w.poll(false))
+	initial := w.last
+
+	consul.set(`
+[server]
+rpc_port = 9545
+
+[backends.a]
+weight = 5
+
+[rate_limit.method_overrides.eth_call]
+limit = 2
+interval = "1m"
+
+[rpc_method_mappings]
+eth_call = "archive"
+`)
+	require.NoError(t, w.poll(true))
+	require.Equal(t, int64(5), a.weight.Load())
+	require.Equal(t, map[string]string{"eth_chainId": "main", "eth_call": "archive"}, srv.rpcMethodMappings)
+	ok, err := srv.mainLim.Take(context.Background(), "127.0.0.1")
+	require.NoError(t, err)
+	require.True(t, ok)
+	ok, err = srv.mainLim.Take(context.Background(), "127.0.0.1")
+	require.NoError(t, err)
+	require.False(t, ok)
+	require.NotNil(t, srv.overrideLims["eth_call"])
+	require.Equal(t, []string{"server"}, restartRequiredKeys(initial, w.last))
+
+	// invalid documents aren't applied at all
+	consul.set(`
+[backends.a]
+weight = 10
+
+[rpc_method_mappings]
+eth_call = "missing"
+`)
+	require.Error(t, w.poll(true))
+	require.Equal(t, int64(5), a.weight.Load())
+	require.Equal(t, "archive", srv.rpcMethodMappings["eth_call"])
+}
+
+func TestEtcdConfigSource(t *testing.T) {
+	etcd := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/v3/kv/range", r.URL.Path)
+		value := base64.StdEncoding.EncodeToString([]byte("[server]\nrpc_port = 8545\n"))
+		_, _ = w.Write([]byte(`{"kvs":[{"value":"` + value + `"}]}`))
+	}))
+	defer etcd.Close()
+
+	source, err := NewRemoteConfigSource(RemoteConfigConfig{URL: "etcd://" + etcd.Listener.Addr().String() + "/proxyd/config"})
+	require.NoError(t, err)
+	data, err := source.Fetch(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "[server]\nrpc_port = 8545\n", string(data))
+
+	_, err = NewRemoteConfigSource(RemoteConfigConfig{URL: "zookeeper://localhost/proxyd"})
+	require.Error(t, err)
+	_, err = NewRemoteConfigSource(RemoteConfigConfig{URL: "consul://localhost"})
+	require.Error(t, err)
+}
+
+func TestLoadConfigRemote(t *testing.T) {
+	consul := &fakeConsul{doc: "[server]\nrpc_port = 9545\n"}
+	consulSrv := httptest.NewServer(consul)
+	defer consulSrv.Close()
+
+	path := filepath.Join(t.TempDir(), "proxyd.toml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+[server]
+rpc_port = 8545
+max_body_size_bytes = 1000
+
+[remote_config]
+url = "consul://`+consulSrv.Listener.Addr().String()+`/proxyd/config"
+token = "token"
+`), 0o600))
+
+	config, _, err := LoadConfig(path)
+	require.NoError(t, err)
+	require.Equal(t, 9545, config.Server.RPCPort)
+	require.Equal(t, int64(1000), config.Server.MaxBodySizeBytes)
+}
@@ -0,0 +1,204 @@
+package proxyd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	defaultReplayConcurrency = 16
+	maxReplayLineSize        = 10 * 1024 * 1024
+)
+
+// ReplayOptions configures a replay of captured requests.
+type ReplayOptions struct {
+	// Target is the RPC URL of the proxyd under test.
+	Target string
+	// Baseline, if set, gets every request too, and responses that differ
+	// from the target's count as divergent.
+	Baseline string
+	// Speed scales the captured timing: 2 replays twice as fast. 0 sends
+	// requests as fast as Concurrency allows.
+	Speed       float64
+	Concurrency int
+	Client      *http.Client
+}
+
+// ReplayReport summarizes a replay.
+type ReplayReport struct {
+	Requests  int
+	Skipped   int
+	Errors    int
+	Divergent int
+	// Latencies of the target, sorted.
+	Latencies []time.Duration
+}
+
+// Percentile returns the latency below which p percent of the requests
+// completed.
+func (r *ReplayReport) Percentile(p float64) time.Duration {
+	return percentile(r.Latencies, p)
+}
+
+// percentile returns the latency below which p percent of the sorted
+// latencies are.
+func percentile(latencies []time.Duration, p float64) time.Duration {
+	if len(latencies) == 0 {
+		return 0
+	}
+	i := int(float64(len(latencies)-1) * p / 100)
+	return latencies[i]
+}
+
+type capturedRequest struct {
+	at   time.Time
+	body []byte
+}
+
+// Replay sends the requests captured in r to opts.Target. r holds one request
+// per line, either a "Raw RPC request" line of proxyd's JSON log, written
+// with server.enable_request_log, or a raw JSON-RPC request or batch.
+// Requests truncated in the log are skipped.
+func Replay(ctx context.Context, r io.Reader, opts ReplayOptions) (*ReplayReport, error) {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = defaultReplayConcurrency
+	}
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+
+	report := &ReplayReport{}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, opts.Concurrency)
+
+	var first time.Time
+	start := time.Now()
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxReplayLineSize)
+	for scanner.Scan() {
+		req, ok := parseCapturedRequest(scanner.Bytes())
+		if !ok {
+			report.Skipped++
+			continue
+		}
+		report.Requests++
+
+		if opts.Speed > 0 && !req.at.IsZero() {
+			if first.IsZero() {
+				first = req.at
+			}
+			wait := time.Until(start.Add(time.Duration(float64(req.at.Sub(first)) / opts.Speed)))
+			select {
+			case <-ctx.Done():
+				wg.Wait()
+				return report, ctx.Err()
+			case <-time.After(wait):
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return report, ctx.Err()
+		case sem <- struct{}{}:
+		}
+		wg.Add(1)
+		go func(body []byte) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			res, latency, err := replayRequest(ctx, opts.Client, opts.Target, body)
+			divergent := false
+			if err == nil && opts.Baseline != "" {
+				baseline, _, baselineErr := replayRequest(ctx, opts.Client, opts.Baseline, body)
+				divergent = baselineErr == nil && !equalJSON(res, baseline)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				report.Errors++
+				return
+			}
+			report.Latencies = append(report.Latencies, latency)
+			if divergent {
+				report.Divergent++
+			}
+		}(req.body)
+	}
+	wg.Wait()
+	sort.Slice(report.Latencies, func(i, j int) bool {
+		return report.Latencies[i] < report.Latencies[j]
+	})
+	if err := scanner.Err(); err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+func parseCapturedRequest(line []byte) (capturedRequest, bool) {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 {
+		return capturedRequest{}, false
+	}
+	if IsBatch(line) {
+		return capturedRequest{body: line}, json.Valid(line)
+	}
+
+	var entry struct {
+		Msg    string    `json:"msg"`
+		Time   time.Time `json:"time"`
+		Body   string    `json:"body"`
+		Method string    `json:"method"`
+	}
+	if err := json.Unmarshal(line, &entry); err != nil {
+		return capturedRequest{}, false
+	}
+	if entry.Method != "" {
+		return capturedRequest{body: line}, true
+	}
+	if entry.Msg != "Raw RPC request" || !json.Valid([]byte(entry.Body)) {
+		return capturedRequest{}, false
+	}
+	return capturedRequest{at: entry.Time, body: []byte(entry.Body)}, true
+}
+
+func replayRequest(ctx context.Context, client *http.Client, url string, body []byte) ([]byte, time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	start := time.Now()
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer res.Body.Close()
+	resBody, err := io.ReadAll(res.Body)
+	latency := time.Since(start)
+	if err != nil {
+		return nil, 0, err
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("response code %d", res.StatusCode)
+	}
+	return resBody, latency, nil
+}
+
+func equalJSON(a, b []byte) bool {
+	var va, vb interface{}
+	if json.Unmarshal(a, &va) != nil || json.Unmarshal(b, &vb) != nil {
+		return bytes.Equal(a, b)
+	}
+	return reflect.DeepEqual(va, vb)
+}
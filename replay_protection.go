@@ -0,0 +1,116 @@
+package proxyd
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	replayProtectionRedisKey        = "replay"
+	defaultFlashbotsReplayWindow    = time.Minute
+	replayProtectionMemoryLimit     = 100000
+	replayProtectionSchemeHMAC      = "hmac"
+	replayProtectionSchemeFlashbots = "flashbots"
+)
+
+// ReplayGuard rejects signed requests whose signature was already used,
+// remembering signatures in Redis if configured so replays are caught
+// across instances. HMAC signatures are remembered for as long as their
+// timestamp is accepted, so once forgotten they're rejected as expired.
+// X-Flashbots-Signature has no timestamp, so those signatures are only
+// remembered for a window, after which an identical request is accepted
+// again. If Redis fails, requests are let through rather than rejected.
+type ReplayGuard struct {
+	hmacTTL         time.Duration
+	flashbotsWindow time.Duration
+
+	redisClient redis.UniversalClient
+	prefix      string
+
+	mu    sync.Mutex
+	local *lru.Cache
+}
+
+// NewReplayGuard returns nil if replay protection is disabled.
+// hmacMaxClockSkew is how far HMAC timestamps may be from proxyd's clock.
+func NewReplayGuard(cfg ReplayProtectionConfig, hmacMaxClockSkew time.Duration, redisClient redis.UniversalClient, namespace string) *ReplayGuard {
+	if !cfg.Enabled {
+		return nil
+	}
+	if hmacMaxClockSkew == 0 {
+		hmacMaxClockSkew = defaultHMACMaxClockSkew
+	}
+	g := &ReplayGuard{
+		// a timestamp is accepted from maxClockSkew before it until
+		// maxClockSkew after it
+		hmacTTL:     2 * hmacMaxClockSkew,
+		redisClient: redisClient,
+		prefix:      replayProtectionRedisKey,
+	}
+	if cfg.FlashbotsSignatures {
+		g.flashbotsWindow = time.Duration(cfg.FlashbotsWindow)
+		if g.flashbotsWindow == 0 {
+			g.flashbotsWindow = defaultFlashbotsReplayWindow
+		}
+	}
+	if namespace != "" {
+		g.prefix = namespace + ":" + replayProtectionRedisKey
+	}
+	if redisClient == nil {
+		g.local, _ = lru.New(replayProtectionMemoryLimit)
+	}
+	return g
+}
+
+// CheckHMAC returns ErrReplayedSignature if the X-Proxyd-Signature header
+// was used before.
+func (g *ReplayGuard) CheckHMAC(ctx context.Context, header string) error {
+	if g == nil {
+		return nil
+	}
+	return g.check(ctx, replayProtectionSchemeHMAC, header, g.hmacTTL)
+}
+
+// CheckFlashbots returns ErrReplayedSignature if the X-Flashbots-Signature
+// header was used within the window.
+func (g *ReplayGuard) CheckFlashbots(ctx context.Context, header string) error {
+	if g == nil || g.flashbotsWindow == 0 {
+		return nil
+	}
+	return g.check(ctx, replayProtectionSchemeFlashbots, header, g.flashbotsWindow)
+}
+
+func (g *ReplayGuard) check(ctx context.Context, scheme string, header string, ttl time.Duration) error {
+	sum := sha256.Sum256([]byte(header))
+	key := scheme + ":" + hex.EncodeToString(sum[:])
+
+	if g.redisClient != nil {
+		fresh, err := g.redisClient.SetNX(ctx, g.prefix+":"+key, 1, ttl).Result()
+		if err != nil {
+			RecordRedisError("ReplayGuard")
+			log.Warn("error checking signature replay", "req_id", GetReqID(ctx), "err", err)
+			return nil
+		}
+		if !fresh {
+			RecordSignatureReplay(scheme)
+			return ErrReplayedSignature
+		}
+		return nil
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if val, ok := g.local.Get(key); ok && time.Now().Before(val.(time.Time)) {
+		RecordSignatureReplay(scheme)
+		return ErrReplayedSignature
+	}
+	g.local.Add(key, time.Now().Add(ttl))
+	return nil
+}
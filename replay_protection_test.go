@@ -0,0 +1,32 @@
+package proxyd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplayGuard(t *testing.T) {
+	ctx := context.Background()
+	require.Nil(t, NewReplayGuard(ReplayProtectionConfig{}, 0, nil, ""))
+
+	g := NewReplayGuard(ReplayProtectionConfig{Enabled: true}, 0, nil, "")
+	require.Equal(t, 2*defaultHMACMaxClockSkew, g.hmacTTL)
+	require.NoError(t, g.CheckHMAC(ctx, "indexer:1:aa"))
+	require.ErrorIs(t, g.CheckHMAC(ctx, "indexer:1:aa"), ErrReplayedSignature)
+	require.NoError(t, g.CheckHMAC(ctx, "indexer:1:bb"))
+	// flashbots signatures are only checked if configured
+	require.NoError(t, g.CheckFlashbots(ctx, "0xabc:0x01"))
+	require.NoError(t, g.CheckFlashbots(ctx, "0xabc:0x01"))
+
+	g = NewReplayGuard(ReplayProtectionConfig{Enabled: true, FlashbotsSignatures: true, FlashbotsWindow: TOMLDuration(50 * time.Millisecond)}, 0, nil, "")
+	require.NoError(t, g.CheckFlashbots(ctx, "0xabc:0x01"))
+	require.ErrorIs(t, g.CheckFlashbots(ctx, "0xabc:0x01"), ErrReplayedSignature)
+	// the same header is a different signature for each scheme
+	require.NoError(t, g.CheckHMAC(ctx, "0xabc:0x01"))
+	// and accepted again after the window
+	time.Sleep(60 * time.Millisecond)
+	require.NoError(t, g.CheckFlashbots(ctx, "0xabc:0x01"))
+}
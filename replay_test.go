@@ -0,0 +1,79 @@
+package proxyd
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplay(t *testing.T) {
+	var targetCalls atomic.Int32
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		targetCalls.Add(1)
+		body, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(body), "eth_chainId") {
+			_, _ = w.Write([]byte(`{"jsonrpc":"2.0","result":"0x1","id":1}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","result":"0x10","id":1}`))
+	}))
+	defer target.Close()
+	baseline := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"id":1,"jsonrpc":"2.0","result":"0x1"}`))
+	}))
+	defer baseline.Close()
+
+	logLine := func(at time.Time, body string) string {
+		line, err := json.Marshal(map[string]interface{}{
+			"time":   at,
+			"level":  "INFO",
+			"msg":    "Raw RPC request",
+			"body":   body,
+			"req_id": "abc",
+		})
+		require.NoError(t, err)
+		return string(line)
+	}
+	now := time.Now()
+	capture := strings.Join([]string{
+		logLine(now, `{"jsonrpc":"2.0","method":"eth_chainId","params":[],"id":1}`),
+		`{"time":"2024-01-01T00:00:00Z","level":"INFO","msg":"started proxyd"}`,
+		logLine(now.Add(100*time.Millisecond), `{"jsonrpc":"2.0","method":"eth_blockNumber","params":[],"id":1}`),
+		// truncated by the request log
+		logLine(now.Add(200*time.Millisecond), `{"jsonrpc":"2.0","method":"eth_call","par...`),
+		`{"jsonrpc":"2.0","method":"eth_chainId","params":[],"id":1}`,
+		"",
+	}, "\n")
+
+	start := time.Now()
+	report, err := Replay(context.Background(), strings.NewReader(capture), ReplayOptions{
+		Target:   target.URL,
+		Baseline: baseline.URL,
+		Speed:    2,
+	})
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+	require.Equal(t, 3, report.Requests)
+	require.Equal(t, 2, report.Skipped)
+	require.Equal(t, 0, report.Errors)
+	require.Equal(t, 1, report.Divergent)
+	require.Len(t, report.Latencies, 3)
+	require.Equal(t, int32(3), targetCalls.Load())
+	require.LessOrEqual(t, report.Percentile(50), report.Percentile(99))
+
+	report, err = Replay(context.Background(), strings.NewReader(capture), ReplayOptions{
+		Target: "http://127.0.0.1:1",
+		Speed:  0,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 3, report.Errors)
+	require.Empty(t, report.Latencies)
+}
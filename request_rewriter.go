@@ -0,0 +1,176 @@
+package proxyd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// backendURLRewrite describes how the client's URL path and query are
+// carried over to the backend URL for a method.
+type backendURLRewrite struct {
+	path         string
+	forwardPath  bool
+	forwardQuery bool
+}
+
+// defaultURLRewrites apply when a backend group has no rule for a method.
+// eth_sendRawTransaction uses URL parameters for MEV protection configuration.
+// TODO: Remove when API gateway handles protocol translation at the boundary.
+var defaultURLRewrites = map[string]*backendURLRewrite{
+	"eth_sendRawTransaction": {forwardPath: true, forwardQuery: true},
+}
+
+type requestRewriteRule struct {
+	renameTo      string
+	defaultParams []json.RawMessage
+	blockParam    int
+	blockTags     map[string]string
+	url           *backendURLRewrite
+}
+
+// RequestRewriter applies a backend group's declarative request rewrite rules
+// before requests are forwarded.
+type RequestRewriter struct {
+	rules map[string]*requestRewriteRule
+}
+
+func NewRequestRewriter(cfgs []*RequestRewriteConfig) (*RequestRewriter, error) {
+	rules := make(map[string]*requestRewriteRule, len(cfgs))
+	for _, cfg := range cfgs {
+		if cfg.Method == "" {
+			return nil, fmt.Errorf("request rewrite rule must specify a method")
+		}
+		if _, ok := rules[cfg.Method]; ok {
+			return nil, fmt.Errorf("duplicate request rewrite rule for method %s", cfg.Method)
+		}
+
+		rule := &requestRewriteRule{
+			renameTo:   cfg.RenameTo,
+			blockParam: -1,
+			blockTags:  cfg.BlockTags,
+		}
+		for _, p := range cfg.DefaultParams {
+			raw, err := json.Marshal(p)
+			if err != nil {
+				return nil, fmt.Errorf("invalid default param for method %s: %w", cfg.Method, err)
+			}
+			rule.defaultParams = append(rule.defaultParams, raw)
+		}
+		if len(cfg.BlockTags) > 0 {
+			if cfg.BlockParam == nil || *cfg.BlockParam < 0 {
+				return nil, fmt.Errorf("block_tags requires a block_param for method %s", cfg.Method)
+			}
+			rule.blockParam = *cfg.BlockParam
+		}
+		if cfg.URLPath != "" || cfg.ForwardPath || cfg.ForwardQuery {
+			if cfg.URLPath != "" && !strings.HasPrefix(cfg.URLPath, "/") {
+				return nil, fmt.Errorf("url_path must start with / for method %s", cfg.Method)
+			}
+			rule.url = &backendURLRewrite{
+				path:         cfg.URLPath,
+				forwardPath:  cfg.ForwardPath,
+				forwardQuery: cfg.ForwardQuery,
+			}
+		}
+		rules[cfg.Method] = rule
+	}
+	return &RequestRewriter{rules: rules}, nil
+}
+
+// RewriteRequests returns the requests with the rules applied. Rewritten
+// requests are copies, so the originals can still be used as cache keys.
+func (r *RequestRewriter) RewriteRequests(ctx context.Context, reqs []*RPCReq) []*RPCReq {
+	if r == nil || len(r.rules) == 0 {
+		return reqs
+	}
+
+	out := make([]*RPCReq, len(reqs))
+	for i, req := range reqs {
+		rule, ok := r.rules[req.Method]
+		if !ok {
+			out[i] = req
+			continue
+		}
+		rewritten, err := rule.apply(req)
+		if err != nil {
+			// leave malformed requests for the backend to reject
+			log.Debug("skipping request rewrite",
+				"method", req.Method,
+				"req_id", GetReqID(ctx),
+				"err", err,
+			)
+			out[i] = req
+			continue
+		}
+		out[i] = rewritten
+	}
+	return out
+}
+
+func (rule *requestRewriteRule) apply(req *RPCReq) (*RPCReq, error) {
+	rewritten := *req
+	if rule.renameTo != "" {
+		rewritten.Method = rule.renameTo
+		rewritten.renamedFrom = req.Method
+	}
+
+	if len(rule.defaultParams) == 0 && rule.blockParam < 0 {
+		return &rewritten, nil
+	}
+
+	var params []json.RawMessage
+	if len(req.Params) > 0 && string(req.Params) != "null" {
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+	}
+
+	for i := len(params); i < len(rule.defaultParams); i++ {
+		params = append(params, rule.defaultParams[i])
+	}
+
+	if rule.blockParam >= 0 && rule.blockParam < len(params) {
+		var tag string
+		if err := json.Unmarshal(params[rule.blockParam], &tag); err == nil {
+			if replacement, ok := rule.blockTags[tag]; ok {
+				params[rule.blockParam] = mustMarshalJSON(replacement)
+			}
+		}
+	}
+
+	rewritten.Params = mustMarshalJSON(params)
+	return &rewritten, nil
+}
+
+// urlRewrite returns the URL rewrite for method. Carrying over the client's
+// path and query is subject to the url_forwarding feature flag.
+func (r *RequestRewriter) urlRewrite(ctx context.Context, method string) *backendURLRewrite {
+	rw := defaultURLRewrites[method]
+	if r != nil {
+		if rule, ok := r.rules[method]; ok && rule.url != nil {
+			rw = rule.url
+		}
+	}
+	if rw == nil || !(rw.forwardPath || rw.forwardQuery) {
+		return rw
+	}
+	if !GetFeatureFlags(ctx).Enabled(ctx, FeatureURLForwarding) {
+		if rw.path == "" {
+			return nil
+		}
+		return &backendURLRewrite{path: rw.path}
+	}
+	return rw
+}
+
+func GetRequestRewriter(ctx context.Context) *RequestRewriter {
+	rewriter, ok := ctx.Value(ContextKeyRequestRewriter).(*RequestRewriter)
+	if !ok {
+		return nil
+	}
+	return rewriter
+}
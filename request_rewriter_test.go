@@ -0,0 +1,84 @@
+package proxyd
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestRewriterRewriteRequests(t *testing.T) {
+	blockParam := 1
+	rw, err := NewRequestRewriter([]*RequestRewriteConfig{
+		{
+			Method:   "eth_getTransactionCount",
+			RenameTo: "eth_getTransactionCountCompat",
+		},
+		{
+			Method:        "eth_getBalance",
+			DefaultParams: []interface{}{"0x0000000000000000000000000000000000000000", "latest"},
+			BlockParam:    &blockParam,
+			BlockTags:     map[string]string{"pending": "latest"},
+		},
+	})
+	require.NoError(t, err)
+
+	orig := []*RPCReq{
+		{JSONRPC: "2.0", Method: "eth_getTransactionCount", Params: json.RawMessage(`["0x1","latest"]`), ID: json.RawMessage("1")},
+		{JSONRPC: "2.0", Method: "eth_getBalance", Params: json.RawMessage(`["0x1"]`), ID: json.RawMessage("2")},
+		{JSONRPC: "2.0", Method: "eth_getBalance", Params: json.RawMessage(`["0x1","pending"]`), ID: json.RawMessage("3")},
+		{JSONRPC: "2.0", Method: "eth_getBalance", Params: json.RawMessage(`{"bad":true}`), ID: json.RawMessage("4")},
+		{JSONRPC: "2.0", Method: "eth_chainId", ID: json.RawMessage("5")},
+	}
+
+	out := rw.RewriteRequests(context.Background(), orig)
+	require.Len(t, out, len(orig))
+
+	require.Equal(t, "eth_getTransactionCountCompat", out[0].Method)
+	require.Equal(t, "eth_getTransactionCount", orig[0].Method, "originals must not be mutated")
+	require.JSONEq(t, `["0x1","latest"]`, string(out[1].Params))
+	require.JSONEq(t, `["0x1","latest"]`, string(out[2].Params))
+	require.Same(t, orig[3], out[3])
+	require.Same(t, orig[4], out[4])
+}
+
+func TestRequestRewriterValidation(t *testing.T) {
+	_, err := NewRequestRewriter([]*RequestRewriteConfig{{RenameTo: "foo"}})
+	require.Error(t, err)
+
+	_, err = NewRequestRewriter([]*RequestRewriteConfig{{Method: "eth_call", BlockTags: map[string]string{"pending": "latest"}}})
+	require.Error(t, err)
+
+	_, err = NewRequestRewriter([]*RequestRewriteConfig{{Method: "eth_call"}, {Method: "eth_call"}})
+	require.Error(t, err)
+
+	_, err = NewRequestRewriter([]*RequestRewriteConfig{{Method: "eth_call", URLPath: "fast"}})
+	require.Error(t, err)
+}
+
+func TestBuildBackendURLWithRewriteRules(t *testing.T) {
+	rw, err := NewRequestRewriter([]*RequestRewriteConfig{
+		{Method: "eth_sendBundle", URLPath: "/bundles", ForwardQuery: true},
+		{Method: "eth_sendRawTransaction"},
+		{Method: "eth_call", ForwardPath: true},
+		{Method: "eth_sendPrivateTransaction", RenameTo: "eth_sendRawTransaction", URLPath: "/private"},
+	})
+	require.NoError(t, err)
+
+	ctx := context.WithValue(context.Background(), ContextKeyRequestRewriter, rw) // nolint:staticcheck
+	ctx = context.WithValue(ctx, ContextKeyPath, "/fast")                         // nolint:staticcheck
+	ctx = context.WithValue(ctx, ContextKeyRawQuery, "hint=hash")                 // nolint:staticcheck
+
+	base := "http://backend:8080/"
+	require.Equal(t, "http://backend:8080/bundles?hint=hash", buildBackendURL(base, []*RPCReq{{Method: "eth_sendBundle"}}, ctx))
+	// a rule without URL settings falls back to the defaults
+	require.Equal(t, "http://backend:8080/fast?hint=hash", buildBackendURL(base, []*RPCReq{{Method: "eth_sendRawTransaction"}}, ctx))
+	require.Equal(t, "http://backend:8080/fast", buildBackendURL(base, []*RPCReq{{Method: "eth_call"}}, ctx))
+	require.Equal(t, base, buildBackendURL(base, []*RPCReq{{Method: "eth_chainId"}}, ctx))
+
+	// renamed requests get the URL rewrite of the method the client asked for
+	renamed := rw.RewriteRequests(ctx, []*RPCReq{{Method: "eth_sendPrivateTransaction", Params: json.RawMessage(`["0x1"]`)}})
+	require.Equal(t, "eth_sendRawTransaction", renamed[0].Method)
+	require.Equal(t, "http://backend:8080/private", buildBackendURL(base, renamed, ctx))
+}
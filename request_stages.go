@@ -0,0 +1,63 @@
+package proxyd
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// requestStage is a stage of an HTTP RPC request, for the latency breakdown
+// in request_stage_duration_seconds.
+type requestStage int
+
+const (
+	requestStageParse requestStage = iota
+	requestStageRateLimit
+	requestStageCache
+	requestStageQueue
+	requestStageBackend
+	requestStageSerialize
+	numRequestStages
+)
+
+var requestStageNames = [numRequestStages]string{
+	"parse",
+	"rate_limit",
+	"cache",
+	"queue",
+	"backend",
+	"serialize",
+}
+
+// stageTimings adds up the time a request spends in each stage. Stages
+// repeat, e.g. for each request of a batch or each backend tried, and may
+// run concurrently, e.g. for multicall, in which case their time is summed.
+type stageTimings struct {
+	nanos [numRequestStages]atomic.Int64
+	seen  [numRequestStages]atomic.Bool
+}
+
+func withStageTimings(ctx context.Context) (context.Context, *stageTimings) {
+	timings := new(stageTimings)
+	return context.WithValue(ctx, ContextKeyRequestStages, timings), timings // nolint:staticcheck
+}
+
+// addStageTime adds the time since start to a stage of the request of ctx.
+// Requests proxyd makes on its own, like consensus polling, aren't timed.
+func addStageTime(ctx context.Context, stage requestStage, start time.Time) {
+	timings, ok := ctx.Value(ContextKeyRequestStages).(*stageTimings)
+	if !ok {
+		return
+	}
+	timings.nanos[stage].Add(int64(time.Since(start)))
+	timings.seen[stage].Store(true)
+}
+
+// record observes the stages the request went through.
+func (t *stageTimings) record() {
+	for stage, name := range requestStageNames {
+		if t.seen[stage].Load() {
+			RecordRequestStage(name, time.Duration(t.nanos[stage].Load()))
+		}
+	}
+}
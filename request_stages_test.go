@@ -0,0 +1,26 @@
+package proxyd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStageTimings(t *testing.T) {
+	// untimed requests are ignored
+	addStageTime(context.Background(), requestStageBackend, time.Now())
+
+	ctx, timings := withStageTimings(context.Background())
+	start := time.Now().Add(-10 * time.Millisecond)
+	addStageTime(ctx, requestStageBackend, start)
+	addStageTime(ctx, requestStageBackend, start)
+	addStageTime(ctx, requestStageParse, time.Now())
+
+	require.GreaterOrEqual(t, time.Duration(timings.nanos[requestStageBackend].Load()), 20*time.Millisecond)
+	require.True(t, timings.seen[requestStageBackend].Load())
+	require.True(t, timings.seen[requestStageParse].Load())
+	require.False(t, timings.seen[requestStageCache].Load())
+	timings.record()
+}
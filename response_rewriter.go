@@ -0,0 +1,76 @@
+package proxyd
+
+// ResponseRewriter applies a backend group's response transforms before
+// results are returned to clients and written to the cache.
+type ResponseRewriter struct {
+	overrides      map[string]string
+	maskFields     map[string]bool
+	nullAsEmpty    map[string]bool
+	injectServedBy bool
+}
+
+func NewResponseRewriter(cfg ResponseRewriteConfig) *ResponseRewriter {
+	r := &ResponseRewriter{
+		overrides:      cfg.Overrides,
+		maskFields:     make(map[string]bool, len(cfg.MaskFields)),
+		nullAsEmpty:    make(map[string]bool, len(cfg.NullAsEmpty)),
+		injectServedBy: cfg.InjectServedBy,
+	}
+	for _, f := range cfg.MaskFields {
+		r.maskFields[f] = true
+	}
+	for _, m := range cfg.NullAsEmpty {
+		r.nullAsEmpty[m] = true
+	}
+	return r
+}
+
+// RewriteResponses transforms res in place. reqs must be the client's
+// requests, in the same order as res.
+func (r *ResponseRewriter) RewriteResponses(reqs []*RPCReq, res []*RPCRes, servedBy string) {
+	if r == nil || len(reqs) != len(res) {
+		return
+	}
+
+	for i, rr := range res {
+		if rr == nil || rr.IsError() {
+			continue
+		}
+		method := reqs[i].Method
+
+		if override, ok := r.overrides[method]; ok {
+			rr.Result = override
+		}
+		if rr.Result == nil && r.nullAsEmpty[method] {
+			rr.Result = emptyArrayResponse
+		}
+		if len(r.maskFields) > 0 {
+			rr.Result = r.mask(rr.Result)
+		}
+		if r.injectServedBy {
+			rr.ServedBy = servedBy
+		}
+	}
+}
+
+// mask removes the configured fields from objects anywhere in the result.
+func (r *ResponseRewriter) mask(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, child := range val {
+			if r.maskFields[k] {
+				delete(val, k)
+				continue
+			}
+			val[k] = r.mask(child)
+		}
+		return val
+	case []interface{}:
+		for i, child := range val {
+			val[i] = r.mask(child)
+		}
+		return val
+	default:
+		return v
+	}
+}
@@ -0,0 +1,65 @@
+package proxyd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseRewriterRewriteResponses(t *testing.T) {
+	rw := NewResponseRewriter(ResponseRewriteConfig{
+		Overrides:      map[string]string{"web3_clientVersion": "proxyd"},
+		MaskFields:     []string{"enode", "ip"},
+		NullAsEmpty:    []string{"eth_getLogs"},
+		InjectServedBy: true,
+	})
+
+	reqs := []*RPCReq{
+		{Method: "web3_clientVersion", ID: json.RawMessage("1")},
+		{Method: "admin_nodeInfo", ID: json.RawMessage("2")},
+		{Method: "eth_getLogs", ID: json.RawMessage("3")},
+		{Method: "eth_getBlockByNumber", ID: json.RawMessage("4")},
+		{Method: "web3_clientVersion", ID: json.RawMessage("5")},
+	}
+	res := []*RPCRes{
+		NewRPCRes(json.RawMessage("1"), "Geth/v1.13.0"),
+		NewRPCRes(json.RawMessage("2"), map[string]interface{}{
+			"name":  "geth",
+			"enode": "enode://abc",
+			"ports": []interface{}{map[string]interface{}{"ip": "10.0.0.1", "port": 30303.0}},
+		}),
+		NewRPCRes(json.RawMessage("3"), nil),
+		NewRPCRes(json.RawMessage("4"), nil),
+		NewRPCErrorRes(json.RawMessage("5"), ErrInternal),
+	}
+
+	rw.RewriteResponses(reqs, res, "main/infura")
+
+	require.Equal(t, "proxyd", res[0].Result)
+	require.Equal(t, map[string]interface{}{
+		"name":  "geth",
+		"ports": []interface{}{map[string]interface{}{"port": 30303.0}},
+	}, res[1].Result)
+	require.Equal(t, emptyArrayResponse, res[2].Result)
+	require.Nil(t, res[3].Result)
+	require.Equal(t, ErrInternal, res[4].Error, "errors must not be rewritten")
+
+	out, err := json.Marshal(res[3])
+	require.NoError(t, err)
+	require.JSONEq(t, `{"jsonrpc":"2.0","result":null,"id":4,"servedBy":"main/infura"}`, string(out))
+	require.Empty(t, res[4].ServedBy)
+}
+
+func TestResponseRewriterNoop(t *testing.T) {
+	var rw *ResponseRewriter
+	res := []*RPCRes{NewRPCRes(json.RawMessage("1"), "0x1")}
+	rw.RewriteResponses([]*RPCReq{{Method: "eth_chainId"}}, res, "main/infura")
+	require.Equal(t, "0x1", res[0].Result)
+
+	rw = NewResponseRewriter(ResponseRewriteConfig{})
+	rw.RewriteResponses([]*RPCReq{{Method: "eth_chainId"}}, res, "main/infura")
+	out, err := json.Marshal(res[0])
+	require.NoError(t, err)
+	require.JSONEq(t, `{"jsonrpc":"2.0","result":"0x1","id":1}`, string(out))
+}
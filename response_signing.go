@@ -0,0 +1,127 @@
+package proxyd
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const defaultResponseSignatureHeader = "X-Proxyd-Response-Signature"
+
+// ResponseSigner signs the bodies of RPC responses with a detached
+// signature header, so consumers downstream of intermediate layers can
+// verify they came from proxyd unaltered. The signature has the format of
+// X-Flashbots-Signature, <address>:<signature> of the EIP-191 hash of the
+// hex keccak256 of the body, and verifies with VerifyFlashbotsAuth.
+type ResponseSigner struct {
+	// key is replaced when its secret is refreshed
+	key    atomic.Pointer[responseSigningKey]
+	header string
+}
+
+type responseSigningKey struct {
+	key     *ecdsa.PrivateKey
+	address common.Address
+}
+
+func parseResponseSigningKey(value string) (*responseSigningKey, error) {
+	if value == "" {
+		return nil, errors.New("response_signing.private_key must be set")
+	}
+	key, err := crypto.HexToECDSA(strings.TrimPrefix(value, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid response_signing.private_key: %w", err)
+	}
+	return &responseSigningKey{key: key, address: crypto.PubkeyToAddress(key.PublicKey)}, nil
+}
+
+func NewResponseSigner(cfg ResponseSigningConfig, secrets *SecretStore) (*ResponseSigner, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	resolved, err := secrets.Resolve(cfg.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	key, err := parseResponseSigningKey(resolved)
+	if err != nil {
+		return nil, err
+	}
+	s := &ResponseSigner{header: cfg.Header}
+	s.key.Store(key)
+	secrets.Watch(cfg.PrivateKey, func(value string) {
+		key, err := parseResponseSigningKey(value)
+		if err != nil {
+			log.Error("ignoring refreshed response signing key", "err", err)
+			return
+		}
+		s.key.Store(key)
+	})
+	if s.header == "" {
+		s.header = defaultResponseSignatureHeader
+	}
+	return s, nil
+}
+
+// Sign returns the signature header value for body.
+func (s *ResponseSigner) Sign(body []byte) (string, error) {
+	key := s.key.Load()
+	hashedBody := crypto.Keccak256Hash(body).Hex()
+	sig, err := crypto.Sign(accounts.TextHash([]byte(hashedBody)), key.key)
+	if err != nil {
+		return "", err
+	}
+	return key.address.Hex() + ":" + hexutil.Encode(sig), nil
+}
+
+// Wrap buffers the responses of h to sign them.
+func (s *ResponseSigner) Wrap(h http.HandlerFunc) http.HandlerFunc {
+	if s == nil {
+		return h
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		sw := &signingResponseWriter{ResponseWriter: w, signer: s}
+		defer sw.flush()
+		h(sw, r)
+	}
+}
+
+// signingResponseWriter buffers a response and writes it with its
+// signature.
+type signingResponseWriter struct {
+	http.ResponseWriter
+	signer *ResponseSigner
+	status int
+	buf    bytes.Buffer
+}
+
+func (w *signingResponseWriter) WriteHeader(status int) {
+	w.status = status
+}
+
+func (w *signingResponseWriter) Write(b []byte) (int, error) {
+	return w.buf.Write(b)
+}
+
+func (w *signingResponseWriter) flush() {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if sig, err := w.signer.Sign(w.buf.Bytes()); err != nil {
+		log.Error("error signing response", "err", err)
+	} else {
+		w.Header().Set(w.signer.header, sig)
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	_, _ = w.ResponseWriter.Write(w.buf.Bytes())
+}
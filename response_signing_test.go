@@ -0,0 +1,47 @@
+package proxyd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseSigner(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	secrets, err := NewSecretStore(SecretsConfig{})
+	require.NoError(t, err)
+
+	signer, err := NewResponseSigner(ResponseSigningConfig{Enabled: true, PrivateKey: hexutil.Encode(crypto.FromECDSA(key))}, secrets)
+	require.NoError(t, err)
+
+	body := `{"jsonrpc":"2.0","result":"0x1","id":1}` + "\n"
+	h := signer.Wrap(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("content-type", "application/json")
+		w.WriteHeader(http.StatusTeapot)
+		_, _ = w.Write([]byte(body))
+	})
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodPost, "/", nil))
+
+	require.Equal(t, http.StatusTeapot, rec.Code)
+	require.Equal(t, body, rec.Body.String())
+	sig := rec.Header().Get("X-Proxyd-Response-Signature")
+	signerAddr, err := VerifyFlashbotsAuth(sig, rec.Body.Bytes())
+	require.NoError(t, err)
+	require.Equal(t, crypto.PubkeyToAddress(key.PublicKey), signerAddr)
+
+	// tampered bodies don't verify
+	_, err = VerifyFlashbotsAuth(sig, []byte(`{"jsonrpc":"2.0","result":"0x2","id":1}`))
+	require.Error(t, err)
+
+	disabled, err := NewResponseSigner(ResponseSigningConfig{}, secrets)
+	require.NoError(t, err)
+	require.Nil(t, disabled)
+	_, err = NewResponseSigner(ResponseSigningConfig{Enabled: true, PrivateKey: "0xzz"}, secrets)
+	require.Error(t, err)
+}
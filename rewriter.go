@@ -13,6 +13,9 @@ type RewriteContext struct {
 	safe          hexutil.Uint64
 	finalized     hexutil.Uint64
 	maxBlockRange uint64
+	// latestOnly rewrites the latest tag only, and leaves explicit block
+	// numbers to the backend, see HeadTracker.
+	latestOnly bool
 }
 
 type RewriteResult uint8
@@ -262,6 +265,13 @@ func rewriteTag(rctx RewriteContext, current string) (string, bool, error) {
 		return current, false, nil
 	}
 
+	if rctx.latestOnly {
+		if *bnh.BlockNumber == rpc.LatestBlockNumber {
+			return rctx.latest.String(), true, nil
+		}
+		return current, false, nil
+	}
+
 	switch *bnh.BlockNumber {
 	case rpc.PendingBlockNumber,
 		rpc.EarliestBlockNumber:
@@ -287,6 +297,14 @@ func rewriteTagBlockNumberOrHash(rctx RewriteContext, current *rpc.BlockNumberOr
 		return current, false, nil
 	}
 
+	if rctx.latestOnly {
+		if *current.BlockNumber == rpc.LatestBlockNumber {
+			bn := rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(rctx.latest))
+			return &bn, true, nil
+		}
+		return current, false, nil
+	}
+
 	switch *current.BlockNumber {
 	case rpc.PendingBlockNumber,
 		rpc.EarliestBlockNumber:
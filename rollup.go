@@ -0,0 +1,186 @@
+package proxyd
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// rollupMethods are served by the rollup node of an OP Stack chain, op-node,
+// or by the legacy l2geth for rollup_*, so they go to rollup_group.
+var rollupMethods = []string{
+	"optimism_outputAtBlock",
+	"optimism_syncStatus",
+	"optimism_rollupConfig",
+	"optimism_version",
+	"rollup_gasPrices",
+	"rollup_getInfo",
+}
+
+// MapRollupMethods returns the method mappings with the built-in rollup
+// methods mapped to rollupGroup, unless they're mapped already or the group
+// is empty.
+func MapRollupMethods(mappings map[string]string, rollupGroup string) map[string]string {
+	if rollupGroup == "" {
+		return mappings
+	}
+	mapped := make(map[string]string, len(mappings)+len(rollupMethods))
+	for method, group := range mappings {
+		mapped[method] = group
+	}
+	for _, method := range rollupMethods {
+		if _, ok := mapped[method]; !ok {
+			mapped[method] = rollupGroup
+		}
+	}
+	return mapped
+}
+
+// outputResponse is the part of an optimism_outputAtBlock result used here.
+type outputResponse struct {
+	OutputRoot string `json:"outputRoot"`
+	BlockRef   struct {
+		Number uint64 `json:"number"`
+	} `json:"blockRef"`
+	SyncStatus struct {
+		FinalizedL2 struct {
+			Number uint64 `json:"number"`
+		} `json:"finalized_l2"`
+	} `json:"syncStatus"`
+}
+
+func decodeResult(res *RPCRes, v interface{}) error {
+	raw, err := json.Marshal(res.Result)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
+
+// isFinalOutput returns whether an optimism_outputAtBlock result is for a
+// finalized block, whose output won't change anymore.
+func isFinalOutput(res *RPCRes) bool {
+	var out outputResponse
+	if err := decodeResult(res, &out); err != nil || out.OutputRoot == "" {
+		return false
+	}
+	return out.BlockRef.Number <= out.SyncStatus.FinalizedL2.Number
+}
+
+// isRollupConsensusMethod returns whether a method is served from every
+// backend of a group with rollup_consensus.
+func isRollupConsensusMethod(method string) bool {
+	return method == "optimism_outputAtBlock" || method == "optimism_syncStatus"
+}
+
+// forwardRollupConsensus asks every backend for an optimism_outputAtBlock or
+// optimism_syncStatus, rather than trusting the first rollup node to answer.
+// An output is only served when a majority of the backends agree on its
+// root. Sync statuses are combined into the lowest head of each kind, the
+// one all the backends that answered have reached.
+func (bg *BackendGroup) forwardRollupConsensus(ctx context.Context, req *RPCReq, backends []*Backend) ([]*RPCRes, string, error) {
+	var (
+		mu      sync.Mutex
+		results []*RPCRes
+		wg      sync.WaitGroup
+	)
+	for _, be := range backends {
+		wg.Add(1)
+		go func(be *Backend) {
+			defer wg.Done()
+			res, err := be.Forward(ctx, []*RPCReq{req}, false)
+			if err != nil {
+				log.Warn("error forwarding rollup consensus request", "name", be.Name, "req_id", GetReqID(ctx), "err", err)
+				return
+			}
+			if len(res) != 1 || res[0].IsError() {
+				return
+			}
+			mu.Lock()
+			results = append(results, res[0])
+			mu.Unlock()
+		}(be)
+	}
+	wg.Wait()
+
+	servedBy := bg.Name + "/rollup_consensus"
+	if len(results) == 0 {
+		RecordRollupConsensus(bg.Name, req.Method, false)
+		return nil, "", ErrNoBackends
+	}
+
+	var res *RPCRes
+	switch req.Method {
+	case "optimism_outputAtBlock":
+		res = outputConsensus(results, len(backends))
+	case "optimism_syncStatus":
+		res = lowestSyncStatus(results)
+	}
+	if res == nil {
+		RecordRollupConsensus(bg.Name, req.Method, false)
+		return nil, "", ErrRollupNoConsensus
+	}
+	RecordRollupConsensus(bg.Name, req.Method, true)
+	return []*RPCRes{res}, servedBy, nil
+}
+
+// outputConsensus returns a result with the output root more than half of
+// the backends returned, if any.
+func outputConsensus(results []*RPCRes, backends int) *RPCRes {
+	votes := make(map[string]int)
+	for _, res := range results {
+		var out outputResponse
+		if err := decodeResult(res, &out); err != nil || out.OutputRoot == "" {
+			continue
+		}
+		votes[out.OutputRoot]++
+		if votes[out.OutputRoot] > backends/2 {
+			return res
+		}
+	}
+	return nil
+}
+
+// lowestSyncStatus combines sync statuses, keeping the block reference with
+// the lowest number for each head, e.g. safe_l2 or finalized_l1.
+func lowestSyncStatus(results []*RPCRes) *RPCRes {
+	var combined map[string]interface{}
+	for _, res := range results {
+		var status map[string]interface{}
+		if err := decodeResult(res, &status); err != nil {
+			continue
+		}
+		if combined == nil {
+			combined = status
+			continue
+		}
+		for head, ref := range status {
+			number, ok := blockRefNumber(ref)
+			if !ok {
+				continue
+			}
+			if lowest, ok := blockRefNumber(combined[head]); !ok || number < lowest {
+				combined[head] = ref
+			}
+		}
+	}
+	if combined == nil {
+		return nil
+	}
+	return &RPCRes{
+		JSONRPC: JSONRPCVersion,
+		Result:  combined,
+		ID:      results[0].ID,
+	}
+}
+
+func blockRefNumber(ref interface{}) (float64, bool) {
+	obj, ok := ref.(map[string]interface{})
+	if !ok {
+		return 0, false
+	}
+	number, ok := obj["number"].(float64)
+	return number, ok
+}
@@ -0,0 +1,75 @@
+package proxyd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/semaphore"
+)
+
+func TestMapRollupMethods(t *testing.T) {
+	mappings := map[string]string{"optimism_syncStatus": "main"}
+	mapped := MapRollupMethods(mappings, "rollup")
+	require.Equal(t, "main", mapped["optimism_syncStatus"])
+	require.Equal(t, "rollup", mapped["optimism_outputAtBlock"])
+	require.Equal(t, "rollup", mapped["rollup_gasPrices"])
+	require.Len(t, mappings, 1)
+	require.Equal(t, mappings, MapRollupMethods(mappings, ""))
+}
+
+func TestRollupConsensus(t *testing.T) {
+	rollupNode := func(outputRoot string, safe int) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var req RPCReq
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			switch req.Method {
+			case "optimism_outputAtBlock":
+				_, _ = fmt.Fprintf(w, `{"jsonrpc":"2.0","result":{"outputRoot":%q,"blockRef":{"number":10},"syncStatus":{"finalized_l2":{"number":20}}},"id":%s}`, outputRoot, req.ID)
+			case "optimism_syncStatus":
+				_, _ = fmt.Fprintf(w, `{"jsonrpc":"2.0","result":{"unsafe_l2":{"number":30},"safe_l2":{"number":%d},"finalized_l2":{"number":20}},"id":%s}`, safe, req.ID)
+			}
+		}))
+	}
+	a := rollupNode("0xaa", 25)
+	defer a.Close()
+	b := rollupNode("0xaa", 22)
+	defer b.Close()
+	c := rollupNode("0xbb", 28)
+	defer c.Close()
+
+	sem := semaphore.NewWeighted(10)
+	newGroup := func(urls ...string) *BackendGroup {
+		bg := &BackendGroup{Name: "rollup", rollupConsensus: true}
+		for i, url := range urls {
+			bg.Backends = append(bg.Backends, NewBackend(fmt.Sprintf("node%d", i), url, "", sem))
+		}
+		return bg
+	}
+	req := func(method string, params string) []*RPCReq {
+		return []*RPCReq{{JSONRPC: "2.0", Method: method, Params: json.RawMessage(params), ID: json.RawMessage("1")}}
+	}
+
+	// the majority's output is served
+	res, servedBy, err := newGroup(a.URL, b.URL, c.URL).Forward(context.Background(), req("optimism_outputAtBlock", `["0xa"]`), false)
+	require.NoError(t, err)
+	require.Equal(t, "rollup/rollup_consensus", servedBy)
+	require.Equal(t, "0xaa", res[0].Result.(map[string]interface{})["outputRoot"])
+	require.True(t, isFinalOutput(res[0]))
+
+	// without a majority nothing is
+	_, _, err = newGroup(a.URL, c.URL).Forward(context.Background(), req("optimism_outputAtBlock", `["0xa"]`), false)
+	require.ErrorIs(t, err, ErrRollupNoConsensus)
+
+	// sync statuses are combined into the lowest heads
+	res, _, err = newGroup(a.URL, b.URL, c.URL).Forward(context.Background(), req("optimism_syncStatus", `[]`), false)
+	require.NoError(t, err)
+	status, err := json.Marshal(res[0].Result)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"unsafe_l2":{"number":30},"safe_l2":{"number":22},"finalized_l2":{"number":20}}`, string(status))
+	require.Equal(t, json.RawMessage("1"), res[0].ID)
+}
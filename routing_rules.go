@@ -0,0 +1,200 @@
+package proxyd
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// RoutingRules route requests to another backend group than the one their
+// method is mapped to when their params match, e.g. eth_getLogs reaching
+// far back to archive nodes. The first rule matching a request wins.
+type RoutingRules struct {
+	rules  []*routingRule
+	groups map[string]*BackendGroup
+}
+
+type routingRule struct {
+	method     string
+	group      string
+	headGroup  string
+	conditions []*routingCondition
+}
+
+// routingCondition holds when the param at path meets all of its
+// predicates.
+type routingCondition struct {
+	path      []string
+	olderThan uint64
+	newerThan uint64
+	equals    string
+	matches   *regexp.Regexp
+}
+
+// NewRoutingRules returns nil if there are no rules.
+func NewRoutingRules(cfg []RoutingRuleConfig, groups map[string]*BackendGroup) (*RoutingRules, error) {
+	if len(cfg) == 0 {
+		return nil, nil
+	}
+	r := &RoutingRules{groups: groups}
+	for i, rc := range cfg {
+		if rc.Method == "" {
+			return nil, fmt.Errorf("routing rule %d: method must be set", i)
+		}
+		if groups[rc.BackendGroup] == nil {
+			return nil, fmt.Errorf("routing rule %d: undefined backend group %s", i, rc.BackendGroup)
+		}
+		if rc.HeadGroup != "" && groups[rc.HeadGroup] == nil {
+			return nil, fmt.Errorf("routing rule %d: undefined head group %s", i, rc.HeadGroup)
+		}
+		rule := &routingRule{
+			method:    rc.Method,
+			group:     rc.BackendGroup,
+			headGroup: rc.HeadGroup,
+		}
+		for _, cc := range rc.When {
+			if cc.Param == "" {
+				return nil, fmt.Errorf("routing rule %d: condition param must be set", i)
+			}
+			cond := &routingCondition{
+				path:      strings.Split(cc.Param, "."),
+				olderThan: cc.OlderThan,
+				newerThan: cc.NewerThan,
+				equals:    cc.Equals,
+			}
+			if cc.Matches != "" {
+				re, err := regexp.Compile(cc.Matches)
+				if err != nil {
+					return nil, fmt.Errorf("routing rule %d: invalid matches: %w", i, err)
+				}
+				cond.matches = re
+			}
+			rule.conditions = append(rule.conditions, cond)
+		}
+		r.rules = append(r.rules, rule)
+	}
+	return r, nil
+}
+
+// Route returns the backend group serving req, given the group its method
+// is mapped to. Methods that aren't mapped aren't routed.
+func (r *RoutingRules) Route(req *RPCReq, group string) string {
+	if r == nil || group == "" {
+		return group
+	}
+	var params interface{}
+	for _, rule := range r.rules {
+		if rule.method != req.Method {
+			continue
+		}
+		if params == nil {
+			if err := json.Unmarshal(req.Params, &params); err != nil {
+				return group
+			}
+		}
+		if r.matches(rule, params, group) {
+			RecordRoutingRule(req.Method, rule.group)
+			return rule.group
+		}
+	}
+	return group
+}
+
+func (r *RoutingRules) matches(rule *routingRule, params interface{}, group string) bool {
+	for _, cond := range rule.conditions {
+		val, ok := paramAt(params, cond.path)
+		if !ok {
+			return false
+		}
+		if cond.equals != "" && val != cond.equals {
+			return false
+		}
+		if cond.matches != nil && !cond.matches.MatchString(val) {
+			return false
+		}
+		if cond.olderThan == 0 && cond.newerThan == 0 {
+			continue
+		}
+		headGroup := rule.headGroup
+		if headGroup == "" {
+			headGroup = group
+		}
+		block, head, ok := r.blockAndHead(headGroup, val)
+		if !ok {
+			return false
+		}
+		// blocks ahead of the head are as new as it gets
+		age := lag(head, block)
+		if cond.olderThan > 0 && age <= cond.olderThan {
+			return false
+		}
+		if cond.newerThan > 0 && age > cond.newerThan {
+			return false
+		}
+	}
+	return true
+}
+
+// blockAndHead resolves a block number or tag against the consensus of the
+// group. Without consensus the head is unknown, and nothing is older or
+// newer than it.
+func (r *RoutingRules) blockAndHead(group string, val string) (uint64, uint64, bool) {
+	bg := r.groups[group]
+	if bg == nil || bg.Consensus == nil {
+		return 0, 0, false
+	}
+	head := uint64(bg.Consensus.GetLatestBlockNumber())
+	if head == 0 {
+		return 0, 0, false
+	}
+	switch val {
+	case "earliest":
+		return 0, head, true
+	case "latest", "pending":
+		return head, head, true
+	case "safe":
+		return uint64(bg.Consensus.GetSafeBlockNumber()), head, true
+	case "finalized":
+		return uint64(bg.Consensus.GetFinalizedBlockNumber()), head, true
+	}
+	block, err := hexutil.DecodeUint64(val)
+	if err != nil {
+		return 0, 0, false
+	}
+	return block, head, true
+}
+
+// paramAt returns the param at a path such as "0.fromBlock", indexing
+// arrays by position and objects by key, as a string.
+func paramAt(v interface{}, path []string) (string, bool) {
+	for _, key := range path {
+		switch val := v.(type) {
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(val) {
+				return "", false
+			}
+			v = val[i]
+		case map[string]interface{}:
+			var ok bool
+			if v, ok = val[key]; !ok {
+				return "", false
+			}
+		default:
+			return "", false
+		}
+	}
+	switch val := v.(type) {
+	case string:
+		return val, true
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(val), true
+	}
+	return "", false
+}
@@ -0,0 +1,78 @@
+package proxyd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoutingRules(t *testing.T) {
+	main := &BackendGroup{Name: "main"}
+	tracker := NewInMemoryConsensusTracker()
+	tracker.SetLatestBlockNumber(2_000_000)
+	tracker.SetFinalizedBlockNumber(1_999_900)
+	main.Consensus = NewConsensusPoller(main, WithAsyncHandler(NewNoopAsyncHandler()), WithTracker(tracker))
+	groups := map[string]*BackendGroup{
+		"main":    main,
+		"archive": {Name: "archive"},
+		"trace":   {Name: "trace"},
+		"other":   {Name: "other"},
+	}
+
+	rules, err := NewRoutingRules([]RoutingRuleConfig{
+		{
+			Method:       "eth_getLogs",
+			BackendGroup: "archive",
+			When:         []RoutingConditionConfig{{Param: "0.fromBlock", OlderThan: 1_000_000}},
+		},
+		{
+			Method:       "debug_traceBlockByNumber",
+			BackendGroup: "trace",
+			When: []RoutingConditionConfig{
+				{Param: "0", NewerThan: 128},
+				{Param: "1.tracer", Equals: "callTracer"},
+			},
+		},
+		{
+			Method:       "eth_call",
+			BackendGroup: "archive",
+			HeadGroup:    "main",
+			When:         []RoutingConditionConfig{{Param: "1", OlderThan: 128}},
+		},
+		{
+			Method:       "eth_getCode",
+			BackendGroup: "archive",
+			When:         []RoutingConditionConfig{{Param: "0", Matches: "^0x00"}},
+		},
+	}, groups)
+	require.NoError(t, err)
+
+	route := func(method, params, group string) string {
+		return rules.Route(&RPCReq{Method: method, Params: json.RawMessage(params)}, group)
+	}
+
+	require.Equal(t, "archive", route("eth_getLogs", `[{"fromBlock":"0x1"}]`, "main"))
+	require.Equal(t, "archive", route("eth_getLogs", `[{"fromBlock":"earliest","toBlock":"latest"}]`, "main"))
+	require.Equal(t, "main", route("eth_getLogs", `[{"fromBlock":"0x1e8480"}]`, "main"))
+	// missing params don't match
+	require.Equal(t, "main", route("eth_getLogs", `[{}]`, "main"))
+
+	require.Equal(t, "trace", route("debug_traceBlockByNumber", `["latest",{"tracer":"callTracer"}]`, "main"))
+	require.Equal(t, "trace", route("debug_traceBlockByNumber", `["finalized",{"tracer":"callTracer"}]`, "main"))
+	require.Equal(t, "main", route("debug_traceBlockByNumber", `["latest",{"tracer":"prestateTracer"}]`, "main"))
+	require.Equal(t, "main", route("debug_traceBlockByNumber", `["0x1",{"tracer":"callTracer"}]`, "main"))
+
+	// the head is only known for groups with consensus
+	require.Equal(t, "other", route("eth_getLogs", `[{"fromBlock":"0x1"}]`, "other"))
+	require.Equal(t, "archive", route("eth_call", `[{},"0x1"]`, "other"))
+
+	require.Equal(t, "archive", route("eth_getCode", `["0x00ab"]`, "main"))
+	require.Equal(t, "main", route("eth_getCode", `["0x01ab"]`, "main"))
+
+	// methods that aren't mapped stay unmapped
+	require.Equal(t, "", route("eth_getLogs", `[{"fromBlock":"0x1"}]`, ""))
+
+	_, err = NewRoutingRules([]RoutingRuleConfig{{Method: "eth_call", BackendGroup: "missing"}}, groups)
+	require.Error(t, err)
+}
@@ -11,6 +11,10 @@ type RPCReq struct {
 	Method  string          `json:"method"`
 	Params  json.RawMessage `json:"params"`
 	ID      json.RawMessage `json:"id"`
+
+	// renamedFrom is the method the client asked for, if a request rewrite
+	// rule renamed it
+	renamedFrom string
 }
 
 type RPCRes struct {
@@ -18,19 +22,23 @@ type RPCRes struct {
 	Result  interface{}
 	Error   *RPCErr
 	ID      json.RawMessage
+	// ServedBy is only set when a response rewriter injects it
+	ServedBy string `json:"-"`
 }
 
 type rpcResJSON struct {
-	JSONRPC string          `json:"jsonrpc"`
-	Result  interface{}     `json:"result,omitempty"`
-	Error   *RPCErr         `json:"error,omitempty"`
-	ID      json.RawMessage `json:"id"`
+	JSONRPC  string          `json:"jsonrpc"`
+	Result   interface{}     `json:"result,omitempty"`
+	Error    *RPCErr         `json:"error,omitempty"`
+	ID       json.RawMessage `json:"id"`
+	ServedBy string          `json:"servedBy,omitempty"`
 }
 
 type nullResultRPCRes struct {
-	JSONRPC string          `json:"jsonrpc"`
-	Result  interface{}     `json:"result"`
-	ID      json.RawMessage `json:"id"`
+	JSONRPC  string          `json:"jsonrpc"`
+	Result   interface{}     `json:"result"`
+	ID       json.RawMessage `json:"id"`
+	ServedBy string          `json:"servedBy,omitempty"`
 }
 
 func (r *RPCRes) IsError() bool {
@@ -40,17 +48,19 @@ func (r *RPCRes) IsError() bool {
 func (r *RPCRes) MarshalJSON() ([]byte, error) {
 	if r.Result == nil && r.Error == nil {
 		return json.Marshal(&nullResultRPCRes{
-			JSONRPC: r.JSONRPC,
-			Result:  nil,
-			ID:      r.ID,
+			JSONRPC:  r.JSONRPC,
+			Result:   nil,
+			ID:       r.ID,
+			ServedBy: r.ServedBy,
 		})
 	}
 
 	return json.Marshal(&rpcResJSON{
-		JSONRPC: r.JSONRPC,
-		Result:  r.Result,
-		Error:   r.Error,
-		ID:      r.ID,
+		JSONRPC:  r.JSONRPC,
+		Result:   r.Result,
+		Error:    r.Error,
+		ID:       r.ID,
+		ServedBy: r.ServedBy,
 	})
 }
 
@@ -125,6 +135,10 @@ func ValidateRPCReq(req *RPCReq) error {
 		return ErrInvalidRequest("no method specified")
 	}
 
+	if !isValidMethodName(req.Method) {
+		return ErrInvalidRequest("invalid method name")
+	}
+
 	if !IsValidID(req.ID) {
 		return ErrInvalidRequest("invalid ID")
 	}
@@ -0,0 +1,49 @@
+package proxyd
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	maxMethodNameLen = 128
+	maxLogValueLen   = 256
+)
+
+// isValidMethodName rejects method names no client sends legitimately, so
+// they can't flood the logs or the metrics. Unknown methods with sane names
+// are still counted as MethodUnknown.
+func isValidMethodName(method string) bool {
+	if method == "" || len(method) > maxMethodNameLen {
+		return false
+	}
+	for _, c := range method {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '_', c == '-', c == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// sanitizeLogValue makes a request derived string safe to log: it's bounded
+// to maxLogValueLen bytes, invalid UTF-8 is replaced, and control and
+// non-printable characters are escaped so they can't forge log lines.
+func sanitizeLogValue(s string) string {
+	truncated := len(s) > maxLogValueLen
+	if truncated {
+		s = s[:maxLogValueLen]
+	}
+	s = strings.ToValidUTF8(s, string(utf8.RuneError))
+	if strings.IndexFunc(s, func(r rune) bool { return !strconv.IsPrint(r) }) >= 0 {
+		quoted := strconv.Quote(s)
+		s = quoted[1 : len(quoted)-1]
+	}
+	if truncated {
+		s += "..."
+	}
+	return s
+}
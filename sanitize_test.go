@@ -0,0 +1,33 @@
+package proxyd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsValidMethodName(t *testing.T) {
+	for _, method := range []string{"eth_chainId", "proxyd_healthz", "rollup.getInfo", "x-custom"} {
+		require.True(t, isValidMethodName(method), method)
+	}
+	for _, method := range []string{"", "eth_chainId\n", "eth chainId", "eth_chainId\"", "é", strings.Repeat("a", maxMethodNameLen+1)} {
+		require.False(t, isValidMethodName(method), method)
+	}
+
+	err := ValidateRPCReq(&RPCReq{JSONRPC: JSONRPCVersion, Method: "eth_call\nINFO forged", ID: []byte("1")})
+	require.Equal(t, ErrInvalidRequest("invalid method name"), err)
+}
+
+func TestSanitizeLogValue(t *testing.T) {
+	require.Equal(t, "curl/8.0", sanitizeLogValue("curl/8.0"))
+	require.Equal(t, `a\nlvl=info msg=forged`, sanitizeLogValue("a\nlvl=info msg=forged"))
+	require.Equal(t, "a�b", sanitizeLogValue("a\xffb"))
+	require.Equal(t, `\x1b[31mred`, sanitizeLogValue("\x1b[31mred"))
+
+	long := sanitizeLogValue(strings.Repeat("a", maxLogValueLen) + "b")
+	require.Equal(t, strings.Repeat("a", maxLogValueLen)+"...", long)
+	// a rune cut in half is replaced
+	cut := sanitizeLogValue(strings.Repeat("a", maxLogValueLen-1) + "é")
+	require.Equal(t, strings.Repeat("a", maxLogValueLen-1)+"�...", cut)
+}
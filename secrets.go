@@ -0,0 +1,382 @@
+package proxyd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	// SecretPrefixVault references a field of a Vault secret, e.g.
+	// vault:secret/data/proxyd#infura_key. Both KV v1 and v2 are supported.
+	SecretPrefixVault = "vault:"
+	// SecretPrefixAWS references an AWS Secrets Manager secret, e.g.
+	// aws-sm:prod/proxyd. A #key suffix selects a key of a JSON secret.
+	SecretPrefixAWS = "aws-sm:"
+	// SecretPrefixFile references a file whose contents are the secret, e.g. a
+	// mounted Kubernetes secret.
+	SecretPrefixFile = "file:"
+
+	defaultSecretRefreshInterval = 5 * time.Minute
+	secretFetchTimeout           = 10 * time.Second
+)
+
+// SecretStore resolves config values that reference environment variables,
+// files, Vault or AWS Secrets Manager. Resolved secrets are refreshed
+// periodically, and watchers are notified when a value changes.
+type SecretStore struct {
+	vaultAddr      string
+	vaultToken     string
+	vaultNamespace string
+	awsRegion      string
+	refresh        time.Duration
+	httpClient     *http.Client
+
+	awsOnce   sync.Once
+	awsClient *secretsmanager.Client
+	awsErr    error
+
+	mu       sync.Mutex
+	values   map[string]string
+	watchers map[string][]func(string)
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+func NewSecretStore(cfg SecretsConfig) (*SecretStore, error) {
+	s := &SecretStore{
+		vaultAddr:      os.Getenv("VAULT_ADDR"),
+		vaultToken:     os.Getenv("VAULT_TOKEN"),
+		vaultNamespace: cfg.Vault.Namespace,
+		awsRegion:      cfg.AWS.Region,
+		refresh:        defaultSecretRefreshInterval,
+		httpClient:     &http.Client{Timeout: secretFetchTimeout},
+		values:         make(map[string]string),
+		watchers:       make(map[string][]func(string)),
+		stop:           make(chan struct{}),
+	}
+	if cfg.Vault.Address != "" {
+		addr, err := ReadFromEnvOrConfig(cfg.Vault.Address)
+		if err != nil {
+			return nil, err
+		}
+		s.vaultAddr = addr
+	}
+	if cfg.Vault.Token != "" {
+		token, err := ReadFromEnvOrConfig(cfg.Vault.Token)
+		if err != nil {
+			return nil, err
+		}
+		s.vaultToken = token
+	}
+	if cfg.RefreshInterval > 0 {
+		s.refresh = time.Duration(cfg.RefreshInterval)
+	}
+	return s, nil
+}
+
+// IsSecretRef returns true if value references a secret that is fetched, and
+// refreshed, by a SecretStore.
+func IsSecretRef(value string) bool {
+	return strings.HasPrefix(value, SecretPrefixVault) ||
+		strings.HasPrefix(value, SecretPrefixAWS) ||
+		strings.HasPrefix(value, SecretPrefixFile)
+}
+
+// Resolve returns the value of a config field. Secret references are fetched
+// and cached. Anything else is resolved with ReadFromEnvOrConfig.
+func (s *SecretStore) Resolve(value string) (string, error) {
+	if !IsSecretRef(value) {
+		return ReadFromEnvOrConfig(value)
+	}
+
+	s.mu.Lock()
+	cached, ok := s.values[value]
+	s.mu.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	secret, err := s.fetch(value)
+	if err != nil {
+		return "", err
+	}
+	s.mu.Lock()
+	s.values[value] = secret
+	s.mu.Unlock()
+	return secret, nil
+}
+
+// Watch calls fn with the new value whenever the secret referenced by value
+// changes. It is a no-op for values that aren't secret references.
+func (s *SecretStore) Watch(value string, fn func(string)) {
+	if !IsSecretRef(value) {
+		return
+	}
+	s.mu.Lock()
+	s.watchers[value] = append(s.watchers[value], fn)
+	s.mu.Unlock()
+}
+
+// checkUnrefreshedSecrets rejects secret references in fields that aren't
+// resolved from the secret stores, which would otherwise be used as the
+// literal reference, or that are only read at startup, which would silently
+// keep a stale value when the secret is rotated. Environment variables can be
+// used for the Redis credentials and files for TLS keys instead.
+func checkUnrefreshedSecrets(config *Config) error {
+	type field struct{ name, value string }
+	// the Redis clients aren't recreated when their credentials change
+	redisFields := func(prefix string, cfg RedisConfig) []field {
+		return []field{
+			{prefix + ".url", cfg.URL},
+			{prefix + ".read_url", cfg.ReadURL},
+			{prefix + ".sentinel_password", cfg.SentinelPassword},
+			{prefix + ".tls_key_file", cfg.TLSKeyFile},
+			{prefix + ".tls_cert_file", cfg.TLSCertFile},
+			{prefix + ".tls_ca_file", cfg.TLSCAFile},
+		}
+	}
+	fields := redisFields("redis", config.Redis)
+	fields = append(fields,
+		field{"server.tls_key_file", config.Server.TLSKeyFile},
+		field{"server.tls_cert_file", config.Server.TLSCertFile},
+	)
+	for _, name := range sortedKeys(config.BackendGroups) {
+		fields = append(fields, redisFields("backend_groups."+name+".consensus_ha_redis", config.BackendGroups[name].ConsensusHARedis)...)
+	}
+	for _, name := range sortedKeys(config.Backends) {
+		cfg := config.Backends[name]
+		fields = append(fields,
+			field{"backends." + name + ".client_key_file", cfg.ClientKeyFile},
+			field{"backends." + name + ".client_cert_file", cfg.ClientCertFile},
+			field{"backends." + name + ".ca_file", cfg.CAFile},
+		)
+	}
+	for _, name := range sortedKeys(config.VirtualHosts) {
+		cfg := config.VirtualHosts[name]
+		fields = append(fields,
+			field{"virtual_hosts." + name + ".tls_key_file", cfg.TLSKeyFile},
+			field{"virtual_hosts." + name + ".tls_cert_file", cfg.TLSCertFile},
+		)
+	}
+	for _, name := range sortedKeys(config.Tenants) {
+		for i, key := range config.Tenants[name].APIKeys {
+			fields = append(fields, field{fmt.Sprintf("tenants.%s.api_keys[%d]", name, i), key})
+		}
+	}
+	for _, alias := range sortedKeys(config.AuthPolicies) {
+		headers := config.AuthPolicies[alias].Headers
+		for _, header := range sortedKeys(headers) {
+			fields = append(fields, field{"auth_policies." + alias + ".headers." + header, headers[header]})
+		}
+	}
+
+	for _, f := range fields {
+		if IsSecretRef(f.value) {
+			return fmt.Errorf("%s can't reference a secret store secret, as it isn't resolved from the secret stores or refreshed", f.name)
+		}
+	}
+	return nil
+}
+
+// secretValue holds a resolved secret that is replaced when the SecretStore
+// refreshes it, e.g. with Watch(ref, v.Store).
+type secretValue struct {
+	v atomic.Pointer[string]
+}
+
+func (v *secretValue) Load() string {
+	if p := v.v.Load(); p != nil {
+		return *p
+	}
+	return ""
+}
+
+func (v *secretValue) Store(value string) {
+	v.v.Store(&value)
+}
+
+// Start refreshes resolved secrets in the background until Stop is called.
+func (s *SecretStore) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(s.refresh)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				s.Refresh()
+			}
+		}
+	}()
+}
+
+func (s *SecretStore) Stop() {
+	close(s.stop)
+	s.wg.Wait()
+}
+
+// Refresh fetches every resolved secret again. Secrets that can't be fetched
+// keep their last value.
+func (s *SecretStore) Refresh() {
+	s.mu.Lock()
+	refs := make([]string, 0, len(s.values))
+	for ref := range s.values {
+		refs = append(refs, ref)
+	}
+	s.mu.Unlock()
+
+	for _, ref := range refs {
+		secret, err := s.fetch(ref)
+		if err != nil {
+			log.Error("error refreshing secret", "ref", ref, "err", err)
+			RecordSecretRefresh(secretProvider(ref), "error")
+			continue
+		}
+		RecordSecretRefresh(secretProvider(ref), "ok")
+
+		s.mu.Lock()
+		changed := s.values[ref] != secret
+		s.values[ref] = secret
+		watchers := s.watchers[ref]
+		s.mu.Unlock()
+		if !changed {
+			continue
+		}
+		log.Info("secret changed", "ref", ref)
+		for _, fn := range watchers {
+			fn(secret)
+		}
+	}
+}
+
+func (s *SecretStore) fetch(ref string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), secretFetchTimeout)
+	defer cancel()
+
+	switch {
+	case strings.HasPrefix(ref, SecretPrefixVault):
+		return s.fetchVault(ctx, strings.TrimPrefix(ref, SecretPrefixVault))
+	case strings.HasPrefix(ref, SecretPrefixAWS):
+		return s.fetchAWS(ctx, strings.TrimPrefix(ref, SecretPrefixAWS))
+	default:
+		data, err := os.ReadFile(strings.TrimPrefix(ref, SecretPrefixFile))
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+}
+
+func (s *SecretStore) fetchVault(ctx context.Context, ref string) (string, error) {
+	path, field, ok := strings.Cut(ref, "#")
+	if !ok || field == "" {
+		return "", fmt.Errorf("vault secret %s must select a field with #field", ref)
+	}
+	if s.vaultAddr == "" {
+		return "", fmt.Errorf("vault address is not configured")
+	}
+
+	url := strings.TrimSuffix(s.vaultAddr, "/") + "/v1/" + strings.TrimPrefix(path, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", s.vaultToken)
+	if s.vaultNamespace != "" {
+		req.Header.Set("X-Vault-Namespace", s.vaultNamespace)
+	}
+	res, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", wrapErr(err, "error fetching vault secret")
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault returned status %d for %s", res.StatusCode, path)
+	}
+
+	var body struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return "", wrapErr(err, "error decoding vault secret")
+	}
+	data := body.Data
+	// KV v2 nests the secret under data.data
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		if _, hasMeta := data["metadata"]; hasMeta {
+			data = nested
+		}
+	}
+	value, ok := data[field].(string)
+	if !ok {
+		return "", fmt.Errorf("vault secret %s has no string field %s", path, field)
+	}
+	return value, nil
+}
+
+func (s *SecretStore) fetchAWS(ctx context.Context, ref string) (string, error) {
+	s.awsOnce.Do(func() {
+		var opts []func(*awsconfig.LoadOptions) error
+		if s.awsRegion != "" {
+			opts = append(opts, awsconfig.WithRegion(s.awsRegion))
+		}
+		cfg, err := awsconfig.LoadDefaultConfig(context.Background(), opts...)
+		if err != nil {
+			s.awsErr = wrapErr(err, "error loading aws config")
+			return
+		}
+		s.awsClient = secretsmanager.NewFromConfig(cfg)
+	})
+	if s.awsErr != nil {
+		return "", s.awsErr
+	}
+
+	id, key, _ := strings.Cut(ref, "#")
+	out, err := s.awsClient.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(id),
+	})
+	if err != nil {
+		return "", wrapErr(err, "error fetching aws secret")
+	}
+	secret := aws.ToString(out.SecretString)
+	if key == "" {
+		return secret, nil
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(secret), &fields); err != nil {
+		return "", fmt.Errorf("aws secret %s is not a JSON object", id)
+	}
+	value, ok := fields[key].(string)
+	if !ok {
+		return "", fmt.Errorf("aws secret %s has no string key %s", id, key)
+	}
+	return value, nil
+}
+
+func secretProvider(ref string) string {
+	switch {
+	case strings.HasPrefix(ref, SecretPrefixVault):
+		return "vault"
+	case strings.HasPrefix(ref, SecretPrefixAWS):
+		return "aws"
+	default:
+		return "file"
+	}
+}
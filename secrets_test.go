@@ -0,0 +1,195 @@
+package proxyd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecretStoreVault(t *testing.T) {
+	var version atomic.Int32
+	version.Store(1)
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/proxyd":
+			if version.Load() == 1 {
+				_, _ = w.Write([]byte(`{"data":{"data":{"infura_key":"key1"},"metadata":{"version":1}}}`))
+			} else {
+				_, _ = w.Write([]byte(`{"data":{"data":{"infura_key":"key2"},"metadata":{"version":2}}}`))
+			}
+		case "/v1/kv/proxyd":
+			_, _ = w.Write([]byte(`{"data":{"redis_password":"hunter2"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer vault.Close()
+
+	s, err := NewSecretStore(SecretsConfig{Vault: VaultConfig{Address: vault.URL, Token: "token"}})
+	require.NoError(t, err)
+
+	value, err := s.Resolve("vault:secret/data/proxyd#infura_key")
+	require.NoError(t, err)
+	require.Equal(t, "key1", value)
+	value, err = s.Resolve("vault:kv/proxyd#redis_password")
+	require.NoError(t, err)
+	require.Equal(t, "hunter2", value)
+
+	_, err = s.Resolve("vault:secret/data/proxyd#missing")
+	require.Error(t, err)
+	_, err = s.Resolve("vault:secret/data/proxyd")
+	require.Error(t, err)
+	_, err = s.Resolve("vault:secret/data/other#infura_key")
+	require.Error(t, err)
+
+	var updates []string
+	s.Watch("vault:secret/data/proxyd#infura_key", func(v string) {
+		updates = append(updates, v)
+	})
+	s.Refresh()
+	require.Empty(t, updates)
+	version.Store(2)
+	s.Refresh()
+	require.Equal(t, []string{"key2"}, updates)
+	value, err = s.Resolve("vault:secret/data/proxyd#infura_key")
+	require.NoError(t, err)
+	require.Equal(t, "key2", value)
+
+	// a failed refresh keeps the last value
+	vault.Close()
+	s.Refresh()
+	value, err = s.Resolve("vault:secret/data/proxyd#infura_key")
+	require.NoError(t, err)
+	require.Equal(t, "key2", value)
+}
+
+func TestSecretStoreFileAndEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "password")
+	require.NoError(t, os.WriteFile(path, []byte("secret1\n"), 0o600))
+	t.Setenv("SECRET_STORE_TEST_VALUE", "from-env")
+
+	s, err := NewSecretStore(SecretsConfig{})
+	require.NoError(t, err)
+
+	value, err := s.Resolve("file:" + path)
+	require.NoError(t, err)
+	require.Equal(t, "secret1", value)
+	value, err = s.Resolve("$SECRET_STORE_TEST_VALUE")
+	require.NoError(t, err)
+	require.Equal(t, "from-env", value)
+	value, err = s.Resolve("plain")
+	require.NoError(t, err)
+	require.Equal(t, "plain", value)
+
+	back := NewBackend("test", "http://localhost:1", "", nil, WithBasicAuth("user", "secret1"))
+	s.Watch("file:"+path, back.SetAuthPassword)
+	require.NoError(t, os.WriteFile(path, []byte("secret2\n"), 0o600))
+	s.Refresh()
+	require.Equal(t, "secret2", back.authPassword)
+}
+
+func TestSecretRefresh(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, value string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(value), 0o600))
+		return SecretPrefixFile + path
+	}
+	hmacRef := write("hmac", "hmac1")
+	ipRef := write("ip", "ip1")
+	signingRef := write("signing", "0x4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318")
+	tokenRef := write("token", "token1")
+	urlRef := write("url", "http://localhost:1/key1")
+
+	s, err := NewSecretStore(SecretsConfig{})
+	require.NoError(t, err)
+	hmacAuth, err := NewHMACAuth(HMACAuthConfig{Keys: map[string]string{"indexer": hmacRef}}, s)
+	require.NoError(t, err)
+	hasher, err := NewIPHasher(IPPrivacyConfig{Enabled: true, Secret: ipRef}, s)
+	require.NoError(t, err)
+	signer, err := NewResponseSigner(ResponseSigningConfig{Enabled: true, PrivateKey: signingRef}, s)
+	require.NoError(t, err)
+	token, err := s.Resolve(tokenRef)
+	require.NoError(t, err)
+	exemptions, err := NewRateLimitExemptions(RateLimitExemptionsConfig{AdminToken: token})
+	require.NoError(t, err)
+	s.Watch(tokenRef, exemptions.adminToken.Store)
+	rpcURL, err := s.Resolve(urlRef)
+	require.NoError(t, err)
+	back := NewBackend("test", rpcURL, "", nil)
+	s.Watch(urlRef, back.SetRPCURL)
+
+	hash := hasher.Hash("192.0.2.1")
+	address := signer.key.Load().address
+
+	write("hmac", "hmac2")
+	write("ip", "ip2")
+	write("signing", "0x8f2a55949038a9610f50fb23b5883af3b4ecb3c3bb792cbcefbd1542c692be63")
+	write("token", "token2")
+	write("url", "http://localhost:1/key2")
+	s.Refresh()
+
+	body := []byte(`{}`)
+	_, err = hmacAuth.Verify(SignHMAC("indexer", []byte("hmac2"), time.Now(), body), body)
+	require.NoError(t, err)
+	_, err = hmacAuth.Verify(SignHMAC("indexer", []byte("hmac1"), time.Now(), body), body)
+	require.Error(t, err)
+	require.NotEqual(t, hash, hasher.Hash("192.0.2.1"))
+	require.NotEqual(t, address, signer.key.Load().address)
+	require.Equal(t, "token2", exemptions.adminToken.Load())
+	require.Equal(t, "http://localhost:1/key2", back.rpcURL)
+
+	// invalid refreshed values keep the last valid one
+	address = signer.key.Load().address
+	write("signing", "not a key")
+	s.Refresh()
+	require.Equal(t, address, signer.key.Load().address)
+}
+
+func TestServerReplaceAuthSecret(t *testing.T) {
+	s := &Server{authenticatedPaths: map[string]string{"secret1": "alice"}}
+	s.ReplaceAuthSecret("secret1", "secret2")
+	alias, _, _, err := s.authenticate(context.Background(), "secret2")
+	require.NoError(t, err)
+	require.Equal(t, "alice", alias)
+	alias, _, _, err = s.authenticate(context.Background(), "secret1")
+	require.NoError(t, err)
+	require.Empty(t, alias)
+}
+
+func TestCheckUnrefreshedSecrets(t *testing.T) {
+	require.NoError(t, checkUnrefreshedSecrets(&Config{Redis: RedisConfig{URL: "$REDIS_URL"}}))
+	err := checkUnrefreshedSecrets(&Config{Redis: RedisConfig{URL: "vault:secret/data/proxyd#redis_url"}})
+	require.ErrorContains(t, err, "redis.url")
+
+	// fields that aren't resolved from the secret stores fail too
+	for name, config := range map[string]*Config{
+		"redis.sentinel_password": {Redis: RedisConfig{SentinelPassword: "aws-sm:prod/proxyd#sentinel"}},
+		"backend_groups.main.consensus_ha_redis.url": {BackendGroups: BackendGroupsConfig{
+			"main": {ConsensusHARedis: RedisConfig{URL: "vault:secret/data/proxyd#redis_url"}},
+		}},
+		"server.tls_key_file":             {Server: ServerConfig{TLSKeyFile: "vault:secret/data/proxyd#tls_key"}},
+		"backends.infura.client_key_file": {Backends: BackendsConfig{"infura": {ClientKeyFile: "file:/run/secrets/key"}}},
+		"tenants.acme.api_keys[1]":        {Tenants: map[string]TenantConfig{"acme": {APIKeys: []string{"key", "vault:secret/data/proxyd#acme"}}}},
+		"auth_policies.alice.headers.Authorization": {AuthPolicies: map[string]AuthPolicyConfig{
+			"alice": {Headers: map[string]string{"Authorization": "aws-sm:prod/proxyd#alice"}},
+		}},
+	} {
+		require.ErrorContains(t, checkUnrefreshedSecrets(config), name+" can't reference", name)
+	}
+	require.NoError(t, checkUnrefreshedSecrets(&Config{
+		Tenants:      map[string]TenantConfig{"acme": {APIKeys: []string{"key"}}},
+		AuthPolicies: map[string]AuthPolicyConfig{"alice": {Headers: map[string]string{"Authorization": "Bearer x"}}},
+	}))
+}
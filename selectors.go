@@ -0,0 +1,201 @@
+package proxyd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	selectorUnknown = "unknown"
+	// selectorGlobalKey is the rate limit key of selector rules, whose limits
+	// are shared by all clients.
+	selectorGlobalKey = "global"
+)
+
+// Selectors labels eth_call, eth_estimateGas and eth_sendRawTransaction
+// requests with the function their calldata calls, by its 4-byte selector,
+// and applies rules to the requests calling some functions: routing them to
+// another backend group, or limiting how often all clients together may call
+// them, e.g. to throttle spam mints. Selectors are named by a file and the
+// config, and requests are counted by selector name, unnamed selectors being
+// counted as unknown.
+type Selectors struct {
+	names map[string]string
+	rules map[string]*selectorRule
+}
+
+type selectorRule struct {
+	backendGroup string
+	lim          FrontendRateLimiter
+}
+
+// NewSelectors returns nil if no selectors are named.
+func NewSelectors(cfg SelectorsConfig, groups map[string]*BackendGroup, limiterFactory limiterFactoryFunc) (*Selectors, error) {
+	if cfg.File == "" && len(cfg.Names) == 0 {
+		return nil, nil
+	}
+	s := &Selectors{
+		names: make(map[string]string),
+		rules: make(map[string]*selectorRule, len(cfg.Rules)),
+	}
+	if cfg.File != "" {
+		data, err := os.ReadFile(cfg.File)
+		if err != nil {
+			return nil, wrapErr(err, "error reading selectors file")
+		}
+		if err := s.addNames(data); err != nil {
+			return nil, fmt.Errorf("invalid selectors file %s: %w", cfg.File, err)
+		}
+	}
+	for selector, name := range cfg.Names {
+		if err := s.addName(selector, name); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, key := range sortedKeys(cfg.Rules) {
+		rc := cfg.Rules[key]
+		name := key
+		if named, ok := s.names[strings.ToLower(key)]; ok {
+			name = named
+		}
+		if !s.named(name) {
+			return nil, fmt.Errorf("selector rule %s matches no named selector", key)
+		}
+		if _, ok := s.rules[name]; ok {
+			return nil, fmt.Errorf("selector %s has more than one rule", name)
+		}
+		if rc.BackendGroup != "" && groups[rc.BackendGroup] == nil {
+			return nil, fmt.Errorf("selector rule %s has undefined backend group %s", key, rc.BackendGroup)
+		}
+		rule := &selectorRule{backendGroup: rc.BackendGroup}
+		if rc.RateLimit > 0 {
+			rule.lim = limiterFactory(time.Duration(rc.RateLimitInterval), rc.RateLimit, "selector_"+name)
+		}
+		s.rules[name] = rule
+	}
+	return s, nil
+}
+
+// addNames adds the selectors of a file with a selector and its name, such
+// as a function signature, per line, e.g.
+//
+//	0xa9059cbb transfer(address,uint256)
+//
+// Empty lines and lines starting with # are skipped.
+func (s *Selectors) addNames(data []byte) error {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		selector, name, ok := strings.Cut(text, " ")
+		if !ok {
+			return fmt.Errorf("line %d: expected a selector and a name", line)
+		}
+		if err := s.addName(selector, strings.TrimSpace(name)); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+	return scanner.Err()
+}
+
+func (s *Selectors) addName(selector, name string) error {
+	b, err := hexutil.Decode(selector)
+	if err != nil || len(b) != 4 {
+		return fmt.Errorf("invalid selector %s, must be 4 hex bytes", selector)
+	}
+	if name == "" || name == selectorUnknown {
+		return fmt.Errorf("selector %s has invalid name %q", selector, name)
+	}
+	s.names[hexutil.Encode(b)] = name
+	return nil
+}
+
+func (s *Selectors) named(name string) bool {
+	for _, n := range s.names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply counts a request by the selector it calls, and returns the backend
+// group of the selector's rule, if any, or ErrOverRateLimit if the rule's
+// rate limit is exceeded. tx is the transaction of eth_sendRawTransaction
+// requests.
+func (s *Selectors) Apply(ctx context.Context, req *RPCReq, tx *types.Transaction, group string) (string, error) {
+	if s == nil {
+		return group, nil
+	}
+	var data []byte
+	switch req.Method {
+	case "eth_sendRawTransaction", "eth_sendRawTransactionConditional":
+		if tx == nil {
+			return group, nil
+		}
+		data = tx.Data()
+	case "eth_call", "eth_estimateGas":
+		data = callData(req)
+	default:
+		return group, nil
+	}
+	if len(data) < 4 {
+		return group, nil
+	}
+	name, ok := s.names[hexutil.Encode(data[:4])]
+	if !ok {
+		RecordSelectorRequest(req.Method, selectorUnknown)
+		return group, nil
+	}
+	RecordSelectorRequest(req.Method, name)
+
+	rule := s.rules[name]
+	if rule == nil {
+		return group, nil
+	}
+	if rule.lim != nil {
+		ok, err := rule.lim.Take(ctx, selectorGlobalKey)
+		if err != nil {
+			log.Warn("error taking selector rate limit", "selector", name, "err", err)
+		} else if !ok {
+			RecordSelectorRateLimited(name)
+			return group, ErrOverRateLimit
+		}
+	}
+	if rule.backendGroup != "" {
+		group = rule.backendGroup
+	}
+	return group, nil
+}
+
+// callData returns the calldata of an eth_call or eth_estimateGas request.
+func callData(req *RPCReq) []byte {
+	var params []json.RawMessage
+	if err := json.Unmarshal(req.Params, &params); err != nil || len(params) == 0 {
+		return nil
+	}
+	var call struct {
+		Data  hexutil.Bytes `json:"data"`
+		Input hexutil.Bytes `json:"input"`
+	}
+	if err := json.Unmarshal(params[0], &call); err != nil {
+		return nil
+	}
+	if len(call.Input) > 0 {
+		return call.Input
+	}
+	return call.Data
+}
@@ -0,0 +1,75 @@
+package proxyd
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelectors(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "selectors.txt")
+	require.NoError(t, os.WriteFile(file, []byte("# ERC-20\n0xa9059cbb transfer(address,uint256)\n\n0x1249C58B mint()\n"), 0o644))
+	groups := map[string]*BackendGroup{"main": {Name: "main"}, "spam": {Name: "spam"}}
+	limiterFactory := func(dur time.Duration, max int, prefix string) FrontendRateLimiter {
+		return NewMemoryFrontendRateLimit(dur, max)
+	}
+
+	s, err := NewSelectors(SelectorsConfig{
+		File:  file,
+		Names: map[string]string{"0x095ea7b3": "approve"},
+		Rules: map[string]SelectorRuleConfig{
+			"mint()":     {BackendGroup: "spam", RateLimit: 1, RateLimitInterval: TOMLDuration(time.Minute)},
+			"0x095ea7b3": {BackendGroup: "spam"},
+		},
+	}, groups, limiterFactory)
+	require.NoError(t, err)
+
+	call := func(method, data string) *RPCReq {
+		return &RPCReq{Method: method, Params: json.RawMessage(`[{"to":"0x5FbDB2315678afecb367f032d93F642f64180aa3","input":"` + data + `"}]`)}
+	}
+	ctx := context.Background()
+
+	group, err := s.Apply(ctx, call("eth_call", "0xa9059cbb00"), nil, "main")
+	require.NoError(t, err)
+	require.Equal(t, "main", group)
+	group, err = s.Apply(ctx, call("eth_estimateGas", "0x095ea7b3"), nil, "main")
+	require.NoError(t, err)
+	require.Equal(t, "spam", group)
+	group, err = s.Apply(ctx, call("eth_getBalance", "0x095ea7b3"), nil, "main")
+	require.NoError(t, err)
+	require.Equal(t, "main", group)
+
+	// transactions are matched by their data, and limits are shared by all
+	// clients
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	to := common.HexToAddress("0x5FbDB2315678afecb367f032d93F642f64180aa3")
+	tx, err := types.SignNewTx(key, types.LatestSignerForChainID(big.NewInt(10)), &types.DynamicFeeTx{ChainID: big.NewInt(10), Gas: 21000, To: &to, Data: common.FromHex("0x1249c58b")})
+	require.NoError(t, err)
+	send := &RPCReq{Method: "eth_sendRawTransaction"}
+	group, err = s.Apply(ctx, send, tx, "main")
+	require.NoError(t, err)
+	require.Equal(t, "spam", group)
+	_, err = s.Apply(ctx, send, tx, "main")
+	require.ErrorIs(t, err, ErrOverRateLimit)
+
+	// rules must name a selector
+	_, err = NewSelectors(SelectorsConfig{File: file, Rules: map[string]SelectorRuleConfig{"burn()": {}}}, groups, limiterFactory)
+	require.Error(t, err)
+	_, err = NewSelectors(SelectorsConfig{Names: map[string]string{"0x1234": "short"}}, groups, limiterFactory)
+	require.Error(t, err)
+
+	var disabled *Selectors
+	group, err = disabled.Apply(ctx, send, tx, "main")
+	require.NoError(t, err)
+	require.Equal(t, "main", group)
+}
@@ -0,0 +1,271 @@
+package proxyd
+
+import (
+	"context"
+	"math"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	senderReputationRedisKey    = "sender_reputation"
+	senderReputationMemoryLimit = 100000
+	defaultReputationHalfLife   = time.Hour
+	defaultReputationMinScore   = 0.3
+)
+
+// Reputation events and how much they count towards the good or bad side of
+// a sender's score. Spam is weighed heavier than failures, which may be
+// honest mistakes.
+const (
+	ReputationEventAccepted    = "accepted"
+	ReputationEventFailed      = "failed"
+	ReputationEventSpam        = "spam"
+	ReputationEventRateLimited = "rate_limited"
+)
+
+var reputationEventWeights = map[string]struct{ good, bad float64 }{
+	ReputationEventAccepted:    {good: 1},
+	ReputationEventFailed:      {bad: 1},
+	ReputationEventSpam:        {bad: 2},
+	ReputationEventRateLimited: {bad: 1},
+}
+
+// recordReputationScript decays a sender's counts to now and adds the
+// event's weights. Floats are returned as strings, which Redis would
+// otherwise truncate.
+var recordReputationScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local vals = redis.call('HMGET', KEYS[1], 'good', 'bad', 'ts')
+local good = tonumber(vals[1]) or 0
+local bad = tonumber(vals[2]) or 0
+local ts = tonumber(vals[3]) or now
+local decay = math.pow(0.5, math.max(now - ts, 0) / tonumber(ARGV[2]))
+good = good * decay + tonumber(ARGV[3])
+bad = bad * decay + tonumber(ARGV[4])
+redis.call('HMSET', KEYS[1], 'good', tostring(good), 'bad', tostring(bad), 'ts', ARGV[1])
+redis.call('PEXPIRE', KEYS[1], ARGV[5])
+return {tostring(good), tostring(bad)}
+`)
+
+// reputation is a sender's decayed good and bad counts as of ts.
+type reputation struct {
+	good, bad float64
+	ts        time.Time
+}
+
+func (r reputation) decayed(now time.Time, halfLife time.Duration) reputation {
+	if r.ts.IsZero() || !now.After(r.ts) {
+		return reputation{good: r.good, bad: r.bad, ts: now}
+	}
+	decay := math.Pow(0.5, float64(now.Sub(r.ts))/float64(halfLife))
+	return reputation{good: r.good * decay, bad: r.bad * decay, ts: now}
+}
+
+// score is between 0 and 1, starting at 0.5 for unknown senders.
+func (r reputation) score() float64 {
+	return (r.good + 1) / (r.good + r.bad + 2)
+}
+
+// SenderReputation scores transaction senders by how their submissions fare:
+// accepted by the backends, rejected by them, rejected by proxyd as spam, or
+// over the sender rate limit. Events count less the older they are, halving
+// every HalfLife, so senders recover. Senders scoring below MinScore are
+// limited by LowScoreRateLimit, routed to LowScoreBackendGroup, and never get
+// the fast submission priority of their preferences or query string. Scores
+// are shared in Redis if configured.
+type SenderReputation struct {
+	halfLife     time.Duration
+	minScore     float64
+	backendGroup string
+	lim          FrontendRateLimiter
+	now          func() time.Time
+
+	redisClient redis.UniversalClient
+	prefix      string
+
+	mu    sync.Mutex
+	local *lru.Cache
+}
+
+// NewSenderReputation returns nil if reputation scoring is disabled.
+func NewSenderReputation(cfg SenderReputationConfig, redisClient redis.UniversalClient, namespace string, limiterFactory limiterFactoryFunc) *SenderReputation {
+	if !cfg.Enabled {
+		return nil
+	}
+	r := &SenderReputation{
+		halfLife:     time.Duration(cfg.HalfLife),
+		minScore:     cfg.MinScore,
+		backendGroup: cfg.LowScoreBackendGroup,
+		now:          time.Now,
+		redisClient:  redisClient,
+		prefix:       senderReputationRedisKey,
+	}
+	if namespace != "" {
+		r.prefix = namespace + ":" + senderReputationRedisKey
+	}
+	if r.halfLife == 0 {
+		r.halfLife = defaultReputationHalfLife
+	}
+	if r.minScore == 0 {
+		r.minScore = defaultReputationMinScore
+	}
+	if cfg.LowScoreRateLimit.Limit > 0 {
+		r.lim = limiterFactory(time.Duration(cfg.LowScoreRateLimit.Interval), cfg.LowScoreRateLimit.Limit, "low_reputation_senders")
+	}
+	if redisClient == nil {
+		r.local, _ = lru.New(senderReputationMemoryLimit)
+	}
+	return r
+}
+
+// ttl is how long a sender is remembered without events, by when its counts
+// have decayed to a thousandth.
+func (r *SenderReputation) ttl() time.Duration {
+	return 10 * r.halfLife
+}
+
+// Record counts an event for the sender of tx.
+func (r *SenderReputation) Record(ctx context.Context, tx *types.Transaction, event string) {
+	if r == nil || tx == nil {
+		return
+	}
+	sender, err := txSender(tx)
+	if err != nil {
+		return
+	}
+	if err := r.record(ctx, sender, event); err != nil {
+		log.Warn("error recording sender reputation", "req_id", GetReqID(ctx), "err", err)
+	}
+}
+
+func (r *SenderReputation) record(ctx context.Context, sender common.Address, event string) error {
+	weights := reputationEventWeights[event]
+	RecordSenderReputationEvent(event)
+	now := r.now()
+	if r.redisClient != nil {
+		key := r.prefix + ":" + sender.Hex()
+		err := recordReputationScript.Run(ctx, r.redisClient, []string{key},
+			now.UnixMilli(), r.halfLife.Milliseconds(), weights.good, weights.bad, r.ttl().Milliseconds()).Err()
+		if err != nil {
+			RecordRedisError("SenderReputation")
+		}
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var rep reputation
+	if val, ok := r.local.Get(sender); ok {
+		rep = val.(reputation)
+	}
+	rep = rep.decayed(now, r.halfLife)
+	rep.good += weights.good
+	rep.bad += weights.bad
+	r.local.Add(sender, rep)
+	return nil
+}
+
+// Score returns the current score of sender.
+func (r *SenderReputation) Score(ctx context.Context, sender common.Address) (float64, error) {
+	now := r.now()
+	var rep reputation
+	if r.redisClient != nil {
+		vals, err := r.redisClient.HMGet(ctx, r.prefix+":"+sender.Hex(), "good", "bad", "ts").Result()
+		if err != nil {
+			RecordRedisError("SenderReputation")
+			return 0, err
+		}
+		rep.good = parseReputationField(vals[0])
+		rep.bad = parseReputationField(vals[1])
+		if ts := parseReputationField(vals[2]); ts > 0 {
+			rep.ts = time.UnixMilli(int64(ts))
+		}
+	} else {
+		r.mu.Lock()
+		if val, ok := r.local.Get(sender); ok {
+			rep = val.(reputation)
+		}
+		r.mu.Unlock()
+	}
+	return rep.decayed(now, r.halfLife).score(), nil
+}
+
+func parseReputationField(val interface{}) float64 {
+	s, ok := val.(string)
+	if !ok {
+		return 0
+	}
+	f, _ := strconv.ParseFloat(s, 64)
+	return f
+}
+
+// Low returns whether the sender of tx scores below the minimum. Senders
+// whose score can't be read are given the benefit of the doubt.
+func (r *SenderReputation) Low(ctx context.Context, tx *types.Transaction) bool {
+	if r == nil {
+		return false
+	}
+	sender, err := txSender(tx)
+	if err != nil {
+		return false
+	}
+	score, err := r.Score(ctx, sender)
+	if err != nil {
+		log.Warn("error reading sender reputation", "req_id", GetReqID(ctx), "err", err)
+		return false
+	}
+	return score < r.minScore
+}
+
+// Limit returns ErrOverSenderRateLimit if a low scoring sender's tx is over
+// the low score rate limit.
+func (r *SenderReputation) Limit(ctx context.Context, tx *types.Transaction) error {
+	if r.lim == nil {
+		return nil
+	}
+	sender, err := txSender(tx)
+	if err != nil {
+		return nil
+	}
+	ok, err := r.lim.Take(ctx, sender.Hex())
+	if err != nil {
+		log.Warn("error taking low reputation rate limit", "req_id", GetReqID(ctx), "err", err)
+		return nil
+	}
+	if !ok {
+		RecordLowReputationSubmission("limited")
+		return ErrOverSenderRateLimit
+	}
+	return nil
+}
+
+// Route returns the backend group to submit a low scoring sender's
+// transactions to, instead of group.
+func (r *SenderReputation) Route(group string) string {
+	if r.backendGroup == "" {
+		return group
+	}
+	RecordLowReputationSubmission("routed")
+	return r.backendGroup
+}
+
+// withoutFastPriority removes the fast submission priority from a query
+// string.
+func withoutFastPriority(query string) string {
+	values, err := url.ParseQuery(query)
+	if err != nil || !values.Has("fast") {
+		return query
+	}
+	RecordLowReputationSubmission("deprioritized")
+	values.Del("fast")
+	return values.Encode()
+}
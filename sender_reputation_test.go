@@ -0,0 +1,82 @@
+package proxyd
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSenderReputation(t *testing.T) {
+	redisServer, err := miniredis.Run()
+	require.NoError(t, err)
+	defer redisServer.Close()
+	redisClient := redis.NewClient(&redis.Options{
+		Addr: fmt.Sprintf("127.0.0.1:%s", redisServer.Port()),
+	})
+	limiterFactory := func(dur time.Duration, max int, prefix string) FrontendRateLimiter {
+		return NewMemoryFrontendRateLimit(dur, max)
+	}
+
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	tx, err := types.SignNewTx(key, types.LatestSignerForChainID(big.NewInt(10)), &types.DynamicFeeTx{ChainID: big.NewInt(10), Gas: 21000})
+	require.NoError(t, err)
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+
+	require.Nil(t, NewSenderReputation(SenderReputationConfig{}, nil, "", limiterFactory))
+
+	for name, client := range map[string]redis.UniversalClient{"memory": nil, "redis": redisClient} {
+		t.Run(name, func(t *testing.T) {
+			r := NewSenderReputation(SenderReputationConfig{
+				Enabled:              true,
+				LowScoreBackendGroup: "slow",
+				LowScoreRateLimit:    RateLimitMethodOverride{Limit: 1, Interval: TOMLDuration(time.Minute)},
+			}, client, "proxyd", limiterFactory)
+			now := time.Now()
+			r.now = func() time.Time { return now }
+			ctx := context.Background()
+
+			// unknown senders start in the middle
+			score, err := r.Score(ctx, sender)
+			require.NoError(t, err)
+			require.Equal(t, 0.5, score)
+			require.False(t, r.Low(ctx, tx))
+
+			// spam sinks the score
+			r.Record(ctx, tx, ReputationEventAccepted)
+			r.Record(ctx, tx, ReputationEventSpam)
+			r.Record(ctx, tx, ReputationEventSpam)
+			score, err = r.Score(ctx, sender)
+			require.NoError(t, err)
+			require.InDelta(t, 2.0/7, score, 1e-6)
+			require.True(t, r.Low(ctx, tx))
+			require.Equal(t, "slow", r.Route("main"))
+			require.NoError(t, r.Limit(ctx, tx))
+			require.ErrorIs(t, r.Limit(ctx, tx), ErrOverSenderRateLimit)
+
+			// and recovers as events decay
+			now = now.Add(time.Hour)
+			score, err = r.Score(ctx, sender)
+			require.NoError(t, err)
+			require.InDelta(t, 1.5/4.5, score, 1e-6)
+			require.False(t, r.Low(ctx, tx))
+
+			// events decay before new ones are added
+			r.Record(ctx, tx, ReputationEventAccepted)
+			score, err = r.Score(ctx, sender)
+			require.NoError(t, err)
+			require.InDelta(t, 2.5/5.5, score, 1e-6)
+		})
+	}
+
+	require.Equal(t, "builder=flashbots", withoutFastPriority("builder=flashbots&fast=true"))
+	require.Equal(t, "builder=flashbots", withoutFastPriority("builder=flashbots"))
+}
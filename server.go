@@ -10,13 +10,17 @@ import (
 	"io"
 	"math"
 	"math/big"
+	"net"
 	"net/http"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
+	"github.com/ethereum-optimism/optimism/op-service/eth"
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
@@ -36,11 +40,27 @@ const (
 	ContextKeyAuth                                  = "authorization"
 	ContextKeyReqID                                 = "req_id"
 	ContextKeyXForwardedFor                         = "x_forwarded_for"
-	ContextKeyOpTxProxyAuth                         = "op_txproxy_auth"
 	ContextKeyInteropValidationStrategy             = "interop_validation_strategy"
 	ContextKeyHeadersToForward                      = "headers_to_forward"
 	ContextKeyRawQuery                              = "raw_query"
 	ContextKeyPath                                  = "path"
+	ContextKeyRequestRewriter                       = "request_rewriter"
+	ContextKeyFeatureFlags                          = "feature_flags"
+	ContextKeyOrigin                                = "origin"
+	ContextKeyAffinity                              = "affinity"
+	ContextKeyPreferredBackend                      = "preferred_backend"
+	ContextKeyTenant                                = "tenant"
+	ContextKeyVirtualHost                           = "virtual_host"
+	ContextKeyAuthPolicy                            = "auth_policy"
+	ContextKeySigner                                = "signer"
+	ContextKeySignerClass                           = "signer_class"
+	ContextKeyFinalizedBlock                        = "finalized_block"
+	ContextKeyCacheControl                          = "cache_control"
+	ContextKeyClientIP                              = "client_ip"
+	ContextKeyRequestStages                         = "request_stages"
+	ContextKeyRequestDebug                          = "request_debug"
+	ContextKeyDeprecations                          = "deprecations"
+	ContextKeyAPIKeyID                              = "api_key_id"
 	DefaultOpTxProxyAuthHeader                      = "X-Optimism-Signature"
 	FlashbotsAuthHeader                             = "X-Flashbots-Signature"
 	DefaultMaxBatchRPCCallsLimit                    = 100
@@ -59,6 +79,13 @@ const (
 	defaultInteropReqSizeLimit                      = 128 * opt.KiB
 	defaultInteropAccessListSizeLimit               = 1000
 	defaultInteropLoadBalancingUnhealthinessTimeout = 10 * time.Second
+	defaultInteropHedgeDelay                        = 100 * time.Millisecond
+	defaultInteropCircuitBreakerThreshold           = 5
+	defaultInteropCircuitBreakerCooldown            = 30 * time.Second
+	defaultInteropBanPeriod                         = time.Minute
+	defaultInteropHealthCheckInterval               = 10 * time.Second
+	maxInteropAdvisoryValidations                   = 1000
+	interopAdvisoryValidationTimeout                = 10 * time.Second
 )
 
 var (
@@ -91,6 +118,7 @@ type Server struct {
 	highPrioOverrideLims     map[string]FrontendRateLimiter
 	senderLim                FrontendRateLimiter
 	interopSenderLim         FrontendRateLimiter
+	interopSenderChainLims   map[eth.ChainID]FrontendRateLimiter
 	allowedChainIds          []*big.Int
 	limExemptOrigins         []*regexp.Regexp
 	limExemptUserAgents      []*regexp.Regexp
@@ -102,8 +130,73 @@ type Server struct {
 	rateLimitHeader          string
 	interopValidatingConfig  InteropValidationConfig
 	interopStrategy          InteropStrategy
+	interopAdmin             *InteropAdmin
+	interopStaticChecker     *InteropStaticChecker
+	interopEnforcedOrigins   []*regexp.Regexp
+	interopAdvisoryOrigins   []*regexp.Regexp
+	interopAdvisorySem       chan struct{}
 	allowedDynamicHeaders    []string
 	verifyFlashbotsSignature bool
+	plugins                  *PluginHost
+	policy                   *PolicyClient
+	events                   *EventPublisher
+	webhooks                 *WebhookNotifier
+	featureFlags             *FeatureFlags
+	blobTx                   BlobTxConfig
+	txFeeFilter              *TxFeeFilter
+	pendingTxs               *PendingTxLimiter
+	pendingNonces            *PendingNonces
+	gasOracle                *GasOracle
+	filters                  *FilterManager
+	affinity                 *BackendAffinity
+	cacheControl             *CacheControl
+	writesGroup              string
+	readsGroup               string
+	rollupGroup              string
+	routingRules             *RoutingRules
+	txPreferences            *TxPreferenceStore
+	responseSigner           *ResponseSigner
+	replayGuard              *ReplayGuard
+	cacheFailClosed          bool
+	rateLimitExemptions      *RateLimitExemptions
+	txAudit                  *TxAuditLog
+	trafficAnalyzer          *TrafficAnalyzer
+	callPolicies             *CallPolicies
+	ipHasher                 *IPHasher
+	senderLimitsFailOpen     bool
+	readYourWrites           *ReadYourWrites
+	txDedup                  *TxDedup
+	estimateGas              *EstimateGas
+	cacheWarmer              *CacheWarmer
+	deprecations             *MethodDeprecations
+	selectors                *Selectors
+	senderReputation         *SenderReputation
+	usageExporter            *UsageExporter
+	wsSendBuffer             WSSendBufferConfig
+	wsReplay                 *WSReplay
+	wsNotificationBatching   WSNotificationBatchingConfig
+	earlyReturn              *EarlyReturn
+	notFoundRetry            *NotFoundRetry
+	loadShedder              *LoadShedder
+	inflight                 *InflightLimiter
+	methodTimeouts           *MethodTimeouts
+	binaryCodecs             []*binaryCodec
+	tenants                  *Tenants
+	virtualHosts             VirtualHosts
+	authPolicies             AuthPolicies
+	keyStore                 *KeyStore
+	hmacAuth                 *HMACAuth
+	signerClasses            SignerClasses
+	strictJSONRPC            *StrictJSONRPC
+	frontendLimits           *FrontendLimits
+	ipFilter                 *IPFilter
+	maintenance              *MaintenanceMode
+
+	// liveMu guards the method mappings, rate limits and authentication
+	// secrets, which can be replaced while the server is running.
+	liveMu                  sync.RWMutex
+	limiterFactory          limiterFactoryFunc
+	highPrioRateLimitConfig RateLimitConfig
 }
 
 type limiterFunc func(method string) bool
@@ -182,24 +275,9 @@ func NewServer(
 		mainLim = NoopFrontendRateLimiter
 	}
 
-	overrideLims := make(map[string]FrontendRateLimiter)
-	highPrioOverrideLims := make(map[string]FrontendRateLimiter)
-	globalMethodLims := make(map[string]bool)
-	for method, override := range rateLimitConfig.MethodOverrides {
-		overrideLims[method] = limiterFactory(time.Duration(override.Interval), override.Limit, method)
-
-		if override.Global {
-			globalMethodLims[method] = true
-		}
-	}
-
-	for method, override := range highPrioRateLimitConfig.MethodOverrides {
-		highPrioOverrideLims[method] = limiterFactory(time.Duration(override.Interval), override.Limit, method)
-
-		if override.Global {
-			globalMethodLims[method] = true
-		}
-	}
+	overrideLims := newOverrideLimiters(rateLimitConfig, limiterFactory)
+	highPrioOverrideLims := newOverrideLimiters(highPrioRateLimitConfig, limiterFactory)
+	globalMethodLims := globallyLimitedMethods(rateLimitConfig, highPrioRateLimitConfig)
 
 	var senderLim FrontendRateLimiter
 	if senderRateLimitConfig.Enabled {
@@ -207,8 +285,16 @@ func NewServer(
 	}
 
 	var interopSenderLim FrontendRateLimiter
+	interopSenderChainLims := make(map[eth.ChainID]FrontendRateLimiter)
 	if interopSenderRateLimitConfig.Enabled {
 		interopSenderLim = limiterFactory(time.Duration(interopSenderRateLimitConfig.Interval), interopSenderRateLimitConfig.Limit, "interop_senders")
+		for id, limit := range interopSenderRateLimitConfig.ChainLimits {
+			chainID, err := eth.ParseDecimalChainID(id)
+			if err != nil {
+				return nil, fmt.Errorf("invalid chain id %s in interop sender rate limits: %w", id, err)
+			}
+			interopSenderChainLims[chainID] = limiterFactory(time.Duration(interopSenderRateLimitConfig.Interval), limit, "interop_senders_"+chainID.String())
+		}
 	}
 
 	rateLimitHeader := defaultRateLimitHeader
@@ -216,6 +302,20 @@ func NewServer(
 		rateLimitHeader = rateLimitConfig.IPHeaderOverride
 	}
 
+	switch interopValidatingConfig.Mode {
+	case "", InteropValidationEnforce, InteropValidationAdvisory:
+	default:
+		return nil, fmt.Errorf("invalid interop validation mode: %s", interopValidatingConfig.Mode)
+	}
+	interopEnforcedOrigins, err := compileOriginPatterns(interopValidatingConfig.EnforcedOrigins)
+	if err != nil {
+		return nil, err
+	}
+	interopAdvisoryOrigins, err := compileOriginPatterns(interopValidatingConfig.AdvisoryOrigins)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Server{
 		BackendGroups:        backendGroups,
 		wsBackendGroup:       wsBackendGroup,
@@ -240,51 +340,162 @@ func NewServer(
 		globallyLimitedMethods:   globalMethodLims,
 		senderLim:                senderLim,
 		interopSenderLim:         interopSenderLim,
+		interopSenderChainLims:   interopSenderChainLims,
 		allowedChainIds:          senderRateLimitConfig.AllowedChainIds,
 		limExemptOrigins:         limExemptOrigins,
 		limExemptUserAgents:      limExemptUserAgents,
 		rateLimitHeader:          rateLimitHeader,
 		interopValidatingConfig:  interopValidatingConfig,
 		interopStrategy:          interopStrategy,
+		interopEnforcedOrigins:   interopEnforcedOrigins,
+		interopAdvisoryOrigins:   interopAdvisoryOrigins,
+		interopAdvisorySem:       make(chan struct{}, maxInteropAdvisoryValidations),
 		allowedDynamicHeaders:    allowedDynamicHeaders,
 		verifyFlashbotsSignature: verifyFlashbotsSignature,
+		limiterFactory:           limiterFactory,
+		highPrioRateLimitConfig:  highPrioRateLimitConfig,
 	}, nil
 }
 
+func newOverrideLimiters(cfg RateLimitConfig, limiterFactory limiterFactoryFunc) map[string]FrontendRateLimiter {
+	lims := make(map[string]FrontendRateLimiter)
+	for method, override := range cfg.MethodOverrides {
+		lims[method] = limiterFactory(time.Duration(override.Interval), override.Limit, method)
+	}
+	return lims
+}
+
+func globallyLimitedMethods(cfgs ...RateLimitConfig) map[string]bool {
+	methods := make(map[string]bool)
+	for _, cfg := range cfgs {
+		for method, override := range cfg.MethodOverrides {
+			if override.Global {
+				methods[method] = true
+			}
+		}
+	}
+	return methods
+}
+
+// SetRPCMethodMappings replaces the method to backend group mappings of a
+// running server.
+func (s *Server) SetRPCMethodMappings(mappings map[string]string) error {
+	mappings = SplitReadsWrites(mappings, s.writesGroup, s.readsGroup)
+	mappings = MapRollupMethods(mappings, s.rollupGroup)
+	for method, group := range mappings {
+		if s.BackendGroups[group] == nil {
+			return fmt.Errorf("method %s is mapped to undefined backend group %s", method, group)
+		}
+		if !s.tenants.Allows(nil, group) {
+			return fmt.Errorf("method %s is mapped to backend group %s of a tenant", method, group)
+		}
+	}
+	s.liveMu.Lock()
+	s.rpcMethodMappings = mappings
+	s.liveMu.Unlock()
+	return nil
+}
+
+// ReplaceAuthSecret replaces an [authentication] secret with its refreshed
+// value, keeping its alias.
+func (s *Server) ReplaceAuthSecret(old, secret string) {
+	s.liveMu.Lock()
+	defer s.liveMu.Unlock()
+	alias, ok := s.authenticatedPaths[old]
+	if !ok || secret == "" {
+		return
+	}
+	delete(s.authenticatedPaths, old)
+	s.authenticatedPaths[secret] = alias
+}
+
+// SetRateLimits replaces the base and per-method rate limits of a running
+// server. The new limiters start with empty windows. Exempt origins and user
+// agents can't be changed.
+func (s *Server) SetRateLimits(cfg RateLimitConfig) {
+	var mainLim FrontendRateLimiter = NoopFrontendRateLimiter
+	if cfg.BaseRate > 0 {
+		mainLim = s.limiterFactory(time.Duration(cfg.BaseInterval), cfg.BaseRate, "main")
+	}
+	overrideLims := newOverrideLimiters(cfg, s.limiterFactory)
+	globalMethodLims := globallyLimitedMethods(cfg, s.highPrioRateLimitConfig)
+
+	s.liveMu.Lock()
+	s.mainLim = mainLim
+	s.overrideLims = overrideLims
+	s.globallyLimitedMethods = globalMethodLims
+	s.liveMu.Unlock()
+}
+
 func (s *Server) RPCListenAndServe(host string, port int) error {
+	ln, err := net.Listen("tcp", fmt.Sprintf("%s:%d", host, port))
+	if err != nil {
+		return err
+	}
+	return s.RPCServe(ln)
+}
+
+// RPCServe serves the RPC server on an existing listener, such as a Unix
+// socket or a socket passed by systemd.
+func (s *Server) RPCServe(ln net.Listener) error {
 	s.srvMu.Lock()
 	hdlr := mux.NewRouter()
 	hdlr.HandleFunc("/healthz", s.HandleHealthz).Methods("GET")
-	hdlr.HandleFunc("/{path:.*}", s.HandleRPC).Methods("POST") // Catch all POST paths
-	c := cors.New(cors.Options{
+	for _, codec := range s.binaryCodecs {
+		hdlr.HandleFunc("/"+codec.name, s.filterIPs(s.responseSigner.Wrap(s.handleBinaryRPC(codec)))).Methods("POST")
+	}
+	hdlr.HandleFunc("/{path:.*}", s.filterIPs(s.responseSigner.Wrap(s.HandleRPC))).Methods("POST") // Catch all POST paths
+	if s.cacheControl != nil {
+		hdlr.HandleFunc("/{path:.*}", s.filterIPs(s.responseSigner.Wrap(s.HandleRPC))).Methods("GET")
+	}
+	corsOpts := cors.Options{
 		AllowedOrigins: []string{"*"},
-	})
-	addr := fmt.Sprintf("%s:%d", host, port)
+	}
+	if s.responseSigner != nil {
+		corsOpts.ExposedHeaders = []string{s.responseSigner.header}
+	}
+	c := cors.New(corsOpts)
+	addr := ln.Addr().String()
 	s.rpcServer = &http.Server{
-		Handler: instrumentedHdlr(c.Handler(hdlr)),
+		Handler: instrumentedHdlr(s.frontendLimits.Handler(c.Handler(hdlr))),
 		Addr:    addr,
 	}
-	log.Info("starting HTTP server", "addr", addr)
+	s.frontendLimits.configure(s.rpcServer)
+	log.Info("starting HTTP server", "addr", addr, "network", ln.Addr().Network())
 	s.srvMu.Unlock()
-	return s.rpcServer.ListenAndServe()
+	return s.rpcServer.Serve(ln)
 }
 
 func (s *Server) WSListenAndServe(host string, port int) error {
+	ln, err := net.Listen("tcp", fmt.Sprintf("%s:%d", host, port))
+	if err != nil {
+		return err
+	}
+	return s.WSServe(ln)
+}
+
+// WSServe serves the WS server on an existing listener, such as a Unix
+// socket or a socket passed by systemd.
+func (s *Server) WSServe(ln net.Listener) error {
 	s.srvMu.Lock()
 	hdlr := mux.NewRouter()
-	hdlr.HandleFunc("/", s.HandleWS)
-	hdlr.HandleFunc("/{authorization}", s.HandleWS)
+	for _, codec := range s.binaryCodecs {
+		hdlr.HandleFunc("/"+codec.name, s.filterIPs(s.handleBinaryWS(codec)))
+	}
+	hdlr.HandleFunc("/", s.filterIPs(s.HandleWS))
+	hdlr.HandleFunc("/{authorization}", s.filterIPs(s.HandleWS))
 	c := cors.New(cors.Options{
 		AllowedOrigins: []string{"*"},
 	})
-	addr := fmt.Sprintf("%s:%d", host, port)
+	addr := ln.Addr().String()
 	s.wsServer = &http.Server{
-		Handler: instrumentedHdlr(c.Handler(hdlr)),
+		Handler: instrumentedHdlr(s.frontendLimits.Handler(c.Handler(hdlr))),
 		Addr:    addr,
 	}
-	log.Info("starting WS server", "addr", addr)
+	s.frontendLimits.configure(s.wsServer)
+	log.Info("starting WS server", "addr", addr, "network", ln.Addr().Network())
 	s.srvMu.Unlock()
-	return s.wsServer.ListenAndServe()
+	return s.wsServer.Serve(ln)
 }
 
 func (s *Server) Shutdown() {
@@ -296,9 +507,19 @@ func (s *Server) Shutdown() {
 	if s.wsServer != nil {
 		_ = s.wsServer.Shutdown(context.Background())
 	}
+	// forwarded early returned transactions still need the backends
+	s.earlyReturn.Shutdown()
+	s.wsReplay.Stop()
 	for _, bg := range s.BackendGroups {
 		bg.Shutdown()
 	}
+	s.txAudit.Close()
+	if s.events != nil {
+		s.events.Close()
+	}
+	if s.webhooks != nil {
+		s.webhooks.Shutdown()
+	}
 }
 
 func (s *Server) HandleHealthz(w http.ResponseWriter, r *http.Request) {
@@ -311,13 +532,19 @@ func (s *Server) HandleRPC(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	var cancel context.CancelFunc
-	ctx, cancel = context.WithTimeout(ctx, s.timeout)
+	// narrowed to the timeout of the requested methods once they're parsed
+	ctx, cancel = context.WithTimeout(ctx, s.methodTimeouts.Max(s.timeout))
 	defer cancel()
+	ctx, cancelClientDeadline := withClientDeadline(ctx, r.Header)
+	defer cancelClientDeadline()
+	ctx, timings := withStageTimings(ctx)
+	defer timings.record()
 
 	origin := r.Header.Get("Origin")
 	userAgent := r.Header.Get("User-Agent")
 	// Use XFF in context since it will automatically be replaced by the remote IP
 	xff := stripXFF(GetXForwardedFor(ctx))
+	clientIP := GetClientIP(ctx)
 	isUnlimitedOrigin := s.isUnlimitedOrigin(origin)
 	isUnlimitedUserAgent := s.isUnlimitedUserAgent(userAgent)
 
@@ -330,18 +557,47 @@ func (s *Server) HandleRPC(w http.ResponseWriter, r *http.Request) {
 		"received RPC request",
 		"req_id", GetReqID(ctx),
 		"auth", GetAuthCtx(ctx),
-		"user_agent", userAgent,
-		"origin", origin,
-		"remote_ip", xff,
+		"user_agent", sanitizeLogValue(userAgent),
+		"origin", sanitizeLogValue(origin),
+		"remote_ip", clientIP,
+		"tenant", GetTenantName(ctx),
 	)
 
-	body, err := io.ReadAll(LimitReader(r.Body, s.maxBodySize))
+	ctx = s.affinity.Session(ctx, w, r)
+	ctx = s.cacheControl.Session(ctx)
+	defer s.loadShedder.Begin()()
+
+	release, err := s.inflight.Acquire(ctx)
+	if err != nil {
+		writeRPCError(ctx, w, nil, err)
+		return
+	}
+	defer release()
+
+	var body []byte
+	if r.Method == http.MethodGet {
+		body, err = s.cacheControl.RequestBody(r)
+		if err != nil {
+			RecordRejectedRequest(RejectReasonBadJSON)
+			writeRPCError(ctx, w, nil, ErrInvalidRequest(err.Error()))
+			return
+		}
+	} else {
+		body, err = io.ReadAll(LimitReader(r.Body, s.maxBodySize))
+	}
 	if errors.Is(err, ErrLimitReaderOverLimit) {
 		log.Error("request body too large", "req_id", GetReqID(ctx))
 		RecordRPCError(ctx, BackendProxyd, MethodUnknown, ErrRequestBodyTooLarge)
+		RecordRejectedRequest(RejectReasonTooLarge)
 		writeRPCError(ctx, w, nil, ErrRequestBodyTooLarge)
 		return
 	}
+	if errors.Is(err, ErrRequestBodyTooSlow) {
+		log.Info("request body sent too slowly", "req_id", GetReqID(ctx), "remote_ip", clientIP)
+		RecordRPCError(ctx, BackendProxyd, MethodUnknown, ErrRequestBodyTooSlow)
+		writeRPCError(ctx, w, nil, ErrRequestBodyTooSlow)
+		return
+	}
 	if err != nil {
 		log.Error("error reading request body", "err", err)
 		writeRPCError(ctx, w, nil, ErrInternal)
@@ -353,28 +609,94 @@ func (s *Server) HandleRPC(w http.ResponseWriter, r *http.Request) {
 	var signer common.Address
 	if flashbotsAuth != "" {
 		signer, err = VerifyFlashbotsAuth(flashbotsAuth, body)
+		RecordFlashbotsSignature(err == nil)
 		if err != nil {
-			log.Error("error verifying flashbots auth", "err", err)
+			log.Error("error verifying flashbots auth", "req_id", GetReqID(ctx), "err", err)
+			RecordRejectedRequest(RejectReasonAuthFailed)
 			writeRPCError(ctx, w, nil, ErrFlashbotsSignature)
 			return
 		}
+		if err := s.replayGuard.CheckFlashbots(ctx, flashbotsAuth); err != nil {
+			log.Info("rejected replayed flashbots signature", "req_id", GetReqID(ctx), "signer", signer)
+			RecordRejectedRequest(RejectReasonAuthFailed)
+			writeRPCError(ctx, w, nil, err)
+			return
+		}
+		ctx = context.WithValue(ctx, ContextKeySigner, signer) // nolint:staticcheck
+		if class := s.signerClasses[signer]; class != nil {
+			ctx = WithSignerClass(ctx, class)
+		}
 	}
 
+	if s.hmacAuth.Signed(r) {
+		hmacHeader := r.Header.Get(HMACAuthHeader)
+		keyID, err := s.hmacAuth.Verify(hmacHeader, body)
+		if err != nil {
+			log.Info("error verifying hmac signature", "req_id", GetReqID(ctx), "err", err)
+			RecordRejectedRequest(RejectReasonAuthFailed)
+			if s.replayGuard != nil && errors.Is(err, ErrExpiredSignature) {
+				writeRPCError(ctx, w, nil, ErrExpiredSignature)
+			} else {
+				writeRPCError(ctx, w, nil, ErrHMACSignature)
+			}
+			return
+		}
+		if err := s.replayGuard.CheckHMAC(ctx, hmacHeader); err != nil {
+			log.Info("rejected replayed hmac signature", "req_id", GetReqID(ctx), "key", keyID)
+			RecordRejectedRequest(RejectReasonAuthFailed)
+			writeRPCError(ctx, w, nil, err)
+			return
+		}
+		if GetAuthCtx(ctx) == "none" {
+			ctx = context.WithValue(ctx, ContextKeyAuth, keyID) // nolint:staticcheck
+			if policy := s.authPolicies[keyID]; policy != nil {
+				ctx = WithAuthPolicy(ctx, policy)
+			}
+		}
+	}
+
+	tenant := GetTenant(ctx)
+	authPolicy := GetAuthPolicy(ctx)
+	if s.enableServedByHeader && authPolicy.debugHeaderEnabled() {
+		ctx, _ = withRequestDebug(ctx)
+	}
+	ctx = s.deprecations.WithNotices(ctx)
+	signerClass := GetSignerClass(ctx)
+	exemptReason, isExempt := s.rateLimitExemptions.Exempt(ctx)
+	if isExempt {
+		RecordRateLimitExemption(exemptReason)
+	}
 	isLimited := func(method string) bool {
 		isGloballyLimitedMethod := s.isGlobalLimit(method)
-		if !isGloballyLimitedMethod && (isUnlimitedOrigin || isUnlimitedUserAgent) {
+		if !isGloballyLimitedMethod && (isUnlimitedOrigin || isUnlimitedUserAgent || isExempt) {
 			return false
 		}
 
 		isHighPrio := s.highPrioSigners[signer]
 		var lim FrontendRateLimiter
+		s.liveMu.RLock()
+		mainLim, overrideLims := s.mainLim, s.overrideLims
+		s.liveMu.RUnlock()
+		if tenant != nil {
+			mainLim, overrideLims = tenant.mainLim, tenant.overrideLims
+		}
+		// tiers limit each alias rather than each IP
+		limKey := clientIP
+		if authPolicy != nil && authPolicy.tier != nil {
+			mainLim, overrideLims = authPolicy.tier.mainLim, authPolicy.tier.overrideLims
+			limKey = authPolicy.Alias
+		}
+		if signerClass != nil {
+			mainLim, overrideLims = signerClass.mainLim, signerClass.overrideLims
+			limKey = signer.Hex()
+		}
 		if method == "" {
-			lim = s.mainLim
+			lim = mainLim
 		} else {
 			if isHighPrio {
 				lim = s.highPrioOverrideLims[method]
 			} else {
-				lim = s.overrideLims[method]
+				lim = overrideLims[method]
 			}
 		}
 
@@ -382,7 +704,9 @@ func (s *Server) HandleRPC(w http.ResponseWriter, r *http.Request) {
 			return false
 		}
 
-		ok, err := lim.Take(ctx, xff)
+		start := time.Now()
+		ok, err := lim.Take(ctx, limKey)
+		addStageTime(ctx, requestStageRateLimit, start)
 		if err != nil {
 			log.Warn("error taking rate limit", "err", err)
 			return true
@@ -392,17 +716,27 @@ func (s *Server) HandleRPC(w http.ResponseWriter, r *http.Request) {
 
 	if s.enableRequestLog {
 		log.Info("Raw RPC request",
-			"body", truncate(string(body), s.maxRequestBodyLogLen),
+			"body", strings.ToValidUTF8(truncate(string(body), s.maxRequestBodyLogLen), string(utf8.RuneError)),
 			"req_id", GetReqID(ctx),
 			"auth", GetAuthCtx(ctx),
 		)
 	}
 
+	if err := s.strictJSONRPC.CheckBody(ctx, body); err != nil {
+		RecordRPCError(ctx, BackendProxyd, MethodUnknown, err)
+		RecordRejectedRequest(RejectReasonBadJSON)
+		writeRPCError(ctx, w, nil, err)
+		return
+	}
+
 	if IsBatch(body) {
+		start := time.Now()
 		reqs, err := ParseBatchRPCReq(body)
+		addStageTime(ctx, requestStageParse, start)
 		if err != nil {
 			log.Error("error parsing batch RPC request", "err", err)
 			RecordRPCError(ctx, BackendProxyd, MethodUnknown, err)
+			RecordRejectedRequest(RejectReasonBadJSON)
 			writeRPCError(ctx, w, nil, ErrParseErr)
 			return
 		}
@@ -411,20 +745,29 @@ func (s *Server) HandleRPC(w http.ResponseWriter, r *http.Request) {
 
 		if len(reqs) > s.maxBatchSize {
 			RecordRPCError(ctx, BackendProxyd, MethodUnknown, ErrTooManyBatchRequests)
+			RecordRejectedRequest(RejectReasonBatchTooBig)
 			writeRPCError(ctx, w, nil, ErrTooManyBatchRequests)
 			return
 		}
 
 		if len(reqs) == 0 {
+			RecordRejectedRequest(RejectReasonBadJSON)
 			writeRPCError(ctx, w, nil, ErrInvalidRequest("must specify at least one batch call"))
 			return
 		}
 
+		ctx, cancel := s.withMethodTimeout(ctx, reqs)
+		defer cancel()
+
 		batchRes, batchContainsCached, servedBy, err := s.handleBatchRPC(ctx, reqs, isLimited, true)
 		if err == context.DeadlineExceeded {
 			writeRPCError(ctx, w, nil, ErrGatewayTimeout)
 			return
 		}
+		if errors.Is(err, ErrContextCanceled) {
+			writeRPCError(ctx, w, nil, ErrContextCanceled)
+			return
+		}
 		if errors.Is(err, ErrConsensusGetReceiptsCantBeBatched) ||
 			errors.Is(err, ErrConsensusGetReceiptsInvalidTarget) {
 			writeRPCError(ctx, w, nil, ErrInvalidRequest(err.Error()))
@@ -436,15 +779,31 @@ func (s *Server) HandleRPC(w http.ResponseWriter, r *http.Request) {
 		}
 		if s.enableServedByHeader {
 			w.Header().Set("x-served-by", servedBy)
+			s.setDebugHeader(ctx, w, servedBy, batchContainsCached)
+		}
+		if backend := s.affinity.Repinned(ctx); backend != "" {
+			w.Header().Set(AffinityRepinnedHeader, backend)
 		}
+		s.loadShedder.SetRetryAfter(w, batchRes)
+		s.deprecations.SetHeaders(ctx, w)
 		setCacheHeader(w, batchContainsCached)
+		if s.cacheControl.SetHeaders(ctx, w, r, batchRes, true) {
+			return
+		}
 		writeBatchRPCRes(ctx, w, batchRes)
 		return
 	}
 
 	rawBody := json.RawMessage(body)
+	ctx, cancel = s.withMethodTimeout(ctx, []json.RawMessage{rawBody})
+	defer cancel()
+
 	backendRes, cached, servedBy, err := s.handleBatchRPC(ctx, []json.RawMessage{rawBody}, isLimited, false)
 	if err != nil {
+		if errors.Is(err, ErrContextCanceled) {
+			writeRPCError(ctx, w, nil, ErrContextCanceled)
+			return
+		}
 		if errors.Is(err, ErrConsensusGetReceiptsCantBeBatched) ||
 			errors.Is(err, ErrConsensusGetReceiptsInvalidTarget) {
 			writeRPCError(ctx, w, nil, ErrInvalidRequest(err.Error()))
@@ -455,11 +814,28 @@ func (s *Server) HandleRPC(w http.ResponseWriter, r *http.Request) {
 	}
 	if s.enableServedByHeader {
 		w.Header().Set("x-served-by", servedBy)
+		s.setDebugHeader(ctx, w, servedBy, cached)
 	}
+	if backend := s.affinity.Repinned(ctx); backend != "" {
+		w.Header().Set(AffinityRepinnedHeader, backend)
+	}
+	s.loadShedder.SetRetryAfter(w, backendRes)
+	s.deprecations.SetHeaders(ctx, w)
 	setCacheHeader(w, cached)
+	if s.cacheControl.SetHeaders(ctx, w, r, backendRes, false) {
+		return
+	}
 	writeRPCRes(ctx, w, backendRes[0])
 }
 
+// withMethodTimeout bounds ctx by the timeout of the methods reqs call.
+func (s *Server) withMethodTimeout(ctx context.Context, reqs []json.RawMessage) (context.Context, context.CancelFunc) {
+	if s.methodTimeouts == nil {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, s.methodTimeouts.Timeout(s.timeout, requestMethods(reqs)...))
+}
+
 // reqSizeLimitCheck is a function which helps define, check and limit the size of the incoming request beyond the "max_body_size_bytes" setting.
 // Rest, if you would like this kind of check to happen at the inception of the request (before the request is parsed into RPCReq), it's better to use the "max_body_size_bytes"
 func reqSizeLimitCheck(ctx context.Context, tx *types.Transaction, maxSize int) error {
@@ -513,24 +889,46 @@ func (s *Server) validateInteropSendRpcRequest(ctx context.Context, tx *types.Tr
 		"validating interop access list",
 		"source", "rpc",
 		"req_id", GetReqID(ctx),
-		"strategy", s.interopValidatingConfig.Strategy,
+		"strategy", s.interopConfig().Strategy,
 		"tx_hash", tx.Hash(),
 	)
-	if err := s.rateLimitInteropSender(ctx, tx); err != nil {
-		return err
+	RecordInteropMessagesChecked(len(interopAccessListChainIDs(interopAccessList)))
+	if err := s.rateLimitInteropSender(ctx, tx, interopAccessList); err != nil {
+		return interopRejection(err, InteropReasonRateLimited)
 	}
 	if err := reqSizeLimitCheck(ctx, tx, s.interopValidatingConfig.ReqSizeLimit); err != nil {
-		return err
+		return interopRejection(err, InteropReasonRequestTooLarge)
 	}
 
-	finalErr := s.interopStrategy.ValidateAccessList(ctx, interopAccessList)
+	if s.isInteropAdvisory(ctx) {
+		s.validateInteropAdvisory(ctx, tx, interopAccessList)
+		return nil
+	}
+
+	finalErr := s.interopStaticChecker.Check(interopAccessList)
+	if finalErr == nil {
+		finalErr = s.interopStrategy.ValidateAccessList(ctx, interopAccessList)
+	}
+	if finalErr != nil && s.interopConfig().FailOpen && !isInteropVerdict(finalErr) {
+		log.Warn("no interop validating backend reachable, forwarding transaction", "req_id", GetReqID(ctx), "tx_hash", tx.Hash(), "error", finalErr)
+		RecordInteropValidationFailOpen()
+		RecordInteropValidation("fail_open", InteropReasonSupervisorUnavailable)
+		return nil
+	}
 
 	if finalErr == nil {
 		log.Info("interop access list validated successfully", "req_id", GetReqID(ctx), "tx_hash", tx.Hash())
-	} else {
-		log.Info("interop access list validation failed", "req_id", GetReqID(ctx), "tx_hash", tx.Hash(), "error", finalErr)
+		RecordInteropValidation("valid", "")
+		return nil
 	}
-	return finalErr
+	reason := interopRejectionReason(finalErr)
+	log.Info("interop access list validation failed", "req_id", GetReqID(ctx), "tx_hash", tx.Hash(), "reason", reason, "error", finalErr)
+	if !s.featureFlags.Enabled(ctx, FeatureStrictInteropValidation) {
+		log.Info("forwarding transaction despite failed interop validation", "req_id", GetReqID(ctx), "tx_hash", tx.Hash())
+		RecordInteropValidation("forwarded", reason)
+		return nil
+	}
+	return interopRejection(finalErr, reason)
 }
 
 func (s *Server) handleBatchRPC(ctx context.Context, reqs []json.RawMessage, isLimited limiterFunc, isBatch bool) ([]*RPCRes, bool, string, error) {
@@ -543,16 +941,47 @@ func (s *Server) handleBatchRPC(ctx context.Context, reqs []json.RawMessage, isL
 	type batchGroup struct {
 		groupID      int
 		backendGroup string
+		// query is the query string submissions are forwarded with instead
+		// of the request's if overrideQuery, see Server.txQuery.
+		query         string
+		overrideQuery bool
+		// backend is the backend the batch is sent to first, see EstimateGas.
+		backend string
 	}
 
+	start := time.Now()
 	responses := make([]*RPCRes, len(reqs))
+	// requests as seen by the plugins' post_response hooks and the event stream
+	parsedReqs := make([]*RPCReq, len(reqs))
+	backends := make([]string, len(reqs))
+	// raw transactions that passed validation, by request index
+	sendTxs := make([]*types.Transaction, len(reqs))
+	lowReputation := make([]bool, len(reqs))
+	// the sends recorded by the pending transaction limiter
+	pendingTaken := make([]bool, len(reqs))
+	// the sends forwarded in the background, whose outcome isn't known yet
+	earlyReturned := make([]bool, len(reqs))
+	// requests that passed admission, with their backend groups, to be
+	// checked by the policy service and forwarded
+	admitted := make([]*RPCReq, len(reqs))
+	groups := make([]string, len(reqs))
 	batches := make(map[batchGroup][]batchElem)
 	ids := make(map[string]int, len(reqs))
 
 	for i := range reqs {
+		if err := s.strictJSONRPC.CheckRequest(ctx, reqs[i]); err != nil {
+			RecordRPCError(ctx, BackendProxyd, MethodUnknown, err)
+			RecordRejectedRequest(RejectReasonBadJSON)
+			responses[i] = NewRPCErrorRes(nil, err)
+			continue
+		}
+
+		start := time.Now()
 		parsedReq, err := ParseRPCReq(reqs[i])
+		addStageTime(ctx, requestStageParse, start)
 		if err != nil {
 			log.Info("error parsing RPC call", "source", "rpc", "err", err)
+			RecordRejectedRequest(RejectReasonBadJSON)
 			responses[i] = NewRPCErrorRes(nil, err)
 			continue
 		}
@@ -569,9 +998,11 @@ func (s *Server) handleBatchRPC(ctx context.Context, reqs []json.RawMessage, isL
 
 		if err := ValidateRPCReq(parsedReq); err != nil {
 			RecordRPCError(ctx, BackendProxyd, MethodUnknown, err)
+			RecordRejectedRequest(RejectReasonBadJSON)
 			responses[i] = NewRPCErrorRes(nil, err)
 			continue
 		}
+		s.trafficAnalyzer.Record(ctx, parsedReq)
 
 		if parsedReq.Method == "eth_accounts" {
 			RecordRPCForward(ctx, BackendProxyd, "eth_accounts", RPCRequestSourceHTTP)
@@ -579,7 +1010,48 @@ func (s *Server) handleBatchRPC(ctx context.Context, reqs []json.RawMessage, isL
 			continue
 		}
 
+		parsedReq, pluginGroup, pluginRes := s.plugins.PreRouting(ctx, parsedReq)
+		parsedReqs[i] = parsedReq
+		if pluginRes != nil {
+			responses[i] = pluginRes
+			continue
+		}
+
+		s.liveMu.RLock()
 		group := s.rpcMethodMappings[parsedReq.Method]
+		_, hasOverrideLim := s.overrideLims[parsedReq.Method]
+		s.liveMu.RUnlock()
+		if vhost := GetVirtualHost(ctx); vhost != nil {
+			group = vhost.rpcMethodMappings[parsedReq.Method]
+		}
+		if tenant := GetTenant(ctx); tenant != nil {
+			group = tenant.rpcMethodMappings[parsedReq.Method]
+			_, hasOverrideLim = tenant.overrideLims[parsedReq.Method]
+		}
+		group = s.routingRules.Route(parsedReq, group)
+		if class := GetSignerClass(ctx); class != nil {
+			group = class.route(parsedReq.Method, group)
+			_, hasOverrideLim = class.overrideLims[parsedReq.Method]
+		}
+		if policy := GetAuthPolicy(ctx); policy != nil {
+			group = policy.Route(parsedReq.Method, group)
+			if policy.tier != nil {
+				_, hasOverrideLim = policy.tier.overrideLims[parsedReq.Method]
+			}
+		}
+		if pluginGroup != "" {
+			if err := s.checkPluginGroup(ctx, parsedReq, pluginGroup); err != nil {
+				responses[i] = NewRPCErrorRes(parsedReq.ID, err)
+				continue
+			}
+			group = pluginGroup
+		}
+		if err := s.deprecations.Check(ctx, parsedReq.Method); err != nil {
+			RecordRPCError(ctx, BackendProxyd, parsedReq.Method, err)
+			RecordRejectedRequest(RejectReasonMethodBlocked)
+			responses[i] = NewRPCErrorRes(parsedReq.ID, err)
+			continue
+		}
 		if group == "" {
 			// use unknown below to prevent DOS vector that fills up memory
 			// with arbitrary method names.
@@ -587,28 +1059,50 @@ func (s *Server) handleBatchRPC(ctx context.Context, reqs []json.RawMessage, isL
 				"blocked request for non-whitelisted method",
 				"source", "rpc",
 				"req_id", GetReqID(ctx),
-				"method", parsedReq.Method,
+				"method", sanitizeLogValue(parsedReq.Method),
 			)
 			RecordRPCError(ctx, BackendProxyd, MethodUnknown, ErrMethodNotWhitelisted)
+			RecordRejectedRequest(RejectReasonMethodBlocked)
 			responses[i] = NewRPCErrorRes(parsedReq.ID, ErrMethodNotWhitelisted)
 			continue
 		}
+		RecordTenantRPCRequest(ctx, parsedReq.Method, group)
+		RecordSignerClassRPCRequest(ctx, parsedReq.Method, group)
+
+		if err := s.maintenance.Check(parsedReq.Method, group); err != nil {
+			RecordRPCError(ctx, BackendProxyd, parsedReq.Method, err)
+			responses[i] = NewRPCErrorRes(parsedReq.ID, err)
+			continue
+		}
+
+		if s.loadShedder.ShedPriority(parsedReq.Method, GetSignerClass(ctx).loadPriority()) {
+			log.Debug(
+				"shed request under load",
+				"source", "rpc",
+				"req_id", GetReqID(ctx),
+				"method", parsedReq.Method,
+			)
+			RecordRPCError(ctx, BackendProxyd, parsedReq.Method, ErrOverloaded)
+			responses[i] = NewRPCErrorRes(parsedReq.ID, ErrOverloaded)
+			continue
+		}
 
 		// Take base rate limit first
 		if isLimited("") {
 			log.Debug(
 				"rate limited individual RPC in a batch request",
 				"source", "rpc",
-				"req_id", parsedReq.ID,
+				"req_id", sanitizeLogValue(string(parsedReq.ID)),
 				"method", parsedReq.Method,
 			)
 			RecordRPCError(ctx, BackendProxyd, parsedReq.Method, ErrOverRateLimit)
+			RecordRejectedRequest(RejectReasonRateLimited)
 			responses[i] = NewRPCErrorRes(parsedReq.ID, ErrOverRateLimit)
 			continue
 		}
 
 		// Take rate limit for specific methods.
-		if _, ok := s.overrideLims[parsedReq.Method]; ok && isLimited(parsedReq.Method) {
+		if hasOverrideLim && isLimited(parsedReq.Method) {
 			log.Debug(
 				"rate limited specific RPC",
 				"source", "rpc",
@@ -616,6 +1110,7 @@ func (s *Server) handleBatchRPC(ctx context.Context, reqs []json.RawMessage, isL
 				"method", parsedReq.Method,
 			)
 			RecordRPCError(ctx, BackendProxyd, parsedReq.Method, ErrOverRateLimit)
+			RecordRejectedRequest(RejectReasonRateLimited)
 			responses[i] = NewRPCErrorRes(parsedReq.ID, ErrOverRateLimit)
 			continue
 		}
@@ -624,23 +1119,178 @@ func (s *Server) handleBatchRPC(ctx context.Context, reqs []json.RawMessage, isL
 		// limits apply regardless of origin or user-agent. As such, they don't use the
 		// isLimited method.
 		if parsedReq.Method == "eth_sendRawTransaction" || parsedReq.Method == "eth_sendRawTransactionConditional" {
+			if res := s.txDedup.Lookup(parsedReq); res != nil {
+				responses[i] = res
+				backends[i] = "dedup"
+				continue
+			}
 			tx, err := convertSendReqToSendTx(ctx, parsedReq)
 			if err != nil {
 				RecordRPCError(ctx, BackendProxyd, parsedReq.Method, err)
 				responses[i] = NewRPCErrorRes(parsedReq.ID, err)
 				continue
 			}
+			if err := s.checkBlobTx(ctx, tx); err != nil {
+				RecordRPCError(ctx, BackendProxyd, parsedReq.Method, err)
+				responses[i] = NewRPCErrorRes(parsedReq.ID, err)
+				continue
+			}
+			if err := s.txFeeFilter.Check(ctx, tx); err != nil {
+				RecordRPCError(ctx, BackendProxyd, parsedReq.Method, err)
+				s.senderReputation.Record(ctx, tx, ReputationEventSpam)
+				responses[i] = NewRPCErrorRes(parsedReq.ID, err)
+				continue
+			}
 			if err := s.rateLimitSender(ctx, tx); err != nil {
 				RecordRPCError(ctx, BackendProxyd, parsedReq.Method, err)
+				if errors.Is(err, ErrOverSenderRateLimit) {
+					RecordRejectedRequest(RejectReasonRateLimited)
+					s.senderReputation.Record(ctx, tx, ReputationEventRateLimited)
+				}
 				responses[i] = NewRPCErrorRes(parsedReq.ID, err)
 				continue
 			}
 			if err := s.validateInteropSendRpcRequest(ctx, tx); err != nil {
 				RecordRPCError(ctx, BackendProxyd, parsedReq.Method, err)
+				s.senderReputation.Record(ctx, tx, ReputationEventSpam)
 				responses[i] = NewRPCErrorRes(parsedReq.ID, err)
 				continue
 			}
+			lowReputation[i] = s.senderReputation.Low(ctx, tx)
+			if lowReputation[i] {
+				if err := s.senderReputation.Limit(ctx, tx); err != nil {
+					RecordRPCError(ctx, BackendProxyd, parsedReq.Method, err)
+					RecordRejectedRequest(RejectReasonRateLimited)
+					s.senderReputation.Record(ctx, tx, ReputationEventRateLimited)
+					responses[i] = NewRPCErrorRes(parsedReq.ID, err)
+					continue
+				}
+			}
+			if tx.Type() == types.BlobTxType && s.blobTx.BackendGroup != "" {
+				group = s.blobTx.BackendGroup
+			}
+			sendTxs[i] = tx
+		}
+
+		if err := s.callPolicies.Check(ctx, parsedReq); err != nil {
+			RecordRPCError(ctx, BackendProxyd, parsedReq.Method, err)
+			RecordRejectedRequest(RejectReasonMethodBlocked)
+			responses[i] = NewRPCErrorRes(parsedReq.ID, err)
+			continue
+		}
 
+		if group, err = s.selectors.Apply(ctx, parsedReq, sendTxs[i], group); err != nil {
+			RecordRPCError(ctx, BackendProxyd, parsedReq.Method, err)
+			RecordRejectedRequest(RejectReasonRateLimited)
+			responses[i] = NewRPCErrorRes(parsedReq.ID, err)
+			continue
+		}
+		// after the selector rules, which may route the transaction too
+		if lowReputation[i] {
+			group = s.senderReputation.Route(group)
+		}
+
+		admitted[i] = parsedReq
+		groups[i] = group
+	}
+
+	// ask the policy service about the whole batch at once
+	policyErrs := s.policy.CheckAll(ctx, admitted)
+
+	for i, parsedReq := range admitted {
+		if parsedReq == nil {
+			continue
+		}
+		group := groups[i]
+
+		if err := policyErrs[i]; err != nil {
+			RecordRPCError(ctx, BackendProxyd, parsedReq.Method, err)
+			responses[i] = NewRPCErrorRes(parsedReq.ID, err)
+			continue
+		}
+
+		parsedReq, pluginGroup, pluginRes := s.plugins.PreForward(ctx, parsedReq, group)
+		parsedReqs[i] = parsedReq
+		if pluginRes != nil {
+			responses[i] = pluginRes
+			continue
+		}
+		if pluginGroup != group {
+			if err := s.checkPluginGroup(ctx, parsedReq, pluginGroup); err != nil {
+				responses[i] = NewRPCErrorRes(parsedReq.ID, err)
+				continue
+			}
+			group = pluginGroup
+		}
+
+		// taken last, so that a nonce isn't held by a request proxyd rejects
+		if sendTxs[i] != nil {
+			taken, err := s.takePendingTx(ctx, sendTxs[i])
+			if err != nil {
+				RecordRPCError(ctx, BackendProxyd, parsedReq.Method, err)
+				s.senderReputation.Record(ctx, sendTxs[i], ReputationEventSpam)
+				responses[i] = NewRPCErrorRes(parsedReq.ID, err)
+				continue
+			}
+			pendingTaken[i] = taken
+		}
+
+		if s.filters.Handles(parsedReq.Method) {
+			responses[i] = s.filters.Handle(ctx, parsedReq, s.BackendGroups[group])
+			backends[i] = BackendProxyd
+			continue
+		}
+
+		if s.gasOracle.Handles(parsedReq.Method) {
+			res, err := s.gasOracle.Get(ctx, parsedReq, group)
+			if err != nil {
+				log.Error("error getting fees from gas oracle", "req_id", GetReqID(ctx), "method", parsedReq.Method, "err", err)
+				responses[i] = NewRPCErrorRes(parsedReq.ID, err)
+				continue
+			}
+			if res != nil {
+				responses[i] = res
+				backends[i] = "gas_oracle"
+				continue
+			}
+		}
+
+		if s.earlyReturn.Handles(parsedReq.Method) {
+			responses[i] = s.earlyReturn.Status(ctx, parsedReq)
+			backends[i] = BackendProxyd
+			continue
+		}
+
+		if s.txPreferences.Handles(parsedReq.Method) {
+			responses[i] = s.txPreferences.Handle(ctx, parsedReq)
+			backends[i] = BackendProxyd
+			continue
+		}
+
+		if sendTxs[i] != nil && s.earlyReturn.Applies(ctx) {
+			fwdCtx := ctx
+			if query, ok := s.txQuery(ctx, sendTxs[i], lowReputation[i]); ok {
+				fwdCtx = context.WithValue(ctx, ContextKeyRawQuery, query) // nolint:staticcheck
+			}
+			tx, taken := sendTxs[i], pendingTaken[i]
+			responses[i] = s.earlyReturn.Forward(fwdCtx, parsedReq, tx, s.BackendGroups[group], func(ctx context.Context, res *RPCRes, servedBy string) {
+				s.observeTxResponse(ctx, parsedReq, tx, res, servedBy, taken)
+			})
+			backends[i] = BackendProxyd
+			earlyReturned[i] = true
+			continue
+		}
+
+		if res := s.estimateGas.Lookup(group, parsedReq); res != nil {
+			responses[i] = res
+			backends[i] = "estimate_cache"
+			continue
+		}
+
+		if res := s.cacheWarmer.Lookup(ctx, group, parsedReq); res != nil {
+			responses[i] = res
+			backends[i] = "cache"
+			continue
 		}
 
 		id := string(parsedReq.ID)
@@ -648,19 +1298,44 @@ func (s *Server) handleBatchRPC(ctx context.Context, reqs []json.RawMessage, isL
 		ids[id]++
 		batchGroupID := ids[id]
 		batchGroup := batchGroup{groupID: batchGroupID, backendGroup: group}
+		if sendTxs[i] != nil {
+			batchGroup.query, batchGroup.overrideQuery = s.txQuery(ctx, sendTxs[i], lowReputation[i])
+		}
+		if s.estimateGas.Applies(parsedReq) {
+			batchGroup.backend = s.estimateGas.Backend()
+		}
 		batches[batchGroup] = append(batches[batchGroup], batchElem{parsedReq, i})
 	}
 
 	servedBy := make(map[string]bool, 0)
 	var cached bool
+	useCache := s.featureFlags.Enabled(ctx, FeatureCache)
 	for group, batch := range batches {
 		var cacheMisses []batchElem
+		cacheCtx := ctx
+		if bg := s.BackendGroups[group.backendGroup]; bg != nil && bg.Consensus != nil {
+			cacheCtx = WithFinalizedBlock(ctx, bg.Consensus.GetFinalizedBlockNumber())
+		}
 
 		for _, req := range batch {
-			backendRes, _ := s.cache.GetRPC(ctx, req.Req)
+			s.cacheControl.Request(ctx, req)
+			if !useCache {
+				cacheMisses = append(cacheMisses, req)
+				continue
+			}
+			start := time.Now()
+			backendRes, err := s.cache.GetRPC(ctx, req.Req)
+			addStageTime(ctx, requestStageCache, start)
+			if err != nil && s.cacheFailClosed && errors.Is(err, ErrRedisUnavailable) {
+				RecordRPCError(ctx, BackendProxyd, req.Req.Method, ErrRedisUnavailable)
+				responses[req.Index] = NewRPCErrorRes(req.Req.ID, ErrRedisUnavailable)
+				continue
+			}
 			if backendRes != nil {
 				responses[req.Index] = backendRes
+				backends[req.Index] = "cache"
 				cached = true
+				s.cacheControl.Response(cacheCtx, req, backendRes)
 			} else {
 				cacheMisses = append(cacheMisses, req)
 			}
@@ -678,11 +1353,47 @@ func (s *Server) handleBatchRPC(ctx context.Context, reqs []json.RawMessage, isL
 				batchRPCShortCircuitsTotal.Inc()
 				return nil, false, "", context.DeadlineExceeded
 			}
+			if ctx.Err() == context.Canceled {
+				log.Info("client canceled batch RPC",
+					"req_id", GetReqID(ctx),
+					"auth", GetAuthCtx(ctx),
+					"batch_index", i,
+				)
+				recordCanceledRequests(parsedReqs, responses, "pending")
+				return nil, false, "", ErrContextCanceled
+			}
 
 			start := i * s.maxUpstreamBatchSize
 			end := int(math.Min(float64(start+s.maxUpstreamBatchSize), float64(len(cacheMisses))))
 			elems := cacheMisses[start:end]
-			res, sb, err := s.BackendGroups[group.backendGroup].Forward(ctx, createBatchRequest(elems), isBatch)
+			fwdCtx, pinned := ctx, ""
+			if backend := s.readYourWrites.Backend(elems); backend != "" {
+				fwdCtx = WithPreferredBackend(ctx, backend)
+			}
+			if s.affinity.Applies(elems) {
+				fwdCtx, pinned = s.affinity.Pin(ctx, group.backendGroup)
+			}
+			if group.backend != "" {
+				fwdCtx = WithPreferredBackend(fwdCtx, group.backend)
+			}
+			if group.overrideQuery {
+				fwdCtx = context.WithValue(fwdCtx, ContextKeyRawQuery, group.query) // nolint:staticcheck
+			}
+			res, sb, err := s.BackendGroups[group.backendGroup].Forward(fwdCtx, createBatchRequest(elems), isBatch)
+			if errors.Is(err, ErrContextCanceled) {
+				for _, elem := range elems {
+					RecordCanceledRPCRequest(elem.Req.Method, "in_flight")
+					responses[elem.Index] = NewRPCErrorRes(elem.Req.ID, err)
+				}
+				recordCanceledRequests(parsedReqs, responses, "pending")
+				return nil, false, "", ErrContextCanceled
+			}
+			if err == nil && s.affinity.Applies(elems) {
+				s.affinity.Served(ctx, group.backendGroup, pinned, sb)
+			}
+			if err == nil {
+				res = s.notFoundRetry.Retry(ctx, s.BackendGroups[group.backendGroup], elems, res)
+			}
 			servedBy[sb] = true
 			if err != nil {
 				if errors.Is(err, ErrConsensusGetReceiptsCantBeBatched) ||
@@ -703,11 +1414,14 @@ func (s *Server) handleBatchRPC(ctx context.Context, reqs []json.RawMessage, isL
 			}
 
 			for i := range elems {
+				s.estimateGas.Response(group.backendGroup, elems[i].Req, res[i])
 				responses[elems[i].Index] = res[i]
+				backends[elems[i].Index] = sb
+				s.cacheControl.Response(cacheCtx, elems[i], res[i])
 
 				// TODO(inphi): batch put these
-				if res[i].Error == nil && res[i].Result != nil {
-					if err := s.cache.PutRPC(ctx, elems[i].Req, res[i]); err != nil {
+				if useCache && res[i].Error == nil && res[i].Result != nil {
+					if err := s.cache.PutRPC(cacheCtx, elems[i].Req, res[i]); err != nil {
 						log.Warn(
 							"cache put error",
 							"req_id", GetReqID(ctx),
@@ -719,6 +1433,33 @@ func (s *Server) handleBatchRPC(ctx context.Context, reqs []json.RawMessage, isL
 		}
 	}
 
+	for i, tx := range sendTxs {
+		// early returned transactions are observed once they're forwarded
+		if tx != nil && responses[i] != nil && !earlyReturned[i] {
+			s.observeTxResponse(ctx, parsedReqs[i], tx, responses[i], backends[i], pendingTaken[i])
+		}
+	}
+
+	if s.pendingNonces != nil {
+		for i, req := range parsedReqs {
+			if req == nil || responses[i] == nil {
+				continue
+			}
+			responses[i] = s.pendingNonces.RewriteResponse(ctx, req, responses[i])
+		}
+	}
+
+	if s.plugins != nil {
+		for i, req := range parsedReqs {
+			if req != nil && responses[i] != nil {
+				responses[i] = s.plugins.PostResponse(ctx, req, responses[i])
+			}
+		}
+	}
+	s.events.PublishRequests(ctx, parsedReqs, responses, backends, time.Since(start))
+	s.txAudit.RecordRequests(ctx, parsedReqs, responses, backends, sendTxs)
+	s.usageExporter.RecordRequests(ctx, parsedReqs, responses)
+
 	servedByString := ""
 	for sb := range servedBy {
 		if servedByString != "" {
@@ -730,7 +1471,44 @@ func (s *Server) handleBatchRPC(ctx context.Context, reqs []json.RawMessage, isL
 	return responses, cached, servedByString, nil
 }
 
+// recordCanceledRequests counts the requests still without a response when
+// the client went away.
+func recordCanceledRequests(reqs []*RPCReq, responses []*RPCRes, stage string) {
+	for i, req := range reqs {
+		if req != nil && responses[i] == nil {
+			RecordCanceledRPCRequest(req.Method, stage)
+		}
+	}
+}
+
+func (s *Server) checkPluginGroup(ctx context.Context, req *RPCReq, group string) error {
+	if s.BackendGroups[group] != nil && s.tenants.Allows(GetTenant(ctx), group) {
+		return nil
+	}
+	log.Error(
+		"plugin chose unknown or reserved backend group",
+		"source", "rpc",
+		"req_id", GetReqID(ctx),
+		"method", req.Method,
+		"backend_group", group,
+	)
+	RecordRPCError(ctx, BackendProxyd, req.Method, ErrPluginFailed)
+	return ErrPluginFailed
+}
+
 func (s *Server) HandleWS(w http.ResponseWriter, r *http.Request) {
+	s.serveWS(w, r, nil)
+}
+
+// handleBinaryWS proxies websockets exchanging binary messages encoded with
+// codec, transcoded to JSON for the backend.
+func (s *Server) handleBinaryWS(codec *binaryCodec) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.serveWS(w, r, codec)
+	}
+}
+
+func (s *Server) serveWS(w http.ResponseWriter, r *http.Request, codec *binaryCodec) {
 	ctx := s.populateContext(w, r)
 	if ctx == nil {
 		return
@@ -745,7 +1523,11 @@ func (s *Server) HandleWS(w http.ResponseWriter, r *http.Request) {
 	}
 	clientConn.SetReadLimit(s.maxBodySize)
 
-	proxier, err := s.wsBackendGroup.ProxyWS(ctx, clientConn, s.wsMethodWhitelist)
+	wsBackendGroup := s.wsBackendGroup
+	if vhost := GetVirtualHost(ctx); vhost != nil && vhost.wsBackendGroup != nil {
+		wsBackendGroup = vhost.wsBackendGroup
+	}
+	proxier, err := wsBackendGroup.ProxyWS(ctx, clientConn, GetAuthPolicy(ctx).wsMethodWhitelist(s.wsMethodWhitelist))
 	if err != nil {
 		if errors.Is(err, ErrNoBackends) {
 			RecordUnserviceableRequest(ctx, RPCRequestSourceWS)
@@ -755,6 +1537,18 @@ func (s *Server) HandleWS(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	proxier.codec = codec
+	proxier.deprecations = s.deprecations
+	proxier.sendQueue = newWSSendQueue(s.wsSendBuffer)
+	if s.wsNotificationBatching.Enabled && r.URL.Query().Get(WSBatchNotificationsParam) == "true" {
+		proxier.batching = &s.wsNotificationBatching
+	}
+	if s.wsReplay != nil {
+		proxier.replay = s.wsReplay
+		proxier.pendingReplays = make(map[string]wsPendingReplay)
+		proxier.replaySubs = make(map[string]wsPendingReplay)
+	}
+
 	activeClientWsConnsGauge.WithLabelValues(GetAuthCtx(ctx)).Inc()
 	go func() {
 		// Below call blocks so run it in a goroutine.
@@ -770,6 +1564,12 @@ func (s *Server) HandleWS(w http.ResponseWriter, r *http.Request) {
 func (s *Server) populateContext(w http.ResponseWriter, r *http.Request) context.Context {
 	vars := mux.Vars(r)
 	authorization := vars["authorization"]
+	// RPC routes match any path, so the secret is its first segment
+	authPath := "/"
+	if authorization == "" {
+		authorization, authPath, _ = strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+		authPath = "/" + authPath
+	}
 	xff := r.Header.Get(s.rateLimitHeader)
 	if xff == "" {
 		ipPort := strings.Split(r.RemoteAddr, ":")
@@ -778,41 +1578,67 @@ func (s *Server) populateContext(w http.ResponseWriter, r *http.Request) context
 		}
 	}
 
-	ctx := context.WithValue(r.Context(), ContextKeyXForwardedFor, xff) // nolint:staticcheck
+	ctx := context.WithValue(r.Context(), ContextKeyXForwardedFor, xff)              // nolint:staticcheck
+	ctx = context.WithValue(ctx, ContextKeyClientIP, s.ipHasher.Hash(stripXFF(xff))) // nolint:staticcheck
 
 	// Store query parameters and path for forwarding to backend
 	ctx = context.WithValue(ctx, ContextKeyRawQuery, r.URL.RawQuery) // nolint:staticcheck
 	ctx = context.WithValue(ctx, ContextKeyPath, r.URL.Path)         // nolint:staticcheck
 
-	opTxProxyAuth := r.Header.Get(DefaultOpTxProxyAuthHeader)
-	if opTxProxyAuth != "" {
-		ctx = context.WithValue(ctx, ContextKeyOpTxProxyAuth, opTxProxyAuth) // nolint:staticcheck
+	ctx = context.WithValue(ctx, ContextKeyOrigin, r.Header.Get("Origin")) // nolint:staticcheck
+
+	if s.featureFlags != nil {
+		ctx = context.WithValue(ctx, ContextKeyFeatureFlags, s.featureFlags) // nolint:staticcheck
+	}
+
+	if vhost := s.virtualHosts.Select(r); vhost != nil {
+		ctx = WithVirtualHost(ctx, vhost)
+	}
+	if tenant := s.tenants.Select(r); tenant != nil {
+		ctx = WithTenant(ctx, tenant)
 	}
 
-	if len(s.authenticatedPaths) > 0 {
-		if authorization == "" || s.authenticatedPaths[authorization] == "" {
-			log.Info("blocked unauthorized request", "authorization", authorization)
+	s.liveMu.RLock()
+	authenticated := len(s.authenticatedPaths) > 0
+	s.liveMu.RUnlock()
+	if authenticated || s.keyStore != nil || s.hmacAuth != nil {
+		alias, keyID, policy, err := s.authenticate(ctx, authorization)
+		if err != nil {
+			log.Error("error authenticating request", "err", err)
+			httpResponseCodesTotal.WithLabelValues("500").Inc()
+			w.WriteHeader(500)
+			return nil
+		}
+		// signed requests are authenticated once their body is read. Only
+		// RPC requests, which are POSTs, have a body to sign.
+		signed := r.Method == http.MethodPost && s.hmacAuth.Signed(r)
+		if alias == "" && !signed {
+			log.Info("blocked unauthorized request", "authorization", sanitizeLogValue(authorization))
+			RecordRejectedRequest(RejectReasonAuthFailed)
 			httpResponseCodesTotal.WithLabelValues("401").Inc()
 			w.WriteHeader(401)
 			return nil
 		}
 
-		ctx = context.WithValue(ctx, ContextKeyAuth, s.authenticatedPaths[authorization]) // nolint:staticcheck
+		if alias != "" {
+			ctx = context.WithValue(ctx, ContextKeyAuth, alias) // nolint:staticcheck
+			// the secret isn't forwarded to backends with the path
+			ctx = context.WithValue(ctx, ContextKeyPath, authPath) // nolint:staticcheck
+			if policy != nil {
+				ctx = WithAuthPolicy(ctx, policy)
+			}
+			if keyID != "" {
+				ctx = WithAPIKeyID(ctx, keyID)
+			}
+		}
 	}
 
 	if len(s.allowedDynamicHeaders) > 0 {
-		filteredHeaderValues := make(map[string][]string)
-		for _, h := range s.allowedDynamicHeaders {
-			values := r.Header.Values(h)
-			if len(values) > 0 {
-				filteredHeaderValues[h] = values
-			}
-		}
+		filteredHeaderValues := s.captureHeaders(r.Header)
 		if len(filteredHeaderValues) > 0 {
 			log.Debug("proxying dynamic headers")
 			ctx = context.WithValue(ctx, ContextKeyHeadersToForward, filteredHeaderValues) // nolint:staticcheck
 		}
-
 	}
 
 	return context.WithValue(
@@ -822,6 +1648,48 @@ func (s *Server) populateContext(w http.ResponseWriter, r *http.Request) context
 	)
 }
 
+// authenticate returns the alias of secret, an [authentication] secret or a
+// key issued at runtime, the ID of the key if it's one, and its auth policy.
+// The alias is empty if secret isn't valid.
+func (s *Server) authenticate(ctx context.Context, secret string) (string, string, *AuthPolicy, error) {
+	if secret == "" {
+		return "", "", nil, nil
+	}
+	s.liveMu.RLock()
+	alias := s.authenticatedPaths[secret]
+	s.liveMu.RUnlock()
+	if alias != "" {
+		return alias, "", s.authPolicies[alias], nil
+	}
+	key, policy, err := s.keyStore.Lookup(ctx, secret)
+	if err != nil || key == nil {
+		return "", "", nil, err
+	}
+	return key.Owner, key.ID, policy, nil
+}
+
+// captureHeaders collects the client headers that at least one backend's
+// header policy may forward. Each backend filters them again when forwarding.
+func (s *Server) captureHeaders(header http.Header) map[string][]string {
+	captured := make(map[string][]string)
+	for _, h := range s.allowedDynamicHeaders {
+		if h == ForwardAllHeaders {
+			for name, values := range header {
+				if isHopByHopHeader(name) || slices.Contains(defaultStrippedHeaders, name) {
+					continue
+				}
+				captured[name] = values
+			}
+			continue
+		}
+		values := header.Values(h)
+		if len(values) > 0 {
+			captured[http.CanonicalHeaderKey(h)] = values
+		}
+	}
+	return captured
+}
+
 func randStr(l int) string {
 	b := make([]byte, l)
 	if _, err := rand.Read(b); err != nil {
@@ -850,6 +1718,8 @@ func (s *Server) isUnlimitedUserAgent(origin string) bool {
 }
 
 func (s *Server) isGlobalLimit(method string) bool {
+	s.liveMu.RLock()
+	defer s.liveMu.RUnlock()
 	return s.globallyLimitedMethods[method]
 }
 
@@ -887,12 +1757,37 @@ func convertSendReqToSendTx(ctx context.Context, req *RPCReq) (*types.Transactio
 	tx := new(types.Transaction)
 	if err := tx.UnmarshalBinary(data); err != nil {
 		log.Debug("could not unmarshal transaction", "err", err, "req_id", GetReqID(ctx))
+		if errors.Is(err, types.ErrTxTypeNotSupported) && len(data) > 0 {
+			RecordRawTransaction("unsupported")
+			return nil, ErrInvalidParams(fmt.Sprintf("transaction type 0x%02x not supported", data[0]))
+		}
 		return nil, ErrInvalidParams(err.Error())
 	}
 
+	txType, ok := txTypeNames[tx.Type()]
+	if !ok {
+		txType = "unknown"
+	}
+	RecordRawTransaction(txType)
+	// Deposits are only derived from L1, so they have no sender signature.
+	if tx.IsDepositTx() {
+		log.Debug("rejecting deposit transaction", "req_id", GetReqID(ctx))
+		return nil, ErrInvalidParams("deposit transactions can't be submitted")
+	}
+
 	return tx, nil
 }
 
+// txTypeNames labels the transaction types that can be decoded.
+var txTypeNames = map[uint8]string{
+	types.LegacyTxType:     "legacy",
+	types.AccessListTxType: "access_list",
+	types.DynamicFeeTxType: "dynamic_fee",
+	types.BlobTxType:       "blob",
+	types.SetCodeTxType:    "set_code",
+	types.DepositTxType:    "deposit",
+}
+
 func (s *Server) genericRateLimitSender(ctx context.Context, tx *types.Transaction, lim FrontendRateLimiter) error {
 	// Check if the transaction is for the expected chain,
 	// otherwise reject before rate limiting to avoid replay attacks.
@@ -901,33 +1796,130 @@ func (s *Server) genericRateLimitSender(ctx context.Context, tx *types.Transacti
 		return txpool.ErrInvalidSender
 	}
 
-	var signer types.Signer
-	// If you pass in a zero chain ID, types.LatestSignerForChainID panics. So we need to handle that case
-	// manually.
-	if tx.ChainId().Sign() == 0 {
-		signer = new(types.HomesteadSigner)
-	} else {
-		signer = types.LatestSignerForChainID(tx.ChainId())
-	}
-
-	from, err := types.Sender(signer, tx)
+	from, err := txSender(tx)
 	if err != nil {
 		log.Debug("could not get sender from transaction", "err", err, "req_id", GetReqID(ctx))
 		return ErrInvalidParams(err.Error())
 	}
+	if _, exempt := s.rateLimitExemptions.Exempt(ctx); exempt {
+		return nil
+	}
+	start := time.Now()
 	ok, err := lim.Take(ctx, fmt.Sprintf("%s:%d", from.Hex(), tx.Nonce()))
+	addStageTime(ctx, requestStageRateLimit, start)
+	if errors.Is(err, ErrRedisUnavailable) {
+		if s.senderLimitsFailOpen {
+			log.Warn("redis is unavailable, skipping sender limit", "err", err, "req_id", GetReqID(ctx))
+			return nil
+		}
+		log.Error("redis is unavailable, rejecting sender", "err", err, "req_id", GetReqID(ctx))
+		return ErrRedisUnavailable
+	}
 	if err != nil {
 		log.Error("error taking from sender limiter", "err", err, "req_id", GetReqID(ctx))
 		return ErrInternal
 	}
 	if !ok {
 		log.Debug("sender rate limit exceeded", "sender", from.Hex(), "req_id", GetReqID(ctx))
+		s.webhooks.NotifyOnce(WebhookEventSenderLimitAbuse, from.Hex(), senderAbuseNotifyInterval, map[string]interface{}{
+			"sender": from.Hex(),
+			"nonce":  tx.Nonce(),
+			"auth":   GetAuthCtx(ctx),
+		})
 		return ErrOverSenderRateLimit
 	}
 
 	return nil
 }
 
+func txSender(tx *types.Transaction) (common.Address, error) {
+	var signer types.Signer
+	// If you pass in a zero chain ID, types.LatestSignerForChainID panics. So we need to handle that case
+	// manually.
+	if tx.ChainId().Sign() == 0 {
+		signer = new(types.HomesteadSigner)
+	} else {
+		signer = types.LatestSignerForChainID(tx.ChainId())
+	}
+	return types.Sender(signer, tx)
+}
+
+// checkBlobTx enforces the blob_tx limits on blob transactions.
+func (s *Server) checkBlobTx(ctx context.Context, tx *types.Transaction) error {
+	if tx.Type() != types.BlobTxType {
+		return nil
+	}
+	blobs := len(tx.BlobHashes())
+	if s.blobTx.MaxBlobs > 0 && blobs > s.blobTx.MaxBlobs {
+		log.Debug("blob transaction has too many blobs", "blobs", blobs, "req_id", GetReqID(ctx))
+		RecordBlobTransaction(blobs, false)
+		return ErrTooManyBlobs
+	}
+	if s.blobTx.MaxBlobFeeCap != nil && tx.BlobGasFeeCap().Cmp(s.blobTx.MaxBlobFeeCap) > 0 {
+		log.Debug("blob transaction fee cap too high", "blob_fee_cap", tx.BlobGasFeeCap(), "req_id", GetReqID(ctx))
+		RecordBlobTransaction(blobs, false)
+		return ErrBlobFeeCapTooHigh
+	}
+	RecordBlobTransaction(blobs, true)
+	return nil
+}
+
+func (s *Server) takePendingTx(ctx context.Context, tx *types.Transaction) (bool, error) {
+	if s.pendingTxs == nil {
+		return false, nil
+	}
+	from, err := txSender(tx)
+	if err != nil {
+		log.Debug("could not get sender from transaction", "err", err, "req_id", GetReqID(ctx))
+		return false, ErrInvalidParams(err.Error())
+	}
+	return s.pendingTxs.Take(ctx, from, tx.Nonce())
+}
+
+// observeTxResponse records the outcome of submitting tx, served by the
+// backend servedBy, with the features that follow submitted transactions.
+// taken is whether the pending transaction limiter recorded tx.
+func (s *Server) observeTxResponse(ctx context.Context, req *RPCReq, tx *types.Transaction, res *RPCRes, servedBy string, taken bool) {
+	if res.IsError() {
+		s.senderReputation.Record(ctx, tx, ReputationEventFailed)
+		// rejected transactions don't count against the pending cap
+		if taken {
+			s.releasePendingTx(ctx, tx)
+		}
+		return
+	}
+	s.txDedup.Observe(req, res)
+	s.readYourWrites.Observe(tx, servedBy)
+	s.notFoundRetry.ObserveTx(tx)
+	s.senderReputation.Record(ctx, tx, ReputationEventAccepted)
+	s.pendingNonces.ObserveTx(ctx, tx)
+}
+
+func (s *Server) releasePendingTx(ctx context.Context, tx *types.Transaction) {
+	from, err := txSender(tx)
+	if err != nil {
+		return
+	}
+	s.pendingTxs.Release(ctx, from, tx.Nonce())
+}
+
+// txQuery returns the query string to submit tx with, and whether it
+// replaces the request's: the submission preferences of its sender or key,
+// without the fast priority if the sender's reputation is low.
+func (s *Server) txQuery(ctx context.Context, tx *types.Transaction, lowReputation bool) (string, bool) {
+	query := s.txPreferences.Query(ctx, tx)
+	override := query != ""
+	if lowReputation {
+		if !override {
+			query, _ = ctx.Value(ContextKeyRawQuery).(string)
+		}
+		if stripped := withoutFastPriority(query); stripped != query {
+			return stripped, true
+		}
+	}
+	return query, override
+}
+
 func (s *Server) rateLimitSender(ctx context.Context, tx *types.Transaction) error {
 	if s.senderLim == nil {
 		log.Warn("sender rate limiter is not enabled, skipping", "req_id", GetReqID(ctx))
@@ -967,6 +1959,7 @@ func writeRPCError(ctx context.Context, w http.ResponseWriter, id json.RawMessag
 }
 
 func writeRPCRes(ctx context.Context, w http.ResponseWriter, res *RPCRes) {
+	defer addStageTime(ctx, requestStageSerialize, time.Now())
 	statusCode := 200
 	if res.IsError() && res.Error.HTTPErrorCode != 0 {
 		statusCode = res.Error.HTTPErrorCode
@@ -986,6 +1979,7 @@ func writeRPCRes(ctx context.Context, w http.ResponseWriter, res *RPCRes) {
 }
 
 func writeBatchRPCRes(ctx context.Context, w http.ResponseWriter, res []*RPCRes) {
+	defer addStageTime(ctx, requestStageSerialize, time.Now())
 	w.Header().Set("content-type", "application/json")
 	w.WriteHeader(200)
 	ww := &recordLenWriter{Writer: w}
@@ -1015,12 +2009,11 @@ func GetAuthCtx(ctx context.Context) string {
 	return authUser
 }
 
-func GetOpTxProxyAuthHeader(ctx context.Context) string {
-	auth, ok := ctx.Value(ContextKeyOpTxProxyAuth).(string)
-	if !ok {
-		return ""
-	}
-	return auth
+// GetSigner returns the address that signed the request's X-Flashbots-Signature,
+// if proxyd verified it.
+func GetSigner(ctx context.Context) (common.Address, bool) {
+	signer, ok := ctx.Value(ContextKeySigner).(common.Address)
+	return signer, ok
 }
 
 func GetReqID(ctx context.Context) string {
@@ -1031,6 +2024,13 @@ func GetReqID(ctx context.Context) string {
 	return reqId
 }
 
+// GetClientIP returns the client IP to log, store or limit by, which is
+// pseudonymized in IP privacy mode.
+func GetClientIP(ctx context.Context) string {
+	ip, _ := ctx.Value(ContextKeyClientIP).(string)
+	return ip
+}
+
 func GetXForwardedFor(ctx context.Context) string {
 	xff, ok := ctx.Value(ContextKeyXForwardedFor).(string)
 	if !ok {
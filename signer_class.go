@@ -0,0 +1,117 @@
+package proxyd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+type requestPriority int
+
+const (
+	priorityNormal requestPriority = iota
+	// never shed
+	priorityHigh
+	// shed along with the low priority methods
+	priorityLow
+)
+
+func parseRequestPriority(s string) (requestPriority, error) {
+	switch s {
+	case "", "normal":
+		return priorityNormal, nil
+	case "high":
+		return priorityHigh, nil
+	case "low":
+		return priorityLow, nil
+	default:
+		return 0, fmt.Errorf("invalid priority %q, must be high, normal or low", s)
+	}
+}
+
+// SignerClass gives the searchers whose X-Flashbots-Signature signer is in
+// the class their own treatment: a load shedding priority, rate limits
+// counted per signer, and method mappings.
+type SignerClass struct {
+	Name              string
+	priority          requestPriority
+	mainLim           FrontendRateLimiter
+	overrideLims      map[string]FrontendRateLimiter
+	rpcMethodMappings map[string]string
+}
+
+// SignerClasses are keyed by signer.
+type SignerClasses map[common.Address]*SignerClass
+
+func NewSignerClasses(cfg map[string]SignerClassConfig, groups map[string]*BackendGroup, limiterFactory limiterFactoryFunc) (SignerClasses, error) {
+	if len(cfg) == 0 {
+		return nil, nil
+	}
+	classes := make(SignerClasses)
+	for _, name := range sortedKeys(cfg) {
+		sc := cfg[name]
+		priority, err := parseRequestPriority(sc.Priority)
+		if err != nil {
+			return nil, fmt.Errorf("signer class %s: %w", name, err)
+		}
+		for _, method := range sortedKeys(sc.RPCMethodMappings) {
+			if groups[sc.RPCMethodMappings[method]] == nil {
+				return nil, fmt.Errorf("signer class %s maps method %s to undefined backend group %s", name, method, sc.RPCMethodMappings[method])
+			}
+		}
+		class := &SignerClass{
+			Name:              name,
+			priority:          priority,
+			mainLim:           NoopFrontendRateLimiter,
+			overrideLims:      make(map[string]FrontendRateLimiter),
+			rpcMethodMappings: sc.RPCMethodMappings,
+		}
+		if sc.RateLimit.BaseRate > 0 {
+			class.mainLim = limiterFactory(time.Duration(sc.RateLimit.BaseInterval), sc.RateLimit.BaseRate, "signer_class_"+name)
+		}
+		for method, override := range sc.RateLimit.MethodOverrides {
+			class.overrideLims[method] = limiterFactory(time.Duration(override.Interval), override.Limit, "signer_class_"+name+"_"+method)
+		}
+		for _, s := range sc.Signers {
+			if !common.IsHexAddress(s) {
+				return nil, fmt.Errorf("signer class %s has invalid signer %s", name, s)
+			}
+			signer := common.HexToAddress(s)
+			if other := classes[signer]; other != nil {
+				return nil, fmt.Errorf("signer %s is in signer classes %s and %s", s, other.Name, name)
+			}
+			classes[signer] = class
+		}
+	}
+	return classes, nil
+}
+
+func (c *SignerClass) loadPriority() requestPriority {
+	if c == nil {
+		return priorityNormal
+	}
+	return c.priority
+}
+
+// route returns the backend group serving method for the class, given the
+// group it's mapped to otherwise.
+func (c *SignerClass) route(method, group string) string {
+	if c == nil {
+		return group
+	}
+	if classGroup, ok := c.rpcMethodMappings[method]; ok {
+		return classGroup
+	}
+	return group
+}
+
+func WithSignerClass(ctx context.Context, class *SignerClass) context.Context {
+	return context.WithValue(ctx, ContextKeySignerClass, class) // nolint:staticcheck
+}
+
+func GetSignerClass(ctx context.Context) *SignerClass {
+	class, _ := ctx.Value(ContextKeySignerClass).(*SignerClass)
+	return class
+}
@@ -0,0 +1,53 @@
+package proxyd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSignerClasses(t *testing.T) {
+	groups := map[string]*BackendGroup{"main": {}, "gold": {}}
+	limiterFactory := func(dur time.Duration, max int, prefix string) FrontendRateLimiter {
+		return NewMemoryFrontendRateLimit(dur, max)
+	}
+	gold := common.HexToAddress("0x3c44cdddb6a900fa2b585dd299e03d12fa4293bc")
+	classes, err := NewSignerClasses(map[string]SignerClassConfig{
+		"gold": {
+			Signers:           []string{gold.Hex()},
+			Priority:          "high",
+			RateLimit:         RateLimitConfig{BaseRate: 10, BaseInterval: TOMLDuration(time.Second)},
+			RPCMethodMappings: map[string]string{"eth_sendBundle": "gold"},
+		},
+	}, groups, limiterFactory)
+	require.NoError(t, err)
+
+	class := classes[gold]
+	require.NotNil(t, class)
+	require.Equal(t, priorityHigh, class.loadPriority())
+	require.Equal(t, "gold", class.route("eth_sendBundle", ""))
+	require.Equal(t, "main", class.route("eth_call", "main"))
+	require.NotEqual(t, NoopFrontendRateLimiter, class.mainLim)
+
+	var noClass *SignerClass
+	require.Equal(t, priorityNormal, noClass.loadPriority())
+	require.Equal(t, "main", noClass.route("eth_call", "main"))
+
+	invalid := map[string]map[string]SignerClassConfig{
+		"priority": {"gold": {Signers: []string{gold.Hex()}, Priority: "urgent"}},
+		"signer":   {"gold": {Signers: []string{"0x1234"}}},
+		"group":    {"gold": {Signers: []string{gold.Hex()}, RPCMethodMappings: map[string]string{"eth_call": "platinum"}}},
+		"duplicate signer": {
+			"gold":   {Signers: []string{gold.Hex()}},
+			"silver": {Signers: []string{gold.Hex()}},
+		},
+	}
+	for name, cfg := range invalid {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewSignerClasses(cfg, groups, limiterFactory)
+			require.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,248 @@
+package proxyd
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// Status is the document served on /admin/status, summarizing the state of
+// proxyd for operators and their tooling during incidents.
+type Status struct {
+	Time          time.Time                     `json:"time"`
+	BackendGroups map[string]BackendGroupStatus `json:"backend_groups"`
+	Cache         CacheStatus                   `json:"cache"`
+	RateLimits    RateLimitStatus               `json:"rate_limits"`
+	Inflight      map[string]InflightStatus     `json:"inflight,omitempty"`
+	LoadShedding  int                           `json:"load_shedding_level"`
+	WSClientConns int                           `json:"ws_client_conns"`
+}
+
+type BackendGroupStatus struct {
+	RoutingStrategy RoutingStrategy  `json:"routing_strategy"`
+	Consensus       *ConsensusStatus `json:"consensus,omitempty"`
+	Backends        []BackendStatus  `json:"backends"`
+}
+
+// ConsensusStatus is the consensus of a consensus aware group.
+type ConsensusStatus struct {
+	LatestBlock    uint64   `json:"latest_block"`
+	SafeBlock      uint64   `json:"safe_block"`
+	FinalizedBlock uint64   `json:"finalized_block"`
+	Backends       []string `json:"backends"`
+}
+
+type BackendStatus struct {
+	Name          string     `json:"name"`
+	Healthy       bool       `json:"healthy"`
+	Degraded      bool       `json:"degraded"`
+	InMaintenance bool       `json:"in_maintenance"`
+	Score         *float64   `json:"score,omitempty"`
+	ErrorRate     float64    `json:"error_rate"`
+	RateLimitRate float64    `json:"rate_limit_rate"`
+	LatencyMs     int64      `json:"latency_ms"`
+	WSConns       int        `json:"ws_conns"`
+	BannedUntil   *time.Time `json:"banned_until,omitempty"`
+	// the blocks the backend reported to the consensus poller
+	LatestBlock    uint64 `json:"latest_block,omitempty"`
+	SafeBlock      uint64 `json:"safe_block,omitempty"`
+	FinalizedBlock uint64 `json:"finalized_block,omitempty"`
+}
+
+// CacheStatus counts the cache lookups since proxyd started.
+type CacheStatus struct {
+	Hits    uint64  `json:"hits"`
+	Misses  uint64  `json:"misses"`
+	HitRate float64 `json:"hit_rate"`
+}
+
+type RateLimitStatus struct {
+	// Rejections counts the requests rejected by the frontend limits since
+	// proxyd started, by reason.
+	Rejections map[string]uint64 `json:"rejections"`
+	// Fallback lists the Redis limiters currently limiting in memory.
+	Fallback []string `json:"fallback"`
+}
+
+// InflightStatus is the saturation of an in-flight request limit.
+type InflightStatus struct {
+	Requests   int     `json:"requests"`
+	Max        int     `json:"max"`
+	Queued     int64   `json:"queued"`
+	Saturation float64 `json:"saturation"`
+}
+
+// StatusReporter serves the status of proxyd on the admin API.
+type StatusReporter struct {
+	srv      *Server
+	groups   map[string]*BackendGroup
+	scorer   *BackendScorer
+	gatherer prometheus.Gatherer
+}
+
+func NewStatusReporter(srv *Server, groups map[string]*BackendGroup, scorer *BackendScorer) *StatusReporter {
+	return &StatusReporter{
+		srv:      srv,
+		groups:   groups,
+		scorer:   scorer,
+		gatherer: prometheus.DefaultGatherer,
+	}
+}
+
+// Status returns the current status. The counters and the WS connections
+// are read from the metrics.
+func (s *StatusReporter) Status() *Status {
+	families, err := s.gatherer.Gather()
+	if err != nil {
+		log.Warn("error gathering metrics for status", "err", err)
+	}
+	metrics := make(map[string][]*dto.Metric, len(families))
+	for _, family := range families {
+		metrics[family.GetName()] = family.GetMetric()
+	}
+
+	status := &Status{
+		Time:          time.Now().UTC(),
+		BackendGroups: make(map[string]BackendGroupStatus, len(s.groups)),
+		RateLimits: RateLimitStatus{
+			Rejections: make(map[string]uint64),
+			Fallback:   []string{},
+		},
+		LoadShedding:  s.srv.loadShedder.Level(),
+		WSClientConns: int(sumMetric(metrics["proxyd_active_client_ws_conns"])),
+	}
+
+	var scores map[string]float64
+	if s.scorer != nil {
+		scores = make(map[string]float64)
+		for _, list := range s.scorer.Scores() {
+			for _, score := range list {
+				scores[score.Backend] = score.Score
+			}
+		}
+	}
+	wsConns := metricByLabel(metrics["proxyd_active_backend_ws_conns"], "backend_name")
+	for name, bg := range s.groups {
+		status.BackendGroups[name] = s.groupStatus(bg, scores, wsConns)
+		if inflight, ok := bg.inflight.Status(); ok {
+			if status.Inflight == nil {
+				status.Inflight = make(map[string]InflightStatus)
+			}
+			status.Inflight[name] = inflight
+		}
+	}
+	if inflight, ok := s.srv.inflight.Status(); ok {
+		if status.Inflight == nil {
+			status.Inflight = make(map[string]InflightStatus)
+		}
+		status.Inflight["global"] = inflight
+	}
+
+	hits := sumMetric(metrics["proxyd_cache_hits_total"])
+	misses := sumMetric(metrics["proxyd_cache_misses_total"])
+	status.Cache = CacheStatus{Hits: uint64(hits), Misses: uint64(misses)}
+	if hits+misses > 0 {
+		status.Cache.HitRate = hits / (hits + misses)
+	}
+
+	for reason, count := range metricByLabel(metrics["proxyd_frontend_limit_rejections_total"], "reason") {
+		status.RateLimits.Rejections[reason] = uint64(count)
+	}
+	for limiter, fallback := range metricByLabel(metrics["proxyd_rate_limit_fallback"], "limiter") {
+		if fallback > 0 {
+			status.RateLimits.Fallback = append(status.RateLimits.Fallback, limiter)
+		}
+	}
+	sort.Strings(status.RateLimits.Fallback)
+	return status
+}
+
+func (s *StatusReporter) groupStatus(bg *BackendGroup, scores map[string]float64, wsConns map[string]float64) BackendGroupStatus {
+	status := BackendGroupStatus{
+		RoutingStrategy: bg.GetRoutingStrategy(),
+		Backends:        []BackendStatus{},
+	}
+	if bg.Consensus != nil {
+		consensus := &ConsensusStatus{
+			LatestBlock:    uint64(bg.Consensus.GetLatestBlockNumber()),
+			SafeBlock:      uint64(bg.Consensus.GetSafeBlockNumber()),
+			FinalizedBlock: uint64(bg.Consensus.GetFinalizedBlockNumber()),
+			Backends:       []string{},
+		}
+		for _, be := range bg.Consensus.GetConsensusGroup() {
+			consensus.Backends = append(consensus.Backends, be.Name)
+		}
+		status.Consensus = consensus
+	}
+
+	for _, be := range bg.members() {
+		backend := BackendStatus{
+			Name:          be.Name,
+			Healthy:       be.IsHealthy(),
+			Degraded:      be.IsDegraded(),
+			InMaintenance: be.InMaintenance(),
+			ErrorRate:     be.ErrorRate(),
+			RateLimitRate: be.RateLimitRate(),
+			LatencyMs:     time.Duration(be.latencySlidingWindow.Avg()).Milliseconds(),
+			WSConns:       int(wsConns[be.Name]),
+		}
+		if score, ok := scores[be.Name]; ok {
+			backend.Score = &score
+		}
+		if bg.Consensus != nil {
+			state := bg.Consensus.GetBackendState(be)
+			latest, _ := state.GetLatestBlock()
+			backend.LatestBlock = uint64(latest)
+			backend.SafeBlock = uint64(state.GetSafeBlockNumber())
+			backend.FinalizedBlock = uint64(state.GetFinalizedBlockNumber())
+			if state.IsBanned() {
+				backend.BannedUntil = &state.bannedUntil
+			}
+		}
+		status.Backends = append(status.Backends, backend)
+	}
+	return status
+}
+
+// ServeHTTP serves the status as JSON on the admin API.
+func (s *StatusReporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeAdminJSON(w, http.StatusOK, s.Status())
+}
+
+// sumMetric sums the counters or gauges of a metric over its labels.
+func sumMetric(metrics []*dto.Metric) float64 {
+	var sum float64
+	for _, m := range metrics {
+		sum += metricValue(m)
+	}
+	return sum
+}
+
+// metricByLabel sums the counters or gauges of a metric by the value of
+// one of its labels.
+func metricByLabel(metrics []*dto.Metric, label string) map[string]float64 {
+	values := make(map[string]float64)
+	for _, m := range metrics {
+		for _, pair := range m.GetLabel() {
+			if pair.GetName() == label {
+				values[pair.GetValue()] += metricValue(m)
+			}
+		}
+	}
+	return values
+}
+
+func metricValue(m *dto.Metric) float64 {
+	if m.Counter != nil {
+		return m.Counter.GetValue()
+	}
+	return m.Gauge.GetValue()
+}
@@ -0,0 +1,70 @@
+package proxyd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatusReporter(t *testing.T) {
+	a := NewBackend("a", "http://a:8545", "", nil)
+	b := NewBackend("b", "http://b:8545", "", nil)
+	bg := &BackendGroup{
+		Name:            "main",
+		Backends:        []*Backend{a, b},
+		routingStrategy: FallbackRoutingStrategy,
+		inflight:        NewInflightLimiter("main", InflightConfig{MaxRequests: 4}),
+	}
+	for i := 0; i < 10; i++ {
+		a.networkRequestsSlidingWindow.Incr()
+		a.intermittentErrorsSlidingWindow.Incr()
+	}
+	release, err := bg.inflight.Acquire(context.Background())
+	require.NoError(t, err)
+	defer release()
+
+	registry := prometheus.NewRegistry()
+	hits := prometheus.NewCounterVec(prometheus.CounterOpts{Namespace: MetricsNamespace, Name: "cache_hits_total"}, []string{"method"})
+	misses := prometheus.NewCounterVec(prometheus.CounterOpts{Namespace: MetricsNamespace, Name: "cache_misses_total"}, []string{"method"})
+	rejections := prometheus.NewCounterVec(prometheus.CounterOpts{Namespace: MetricsNamespace, Name: "frontend_limit_rejections_total"}, []string{"reason"})
+	fallback := prometheus.NewGaugeVec(prometheus.GaugeOpts{Namespace: MetricsNamespace, Name: "rate_limit_fallback"}, []string{"limiter"})
+	backendConns := prometheus.NewGaugeVec(prometheus.GaugeOpts{Namespace: MetricsNamespace, Name: "active_backend_ws_conns"}, []string{"backend_name"})
+	registry.MustRegister(hits, misses, rejections, fallback, backendConns)
+	hits.WithLabelValues("eth_chainId").Add(3)
+	hits.WithLabelValues("eth_getBlockByHash").Add(1)
+	misses.WithLabelValues("eth_chainId").Add(4)
+	rejections.WithLabelValues("rate_limit").Add(7)
+	fallback.WithLabelValues("main").Set(1)
+	fallback.WithLabelValues("senders").Set(0)
+	backendConns.WithLabelValues("b").Set(2)
+
+	reporter := NewStatusReporter(&Server{}, map[string]*BackendGroup{"main": bg}, nil)
+	reporter.gatherer = registry
+
+	rec := httptest.NewRecorder()
+	reporter.ServeHTTP(rec, httptest.NewRequest("GET", "/admin/status", nil))
+	require.Equal(t, 200, rec.Code)
+	var status Status
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &status))
+
+	group := status.BackendGroups["main"]
+	require.Equal(t, FallbackRoutingStrategy, group.RoutingStrategy)
+	require.Nil(t, group.Consensus)
+	require.Len(t, group.Backends, 2)
+	require.Equal(t, "a", group.Backends[0].Name)
+	require.False(t, group.Backends[0].Healthy)
+	require.Equal(t, 1.0, group.Backends[0].ErrorRate)
+	require.Nil(t, group.Backends[0].Score)
+	require.True(t, group.Backends[1].Healthy)
+	require.Equal(t, 2, group.Backends[1].WSConns)
+
+	require.Equal(t, CacheStatus{Hits: 4, Misses: 4, HitRate: 0.5}, status.Cache)
+	require.Equal(t, map[string]uint64{"rate_limit": 7}, status.RateLimits.Rejections)
+	require.Equal(t, []string{"main"}, status.RateLimits.Fallback)
+	require.Equal(t, InflightStatus{Requests: 1, Max: 4, Saturation: 0.25}, status.Inflight["main"])
+	require.Zero(t, status.LoadShedding)
+}
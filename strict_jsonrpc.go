@@ -0,0 +1,178 @@
+package proxyd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// methods in these namespaces take positional params
+var positionalParamsPrefixes = []string{"eth_", "net_", "web3_", "debug_", "trace_", "txpool_"}
+
+// StrictJSONRPC rejects HTTP requests that are valid enough for proxyd and
+// most backends but don't follow the JSON-RPC 2.0 spec: bodies with duplicate
+// keys or trailing data, and requests with a jsonrpc version other than the
+// string "2.0", an ID that isn't a string, number or null, or params of the
+// wrong JSON type. In warn only mode, violations are only logged and counted,
+// to find the clients that would break before enforcing.
+type StrictJSONRPC struct {
+	warnOnly bool
+}
+
+func NewStrictJSONRPC(cfg StrictJSONRPCConfig) *StrictJSONRPC {
+	if !cfg.Enabled {
+		return nil
+	}
+	return &StrictJSONRPC{warnOnly: cfg.WarnOnly}
+}
+
+// CheckBody checks the syntax of a whole body, batch or not.
+func (s *StrictJSONRPC) CheckBody(ctx context.Context, body []byte) *RPCErr {
+	if s == nil {
+		return nil
+	}
+	reason, err := checkStrictJSON(body)
+	return s.enforce(ctx, reason, err)
+}
+
+// CheckRequest checks the fields of a single request.
+func (s *StrictJSONRPC) CheckRequest(ctx context.Context, raw json.RawMessage) *RPCErr {
+	if s == nil {
+		return nil
+	}
+	reason, err := checkStrictRequest(raw)
+	return s.enforce(ctx, reason, err)
+}
+
+func (s *StrictJSONRPC) enforce(ctx context.Context, reason string, err *RPCErr) *RPCErr {
+	if err == nil {
+		return nil
+	}
+	RecordStrictJSONRPCViolation(reason)
+	if s.warnOnly {
+		log.Warn("request violates JSON-RPC 2.0", "req_id", GetReqID(ctx), "auth", GetAuthCtx(ctx), "reason", reason, "err", err)
+		return nil
+	}
+	log.Info("rejected request violating JSON-RPC 2.0", "req_id", GetReqID(ctx), "auth", GetAuthCtx(ctx), "reason", reason, "err", err)
+	return err
+}
+
+type jsonFrame struct {
+	// keys is nil for arrays
+	keys      map[string]bool
+	expectKey bool
+}
+
+// checkStrictJSON returns a violation if body has duplicate keys in any
+// object or anything but whitespace after its value.
+func checkStrictJSON(body []byte) (string, *RPCErr) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var stack []*jsonFrame
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return "malformed", ErrParseErr
+		}
+		if d, ok := tok.(json.Delim); ok && (d == '{' || d == '[') {
+			frame := &jsonFrame{}
+			if d == '{' {
+				frame.keys = make(map[string]bool)
+				frame.expectKey = true
+			}
+			stack = append(stack, frame)
+			continue
+		}
+		if len(stack) > 0 {
+			top := stack[len(stack)-1]
+			if key, ok := tok.(string); ok && top.expectKey {
+				if top.keys[key] {
+					return "duplicate_key", ErrInvalidRequest(fmt.Sprintf("duplicate key %q", truncate(key, 64)))
+				}
+				top.keys[key] = true
+				top.expectKey = false
+				continue
+			}
+		}
+		if d, ok := tok.(json.Delim); ok && (d == '}' || d == ']') {
+			stack = stack[:len(stack)-1]
+		}
+		// a value ended
+		if len(stack) == 0 {
+			break
+		}
+		if top := stack[len(stack)-1]; top.keys != nil {
+			top.expectKey = true
+		}
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return "trailing_data", ErrParseErr
+	}
+	return "", nil
+}
+
+// checkStrictRequest returns a violation if a request's fields don't have the
+// types the spec requires. Reporting these as invalid requests rather than
+// parse errors is what the spec asks for.
+func checkStrictRequest(raw json.RawMessage) (string, *RPCErr) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return "not_object", ErrInvalidRequest("request must be an object")
+	}
+	if string(fields["jsonrpc"]) != `"`+JSONRPCVersion+`"` {
+		return "jsonrpc_version", ErrInvalidRequest("invalid JSON-RPC version")
+	}
+	var method string
+	if err := json.Unmarshal(fields["method"], &method); err != nil {
+		return "method", ErrInvalidRequest("method must be a string")
+	}
+	if id, ok := fields["id"]; ok {
+		switch jsonKind(id) {
+		case '"', '0', 'n':
+		default:
+			return "id", ErrInvalidRequest("id must be a string, number or null")
+		}
+	}
+	params, ok := fields["params"]
+	if !ok {
+		return "", nil
+	}
+	kind := jsonKind(params)
+	if kind != '[' && kind != '{' {
+		return "params", ErrInvalidParams("params must be an array or object")
+	}
+	if kind != '[' && hasPositionalParams(method) {
+		return "params", ErrInvalidParams(fmt.Sprintf("%s params must be an array", method))
+	}
+	return "", nil
+}
+
+func hasPositionalParams(method string) bool {
+	for _, prefix := range positionalParamsPrefixes {
+		if strings.HasPrefix(method, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonKind returns the first character of a JSON value, or '0' for numbers.
+func jsonKind(value json.RawMessage) byte {
+	value = bytes.TrimSpace(value)
+	if len(value) == 0 {
+		return 0
+	}
+	switch c := value[0]; c {
+	case '"', '[', '{', 't', 'f', 'n':
+		return c
+	case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		return '0'
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,66 @@
+package proxyd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStrictJSONRPC(t *testing.T) {
+	valid := []string{
+		`{"jsonrpc":"2.0","method":"eth_chainId","id":1}`,
+		`{"jsonrpc":"2.0","method":"eth_getBalance","params":["0x0","latest"],"id":"a"}`,
+		`{"jsonrpc":"2.0","method":"custom_method","params":{"a":{"a":1}},"id":null}`,
+		`{"jsonrpc":"2.0","method":"eth_call","params":[{"to":"0x0"},{"to":"0x0"}],"id":1} `,
+		`[{"jsonrpc":"2.0","method":"eth_chainId","id":1},{"jsonrpc":"2.0","method":"eth_chainId","id":1}]`,
+	}
+	for _, body := range valid {
+		_, err := checkStrictJSON([]byte(body))
+		require.Nil(t, err, body)
+	}
+
+	bodies := map[string]string{
+		`{"jsonrpc":"2.0","method":"eth_chainId","method":"eth_call","id":1}`:      "duplicate_key",
+		`{"jsonrpc":"2.0","method":"eth_call","params":[{"to":"0x0","to":"0x1"}]}`: "duplicate_key",
+		`[{"id":1},{"id":1,"id":2}]`:                             "duplicate_key",
+		`{"jsonrpc":"2.0","method":"eth_chainId","id":1}garbage`: "trailing_data",
+		`{"jsonrpc":"2.0","method":"eth_chainId","id":1}{}`:      "trailing_data",
+		`{"jsonrpc":"2.0",`:                                      "malformed",
+	}
+	for body, reason := range bodies {
+		r, err := checkStrictJSON([]byte(body))
+		require.NotNil(t, err, body)
+		require.Equal(t, reason, r, body)
+	}
+
+	requests := map[string]int{
+		`{"jsonrpc":"2.0","method":"eth_chainId","id":1}`:                 0,
+		`{"jsonrpc":"2.0","method":"eth_chainId"}`:                        0,
+		`{"jsonrpc":2.0,"method":"eth_chainId","id":1}`:                   -32600,
+		`{"jsonrpc":"1.0","method":"eth_chainId","id":1}`:                 -32600,
+		`{"method":"eth_chainId","id":1}`:                                 -32600,
+		`{"jsonrpc":"2.0","method":1,"id":1}`:                             -32600,
+		`{"jsonrpc":"2.0","method":"eth_chainId","id":true}`:              -32600,
+		`{"jsonrpc":"2.0","method":"eth_chainId","id":{"a":1}}`:           -32600,
+		`{"jsonrpc":"2.0","method":"eth_chainId","params":"0x1","id":1}`:  -32602,
+		`{"jsonrpc":"2.0","method":"eth_getBalance","params":{},"id":1}`:  -32602,
+		`{"jsonrpc":"2.0","method":"custom_method","params":{},"id":1}`:   0,
+		`{"jsonrpc":"2.0","method":"custom_method","params":null,"id":1}`: -32602,
+		`["not", "an", "object"]`:                                         -32600,
+	}
+	for req, code := range requests {
+		_, err := checkStrictRequest([]byte(req))
+		if code == 0 {
+			require.Nil(t, err, req)
+		} else {
+			require.NotNil(t, err, req)
+			require.Equal(t, code, err.Code, req)
+		}
+	}
+
+	body := []byte(`{"jsonrpc":"2.0","method":"eth_chainId","id":true}`)
+	require.NotNil(t, NewStrictJSONRPC(StrictJSONRPCConfig{Enabled: true}).CheckRequest(context.Background(), body))
+	require.Nil(t, NewStrictJSONRPC(StrictJSONRPCConfig{Enabled: true, WarnOnly: true}).CheckRequest(context.Background(), body))
+	require.Nil(t, NewStrictJSONRPC(StrictJSONRPCConfig{}).CheckRequest(context.Background(), body))
+}
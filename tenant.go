@@ -0,0 +1,225 @@
+package proxyd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const APIKeyHeader = "X-API-Key"
+
+// Tenant is one product served by a shared proxyd. A tenant's requests are
+// routed with its own method mappings, to backend groups no other tenant can
+// use, rate limited separately and cached under their own key prefix.
+type Tenant struct {
+	Name              string
+	rpcMethodMappings map[string]string
+	mainLim           FrontendRateLimiter
+	overrideLims      map[string]FrontendRateLimiter
+	cacheKeyPrefix    string
+}
+
+// Tenants selects the tenant of requests.
+type Tenants struct {
+	byAPIKey map[string]*Tenant
+	byHost   map[string]*Tenant
+	// groupOwners maps reserved backend groups to their tenant
+	groupOwners map[string]string
+}
+
+func NewTenants(cfg map[string]TenantConfig, limiterFactory limiterFactoryFunc) *Tenants {
+	if len(cfg) == 0 {
+		return nil
+	}
+	t := &Tenants{
+		byAPIKey:    make(map[string]*Tenant),
+		byHost:      make(map[string]*Tenant),
+		groupOwners: make(map[string]string),
+	}
+	for name, tc := range cfg {
+		tenant := &Tenant{
+			Name:              name,
+			rpcMethodMappings: tc.RPCMethodMappings,
+			mainLim:           NoopFrontendRateLimiter,
+			overrideLims:      make(map[string]FrontendRateLimiter),
+			cacheKeyPrefix:    tc.CacheKeyPrefix,
+		}
+		if tenant.cacheKeyPrefix == "" {
+			tenant.cacheKeyPrefix = name
+		}
+		// limiter prefixes keep the tenants' counters apart in redis
+		if tc.RateLimit.BaseRate > 0 {
+			tenant.mainLim = limiterFactory(time.Duration(tc.RateLimit.BaseInterval), tc.RateLimit.BaseRate, "tenant_"+name)
+		}
+		for method, override := range tc.RateLimit.MethodOverrides {
+			tenant.overrideLims[method] = limiterFactory(time.Duration(override.Interval), override.Limit, "tenant_"+name+"_"+method)
+		}
+		for _, key := range tc.APIKeys {
+			t.byAPIKey[key] = tenant
+		}
+		for _, host := range tc.Hosts {
+			t.byHost[strings.ToLower(host)] = tenant
+		}
+		for _, group := range tc.BackendGroups {
+			t.groupOwners[group] = name
+		}
+	}
+	return t
+}
+
+// Select returns the tenant of r, or nil if r doesn't belong to any.
+func (t *Tenants) Select(r *http.Request) *Tenant {
+	if t == nil {
+		return nil
+	}
+	key := r.Header.Get(APIKeyHeader)
+	if key == "" {
+		key, _, _ = strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	}
+	if tenant := t.byAPIKey[key]; key != "" && tenant != nil {
+		return tenant
+	}
+	return t.byHost[requestHost(r)]
+}
+
+// Allows reports whether tenant, nil for requests without a tenant, may use
+// the backend group.
+func (t *Tenants) Allows(tenant *Tenant, group string) bool {
+	if t == nil {
+		return true
+	}
+	owner, reserved := t.groupOwners[group]
+	if !reserved {
+		return true
+	}
+	return tenant != nil && tenant.Name == owner
+}
+
+func WithTenant(ctx context.Context, tenant *Tenant) context.Context {
+	return context.WithValue(ctx, ContextKeyTenant, tenant) // nolint:staticcheck
+}
+
+func GetTenant(ctx context.Context) *Tenant {
+	tenant, _ := ctx.Value(ContextKeyTenant).(*Tenant)
+	return tenant
+}
+
+// GetTenantName returns the name of the request's tenant, or "none".
+func GetTenantName(ctx context.Context) string {
+	if tenant := GetTenant(ctx); tenant != nil {
+		return tenant.Name
+	}
+	return "none"
+}
+
+// checkTenantsConfig checks that tenants are selected unambiguously and that
+// their backend groups are isolated.
+func checkTenantsConfig(config *Config) error {
+	apiKeys := make(map[string]string)
+	hosts := make(map[string]string)
+	owners := make(map[string]string)
+	for _, name := range sortedKeys(config.Tenants) {
+		tc := config.Tenants[name]
+		if len(tc.APIKeys) == 0 && len(tc.Hosts) == 0 {
+			return fmt.Errorf("tenant %s must have api_keys or hosts", name)
+		}
+		for _, key := range tc.APIKeys {
+			if other, ok := apiKeys[key]; ok {
+				return fmt.Errorf("tenants %s and %s share an api key", other, name)
+			}
+			apiKeys[key] = name
+		}
+		for _, host := range tc.Hosts {
+			host = strings.ToLower(host)
+			if other, ok := hosts[host]; ok {
+				return fmt.Errorf("tenants %s and %s share host %s", other, name, host)
+			}
+			hosts[host] = name
+		}
+		for _, group := range tc.BackendGroups {
+			if config.BackendGroups[group] == nil {
+				return fmt.Errorf("tenant %s has undefined backend group %s", name, group)
+			}
+			if other, ok := owners[group]; ok {
+				return fmt.Errorf("tenants %s and %s share backend group %s", other, name, group)
+			}
+			owners[group] = name
+		}
+	}
+	for _, name := range sortedKeys(config.Tenants) {
+		for _, method := range sortedKeys(config.Tenants[name].RPCMethodMappings) {
+			group := config.Tenants[name].RPCMethodMappings[method]
+			if config.BackendGroups[group] == nil {
+				return fmt.Errorf("tenant %s maps method %s to undefined backend group %s", name, method, group)
+			}
+			if owner, ok := owners[group]; ok && owner != name {
+				return fmt.Errorf("tenant %s maps method %s to backend group %s of tenant %s", name, method, group, owner)
+			}
+		}
+	}
+	for _, method := range sortedKeys(config.RPCMethodMappings) {
+		if owner, ok := owners[config.RPCMethodMappings[method]]; ok {
+			return fmt.Errorf("method %s is mapped to backend group %s of tenant %s", method, config.RPCMethodMappings[method], owner)
+		}
+	}
+	// routing rules apply to the requests of every tenant
+	for i, rule := range config.RoutingRules {
+		if owner, ok := owners[rule.BackendGroup]; ok {
+			return fmt.Errorf("routing_rules[%d] routes to backend group %s of tenant %s", i, rule.BackendGroup, owner)
+		}
+	}
+	for _, host := range sortedKeys(config.VirtualHosts) {
+		vc := config.VirtualHosts[host]
+		for _, method := range sortedKeys(vc.RPCMethodMappings) {
+			if owner, ok := owners[vc.RPCMethodMappings[method]]; ok {
+				return fmt.Errorf("virtual host %s maps method %s to backend group %s of tenant %s", host, method, vc.RPCMethodMappings[method], owner)
+			}
+		}
+		if owner, ok := owners[vc.WSBackendGroup]; ok {
+			return fmt.Errorf("ws backend group %s of virtual host %s belongs to tenant %s", vc.WSBackendGroup, host, owner)
+		}
+	}
+	for _, name := range sortedKeys(config.SignerClasses) {
+		sc := config.SignerClasses[name]
+		for _, method := range sortedKeys(sc.RPCMethodMappings) {
+			if owner, ok := owners[sc.RPCMethodMappings[method]]; ok {
+				return fmt.Errorf("signer class %s maps method %s to backend group %s of tenant %s", name, method, sc.RPCMethodMappings[method], owner)
+			}
+		}
+	}
+	for _, alias := range sortedKeys(config.AuthPolicies) {
+		if owner, ok := owners[config.AuthPolicies[alias].BackendGroup]; ok {
+			return fmt.Errorf("backend group %s of auth policy %s belongs to tenant %s", config.AuthPolicies[alias].BackendGroup, alias, owner)
+		}
+	}
+	if owner, ok := owners[config.WSBackendGroup]; ok {
+		return fmt.Errorf("ws backend group %s belongs to tenant %s", config.WSBackendGroup, owner)
+	}
+	return nil
+}
+
+// tenantCache namespaces the keys of tenants' requests.
+type tenantCache struct {
+	Cache
+}
+
+func newTenantCache(cache Cache) Cache {
+	return &tenantCache{cache}
+}
+
+func (c *tenantCache) key(ctx context.Context, key string) string {
+	if tenant := GetTenant(ctx); tenant != nil {
+		return tenant.cacheKeyPrefix + ":" + key
+	}
+	return key
+}
+
+func (c *tenantCache) Get(ctx context.Context, key string) (string, error) {
+	return c.Cache.Get(ctx, c.key(ctx, key))
+}
+
+func (c *tenantCache) Put(ctx context.Context, key string, value string) error {
+	return c.Cache.Put(ctx, c.key(ctx, key), value)
+}
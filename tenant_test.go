@@ -0,0 +1,73 @@
+package proxyd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckTenantsConfig(t *testing.T) {
+	newConfig := func() *Config {
+		return &Config{
+			BackendGroups: BackendGroupsConfig{
+				"main": {Backends: []string{"a"}},
+				"acme": {Backends: []string{"b"}},
+			},
+			RPCMethodMappings: map[string]string{"eth_chainId": "main"},
+			Tenants: map[string]TenantConfig{
+				"acme": {
+					APIKeys:           []string{"key"},
+					BackendGroups:     []string{"acme"},
+					RPCMethodMappings: map[string]string{"eth_chainId": "acme", "eth_blockNumber": "main"},
+				},
+			},
+		}
+	}
+	require.NoError(t, checkTenantsConfig(newConfig()))
+
+	config := newConfig()
+	config.RPCMethodMappings["eth_call"] = "acme"
+	require.ErrorContains(t, checkTenantsConfig(config), "backend group acme of tenant acme")
+
+	config = newConfig()
+	config.Tenants["other"] = TenantConfig{
+		Hosts:             []string{"other.example"},
+		RPCMethodMappings: map[string]string{"eth_chainId": "acme"},
+	}
+	require.ErrorContains(t, checkTenantsConfig(config), "tenant other maps method eth_chainId to backend group acme of tenant acme")
+
+	config = newConfig()
+	config.Tenants["other"] = TenantConfig{APIKeys: []string{"key"}}
+	require.ErrorContains(t, checkTenantsConfig(config), "share an api key")
+
+	config = newConfig()
+	config.Tenants["other"] = TenantConfig{}
+	require.ErrorContains(t, checkTenantsConfig(config), "must have api_keys or hosts")
+
+	config = newConfig()
+	config.RoutingRules = []RoutingRuleConfig{{Method: "eth_call", BackendGroup: "acme"}}
+	require.ErrorContains(t, checkTenantsConfig(config), "routing_rules[0] routes to backend group acme of tenant acme")
+}
+
+func TestTenantCache(t *testing.T) {
+	tenants := NewTenants(map[string]TenantConfig{
+		"acme": {APIKeys: []string{"key"}, CacheKeyPrefix: "acme"},
+	}, nil)
+	backing := newMemoryCache()
+	cache := newTenantCache(backing)
+	ctx := WithTenant(context.Background(), tenants.byAPIKey["key"])
+
+	require.NoError(t, cache.Put(ctx, "k", "tenant"))
+	require.NoError(t, cache.Put(context.Background(), "k", "shared"))
+
+	val, err := cache.Get(ctx, "k")
+	require.NoError(t, err)
+	require.Equal(t, "tenant", val)
+	val, err = cache.Get(context.Background(), "k")
+	require.NoError(t, err)
+	require.Equal(t, "shared", val)
+	val, err = backing.Get(ctx, "acme:k")
+	require.NoError(t, err)
+	require.Equal(t, "tenant", val)
+}
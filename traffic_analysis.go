@@ -0,0 +1,279 @@
+package proxyd
+
+import (
+	"container/heap"
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultTrafficAnalysisWindow   = time.Minute
+	defaultTrafficAnalysisCapacity = 1000
+	defaultTrafficAnalysisTopK     = 10
+)
+
+// TopItem is a heavy hitter and how many requests it made. Count may
+// overestimate by up to Error.
+type TopItem struct {
+	Item  string `json:"item"`
+	Count uint64 `json:"count"`
+	Error uint64 `json:"error,omitempty"`
+}
+
+// TrafficTop is the document served on /admin/top.
+type TrafficTop struct {
+	Since     time.Time `json:"since"`
+	Methods   []TopItem `json:"methods"`
+	IPs       []TopItem `json:"ips"`
+	Keys      []TopItem `json:"keys"`
+	Contracts []TopItem `json:"contracts"`
+}
+
+// TrafficAnalyzer tracks the heaviest methods, client IPs, auth keys and
+// contracts called with eth_call in bounded memory, answering who is
+// hammering proxyd right now. Counts cover the current window and the one
+// before, so they always span at least one full window.
+type TrafficAnalyzer struct {
+	window     time.Duration
+	capacity   int
+	adminToken secretValue
+
+	mu       sync.Mutex
+	start    time.Time
+	current  *trafficSketches
+	previous *trafficSketches
+}
+
+type trafficSketches struct {
+	methods   *spaceSaving
+	ips       *spaceSaving
+	keys      *spaceSaving
+	contracts *spaceSaving
+}
+
+// NewTrafficAnalyzer returns nil if traffic analysis is disabled.
+func NewTrafficAnalyzer(cfg TrafficAnalysisConfig) *TrafficAnalyzer {
+	if !cfg.Enabled {
+		return nil
+	}
+	a := &TrafficAnalyzer{
+		window:   time.Duration(cfg.Window),
+		capacity: cfg.Capacity,
+		start:    time.Now(),
+	}
+	a.adminToken.Store(cfg.AdminToken)
+	if a.window == 0 {
+		a.window = defaultTrafficAnalysisWindow
+	}
+	if a.capacity == 0 {
+		a.capacity = defaultTrafficAnalysisCapacity
+	}
+	a.current = a.newSketches()
+	a.previous = a.newSketches()
+	return a
+}
+
+func (a *TrafficAnalyzer) newSketches() *trafficSketches {
+	return &trafficSketches{
+		methods:   newSpaceSaving(a.capacity),
+		ips:       newSpaceSaving(a.capacity),
+		keys:      newSpaceSaving(a.capacity),
+		contracts: newSpaceSaving(a.capacity),
+	}
+}
+
+// Record counts a request of ctx.
+func (a *TrafficAnalyzer) Record(ctx context.Context, req *RPCReq) {
+	if a == nil {
+		return
+	}
+	contract := ethCallTarget(req)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.rotate()
+	a.current.methods.Add(req.Method)
+	if ip := GetClientIP(ctx); ip != "" {
+		a.current.ips.Add(ip)
+	}
+	if key := GetAuthCtx(ctx); key != "none" {
+		a.current.keys.Add(key)
+	}
+	if contract != "" {
+		a.current.contracts.Add(contract)
+	}
+}
+
+// rotate starts a new window if the current one is over.
+func (a *TrafficAnalyzer) rotate() {
+	elapsed := time.Since(a.start)
+	if elapsed < a.window {
+		return
+	}
+	if elapsed < 2*a.window {
+		a.previous = a.current
+	} else {
+		// no traffic in the last window
+		a.previous = a.newSketches()
+	}
+	a.current = a.newSketches()
+	a.start = a.start.Add(elapsed / a.window * a.window)
+}
+
+// Top returns the k heaviest items of each kind.
+func (a *TrafficAnalyzer) Top(k int) *TrafficTop {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.rotate()
+	return &TrafficTop{
+		Since:     a.start.Add(-a.window).UTC(),
+		Methods:   topItems(k, a.previous.methods, a.current.methods),
+		IPs:       topItems(k, a.previous.ips, a.current.ips),
+		Keys:      topItems(k, a.previous.keys, a.current.keys),
+		Contracts: topItems(k, a.previous.contracts, a.current.contracts),
+	}
+}
+
+// ServeHTTP serves the heavy hitters on GET /admin/top?k=, 10 by default.
+func (a *TrafficAnalyzer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !checkAdminToken(w, r, a.adminToken.Load()) {
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	k := defaultTrafficAnalysisTopK
+	if param := r.URL.Query().Get("k"); param != "" {
+		var err error
+		if k, err = strconv.Atoi(param); err != nil || k <= 0 {
+			http.Error(w, "invalid k", http.StatusBadRequest)
+			return
+		}
+	}
+	writeAdminJSON(w, http.StatusOK, a.Top(k))
+}
+
+// ethCallTarget returns the lowercased to address of an eth_call.
+func ethCallTarget(req *RPCReq) string {
+	if req.Method != "eth_call" {
+		return ""
+	}
+	var params []json.RawMessage
+	if err := json.Unmarshal(req.Params, &params); err != nil || len(params) == 0 {
+		return ""
+	}
+	var call struct {
+		To string `json:"to"`
+	}
+	if err := json.Unmarshal(params[0], &call); err != nil {
+		return ""
+	}
+	return strings.ToLower(call.To)
+}
+
+// topItems merges the counts of the sketches and returns the k largest.
+func topItems(k int, sketches ...*spaceSaving) []TopItem {
+	merged := make(map[string]*TopItem)
+	for _, s := range sketches {
+		for _, c := range s.counters {
+			item := merged[c.item]
+			if item == nil {
+				item = &TopItem{Item: c.item}
+				merged[c.item] = item
+			}
+			item.Count += c.count
+			item.Error += c.err
+		}
+	}
+	items := make([]TopItem, 0, len(merged))
+	for _, item := range merged {
+		items = append(items, *item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Count != items[j].Count {
+			return items[i].Count > items[j].Count
+		}
+		return items[i].Item < items[j].Item
+	})
+	if len(items) > k {
+		items = items[:k]
+	}
+	return items
+}
+
+// spaceSaving is the Space-Saving heavy hitters sketch: it counts up to
+// capacity items, and a new item replaces the least counted one, inheriting
+// its count as overestimation error. Any item more frequent than 1/capacity
+// of the total is guaranteed to be tracked.
+type spaceSaving struct {
+	capacity int
+	index    map[string]*ssCounter
+	counters ssHeap
+}
+
+type ssCounter struct {
+	item  string
+	count uint64
+	err   uint64
+	pos   int
+}
+
+func newSpaceSaving(capacity int) *spaceSaving {
+	return &spaceSaving{
+		capacity: capacity,
+		index:    make(map[string]*ssCounter),
+	}
+}
+
+func (s *spaceSaving) Add(item string) {
+	if c := s.index[item]; c != nil {
+		c.count++
+		heap.Fix(&s.counters, c.pos)
+		return
+	}
+	if len(s.counters) < s.capacity {
+		c := &ssCounter{item: item, count: 1}
+		s.index[item] = c
+		heap.Push(&s.counters, c)
+		return
+	}
+	c := s.counters[0]
+	delete(s.index, c.item)
+	c.item = item
+	c.err = c.count
+	c.count++
+	s.index[item] = c
+	heap.Fix(&s.counters, 0)
+}
+
+// ssHeap is a min-heap of counters.
+type ssHeap []*ssCounter
+
+func (h ssHeap) Len() int           { return len(h) }
+func (h ssHeap) Less(i, j int) bool { return h[i].count < h[j].count }
+
+func (h ssHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].pos = i
+	h[j].pos = j
+}
+
+func (h *ssHeap) Push(x any) {
+	c := x.(*ssCounter)
+	c.pos = len(*h)
+	*h = append(*h, c)
+}
+
+func (h *ssHeap) Pop() any {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return c
+}
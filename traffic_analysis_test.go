@@ -0,0 +1,77 @@
+package proxyd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpaceSaving(t *testing.T) {
+	s := newSpaceSaving(10)
+	for i := 0; i < 100; i++ {
+		s.Add("heavy")
+		if i%2 == 0 {
+			s.Add("medium")
+		}
+		// a long tail of distinct items
+		s.Add(fmt.Sprintf("tail-%d", i))
+	}
+	require.Len(t, s.counters, 10)
+
+	top := topItems(2, s)
+	require.Equal(t, "heavy", top[0].Item)
+	require.Equal(t, uint64(100), top[0].Count)
+	require.Zero(t, top[0].Error)
+	require.Equal(t, "medium", top[1].Item)
+	require.GreaterOrEqual(t, top[1].Count, uint64(50))
+}
+
+func TestTrafficAnalyzer(t *testing.T) {
+	a := NewTrafficAnalyzer(TrafficAnalysisConfig{Enabled: true, Window: TOMLDuration(time.Minute), AdminToken: "secret"})
+	ctx := context.WithValue(context.Background(), ContextKeyClientIP, "1.2.3.4") // nolint:staticcheck
+	authCtx := context.WithValue(ctx, ContextKeyAuth, "partner")                  // nolint:staticcheck
+
+	call := &RPCReq{Method: "eth_call", Params: json.RawMessage(`[{"to":"0xAbC0000000000000000000000000000000000001","data":"0x"},"latest"]`)}
+	for i := 0; i < 3; i++ {
+		a.Record(authCtx, call)
+	}
+	a.Record(ctx, &RPCReq{Method: "eth_chainId"})
+
+	top := a.Top(10)
+	require.Equal(t, []TopItem{{Item: "eth_call", Count: 3}, {Item: "eth_chainId", Count: 1}}, top.Methods)
+	require.Equal(t, []TopItem{{Item: "1.2.3.4", Count: 4}}, top.IPs)
+	require.Equal(t, []TopItem{{Item: "partner", Count: 3}}, top.Keys)
+	require.Equal(t, []TopItem{{Item: "0xabc0000000000000000000000000000000000001", Count: 3}}, top.Contracts)
+
+	// the previous window still counts
+	a.start = a.start.Add(-time.Minute)
+	a.Record(ctx, &RPCReq{Method: "eth_chainId"})
+	top = a.Top(1)
+	require.Equal(t, []TopItem{{Item: "eth_call", Count: 3}}, top.Methods)
+
+	// but not older ones
+	a.start = a.start.Add(-2 * time.Minute)
+	top = a.Top(10)
+	require.Empty(t, top.Methods)
+
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest("GET", "/admin/top?k=5", nil))
+	require.Equal(t, 401, rec.Code)
+
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/admin/top?k=5", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	a.ServeHTTP(rec, req)
+	require.Equal(t, 200, rec.Code)
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest("GET", "/admin/top?k=-1", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	a.ServeHTTP(rec, req)
+	require.Equal(t, 400, rec.Code)
+}
@@ -0,0 +1,476 @@
+package proxyd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	TxAuditSinkRedis = "redis"
+	TxAuditSinkFile  = "file"
+
+	txAuditRedisKey            = "tx_audit"
+	defaultTxAuditStreamMaxLen = 1000000
+	defaultTxAuditMaxFileSize  = 100
+	defaultTxAuditMaxBackups   = 10
+	txAuditBufferSize          = 10000
+	txAuditBatchSize           = 100
+	txAuditWriteTimeout        = 5 * time.Second
+	// queries scan at most this many of the latest records of the stream
+	txAuditMaxScan       = 100000
+	txAuditScanPageSize  = 1000
+	defaultTxAuditLimit  = 100
+	maxTxAuditQueryLimit = 1000
+)
+
+// TxAuditRecord is the audit record of a transaction submission.
+type TxAuditRecord struct {
+	Time     time.Time   `json:"time"`
+	ReqID    string      `json:"req_id"`
+	Method   string      `json:"method"`
+	TxHash   string      `json:"tx_hash,omitempty"`
+	Sender   string      `json:"sender,omitempty"`
+	ClientIP string      `json:"client_ip"`
+	Key      string      `json:"key"`
+	Backend  string      `json:"backend"`
+	Result   interface{} `json:"result,omitempty"`
+	Error    *RPCErr     `json:"error,omitempty"`
+}
+
+// TxAuditQuery filters records. Empty fields match everything.
+type TxAuditQuery struct {
+	TxHash   string
+	Sender   string
+	Key      string
+	ClientIP string
+	Limit    int
+}
+
+func (q *TxAuditQuery) matches(rec *TxAuditRecord) bool {
+	return (q.TxHash == "" || strings.EqualFold(q.TxHash, rec.TxHash)) &&
+		(q.Sender == "" || strings.EqualFold(q.Sender, rec.Sender)) &&
+		(q.Key == "" || q.Key == rec.Key) &&
+		(q.ClientIP == "" || q.ClientIP == rec.ClientIP)
+}
+
+// txAuditSink persists records and finds them, latest first.
+type txAuditSink interface {
+	Write(ctx context.Context, recs []*TxAuditRecord) error
+	Query(ctx context.Context, q *TxAuditQuery) ([]*TxAuditRecord, error)
+	Close() error
+}
+
+// TxAuditLog keeps an auditable record of every eth_sendRawTransaction and
+// eth_sendRawTransactionConditional, including those proxyd rejected, for
+// abuse investigations and support. Records are written in the background,
+// and dropped rather than slowing down requests if the sink falls behind.
+// They're queried through the admin endpoint.
+type TxAuditLog struct {
+	sink       txAuditSink
+	adminToken secretValue
+	ipHasher   *IPHasher
+	records    chan *TxAuditRecord
+	done       chan struct{}
+	closeOnce  sync.Once
+}
+
+// NewTxAuditLog returns nil if the audit log is disabled.
+func NewTxAuditLog(cfg TxAuditConfig, redisClient redis.UniversalClient, namespace string, ipHasher *IPHasher) (*TxAuditLog, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	var sink txAuditSink
+	switch cfg.Sink {
+	case TxAuditSinkRedis:
+		if redisClient == nil {
+			return nil, errors.New("tx_audit with the redis sink requires a redis config")
+		}
+		sink = newRedisTxAuditSink(redisClient, namespace, cfg.StreamMaxLen)
+	case TxAuditSinkFile:
+		var err error
+		if sink, err = newFileTxAuditSink(cfg.File, cfg.MaxFileSizeMB, cfg.MaxBackups); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("invalid tx_audit.sink %q, must be %s or %s", cfg.Sink, TxAuditSinkRedis, TxAuditSinkFile)
+	}
+	return newTxAuditLog(sink, cfg.AdminToken, ipHasher), nil
+}
+
+func newTxAuditLog(sink txAuditSink, adminToken string, ipHasher *IPHasher) *TxAuditLog {
+	l := &TxAuditLog{
+		sink:     sink,
+		ipHasher: ipHasher,
+		records:  make(chan *TxAuditRecord, txAuditBufferSize),
+		done:     make(chan struct{}),
+	}
+	l.adminToken.Store(adminToken)
+	go l.run()
+	return l
+}
+
+// RecordRequests queues a record for each transaction submission that was
+// answered. sendTxs are the transactions proxyd accepted.
+func (l *TxAuditLog) RecordRequests(ctx context.Context, reqs []*RPCReq, res []*RPCRes, backends []string, sendTxs []*types.Transaction) {
+	if l == nil {
+		return
+	}
+
+	now := time.Now()
+	for i, req := range reqs {
+		if req == nil || res[i] == nil {
+			continue
+		}
+		if req.Method != "eth_sendRawTransaction" && req.Method != "eth_sendRawTransactionConditional" {
+			continue
+		}
+		rec := &TxAuditRecord{
+			Time:     now,
+			ReqID:    GetReqID(ctx),
+			Method:   req.Method,
+			ClientIP: GetClientIP(ctx),
+			Key:      GetAuthCtx(ctx),
+			Backend:  backends[i],
+			Result:   res[i].Result,
+			Error:    res[i].Error,
+		}
+		if rec.Backend == "" {
+			rec.Backend = BackendProxyd
+		}
+		tx := sendTxs[i]
+		if tx == nil {
+			// rejected by proxyd, possibly before being decoded
+			tx, _ = convertSendReqToSendTx(ctx, req)
+		}
+		if tx != nil {
+			rec.TxHash = tx.Hash().Hex()
+			if sender, err := txSender(tx); err == nil {
+				rec.Sender = sender.Hex()
+			}
+		}
+		select {
+		case l.records <- rec:
+		default:
+			RecordTxAuditDropped(1)
+		}
+	}
+}
+
+// Query returns the latest records matching q.
+func (l *TxAuditLog) Query(ctx context.Context, q *TxAuditQuery) ([]*TxAuditRecord, error) {
+	if q.Limit <= 0 {
+		q.Limit = defaultTxAuditLimit
+	}
+	if q.Limit > maxTxAuditQueryLimit {
+		q.Limit = maxTxAuditQueryLimit
+	}
+	return l.sink.Query(ctx, q)
+}
+
+// Close writes the queued records and closes the sink.
+func (l *TxAuditLog) Close() {
+	if l == nil {
+		return
+	}
+	l.closeOnce.Do(func() {
+		close(l.records)
+		<-l.done
+		if err := l.sink.Close(); err != nil {
+			log.Error("error closing tx audit sink", "err", err)
+		}
+	})
+}
+
+func (l *TxAuditLog) run() {
+	defer close(l.done)
+	for rec := range l.records {
+		// write whatever else is queued along with it
+		recs := []*TxAuditRecord{rec}
+	drain:
+		for len(recs) < txAuditBatchSize {
+			select {
+			case rec, ok := <-l.records:
+				if !ok {
+					break drain
+				}
+				recs = append(recs, rec)
+			default:
+				break drain
+			}
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), txAuditWriteTimeout)
+		if err := l.sink.Write(ctx, recs); err != nil {
+			log.Error("error writing tx audit records", "count", len(recs), "err", err)
+			RecordTxAuditDropped(len(recs))
+		}
+		cancel()
+	}
+}
+
+// ServeHTTP serves the audit endpoint:
+//
+//	GET /admin/txs?hash=&sender=&key=&ip=&limit=   lists the latest matching records
+func (l *TxAuditLog) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !checkAdminToken(w, r, l.adminToken.Load()) {
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	params := r.URL.Query()
+	q := &TxAuditQuery{
+		TxHash: params.Get("hash"),
+		Sender: params.Get("sender"),
+		Key:    params.Get("key"),
+		// IPs are looked up by their current pseudonym in IP privacy mode
+		ClientIP: l.ipHasher.Hash(params.Get("ip")),
+	}
+	if limit := params.Get("limit"); limit != "" {
+		var err error
+		if q.Limit, err = strconv.Atoi(limit); err != nil {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+	recs, err := l.Query(r.Context(), q)
+	if err != nil {
+		log.Error("error querying tx audit records", "err", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	if recs == nil {
+		recs = []*TxAuditRecord{}
+	}
+	writeAdminJSON(w, http.StatusOK, recs)
+}
+
+// redisTxAuditSink appends records to a Redis stream, trimmed to about
+// maxLen records.
+type redisTxAuditSink struct {
+	client redis.UniversalClient
+	key    string
+	maxLen int64
+}
+
+func newRedisTxAuditSink(client redis.UniversalClient, namespace string, maxLen int64) *redisTxAuditSink {
+	s := &redisTxAuditSink{
+		client: client,
+		key:    txAuditRedisKey,
+		maxLen: maxLen,
+	}
+	if namespace != "" {
+		s.key = namespace + ":" + txAuditRedisKey
+	}
+	if s.maxLen <= 0 {
+		s.maxLen = defaultTxAuditStreamMaxLen
+	}
+	return s
+}
+
+func (s *redisTxAuditSink) Write(ctx context.Context, recs []*TxAuditRecord) error {
+	_, err := s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, rec := range recs {
+			pipe.XAdd(ctx, &redis.XAddArgs{
+				Stream: s.key,
+				MaxLen: s.maxLen,
+				Approx: true,
+				Values: []interface{}{"record", mustMarshalJSON(rec)},
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		RecordRedisError("TxAudit")
+	}
+	return err
+}
+
+func (s *redisTxAuditSink) Query(ctx context.Context, q *TxAuditQuery) ([]*TxAuditRecord, error) {
+	var recs []*TxAuditRecord
+	end := "+"
+	for scanned := 0; scanned < txAuditMaxScan; {
+		msgs, err := s.client.XRevRangeN(ctx, s.key, end, "-", txAuditScanPageSize).Result()
+		if err != nil {
+			return nil, err
+		}
+		for _, msg := range msgs {
+			raw, _ := msg.Values["record"].(string)
+			var rec TxAuditRecord
+			if err := json.Unmarshal([]byte(raw), &rec); err != nil {
+				continue
+			}
+			if q.matches(&rec) {
+				recs = append(recs, &rec)
+				if len(recs) == q.Limit {
+					return recs, nil
+				}
+			}
+		}
+		if len(msgs) < txAuditScanPageSize {
+			break
+		}
+		scanned += len(msgs)
+		end = "(" + msgs[len(msgs)-1].ID
+	}
+	return recs, nil
+}
+
+func (s *redisTxAuditSink) Close() error {
+	return nil
+}
+
+// fileTxAuditSink appends records to a file as JSON lines, rotating it to
+// path.1, path.2 and so on when it reaches maxSize, keeping maxBackups of
+// them.
+type fileTxAuditSink struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+func newFileTxAuditSink(path string, maxSizeMB int, maxBackups int) (*fileTxAuditSink, error) {
+	if path == "" {
+		return nil, errors.New("tx_audit.file must be set with the file sink")
+	}
+	if maxSizeMB <= 0 {
+		maxSizeMB = defaultTxAuditMaxFileSize
+	}
+	if maxBackups <= 0 {
+		maxBackups = defaultTxAuditMaxBackups
+	}
+	s := &fileTxAuditSink{
+		path:       path,
+		maxSize:    int64(maxSizeMB) << 20,
+		maxBackups: maxBackups,
+	}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *fileTxAuditSink) open() error {
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return wrapErr(err, "error opening tx audit file")
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return wrapErr(err, "error opening tx audit file")
+	}
+	s.file = f
+	s.size = info.Size()
+	return nil
+}
+
+func (s *fileTxAuditSink) Write(ctx context.Context, recs []*TxAuditRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, rec := range recs {
+		line := append(mustMarshalJSON(rec), '\n')
+		if s.size > 0 && s.size+int64(len(line)) > s.maxSize {
+			if err := s.rotate(); err != nil {
+				return err
+			}
+		}
+		n, err := s.file.Write(line)
+		s.size += int64(n)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *fileTxAuditSink) backup(i int) string {
+	return s.path + "." + strconv.Itoa(i)
+}
+
+func (s *fileTxAuditSink) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return err
+	}
+	for i := s.maxBackups - 1; i > 0; i-- {
+		if err := os.Rename(s.backup(i), s.backup(i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := os.Rename(s.path, s.backup(1)); err != nil {
+		return err
+	}
+	return s.open()
+}
+
+func (s *fileTxAuditSink) Query(ctx context.Context, q *TxAuditQuery) ([]*TxAuditRecord, error) {
+	// holding the lock keeps files from rotating while they're read
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var recs []*TxAuditRecord
+	for i := 0; i <= s.maxBackups && len(recs) < q.Limit; i++ {
+		path := s.path
+		if i > 0 {
+			path = s.backup(i)
+		}
+		matches, err := readTxAuditFile(path, q)
+		if os.IsNotExist(err) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		// latest first
+		for j := len(matches) - 1; j >= 0 && len(recs) < q.Limit; j-- {
+			recs = append(recs, matches[j])
+		}
+	}
+	return recs, nil
+}
+
+func readTxAuditFile(path string, q *TxAuditQuery) ([]*TxAuditRecord, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var matches []*TxAuditRecord
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16<<20)
+	for scanner.Scan() {
+		var rec TxAuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			continue
+		}
+		if q.matches(&rec) {
+			matches = append(matches, &rec)
+		}
+	}
+	return matches, scanner.Err()
+}
+
+func (s *fileTxAuditSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
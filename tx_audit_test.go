@@ -0,0 +1,113 @@
+package proxyd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileTxAuditSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "txs.log")
+	sink, err := newFileTxAuditSink(path, 1, 2)
+	require.NoError(t, err)
+	// rotate every few records
+	sink.maxSize = 1024
+
+	ctx := context.Background()
+	for i := 0; i < 30; i++ {
+		require.NoError(t, sink.Write(ctx, []*TxAuditRecord{{
+			Time:     time.Unix(int64(i), 0),
+			TxHash:   "0x" + strconv.Itoa(i),
+			Sender:   "0xAbc",
+			ClientIP: "192.0.2.1",
+			Key:      "none",
+			Backend:  "main",
+		}}))
+	}
+	_, err = os.Stat(path + ".2")
+	require.NoError(t, err)
+	_, err = os.Stat(path + ".3")
+	require.True(t, os.IsNotExist(err))
+
+	recs, err := sink.Query(ctx, &TxAuditQuery{Sender: "0xabc", Limit: 5})
+	require.NoError(t, err)
+	require.Len(t, recs, 5)
+	for i, rec := range recs {
+		require.Equal(t, "0x"+strconv.Itoa(29-i), rec.TxHash)
+	}
+
+	recs, err = sink.Query(ctx, &TxAuditQuery{TxHash: "0x28", Limit: 5})
+	require.NoError(t, err)
+	require.Len(t, recs, 1)
+
+	// rotated out
+	recs, err = sink.Query(ctx, &TxAuditQuery{TxHash: "0x0", Limit: 5})
+	require.NoError(t, err)
+	require.Empty(t, recs)
+	require.NoError(t, sink.Close())
+}
+
+func TestTxAuditLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "txs.log")
+	l, err := NewTxAuditLog(TxAuditConfig{
+		Enabled:    true,
+		Sink:       TxAuditSinkFile,
+		File:       path,
+		AdminToken: "admin",
+	}, nil, "", nil)
+	require.NoError(t, err)
+
+	ctx := context.WithValue(context.Background(), ContextKeyClientIP, "192.0.2.1") // nolint:staticcheck
+	ctx = context.WithValue(ctx, ContextKeyAuth, "partner")                         // nolint:staticcheck
+	reqs := []*RPCReq{
+		{Method: "eth_chainId", ID: json.RawMessage("1")},
+		{Method: "eth_sendRawTransaction", ID: json.RawMessage("2"), Params: json.RawMessage(`["0xdead"]`)},
+	}
+	res := []*RPCRes{
+		NewRPCRes(reqs[0].ID, "0x1"),
+		NewRPCErrorRes(reqs[1].ID, ErrInvalidParams("rlp: value size exceeds available input length")),
+	}
+	l.RecordRequests(ctx, reqs, res, []string{"main", ""}, make([]*types.Transaction, 2))
+	l.Close()
+
+	l, err = NewTxAuditLog(TxAuditConfig{
+		Enabled:    true,
+		Sink:       TxAuditSinkFile,
+		File:       path,
+		AdminToken: "admin",
+	}, nil, "", nil)
+	require.NoError(t, err)
+	defer l.Close()
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/txs?key=partner", nil)
+	rec := httptest.NewRecorder()
+	l.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	req.Header.Set("Authorization", "Bearer admin")
+	rec = httptest.NewRecorder()
+	l.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var recs []*TxAuditRecord
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &recs))
+	require.Len(t, recs, 1)
+	require.Equal(t, "eth_sendRawTransaction", recs[0].Method)
+	require.Equal(t, "192.0.2.1", recs[0].ClientIP)
+	require.Equal(t, BackendProxyd, recs[0].Backend)
+	require.Equal(t, ErrInvalidParams("").Code, recs[0].Error.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/admin/txs?key=other", nil)
+	req.Header.Set("Authorization", "Bearer admin")
+	rec = httptest.NewRecorder()
+	l.ServeHTTP(rec, req)
+	require.JSONEq(t, `[]`, rec.Body.String())
+}
@@ -0,0 +1,82 @@
+package proxyd
+
+import (
+	"crypto/sha256"
+	"sync"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru"
+)
+
+const (
+	defaultTxDedupWindow = 30 * time.Second
+	txDedupMemoryLimit   = 100000
+)
+
+// TxDedup answers byte-identical transaction submissions, which clients send
+// when they retry aggressively, with the result of the first one for a while
+// instead of forwarding them again. Only successful submissions are
+// remembered, so failed ones can be retried.
+type TxDedup struct {
+	window time.Duration
+
+	mu     sync.Mutex
+	recent *lru.Cache
+}
+
+type dedupedTx struct {
+	result  interface{}
+	expires time.Time
+}
+
+func NewTxDedup(cfg TxDedupConfig) *TxDedup {
+	d := &TxDedup{
+		window: time.Duration(cfg.Window),
+	}
+	if d.window == 0 {
+		d.window = defaultTxDedupWindow
+	}
+	d.recent, _ = lru.New(txDedupMemoryLimit)
+	return d
+}
+
+// Lookup returns the response to an earlier submission identical to req, if
+// any, with the ID of req.
+func (d *TxDedup) Lookup(req *RPCReq) *RPCRes {
+	if d == nil {
+		return nil
+	}
+	key := txDedupKey(req)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	val, ok := d.recent.Get(key)
+	if !ok {
+		return nil
+	}
+	tx := val.(dedupedTx)
+	if time.Now().After(tx.expires) {
+		d.recent.Remove(key)
+		return nil
+	}
+	RecordTxDedupHit(req.Method)
+	return &RPCRes{
+		JSONRPC: JSONRPCVersion,
+		Result:  tx.result,
+		ID:      req.ID,
+	}
+}
+
+// Observe records the response to a submission.
+func (d *TxDedup) Observe(req *RPCReq, res *RPCRes) {
+	if d == nil || res == nil || res.IsError() {
+		return
+	}
+	tx := dedupedTx{result: res.Result, expires: time.Now().Add(d.window)}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.recent.Add(txDedupKey(req), tx)
+}
+
+func txDedupKey(req *RPCReq) [sha256.Size]byte {
+	return sha256.Sum256(append([]byte(req.Method+":"), req.Params...))
+}
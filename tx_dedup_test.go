@@ -0,0 +1,44 @@
+package proxyd
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTxDedup(t *testing.T) {
+	d := NewTxDedup(TxDedupConfig{Window: TOMLDuration(50 * time.Millisecond)})
+
+	send := func(id, params string) *RPCReq {
+		return &RPCReq{JSONRPC: "2.0", Method: "eth_sendRawTransaction", Params: json.RawMessage(params), ID: json.RawMessage(id)}
+	}
+
+	req := send("1", `["0x01"]`)
+	require.Nil(t, d.Lookup(req))
+	d.Observe(req, &RPCRes{JSONRPC: "2.0", Result: "0xabcd", ID: req.ID})
+
+	// identical submissions get the first result with their own ID
+	res := d.Lookup(send("2", `["0x01"]`))
+	require.NotNil(t, res)
+	require.Equal(t, "0xabcd", res.Result)
+	require.Equal(t, json.RawMessage("2"), res.ID)
+
+	// others are forwarded
+	require.Nil(t, d.Lookup(send("3", `["0x02"]`)))
+	require.Nil(t, d.Lookup(&RPCReq{JSONRPC: "2.0", Method: "eth_sendRawTransactionConditional", Params: json.RawMessage(`["0x01"]`), ID: json.RawMessage("4")}))
+
+	// failed submissions aren't remembered
+	failed := send("5", `["0x03"]`)
+	d.Observe(failed, NewRPCErrorRes(failed.ID, ErrInternal))
+	require.Nil(t, d.Lookup(failed))
+
+	// nor are submissions past the window
+	time.Sleep(60 * time.Millisecond)
+	require.Nil(t, d.Lookup(req))
+
+	var disabled *TxDedup
+	disabled.Observe(req, res)
+	require.Nil(t, disabled.Lookup(req))
+}
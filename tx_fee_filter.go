@@ -0,0 +1,64 @@
+package proxyd
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// TxFeeFilter rejects raw transactions whose fees are too low to ever be
+// included, or absurdly high, given the current base fee.
+type TxFeeFilter struct {
+	cfg TxFeeFilterConfig
+	// baseFee returns the current base fee, or nil if it isn't known.
+	baseFee func() *big.Int
+}
+
+func NewTxFeeFilter(cfg TxFeeFilterConfig, baseFee func() *big.Int) *TxFeeFilter {
+	return &TxFeeFilter{cfg: cfg, baseFee: baseFee}
+}
+
+func (f *TxFeeFilter) Check(ctx context.Context, tx *types.Transaction) error {
+	if f == nil {
+		return nil
+	}
+	baseFee := f.baseFee()
+	if baseFee == nil {
+		log.Debug("base fee unknown, skipping fee filter", "req_id", GetReqID(ctx))
+		return nil
+	}
+
+	if err := checkFeeBounds("max fee per gas", tx.GasFeeCap(), baseFee, f.cfg.MinFeeCapMultiplier, f.cfg.MaxFeeCapMultiplier); err != nil {
+		log.Debug("rejecting transaction fee cap", "err", err, "tx_hash", tx.Hash(), "req_id", GetReqID(ctx))
+		return err
+	}
+	if err := checkFeeBounds("max priority fee per gas", tx.GasTipCap(), baseFee, f.cfg.MinTipCapMultiplier, f.cfg.MaxTipCapMultiplier); err != nil {
+		log.Debug("rejecting transaction tip cap", "err", err, "tx_hash", tx.Hash(), "req_id", GetReqID(ctx))
+		return err
+	}
+	return nil
+}
+
+func checkFeeBounds(name string, fee, baseFee *big.Int, minMultiplier, maxMultiplier float64) error {
+	if minMultiplier > 0 {
+		if floor := scaleFee(baseFee, minMultiplier); fee.Cmp(floor) < 0 {
+			RecordTxFeeRejection(name, "too_low")
+			return ErrTxFeeOutOfRange(fmt.Sprintf("%s %s is below the minimum of %s (%gx base fee %s)", name, fee, floor, minMultiplier, baseFee))
+		}
+	}
+	if maxMultiplier > 0 {
+		if ceiling := scaleFee(baseFee, maxMultiplier); fee.Cmp(ceiling) > 0 {
+			RecordTxFeeRejection(name, "too_high")
+			return ErrTxFeeOutOfRange(fmt.Sprintf("%s %s is above the maximum of %s (%gx base fee %s)", name, fee, ceiling, maxMultiplier, baseFee))
+		}
+	}
+	return nil
+}
+
+func scaleFee(fee *big.Int, multiplier float64) *big.Int {
+	scaled, _ := new(big.Float).Mul(new(big.Float).SetInt(fee), big.NewFloat(multiplier)).Int(nil)
+	return scaled
+}
@@ -0,0 +1,51 @@
+package proxyd
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTxFeeFilter(t *testing.T) {
+	var baseFee *big.Int
+	filter := NewTxFeeFilter(TxFeeFilterConfig{
+		MinFeeCapMultiplier: 1,
+		MaxFeeCapMultiplier: 100,
+		MaxTipCapMultiplier: 10,
+	}, func() *big.Int { return baseFee })
+	ctx := context.Background()
+	tx := func(feeCap, tipCap int64) *types.Transaction {
+		return types.NewTx(&types.DynamicFeeTx{
+			ChainID:   big.NewInt(10),
+			GasFeeCap: big.NewInt(feeCap),
+			GasTipCap: big.NewInt(tipCap),
+			Gas:       21000,
+			To:        &common.Address{},
+		})
+	}
+
+	// without a base fee everything goes through
+	require.NoError(t, filter.Check(ctx, tx(1, 1)))
+
+	baseFee = big.NewInt(1000)
+	require.NoError(t, filter.Check(ctx, tx(1000, 0)))
+	require.NoError(t, filter.Check(ctx, tx(100000, 10000)))
+
+	err := filter.Check(ctx, tx(999, 0))
+	require.Equal(t, "max fee per gas 999 is below the minimum of 1000 (1x base fee 1000)", err.Error())
+	err = filter.Check(ctx, tx(100001, 0))
+	require.Equal(t, "max fee per gas 100001 is above the maximum of 100000 (100x base fee 1000)", err.Error())
+	err = filter.Check(ctx, tx(20000, 10001))
+	require.Equal(t, "max priority fee per gas 10001 is above the maximum of 10000 (10x base fee 1000)", err.Error())
+
+	// legacy transactions are bound by their gas price
+	legacy := types.NewTx(&types.LegacyTx{GasPrice: big.NewInt(500), Gas: 21000, To: &common.Address{}})
+	require.Error(t, filter.Check(ctx, legacy))
+
+	var nilFilter *TxFeeFilter
+	require.NoError(t, nilFilter.Check(ctx, tx(1, 1)))
+}
@@ -0,0 +1,201 @@
+package proxyd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	TxPreferencesGetMethod = "proxyd_getTxPreferences"
+	TxPreferencesSetMethod = "proxyd_setTxPreferences"
+
+	txPreferencesRedisKey    = "tx_preferences"
+	txPreferencesMemoryLimit = 100000
+)
+
+// TxPreferences are how a user wants their transactions submitted, given to
+// the backends as the query string wallets otherwise add to the RPC URL,
+// e.g. ?builder=flashbots&hint=hash.
+type TxPreferences struct {
+	Builders   []string `json:"builders,omitempty"`
+	Hints      []string `json:"hints,omitempty"`
+	Fast       bool     `json:"fast,omitempty"`
+	UseMempool bool     `json:"useMempool,omitempty"`
+}
+
+func (p *TxPreferences) query() string {
+	q := url.Values{}
+	for _, builder := range p.Builders {
+		q.Add("builder", builder)
+	}
+	for _, hint := range p.Hints {
+		q.Add("hint", hint)
+	}
+	if p.Fast {
+		q.Set("fast", "true")
+	}
+	if p.UseMempool {
+		q.Set("useMempool", "true")
+	}
+	return q.Encode()
+}
+
+// TxPreferenceStore keeps the transaction preferences of senders and API
+// keys, in Redis if configured, and applies them to the submissions of
+// requests without a query string of their own. The preferences of the
+// sender of a transaction win over those of the key submitting it. They're
+// managed with proxyd_setTxPreferences and proxyd_getTxPreferences, which
+// act on the signer of a request with a verified X-Flashbots-Signature, or
+// else on its API key or auth alias.
+type TxPreferenceStore struct {
+	redisClient redis.UniversalClient
+	prefix      string
+
+	mu    sync.Mutex
+	local *lru.Cache
+}
+
+func NewTxPreferenceStore(redisClient redis.UniversalClient, namespace string) *TxPreferenceStore {
+	s := &TxPreferenceStore{
+		redisClient: redisClient,
+		prefix:      txPreferencesRedisKey,
+	}
+	if namespace != "" {
+		s.prefix = namespace + ":" + txPreferencesRedisKey
+	}
+	if redisClient == nil {
+		s.local, _ = lru.New(txPreferencesMemoryLimit)
+	}
+	return s
+}
+
+// Handles returns whether the method is served by TxPreferenceStore.
+func (s *TxPreferenceStore) Handles(method string) bool {
+	return s != nil && (method == TxPreferencesGetMethod || method == TxPreferencesSetMethod)
+}
+
+// Handle answers proxyd_getTxPreferences with the stored preferences, or
+// null, and proxyd_setTxPreferences, which takes the preferences, or null
+// to clear them.
+func (s *TxPreferenceStore) Handle(ctx context.Context, req *RPCReq) *RPCRes {
+	owner, ok := preferencesOwner(ctx)
+	if !ok {
+		return NewRPCErrorRes(req.ID, ErrTxPreferencesUnauthenticated)
+	}
+
+	if req.Method == TxPreferencesGetMethod {
+		prefs, err := s.get(ctx, owner)
+		if err != nil {
+			log.Error("error getting tx preferences", "req_id", GetReqID(ctx), "err", err)
+			return NewRPCErrorRes(req.ID, ErrInternal)
+		}
+		return NewRPCRes(req.ID, prefs)
+	}
+
+	var params []*TxPreferences
+	if err := json.Unmarshal(req.Params, &params); err != nil || len(params) != 1 {
+		return NewRPCErrorRes(req.ID, ErrInvalidParams("expected the preferences or null"))
+	}
+	if err := s.set(ctx, owner, params[0]); err != nil {
+		log.Error("error setting tx preferences", "req_id", GetReqID(ctx), "err", err)
+		return NewRPCErrorRes(req.ID, ErrInternal)
+	}
+	return NewRPCRes(req.ID, true)
+}
+
+// Query returns the query string to submit tx with for the request, or ""
+// to leave the request as it is.
+func (s *TxPreferenceStore) Query(ctx context.Context, tx *types.Transaction) string {
+	if s == nil {
+		return ""
+	}
+	if rawQuery, _ := ctx.Value(ContextKeyRawQuery).(string); rawQuery != "" {
+		return ""
+	}
+	owners := make([]string, 0, 2)
+	if sender, err := txSender(tx); err == nil {
+		owners = append(owners, "sender:"+strings.ToLower(sender.Hex()))
+	}
+	if auth := GetAuthCtx(ctx); auth != "none" {
+		owners = append(owners, "key:"+auth)
+	}
+	for _, owner := range owners {
+		prefs, err := s.get(ctx, owner)
+		if err != nil {
+			log.Warn("error getting tx preferences", "req_id", GetReqID(ctx), "err", err)
+			return ""
+		}
+		if prefs != nil {
+			RecordTxPreferencesApplied(strings.SplitN(owner, ":", 2)[0])
+			return prefs.query()
+		}
+	}
+	return ""
+}
+
+// preferencesOwner returns who the preferences of a request act on.
+func preferencesOwner(ctx context.Context) (string, bool) {
+	if signer, ok := GetSigner(ctx); ok {
+		return "sender:" + strings.ToLower(signer.Hex()), true
+	}
+	if auth := GetAuthCtx(ctx); auth != "none" {
+		return "key:" + auth, true
+	}
+	return "", false
+}
+
+func (s *TxPreferenceStore) get(ctx context.Context, owner string) (*TxPreferences, error) {
+	var raw string
+	if s.redisClient != nil {
+		val, err := s.redisClient.Get(ctx, s.prefix+":"+owner).Result()
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		raw = val
+	} else {
+		s.mu.Lock()
+		val, ok := s.local.Get(owner)
+		s.mu.Unlock()
+		if !ok {
+			return nil, nil
+		}
+		raw = val.(string)
+	}
+	var prefs TxPreferences
+	if err := json.Unmarshal([]byte(raw), &prefs); err != nil {
+		return nil, err
+	}
+	return &prefs, nil
+}
+
+func (s *TxPreferenceStore) set(ctx context.Context, owner string, prefs *TxPreferences) error {
+	if prefs == nil {
+		if s.redisClient != nil {
+			return s.redisClient.Del(ctx, s.prefix+":"+owner).Err()
+		}
+		s.mu.Lock()
+		s.local.Remove(owner)
+		s.mu.Unlock()
+		return nil
+	}
+	raw := string(mustMarshalJSON(prefs))
+	if s.redisClient != nil {
+		return s.redisClient.Set(ctx, s.prefix+":"+owner, raw, 0).Err()
+	}
+	s.mu.Lock()
+	s.local.Add(owner, raw)
+	s.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,330 @@
+package proxyd
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	usageExportUploadTimeout = 30 * time.Second
+	// usageExportMaxPending bounds the hours kept for retry while uploads
+	// fail.
+	usageExportMaxPending = 24
+)
+
+var usageExportHeader = []string{"hour", "instance", "key", "method", "requests", "errors", "compute_units"}
+
+// usageSink stores an hour's usage report under name.
+type usageSink interface {
+	Put(ctx context.Context, name string, data []byte) error
+}
+
+type objectUsageSink struct {
+	client *s3.Client
+	bucket string
+	prefix string
+}
+
+func (s *objectUsageSink) Put(ctx context.Context, name string, data []byte) error {
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(path.Join(s.prefix, name)),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String("text/csv"),
+	})
+	return wrapErr(err, "error uploading usage report")
+}
+
+type fileUsageSink struct {
+	dir string
+}
+
+func (s *fileUsageSink) Put(ctx context.Context, name string, data []byte) error {
+	file := filepath.Join(s.dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(file, data, 0o644)
+}
+
+// newUsageSink returns the sink for a s3://bucket/prefix, gs://bucket/prefix
+// or file:///dir URL.
+func newUsageSink(cfg UsageExportConfig) (usageSink, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, wrapErr(err, "invalid usage_export.url")
+	}
+	switch u.Scheme {
+	case "s3", "gs":
+		if u.Host == "" {
+			return nil, fmt.Errorf("usage_export.url must look like %s://bucket/prefix", u.Scheme)
+		}
+		client, err := newObjectStoreClient(u.Scheme, cfg.Region)
+		if err != nil {
+			return nil, err
+		}
+		return &objectUsageSink{client: client, bucket: u.Host, prefix: strings.Trim(u.Path, "/")}, nil
+	case "file":
+		if u.Path == "" {
+			return nil, fmt.Errorf("usage_export.url must look like file:///dir")
+		}
+		return &fileUsageSink{dir: u.Path}, nil
+	default:
+		return nil, fmt.Errorf("unsupported usage_export.url scheme %q", u.Scheme)
+	}
+}
+
+type usageKey struct {
+	key    string
+	method string
+}
+
+type usageCounts struct {
+	requests     uint64
+	errors       uint64
+	computeUnits uint64
+}
+
+type usageReport struct {
+	name string
+	data []byte
+}
+
+// UsageExporter counts the requests and compute units of each auth alias, or
+// ID of API keys issued at runtime, by method, and writes them hourly as CSV
+// files to S3 or GCS, for billing to be reconciled against rather than
+// Prometheus, whose retention is limited and counters reset on restart. Each
+// process writes its own file per hour, at
+// <prefix>/<yyyy>/<mm>/<dd>/<hh>/<instance>-<start>.csv with the unix time it
+// started, so the files of an hour add up to the fleet's usage, across
+// restarts too. The hour a process stops in is written on shutdown. Requests
+// without authentication are counted under the key "none". Reports that fail
+// to upload are retried with the next hour's. Only HTTP requests are counted.
+// Reports are only written as CSV, not Parquet.
+type UsageExporter struct {
+	sink         usageSink
+	instance     string
+	started      int64
+	computeUnits map[string]uint64
+	defaultUnits uint64
+	now          func() time.Time
+
+	mu      sync.Mutex
+	hour    time.Time
+	counts  map[usageKey]*usageCounts
+	pending []usageReport
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewUsageExporter returns nil if usage export is disabled.
+func NewUsageExporter(cfg UsageExportConfig) (*UsageExporter, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	sink, err := newUsageSink(cfg)
+	if err != nil {
+		return nil, err
+	}
+	instance := cfg.Instance
+	if instance == "" {
+		if instance, err = os.Hostname(); err != nil {
+			return nil, wrapErr(err, "error getting hostname for usage_export.instance")
+		}
+	}
+	return newUsageExporter(sink, instance, cfg), nil
+}
+
+func newUsageExporter(sink usageSink, instance string, cfg UsageExportConfig) *UsageExporter {
+	e := &UsageExporter{
+		sink:         sink,
+		instance:     instance,
+		computeUnits: cfg.ComputeUnits,
+		defaultUnits: cfg.DefaultComputeUnits,
+		now:          time.Now,
+		counts:       make(map[usageKey]*usageCounts),
+		stop:         make(chan struct{}),
+	}
+	e.started = e.now().Unix()
+	if e.defaultUnits == 0 {
+		e.defaultUnits = 1
+	}
+	e.hour = e.now().UTC().Truncate(time.Hour)
+	return e
+}
+
+// RecordRequests counts the requests of a batch with their responses.
+// Requests that failed to parse aren't counted, and methods that aren't
+// served are counted as MethodUnknown, so clients can't grow the reports at
+// will.
+func (e *UsageExporter) RecordRequests(ctx context.Context, reqs []*RPCReq, responses []*RPCRes) {
+	if e == nil {
+		return
+	}
+	// keys issued at runtime are billed by key rather than by owner
+	key := GetAPIKeyID(ctx)
+	if key == "" {
+		key = GetAuthCtx(ctx)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.rotate()
+	for i, req := range reqs {
+		if req == nil {
+			continue
+		}
+		res := responses[i]
+		method := req.Method
+		if res != nil && res.IsError() && res.Error.Code == notFoundRpcError {
+			method = MethodUnknown
+		}
+		k := usageKey{key: key, method: method}
+		counts := e.counts[k]
+		if counts == nil {
+			counts = new(usageCounts)
+			e.counts[k] = counts
+		}
+		counts.requests++
+		if res != nil && res.IsError() {
+			counts.errors++
+		}
+		units, ok := e.computeUnits[method]
+		if !ok {
+			units = e.defaultUnits
+		}
+		counts.computeUnits += units
+	}
+}
+
+// rotate moves the counts of past hours into a pending report. e.mu must be
+// held.
+func (e *UsageExporter) rotate() {
+	hour := e.now().UTC().Truncate(time.Hour)
+	if !hour.After(e.hour) {
+		return
+	}
+	if len(e.counts) > 0 {
+		e.pending = append(e.pending, e.report())
+		if len(e.pending) > usageExportMaxPending {
+			log.Error("dropping usage report that failed to upload", "name", e.pending[0].name)
+			RecordUsageExport("dropped")
+			e.pending = e.pending[1:]
+		}
+	}
+	e.hour = hour
+	e.counts = make(map[usageKey]*usageCounts)
+}
+
+// report encodes the counts of the current hour. e.mu must be held.
+func (e *UsageExporter) report() usageReport {
+	keys := make([]usageKey, 0, len(e.counts))
+	for k := range e.counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].key != keys[j].key {
+			return keys[i].key < keys[j].key
+		}
+		return keys[i].method < keys[j].method
+	})
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	hour := e.hour.Format(time.RFC3339)
+	_ = w.Write(usageExportHeader)
+	for _, k := range keys {
+		c := e.counts[k]
+		_ = w.Write([]string{
+			hour,
+			e.instance,
+			k.key,
+			k.method,
+			strconv.FormatUint(c.requests, 10),
+			strconv.FormatUint(c.errors, 10),
+			strconv.FormatUint(c.computeUnits, 10),
+		})
+	}
+	w.Flush()
+	return usageReport{
+		name: fmt.Sprintf("%s/%s-%d.csv", e.hour.Format("2006/01/02/15"), e.instance, e.started),
+		data: buf.Bytes(),
+	}
+}
+
+// Start uploads the reports of past hours until Stop is called.
+func (e *UsageExporter) Start() {
+	if e == nil {
+		return
+	}
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-e.stop:
+				return
+			case <-ticker.C:
+				e.flush(false)
+			}
+		}
+	}()
+}
+
+// Stop uploads what was counted so far, including the current hour's.
+func (e *UsageExporter) Stop() {
+	if e == nil {
+		return
+	}
+	close(e.stop)
+	e.wg.Wait()
+	e.flush(true)
+}
+
+// flush uploads the pending reports, and with partial the current hour's.
+func (e *UsageExporter) flush(partial bool) {
+	e.mu.Lock()
+	e.rotate()
+	reports := e.pending
+	e.pending = nil
+	if partial && len(e.counts) > 0 {
+		reports = append(reports, e.report())
+	}
+	e.mu.Unlock()
+
+	var failed []usageReport
+	for _, report := range reports {
+		ctx, cancel := context.WithTimeout(context.Background(), usageExportUploadTimeout)
+		err := e.sink.Put(ctx, report.name, report.data)
+		cancel()
+		if err != nil {
+			log.Error("error exporting usage report", "name", report.name, "err", err)
+			RecordUsageExport("failed")
+			failed = append(failed, report)
+			continue
+		}
+		RecordUsageExport("uploaded")
+	}
+	if len(failed) > 0 && !partial {
+		e.mu.Lock()
+		e.pending = append(failed, e.pending...)
+		e.mu.Unlock()
+	}
+}
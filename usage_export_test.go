@@ -0,0 +1,116 @@
+package proxyd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type failingUsageSink struct {
+	fail bool
+	puts map[string]string
+}
+
+func (s *failingUsageSink) Put(ctx context.Context, name string, data []byte) error {
+	if s.fail {
+		return errors.New("unavailable")
+	}
+	s.puts[name] = string(data)
+	return nil
+}
+
+func TestUsageExporter(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC)
+	sink, err := newUsageSink(UsageExportConfig{URL: "file://" + dir})
+	require.NoError(t, err)
+	e := newUsageExporter(sink, "proxyd-0", UsageExportConfig{
+		ComputeUnits: map[string]uint64{"eth_call": 10},
+	})
+	e.now = func() time.Time { return now }
+	e.hour = now.Truncate(time.Hour)
+	e.started = 1714559400
+
+	ctx := context.WithValue(context.Background(), ContextKeyAuth, "alice") // nolint:staticcheck
+	reqs := []*RPCReq{
+		{Method: "eth_call"},
+		{Method: "eth_call"},
+		{Method: "eth_chainId"},
+		{Method: "eth_foo"},
+		nil,
+	}
+	responses := []*RPCRes{
+		{Result: "0x"},
+		{Error: &RPCErr{Code: -32000, Message: "execution reverted"}},
+		{Result: "0x1"},
+		{Error: ErrMethodNotWhitelisted},
+		{Error: ErrParseErr},
+	}
+	e.RecordRequests(ctx, reqs, responses)
+	e.RecordRequests(context.Background(), reqs[2:3], responses[2:3])
+	// keys issued at runtime are counted by ID rather than by owner
+	keyCtx := WithAPIKeyID(context.WithValue(context.Background(), ContextKeyAuth, "alice"), "3f9a0c21d4b7e855") // nolint:staticcheck
+	e.RecordRequests(keyCtx, reqs[:1], responses[:1])
+
+	// nothing is written before the hour is over
+	e.flush(false)
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+
+	now = now.Add(time.Hour)
+	e.RecordRequests(ctx, reqs[:1], responses[:1])
+	e.flush(false)
+	data, err := os.ReadFile(filepath.Join(dir, "2024/05/01/10/proxyd-0-1714559400.csv"))
+	require.NoError(t, err)
+	require.Equal(t, `hour,instance,key,method,requests,errors,compute_units
+2024-05-01T10:00:00Z,proxyd-0,3f9a0c21d4b7e855,eth_call,1,0,10
+2024-05-01T10:00:00Z,proxyd-0,alice,eth_call,2,1,20
+2024-05-01T10:00:00Z,proxyd-0,alice,eth_chainId,1,0,1
+2024-05-01T10:00:00Z,proxyd-0,alice,unknown,1,1,1
+2024-05-01T10:00:00Z,proxyd-0,none,eth_chainId,1,0,1
+`, string(data))
+
+	// the hour in progress is written on shutdown
+	e.Start()
+	e.Stop()
+	data, err = os.ReadFile(filepath.Join(dir, "2024/05/01/11/proxyd-0-1714559400.csv"))
+	require.NoError(t, err)
+	require.Equal(t, `hour,instance,key,method,requests,errors,compute_units
+2024-05-01T11:00:00Z,proxyd-0,alice,eth_call,1,0,10
+`, string(data))
+}
+
+func TestUsageExporterRetry(t *testing.T) {
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	sink := &failingUsageSink{fail: true, puts: make(map[string]string)}
+	e := newUsageExporter(sink, "proxyd-0", UsageExportConfig{DefaultComputeUnits: 2})
+	e.now = func() time.Time { return now }
+	e.hour = now.Truncate(time.Hour)
+	e.started = 1
+
+	req := []*RPCReq{{Method: "eth_chainId", ID: json.RawMessage("1")}}
+	res := []*RPCRes{{Result: "0x1"}}
+	e.RecordRequests(context.Background(), req, res)
+	now = now.Add(time.Hour)
+	e.flush(false)
+	require.Empty(t, sink.puts)
+	require.Len(t, e.pending, 1)
+
+	// failed reports are uploaded with the next hour's
+	e.RecordRequests(context.Background(), req, res)
+	now = now.Add(time.Hour)
+	sink.fail = false
+	e.flush(false)
+	require.Empty(t, e.pending)
+	require.Equal(t, `hour,instance,key,method,requests,errors,compute_units
+2024-05-01T10:00:00Z,proxyd-0,none,eth_chainId,1,0,2
+`, sink.puts["2024/05/01/10/proxyd-0-1.csv"])
+	require.Contains(t, sink.puts, "2024/05/01/11/proxyd-0-1.csv")
+}
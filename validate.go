@@ -0,0 +1,933 @@
+package proxyd
+
+import (
+	"compress/flate"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const backendReachabilityTimeout = 5 * time.Second
+
+// ValidateConfigFiles loads the config like LoadConfig and validates it like
+// ValidateConfig. It also returns the keys that don't correspond to any config
+// option, which are usually typos.
+func ValidateConfigFiles(paths []string, checkBackends bool) (*Config, []string, []error) {
+	config, unknown, err := LoadConfig(paths...)
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+	return config, unknown, ValidateConfig(config, checkBackends)
+}
+
+// redactedValue replaces secrets in RedactConfig.
+const redactedValue = "REDACTED"
+
+// RedactConfig returns a copy of config for printing, with the values of the
+// fields that may hold secrets replaced. Environment variable and secret
+// references are kept, as they don't contain the secret.
+func RedactConfig(config *Config) *Config {
+	redacted := *config
+	redactRedis := func(cfg *RedisConfig) {
+		redactSecret(&cfg.URL)
+		redactSecret(&cfg.ReadURL)
+		redactSecret(&cfg.SentinelPassword)
+	}
+	redactRedis(&redacted.Redis)
+
+	redacted.Backends = make(BackendsConfig, len(config.Backends))
+	for name, cfg := range config.Backends {
+		b := *cfg
+		redactSecret(&b.RPCURL)
+		redactSecret(&b.WSURL)
+		redactSecret(&b.Password)
+		b.Headers = redactSecretMap(cfg.Headers)
+		redacted.Backends[name] = &b
+	}
+	redacted.BackendGroups = make(BackendGroupsConfig, len(config.BackendGroups))
+	for name, cfg := range config.BackendGroups {
+		bg := *cfg
+		redactRedis(&bg.ConsensusHARedis)
+		redacted.BackendGroups[name] = &bg
+	}
+
+	if config.Authentication != nil {
+		// the keys are the secrets
+		redacted.Authentication = make(map[string]string, len(config.Authentication))
+		for i, key := range sortedKeys(config.Authentication) {
+			alias := config.Authentication[key]
+			if !IsSecretRef(key) && !strings.HasPrefix(key, "$") {
+				key = fmt.Sprintf("%s-%d", redactedValue, i)
+			}
+			redacted.Authentication[key] = alias
+		}
+	}
+	redacted.HMACAuth.Keys = redactSecretMap(config.HMACAuth.Keys)
+	if config.Tenants != nil {
+		redacted.Tenants = make(map[string]TenantConfig, len(config.Tenants))
+		for name, tc := range config.Tenants {
+			tc.APIKeys = make([]string, len(tc.APIKeys))
+			for i := range tc.APIKeys {
+				tc.APIKeys[i] = redactedValue
+			}
+			redacted.Tenants[name] = tc
+		}
+	}
+	if config.AuthPolicies != nil {
+		redacted.AuthPolicies = make(map[string]AuthPolicyConfig, len(config.AuthPolicies))
+		for alias, pc := range config.AuthPolicies {
+			pc.Headers = redactSecretMap(pc.Headers)
+			redacted.AuthPolicies[alias] = pc
+		}
+	}
+	for _, field := range []*string{
+		&redacted.APIKeys.AdminToken,
+		&redacted.MaintenanceMode.AdminToken,
+		&redacted.TxAudit.AdminToken,
+		&redacted.TrafficAnalysis.AdminToken,
+		&redacted.RateLimitExemptions.AdminToken,
+		&redacted.FaultInjection.AdminToken,
+		&redacted.InteropValidationConfig.AdminToken,
+		&redacted.IPPrivacy.Secret,
+		&redacted.Webhooks.Secret,
+		&redacted.ResponseSigning.PrivateKey,
+		&redacted.Secrets.Vault.Token,
+	} {
+		redactSecret(field)
+	}
+	return &redacted
+}
+
+func redactSecret(value *string) {
+	if *value != "" && !IsSecretRef(*value) && !strings.HasPrefix(*value, "$") {
+		*value = redactedValue
+	}
+}
+
+func redactSecretMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	redacted := make(map[string]string, len(m))
+	for k, v := range m {
+		redactSecret(&v)
+		redacted[k] = v
+	}
+	return redacted
+}
+
+// ValidateConfig checks a config without starting proxyd and returns every
+// problem found. Environment variable and secret references are resolved but
+// the config isn't modified. If checkBackends is set, it also checks that each
+// backend accepts connections.
+func ValidateConfig(config *Config, checkBackends bool) []error {
+	var errs []error
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+	secrets, err := NewSecretStore(config.Secrets)
+	if err != nil {
+		fail("%w", err)
+		secrets, _ = NewSecretStore(SecretsConfig{})
+	}
+	resolve := func(what, value string) string {
+		resolved, err := secrets.Resolve(value)
+		if err != nil {
+			fail("%s: %w", what, err)
+		}
+		return resolved
+	}
+
+	wsBackends := wsBackendNames(config)
+	for _, name := range sortedKeys(config.Backends) {
+		cfg := config.Backends[name]
+		urls, err := resolveBackendURLs(cfg, secrets)
+		if err != nil {
+			fail("backend %s %w", name, err)
+			continue
+		}
+		errs = append(errs, checkBackendURLs(name, cfg, urls, wsBackends[name])...)
+		resolve(fmt.Sprintf("backend %s password", name), cfg.Password)
+		for _, header := range sortedKeys(cfg.Headers) {
+			resolve(fmt.Sprintf("backend %s header %s", name, header), cfg.Headers[header])
+		}
+		receiptsTarget, err := ReadFromEnvOrConfig(cfg.ConsensusReceiptsTarget)
+		if err == nil {
+			_, err = validateReceiptsTarget(receiptsTarget)
+		}
+		if err != nil {
+			fail("backend %s: %w", name, err)
+		}
+
+		if checkBackends && (urls.rpc != "" || urls.ipcPath != "") {
+			if err := checkBackendReachable(urls.rpc, urls.ipcPath); err != nil {
+				fail("backend %s is not reachable: %w", name, err)
+			}
+		}
+	}
+
+	for _, authKey := range sortedKeys(config.Authentication) {
+		if authKey != "none" {
+			resolve(fmt.Sprintf("authentication key for %s", config.Authentication[authKey]), authKey)
+		}
+	}
+	resolve("redis url", config.Redis.URL)
+	resolve("redis read_url", config.Redis.ReadURL)
+	for _, token := range []struct{ what, value string }{
+		{"api_keys.admin_token", config.APIKeys.AdminToken},
+		{"maintenance_mode.admin_token", config.MaintenanceMode.AdminToken},
+		{"tx_audit.admin_token", config.TxAudit.AdminToken},
+		{"traffic_analysis.admin_token", config.TrafficAnalysis.AdminToken},
+		{"rate_limit_exemptions.admin_token", config.RateLimitExemptions.AdminToken},
+		{"fault_injection.admin_token", config.FaultInjection.AdminToken},
+		{"interop_validation.admin_token", config.InteropValidationConfig.AdminToken},
+		{"ip_privacy.secret", config.IPPrivacy.Secret},
+		{"webhooks.secret", config.Webhooks.Secret},
+	} {
+		resolve(token.what, token.value)
+	}
+	if _, err := NewHMACAuth(config.HMACAuth, secrets); err != nil {
+		fail("%w", err)
+	}
+	if _, err := NewResponseSigner(config.ResponseSigning, secrets); err != nil {
+		fail("%w", err)
+	}
+	if len(config.Plugins) > 0 {
+		if _, err := NewPluginHost(config.Plugins); err != nil {
+			fail("%w", err)
+		}
+	}
+	if _, err := resolveL1RPCURL(config.DerivationHealth); err != nil {
+		fail("%w", err)
+	}
+
+	return append(errs, checkConfig(config)...)
+}
+
+// checkConfig checks the parts of a config that don't depend on secrets or
+// the network, and returns every problem found. Start fails on the first of
+// them, ValidateConfig reports them all.
+// ConfigWarnings returns the problems of config that don't stop proxyd from
+// starting.
+func ConfigWarnings(config *Config) []string {
+	var warnings []string
+	for _, name := range sortedKeys(config.BackendGroups) {
+		bg := config.BackendGroups[name]
+		if len(bg.Backends) == 0 && bg.Discovery == nil {
+			warnings = append(warnings, fmt.Sprintf("backend group %s has no backends, its requests will fail", name))
+		}
+	}
+	return warnings
+}
+
+func checkConfig(config *Config) []error {
+	var errs []error
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if err := checkUnrefreshedSecrets(config); err != nil {
+		fail("%w", err)
+	}
+	if len(config.Backends) == 0 {
+		fail("must define at least one backend")
+	}
+	if len(config.BackendGroups) == 0 {
+		fail("must define at least one backend group")
+	}
+	mappings := MapRollupMethods(SplitReadsWrites(config.RPCMethodMappings, config.WritesGroup, config.ReadsGroup), config.RollupGroup)
+	if len(mappings) == 0 {
+		fail("must define at least one RPC method mapping")
+	}
+
+	for _, name := range sortedKeys(config.Backends) {
+		cfg := config.Backends[name]
+		if _, err := NewHeaderPolicy(cfg.HeaderPolicy); err != nil {
+			fail("invalid header_policy for backend %s: %w", name, err)
+		}
+		if _, err := newMaintenanceWindows(cfg.Maintenance); err != nil {
+			fail("invalid maintenance of backend %s: %w", name, err)
+		}
+		if _, err := ParseClientFlavor(cfg.ClientFlavor); err != nil {
+			fail("backend %s: %w", name, err)
+		}
+	}
+
+	for _, name := range sortedKeys(config.BackendGroups) {
+		bg := config.BackendGroups[name]
+		if bg.ConsensusQuorum < 0 || bg.ConsensusQuorum > len(bg.Backends) {
+			fail("consensus_quorum of backend group %s must be between 0 and its number of backends", name)
+		}
+		if bg.LatestTranslation != nil {
+			if bg.ConsensusAware || bg.RoutingStrategy == ConsensusAwareRoutingStrategy {
+				fail("latest_translation cannot be used with consensus aware backend group %s", name)
+			}
+			if bg.LatestTranslation.Interval < 0 {
+				fail("latest_translation.interval of backend group %s must be >= 0", name)
+			}
+		}
+		if bg.Discovery != nil {
+			if bg.ConsensusAware || bg.RoutingStrategy == ConsensusAwareRoutingStrategy {
+				fail("discovery cannot be used with consensus aware backend group %s", name)
+			}
+			if t := config.Backends[bg.Discovery.Template]; t == nil || t.RPCURL == "" {
+				fail("discovery template %s of backend group %s must be a backend with an rpc_url", bg.Discovery.Template, name)
+			}
+			if err := checkDiscoveryConfig(bg.Discovery); err != nil {
+				fail("invalid discovery for backend group %s: %w", name, err)
+			}
+		}
+		members := make(map[string]bool, len(bg.Backends))
+		for _, b := range bg.Backends {
+			members[b] = true
+			if config.Backends[b] == nil {
+				fail("backend group %s references undefined backend %s", name, b)
+			}
+		}
+		for _, fb := range bg.Fallbacks {
+			if !members[fb] {
+				fail("fallback %s of backend group %s is not one of its backends", fb, name)
+			}
+		}
+
+		if bg.BlockReceiptsMinBatch < 0 {
+			fail("block_receipts_min_batch of backend group %s must be >= 0", name)
+		}
+
+		if bg.ConsensusAware && bg.RoutingStrategy != "" {
+			fail("consensus_aware and routing_strategy are mutually exclusive for backend group %s", name)
+		}
+		switch bg.RoutingStrategy {
+		case ConsensusAwareRoutingStrategy, MulticallRoutingStrategy, FallbackRoutingStrategy, "":
+		default:
+			fail("invalid routing_strategy %q for backend group %s", bg.RoutingStrategy, name)
+		}
+
+		if _, err := NewRequestRewriter(bg.RequestRewrites); err != nil {
+			fail("invalid request_rewrites for backend group %s: %w", name, err)
+		}
+		if err := checkInflightConfig(bg.Inflight); err != nil {
+			fail("invalid inflight for backend group %s: %w", name, err)
+		}
+	}
+	if err := checkInflightConfig(config.Inflight); err != nil {
+		fail("invalid inflight: %w", err)
+	}
+	if level := config.WSCompression.Level; level < flate.HuffmanOnly || level > flate.BestCompression {
+		fail("ws_compression.level must be between %d and %d", flate.HuffmanOnly, flate.BestCompression)
+	}
+	if config.WSCompression.Threshold < 0 {
+		fail("ws_compression.threshold must be >= 0")
+	}
+	if config.WSReplay.Size < 0 || config.WSReplay.TTL < 0 {
+		fail("ws_replay.size and ws_replay.ttl must be >= 0")
+	}
+	if config.WSNotificationBatching.FlushInterval < 0 || config.WSNotificationBatching.MaxSize < 0 {
+		fail("ws_notification_batching.flush_interval and ws_notification_batching.max_size must be >= 0")
+	}
+	if config.WSSendBuffer.Size < 0 {
+		fail("ws_send_buffer.size must be >= 0")
+	}
+	switch config.WSSendBuffer.Policy {
+	case "", WSSendPolicyDisconnect, WSSendPolicySkip:
+	default:
+		fail("invalid ws_send_buffer.policy %s, must be disconnect or skip", config.WSSendBuffer.Policy)
+	}
+	if err := checkTenantsConfig(config); err != nil {
+		fail("%w", err)
+	}
+	if (config.Server.TLSCertFile == "") != (config.Server.TLSKeyFile == "") {
+		fail("server.tls_cert_file and server.tls_key_file must be set together")
+	}
+	for _, host := range sortedKeys(config.VirtualHosts) {
+		vc := config.VirtualHosts[host]
+		for _, method := range sortedKeys(vc.RPCMethodMappings) {
+			if config.BackendGroups[vc.RPCMethodMappings[method]] == nil {
+				fail("virtual host %s maps method %s to undefined backend group %s", host, method, vc.RPCMethodMappings[method])
+			}
+		}
+		if vc.WSBackendGroup != "" && config.BackendGroups[vc.WSBackendGroup] == nil {
+			fail("virtual host %s has undefined ws backend group %s", host, vc.WSBackendGroup)
+		}
+		if (vc.TLSCertFile == "") != (vc.TLSKeyFile == "") {
+			fail("tls_cert_file and tls_key_file of virtual host %s must be set together", host)
+		}
+	}
+	if _, err := newServerTLSConfig(config.Server, nil); err != nil {
+		fail("%w", err)
+	}
+	aliases := make(map[string]bool)
+	for _, alias := range config.Authentication {
+		aliases[alias] = true
+	}
+	// HMAC key IDs are aliases too, API keys are only known at runtime
+	for id := range config.HMACAuth.Keys {
+		aliases[id] = true
+	}
+	for _, alias := range sortedKeys(config.AuthPolicies) {
+		pc := config.AuthPolicies[alias]
+		if !aliases[alias] && !config.APIKeys.Enabled {
+			fail("auth policy %s is not an authentication alias", alias)
+		}
+		if _, ok := config.RateLimitTiers[pc.RateLimitTier]; pc.RateLimitTier != "" && !ok {
+			fail("auth policy %s has undefined rate limit tier %s", alias, pc.RateLimitTier)
+		}
+		if pc.BackendGroup != "" && config.BackendGroups[pc.BackendGroup] == nil {
+			fail("auth policy %s has undefined backend group %s", alias, pc.BackendGroup)
+		}
+	}
+	if _, err := NewCallPolicies(config.CallPolicies, config.RateLimitTiers); err != nil {
+		fail("%w", err)
+	}
+
+	if config.WSBackendGroup != "" && config.BackendGroups[config.WSBackendGroup] == nil {
+		fail("ws backend group %s does not exist", config.WSBackendGroup)
+	}
+	if config.WSBackendGroup == "" && (config.Server.WSPort != 0 || config.Server.WSUnixSocket != "") {
+		fail("a ws port was defined, but no ws group was defined")
+	}
+
+	for _, method := range sortedKeys(config.RPCMethodMappings) {
+		group := config.RPCMethodMappings[method]
+		if config.BackendGroups[group] == nil {
+			fail("method %s is mapped to undefined backend group %s", method, group)
+		}
+	}
+	if config.WritesGroup != "" && config.BackendGroups[config.WritesGroup] == nil {
+		fail("writes_group %s does not exist", config.WritesGroup)
+	}
+	if config.ReadsGroup != "" && config.BackendGroups[config.ReadsGroup] == nil {
+		fail("reads_group %s does not exist", config.ReadsGroup)
+	}
+	if config.RollupGroup != "" && config.BackendGroups[config.RollupGroup] == nil {
+		fail("rollup_group %s does not exist", config.RollupGroup)
+	}
+
+	for i, rule := range config.RoutingRules {
+		if rule.Method == "" {
+			fail("routing_rules[%d].method must be set", i)
+		}
+		if config.BackendGroups[rule.BackendGroup] == nil {
+			fail("routing_rules[%d].backend_group %s does not exist", i, rule.BackendGroup)
+		}
+		if rule.HeadGroup != "" && config.BackendGroups[rule.HeadGroup] == nil {
+			fail("routing_rules[%d].head_group %s does not exist", i, rule.HeadGroup)
+		}
+		for _, cond := range rule.When {
+			if cond.Param == "" {
+				fail("routing_rules[%d] condition param must be set", i)
+			}
+			if _, err := regexp.Compile(cond.Matches); err != nil {
+				fail("invalid routing_rules[%d] condition matches: %w", i, err)
+			}
+		}
+	}
+
+	if config.BlobTx.BackendGroup != "" && config.BackendGroups[config.BlobTx.BackendGroup] == nil {
+		fail("blob_tx.backend_group %s does not exist", config.BlobTx.BackendGroup)
+	}
+	if config.BlobTx.MaxBlobs < 0 {
+		fail("blob_tx.max_blobs must be >= 0")
+	}
+	if config.BlobTx.MaxBlobFeeCap != nil && config.BlobTx.MaxBlobFeeCap.Sign() <= 0 {
+		fail("blob_tx.max_blob_fee_cap must be > 0")
+	}
+
+	if config.PendingTxLimit.Enabled {
+		if config.PendingTxLimit.MaxPending <= 0 {
+			fail("pending_tx_limit.max_pending must be > 0")
+		}
+		if config.Redis.URL == "" {
+			fail("pending_tx_limit requires a redis config")
+		}
+		if _, ok := mappings["eth_sendRawTransaction"]; !ok {
+			fail("pending_tx_limit requires eth_sendRawTransaction to be mapped")
+		}
+	}
+
+	for _, method := range config.Affinity.Methods {
+		if _, ok := config.RPCMethodMappings[method]; !ok {
+			fail("affinity method %s is not in rpc_method_mappings", method)
+		}
+	}
+	if config.ReadYourWrites.Window < 0 {
+		fail("read_your_writes.window must be >= 0")
+	}
+	shed := config.LoadShedding
+	if shed.Enabled && shed.MaxHeapBytes == 0 && shed.MaxGoroutines == 0 && shed.MaxInflightRequests <= 0 {
+		fail("load_shedding needs max_heap_bytes, max_goroutines or max_inflight_requests")
+	}
+	if shed.MaxGoroutines < 0 || shed.MaxInflightRequests < 0 || shed.Interval < 0 || shed.RetryAfter < 0 {
+		fail("load_shedding values must be >= 0")
+	}
+
+	if config.BackendScoring.Interval < 0 {
+		fail("backend_scoring.interval must be >= 0")
+	}
+
+	retry := config.NotFoundRetry
+	if retry.MaxRetries < 0 || retry.Backoff < 0 || retry.TxWindow < 0 {
+		fail("not_found_retry values must be >= 0")
+	}
+	if config.Affinity.TTL < 0 {
+		fail("affinity.ttl must be >= 0")
+	}
+
+	if config.Filters.MaxFilters < 0 {
+		fail("filters.max_filters must be >= 0")
+	}
+
+	fees := config.TxFeeFilter
+	if fees.MinFeeCapMultiplier < 0 || fees.MaxFeeCapMultiplier < 0 || fees.MinTipCapMultiplier < 0 || fees.MaxTipCapMultiplier < 0 {
+		fail("tx_fee_filter multipliers must be >= 0")
+	}
+	if fees.MaxFeeCapMultiplier > 0 && fees.MinFeeCapMultiplier > fees.MaxFeeCapMultiplier {
+		fail("tx_fee_filter.min_fee_cap_multiplier must be <= max_fee_cap_multiplier")
+	}
+	if fees.MaxTipCapMultiplier > 0 && fees.MinTipCapMultiplier > fees.MaxTipCapMultiplier {
+		fail("tx_fee_filter.min_tip_cap_multiplier must be <= max_tip_cap_multiplier")
+	}
+
+	if _, ok := config.Authentication["none"]; ok {
+		fail("cannot use none as an auth key")
+	}
+
+	if config.Redis.ReadURL != "" && config.Redis.URL == "" {
+		fail("must specify a Redis primary URL. only read endpoint is set")
+	}
+	if config.RateLimit.UseRedis && config.Redis.URL == "" {
+		fail("must specify a Redis URL if UseRedis is true in rate limit config")
+	}
+	if config.Redis.SentinelMasterName != "" {
+		if len(config.Redis.SentinelAddrs) == 0 {
+			fail("redis.sentinel_addrs must be set with sentinel_master_name")
+		}
+		if config.Redis.RedisCluster {
+			fail("redis.sentinel_master_name cannot be used with redis_cluster")
+		}
+	}
+	if config.Redis.FallbackRateLimitDivisor < 0 {
+		fail("redis.fallback_rate_limit_divisor must be >= 0")
+	}
+	if (config.Redis.TLSCertFile == "") != (config.Redis.TLSKeyFile == "") {
+		fail("redis.tls_cert_file and tls_key_file must be set together")
+	}
+	for _, mode := range []struct{ what, value string }{
+		{"cache", config.Redis.DegradedMode.Cache},
+		{"sender_limits", config.Redis.DegradedMode.SenderLimits},
+	} {
+		if mode.value != "" && mode.value != RedisFailOpen && mode.value != RedisFailClosed {
+			fail("redis.degraded_mode.%s must be %s or %s", mode.what, RedisFailOpen, RedisFailClosed)
+		}
+	}
+	if config.SenderRateLimit.Enabled {
+		if config.SenderRateLimit.Limit <= 0 {
+			fail("limit in sender_rate_limit must be > 0")
+		}
+		if time.Duration(config.SenderRateLimit.Interval) < time.Second {
+			fail("interval in sender_rate_limit must be >= 1s")
+		}
+	}
+
+	if _, err := parseUnixSocketMode(config.Server.UnixSocketMode); err != nil {
+		fail("%w", err)
+	}
+	for _, method := range sortedKeys(config.Server.MethodTimeouts) {
+		if config.Server.MethodTimeouts[method] <= 0 {
+			fail("method_timeouts for %s must be > 0", method)
+		}
+	}
+	if config.Policy.URL != "" && len(config.Policy.Methods) == 0 {
+		fail("policy.methods must not be empty")
+	}
+	for _, name := range sortedKeys(config.FeatureFlags.Flags) {
+		if _, err := newFeatureFlag(name, config.FeatureFlags.Flags[name]); err != nil {
+			fail("%w", err)
+		}
+	}
+	if config.FeatureFlags.Redis && config.Redis.URL == "" {
+		fail("feature_flags.redis requires a redis config")
+	}
+	for _, name := range sortedKeys(config.SignerClasses) {
+		sc := config.SignerClasses[name]
+		if _, err := parseRequestPriority(sc.Priority); err != nil {
+			fail("signer class %s: %w", name, err)
+		}
+		for _, s := range sc.Signers {
+			if !common.IsHexAddress(s) {
+				fail("signer class %s has invalid signer %s", name, s)
+			}
+		}
+		for _, method := range sortedKeys(sc.RPCMethodMappings) {
+			if config.BackendGroups[sc.RPCMethodMappings[method]] == nil {
+				fail("signer class %s maps method %s to undefined backend group %s", name, method, sc.RPCMethodMappings[method])
+			}
+		}
+	}
+	if config.HMACAuth.MaxClockSkew < 0 {
+		fail("hmac_auth.max_clock_skew must be >= 0")
+	}
+	if config.ReplayProtection.FlashbotsWindow < 0 {
+		fail("replay_protection.flashbots_window must be >= 0")
+	}
+	if config.APIKeys.Enabled {
+		if config.Redis.URL == "" {
+			fail("api_keys requires a redis config")
+		}
+		if config.APIKeys.AdminToken == "" {
+			fail("api_keys.admin_token must be set")
+		}
+		if !config.Metrics.Enabled {
+			fail("api_keys are managed on the metrics listener, which must be enabled")
+		}
+	}
+	for _, rules := range [][]string{config.IPFilter.Allow, config.IPFilter.Deny} {
+		if _, err := parseIPRules(rules); err != nil {
+			fail("%w", err)
+		}
+	}
+	if config.IPFilter.RefreshInterval < 0 {
+		fail("ip_filter.refresh_interval must be >= 0")
+	}
+	for _, group := range sortedKeys(config.MaintenanceMode.Groups) {
+		if config.BackendGroups[group] == nil {
+			fail("maintenance_mode has undefined backend group %s", group)
+		}
+	}
+	if config.MaintenanceMode.AdminToken != "" {
+		if !config.Metrics.Enabled {
+			fail("maintenance_mode.admin_token enables an endpoint on the metrics listener, which must be enabled")
+		}
+	}
+	if config.IPPrivacy.Enabled {
+		if config.IPPrivacy.Secret == "" {
+			fail("ip_privacy.secret must be set")
+		}
+		if config.IPPrivacy.RotationInterval < 0 {
+			fail("ip_privacy.rotation_interval must be >= 0")
+		}
+	}
+	if config.TxAudit.Enabled {
+		switch config.TxAudit.Sink {
+		case TxAuditSinkRedis:
+			if config.Redis.URL == "" {
+				fail("tx_audit with the redis sink requires a redis config")
+			}
+		case TxAuditSinkFile:
+			if config.TxAudit.File == "" {
+				fail("tx_audit.file must be set with the file sink")
+			}
+		default:
+			fail("invalid tx_audit.sink %q, must be %s or %s", config.TxAudit.Sink, TxAuditSinkRedis, TxAuditSinkFile)
+		}
+	}
+	if config.TxAudit.AdminToken != "" {
+		if !config.Metrics.Enabled {
+			fail("tx_audit.admin_token enables an endpoint on the metrics listener, which must be enabled")
+		}
+	}
+	if config.TrafficAnalysis.Enabled {
+		if config.TrafficAnalysis.AdminToken == "" {
+			fail("traffic_analysis requires an admin_token to serve /admin/top")
+		}
+		if !config.Metrics.Enabled {
+			fail("traffic_analysis.admin_token enables an endpoint on the metrics listener, which must be enabled")
+		}
+		if config.TrafficAnalysis.Capacity < 0 {
+			fail("traffic_analysis.capacity must not be negative")
+		}
+	}
+	if err := (&RateLimitExemptions{}).Set(config.RateLimitExemptions.RateLimitExemptionList); err != nil {
+		fail("rate_limit_exemptions: %w", err)
+	}
+	if config.RateLimitExemptions.AdminToken != "" {
+		if !config.Metrics.Enabled {
+			fail("rate_limit_exemptions.admin_token enables an endpoint on the metrics listener, which must be enabled")
+		}
+	}
+	for _, name := range sortedKeys(config.FaultInjection.Backends) {
+		if config.Backends[name] == nil {
+			fail("fault_injection has undefined backend %s", name)
+		} else if err := checkFaultConfig(config.FaultInjection.Backends[name]); err != nil {
+			fail("faults of backend %s: %v", name, err)
+		}
+	}
+	if config.FaultInjection.Enabled && config.FaultInjection.AdminToken != "" {
+		if !config.Metrics.Enabled {
+			fail("fault_injection.admin_token enables an endpoint on the metrics listener, which must be enabled")
+		}
+	}
+	if config.Cache.Immutable.Enabled && !config.Cache.Enabled {
+		fail("cache.immutable requires cache.enabled")
+	}
+	if config.Cache.Immutable.MaxEntries < 0 {
+		fail("cache.immutable.max_entries must be >= 0")
+	}
+	if rep := config.SenderReputation; rep.Enabled {
+		if rep.MinScore < 0 || rep.MinScore > 1 {
+			fail("sender_reputation.min_score must be between 0 and 1")
+		}
+		if rep.HalfLife < 0 {
+			fail("sender_reputation.half_life must be >= 0")
+		}
+		if rep.LowScoreBackendGroup != "" && config.BackendGroups[rep.LowScoreBackendGroup] == nil {
+			fail("sender_reputation.low_score_backend_group %s does not exist", rep.LowScoreBackendGroup)
+		}
+	}
+	if usage := config.UsageExport; usage.Enabled && usage.URL == "" {
+		fail("usage_export.url must be set")
+	}
+	if warming := config.Cache.Warming; warming.Enabled {
+		if config.Redis.URL == "" {
+			fail("cache.warming requires a redis config")
+		}
+		if bg := config.BackendGroups[warming.BackendGroup]; bg == nil {
+			fail("cache.warming.backend_group %s does not exist", warming.BackendGroup)
+		} else if !bg.ConsensusAware && bg.RoutingStrategy != ConsensusAwareRoutingStrategy {
+			fail("cache.warming.backend_group %s must be consensus aware", warming.BackendGroup)
+		}
+		for _, req := range warming.Requests {
+			var params []json.RawMessage
+			if req.Method == "" || json.Unmarshal([]byte(req.Params), &params) != nil {
+				fail("cache.warming.requests need a method and a JSON array of params")
+			}
+		}
+		if warming.PollInterval < 0 || warming.TTL < 0 {
+			fail("cache.warming.poll_interval and cache.warming.ttl must be >= 0")
+		}
+	}
+	switch config.Cassette.Mode {
+	case "":
+	case CassetteModeRecord, CassetteModeReplay:
+		if config.Cassette.Dir == "" {
+			fail("cassette.dir must be set")
+		}
+	default:
+		fail("invalid cassette.mode %s, must be record or replay", config.Cassette.Mode)
+	}
+	if config.TxDedup.Window < 0 {
+		fail("tx_dedup.window must be >= 0")
+	}
+	if config.EstimateGas.Enabled {
+		if config.EstimateGas.CacheTTL < 0 {
+			fail("estimate_gas.cache_ttl must be >= 0")
+		}
+		if config.EstimateGas.Multiplier != 0 && config.EstimateGas.Multiplier < 1 {
+			fail("estimate_gas.multiplier must be >= 1")
+		}
+		if be := config.EstimateGas.Backend; be != "" && config.Backends[be] == nil {
+			fail("estimate_gas.backend %s does not exist", be)
+		}
+	}
+	if config.EarlyReturn.Enabled && len(config.EarlyReturn.Callers) == 0 {
+		fail("early_return.callers must be set")
+	}
+	if config.EarlyReturn.Timeout < 0 || config.EarlyReturn.StatusTTL < 0 {
+		fail("early_return.timeout and early_return.status_ttl must be >= 0")
+	}
+	if config.LeaderElection.Interval < 0 {
+		fail("leader_election.interval must be >= 0")
+	}
+	if config.DerivationHealth.Interval < 0 {
+		fail("derivation_health.interval must be >= 0")
+	}
+	if config.CapabilityProbing.Interval < 0 {
+		fail("capability_probing.interval must be >= 0")
+	}
+	if config.CacheControl.MaxAge < 0 {
+		fail("cache_control.max_age must be >= 0")
+	}
+	if config.InteropValidationConfig.AdminToken != "" && !config.Metrics.Enabled {
+		fail("interop_validation.admin_token enables an endpoint on the metrics listener, which must be enabled")
+	}
+	if config.HealthGossip.Enabled && config.Redis.URL == "" {
+		fail("health_gossip requires a redis config")
+	}
+
+	return errs
+}
+
+// backendURLs are the resolved addresses of a backend.
+type backendURLs struct {
+	rpc       string
+	ws        string
+	ipcPath   string
+	conductor string
+	leader    string
+	rollupRPC string
+}
+
+// resolveBackendURLs resolves the addresses of a backend like Start does.
+func resolveBackendURLs(cfg *BackendConfig, secrets *SecretStore) (backendURLs, error) {
+	var urls backendURLs
+	var err error
+	if urls.rpc, err = secrets.Resolve(cfg.RPCURL); err != nil {
+		return urls, fmt.Errorf("rpc_url: %w", err)
+	}
+	if urls.ws, err = secrets.Resolve(cfg.WSURL); err != nil {
+		return urls, fmt.Errorf("ws_url: %w", err)
+	}
+	for _, v := range []struct {
+		what  string
+		value string
+		dst   *string
+	}{
+		{"ipc_path", cfg.IPCPath, &urls.ipcPath},
+		{"conductor_url", cfg.ConductorURL, &urls.conductor},
+		{"leader_url", cfg.LeaderURL, &urls.leader},
+		{"rollup_rpc_url", cfg.RollupRPCURL, &urls.rollupRPC},
+	} {
+		if *v.dst, err = ReadFromEnvOrConfig(v.value); err != nil {
+			return urls, fmt.Errorf("%s: %w", v.what, err)
+		}
+	}
+	return urls, nil
+}
+
+// checkBackendURLs checks the resolved addresses of a backend. ws is set for
+// backends of the ws backend group, the only ones whose ws_url is dialed.
+func checkBackendURLs(name string, cfg *BackendConfig, urls backendURLs, ws bool) []error {
+	var errs []error
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+	switch {
+	case urls.ipcPath != "" && urls.rpc != "":
+		fail("rpc_url and ipc_path are mutually exclusive for backend %s", name)
+	case urls.ipcPath != "" && (cfg.CAFile != "" || cfg.ClientCertFile != "" || cfg.ClientKeyFile != ""):
+		fail("ca_file, client_cert_file and client_key_file cannot be used with ipc_path for backend %s", name)
+	case urls.ipcPath == "" && urls.rpc == "":
+		fail("must define an RPC URL or IPC path for backend %s", name)
+	}
+	if urls.rpc != "" {
+		schemes := []string{"http", "https"}
+		if ws {
+			// backends only used for ws subscriptions may point rpc_url at
+			// their ws endpoint
+			schemes = append(schemes, "ws", "wss")
+		}
+		if err := checkBackendURL(urls.rpc, schemes...); err != nil {
+			fail("invalid rpc_url for backend %s: %w", name, err)
+		}
+	}
+	if urls.ws != "" && ws {
+		if err := checkBackendURL(urls.ws, "ws", "wss"); err != nil {
+			fail("invalid ws_url for backend %s: %w", name, err)
+		}
+	}
+	if urls.conductor != "" && urls.leader != "" {
+		fail("conductor_url and leader_url are mutually exclusive for backend %s", name)
+	}
+	for _, u := range []string{urls.conductor, urls.leader} {
+		if u == "" {
+			continue
+		}
+		if err := checkBackendURL(u, "http", "https"); err != nil {
+			fail("invalid leader check url for backend %s: %w", name, err)
+		}
+	}
+	if urls.rollupRPC != "" {
+		if err := checkBackendURL(urls.rollupRPC, "http", "https"); err != nil {
+			fail("invalid rollup_rpc_url for backend %s: %w", name, err)
+		}
+	}
+	return errs
+}
+
+// wsBackendNames returns the backends of the ws backend group.
+func wsBackendNames(config *Config) map[string]bool {
+	names := make(map[string]bool)
+	if bg := config.BackendGroups[config.WSBackendGroup]; bg != nil {
+		for _, b := range bg.Backends {
+			names[b] = true
+		}
+	}
+	return names
+}
+
+// resolveL1RPCURL resolves and checks derivation_health.l1_rpc_url.
+func resolveL1RPCURL(cfg DerivationHealthConfig) (string, error) {
+	l1RPCURL, err := ReadFromEnvOrConfig(cfg.L1RPCURL)
+	if err != nil {
+		return "", err
+	}
+	if l1RPCURL != "" {
+		if err := checkBackendURL(l1RPCURL, "http", "https"); err != nil {
+			return "", fmt.Errorf("invalid derivation_health.l1_rpc_url: %w", err)
+		}
+	}
+	return l1RPCURL, nil
+}
+
+func checkBackendURL(raw string, schemes ...string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	for _, s := range schemes {
+		if u.Scheme == s {
+			if u.Host == "" {
+				return fmt.Errorf("missing host")
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("scheme must be one of %s", strings.Join(schemes, ", "))
+}
+
+// checkBackendReachable checks that the backend accepts connections. It does
+// not send any requests.
+func checkBackendReachable(rpcURL, ipcPath string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), backendReachabilityTimeout)
+	defer cancel()
+
+	var conn net.Conn
+	var err error
+	if ipcPath != "" {
+		conn, err = dialIPC(ctx, ipcPath)
+	} else {
+		var u *url.URL
+		if u, err = url.Parse(rpcURL); err != nil {
+			return err
+		}
+		host := u.Host
+		if u.Port() == "" {
+			port := "80"
+			if u.Scheme == "https" {
+				port = "443"
+			}
+			host = net.JoinHostPort(u.Hostname(), port)
+		}
+		var d net.Dialer
+		conn, err = d.DialContext(ctx, "tcp", host)
+	}
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func checkInflightConfig(cfg InflightConfig) error {
+	if cfg.MaxRequests < 0 || cfg.MaxQueue < 0 || cfg.QueueTimeout < 0 {
+		return errors.New("values must be >= 0")
+	}
+	return nil
+}
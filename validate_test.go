@@ -0,0 +1,130 @@
+package proxyd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestConfig(t *testing.T, data string) string {
+	path := filepath.Join(t.TempDir(), "proxyd.toml")
+	require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+	return path
+}
+
+func TestValidateConfigFile(t *testing.T) {
+	path := writeTestConfig(t, `
+[server]
+rpc_port = 8545
+
+[backends.infura]
+rpc_url = "https://mainnet.infura.io"
+
+[backend_groups.main]
+backends = ["infura"]
+
+# accepted, with a warning
+[backend_groups.empty]
+backends = []
+
+[rpc_method_mappings]
+eth_chainId = "main"
+`)
+	config, unknown, errs := ValidateConfigFiles([]string{path}, false)
+	require.Empty(t, errs)
+	require.Empty(t, unknown)
+	require.Equal(t, 8545, config.Server.RPCPort)
+	require.Equal(t, []string{"backend group empty has no backends, its requests will fail"}, ConfigWarnings(config))
+}
+
+func TestValidateConfigFileErrors(t *testing.T) {
+	t.Setenv("VALIDATE_TEST_UNSET_URL", "")
+
+	path := writeTestConfig(t, `
+[server]
+rpc_prot = 8545
+
+[backends.infura]
+rpc_url = "$VALIDATE_TEST_UNSET_URL"
+
+[backends.alchemy]
+rpc_url = "ftp://alchemy.com"
+
+[backend_groups.main]
+backends = ["infura", "quicknode"]
+routing_strategy = "round_robin"
+
+[rpc_method_mappings]
+eth_chainId = "main"
+eth_call = "archive"
+`)
+	_, unknown, errs := ValidateConfigFiles([]string{path}, false)
+	require.Equal(t, []string{"server.rpc_prot"}, unknown)
+
+	var msgs []string
+	for _, err := range errs {
+		msgs = append(msgs, err.Error())
+	}
+	require.Equal(t, []string{
+		"invalid rpc_url for backend alchemy: scheme must be one of http, https",
+		"backend infura rpc_url: config env var $VALIDATE_TEST_UNSET_URL not found",
+		"backend group main references undefined backend quicknode",
+		`invalid routing_strategy "round_robin" for backend group main`,
+		"method eth_call is mapped to undefined backend group archive",
+	}, msgs)
+}
+
+func TestValidateConfigFileParseError(t *testing.T) {
+	path := writeTestConfig(t, "[server\n")
+	config, _, errs := ValidateConfigFiles([]string{path}, false)
+	require.Nil(t, config)
+	require.Len(t, errs, 1)
+}
+
+func TestStartUsesValidateChecks(t *testing.T) {
+	config, _, err := LoadConfig(writeTestConfig(t, `
+[backends.infura]
+rpc_url = "ftp://mainnet.infura.io"
+
+[backend_groups.main]
+backends = ["infura"]
+
+[rpc_method_mappings]
+eth_chainId = "main"
+`))
+	require.NoError(t, err)
+	_, _, err = Start(config)
+	require.EqualError(t, err, "invalid rpc_url for backend infura: scheme must be one of http, https")
+}
+
+func TestRedactConfig(t *testing.T) {
+	config := &Config{
+		Redis: RedisConfig{URL: "redis://:pass@localhost:6379"},
+		Backends: BackendsConfig{
+			"infura": {
+				RPCURL:   "https://mainnet.infura.io/v3/key",
+				Password: "vault:secret/data/proxyd#infura",
+				Headers:  map[string]string{"X-Key": "$INFURA_HEADER"},
+			},
+		},
+		Authentication: map[string]string{"plain-key": "alice", "file:/run/secrets/bob": "bob"},
+		HMACAuth:       HMACAuthConfig{Keys: map[string]string{"carol": "shared"}},
+		APIKeys:        APIKeysConfig{AdminToken: "token"},
+	}
+
+	redacted := RedactConfig(config)
+	require.Equal(t, redactedValue, redacted.Redis.URL)
+	require.Equal(t, redactedValue, redacted.Backends["infura"].RPCURL)
+	require.Equal(t, "vault:secret/data/proxyd#infura", redacted.Backends["infura"].Password)
+	require.Equal(t, "$INFURA_HEADER", redacted.Backends["infura"].Headers["X-Key"])
+	require.Equal(t, map[string]string{"REDACTED-1": "alice", "file:/run/secrets/bob": "bob"}, redacted.Authentication)
+	require.Equal(t, redactedValue, redacted.HMACAuth.Keys["carol"])
+	require.Equal(t, redactedValue, redacted.APIKeys.AdminToken)
+
+	// the original is unchanged
+	require.Equal(t, "https://mainnet.infura.io/v3/key", config.Backends["infura"].RPCURL)
+	require.Equal(t, "shared", config.HMACAuth.Keys["carol"])
+	require.Contains(t, config.Authentication, "plain-key")
+}
@@ -0,0 +1,124 @@
+package proxyd
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// VirtualHost routes the requests for one host name to its own backend
+// groups, so one proxyd can serve several networks.
+type VirtualHost struct {
+	Name              string
+	rpcMethodMappings map[string]string
+	wsBackendGroup    *BackendGroup
+	cert              *tls.Certificate
+}
+
+// VirtualHosts are keyed by lowercase host name.
+type VirtualHosts map[string]*VirtualHost
+
+func NewVirtualHosts(cfg map[string]VirtualHostConfig, groups map[string]*BackendGroup) (VirtualHosts, error) {
+	if len(cfg) == 0 {
+		return nil, nil
+	}
+	vhosts := make(VirtualHosts, len(cfg))
+	for _, name := range sortedKeys(cfg) {
+		vc := cfg[name]
+		vhost := &VirtualHost{
+			Name:              strings.ToLower(name),
+			rpcMethodMappings: vc.RPCMethodMappings,
+		}
+		for method, group := range vc.RPCMethodMappings {
+			if groups[group] == nil {
+				return nil, fmt.Errorf("virtual host %s maps method %s to undefined backend group %s", name, method, group)
+			}
+		}
+		if vc.WSBackendGroup != "" {
+			vhost.wsBackendGroup = groups[vc.WSBackendGroup]
+			if vhost.wsBackendGroup == nil {
+				return nil, fmt.Errorf("virtual host %s has undefined ws backend group %s", name, vc.WSBackendGroup)
+			}
+		}
+		if vc.TLSCertFile != "" || vc.TLSKeyFile != "" {
+			cert, err := tls.LoadX509KeyPair(vc.TLSCertFile, vc.TLSKeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("error loading tls certificate of virtual host %s: %w", name, err)
+			}
+			vhost.cert = &cert
+		}
+		vhosts[vhost.Name] = vhost
+	}
+	return vhosts, nil
+}
+
+// Select returns the virtual host r is for, or nil.
+func (v VirtualHosts) Select(r *http.Request) *VirtualHost {
+	if v == nil {
+		return nil
+	}
+	return v[requestHost(r)]
+}
+
+// TLSConfig returns the config to terminate TLS with, serving each virtual
+// host's certificate by SNI and defaultCert otherwise. It returns nil if there
+// are no certificates.
+func (v VirtualHosts) TLSConfig(defaultCert *tls.Certificate) *tls.Config {
+	certs := make(map[string]*tls.Certificate)
+	for name, vhost := range v {
+		if vhost.cert != nil {
+			certs[name] = vhost.cert
+		}
+	}
+	if defaultCert == nil && len(certs) == 0 {
+		return nil
+	}
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			if cert := certs[strings.ToLower(hello.ServerName)]; cert != nil {
+				return cert, nil
+			}
+			if defaultCert != nil {
+				return defaultCert, nil
+			}
+			return nil, errors.New("no certificate for server name " + hello.ServerName)
+		},
+	}
+}
+
+// newServerTLSConfig returns the config to terminate TLS with on the RPC and
+// WS listeners, or nil to serve plain HTTP.
+func newServerTLSConfig(cfg ServerConfig, vhosts VirtualHosts) (*tls.Config, error) {
+	var defaultCert *tls.Certificate
+	if cfg.TLSCertFile != "" || cfg.TLSKeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.TLSCertFile, cfg.TLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("error loading tls certificate: %w", err)
+		}
+		defaultCert = &cert
+	}
+	return vhosts.TLSConfig(defaultCert), nil
+}
+
+// requestHost returns the lowercase host r was sent to, without port.
+func requestHost(r *http.Request) string {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(host)
+}
+
+func WithVirtualHost(ctx context.Context, vhost *VirtualHost) context.Context {
+	return context.WithValue(ctx, ContextKeyVirtualHost, vhost) // nolint:staticcheck
+}
+
+func GetVirtualHost(ctx context.Context) *VirtualHost {
+	vhost, _ := ctx.Value(ContextKeyVirtualHost).(*VirtualHost)
+	return vhost
+}
@@ -0,0 +1,67 @@
+package proxyd
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCertificate(t *testing.T, host string) *tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: host},
+		DNSNames:     []string{host},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	return &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestVirtualHostsTLSConfig(t *testing.T) {
+	mainnetCert := newTestCertificate(t, "mainnet.rpc.example.com")
+	defaultCert := newTestCertificate(t, "rpc.example.com")
+	vhosts := VirtualHosts{
+		"mainnet.rpc.example.com": {Name: "mainnet.rpc.example.com", cert: mainnetCert},
+		"sepolia.rpc.example.com": {Name: "sepolia.rpc.example.com"},
+	}
+
+	cfg := vhosts.TLSConfig(defaultCert)
+	getCert := func(serverName string) *tls.Certificate {
+		cert, err := cfg.GetCertificate(&tls.ClientHelloInfo{ServerName: serverName})
+		require.NoError(t, err)
+		return cert
+	}
+	require.Equal(t, mainnetCert, getCert("MAINNET.rpc.example.com"))
+	require.Equal(t, defaultCert, getCert("sepolia.rpc.example.com"))
+	require.Equal(t, defaultCert, getCert(""))
+
+	cfg = vhosts.TLSConfig(nil)
+	_, err := cfg.GetCertificate(&tls.ClientHelloInfo{ServerName: "sepolia.rpc.example.com"})
+	require.Error(t, err)
+
+	require.Nil(t, VirtualHosts{"sepolia.rpc.example.com": {}}.TLSConfig(nil))
+}
+
+func TestVirtualHostsSelect(t *testing.T) {
+	vhost := &VirtualHost{Name: "mainnet.rpc.example.com"}
+	vhosts := VirtualHosts{vhost.Name: vhost}
+
+	r := httptest.NewRequest("POST", "http://Mainnet.RPC.example.com:8545/", nil)
+	require.Equal(t, vhost, vhosts.Select(r))
+	r = httptest.NewRequest("POST", "http://sepolia.rpc.example.com/", nil)
+	require.Nil(t, vhosts.Select(r))
+	require.Nil(t, VirtualHosts(nil).Select(r))
+}
@@ -0,0 +1,268 @@
+package proxyd
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	WebhookEventBackendBanned       = "backend_banned"
+	WebhookEventBackendUnbanned     = "backend_unbanned"
+	WebhookEventConsensusStalled    = "consensus_stalled"
+	WebhookEventConsensusRecovered  = "consensus_recovered"
+	WebhookEventFallbackActivated   = "fallback_activated"
+	WebhookEventFallbackDeactivated = "fallback_deactivated"
+	WebhookEventSenderLimitAbuse    = "sender_limit_abuse"
+
+	// WebhookSignatureHeader carries "sha256=" followed by the hex encoded
+	// HMAC-SHA256 of the body, keyed with the configured secret.
+	WebhookSignatureHeader = "X-Proxyd-Signature"
+	WebhookEventHeader     = "X-Proxyd-Event"
+
+	defaultWebhookQueueSize        = 1000
+	defaultWebhookMaxRetries       = 5
+	defaultWebhookRetryBackoff     = time.Second
+	defaultWebhookTimeout          = 5 * time.Second
+	defaultConsensusStallThreshold = 2 * time.Minute
+	// senderAbuseNotifyInterval limits sender_limit_abuse to one event per
+	// sender per interval.
+	senderAbuseNotifyInterval = time.Minute
+)
+
+// WebhookEvent is the JSON body of a webhook.
+type WebhookEvent struct {
+	Type string                 `json:"type"`
+	Time time.Time              `json:"time"`
+	Data map[string]interface{} `json:"data"`
+}
+
+// WebhookNotifier delivers operational events to the configured webhook
+// endpoints in the background. Failed deliveries are retried with exponential
+// backoff and finally appended to a dead-letter file.
+type WebhookNotifier struct {
+	urls           []string
+	secret         secretValue
+	types          map[string]bool
+	maxRetries     int
+	retryBackoff   time.Duration
+	deadLetterFile string
+	stallThreshold time.Duration
+	client         *http.Client
+	queue          chan *WebhookEvent
+
+	deadLetterMu sync.Mutex
+	recentMu     sync.Mutex
+	recent       map[string]time.Time
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewWebhookNotifier(cfg WebhooksConfig, secrets *SecretStore) (*WebhookNotifier, error) {
+	if len(cfg.URLs) == 0 {
+		return nil, fmt.Errorf("webhooks.urls must not be empty")
+	}
+	secret, err := secrets.Resolve(cfg.Secret)
+	if err != nil {
+		return nil, err
+	}
+	if secret == "" {
+		return nil, fmt.Errorf("webhooks.secret must not be empty")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	n := &WebhookNotifier{
+		urls:           cfg.URLs,
+		maxRetries:     defaultWebhookMaxRetries,
+		retryBackoff:   defaultWebhookRetryBackoff,
+		deadLetterFile: cfg.DeadLetterFile,
+		stallThreshold: defaultConsensusStallThreshold,
+		client:         &http.Client{Timeout: defaultWebhookTimeout},
+		queue:          make(chan *WebhookEvent, defaultWebhookQueueSize),
+		recent:         make(map[string]time.Time),
+		ctx:            ctx,
+		cancel:         cancel,
+	}
+	n.secret.Store(secret)
+	secrets.Watch(cfg.Secret, n.secret.Store)
+	if len(cfg.Events) > 0 {
+		n.types = make(map[string]bool, len(cfg.Events))
+		for _, t := range cfg.Events {
+			n.types[t] = true
+		}
+	}
+	if cfg.MaxRetries > 0 {
+		n.maxRetries = cfg.MaxRetries
+	}
+	if cfg.RetryBackoff > 0 {
+		n.retryBackoff = time.Duration(cfg.RetryBackoff)
+	}
+	if cfg.ConsensusStallThreshold > 0 {
+		n.stallThreshold = time.Duration(cfg.ConsensusStallThreshold)
+	}
+
+	n.wg.Add(1)
+	go n.run()
+	return n, nil
+}
+
+// Notify queues an event for delivery without blocking. It is a no-op on a
+// nil notifier, and for event types that aren't enabled.
+func (n *WebhookNotifier) Notify(eventType string, data map[string]interface{}) {
+	if n == nil || (n.types != nil && !n.types[eventType]) {
+		return
+	}
+	ev := &WebhookEvent{Type: eventType, Time: time.Now(), Data: data}
+	select {
+	case n.queue <- ev:
+	default:
+		log.Warn("webhook queue full, dead-lettering event", "type", eventType)
+		n.deadLetter(ev)
+	}
+}
+
+// NotifyOnce is like Notify, but drops events whose key was already notified
+// within interval.
+func (n *WebhookNotifier) NotifyOnce(eventType, key string, interval time.Duration, data map[string]interface{}) {
+	if n == nil {
+		return
+	}
+	now := time.Now()
+	n.recentMu.Lock()
+	if last, ok := n.recent[eventType+":"+key]; ok && now.Sub(last) < interval {
+		n.recentMu.Unlock()
+		return
+	}
+	n.recent[eventType+":"+key] = now
+	for k, last := range n.recent {
+		if now.Sub(last) >= interval {
+			delete(n.recent, k)
+		}
+	}
+	n.recentMu.Unlock()
+	n.Notify(eventType, data)
+}
+
+// Shutdown stops delivery. Events still queued are dead-lettered.
+func (n *WebhookNotifier) Shutdown() {
+	n.cancel()
+	n.wg.Wait()
+	for {
+		select {
+		case ev := <-n.queue:
+			n.deadLetter(ev)
+		default:
+			return
+		}
+	}
+}
+
+func (n *WebhookNotifier) run() {
+	defer n.wg.Done()
+	for {
+		select {
+		case <-n.ctx.Done():
+			return
+		case ev := <-n.queue:
+			n.deliver(ev)
+		}
+	}
+}
+
+func (n *WebhookNotifier) deliver(ev *WebhookEvent) {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		log.Error("error encoding webhook event", "type", ev.Type, "err", err)
+		return
+	}
+
+	for _, url := range n.urls {
+		backoff := n.retryBackoff
+		for attempt := 0; ; attempt++ {
+			err = n.post(url, ev.Type, body)
+			if err == nil {
+				RecordWebhookDelivery(ev.Type, "ok")
+				break
+			}
+			if attempt >= n.maxRetries {
+				log.Error("webhook delivery failed", "type", ev.Type, "url", url, "attempts", attempt+1, "err", err)
+				RecordWebhookDelivery(ev.Type, "failed")
+				n.deadLetter(ev)
+				break
+			}
+			log.Warn("webhook delivery failed, retrying", "type", ev.Type, "url", url, "attempt", attempt+1, "err", err)
+			select {
+			case <-n.ctx.Done():
+				n.deadLetter(ev)
+				return
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+	}
+}
+
+func (n *WebhookNotifier) post(url, eventType string, body []byte) error {
+	req, err := http.NewRequestWithContext(n.ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, eventType)
+	req.Header.Set(WebhookSignatureHeader, SignWebhook([]byte(n.secret.Load()), body))
+
+	res, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", res.StatusCode)
+	}
+	return nil
+}
+
+// deadLetter appends an undeliverable event to the dead-letter file as a JSON
+// line, or logs it if no file is configured.
+func (n *WebhookNotifier) deadLetter(ev *WebhookEvent) {
+	RecordWebhookDelivery(ev.Type, "dead_letter")
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return
+	}
+	if n.deadLetterFile == "" {
+		log.Error("dropping undeliverable webhook event", "event", string(body))
+		return
+	}
+
+	n.deadLetterMu.Lock()
+	defer n.deadLetterMu.Unlock()
+	f, err := os.OpenFile(n.deadLetterFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		log.Error("error opening webhook dead-letter file", "err", err, "event", string(body))
+		return
+	}
+	defer f.Close()
+	if _, err := f.Write(append(body, '\n')); err != nil {
+		log.Error("error writing webhook dead-letter file", "err", err, "event", string(body))
+	}
+}
+
+// SignWebhook returns the signature for a webhook body.
+func SignWebhook(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,167 @@
+package proxyd
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type webhookRecorder struct {
+	mu       sync.Mutex
+	events   []WebhookEvent
+	failures int
+}
+
+func (r *webhookRecorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.failures > 0 {
+		r.failures--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	body, _ := io.ReadAll(req.Body)
+	if req.Header.Get(WebhookSignatureHeader) != SignWebhook([]byte("secret"), body) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	var ev WebhookEvent
+	_ = json.Unmarshal(body, &ev)
+	if req.Header.Get(WebhookEventHeader) != ev.Type {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	r.events = append(r.events, ev)
+}
+
+func (r *webhookRecorder) types() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var types []string
+	for _, ev := range r.events {
+		types = append(types, ev.Type)
+	}
+	return types
+}
+
+func TestWebhookNotifierDelivery(t *testing.T) {
+	rec := &webhookRecorder{failures: 2}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	n, err := NewWebhookNotifier(WebhooksConfig{
+		URLs:         []string{srv.URL},
+		Secret:       "secret",
+		Events:       []string{WebhookEventBackendBanned, WebhookEventSenderLimitAbuse},
+		RetryBackoff: TOMLDuration(time.Millisecond),
+	}, nil)
+	require.NoError(t, err)
+	defer n.Shutdown()
+
+	n.Notify(WebhookEventBackendBanned, map[string]interface{}{"backend": "infura"})
+	n.Notify(WebhookEventBackendUnbanned, map[string]interface{}{"backend": "infura"})
+	for i := 0; i < 3; i++ {
+		n.NotifyOnce(WebhookEventSenderLimitAbuse, "0xabc", time.Minute, nil)
+	}
+
+	require.Eventually(t, func() bool {
+		return len(rec.types()) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, []string{WebhookEventBackendBanned, WebhookEventSenderLimitAbuse}, rec.types())
+	require.Equal(t, "infura", rec.events[0].Data["backend"])
+}
+
+func TestWebhookNotifierDeadLetter(t *testing.T) {
+	rec := &webhookRecorder{failures: 100}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	deadLetter := filepath.Join(t.TempDir(), "dead.jsonl")
+	n, err := NewWebhookNotifier(WebhooksConfig{
+		URLs:           []string{srv.URL},
+		Secret:         "secret",
+		MaxRetries:     1,
+		RetryBackoff:   TOMLDuration(time.Millisecond),
+		DeadLetterFile: deadLetter,
+	}, nil)
+	require.NoError(t, err)
+	defer n.Shutdown()
+
+	n.Notify(WebhookEventConsensusStalled, map[string]interface{}{"backend_group": "main"})
+	require.Eventually(t, func() bool {
+		data, _ := os.ReadFile(deadLetter)
+		return strings.Contains(string(data), WebhookEventConsensusStalled)
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Empty(t, rec.types())
+}
+
+func TestWebhookNotifierSecretRef(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "webhook-secret")
+	require.NoError(t, os.WriteFile(file, []byte("from-file\n"), 0o600))
+	secrets, err := NewSecretStore(SecretsConfig{})
+	require.NoError(t, err)
+
+	n, err := NewWebhookNotifier(WebhooksConfig{URLs: []string{"http://localhost"}, Secret: SecretPrefixFile + file}, secrets)
+	require.NoError(t, err)
+	defer n.Shutdown()
+	require.Equal(t, "from-file", n.secret.Load())
+
+	require.NoError(t, os.WriteFile(file, []byte("rotated"), 0o600))
+	secrets.Refresh()
+	require.Equal(t, "rotated", n.secret.Load())
+}
+
+func TestWebhookNotifierValidation(t *testing.T) {
+	_, err := NewWebhookNotifier(WebhooksConfig{Secret: "secret"}, nil)
+	require.Error(t, err)
+	_, err = NewWebhookNotifier(WebhooksConfig{URLs: []string{"http://localhost"}}, nil)
+	require.Error(t, err)
+
+	var n *WebhookNotifier
+	require.NotPanics(t, func() { n.Notify(WebhookEventBackendBanned, nil) })
+}
+
+func TestConsensusPollerWebhooks(t *testing.T) {
+	rec := &webhookRecorder{}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	n, err := NewWebhookNotifier(WebhooksConfig{URLs: []string{srv.URL}, Secret: "secret"}, nil)
+	require.NoError(t, err)
+	defer n.Shutdown()
+
+	primary := NewBackend("primary", "http://localhost:1", "", nil)
+	fallback := NewBackend("fallback", "http://localhost:2", "", nil)
+	bg := &BackendGroup{
+		Name:             "main",
+		Backends:         []*Backend{primary, fallback},
+		FallbackBackends: map[string]bool{"primary": false, "fallback": true},
+	}
+	cp := NewConsensusPoller(bg, WithAsyncHandler(NewNoopAsyncHandler()), WithWebhookNotifier(n))
+	bg.Consensus = cp
+
+	cp.Ban(primary)
+	cp.Unban(primary)
+	cp.Unban(primary)
+	// no healthy primaries, so the fallback takes over
+	cp.getConsensusCandidates()
+	cp.getConsensusCandidates()
+
+	require.Eventually(t, func() bool {
+		return len(rec.types()) == 3
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, []string{
+		WebhookEventBackendBanned,
+		WebhookEventBackendUnbanned,
+		WebhookEventFallbackActivated,
+	}, rec.types())
+}
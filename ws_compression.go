@@ -0,0 +1,41 @@
+package proxyd
+
+import (
+	"github.com/gorilla/websocket"
+)
+
+const (
+	defaultWSCompressionLevel     = 1
+	defaultWSCompressionThreshold = 512
+)
+
+func (c WSCompressionConfig) level() int {
+	if c.Level == 0 {
+		return defaultWSCompressionLevel
+	}
+	return c.Level
+}
+
+func (c WSCompressionConfig) threshold() int {
+	if c.Threshold == 0 {
+		return defaultWSCompressionThreshold
+	}
+	return c.Threshold
+}
+
+// setWSCompressionLevel applies the configured level to conn. It only takes
+// effect if conn negotiated permessage-deflate.
+func setWSCompressionLevel(conn *websocket.Conn, cfg WSCompressionConfig) {
+	if !cfg.Client && !cfg.Upstream {
+		return
+	}
+	// the level is validated with the config
+	_ = conn.SetCompressionLevel(cfg.level())
+}
+
+// writeWSMessage writes msg to conn, compressed if it's at least threshold
+// bytes and conn negotiated compression.
+func writeWSMessage(conn *websocket.Conn, msgType int, msg []byte, threshold int) error {
+	conn.EnableWriteCompression(len(msg) >= threshold)
+	return conn.WriteMessage(msgType, msg)
+}
@@ -0,0 +1,137 @@
+package proxyd
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// wsLog is a notification of a logs subscription.
+type wsLog struct {
+	data        json.RawMessage
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	BlockHash   common.Hash    `json:"blockHash"`
+	LogIndex    hexutil.Uint64 `json:"logIndex"`
+	Removed     bool           `json:"removed"`
+}
+
+type wsLogID struct {
+	blockHash common.Hash
+	logIndex  uint64
+}
+
+func parseWSLog(data json.RawMessage) (wsLog, bool) {
+	l := wsLog{data: data}
+	if err := json.Unmarshal(data, &l); err != nil {
+		return l, false
+	}
+	return l, true
+}
+
+func (l *wsLog) id() wsLogID {
+	return wsLogID{blockHash: l.BlockHash, logIndex: uint64(l.LogIndex)}
+}
+
+// removedData returns the log with removed set.
+func (l *wsLog) removedData() json.RawMessage {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(l.data, &fields); err != nil {
+		return l.data
+	}
+	fields["removed"] = json.RawMessage("true")
+	return mustMarshalJSON(fields)
+}
+
+// reconcileWSLogs returns the notifications to send a logs subscription that
+// moves to backend, which may not have the blocks the logs it was delivered
+// came from, e.g. because they came from another backend or were reorged
+// since. The delivered logs whose block isn't canonical on backend come
+// first, with removed set, unless they were removed already. Then come the
+// pending logs, except those of blocks that aren't canonical on backend.
+// Blocks backend doesn't have yet are taken as canonical.
+func reconcileWSLogs(ctx context.Context, backend *Backend, delivered, pending []json.RawMessage) []json.RawMessage {
+	var numbers []uint64
+	seen := make(map[uint64]bool)
+	parse := func(events []json.RawMessage) []wsLog {
+		logs := make([]wsLog, len(events))
+		for i, data := range events {
+			l, ok := parseWSLog(data)
+			if ok && l.BlockHash != (common.Hash{}) && !seen[uint64(l.BlockNumber)] {
+				seen[uint64(l.BlockNumber)] = true
+				numbers = append(numbers, uint64(l.BlockNumber))
+			}
+			logs[i] = l
+		}
+		return logs
+	}
+	deliveredLogs, pendingLogs := parse(delivered), parse(pending)
+
+	canonical, err := canonicalBlockHashes(ctx, backend, numbers)
+	if err != nil {
+		log.Warn("error checking ws logs for reorgs", "backend", backend.Name, "req_id", GetReqID(ctx), "err", err)
+	}
+	reorged := func(l wsLog) bool {
+		hash, ok := canonical[uint64(l.BlockNumber)]
+		return ok && l.BlockHash != (common.Hash{}) && hash != l.BlockHash
+	}
+
+	removed := make(map[wsLogID]bool)
+	for _, l := range deliveredLogs {
+		if l.Removed {
+			removed[l.id()] = true
+		}
+	}
+	var out []json.RawMessage
+	for _, l := range deliveredLogs {
+		if !l.Removed && !removed[l.id()] && reorged(l) {
+			removed[l.id()] = true
+			out = append(out, l.removedData())
+		}
+	}
+	for _, l := range pendingLogs {
+		if l.Removed || !reorged(l) {
+			out = append(out, l.data)
+		}
+	}
+	return out
+}
+
+// canonicalBlockHashes returns the hashes backend has for the block numbers.
+// Blocks it doesn't have are left out.
+func canonicalBlockHashes(ctx context.Context, backend *Backend, numbers []uint64) (map[uint64]common.Hash, error) {
+	if len(numbers) == 0 {
+		return nil, nil
+	}
+	reqs := make([]*RPCReq, len(numbers))
+	for i, number := range numbers {
+		reqs[i] = &RPCReq{
+			JSONRPC: JSONRPCVersion,
+			Method:  "eth_getBlockByNumber",
+			Params:  mustMarshalJSON([]interface{}{hexutil.Uint64(number), false}),
+			ID:      json.RawMessage(strconv.Itoa(i)),
+		}
+	}
+	responses, err := backend.Forward(ctx, reqs, true)
+	if err != nil {
+		return nil, err
+	}
+	hashes := make(map[uint64]common.Hash, len(numbers))
+	for _, res := range responses {
+		i, err := strconv.Atoi(string(res.ID))
+		if err != nil || i < 0 || i >= len(numbers) || res.IsError() || res.Result == nil {
+			continue
+		}
+		var block struct {
+			Hash *common.Hash `json:"hash"`
+		}
+		if err := json.Unmarshal(mustMarshalJSON(res.Result), &block); err != nil || block.Hash == nil {
+			continue
+		}
+		hashes[numbers[i]] = *block.Hash
+	}
+	return hashes, nil
+}
@@ -0,0 +1,68 @@
+package proxyd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/semaphore"
+)
+
+// newWSLogsTestBackend returns a backend answering eth_getBlockByNumber with
+// the hashes, by hex block number, and null for other blocks.
+func newWSLogsTestBackend(t *testing.T, hashes map[string]string) *Backend {
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reqs []RPCReq
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&reqs))
+		var out []string
+		for _, req := range reqs {
+			var params []interface{}
+			require.NoError(t, json.Unmarshal(req.Params, &params))
+			result := "null"
+			if hash, ok := hashes[params[0].(string)]; ok {
+				result = fmt.Sprintf(`{"hash":"%s"}`, hash)
+			}
+			out = append(out, fmt.Sprintf(`{"jsonrpc":"2.0","result":%s,"id":%s}`, result, req.ID))
+		}
+		_, _ = fmt.Fprintf(w, "[%s]", strings.Join(out, ","))
+	}))
+	t.Cleanup(node.Close)
+	return NewBackend("node", node.URL, "", semaphore.NewWeighted(1))
+}
+
+func testWSLog(block, hash, index string) json.RawMessage {
+	return json.RawMessage(fmt.Sprintf(`{"blockHash":"0x00000000000000000000000000000000000000000000000000000000000000%s","blockNumber":"%s","logIndex":"%s"}`, hash, block, index))
+}
+
+func TestReconcileWSLogs(t *testing.T) {
+	// block 0x11 was reorged and the backend doesn't have block 0x12 yet
+	backend := newWSLogsTestBackend(t, map[string]string{
+		"0x10": "0x00000000000000000000000000000000000000000000000000000000000000a0",
+		"0x11": "0x00000000000000000000000000000000000000000000000000000000000000b1",
+	})
+	ctx := context.Background()
+
+	// delivered logs of the reorged block are removed, pending ones aren't
+	// sent, and blocks the backend doesn't have yet are
+	delivered := []json.RawMessage{testWSLog("0x10", "a0", "0x0"), testWSLog("0x11", "b0", "0x0")}
+	pending := []json.RawMessage{testWSLog("0x11", "b0", "0x1"), testWSLog("0x12", "c0", "0x0")}
+	out := reconcileWSLogs(ctx, backend, delivered, pending)
+	require.Len(t, out, 2)
+	require.JSONEq(t, `{"blockHash":"0x00000000000000000000000000000000000000000000000000000000000000b0","blockNumber":"0x11","logIndex":"0x0","removed":true}`, string(out[0]))
+	require.Equal(t, testWSLog("0x12", "c0", "0x0"), out[1])
+
+	// nor are they removed twice
+	delivered = append(delivered, out[0])
+	out = reconcileWSLogs(ctx, backend, delivered, pending)
+	require.Equal(t, []json.RawMessage{testWSLog("0x12", "c0", "0x0")}, out)
+
+	// logs that can't be checked are sent as they are
+	unknown := newWSLogsTestBackend(t, nil)
+	out = reconcileWSLogs(ctx, unknown, delivered, pending)
+	require.Equal(t, pending, out)
+}
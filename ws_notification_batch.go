@@ -0,0 +1,108 @@
+package proxyd
+
+import (
+	"bytes"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	defaultWSBatchFlushInterval = 20 * time.Millisecond
+	defaultWSBatchMaxSize       = 100
+
+	// WSBatchNotificationsParam is the query parameter websocket clients opt
+	// in to batched notifications with.
+	WSBatchNotificationsParam = "batch_notifications"
+)
+
+// wsNotificationBatcher coalesces the subscription notifications for a
+// websocket client into JSON-RPC batches, sent when the flush interval has
+// passed since the first one, when the batch is full, or before a response
+// so that the order of messages is kept. This saves indexers subscribing to
+// every log the framing of each message.
+type wsNotificationBatcher struct {
+	interval time.Duration
+	maxSize  int
+	// deliver sends a message to the client
+	deliver func(msgType int, msg []byte) error
+	// fail ends the connection on errors of flushes in the background
+	fail func(err error)
+
+	mu      sync.Mutex
+	pending [][]byte
+	timer   *time.Timer
+}
+
+func newWSNotificationBatcher(cfg WSNotificationBatchingConfig, deliver func(int, []byte) error, fail func(error)) *wsNotificationBatcher {
+	b := &wsNotificationBatcher{
+		interval: time.Duration(cfg.FlushInterval),
+		maxSize:  cfg.MaxSize,
+		deliver:  deliver,
+		fail:     fail,
+	}
+	if b.interval == 0 {
+		b.interval = defaultWSBatchFlushInterval
+	}
+	if b.maxSize == 0 {
+		b.maxSize = defaultWSBatchMaxSize
+	}
+	return b
+}
+
+// send batches a notification, or flushes the batch and sends any other
+// message.
+func (b *wsNotificationBatcher) send(msgType int, msg []byte, notification bool) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !notification || msgType != websocket.TextMessage {
+		if err := b.flush(); err != nil {
+			return err
+		}
+		return b.deliver(msgType, msg)
+	}
+	b.pending = append(b.pending, msg)
+	if len(b.pending) >= b.maxSize {
+		return b.flush()
+	}
+	if b.timer == nil {
+		b.timer = time.AfterFunc(b.interval, func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			if err := b.flush(); err != nil {
+				b.fail(err)
+			}
+		})
+	}
+	return nil
+}
+
+// flush sends the pending notifications as one batch. b.mu must be held.
+func (b *wsNotificationBatcher) flush() error {
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	if len(b.pending) == 0 {
+		return nil
+	}
+	batch := make([]byte, 0, 2+len(b.pending)*len(b.pending[0]))
+	batch = append(batch, '[')
+	batch = append(batch, bytes.Join(b.pending, []byte{','})...)
+	batch = append(batch, ']')
+	RecordWSNotificationBatch(len(b.pending))
+	b.pending = nil
+	return b.deliver(websocket.TextMessage, batch)
+}
+
+// close stops flushing in the background.
+func (b *wsNotificationBatcher) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	b.pending = nil
+}
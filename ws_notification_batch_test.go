@@ -0,0 +1,52 @@
+package proxyd
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWSNotificationBatcher(t *testing.T) {
+	var mu sync.Mutex
+	var sent []string
+	sentMsgs := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), sent...)
+	}
+	b := newWSNotificationBatcher(WSNotificationBatchingConfig{
+		FlushInterval: TOMLDuration(50 * time.Millisecond),
+		MaxSize:       3,
+	}, func(msgType int, msg []byte) error {
+		mu.Lock()
+		defer mu.Unlock()
+		sent = append(sent, string(msg))
+		return nil
+	}, func(err error) {
+		t.Errorf("unexpected flush error: %v", err)
+	})
+	defer b.close()
+
+	// notifications wait for the flush interval
+	require.NoError(t, b.send(websocket.TextMessage, []byte(`{"n":1}`), true))
+	require.NoError(t, b.send(websocket.TextMessage, []byte(`{"n":2}`), true))
+	require.Empty(t, sentMsgs())
+	require.Eventually(t, func() bool { return len(sentMsgs()) == 1 }, time.Second, 5*time.Millisecond)
+	require.Equal(t, `[{"n":1},{"n":2}]`, sentMsgs()[0])
+
+	// or until the batch is full
+	for _, n := range []string{`{"n":3}`, `{"n":4}`, `{"n":5}`} {
+		require.NoError(t, b.send(websocket.TextMessage, []byte(n), true))
+	}
+	require.Equal(t, `[{"n":3},{"n":4},{"n":5}]`, sentMsgs()[1])
+
+	// and responses are sent after the pending notifications
+	require.NoError(t, b.send(websocket.TextMessage, []byte(`{"n":6}`), true))
+	require.NoError(t, b.send(websocket.TextMessage, []byte(`{"id":1}`), false))
+	require.Equal(t, []string{`[{"n":6}]`, `{"id":1}`}, sentMsgs()[2:])
+	time.Sleep(70 * time.Millisecond)
+	require.Len(t, sentMsgs(), 4)
+}
@@ -0,0 +1,376 @@
+package proxyd
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/gorilla/websocket"
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	defaultWSReplaySize = 1024
+	defaultWSReplayTTL  = time.Hour
+	wsReplayRedisKey    = "ws_replay"
+	wsReplayMemoryLimit = 10000
+
+	// logs get the low bits of the position of their block
+	wsReplayLogIndexBits = 20
+)
+
+// WSReplayCursor is the last notification a client received, given when it
+// subscribes again as {"replayFrom": cursor} after the other params.
+type WSReplayCursor struct {
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	LogIndex    hexutil.Uint64 `json:"logIndex"`
+}
+
+func (c *WSReplayCursor) position() uint64 {
+	return uint64(c.BlockNumber)<<wsReplayLogIndexBits | uint64(c.LogIndex)
+}
+
+// WSReplay keeps the last newHeads and logs notifications sent for each
+// subscription, in Redis if configured, so that clients reconnecting with a
+// cursor get the ones they missed before the live ones. Subscriptions with the
+// same params share their notifications, and a notification may be delivered
+// both from the buffer and live, giving at-least-once delivery. When the last
+// subscriber of some params on this instance leaves, a subscription of its
+// own to the same backend keeps recording them for the TTL, or until a
+// subscriber is back.
+type WSReplay struct {
+	redisClient redis.UniversalClient
+	prefix      string
+	size        int
+	ttl         time.Duration
+
+	mu    sync.Mutex
+	local *lru.Cache
+
+	subsMu      sync.Mutex
+	subscribers map[string]int
+	keepers     map[string]*wsReplayKeeper
+	stopped     bool
+}
+
+// wsReplayKeeper records the notifications of a subscription nobody on this
+// instance is subscribed to anymore.
+type wsReplayKeeper struct {
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+func (k *wsReplayKeeper) close() {
+	k.stopOnce.Do(func() { close(k.stop) })
+}
+
+type wsReplayEvent struct {
+	position uint64
+	data     json.RawMessage
+}
+
+func NewWSReplay(cfg WSReplayConfig, redisClient redis.UniversalClient, namespace string) *WSReplay {
+	r := &WSReplay{
+		redisClient: redisClient,
+		prefix:      wsReplayRedisKey,
+		size:        cfg.Size,
+		ttl:         time.Duration(cfg.TTL),
+		subscribers: make(map[string]int),
+		keepers:     make(map[string]*wsReplayKeeper),
+	}
+	if namespace != "" {
+		r.prefix = namespace + ":" + wsReplayRedisKey
+	}
+	if r.size == 0 {
+		r.size = defaultWSReplaySize
+	}
+	if r.ttl == 0 {
+		r.ttl = defaultWSReplayTTL
+	}
+	if redisClient == nil {
+		r.local, _ = lru.New(wsReplayMemoryLimit)
+	}
+	return r
+}
+
+// Subscribe returns the key of a newHeads or logs eth_subscribe request, and
+// the cursor it was given, if any, which is removed from its params.
+func (r *WSReplay) Subscribe(req *RPCReq) (string, *WSReplayCursor, bool) {
+	if r == nil || req.Method != "eth_subscribe" {
+		return "", nil, false
+	}
+	var params []json.RawMessage
+	if err := json.Unmarshal(req.Params, &params); err != nil || len(params) == 0 {
+		return "", nil, false
+	}
+	var kind string
+	if err := json.Unmarshal(params[0], &kind); err != nil || (kind != "newHeads" && kind != "logs") {
+		return "", nil, false
+	}
+
+	var cursor *WSReplayCursor
+	if last := params[len(params)-1]; len(params) > 1 && bytes.Contains(last, []byte(`"replayFrom"`)) {
+		var opt struct {
+			ReplayFrom *WSReplayCursor `json:"replayFrom"`
+		}
+		if err := json.Unmarshal(last, &opt); err == nil && opt.ReplayFrom != nil {
+			cursor = opt.ReplayFrom
+			params = params[:len(params)-1]
+			req.Params = mustMarshalJSON(params)
+		}
+	}
+
+	// the filter is re-encoded so that equal filters share a key
+	var filter interface{}
+	if len(params) > 1 {
+		if err := json.Unmarshal(params[1], &filter); err != nil {
+			return "", nil, false
+		}
+	}
+	sum := sha256.Sum256(mustMarshalJSON(filter))
+	return kind + ":" + hex.EncodeToString(sum[:]), cursor, true
+}
+
+// Join counts a subscriber of the subscription, accepted by the backend,
+// which records its notifications from then on.
+func (r *WSReplay) Join(key string) {
+	r.subsMu.Lock()
+	defer r.subsMu.Unlock()
+	r.subscribers[key]++
+	if k := r.keepers[key]; k != nil {
+		k.close()
+		delete(r.keepers, key)
+	}
+}
+
+// Leave uncounts a subscriber of the subscription. Once none is left, the
+// notifications keep being recorded for the TTL by subscribing to backend
+// with params.
+func (r *WSReplay) Leave(key string, backend *Backend, params json.RawMessage) {
+	r.subsMu.Lock()
+	defer r.subsMu.Unlock()
+	r.subscribers[key]--
+	if r.subscribers[key] > 0 {
+		return
+	}
+	delete(r.subscribers, key)
+	if r.stopped || r.keepers[key] != nil {
+		return
+	}
+	k := &wsReplayKeeper{stop: make(chan struct{})}
+	r.keepers[key] = k
+	go r.keep(key, backend, params, k)
+}
+
+// Stop ends the subscriptions recording for subscribers that left.
+func (r *WSReplay) Stop() {
+	if r == nil {
+		return
+	}
+	r.subsMu.Lock()
+	defer r.subsMu.Unlock()
+	r.stopped = true
+	for key, k := range r.keepers {
+		k.close()
+		delete(r.keepers, key)
+	}
+}
+
+func (r *WSReplay) keep(key string, backend *Backend, params json.RawMessage, k *wsReplayKeeper) {
+	defer func() {
+		r.subsMu.Lock()
+		if r.keepers[key] == k {
+			delete(r.keepers, key)
+		}
+		r.subsMu.Unlock()
+	}()
+
+	conn, err := backend.dialWS()
+	if err != nil {
+		log.Warn("error dialing backend to keep recording ws notifications", "backend", backend.Name, "err", err)
+		return
+	}
+	activeBackendWsConnsGauge.WithLabelValues(backend.Name).Inc()
+	defer activeBackendWsConnsGauge.WithLabelValues(backend.Name).Dec()
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		timer := time.NewTimer(r.ttl)
+		defer timer.Stop()
+		select {
+		case <-k.stop:
+		case <-done:
+		case <-timer.C:
+		}
+		conn.Close()
+	}()
+
+	req := &RPCReq{JSONRPC: JSONRPCVersion, Method: "eth_subscribe", Params: params, ID: json.RawMessage("1")}
+	if err := conn.WriteMessage(websocket.TextMessage, mustMarshalJSON(req)); err != nil {
+		log.Warn("error subscribing to keep recording ws notifications", "backend", backend.Name, "err", err)
+		return
+	}
+	ctx := context.Background()
+	for {
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		if _, result, ok := parseWSSubscriptionNotification(msg); ok {
+			r.Record(ctx, key, result)
+		}
+	}
+}
+
+// parseWSSubscriptionNotification returns the subscription ID and result of
+// an eth_subscription notification.
+func parseWSSubscriptionNotification(msg []byte) (string, json.RawMessage, bool) {
+	var n struct {
+		Method string `json:"method"`
+		Params struct {
+			Subscription string          `json:"subscription"`
+			Result       json.RawMessage `json:"result"`
+		} `json:"params"`
+	}
+	if err := json.Unmarshal(msg, &n); err != nil || n.Method != "eth_subscription" {
+		return "", nil, false
+	}
+	return n.Params.Subscription, n.Params.Result, true
+}
+
+// Record keeps a notification of the subscription.
+func (r *WSReplay) Record(ctx context.Context, key string, data json.RawMessage) {
+	pos, ok := wsReplayPosition(data)
+	if !ok {
+		return
+	}
+	if r.redisClient == nil {
+		r.recordLocal(key, wsReplayEvent{position: pos, data: data})
+		return
+	}
+	_, err := r.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		k := r.prefix + ":" + key
+		pipe.ZAdd(ctx, k, redis.Z{Score: float64(pos), Member: string(data)})
+		pipe.ZRemRangeByRank(ctx, k, 0, int64(-r.size-1))
+		pipe.Expire(ctx, k, r.ttl)
+		return nil
+	})
+	if err != nil {
+		RecordRedisError("WSReplay")
+		log.Error("error recording ws notification", "err", err, "req_id", GetReqID(ctx))
+	}
+}
+
+func (r *WSReplay) recordLocal(key string, ev wsReplayEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var events []wsReplayEvent
+	if val, ok := r.local.Get(key); ok {
+		events = val.([]wsReplayEvent)
+	}
+	i := sort.Search(len(events), func(i int) bool { return events[i].position > ev.position })
+	for j := i - 1; j >= 0 && events[j].position == ev.position; j-- {
+		if bytes.Equal(events[j].data, ev.data) {
+			return
+		}
+	}
+	events = append(events[:i], append([]wsReplayEvent{ev}, events[i:]...)...)
+	if len(events) > r.size {
+		events = events[len(events)-r.size:]
+	}
+	r.local.Add(key, events)
+}
+
+// Since returns the kept notifications of the subscription after the
+// cursor, oldest first.
+func (r *WSReplay) Since(ctx context.Context, key string, cursor *WSReplayCursor) ([]json.RawMessage, error) {
+	events, err := r.kept(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	pos := cursor.position()
+	var out []json.RawMessage
+	for _, ev := range events {
+		if ev.position > pos {
+			out = append(out, ev.data)
+		}
+	}
+	return out, nil
+}
+
+// Replay returns the notifications to send a client subscribing again with
+// the cursor, served by backend. Those of logs subscriptions may have been
+// recorded from another backend or before a reorg, so they're reconciled with
+// the blocks backend has now, see reconcileWSLogs.
+func (r *WSReplay) Replay(ctx context.Context, key string, cursor *WSReplayCursor, backend *Backend) ([]json.RawMessage, error) {
+	if !strings.HasPrefix(key, "logs:") {
+		return r.Since(ctx, key, cursor)
+	}
+	events, err := r.kept(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	pos := cursor.position()
+	var delivered, pending []json.RawMessage
+	for _, ev := range events {
+		if ev.position <= pos {
+			delivered = append(delivered, ev.data)
+		} else {
+			pending = append(pending, ev.data)
+		}
+	}
+	return reconcileWSLogs(ctx, backend, delivered, pending), nil
+}
+
+// kept returns the kept notifications of the subscription, oldest first.
+func (r *WSReplay) kept(ctx context.Context, key string) ([]wsReplayEvent, error) {
+	if r.redisClient == nil {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		val, ok := r.local.Get(key)
+		if !ok {
+			return nil, nil
+		}
+		return append([]wsReplayEvent(nil), val.([]wsReplayEvent)...), nil
+	}
+
+	members, err := r.redisClient.ZRangeWithScores(ctx, r.prefix+":"+key, 0, -1).Result()
+	if err != nil {
+		RecordRedisError("WSReplay")
+		return nil, err
+	}
+	out := make([]wsReplayEvent, len(members))
+	for i, m := range members {
+		out[i] = wsReplayEvent{position: uint64(m.Score), data: json.RawMessage(m.Member.(string))}
+	}
+	return out, nil
+}
+
+// wsReplayPosition orders a newHeads header by its number, and a log by its
+// block number and index.
+func wsReplayPosition(data json.RawMessage) (uint64, bool) {
+	var ev struct {
+		Number      *hexutil.Uint64 `json:"number"`
+		BlockNumber *hexutil.Uint64 `json:"blockNumber"`
+		LogIndex    *hexutil.Uint64 `json:"logIndex"`
+	}
+	if err := json.Unmarshal(data, &ev); err != nil {
+		return 0, false
+	}
+	switch {
+	case ev.BlockNumber != nil && ev.LogIndex != nil:
+		return (&WSReplayCursor{BlockNumber: *ev.BlockNumber, LogIndex: *ev.LogIndex}).position(), true
+	case ev.Number != nil:
+		return (&WSReplayCursor{BlockNumber: *ev.Number}).position(), true
+	}
+	return 0, false
+}
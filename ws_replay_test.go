@@ -0,0 +1,147 @@
+package proxyd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/alicebob/miniredis"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWSReplaySubscribe(t *testing.T) {
+	r := NewWSReplay(WSReplayConfig{}, nil, "")
+	subscribe := func(params string) (string, *WSReplayCursor, bool, string) {
+		req := &RPCReq{JSONRPC: "2.0", Method: "eth_subscribe", Params: json.RawMessage(params), ID: json.RawMessage("1")}
+		key, cursor, ok := r.Subscribe(req)
+		return key, cursor, ok, string(req.Params)
+	}
+
+	key, cursor, ok, params := subscribe(`["newHeads"]`)
+	require.True(t, ok)
+	require.Nil(t, cursor)
+	require.Equal(t, `["newHeads"]`, params)
+
+	// the cursor is removed before forwarding
+	key2, cursor, ok, params := subscribe(`["newHeads",{"replayFrom":{"blockNumber":"0x10"}}]`)
+	require.True(t, ok)
+	require.Equal(t, key, key2)
+	require.Equal(t, &WSReplayCursor{BlockNumber: 0x10}, cursor)
+	require.Equal(t, `["newHeads"]`, params)
+
+	// equal filters share a key
+	logs, _, ok, _ := subscribe(`["logs",{"address":"0x01","topics":[]}]`)
+	require.True(t, ok)
+	logs2, cursor, _, params := subscribe(`["logs",{"topics":[],"address":"0x01"},{"replayFrom":{"blockNumber":"0x10","logIndex":"0x2"}}]`)
+	require.Equal(t, logs, logs2)
+	require.Equal(t, &WSReplayCursor{BlockNumber: 0x10, LogIndex: 0x2}, cursor)
+	require.JSONEq(t, `["logs",{"topics":[],"address":"0x01"}]`, params)
+	other, _, _, _ := subscribe(`["logs",{"address":"0x02"}]`)
+	require.NotEqual(t, logs, other)
+
+	_, _, ok, _ = subscribe(`["newPendingTransactions"]`)
+	require.False(t, ok)
+
+	var disabled *WSReplay
+	_, _, ok = disabled.Subscribe(&RPCReq{Method: "eth_subscribe", Params: json.RawMessage(`["newHeads"]`)})
+	require.False(t, ok)
+}
+
+func TestWSReplay(t *testing.T) {
+	redisServer, err := miniredis.Run()
+	require.NoError(t, err)
+	defer redisServer.Close()
+	redisClient := redis.NewClient(&redis.Options{
+		Addr: fmt.Sprintf("127.0.0.1:%s", redisServer.Port()),
+	})
+
+	for name, r := range map[string]*WSReplay{
+		"memory": NewWSReplay(WSReplayConfig{Size: 3}, nil, ""),
+		"redis":  NewWSReplay(WSReplayConfig{Size: 3}, redisClient, "proxyd"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			log := func(block, index string) json.RawMessage {
+				return json.RawMessage(fmt.Sprintf(`{"blockNumber":"%s","logIndex":"%s"}`, block, index))
+			}
+			r.Record(ctx, "logs", log("0x11", "0x0"))
+			r.Record(ctx, "logs", log("0x10", "0x1"))
+			r.Record(ctx, "logs", log("0x10", "0x2"))
+			// duplicates from other subscriptions are kept once
+			r.Record(ctx, "logs", log("0x10", "0x2"))
+			r.Record(ctx, "heads", json.RawMessage(`{"number":"0x10"}`))
+
+			events, err := r.Since(ctx, "logs", &WSReplayCursor{BlockNumber: 0x10, LogIndex: 0x1})
+			require.NoError(t, err)
+			require.Equal(t, []json.RawMessage{log("0x10", "0x2"), log("0x11", "0x0")}, events)
+
+			// only the last notifications are kept
+			r.Record(ctx, "logs", log("0x12", "0x0"))
+			events, err = r.Since(ctx, "logs", &WSReplayCursor{})
+			require.NoError(t, err)
+			require.Equal(t, []json.RawMessage{log("0x10", "0x2"), log("0x11", "0x0"), log("0x12", "0x0")}, events)
+
+			events, err = r.Since(ctx, "heads", &WSReplayCursor{BlockNumber: 0xf})
+			require.NoError(t, err)
+			require.Equal(t, []json.RawMessage{json.RawMessage(`{"number":"0x10"}`)}, events)
+			events, err = r.Since(ctx, "unknown", &WSReplayCursor{})
+			require.NoError(t, err)
+			require.Empty(t, events)
+		})
+	}
+}
+
+func TestWSReplayReorgedLogs(t *testing.T) {
+	// block 0x11 was reorged and the backend doesn't have block 0x12 yet
+	backend := newWSLogsTestBackend(t, map[string]string{
+		"0x10": "0x00000000000000000000000000000000000000000000000000000000000000a0",
+		"0x11": "0x00000000000000000000000000000000000000000000000000000000000000b1",
+	})
+
+	redisServer, err := miniredis.Run()
+	require.NoError(t, err)
+	defer redisServer.Close()
+	redisClient := redis.NewClient(&redis.Options{
+		Addr: fmt.Sprintf("127.0.0.1:%s", redisServer.Port()),
+	})
+
+	for name, r := range map[string]*WSReplay{
+		"memory": NewWSReplay(WSReplayConfig{}, nil, ""),
+		"redis":  NewWSReplay(WSReplayConfig{}, redisClient, "proxyd"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			log := testWSLog
+			for _, l := range []json.RawMessage{
+				log("0x10", "a0", "0x0"),
+				log("0x11", "b0", "0x0"),
+				log("0x11", "b0", "0x1"),
+				log("0x12", "c0", "0x0"),
+			} {
+				r.Record(ctx, "logs:x", l)
+			}
+
+			// delivered logs of the reorged block are removed, later ones
+			// aren't replayed, and blocks the backend doesn't have yet are
+			events, err := r.Replay(ctx, "logs:x", &WSReplayCursor{BlockNumber: 0x11, LogIndex: 0x0}, backend)
+			require.NoError(t, err)
+			require.Len(t, events, 2)
+			require.JSONEq(t, `{"blockHash":"0x00000000000000000000000000000000000000000000000000000000000000b0","blockNumber":"0x11","logIndex":"0x0","removed":true}`, string(events[0]))
+			require.Equal(t, log("0x12", "c0", "0x0"), events[1])
+
+			// nor are they removed twice
+			r.Record(ctx, "logs:x", json.RawMessage(events[0]))
+			events, err = r.Replay(ctx, "logs:x", &WSReplayCursor{BlockNumber: 0x11, LogIndex: 0x0}, backend)
+			require.NoError(t, err)
+			require.Equal(t, []json.RawMessage{log("0x12", "c0", "0x0")}, events)
+
+			// heads are replayed as kept
+			r.Record(ctx, "newHeads:x", json.RawMessage(`{"number":"0x11"}`))
+			events, err = r.Replay(ctx, "newHeads:x", &WSReplayCursor{BlockNumber: 0x10}, backend)
+			require.NoError(t, err)
+			require.Equal(t, []json.RawMessage{json.RawMessage(`{"number":"0x11"}`)}, events)
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package proxyd
+
+import (
+	"errors"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	WSSendPolicyDisconnect = "disconnect"
+	WSSendPolicySkip       = "skip"
+
+	// wsDroppedMethod notifies clients of the subscription notifications
+	// dropped while they were too slow to receive them.
+	wsDroppedMethod = "proxyd_notificationsDropped"
+)
+
+var ErrWSSlowConsumer = errors.New("websocket client too slow")
+
+type wsMessage struct {
+	msgType int
+	data    []byte
+}
+
+// wsSendQueue buffers the backend messages for a websocket client, so that a
+// client slow to read doesn't hold up reading from its backend connection.
+// When the queue is full, the client is either disconnected, or has
+// subscription notifications dropped and is told how many were dropped once
+// there is room again. Responses are never dropped, they wait for room.
+type wsSendQueue struct {
+	policy string
+	msgs   chan wsMessage
+	// dropped is only accessed by the backend pump
+	dropped int
+}
+
+// newWSSendQueue returns nil if cfg has no size, in which case messages are
+// written to the client as they are read.
+func newWSSendQueue(cfg WSSendBufferConfig) *wsSendQueue {
+	if cfg.Size <= 0 {
+		return nil
+	}
+	q := &wsSendQueue{
+		policy: cfg.Policy,
+		msgs:   make(chan wsMessage, cfg.Size),
+	}
+	if q.policy == "" {
+		q.policy = WSSendPolicyDisconnect
+	}
+	return q
+}
+
+// push queues a message for the client, and returns ErrWSSlowConsumer if the
+// client must be disconnected.
+func (q *wsSendQueue) push(done <-chan struct{}, m wsMessage, notification bool, backendName string) error {
+	if q.dropped > 0 {
+		gap := wsMessage{msgType: websocket.TextMessage, data: mustMarshalJSON(map[string]interface{}{
+			"jsonrpc": JSONRPCVersion,
+			"method":  wsDroppedMethod,
+			"params":  map[string]int{"count": q.dropped},
+		})}
+		select {
+		case q.msgs <- gap:
+			q.dropped = 0
+		default:
+		}
+	}
+
+	select {
+	case q.msgs <- m:
+		return nil
+	default:
+	}
+
+	switch {
+	case q.policy == WSSendPolicyDisconnect:
+		RecordWSSlowConsumer(backendName, WSSendPolicyDisconnect)
+		return ErrWSSlowConsumer
+	case notification:
+		RecordWSSlowConsumer(backendName, WSSendPolicySkip)
+		q.dropped++
+		return nil
+	}
+	select {
+	case q.msgs <- m:
+	case <-done:
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package proxyd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWSSendQueue(t *testing.T) {
+	require.Nil(t, newWSSendQueue(WSSendBufferConfig{}))
+
+	done := make(chan struct{})
+	msg := func(data string) wsMessage {
+		return wsMessage{msgType: websocket.TextMessage, data: []byte(data)}
+	}
+
+	t.Run("disconnect", func(t *testing.T) {
+		q := newWSSendQueue(WSSendBufferConfig{Size: 1})
+		require.NoError(t, q.push(done, msg("1"), true, "backend"))
+		require.ErrorIs(t, q.push(done, msg("2"), true, "backend"), ErrWSSlowConsumer)
+	})
+
+	t.Run("skip", func(t *testing.T) {
+		q := newWSSendQueue(WSSendBufferConfig{Size: 2, Policy: WSSendPolicySkip})
+		require.NoError(t, q.push(done, msg("1"), true, "backend"))
+		require.NoError(t, q.push(done, msg("2"), true, "backend"))
+		// notifications are dropped while the queue is full
+		require.NoError(t, q.push(done, msg("3"), true, "backend"))
+		require.NoError(t, q.push(done, msg("4"), true, "backend"))
+		require.Equal(t, 2, q.dropped)
+
+		// and counted once there's room again
+		require.Equal(t, "1", string((<-q.msgs).data))
+		require.Equal(t, "2", string((<-q.msgs).data))
+		require.NoError(t, q.push(done, msg("5"), true, "backend"))
+		var gap map[string]interface{}
+		require.NoError(t, json.Unmarshal((<-q.msgs).data, &gap))
+		require.Equal(t, wsDroppedMethod, gap["method"])
+		require.Equal(t, map[string]interface{}{"count": float64(2)}, gap["params"])
+		require.Equal(t, "5", string((<-q.msgs).data))
+		require.Zero(t, q.dropped)
+
+		// responses wait for room instead
+		require.NoError(t, q.push(done, msg("6"), true, "backend"))
+		require.NoError(t, q.push(done, msg("7"), true, "backend"))
+		pushed := make(chan error)
+		go func() { pushed <- q.push(done, msg("8"), false, "backend") }()
+		require.Equal(t, "6", string((<-q.msgs).data))
+		require.NoError(t, <-pushed)
+		require.Equal(t, "7", string((<-q.msgs).data))
+		require.Equal(t, "8", string((<-q.msgs).data))
+	})
+}